@@ -0,0 +1,125 @@
+// Package access formats game output for screen readers: markup is flattened
+// without decoration, the exits are said in a sentence, and changes to the
+// player's health and score are announced in words, where the TUI would show
+// them in boxes, bars and colors. The plain CLI and the TUI both use it in
+// accessible mode (--accessible).
+package access
+
+import (
+	"strings"
+
+	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/messages"
+	"github.com/nathoo/questcore/types"
+)
+
+// Formatter formats the output of an engine's steps. It remembers the
+// player's health and score, to announce how each step changed them.
+type Formatter struct {
+	eng   *engine.Engine
+	hp    int
+	score int
+}
+
+// New returns a formatter for eng's output.
+func New(eng *engine.Engine) *Formatter {
+	f := &Formatter{eng: eng}
+	f.Sync()
+	return f
+}
+
+// Sync takes the player's current health and score as the baseline for the
+// next step, so that changes not made by a step, such as loading a save,
+// are not announced.
+func (f *Formatter) Sync() {
+	s := f.eng.State
+	f.hp, f.score = s.Player.Stats["hp"], s.Counters["score"]
+}
+
+// Step formats the result of a step: its output, with the exits put in a
+// sentence, then the changes to the player's health and score, its
+// notifications and its epilogue. Blank lines are dropped.
+func (f *Formatter) Step(result types.Result) []string {
+	var lines []string
+	add := func(line string) {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	room := f.eng.State.Player.Location
+	exits := f.eng.ExitsLine(room)
+	for _, line := range result.Output {
+		if exits != "" && line == exits {
+			add(f.exits(f.eng.ExitDirs(room)))
+			continue
+		}
+		add(f.Line(line))
+	}
+	for _, line := range f.changes() {
+		add(line)
+	}
+	for _, note := range result.Notifications {
+		add(note)
+	}
+	if result.Epilogue != nil {
+		for _, line := range engine.EpilogueLines(result.Epilogue) {
+			add(f.Line(line))
+		}
+	}
+	return lines
+}
+
+// Line renders one line of game output as text: headers and quotes are
+// left bare, and dialogue is attributed to its speaker in words.
+func (f *Formatter) Line(line string) string {
+	b := markup.Parse(line)
+	if b.Kind == markup.Dialogue && b.Speaker != "" {
+		return f.msg("speaker_says", "speaker", b.Speaker, "text", b.Text())
+	}
+	return b.Text()
+}
+
+// exits says what the exits are: "Exits are north and south."
+func (f *Formatter) exits(dirs []string) string {
+	if len(dirs) == 1 {
+		return f.msg("exit_is", "dir", dirs[0])
+	}
+	return f.msg("exits_are", "list", f.list(dirs))
+}
+
+// list joins items as a sentence would: "north, east and south".
+func (f *Formatter) list(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	last := len(items) - 1
+	return f.msg("list_and", "list", strings.Join(items[:last], ", "), "last", items[last])
+}
+
+// changes announces how the player's health and score changed since the
+// last step, and takes the new values as the baseline.
+func (f *Formatter) changes() []string {
+	s := f.eng.State
+	hp, maxHP, score := s.Player.Stats["hp"], s.Player.Stats["max_hp"], s.Counters["score"]
+	var lines []string
+	switch {
+	case hp < f.hp:
+		lines = append(lines, f.msg("hp_lost", "n", f.hp-hp, "hp", hp, "max", maxHP))
+	case hp > f.hp:
+		lines = append(lines, f.msg("hp_gained", "n", hp-f.hp, "hp", hp, "max", maxHP))
+	}
+	switch {
+	case score > f.score:
+		lines = append(lines, f.msg("score_up", "n", score-f.score, "score", score))
+	case score < f.score:
+		lines = append(lines, f.msg("score_down", "n", f.score-score, "score", score))
+	}
+	f.hp, f.score = hp, score
+	return lines
+}
+
+func (f *Formatter) msg(key string, args ...any) string {
+	return messages.Text(f.eng.Defs.Messages, key, args...)
+}
@@ -0,0 +1,96 @@
+package access
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+func testDefs() *state.Defs {
+	return &state.Defs{
+		Game: types.GameDef{Title: "Test", Start: "hall"},
+		Rooms: map[string]types.RoomDef{
+			"hall": {
+				ID:          "hall",
+				Description: "A grand hall.",
+				Exits:       map[string]string{"north": "garden", "south": "cellar", "east": "kitchen"},
+			},
+			"garden":  {ID: "garden", Description: "A garden.", Exits: map[string]string{"south": "hall"}},
+			"cellar":  {ID: "cellar", Description: "A cellar."},
+			"kitchen": {ID: "kitchen", Description: "A kitchen."},
+		},
+	}
+}
+
+func TestStep_ExitsInWords(t *testing.T) {
+	eng := engine.New(testDefs())
+	f := New(eng)
+
+	lines := f.Step(eng.Step("look"))
+	if !contains(lines, "Exits are east, north and south.") {
+		t.Errorf("look = %q, want the exits in a sentence", lines)
+	}
+	lines = f.Step(eng.Step("north"))
+	if !contains(lines, "The only exit is south.") {
+		t.Errorf("north = %q, want the only exit named", lines)
+	}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" || strings.Contains(line, "*") {
+			t.Errorf("line %q should be plain and not blank", line)
+		}
+	}
+}
+
+func TestStep_AnnouncesChanges(t *testing.T) {
+	eng := engine.New(testDefs())
+	eng.State.Player.Stats = map[string]int{"hp": 10, "max_hp": 10}
+	f := New(eng)
+
+	eng.State.Player.Stats["hp"] = 7
+	eng.State.Counters["score"] = 5
+	lines := f.Step(eng.Step("look"))
+	for _, want := range []string{"You lose 3 health, leaving 7 of 10.", "Your score rises by 5 to 5."} {
+		if !contains(lines, want) {
+			t.Errorf("lines = %q, want %q", lines, want)
+		}
+	}
+
+	eng.State.Player.Stats["hp"] = 9
+	if lines := f.Step(eng.Step("look")); !contains(lines, "You gain 2 health, for 9 of 10.") {
+		t.Errorf("lines = %q, want the healing announced", lines)
+	}
+
+	eng.State.Counters["score"] = 0
+	f.Sync()
+	for _, line := range f.Step(eng.Step("look")) {
+		if strings.Contains(line, "score") {
+			t.Errorf("change before Sync announced: %q", line)
+		}
+	}
+}
+
+func TestLine(t *testing.T) {
+	f := New(engine.New(testDefs()))
+	tests := map[string]string{
+		"@Scholar Elara: 'Welcome.'": "Scholar Elara says: 'Welcome.'",
+		"# The Hall":                 "The Hall",
+		"A *bright* lamp.":           "A bright lamp.",
+	}
+	for line, want := range tests {
+		if got := f.Line(line); got != want {
+			t.Errorf("Line(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func contains(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want {
+			return true
+		}
+	}
+	return false
+}
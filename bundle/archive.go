@@ -0,0 +1,135 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Ext is the file extension of single-file game archives, which questcore
+// plays as it does game directories.
+const Ext = ".qcgame"
+
+// manifestName is the archive entry holding the Manifest.
+const manifestName = "manifest.json"
+
+// Manifest describes the game in a .qcgame archive.
+type Manifest struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+	Hash    string `json:"hash"` // "sha256:<hex>" over the game's files; see contentHash
+}
+
+// WriteArchive writes the game in dir to w as a .qcgame archive: a zip of
+// the files Pack bundles, with a manifest giving the game's title and
+// version and a hash of its content.
+func WriteArchive(w io.Writer, dir, title, version string) (Manifest, error) {
+	names, err := contentNames(dir)
+	if err != nil {
+		return Manifest{}, err
+	}
+	files := map[string][]byte{}
+	for _, name := range names {
+		if files[name], err = os.ReadFile(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			return Manifest{}, err
+		}
+	}
+	m := Manifest{Title: title, Version: version, Hash: contentHash(files)}
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	zw := zip.NewWriter(w)
+	if err := writeEntry(zw, manifestName, manifest); err != nil {
+		return Manifest{}, err
+	}
+	for _, name := range names {
+		if err := writeEntry(zw, name, files[name]); err != nil {
+			return Manifest{}, err
+		}
+	}
+	return m, zw.Close()
+}
+
+// writeEntry adds a file to a zip.
+func writeEntry(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// OpenArchive reads the .qcgame archive at path and returns its files, for
+// loader.LoadFS, and its manifest. The archive is rejected if its files
+// don't match the manifest's hash.
+func OpenArchive(path string) (fs.FS, Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, Manifest{}, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, Manifest{}, fmt.Errorf("%s: not a game archive: %w", path, err)
+	}
+
+	var m Manifest
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		content, err := readEntry(f)
+		if err != nil {
+			return nil, Manifest{}, fmt.Errorf("%s: %w", path, err)
+		}
+		if f.Name == manifestName {
+			if err := json.Unmarshal(content, &m); err != nil {
+				return nil, Manifest{}, fmt.Errorf("%s: bad manifest: %w", path, err)
+			}
+			continue
+		}
+		files[f.Name] = content
+	}
+	switch {
+	case m.Hash == "":
+		return nil, Manifest{}, fmt.Errorf("%s: not a game archive: no manifest", path)
+	case m.Hash != contentHash(files):
+		return nil, Manifest{}, errors.New(path + ": game archive is corrupt: content doesn't match its manifest")
+	}
+	return zr, m, nil
+}
+
+// readEntry returns the content of a zip entry.
+func readEntry(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// contentHash hashes files by name and content, in name order, so that the
+// same game always hashes the same however it was zipped.
+func contentHash(files map[string][]byte) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%d\x00", name, len(files[name]))
+		h.Write(files[name])
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
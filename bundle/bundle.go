@@ -0,0 +1,287 @@
+// Package bundle builds self-contained game executables: a questcore runtime
+// binary with a game's files appended as a zip payload, packaged as a
+// zip archive per target platform for distribution to players. It also
+// packs games into single-file .qcgame archives, which questcore plays
+// directly.
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/nathoo/questcore/loader"
+)
+
+// magic ends every bundled executable. It is preceded by the payload length
+// as a big-endian uint64, which is preceded by the payload itself.
+const magic = "QCGAME\x00\x01"
+
+const trailerSize = 8 + len(magic)
+
+// Platform is a build target.
+type Platform struct {
+	Name string // name used in archive file names, e.g. "mac"
+	OS   string // GOOS
+	Arch string // GOARCH
+}
+
+// platformAliases maps the friendly names accepted by ParsePlatforms to
+// targets. Any other name must be given as "os/arch".
+var platformAliases = map[string]Platform{
+	"linux":   {Name: "linux", OS: "linux", Arch: "amd64"},
+	"windows": {Name: "windows", OS: "windows", Arch: "amd64"},
+	"mac":     {Name: "mac", OS: "darwin", Arch: "arm64"},
+}
+
+// ParsePlatforms parses a comma-separated platform list such as
+// "linux,windows,mac" or "linux/arm64".
+func ParsePlatforms(list string) ([]Platform, error) {
+	var platforms []Platform
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if p, ok := platformAliases[name]; ok {
+			platforms = append(platforms, p)
+			continue
+		}
+		goos, goarch, ok := strings.Cut(name, "/")
+		if !ok || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("unknown platform %q (use linux, windows, mac, or os/arch)", name)
+		}
+		platforms = append(platforms, Platform{
+			Name: goos + "-" + goarch, OS: goos, Arch: goarch,
+		})
+	}
+	if len(platforms) == 0 {
+		return nil, errors.New("no platforms given")
+	}
+	return platforms, nil
+}
+
+// RuntimeName is the file name of the prebuilt questcore binary for p, as
+// published with each release: questcore-<os>-<arch>[.exe].
+func RuntimeName(p Platform) string {
+	return "questcore-" + p.OS + "-" + p.Arch + exeSuffix(p)
+}
+
+func exeSuffix(p Platform) string {
+	if p.OS == "windows" {
+		return ".exe"
+	}
+	return ""
+}
+
+// Pack zips the game's .lua files, those loader.GameFiles lists and those
+// in its locales directory, and the images its rooms and entities show.
+func Pack(dir string) ([]byte, error) {
+	names, err := contentNames(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		src, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(src); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// contentNames returns the names of the files Pack bundles from the game
+// in dir. The game is loaded to find the images it shows, so a game that
+// doesn't load isn't bundled.
+func contentNames(dir string) ([]string, error) {
+	names, err := loader.GameFiles(os.DirFS(dir))
+	if err != nil {
+		return nil, fmt.Errorf("reading game directory %s: %w", dir, err)
+	}
+	if locales, err := os.ReadDir(filepath.Join(dir, "locales")); err == nil {
+		names = append(names, luaNames(locales, "locales/")...)
+	}
+	defs, err := loader.Load(dir)
+	if err != nil {
+		return nil, fmt.Errorf("loading game %s: %w", dir, err)
+	}
+	return append(names, loader.Images(defs)...), nil
+}
+
+// luaNames returns the names of the .lua files among entries, with prefix
+// prepended.
+func luaNames(entries []os.DirEntry, prefix string) []string {
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".lua") {
+			names = append(names, prefix+e.Name())
+		}
+	}
+	return names
+}
+
+// Append writes the runtime binary followed by the game payload and trailer.
+func Append(w io.Writer, runtimeBin io.Reader, payload []byte) error {
+	if _, err := io.Copy(w, runtimeBin); err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	var trailer [trailerSize]byte
+	binary.BigEndian.PutUint64(trailer[:8], uint64(len(payload)))
+	copy(trailer[8:], magic)
+	_, err := w.Write(trailer[:])
+	return err
+}
+
+// Embedded returns the game bundled into the executable at path, or
+// ok=false if it has none.
+func Embedded(path string) (fsys fs.FS, ok bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+	size := info.Size()
+	if size < int64(trailerSize) {
+		return nil, false, nil
+	}
+
+	var trailer [trailerSize]byte
+	if _, err := f.ReadAt(trailer[:], size-int64(trailerSize)); err != nil {
+		return nil, false, err
+	}
+	if string(trailer[8:]) != magic {
+		return nil, false, nil
+	}
+	n := binary.BigEndian.Uint64(trailer[:8])
+	if n > uint64(size)-uint64(trailerSize) {
+		return nil, false, errors.New("corrupt game bundle")
+	}
+
+	payload := make([]byte, n)
+	if _, err := f.ReadAt(payload, size-int64(trailerSize)-int64(n)); err != nil {
+		return nil, false, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(payload), int64(n))
+	if err != nil {
+		return nil, false, fmt.Errorf("corrupt game bundle: %w", err)
+	}
+	return zr, true, nil
+}
+
+// Options configures Build.
+type Options struct {
+	GameDir    string     // game to bundle
+	Name       string     // base name for executables and archives
+	Version    string     // appended to archive names if non-empty
+	Platforms  []Platform // targets to build
+	RuntimeDir string     // directory holding questcore-<os>-<arch> binaries
+	OutDir     string     // where archives are written
+}
+
+// Build writes one zip archive per platform to opts.OutDir, named
+// <name>[-<version>]-<platform>.zip, each containing a single executable
+// that plays the game. The running executable is used for the current
+// platform; other platforms need their runtime in opts.RuntimeDir. Returns
+// the paths of the archives written.
+func Build(opts Options) ([]string, error) {
+	payload, err := Pack(opts.GameDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var archives []string
+	for _, p := range opts.Platforms {
+		runtimePath, err := findRuntime(p, opts.RuntimeDir)
+		if err != nil {
+			return archives, err
+		}
+		path, err := writeArchive(p, runtimePath, payload, opts)
+		if err != nil {
+			return archives, fmt.Errorf("bundling for %s: %w", p.Name, err)
+		}
+		archives = append(archives, path)
+	}
+	sort.Strings(archives)
+	return archives, nil
+}
+
+// findRuntime locates the questcore binary for p.
+func findRuntime(p Platform, runtimeDir string) (string, error) {
+	if runtimeDir != "" {
+		path := filepath.Join(runtimeDir, RuntimeName(p))
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	if p.OS == runtime.GOOS && p.Arch == runtime.GOARCH {
+		return os.Executable()
+	}
+	return "", fmt.Errorf("no questcore runtime for %s/%s: put %s from the questcore release in the runtimes directory",
+		p.OS, p.Arch, RuntimeName(p))
+}
+
+func writeArchive(p Platform, runtimePath string, payload []byte, opts Options) (string, error) {
+	runtimeBin, err := os.Open(runtimePath)
+	if err != nil {
+		return "", err
+	}
+	defer runtimeBin.Close()
+
+	base := opts.Name
+	if opts.Version != "" {
+		base += "-" + opts.Version
+	}
+	path := filepath.Join(opts.OutDir, base+"-"+p.Name+".zip")
+	out, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	zw := zip.NewWriter(out)
+	hdr := &zip.FileHeader{Name: opts.Name + "/" + opts.Name + exeSuffix(p), Method: zip.Deflate}
+	hdr.SetMode(0o755)
+	w, err := zw.CreateHeader(hdr)
+	if err != nil {
+		return "", err
+	}
+	if err := Append(w, runtimeBin, payload); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return path, out.Close()
+}
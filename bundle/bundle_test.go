@@ -0,0 +1,266 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParsePlatforms(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []Platform
+		wantErr bool
+	}{
+		{"linux", []Platform{{"linux", "linux", "amd64"}}, false},
+		{"windows, mac", []Platform{{"windows", "windows", "amd64"}, {"mac", "darwin", "arm64"}}, false},
+		{"linux/arm64", []Platform{{"linux-arm64", "linux", "arm64"}}, false},
+		{"amiga", nil, true},
+		{"", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePlatforms(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePlatforms(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("ParsePlatforms(%q) = %v, want %v", tt.list, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ParsePlatforms(%q)[%d] = %v, want %v", tt.list, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestRuntimeName(t *testing.T) {
+	if got := RuntimeName(Platform{OS: "windows", Arch: "amd64"}); got != "questcore-windows-amd64.exe" {
+		t.Errorf("got %q", got)
+	}
+	if got := RuntimeName(Platform{OS: "darwin", Arch: "arm64"}); got != "questcore-darwin-arm64" {
+		t.Errorf("got %q", got)
+	}
+}
+
+// writeGame creates a tiny game directory and returns its path.
+func writeGame(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "game.lua"), []byte(`Game { title = "T", start = "hall" }`), 0o644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not content"), 0o644)
+	os.Mkdir(filepath.Join(dir, "locales"), 0o755)
+	os.WriteFile(filepath.Join(dir, "locales", "fr.lua"), []byte(`Messages { wait = "Le temps passe." }`), 0o644)
+	os.Mkdir(filepath.Join(dir, "rooms"), 0o755)
+	os.WriteFile(filepath.Join(dir, "rooms", "hall.lua"), []byte(`Room "hall" { description = "A hall." }`), 0o644)
+	return dir
+}
+
+func TestAppendAndEmbedded_RoundTrip(t *testing.T) {
+	payload, err := Pack(writeGame(t))
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+
+	exe := filepath.Join(t.TempDir(), "game")
+	var buf bytes.Buffer
+	if err := Append(&buf, bytes.NewReader([]byte("RUNTIME")), payload); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	os.WriteFile(exe, buf.Bytes(), 0o755)
+
+	fsys, ok, err := Embedded(exe)
+	if err != nil || !ok {
+		t.Fatalf("Embedded = ok %v, err %v", ok, err)
+	}
+	src, err := fs.ReadFile(fsys, "game.lua")
+	if err != nil || !bytes.Contains(src, []byte(`title = "T"`)) {
+		t.Errorf("game.lua = %q, err %v", src, err)
+	}
+	if _, err := fs.ReadFile(fsys, "locales/fr.lua"); err != nil {
+		t.Errorf("locales/fr.lua should be packed: %v", err)
+	}
+	if _, err := fs.ReadFile(fsys, "rooms/hall.lua"); err != nil {
+		t.Errorf("rooms/hall.lua should be packed: %v", err)
+	}
+	if _, err := fs.ReadFile(fsys, "notes.txt"); err == nil {
+		t.Error("non-Lua files should not be packed")
+	}
+}
+
+func TestPack_Images(t *testing.T) {
+	dir := writeGame(t)
+	png := []byte("\x89PNG fake")
+	os.Mkdir(filepath.Join(dir, "images"), 0o755)
+	os.WriteFile(filepath.Join(dir, "images", "hall.png"), png, 0o644)
+	os.WriteFile(filepath.Join(dir, "images", "unused.png"), png, 0o644)
+	os.WriteFile(filepath.Join(dir, "rooms", "hall.lua"), []byte(`Room "hall" { description = "A hall.", image = "images/hall.png" }`), 0o644)
+
+	payload, err := Pack(dir)
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	exe := filepath.Join(t.TempDir(), "game")
+	var buf bytes.Buffer
+	if err := Append(&buf, bytes.NewReader([]byte("RUNTIME")), payload); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	os.WriteFile(exe, buf.Bytes(), 0o755)
+
+	fsys, ok, err := Embedded(exe)
+	if err != nil || !ok {
+		t.Fatalf("Embedded = ok %v, err %v", ok, err)
+	}
+	if got, err := fs.ReadFile(fsys, "images/hall.png"); err != nil || !bytes.Equal(got, png) {
+		t.Errorf("images/hall.png = %q, err %v; want the room's image packed", got, err)
+	}
+	if _, err := fs.ReadFile(fsys, "images/unused.png"); err == nil {
+		t.Error("images no room or entity shows should not be packed")
+	}
+}
+
+func TestEmbedded_PlainExecutable(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "plain")
+	os.WriteFile(exe, []byte("just a binary"), 0o755)
+
+	_, ok, err := Embedded(exe)
+	if ok || err != nil {
+		t.Errorf("Embedded = ok %v, err %v; want no game", ok, err)
+	}
+}
+
+func TestBuild_ArchivesPerPlatform(t *testing.T) {
+	runtimes := t.TempDir()
+	os.WriteFile(filepath.Join(runtimes, "questcore-windows-amd64.exe"), []byte("WIN"), 0o755)
+	os.WriteFile(filepath.Join(runtimes, "questcore-linux-arm64"), []byte("ARM"), 0o755)
+	platforms, _ := ParsePlatforms("windows,linux/arm64")
+	out := t.TempDir()
+
+	archives, err := Build(Options{
+		GameDir:    writeGame(t),
+		Name:       "my_game",
+		Version:    "1.2",
+		Platforms:  platforms,
+		RuntimeDir: runtimes,
+		OutDir:     out,
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	want := []string{
+		filepath.Join(out, "my_game-1.2-linux-arm64.zip"),
+		filepath.Join(out, "my_game-1.2-windows.zip"),
+	}
+	if len(archives) != 2 || archives[0] != want[0] || archives[1] != want[1] {
+		t.Fatalf("archives = %v, want %v", archives, want)
+	}
+
+	zr, err := zip.OpenReader(want[1])
+	if err != nil {
+		t.Fatalf("opening archive: %v", err)
+	}
+	defer zr.Close()
+	if len(zr.File) != 1 || zr.File[0].Name != "my_game/my_game.exe" {
+		t.Fatalf("archive contents = %v", zr.File)
+	}
+	rc, _ := zr.File[0].Open()
+	exe, _ := io.ReadAll(rc)
+	rc.Close()
+	if !bytes.HasPrefix(exe, []byte("WIN")) {
+		t.Error("executable should start with the windows runtime")
+	}
+}
+
+func TestBuild_MissingRuntime(t *testing.T) {
+	_, err := Build(Options{
+		GameDir:   writeGame(t),
+		Name:      "g",
+		Platforms: []Platform{{Name: "plan9-386", OS: "plan9", Arch: "386"}},
+		OutDir:    t.TempDir(),
+	})
+	if err == nil {
+		t.Fatal("expected error for missing runtime")
+	}
+}
+
+func TestArchive_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "t"+Ext)
+	f, _ := os.Create(path)
+	written, err := WriteArchive(f, writeGame(t), "T", "1.0")
+	f.Close()
+	if err != nil {
+		t.Fatalf("WriteArchive: %v", err)
+	}
+
+	fsys, m, err := OpenArchive(path)
+	if err != nil {
+		t.Fatalf("OpenArchive: %v", err)
+	}
+	if m != written || m.Title != "T" || m.Version != "1.0" {
+		t.Errorf("manifest = %+v, wrote %+v", m, written)
+	}
+	if _, err := fs.ReadFile(fsys, "rooms/hall.lua"); err != nil {
+		t.Errorf("rooms/hall.lua should be packed: %v", err)
+	}
+	if _, err := fs.ReadFile(fsys, "notes.txt"); err == nil {
+		t.Error("non-Lua files should not be packed")
+	}
+}
+
+func TestOpenArchive_Rejects(t *testing.T) {
+	// tampered rewrites a packed archive with hall.lua changed but the
+	// original manifest kept.
+	tampered := func(t *testing.T) []byte {
+		var packed bytes.Buffer
+		if _, err := WriteArchive(&packed, writeGame(t), "T", ""); err != nil {
+			t.Fatalf("WriteArchive: %v", err)
+		}
+		zr, _ := zip.NewReader(bytes.NewReader(packed.Bytes()), int64(packed.Len()))
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, f := range zr.File {
+			rc, _ := f.Open()
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			if f.Name == "rooms/hall.lua" {
+				data = []byte(`Room "hall" { description = "Changed." }`)
+			}
+			w, _ := zw.Create(f.Name)
+			w.Write(data)
+		}
+		zw.Close()
+		return buf.Bytes()
+	}
+	noManifest := func(t *testing.T) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, _ := zw.Create("game.lua")
+		w.Write([]byte(`Game { title = "T", start = "hall" }`))
+		zw.Close()
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name string
+		data func(*testing.T) []byte
+	}{
+		{"tampered", tampered},
+		{"no manifest", noManifest},
+		{"not a zip", func(*testing.T) []byte { return []byte("not a zip") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "t"+Ext)
+			os.WriteFile(path, tt.data(t), 0o644)
+			if _, _, err := OpenArchive(path); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -3,102 +3,200 @@
 package cli
 
 import (
-	"bufio"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/nathoo/questcore/access"
+	"github.com/nathoo/questcore/config"
+	"github.com/nathoo/questcore/console"
 	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/rules"
 	"github.com/nathoo/questcore/engine/save"
 	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/engine/trace"
+	"github.com/nathoo/questcore/history"
+	"github.com/nathoo/questcore/profile"
+	"github.com/nathoo/questcore/theme"
 	"github.com/nathoo/questcore/types"
 )
 
 // CLI handles terminal interaction with the player.
 type CLI struct {
-	Engine    *engine.Engine
-	Defs      *state.Defs
-	In        io.Reader
-	Out       io.Writer
-	SaveDir   string
-	Trace     bool
-	EchoInput bool   // echo each input line after the prompt (for script playback)
-	lastCmd   string // for "again"/"g" repeat
+	Engine      *engine.Engine
+	Defs        *state.Defs
+	In          io.Reader
+	Out         io.Writer
+	SaveDir     string
+	Autosave    int    // turns between saves to the "autosave" slot (0 = off)
+	ProfilePath string // achievements kept between playthroughs ("" = this session only)
+	HistoryPath string // commands kept between sessions at a terminal ("" = this session only)
+	Trace       bool
+	TraceOnly   []trace.Subsystem   // subsystems Trace shows (none = all)
+	EchoInput   bool                // echo each input line after the prompt (for script playback)
+	Delay       time.Duration       // pause before each echoed command (for script playback; see #delay)
+	Color       theme.Theme         // colors output with ANSI escapes (nil = plain text)
+	Access      *access.Formatter   // words output for screen readers (nil = as shown)
+	Console     *console.Console    // the authors' /lua prompt (nil = off; see --dev)
+	lastCmd     string              // for "again"/"g" repeat
+	autosaved   int                 // the turn of the last autosave
+	choices     []types.Choice      // the options last offered, picked by number
+	luaMode     bool                // input is Lua for Console, until /lua again
+	sleep       func(time.Duration) // pauses script playback (nil = time.Sleep)
+	execDepth   int                 // nesting level of /exec files
+
+	marks    map[string]*types.State // in-memory checkpoints set by /mark, by name
+	recorder *history.Recorder       // where /record writes commands (nil = not recording)
+
+	traceLog  *trace.Memory // the engine's trace records since the last command (nil = not yet traced)
+	lastTrace []trace.Event // the trace records of the last game command, for /trace and /why
+
+	// Script expectations (see script.go).
+	lastInput    string   // last line handled, for failure messages
+	lastOutput   []string // lines printed while handling lastInput
+	expectations int
+	failures     []string
 }
 
 // New creates a CLI wired to the given engine.
 func New(eng *engine.Engine, defs *state.Defs) *CLI {
-	home, _ := os.UserHomeDir()
-	saveDir := filepath.Join(home, ".questcore", "saves")
 	return &CLI{
 		Engine:  eng,
 		Defs:    defs,
 		In:      os.Stdin,
 		Out:     os.Stdout,
-		SaveDir: saveDir,
+		SaveDir: filepath.Join(config.DataDir(), "saves"),
 	}
 }
 
 // Run starts the game loop. It shows the intro, describes the starting room,
 // then loops: prompt → input → dispatch → output.
 func (c *CLI) Run() {
+	c.loadProfile()
+
 	// Show intro.
-	if c.Defs.Game.Intro != "" {
-		c.printLine(c.Defs.Game.Intro)
-		c.printLine("")
+	if intro := c.Defs.Game.Intro; intro != "" {
+		if c.Access != nil {
+			c.printLine(c.Access.Line(intro))
+		} else {
+			c.printOutput(intro)
+			c.printLine("")
+		}
 	}
 
 	// Describe starting room.
 	result := c.Engine.Step("look")
 	c.printResult(result)
 
-	scanner := bufio.NewScanner(c.In)
+	readLine := c.lineReader()
 	for {
-		c.print("> ")
-		if !scanner.Scan() {
-			break
+		prompt := "> "
+		if c.luaMode {
+			prompt = "lua> "
 		}
-		input := strings.TrimSpace(scanner.Text())
+		line, err := readLine(c.paint(prompt, "prompt"))
+		if err != nil {
+			break // end of input, or Ctrl-C
+		}
+		input := strings.TrimSpace(line)
 		if input == "" {
 			continue
 		}
-		// Skip comment lines (for script files).
+		// Check expectations, run playback directives and skip comment
+		// lines (for script files).
 		if strings.HasPrefix(input, "#") {
+			if isDirective(input) {
+				c.handleDirective(input)
+			} else if isPlayback(input) {
+				c.handlePlayback(input)
+			}
 			continue
 		}
 		if c.EchoInput {
+			c.pause(c.Delay)
 			c.printLine(input)
 		}
 
-		// Meta-commands start with '/'.
-		if strings.HasPrefix(input, "/") {
-			if c.handleMeta(input) {
-				return // /quit
-			}
-			continue
+		if c.handleInput(input) {
+			break // /quit
 		}
+	}
+	c.stopRecording()
+	c.printExpectationSummary()
+}
 
-		// "again" / "g" repeats the last game command.
-		lower := strings.ToLower(input)
-		if lower == "again" || lower == "g" {
-			if c.lastCmd == "" {
-				c.printLine("Nothing to repeat.")
-				continue
-			}
-			input = c.lastCmd
-		} else {
-			c.lastCmd = input
+// handleInput runs one line of player input: a meta-command or a game
+// command. Returns true if the game should exit.
+func (c *CLI) handleInput(input string) bool {
+	c.lastInput = input
+	c.lastOutput = nil
+
+	// A number picks one of the options just offered.
+	choices := c.choices
+	c.choices = nil
+	if n, err := strconv.Atoi(input); err == nil && n >= 1 && n <= len(choices) {
+		input = choices[n-1].Command
+	}
+
+	// Meta-commands start with '/'. The game's own may stand for a game
+	// command, which is run in their place.
+	if strings.HasPrefix(input, "/") {
+		result, command, ok := c.Engine.MetaCommand(input)
+		if !ok {
+			return c.handleMeta(input)
+		}
+		if command == "" {
+			c.printResult(result)
+			return false
 		}
+		input = command
+	} else if c.luaMode {
+		c.evalLua(input)
+		return false
+	}
 
-		result := c.Engine.Step(input)
-		c.printResult(result)
+	// "again" / "g" repeats the last game command.
+	lower := strings.ToLower(input)
+	if lower == "again" || lower == "g" {
+		if c.lastCmd == "" {
+			c.printLine("Nothing to repeat.")
+			return false
+		}
+		input = c.lastCmd
+	} else {
+		c.lastCmd = input
+	}
 
-		if c.Trace {
-			c.printTrace(result)
+	if c.traceLog == nil {
+		c.traceLog = &trace.Memory{}
+		c.Engine.Trace = trace.Multi(c.Engine.Trace, c.traceLog)
+	}
+	c.traceLog.Take() // drop records of steps run by meta-commands
+	c.record(input)
+	results := c.Engine.StepAll(input)
+	c.lastTrace = c.traceLog.Take()
+	for i, result := range results {
+		if i > 0 {
+			c.printLine("")
 		}
+		c.printResult(result)
+		if profile.Unlocked(result.Events) {
+			c.saveProfile()
+		}
+	}
+	c.autosave()
+
+	if c.Trace {
+		c.printTrace()
 	}
+	return false
 }
 
 // handleMeta dispatches meta-commands. Returns true if the game should exit.
@@ -121,20 +219,49 @@ func (c *CLI) handleMeta(input string) bool {
 	case "/load":
 		c.cmdLoad(arg)
 
+	case "/export-save":
+		c.cmdExportSave()
+
+	case "/import-save":
+		c.cmdImportSave(strings.TrimPrefix(input, cmd))
+
+	case "/mark":
+		c.cmdMark(arg)
+
+	case "/rewind":
+		c.cmdRewind(arg)
+
+	case "/record":
+		c.cmdRecord(arg)
+
+	case "/stats":
+		for _, line := range c.Engine.StatsReport() {
+			c.printLine(line)
+		}
+
 	case "/help":
 		c.cmdHelp()
 
 	case "/state":
 		c.cmdState()
 
+	case "/exec":
+		return c.cmdExec(strings.TrimSpace(strings.TrimPrefix(input, cmd)))
+
 	case "/trace":
-		c.Trace = !c.Trace
-		if c.Trace {
-			c.printSystem("Trace output enabled.")
-		} else {
-			c.printSystem("Trace output disabled.")
+		c.cmdTrace(arg)
+
+	case "/lua":
+		c.cmdLua(strings.TrimSpace(strings.TrimPrefix(input, cmd)))
+
+	case "/why":
+		for _, line := range trace.Why(c.lastTrace) {
+			c.printLine(line)
 		}
 
+	case "/rules":
+		c.cmdRules(strings.TrimSpace(strings.TrimPrefix(input, cmd)))
+
 	default:
 		c.printSystem(fmt.Sprintf("Unknown command: %s. Type /help for available commands.", cmd))
 	}
@@ -147,24 +274,63 @@ func (c *CLI) cmdSave(name string) {
 		name = "quicksave"
 	}
 
-	data, err := save.Save(c.Engine.State, c.Defs)
-	if err != nil {
+	if err := c.writeSave(name); err != nil {
 		c.printSystem(fmt.Sprintf("Save failed: %v", err))
 		return
 	}
+	c.printSystem(fmt.Sprintf("Game saved to %s.", name))
+}
 
+// writeSave saves the game to the slot name in SaveDir.
+func (c *CLI) writeSave(name string) error {
+	data, err := save.Save(c.Engine.State, c.Defs)
+	if err != nil {
+		return err
+	}
 	if err := os.MkdirAll(c.SaveDir, 0o755); err != nil {
-		c.printSystem(fmt.Sprintf("Save failed: %v", err))
+		return err
+	}
+	return os.WriteFile(filepath.Join(c.SaveDir, name+".json"), data, 0o644)
+}
+
+// autosave saves the game to the "autosave" slot once Autosave turns have
+// passed since the last autosave. It says nothing unless the save fails.
+func (c *CLI) autosave() {
+	turn := c.Engine.State.TurnCount
+	if turn < c.autosaved {
+		c.autosaved = turn // an earlier save was loaded
+	}
+	if c.Autosave <= 0 || turn-c.autosaved < c.Autosave {
 		return
 	}
+	c.autosaved = turn
+	if err := c.writeSave("autosave"); err != nil {
+		c.printSystem(fmt.Sprintf("Autosave failed: %v", err))
+	}
+}
 
-	path := filepath.Join(c.SaveDir, name+".json")
-	if err := os.WriteFile(path, data, 0o644); err != nil {
-		c.printSystem(fmt.Sprintf("Save failed: %v", err))
+// loadProfile restores the achievements unlocked in earlier playthroughs.
+func (c *CLI) loadProfile() {
+	if c.ProfilePath == "" {
+		return
+	}
+	p, err := profile.Load(c.ProfilePath)
+	if err != nil {
+		c.printSystem(fmt.Sprintf("Could not load achievements: %v", err))
 		return
 	}
+	c.Engine.State.Achievements = p.Achievements
+}
 
-	c.printSystem(fmt.Sprintf("Game saved to %s.", name))
+// saveProfile records the player's achievements in their profile.
+func (c *CLI) saveProfile() {
+	if c.ProfilePath == "" {
+		return
+	}
+	p := &profile.Profile{Game: c.Defs.Game.Title, Achievements: c.Engine.State.Achievements}
+	if err := p.Save(c.ProfilePath); err != nil {
+		c.printSystem(fmt.Sprintf("Could not save achievements: %v", err))
+	}
 }
 
 func (c *CLI) cmdLoad(name string) {
@@ -180,6 +346,9 @@ func (c *CLI) cmdLoad(name string) {
 	}
 
 	sd, err := save.Load(data)
+	if err == nil {
+		err = sd.CheckGame(c.Defs)
+	}
 	if err != nil {
 		c.printSystem(fmt.Sprintf("Load failed: %v", err))
 		return
@@ -187,11 +356,180 @@ func (c *CLI) cmdLoad(name string) {
 
 	save.ApplySave(c.Engine.State, sd)
 	c.Engine.RestoreRNG(sd.RNGSeed, sd.RNGPosition)
+	c.syncAccess()
 	c.printSystem(fmt.Sprintf("Game loaded from %s (turn %d).", name, sd.Turn))
 
 	// Show current room after loading.
-	result := c.Engine.Step("look")
-	c.printResult(result)
+	c.printResult(c.Engine.Look())
+}
+
+func (c *CLI) cmdExportSave() {
+	code, err := save.Export(c.Engine.State, c.Defs)
+	if err != nil {
+		c.printSystem(fmt.Sprintf("Export failed: %v", err))
+		return
+	}
+	c.printSystem("Save code (restore with /import-save <code>):")
+	c.printLine(code) // unbracketed, so it copies cleanly
+}
+
+func (c *CLI) cmdImportSave(code string) {
+	if strings.TrimSpace(code) == "" {
+		c.printSystem("Usage: /import-save <code>")
+		return
+	}
+	sd, err := save.Import(code)
+	if err == nil {
+		err = sd.CheckGame(c.Defs)
+	}
+	if err != nil {
+		c.printSystem(fmt.Sprintf("Import failed: %v", err))
+		return
+	}
+
+	save.ApplySave(c.Engine.State, sd)
+	c.Engine.RestoreRNG(sd.RNGSeed, sd.RNGPosition)
+	c.syncAccess()
+	c.printSystem(fmt.Sprintf("Save code imported (turn %d).", sd.Turn))
+
+	c.printResult(c.Engine.Look())
+}
+
+// cmdRecord starts writing the game commands played to the script file
+// path, stops with "off", or says where they are going.
+func (c *CLI) cmdRecord(path string) {
+	switch path {
+	case "":
+		if c.recorder == nil {
+			c.printSystem("Not recording. Usage: /record <file>, /record off")
+			return
+		}
+		c.printSystem(fmt.Sprintf("Recording to %s (%d commands).", c.recorder.Path(), c.recorder.Count()))
+	case "off":
+		if c.recorder == nil {
+			c.printSystem("Not recording.")
+			return
+		}
+		c.stopRecording()
+	default:
+		c.stopRecording()
+		r, err := history.Record(path, c.Engine)
+		if err != nil {
+			c.printSystem(fmt.Sprintf("Could not record: %v", err))
+			return
+		}
+		c.recorder = r
+		c.printSystem(fmt.Sprintf("Recording to %s (%d commands so far).", path, r.Count()))
+	}
+}
+
+// record writes a game command to the /record script, if one is open.
+func (c *CLI) record(command string) {
+	if c.recorder == nil {
+		return
+	}
+	if err := c.recorder.Add(command); err != nil {
+		c.printSystem(fmt.Sprintf("Recording failed: %v", err))
+		c.stopRecording()
+	}
+}
+
+// stopRecording closes the /record script, if one is open.
+func (c *CLI) stopRecording() {
+	if c.recorder == nil {
+		return
+	}
+	r := c.recorder
+	c.recorder = nil
+	if err := r.Close(); err != nil {
+		c.printSystem(fmt.Sprintf("Recording failed: %v", err))
+		return
+	}
+	c.printSystem(fmt.Sprintf("Recorded %d commands to %s.", r.Count(), r.Path()))
+}
+
+// cmdMark remembers the game as it is now under name, for /rewind. Marks
+// live in memory only and are lost on quitting.
+func (c *CLI) cmdMark(name string) {
+	if name == "" {
+		c.printSystem("Usage: /mark <name>")
+		return
+	}
+	if c.marks == nil {
+		c.marks = map[string]*types.State{}
+	}
+	c.marks[name] = c.Engine.Snapshot()
+	c.printSystem(fmt.Sprintf("Marked %s (turn %d).", name, c.Engine.State.TurnCount))
+}
+
+// cmdRewind returns the game to the mark called name. Achievements unlocked
+// since are kept, as they are by /load.
+func (c *CLI) cmdRewind(name string) {
+	snap, ok := c.marks[name]
+	if !ok {
+		c.printSystem(rewindUsage(name, c.marks))
+		return
+	}
+	achievements := c.Engine.State.Achievements
+	c.Engine.Restore(snap)
+	c.Engine.State.Achievements = achievements
+	c.syncAccess()
+	c.printSystem(fmt.Sprintf("Rewound to %s (turn %d).", name, snap.TurnCount))
+
+	c.printResult(c.Engine.Look())
+}
+
+// rewindUsage explains a /rewind to an unknown mark and lists the marks set.
+func rewindUsage(name string, marks map[string]*types.State) string {
+	msg := "Usage: /rewind <name>"
+	if name != "" {
+		msg = fmt.Sprintf("No mark called %s.", name)
+	}
+	if len(marks) == 0 {
+		return msg + " Set one with /mark <name>."
+	}
+	return msg + " Marks: " + strings.Join(slices.Sorted(maps.Keys(marks)), ", ") + "."
+}
+
+// maxExecDepth limits how deeply /exec files may include each other.
+const maxExecDepth = 8
+
+// cmdExec runs each line of a command file through the normal input loop,
+// echoing it after a prompt. Blank lines and # comments are skipped.
+// Returns true if the file issued /quit.
+func (c *CLI) cmdExec(path string) bool {
+	if path == "" {
+		c.printSystem("Usage: /exec <file>")
+		return false
+	}
+	if c.execDepth >= maxExecDepth {
+		c.printSystem(fmt.Sprintf("Exec failed: %s: files nested too deeply.", path))
+		return false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.printSystem(fmt.Sprintf("Exec failed: %v", err))
+		return false
+	}
+
+	c.execDepth++
+	defer func() { c.execDepth-- }()
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if isDirective(line) {
+			c.handleDirective(line)
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		c.printLine("> " + line)
+		if c.handleInput(line) {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *CLI) cmdHelp() {
@@ -199,10 +537,25 @@ func (c *CLI) cmdHelp() {
 		"System:",
 		"  /save [name]  — Save game (default: quicksave)",
 		"  /load [name]  — Load game (default: quicksave)",
+		"  /export-save  — Print the game as a code to copy",
+		"  /import-save <code> — Restore a game from a code",
+		"  /mark <name>  — Remember this moment, in memory only",
+		"  /rewind <name> — Return to a mark",
+		"  /stats        — Show statistics for this session and game",
+		"  /record <file> — Write the commands you play to a --script file",
+		"  /record off   — Stop recording",
 		"  /quit         — Exit game",
 		"  /help         — Show this help",
 		"  /state        — Debug: dump current state",
-		"  /trace        — Toggle debug trace output",
+		"  /trace [subsystems] — Toggle debug trace output, or show only",
+		"                  some of parse,resolve,rules,effects,events",
+		"  /why          — Debug: explain how the last command was handled",
+		"  /lua [code]   — Debug: run Lua against the game (with --dev)",
+		"  /rules [verb|thing] — Debug: list the rules that can apply here",
+		"  /exec <file>  — Run commands from a file",
+	}
+	help = append(help, c.Engine.MetaCommandHelp()...)
+	help = append(help,
 		"",
 		"Game commands:",
 		"  look (l)              — Describe the room",
@@ -217,13 +570,15 @@ func (c *CLI) cmdHelp() {
 		"  give <item> to <npc>  — Give an item to someone",
 		"  inventory (i)         — Check what you're carrying",
 		"  wait (z)              — Let time pass",
+		"  verbose / brief       — Room text every visit / first visit only",
+		"  superbrief            — Never show room text when moving",
 		"  again (g)             — Repeat your last command",
 		"",
 		"Combat:",
 		"  attack                — Attack the enemy",
 		"  defend                — Defend (reduces damage taken)",
 		"  flee                  — Attempt to flee combat",
-	}
+	)
 	for _, line := range help {
 		c.printLine(line)
 	}
@@ -248,28 +603,151 @@ func (c *CLI) cmdState() {
 	}
 }
 
-func (c *CLI) printTrace(result types.Result) {
-	if len(result.Effects) > 0 {
-		c.printSystem(fmt.Sprintf("[trace] Effects: %d", len(result.Effects)))
-		for _, e := range result.Effects {
-			c.printSystem(fmt.Sprintf("[trace]   %s %v", e.Type, e.Params))
+// cmdTrace toggles trace output, or with a list of subsystems
+// ("rules,effects", or "all") turns it on for those.
+func (c *CLI) cmdTrace(list string) {
+	if list == "" {
+		c.Trace = !c.Trace
+	} else {
+		only, err := trace.ParseSubsystems(list)
+		if err != nil {
+			c.printSystem(err.Error())
+			return
+		}
+		c.Trace, c.TraceOnly = true, only
+	}
+	if c.Trace {
+		c.printSystem("Trace output enabled.")
+	} else {
+		c.printSystem("Trace output disabled.")
+	}
+}
+
+// cmdLua runs code in the Lua console, or without code switches to and
+// from Lua mode, where every line that isn't a meta-command is Lua.
+func (c *CLI) cmdLua(code string) {
+	switch {
+	case c.Console == nil:
+		c.printSystem("The Lua console is for authors: start the game with --dev.")
+	case code != "":
+		c.evalLua(code)
+	case c.luaMode:
+		c.luaMode = false
+		c.printSystem("Back to the game.")
+	default:
+		c.luaMode = true
+		c.printSystem("Lua console: type Lua to inspect or change the game, /lua to return.")
+	}
+}
+
+// evalLua runs code in the Lua console and prints what it gave.
+func (c *CLI) evalLua(code string) {
+	out, err := c.Console.Eval(code)
+	if out != "" {
+		for _, line := range strings.Split(out, "\n") {
+			c.printLine(line)
 		}
 	}
-	if len(result.Events) > 0 {
-		c.printSystem(fmt.Sprintf("[trace] Events: %d", len(result.Events)))
-		for _, e := range result.Events {
-			c.printSystem(fmt.Sprintf("[trace]   %s", e.Type))
+	if err != nil {
+		c.printSystem(fmt.Sprintf("Lua error: %v", err))
+	}
+	c.syncAccess()
+}
+
+// cmdRules lists the rules that can apply here, for query; see
+// Engine.ApplicableRules.
+func (c *CLI) cmdRules(query string) {
+	entries, err := c.Engine.ApplicableRules(query)
+	if err != nil {
+		c.printSystem(err.Error())
+		return
+	}
+	for _, line := range ruleListing(query, entries) {
+		c.printLine(line)
+	}
+}
+
+// ruleListing words a /rules listing.
+func ruleListing(query string, entries []rules.Entry) []string {
+	about := ""
+	if query != "" {
+		about = " for " + query
+	}
+	if len(entries) == 0 {
+		return []string{"No rules" + about + " here."}
+	}
+	lines := []string{"Rules" + about + " here:"}
+	for _, e := range entries {
+		lines = append(lines, "  "+e.String())
+	}
+	return lines
+}
+
+// printTrace prints the trace records of the last command, from the
+// subsystems in TraceOnly.
+func (c *CLI) printTrace() {
+	filter := trace.Filter{Level: trace.Debug, Subsystems: c.TraceOnly}
+	for _, e := range c.lastTrace {
+		if filter.Allows(e) {
+			c.printSystem(e.String())
 		}
 	}
 }
 
+// printResult prints a step's output, then its notifications and epilogue,
+// and numbers the choices it offers. In accessible mode they are put in
+// words by c.Access.
 func (c *CLI) printResult(result types.Result) {
+	defer c.printChoices(result.Choices)
+	if c.Access != nil {
+		for _, line := range c.Access.Step(result) {
+			c.printLine(line)
+		}
+		return
+	}
 	for _, line := range result.Output {
-		c.printLine(line)
+		c.printOutput(line)
+	}
+	for _, note := range result.Notifications {
+		c.printSystem(note)
+	}
+	if result.Epilogue != nil {
+		for _, line := range engine.EpilogueLines(result.Epilogue) {
+			c.printOutput(line)
+		}
+	}
+}
+
+// printChoices lists the options a step offered, numbered, so the player
+// can pick one by typing its number.
+func (c *CLI) printChoices(choices []types.Choice) {
+	c.choices = choices
+	for i, choice := range choices {
+		c.printLine(fmt.Sprintf("  %d. %s", i+1, choice.Label))
+	}
+}
+
+// syncAccess keeps a restored game's health and score from being announced
+// as changes in accessible mode.
+func (c *CLI) syncAccess() {
+	if c.Access != nil {
+		c.Access.Sync()
+	}
+}
+
+// printOutput prints a line of game output as plain text, colored if color
+// is on.
+func (c *CLI) printOutput(line string) {
+	c.lastOutput = append(c.lastOutput, markup.Plain(line))
+	if c.Color != nil {
+		fmt.Fprintln(c.Out, c.colorize(line))
+		return
 	}
+	fmt.Fprintln(c.Out, markup.Plain(line))
 }
 
 func (c *CLI) printLine(text string) {
+	c.lastOutput = append(c.lastOutput, text)
 	fmt.Fprintln(c.Out, text)
 }
 
@@ -278,5 +756,6 @@ func (c *CLI) print(text string) {
 }
 
 func (c *CLI) printSystem(text string) {
-	fmt.Fprintf(c.Out, "[%s]\n", text)
+	c.lastOutput = append(c.lastOutput, text)
+	fmt.Fprintln(c.Out, c.paint("["+text+"]", "system"))
 }
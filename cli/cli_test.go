@@ -1,12 +1,24 @@
 package cli
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/nathoo/questcore/access"
+	"github.com/nathoo/questcore/console"
 	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/save"
 	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/history"
+	"github.com/nathoo/questcore/theme"
 	"github.com/nathoo/questcore/types"
 )
 
@@ -95,6 +107,19 @@ func TestCLI_Navigation(t *testing.T) {
 	}
 }
 
+func TestCLI_ChainedCommands(t *testing.T) {
+	c, out := newTestCLI(t, "take key and go north, then go nowhere, then go south\n/quit\n")
+	c.Run()
+
+	output := out.String()
+	if !strings.Contains(output, "You take the rusty key.") || !strings.Contains(output, "A peaceful garden.") {
+		t.Errorf("expected the key taken and the garden shown, got:\n%s", output)
+	}
+	if c.Engine.State.Player.Location != "garden" {
+		t.Errorf("expected the chain to stop at the failed move, player in %s", c.Engine.State.Player.Location)
+	}
+}
+
 func TestCLI_HelpCommand(t *testing.T) {
 	c, out := newTestCLI(t, "/help\n/quit\n")
 	c.Run()
@@ -154,6 +179,74 @@ func TestCLI_SaveAndLoad(t *testing.T) {
 	}
 }
 
+func TestCLI_LoadWithQuestionPending(t *testing.T) {
+	c, out := newTestCLI(t, "/load riddle\n/quit\n")
+	c.Defs.Answers = map[string]types.AnswerDef{
+		"riddle": {ID: "riddle", Accept: []string{"echo"}, Attempts: 2},
+	}
+	s := state.NewState(c.Defs)
+	s.Pending = "answer:riddle"
+	s.Attempts = map[string]int{"riddle": 1}
+	data, err := save.Save(s, c.Defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(c.SaveDir, "riddle.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c.Run()
+
+	output := out.String()
+	if !strings.Contains(output, "Game loaded from riddle") || !strings.Contains(output, "A grand hall.") {
+		t.Errorf("expected load confirmation and the hall, got:\n%s", output)
+	}
+	if got := c.Engine.State.Attempts["riddle"]; got != 1 || c.Engine.State.Pending != "answer:riddle" {
+		t.Errorf("loading answered the riddle: attempts %d, pending %q\n%s", got, c.Engine.State.Pending, output)
+	}
+}
+
+func TestCLI_Autosave(t *testing.T) {
+	for _, every := range []int{0, 2} {
+		c, out := newTestCLI(t, "wait\nwait\nwait\n/quit\n")
+		c.Autosave = every
+		c.Run()
+
+		_, err := os.Stat(filepath.Join(c.SaveDir, "autosave.json"))
+		if saved := err == nil; saved != (every > 0) {
+			t.Errorf("Autosave %d: autosave written = %v", every, saved)
+		}
+		if strings.Contains(out.String(), "saved") {
+			t.Errorf("Autosave %d: autosaving should be silent:\n%s", every, out.String())
+		}
+	}
+}
+
+func TestCLI_ExportAndImportSave(t *testing.T) {
+	c, out := newTestCLI(t, "go north\n/export-save\n/quit\n")
+	c.Run()
+
+	var code string
+	lines := strings.Split(out.String(), "\n")
+	for i, line := range lines {
+		if strings.Contains(line, "Save code") && i+1 < len(lines) {
+			code = strings.TrimSpace(lines[i+1])
+		}
+	}
+	if code == "" {
+		t.Fatalf("expected a save code, got:\n%s", out.String())
+	}
+
+	c2, out2 := newTestCLI(t, "/import-save "+code+"\n/import-save garbage\n/quit\n")
+	c2.Run()
+	output := out2.String()
+	if !strings.Contains(output, "Save code imported") || !strings.Contains(output, "A peaceful garden.") {
+		t.Errorf("expected import confirmation and garden, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Import failed: invalid save code") {
+		t.Error("expected error for a bad code")
+	}
+}
+
 func TestCLI_UnknownMetaCommand(t *testing.T) {
 	c, out := newTestCLI(t, "/bogus\n/quit\n")
 	c.Run()
@@ -164,6 +257,42 @@ func TestCLI_UnknownMetaCommand(t *testing.T) {
 	}
 }
 
+func TestCLI_GameMetaCommands(t *testing.T) {
+	c, out := newTestCLI(t, "/help\n/spells\n/peek\n/quit\n")
+	c.Defs.MetaCommands = map[string]types.MetaCommandDef{
+		"/spells": {Name: "/spells", Help: "List your spells", Effects: []types.Effect{
+			{Type: "say", Params: map[string]any{"text": "You know no spells."}},
+		}},
+		"/peek": {Name: "/peek", Verb: "look"},
+	}
+	c.Run()
+
+	output := out.String()
+	for _, want := range []string{"/spells — List your spells", "You know no spells."} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "Unknown command") {
+		t.Errorf("game meta-command reported unknown:\n%s", output)
+	}
+	if n := strings.Count(output, "A grand hall."); n != 2 {
+		t.Errorf("room described %d times, want 2 (start and /peek)", n)
+	}
+}
+
+func TestCLI_ChoicesPickedByNumber(t *testing.T) {
+	c, out := newTestCLI(t, "restart\n2\n/quit\n")
+	c.Run()
+
+	output := out.String()
+	for _, want := range []string{"  1. Yes", "  2. No", "Okay, carrying on."} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
 func TestCLI_TraceToggle(t *testing.T) {
 	c, out := newTestCLI(t, "/trace\nlook\n/trace\n/quit\n")
 	c.Run()
@@ -177,6 +306,26 @@ func TestCLI_TraceToggle(t *testing.T) {
 	}
 }
 
+func TestCLI_TraceSubsystems(t *testing.T) {
+	c, out := newTestCLI(t, "/trace\ntake key\n/trace effects\ngo north\n/trace magic\nlook\n/quit\n")
+	c.Run()
+
+	output := out.String()
+	turns := strings.Split(output, "Trace output enabled.")
+	if len(turns) != 3 {
+		t.Fatalf("expected trace enabled twice, got:\n%s", output)
+	}
+	if !strings.Contains(turns[1], `[trace] parse: "take key"`) || !strings.Contains(turns[1], "[trace] rules: handled by the built-in take") {
+		t.Errorf("expected the whole trace of take, got:\n%s", turns[1])
+	}
+	if strings.Contains(turns[2], "[trace] parse:") || !strings.Contains(turns[2], "[trace] effects: move_player") {
+		t.Errorf("expected only effects traced for go, got:\n%s", turns[2])
+	}
+	if !strings.Contains(output, `unknown trace subsystem "magic"`) {
+		t.Error("expected an unknown subsystem error")
+	}
+}
+
 func TestCLI_StateCommand(t *testing.T) {
 	c, out := newTestCLI(t, "/state\n/quit\n")
 	c.Run()
@@ -290,3 +439,472 @@ func TestCLI_EchoInput_WithNavigation(t *testing.T) {
 		t.Error("expected garden description after going north")
 	}
 }
+
+func TestCLI_Exec(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "setup.txt")
+	os.WriteFile(script, []byte("# walk to the garden\ntake key\n\nnorth\n/state\n"), 0o644)
+
+	c, out := newTestCLI(t, "/exec "+script+"\n")
+	c.Run()
+
+	output := out.String()
+	for _, want := range []string{"> take key", "You take the rusty key.", "> north", "peaceful garden", "Location: garden"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "walk to the garden") {
+		t.Error("comment lines should be skipped")
+	}
+}
+
+func TestCLI_Exec_QuitStopsGame(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "quit.txt")
+	os.WriteFile(script, []byte("/quit\nnorth\n"), 0o644)
+
+	c, out := newTestCLI(t, "/exec "+script+"\nnorth\n")
+	c.Run()
+
+	if strings.Contains(out.String(), "peaceful garden") {
+		t.Error("commands after /quit should not run")
+	}
+}
+
+func TestCLI_Exec_MissingFile(t *testing.T) {
+	c, out := newTestCLI(t, "/exec /nonexistent/file.txt\n")
+	c.Run()
+
+	if !strings.Contains(out.String(), "Exec failed") {
+		t.Errorf("expected exec failure message, got:\n%s", out.String())
+	}
+}
+
+func TestCLI_Exec_RecursionLimited(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "loop.txt")
+	os.WriteFile(script, []byte("/exec "+script+"\n"), 0o644)
+
+	c, out := newTestCLI(t, "/exec "+script+"\n")
+	c.Run()
+
+	if !strings.Contains(out.String(), "nested too deeply") {
+		t.Errorf("expected nesting error, got:\n%s", out.String())
+	}
+}
+
+func TestCLI_ScriptExpectations_Pass(t *testing.T) {
+	script := strings.Join([]string{
+		"take key",
+		`#expect "You take the rusty key"`,
+		"#expect-item key",
+		"north",
+		"#expect-location garden",
+		"#expect-flag game_over=false",
+		"#expect-counter coins=0",
+	}, "\n") + "\n"
+	c, out := newTestCLI(t, script)
+	c.Run()
+
+	if len(c.Failures()) != 0 {
+		t.Errorf("unexpected failures: %v", c.Failures())
+	}
+	if !strings.Contains(out.String(), "Expectations: 5 passed, 0 failed") {
+		t.Errorf("expected summary, got:\n%s", out.String())
+	}
+}
+
+func TestCLI_ScriptExpectations_Fail(t *testing.T) {
+	script := strings.Join([]string{
+		"north",
+		`#expect "You take the rusty key"`,
+		"#expect-location hall",
+		"#expect-flag door_open",
+		"#expect-bogus",
+	}, "\n") + "\n"
+	c, out := newTestCLI(t, script)
+	c.Run()
+
+	failures := c.Failures()
+	if len(failures) != 4 {
+		t.Fatalf("expected 4 failures, got %v", failures)
+	}
+	for i, want := range []string{
+		`FAIL after "north": output does not contain "You take the rusty key"`,
+		"location = garden, want hall",
+		"malformed directive",
+		"unknown directive #expect-bogus",
+	} {
+		if !strings.Contains(failures[i], want) {
+			t.Errorf("failure %d = %q, want it to contain %q", i, failures[i], want)
+		}
+	}
+	if !strings.Contains(out.String(), "Expectations: 0 passed, 4 failed") {
+		t.Errorf("expected summary, got:\n%s", out.String())
+	}
+}
+
+func TestCLI_ScriptPlayback(t *testing.T) {
+	script := strings.Join([]string{
+		"#comment Let's look around.",
+		"look",
+		"#delay 1.5s",
+		"take key",
+		`#expect "You take the rusty key"`,
+		"#delay soon",
+		"# a quiet comment",
+	}, "\n") + "\n"
+	c, out := newTestCLI(t, script)
+	c.EchoInput = true
+	c.Delay = 200 * time.Millisecond
+	var paused []time.Duration
+	c.sleep = func(d time.Duration) { paused = append(paused, d) }
+	c.Run()
+
+	want := []time.Duration{200 * time.Millisecond, 1500 * time.Millisecond, 200 * time.Millisecond}
+	if !slices.Equal(paused, want) {
+		t.Errorf("paused %v, want %v", paused, want)
+	}
+	output := out.String()
+	if !strings.Contains(output, "Let's look around.") {
+		t.Errorf("#comment should be printed:\n%s", output)
+	}
+	if strings.Contains(output, "a quiet comment") {
+		t.Errorf("plain comments should stay silent:\n%s", output)
+	}
+	if !strings.Contains(output, "malformed directive") {
+		t.Errorf("a bad #delay should be reported:\n%s", output)
+	}
+	if len(c.Failures()) != 0 {
+		t.Errorf("unexpected failures: %v", c.Failures())
+	}
+}
+
+func TestCLI_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.txt")
+	c, out := newTestCLI(t, "take key\n/record "+path+"\n/stats\nnorth\n/record off\nsouth\n")
+	c.Run()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	script := string(data)
+	if !strings.HasPrefix(script, "# ") || !strings.Contains(script, "--script "+path) {
+		t.Errorf("script should open with replay instructions:\n%s", script)
+	}
+	lines := strings.Split(strings.TrimSpace(script), "\n")
+	var commands []string
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "#") {
+			commands = append(commands, line)
+		}
+	}
+	if want := []string{"look", "take key", "north"}; !slices.Equal(commands, want) {
+		t.Errorf("recorded %q, want %q", commands, want)
+	}
+	if !strings.Contains(out.String(), "Recorded 3 commands") {
+		t.Errorf("/record off should say what was recorded:\n%s", out.String())
+	}
+
+	// The script plays back to the same place.
+	replay, _ := newTestCLI(t, script)
+	replay.EchoInput = true
+	replay.Run()
+	if replay.Engine.State.Player.Location != "garden" {
+		t.Errorf("replay ended in %q, want garden", replay.Engine.State.Player.Location)
+	}
+}
+
+func TestCLI_NoExpectationsNoSummary(t *testing.T) {
+	c, out := newTestCLI(t, "look\n# just a comment\n")
+	c.Run()
+
+	if strings.Contains(out.String(), "Expectations:") {
+		t.Errorf("summary should only appear when expectations are used:\n%s", out.String())
+	}
+}
+
+func TestCLI_NotificationsBracketed(t *testing.T) {
+	c, out := newTestCLI(t, "")
+	c.printResult(types.Result{
+		Output:        []string{"You sing a verse."},
+		Notifications: []string{"Achievement: Bard"},
+	})
+	if got := out.String(); got != "You sing a verse.\n[Achievement: Bard]\n" {
+		t.Errorf("output = %q", got)
+	}
+}
+
+func TestCLI_Color(t *testing.T) {
+	c, out := newTestCLI(t, "")
+	c.Color = theme.Default()
+	c.printResult(types.Result{
+		Output:        []string{"# Hall", "A *rusty key* lies here.", "! You can't.", "~ Exits: north."},
+		Notifications: []string{"Score +5"},
+	})
+	want := "\x1b[1;38;5;255m*** Hall ***\x1b[0m\n" +
+		"A \x1b[1mrusty key\x1b[22m lies here.\n" +
+		"\x1b[38;5;196mYou can't.\x1b[0m\n" +
+		"\x1b[38;5;243mExits: north.\x1b[0m\n" +
+		"\x1b[38;5;243m[Score +5]\x1b[0m\n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q\nwant %q", got, want)
+	}
+	if c.lastOutput[1] != "A rusty key lies here." {
+		t.Errorf("expectations should see plain text, got %q", c.lastOutput[1])
+	}
+}
+
+func TestCLI_Accessible(t *testing.T) {
+	c, out := newTestCLI(t, "north\n/quit\n")
+	c.Access = access.New(c.Engine)
+	c.Run()
+
+	output := out.String()
+	for _, want := range []string{"Welcome to the test.\nA grand hall.\n", "The only exit is north.", "The only exit is south."} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output should contain %q:\n%s", want, output)
+		}
+	}
+	for _, unwanted := range []string{"***", "Exits:", "\n\n"} {
+		if strings.Contains(output, unwanted) {
+			t.Errorf("output should not contain %q:\n%s", unwanted, output)
+		}
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	tests := []struct {
+		mode              string
+		terminal, noColor bool
+		want              bool
+	}{
+		{"auto", true, false, true},
+		{"auto", false, false, false},
+		{"auto", true, true, false},
+		{"always", false, true, true},
+		{"never", true, false, false},
+	}
+	for _, tt := range tests {
+		if got, err := UseColor(tt.mode, tt.terminal, tt.noColor); err != nil || got != tt.want {
+			t.Errorf("UseColor(%q, %v, %v) = %v, %v; want %v", tt.mode, tt.terminal, tt.noColor, got, err, tt.want)
+		}
+	}
+	if _, err := UseColor("sometimes", true, false); err == nil {
+		t.Error("invalid mode accepted")
+	}
+}
+
+func TestColorCode(t *testing.T) {
+	for color, want := range map[string]string{
+		"": "", "208": "38;5;208", "#ff8700": "38;2;255;135;0", "#f80": "38;2;255;136;0",
+	} {
+		if got := colorCode(color); got != want {
+			t.Errorf("colorCode(%q) = %q, want %q", color, got, want)
+		}
+	}
+}
+
+func TestCLI_AchievementsPersistInProfile(t *testing.T) {
+	defs := testDefs()
+	defs.Achievements = map[string]types.AchievementDef{
+		"keeper": {ID: "keeper", Name: "Keeper of Keys"},
+	}
+	defs.GlobalRules = []types.RuleDef{{
+		ID: "take_key", Scope: "global",
+		When:    types.MatchCriteria{Verb: "take", Object: "key"},
+		Effects: []types.Effect{{Type: "unlock_achievement", Params: map[string]any{"achievement": "keeper"}}},
+	}}
+	path := filepath.Join(t.TempDir(), "profile.json")
+
+	var out bytes.Buffer
+	c := &CLI{Engine: engine.New(defs), Defs: defs, In: strings.NewReader("take key\n/quit\n"), Out: &out, ProfilePath: path}
+	c.Run()
+	if !strings.Contains(out.String(), "[Achievement unlocked: Keeper of Keys]") {
+		t.Errorf("expected unlock notification, got:\n%s", out.String())
+	}
+
+	// A new session starts with the achievement already unlocked.
+	out.Reset()
+	c = &CLI{Engine: engine.New(defs), Defs: defs, In: strings.NewReader("achievements\n/quit\n"), Out: &out, ProfilePath: path}
+	c.Run()
+	if !strings.Contains(out.String(), "[x] Keeper of Keys") {
+		t.Errorf("expected unlocked achievement from the profile, got:\n%s", out.String())
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "ring", Scope: "global", When: types.MatchCriteria{Verb: "ring"},
+		Effects: []types.Effect{
+			{Type: "say", Params: map[string]any{"text": "*Dong.*"}},
+			{Type: "play_sound", Params: map[string]any{"sound": "bell"}},
+		},
+	})
+	var out bytes.Buffer
+	if err := RunJSON(engine.New(defs), defs, strings.NewReader("ring\n/save\ntake key and go north\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := json.NewDecoder(&out)
+	var steps []jsonStep
+	for dec.More() {
+		var step jsonStep
+		if err := dec.Decode(&step); err != nil {
+			t.Fatal(err)
+		}
+		steps = append(steps, step)
+	}
+	if len(steps) != 4 {
+		t.Fatalf("expected 4 replies, got %d", len(steps))
+	}
+
+	if first := steps[0]; first.Input != "" || first.Room != "hall" || first.Output[0] != "Welcome to the test." {
+		t.Errorf("first reply = %+v", first)
+	}
+	ring := steps[1]
+	if ring.Output[0] != "Dong." || len(ring.Blocks) != 1 || !ring.Blocks[0].Spans[0].Bold {
+		t.Errorf("ring output = %v, blocks = %+v", ring.Output, ring.Blocks)
+	}
+	if len(ring.Cues) != 1 || ring.Cues[0] != (jsonCue{Type: "sound", Name: "bell"}) {
+		t.Errorf("ring cues = %v", ring.Cues)
+	}
+	if steps[2].Error == "" {
+		t.Error("expected an error for a meta-command")
+	}
+	chained := steps[3]
+	if chained.Room != "garden" || !slices.Contains(chained.Output, "You take the rusty key.") || !slices.Contains(chained.Output, "A peaceful garden.") {
+		t.Errorf("chained reply = %+v", chained)
+	}
+}
+
+func TestLineEditor(t *testing.T) {
+	h := history.New(10)
+	h.Push("look")
+	h.Push("take key")
+	rawCalls := 0
+	newEditor := func(keys string) *lineEditor {
+		return &lineEditor{
+			in:      bufio.NewReader(strings.NewReader(keys)),
+			out:     io.Discard,
+			history: h,
+			raw: func() (func(), error) {
+				rawCalls++
+				return func() { rawCalls-- }, nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name, keys, want string
+	}{
+		{"typing", "go north\r", "go north"},
+		{"backspace", "go nortx\x7fh\r", "go north"},
+		{"insert after moving left", "go nrth\x1b[D\x1b[D\x1b[Do\r", "go north"},
+		{"ctrl-a and ctrl-e", "north\x01go \x05!\r", "go north!"},
+		{"home and delete", "xgo\x1b[H\x1b[3~\r", "go"},
+		{"ctrl-w", "take rusty key\x17lamp\r", "take rusty lamp"},
+		{"ctrl-w after spaces", "take key  \x17\r", "take "},
+		{"ctrl-u", "drop all\x1b[D\x1b[D\x1b[D\x15take\r", "takeall"},
+		{"ctrl-k", "open door now\x01\x06\x06\x06\x06\x06\x06\x06\x06\x06\x0b\r", "open door"},
+		{"up recalls", "\x1b[A\x1b[A\r", "look"},
+		{"up then down", "\x1b[A\x1b[A\x1b[B\r", "take key"},
+		{"down past newest clears", "\x1b[A\x1b[B\r", ""},
+		{"ctrl-p", "\x10 now\r", "take key now"},
+	}
+	for _, tt := range tests {
+		got, err := newEditor(tt.keys).readLine("> ")
+		if err != nil || got != tt.want {
+			t.Errorf("%s: readLine = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+	if rawCalls != 0 {
+		t.Errorf("raw mode left on %d times", rawCalls)
+	}
+
+	if _, err := newEditor("\x04").readLine("> "); err != io.EOF {
+		t.Errorf("ctrl-d on an empty line: err = %v, want EOF", err)
+	}
+	if _, err := newEditor("look\x03").readLine("> "); err != errInterrupted {
+		t.Errorf("ctrl-c: err = %v, want errInterrupted", err)
+	}
+}
+
+func TestCLI_MarkAndRewind(t *testing.T) {
+	c, out := newTestCLI(t, "/rewind start\n/mark start\ntake key\ngo north\n/rewind start\ninventory\n/rewind nowhere\n/quit\n")
+	c.Run()
+
+	output := out.String()
+	if !strings.Contains(output, "No mark called start. Set one with /mark <name>.") {
+		t.Errorf("expected an unknown-mark message before any mark, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Marked start (turn 1).") || !strings.Contains(output, "Rewound to start (turn 1).") {
+		t.Errorf("expected mark and rewind confirmations, got:\n%s", output)
+	}
+	if c.Engine.State.Player.Location != "hall" || state.HasItem(c.Engine.State, "key") {
+		t.Errorf("after rewinding: location %q, inventory %v", c.Engine.State.Player.Location, c.Engine.State.Player.Inventory)
+	}
+	if !strings.Contains(output, "No mark called nowhere. Marks: start.") {
+		t.Errorf("expected the marks listed, got:\n%s", output)
+	}
+}
+
+func TestCLI_WhyAndRules(t *testing.T) {
+	defs := testDefs()
+	defs.Rooms["hall"] = types.RoomDef{ID: "hall", Description: "A grand hall.", Exits: map[string]string{"north": "garden"},
+		Rules: []types.RuleDef{{ID: "take_key", When: types.MatchCriteria{Verb: "take", Object: "key"},
+			Conditions: []types.Condition{{Type: "flag_set", Params: map[string]any{"flag": "allowed"}}}}}}
+	var out bytes.Buffer
+	c := &CLI{Engine: engine.New(defs), Defs: defs, In: strings.NewReader("/why\ntake key\n/why\n/rules take\n/rules\n/quit\n"), Out: &out, SaveDir: t.TempDir()}
+	c.Run()
+
+	output := out.String()
+	for _, want := range []string{
+		"No command to explain yet.",
+		`Parsed: "take key" → verb take, object key`,
+		"Resolved: object key",
+		"✗ rule take_key (room:hall): condition flag_set(flag=allowed) is false",
+		"Handled by the built-in take.",
+		"Rules for take here:\n  take_key (room:hall): take key — condition flag_set(flag=allowed) is false",
+		"Rules here:",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output lacks %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestCLI_Lua(t *testing.T) {
+	defs := testDefs()
+	eng := engine.New(defs)
+	lua := console.New(eng)
+	defer lua.Close()
+	var out bytes.Buffer
+	c := &CLI{Engine: eng, Defs: defs, Console: lua, In: strings.NewReader("/lua location()\n/lua\nteleport('garden')\nget_flag(\nlocation()\n/lua\nlook\n/quit\n"), Out: &out, SaveDir: t.TempDir()}
+	c.Run()
+
+	output := out.String()
+	for _, want := range []string{
+		`"hall"`,
+		"Lua console: type Lua",
+		"[Lua error: ",
+		`"garden"`,
+		"Back to the game.",
+		"A peaceful garden.",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output lacks %q:\n%s", want, output)
+		}
+	}
+}
+
+func TestCLI_LuaNeedsDev(t *testing.T) {
+	c, out := newTestCLI(t, "/lua location()\n/quit\n")
+	c.Run()
+	if !strings.Contains(out.String(), "start the game with --dev") {
+		t.Errorf("output = %s", out.String())
+	}
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/state"
+)
+
+// ColorModes are the values of the --color flag: color when writing to a
+// terminal, always, or never.
+var ColorModes = []string{"auto", "always", "never"}
+
+// UseColor reports whether output is colored under mode, where terminal says
+// whether it goes to a terminal. As is customary, a set NO_COLOR
+// environment variable (noColor) turns "auto" off.
+func UseColor(mode string, terminal, noColor bool) (bool, error) {
+	switch mode {
+	case "auto", "":
+		return terminal && !noColor, nil
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid --color %q (choose %s)", mode, strings.Join(ColorModes, ", "))
+}
+
+// blockColors are the theme colors of the kinds of output block, as in the
+// TUI. Plain paragraphs keep the terminal's own color, except in combat.
+var blockColors = map[markup.Kind]string{
+	markup.Header:   "header",
+	markup.Quote:    "quote",
+	markup.Dialogue: "dialogue",
+	markup.Failure:  "error",
+	markup.Aside:    "aside",
+}
+
+// colorize renders a line of game output as markup.Plain does, colored
+// from c.Color for its kind of block, with bold text in bold.
+func (c *CLI) colorize(line string) string {
+	b := markup.Parse(line)
+	name := blockColors[b.Kind]
+	s := c.Engine.State
+	if b.Kind == markup.Paragraph && (state.InCombat(s) || state.GetFlag(s, "game_over")) {
+		name = "combat"
+	}
+
+	switch b.Kind {
+	case markup.Header:
+		return ansi(markup.Plain(line), c.Color[name], "1")
+	case markup.Quote:
+		return "  " + ansi(spansText(b.Spans), c.Color[name], "3")
+	}
+	return ansi(spansText(b.Spans), c.Color[name])
+}
+
+// paint colors text with the theme color name, if color is on.
+func (c *CLI) paint(text, name string) string {
+	if c.Color == nil {
+		return text
+	}
+	return ansi(text, c.Color[name])
+}
+
+// spansText joins spans, with escapes turning bold on and off around the
+// bold ones.
+func spansText(spans []markup.Span) string {
+	var sb strings.Builder
+	for _, span := range spans {
+		if span.Bold {
+			sb.WriteString("\x1b[1m" + span.Text + "\x1b[22m")
+		} else {
+			sb.WriteString(span.Text)
+		}
+	}
+	return sb.String()
+}
+
+// ansi wraps text in the escapes for a theme color and any other SGR
+// attributes ("1" bold, "3" italic). With neither it returns text as is.
+func ansi(text, color string, attrs ...string) string {
+	if code := colorCode(color); code != "" {
+		attrs = append(attrs, code)
+	}
+	if len(attrs) == 0 || text == "" {
+		return text
+	}
+	return "\x1b[" + strings.Join(attrs, ";") + "m" + text + "\x1b[0m"
+}
+
+// colorCode returns the SGR foreground code for a theme color: "38;5;n"
+// for a 256-color number or "38;2;r;g;b" for a hex value, and "" for none.
+func colorCode(color string) string {
+	hex, ok := strings.CutPrefix(color, "#")
+	if !ok {
+		if color == "" {
+			return ""
+		}
+		return "38;5;" + color
+	}
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return ""
+	}
+	return fmt.Sprintf("38;2;%d;%d;%d", rgb>>16, rgb>>8&0xff, rgb&0xff)
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// jsonStep is the reply to one command in JSON mode.
+type jsonStep struct {
+	Input         string         `json:"input"`
+	Output        []string       `json:"output"` // plain text, one line each
+	Blocks        []markup.Block `json:"blocks"` // the same lines, parsed for styling
+	Notifications []string       `json:"notifications,omitempty"`
+	Cues          []jsonCue      `json:"cues,omitempty"`
+	Choices       []jsonChoice   `json:"choices,omitempty"` // options to offer as a menu
+	Image         string         `json:"image,omitempty"`   // illustration, relative to the game directory
+	Music         string         `json:"music,omitempty"`   // track playing after the step
+	Room          string         `json:"room"`
+	Turn          int            `json:"turn"`
+	GameOver      bool           `json:"game_over,omitempty"`
+	Error         string         `json:"error,omitempty"`
+}
+
+// jsonCue is a sound to play or a music change.
+type jsonCue struct {
+	Type string `json:"type"` // "sound" or "music"
+	Name string `json:"name"` // "" for music means stop
+}
+
+// jsonChoice is an option the step offered; sending its command picks it.
+type jsonChoice struct {
+	Label   string `json:"label"`
+	Command string `json:"command"`
+}
+
+// RunJSON plays the game over a line-based JSON protocol for graphical
+// clients: each line read from in is a command, and each is answered with
+// one JSON object on its own line of out, commands chained in a line
+// included. The first object, with an empty
+// input, carries the intro and the starting room. Of the meta-commands,
+// only the game's own are supported.
+func RunJSON(eng *engine.Engine, defs *state.Defs, in io.Reader, out io.Writer) error {
+	enc := json.NewEncoder(out)
+
+	first := eng.Step("look")
+	if defs.Game.Intro != "" {
+		first.Output = append([]string{defs.Game.Intro, ""}, first.Output...)
+	}
+	if err := enc.Encode(newJSONStep(eng, "", first)); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		input := strings.TrimSpace(scanner.Text())
+		var step jsonStep
+		if strings.HasPrefix(input, "/") {
+			result, command, ok := eng.MetaCommand(input)
+			if command != "" {
+				result = eng.Step(command)
+			}
+			step = newJSONStep(eng, input, result)
+			if !ok {
+				step.Error = "only the game's own meta-commands are supported in JSON mode"
+			}
+		} else {
+			step = newJSONStep(eng, input, joinResults(eng.StepAll(input)))
+		}
+		if err := enc.Encode(step); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// joinResults joins the results of the commands chained in one line into
+// one, their output separated by blank lines as in plain mode. The image
+// shown last stands, and the choices, epilogue and failure are the last
+// command's.
+func joinResults(results []types.Result) types.Result {
+	var joined types.Result
+	for i, r := range results {
+		if i > 0 {
+			joined.Output = append(joined.Output, "")
+		}
+		joined.Effects = append(joined.Effects, r.Effects...)
+		joined.Events = append(joined.Events, r.Events...)
+		joined.Output = append(joined.Output, r.Output...)
+		joined.Notifications = append(joined.Notifications, r.Notifications...)
+		joined.Cues = append(joined.Cues, r.Cues...)
+		if r.Image != "" {
+			joined.Image = r.Image
+		}
+		joined.Failed, joined.Epilogue, joined.Choices = r.Failed, r.Epilogue, r.Choices
+	}
+	return joined
+}
+
+// newJSONStep describes the result of input and the state it left.
+func newJSONStep(eng *engine.Engine, input string, result types.Result) jsonStep {
+	lines := result.Output
+	if result.Epilogue != nil {
+		lines = append(lines, engine.EpilogueLines(result.Epilogue)...)
+	}
+	step := jsonStep{
+		Input:         input,
+		Output:        []string{},
+		Blocks:        []markup.Block{},
+		Notifications: result.Notifications,
+		Image:         result.Image,
+		Music:         eng.State.Music,
+		Room:          eng.State.Player.Location,
+		Turn:          eng.State.TurnCount,
+		GameOver:      state.GetFlag(eng.State, "game_over"),
+	}
+	for _, line := range lines {
+		step.Output = append(step.Output, markup.Plain(line))
+		step.Blocks = append(step.Blocks, markup.Parse(line))
+	}
+	for _, cue := range result.Cues {
+		step.Cues = append(step.Cues, jsonCue{Type: cue.Type, Name: cue.Name})
+	}
+	for _, choice := range result.Choices {
+		step.Choices = append(step.Choices, jsonChoice{Label: choice.Label, Command: choice.Command})
+	}
+	return step
+}
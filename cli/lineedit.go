@@ -0,0 +1,238 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/x/term"
+
+	"github.com/nathoo/questcore/history"
+)
+
+// errInterrupted is returned by readLine when the player presses Ctrl-C.
+var errInterrupted = errors.New("interrupted")
+
+// lineEditor reads lines typed at a terminal with the usual editing keys:
+//
+//	Left/Right, Ctrl-B/F    move the cursor
+//	Home/End, Ctrl-A/E      go to the start or end of the line
+//	Up/Down, Ctrl-P/N       recall earlier and later commands
+//	Backspace, Delete       delete a character
+//	Ctrl-W                  delete the word before the cursor
+//	Ctrl-U, Ctrl-K          delete to the start or end of the line
+//	Ctrl-D                  delete a character; on an empty line, end input
+//	Ctrl-C                  quit
+type lineEditor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	history *history.History
+	raw     func() (restore func(), err error) // puts the terminal in raw mode
+
+	line   []rune
+	cursor int
+}
+
+// lineReader returns what Run reads input with: a line editor, whose
+// commands are kept in the history, when playing at a terminal, or else a
+// plain scanner.
+func (c *CLI) lineReader() func(prompt string) (string, error) {
+	if f, ok := c.In.(*os.File); ok && !c.EchoInput {
+		h := history.New(history.DefaultMax)
+		if c.HistoryPath != "" {
+			if loaded, err := history.Load(c.HistoryPath, history.DefaultMax); err == nil {
+				h = loaded
+			}
+		}
+		if ed, ok := newTerminalEditor(f, c.Out, h); ok {
+			return func(prompt string) (string, error) {
+				line, err := ed.readLine(prompt)
+				if cmd := strings.TrimSpace(line); cmd != "" {
+					h.Push(cmd)
+					if c.HistoryPath != "" {
+						// History is a convenience: failing to keep it doesn't stop play.
+						h.Save(c.HistoryPath)
+					}
+				}
+				return line, err
+			}
+		}
+	}
+
+	scanner := bufio.NewScanner(c.In)
+	return func(prompt string) (string, error) {
+		c.print(prompt)
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		return scanner.Text(), nil
+	}
+}
+
+// newTerminalEditor returns a line editor for f if it is a terminal.
+func newTerminalEditor(f *os.File, out io.Writer, h *history.History) (*lineEditor, bool) {
+	fd := f.Fd()
+	if !term.IsTerminal(fd) {
+		return nil, false
+	}
+	raw := func() (func(), error) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return nil, err
+		}
+		return func() { term.Restore(fd, state) }, nil
+	}
+	return &lineEditor{in: bufio.NewReader(f), out: out, history: h, raw: raw}, true
+}
+
+// readLine shows prompt and reads a line. It returns io.EOF at the end of
+// input and errInterrupted on Ctrl-C.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	restore, err := e.raw()
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	e.line, e.cursor = nil, 0
+	e.history.ResetCursor()
+	io.WriteString(e.out, prompt)
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			io.WriteString(e.out, "\r\n")
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			io.WriteString(e.out, "\r\n")
+			return string(e.line), nil
+		case 3: // Ctrl-C
+			io.WriteString(e.out, "^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if len(e.line) == 0 {
+				io.WriteString(e.out, "\r\n")
+				return "", io.EOF
+			}
+			e.deleteRange(e.cursor, e.cursor+1)
+		case 1: // Ctrl-A
+			e.cursor = 0
+		case 5: // Ctrl-E
+			e.cursor = len(e.line)
+		case 2: // Ctrl-B
+			e.move(-1)
+		case 6: // Ctrl-F
+			e.move(1)
+		case 16: // Ctrl-P
+			e.recall(e.history.Prev)
+		case 14: // Ctrl-N
+			e.recall(e.history.Next)
+		case 127, 8: // Backspace
+			e.deleteRange(e.cursor-1, e.cursor)
+		case 23: // Ctrl-W
+			e.deleteRange(e.wordStart(), e.cursor)
+		case 21: // Ctrl-U
+			e.deleteRange(0, e.cursor)
+		case 11: // Ctrl-K
+			e.deleteRange(e.cursor, len(e.line))
+		case 27: // Escape: an arrow or other special key
+			e.escape()
+		default:
+			if unicode.IsPrint(r) {
+				e.line = append(e.line[:e.cursor], append([]rune{r}, e.line[e.cursor:]...)...)
+				e.cursor++
+			}
+		}
+		e.redraw(prompt)
+	}
+}
+
+// escape handles the rest of an escape sequence: "[A" for up, "[3~" for
+// delete, "OH" for home, and so on. Unknown sequences are ignored.
+func (e *lineEditor) escape() {
+	kind, _, err := e.in.ReadRune()
+	if err != nil || (kind != '[' && kind != 'O') {
+		return
+	}
+	var seq strings.Builder
+	for {
+		r, _, err := e.in.ReadRune()
+		if err != nil {
+			return
+		}
+		seq.WriteRune(r)
+		if (r < '0' || r > '9') && r != ';' {
+			break
+		}
+	}
+	switch seq.String() {
+	case "A":
+		e.recall(e.history.Prev)
+	case "B":
+		e.recall(e.history.Next)
+	case "C":
+		e.move(1)
+	case "D":
+		e.move(-1)
+	case "H", "1~", "7~":
+		e.cursor = 0
+	case "F", "4~", "8~":
+		e.cursor = len(e.line)
+	case "3~":
+		e.deleteRange(e.cursor, e.cursor+1)
+	}
+}
+
+// recall replaces the line with an entry from the history; past the newest
+// entry the line is cleared.
+func (e *lineEditor) recall(step func() (string, bool)) {
+	cmd, _ := step()
+	e.line = []rune(cmd)
+	e.cursor = len(e.line)
+}
+
+func (e *lineEditor) move(n int) {
+	e.cursor = max(0, min(len(e.line), e.cursor+n))
+}
+
+// deleteRange deletes the runes from i to j, clamped to the line, and leaves
+// the cursor where they were.
+func (e *lineEditor) deleteRange(i, j int) {
+	i, j = max(i, 0), min(j, len(e.line))
+	if i >= j {
+		return
+	}
+	e.line = append(e.line[:i], e.line[j:]...)
+	e.cursor = i
+}
+
+// wordStart returns where the word before the cursor starts, skipping any
+// spaces just before it.
+func (e *lineEditor) wordStart() int {
+	i := e.cursor
+	for i > 0 && e.line[i-1] == ' ' {
+		i--
+	}
+	for i > 0 && e.line[i-1] != ' ' {
+		i--
+	}
+	return i
+}
+
+// redraw rewrites the prompt and line, and puts the cursor in place.
+func (e *lineEditor) redraw(prompt string) {
+	var sb strings.Builder
+	sb.WriteString("\r" + prompt + string(e.line) + "\x1b[K")
+	if back := len(e.line) - e.cursor; back > 0 {
+		sb.WriteString("\x1b[" + strconv.Itoa(back) + "D")
+	}
+	io.WriteString(e.out, sb.String())
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/state"
+)
+
+// Script assertion directives. In a command script they check the state
+// left by the preceding command:
+//
+//	#expect "You take the key"     output of the last command contains text
+//	#expect-flag has_key=true      flag has the given value
+//	#expect-counter coins=3        counter has the given value
+//	#expect-location garden        player is in the room
+//	#expect-item key               player carries the item
+//
+// Other lines starting with '#' are comments.
+const directivePrefix = "#expect"
+
+// isDirective reports whether a script line is an assertion directive.
+func isDirective(line string) bool {
+	return strings.HasPrefix(line, directivePrefix)
+}
+
+// handleDirective evaluates an assertion directive and records the result.
+func (c *CLI) handleDirective(line string) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	var failure string
+	switch name {
+	case "#expect":
+		text, err := strconv.Unquote(arg)
+		if err != nil {
+			text = arg
+		}
+		if !strings.Contains(strings.Join(c.lastOutput, "\n"), text) {
+			failure = fmt.Sprintf("output does not contain %q", text)
+		}
+
+	case "#expect-flag":
+		flag, value, ok := strings.Cut(arg, "=")
+		want, err := strconv.ParseBool(value)
+		if !ok || err != nil {
+			failure = "malformed directive, want #expect-flag <name>=<true|false>"
+			break
+		}
+		if got := state.GetFlag(c.Engine.State, flag); got != want {
+			failure = fmt.Sprintf("flag %s = %v, want %v", flag, got, want)
+		}
+
+	case "#expect-counter":
+		counter, value, ok := strings.Cut(arg, "=")
+		want, err := strconv.Atoi(value)
+		if !ok || err != nil {
+			failure = "malformed directive, want #expect-counter <name>=<int>"
+			break
+		}
+		if got := state.GetCounter(c.Engine.State, counter); got != want {
+			failure = fmt.Sprintf("counter %s = %d, want %d", counter, got, want)
+		}
+
+	case "#expect-location":
+		if got := state.PlayerLocation(c.Engine.State); got != arg {
+			failure = fmt.Sprintf("location = %s, want %s", got, arg)
+		}
+
+	case "#expect-item":
+		if !state.HasItem(c.Engine.State, arg) {
+			failure = fmt.Sprintf("player does not carry %s", arg)
+		}
+
+	default:
+		failure = fmt.Sprintf("unknown directive %s", name)
+	}
+
+	c.expectations++
+	if failure != "" {
+		msg := fmt.Sprintf("FAIL after %q: %s", c.lastInput, failure)
+		c.failures = append(c.failures, msg)
+		c.printSystem(msg)
+	}
+}
+
+// Script playback directives, for demo recordings:
+//
+//	#delay 1.5s                    pause before going on
+//	#comment Now for the riddle.   print the text, as an aside
+const (
+	delayDirective   = "#delay"
+	commentDirective = "#comment"
+)
+
+// isPlayback reports whether a script line is a playback directive.
+func isPlayback(line string) bool {
+	name, _, _ := strings.Cut(line, " ")
+	return name == delayDirective || name == commentDirective
+}
+
+// handlePlayback runs a playback directive.
+func (c *CLI) handlePlayback(line string) {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case delayDirective:
+		d, err := time.ParseDuration(arg)
+		if err != nil || d < 0 {
+			c.printSystem(fmt.Sprintf("malformed directive %q, want #delay <duration>, such as 500ms", line))
+			return
+		}
+		c.pause(d)
+	case commentDirective:
+		// The comment isn't output of the last command, for #expect.
+		last := c.lastOutput
+		c.printOutput(markup.Note(markup.Escape(arg)))
+		c.lastOutput = last
+	}
+}
+
+// pause waits d, to pace script playback.
+func (c *CLI) pause(d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	if c.sleep != nil {
+		c.sleep(d)
+		return
+	}
+	time.Sleep(d)
+}
+
+// Failures returns the messages of script expectations that did not hold.
+func (c *CLI) Failures() []string {
+	return c.failures
+}
+
+// printExpectationSummary reports how many expectations passed, if a script
+// used any.
+func (c *CLI) printExpectationSummary() {
+	if c.expectations == 0 {
+		return
+	}
+	passed := c.expectations - len(c.failures)
+	c.printSystem(fmt.Sprintf("Expectations: %d passed, %d failed", passed, len(c.failures)))
+	for _, f := range c.failures {
+		c.printSystem(f)
+	}
+}
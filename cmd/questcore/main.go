@@ -1,14 +1,51 @@
 // QuestCore is a deterministic, data-driven game engine for text adventures.
-// Usage: questcore [--version] [--plain] [--script <file>] [--trace] <game_directory>
+// Usage: questcore [--version] [--plain] [--json] [--script <file>] [--script-delay <duration>] [--trace] [--trace-only <subsystems>] [--trace-file <file>] [--strict] [--seed <n>] [--log <file>] [--theme <name>] [--color=auto|always|never] [--accessible] [--dev] <game_directory>
+//
+//	questcore map [--mermaid] <game_directory>
+//	questcore check [--json] [--deep] <game_directory>
+//	questcore schema [<game_directory>]
+//	questcore test [-update] [-exact] [--seed <n>] <game_directory>
+//	questcore pace [--seed <n>] [--window <n>] <game_directory> <log>...
+//	questcore simulate [--runs <n>] [--seed <n>] [--stat <name>=<n>]... <game_directory> <enemy>...
+//	questcore bundle [--platforms linux,windows,mac] [--runtimes <dir>] [--out <dir>] <game_directory>
+//	questcore pack [--out <file>] <game_directory>
+//	questcore bench [--turns <n>] [--seed <n>] <game_directory> [<log>]
+//
+// A bundled executable plays its embedded game when no game directory is
+// given. A .qcgame archive made by pack is played in place of a game
+// directory.
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/nathoo/questcore/access"
+	"github.com/nathoo/questcore/bundle"
 	"github.com/nathoo/questcore/cli"
+	"github.com/nathoo/questcore/config"
+	"github.com/nathoo/questcore/console"
 	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/save"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/engine/trace"
+	"github.com/nathoo/questcore/graph"
+	"github.com/nathoo/questcore/history"
 	"github.com/nathoo/questcore/loader"
+	"github.com/nathoo/questcore/pacing"
+	"github.com/nathoo/questcore/profile"
+	"github.com/nathoo/questcore/schema"
+	"github.com/nathoo/questcore/simulate"
+	"github.com/nathoo/questcore/testkit"
+	"github.com/nathoo/questcore/theme"
 	"github.com/nathoo/questcore/tui"
 )
 
@@ -20,12 +57,53 @@ var (
 )
 
 func main() {
+	save.EngineVersion = version
 	plain := false
-	trace := false
+	jsonMode := false
+	tracing := false
+	strict := false
+	accessible := false
+	dev := false
 	var gameDir string
 	var scriptFile string
+	var scriptDelay time.Duration
+	var logFile string
+	var traceFile string
+	var traceOnly []trace.Subsystem
+	var themeName string
+	var colorMode string
+	var seed int64
+	seedSet := false
 
 	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "map" {
+		os.Exit(runMap(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "check" {
+		os.Exit(runCheck(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "schema" {
+		os.Exit(runSchema(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "test" {
+		os.Exit(runTest(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "pace" {
+		os.Exit(runPace(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "simulate" {
+		os.Exit(runSimulate(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "bundle" {
+		os.Exit(runBundle(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "pack" {
+		os.Exit(runPack(args[1:]))
+	}
+	if len(args) > 0 && args[0] == "bench" {
+		os.Exit(runBench(args[1:]))
+	}
+
 	for i := 0; i < len(args); i++ {
 		switch args[i] {
 		case "--version":
@@ -33,8 +111,16 @@ func main() {
 			return
 		case "--plain":
 			plain = true
+		case "--json":
+			jsonMode = true
 		case "--trace":
-			trace = true
+			tracing = true
+		case "--dev":
+			dev = true
+		case "--strict":
+			strict = true
+		case "--accessible":
+			accessible = true
 		case "--script":
 			if i+1 >= len(args) {
 				fmt.Fprintf(os.Stderr, "--script requires a file path\n")
@@ -42,28 +128,143 @@ func main() {
 			}
 			i++
 			scriptFile = args[i]
+		case "--script-delay":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "--script-delay requires a duration, such as 500ms\n")
+				os.Exit(1)
+			}
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d < 0 {
+				fmt.Fprintf(os.Stderr, "invalid duration %q\n", args[i])
+				os.Exit(1)
+			}
+			scriptDelay = d
+		case "--trace-file":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "--trace-file requires a file path (- for stderr)\n")
+				os.Exit(1)
+			}
+			i++
+			traceFile = args[i]
+		case "--trace-only":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "--trace-only requires a list of subsystems\n")
+				os.Exit(1)
+			}
+			i++
+			only, err := trace.ParseSubsystems(args[i])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			traceOnly = only
+		case "--log":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "--log requires a file path\n")
+				os.Exit(1)
+			}
+			i++
+			logFile = args[i]
+		case "--theme":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "--theme requires a name (%s)\n", strings.Join(theme.Presets, ", "))
+				os.Exit(1)
+			}
+			i++
+			themeName = args[i]
+		case "--seed":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "--seed requires a number\n")
+				os.Exit(1)
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid seed %q\n", args[i])
+				os.Exit(1)
+			}
+			seed, seedSet = n, true
 		default:
-			if gameDir == "" {
+			if mode, ok := strings.CutPrefix(args[i], "--color="); ok {
+				if _, err := cli.UseColor(mode, false, false); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				colorMode = mode
+			} else if gameDir == "" {
 				gameDir = args[i]
 			}
 		}
 	}
 
-	if gameDir == "" {
-		fmt.Fprintf(os.Stderr, "Usage: questcore [--version] [--plain] [--script <file>] [--trace] <game_directory>\n")
+	// The player's settings fill in what the flags leave out.
+	cfg, err := config.Load(config.DefaultPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading settings: %v\n", err)
 		os.Exit(1)
 	}
+	if themeName == "" {
+		themeName = cfg.Theme
+	}
+	if colorMode == "" {
+		colorMode = cfg.Color
+	}
+	if colorMode == "" {
+		colorMode = "auto"
+	}
 
 	// Load and compile Lua game content.
-	defs, err := loader.Load(gameDir)
+	defs, err := loadGame(gameDir)
+	if err == errNoGame {
+		fmt.Fprintf(os.Stderr, "Usage: questcore [--version] [--plain] [--json] [--script <file>] [--script-delay <duration>] [--trace] [--trace-only <subsystems>] [--trace-file <file>] [--strict] [--seed <n>] [--log <file>] [--theme <name>] [--color=auto|always|never] [--accessible] [--dev] <game_directory>\n")
+		os.Exit(1)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading game: %v\n", err)
 		os.Exit(1)
 	}
 
-	eng := engine.New(defs)
+	var opts []engine.Option
+	if seedSet {
+		opts = append(opts, engine.WithSeed(seed))
+	}
+	eng := engine.New(defs, opts...)
+	eng.Strict = strict
+	// Command log: every command is appended to the file as it is typed.
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening log: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		eng.LogWriter = f
+	}
+	// Trace file: a record of every decision, as JSON lines (or text on
+	// stderr for "-").
+	if traceFile != "" {
+		var sink trace.Sink = trace.Stderr()
+		if traceFile != "-" {
+			f, err := trace.OpenFile(traceFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error opening trace file: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			sink = f
+		}
+		eng.Trace = trace.Filter{Sink: sink, Level: trace.Debug, Subsystems: traceOnly}
+	}
+	// Dev mode: the /lua console, for authors to inspect and change the game.
+	var lua *console.Console
+	if dev {
+		lua = console.New(eng)
+		defer lua.Close()
+	}
 
-	// Script mode: open file, force plain, echo commands.
+	// Script mode: open file, force plain, echo commands. Exits non-zero if
+	// any #expect directive in the script fails.
 	if scriptFile != "" {
 		f, err := os.Open(scriptFile)
 		if err != nil {
@@ -72,11 +273,27 @@ func main() {
 		}
 		fmt.Printf("%s v%s by %s\n\n", defs.Game.Title, defs.Game.Version, defs.Game.Author)
 		c := cli.New(eng, defs)
+		c.SaveDir = cfg.Saves(defs.Game.Title, defs.Game.Version)
 		c.In = f
 		c.EchoInput = true
-		c.Trace = trace
+		c.Delay = scriptDelay
+		c.Trace, c.TraceOnly = tracing, traceOnly
+		c.Console = lua
+		setOutput(c, accessible, colorMode, themeName)
 		c.Run()
 		f.Close()
+		if len(c.Failures()) > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// JSON mode: one JSON object per command, for graphical clients.
+	if jsonMode {
+		if err := cli.RunJSON(eng, defs, os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 		return
 	}
 
@@ -84,15 +301,479 @@ func main() {
 	if plain || !isTerminal() {
 		fmt.Printf("%s v%s by %s\n\n", defs.Game.Title, defs.Game.Version, defs.Game.Author)
 		c := cli.New(eng, defs)
-		c.Trace = trace
+		c.SaveDir = cfg.Saves(defs.Game.Title, defs.Game.Version)
+		c.Autosave = cfg.Autosave
+		c.ProfilePath = profile.DefaultPath(defs.Game.Title)
+		c.HistoryPath = history.DefaultPath()
+		c.Trace, c.TraceOnly = tracing, traceOnly
+		c.Console = lua
+		setOutput(c, accessible, colorMode, themeName)
 		c.Run()
 		return
 	}
 
-	if err := tui.Run(eng, defs); err != nil {
+	if err := tui.Run(eng, defs, tui.Options{
+		Theme:      themeName,
+		Accessible: accessible,
+		Console:    lua,
+		SaveDir:    cfg.Saves(defs.Game.Title, defs.Game.Version),
+		Autosave:   cfg.Autosave,
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// setOutput sets how c writes output: put in words for screen readers with
+// --accessible, or else in the colors of the --color mode.
+func setOutput(c *cli.CLI, accessible bool, colorMode, themeName string) {
+	if accessible {
+		c.Access = access.New(c.Engine)
+		return
+	}
+	c.Color = plainColors(colorMode, themeName, c.Defs)
+}
+
+// plainColors returns the colors of plain-text play under the --color mode,
+// or nil for none. It exits if the theme can't be loaded.
+func plainColors(mode, themeName string, defs *state.Defs) theme.Theme {
+	on, _ := cli.UseColor(mode, isTerminal(), os.Getenv("NO_COLOR") != "")
+	if !on {
+		return nil
+	}
+	colors, err := theme.Select(themeName, defs.Game.Theme)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	return colors
+}
+
+// errNoGame is returned by loadGame when no game directory was given and the
+// executable has no bundled game.
+var errNoGame = errors.New("no game")
+
+// loadGame loads the game in dir, which may be a .qcgame archive, or the
+// game bundled into this executable if dir is empty.
+func loadGame(dir string) (*state.Defs, error) {
+	if strings.HasSuffix(dir, bundle.Ext) {
+		fsys, _, err := bundle.OpenArchive(dir)
+		if err != nil {
+			return nil, err
+		}
+		return loader.LoadFS(fsys)
+	}
+	if dir != "" {
+		return loader.Load(dir)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, errNoGame
+	}
+	fsys, ok, err := bundle.Embedded(exe)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errNoGame
+	}
+	return loader.LoadFS(fsys)
+}
+
+// runMap loads a game and prints its room graph as DOT (default) or Mermaid.
+// Unreachable rooms are also reported on stderr. Returns the exit code.
+func runMap(args []string) int {
+	mermaid := false
+	var gameDir string
+	for _, a := range args {
+		switch a {
+		case "--mermaid":
+			mermaid = true
+		case "--dot":
+			mermaid = false
+		default:
+			if gameDir == "" {
+				gameDir = a
+			}
+		}
+	}
+	if gameDir == "" {
+		fmt.Fprintf(os.Stderr, "Usage: questcore map [--dot|--mermaid] <game_directory>\n")
+		return 1
+	}
+
+	defs, err := loader.Load(gameDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading game: %v\n", err)
+		return 1
+	}
+
+	g := graph.Build(defs)
+	if mermaid {
+		fmt.Print(g.Mermaid())
+	} else {
+		fmt.Print(g.DOT())
+	}
+	for _, id := range g.Unreachable {
+		fmt.Fprintf(os.Stderr, "warning: room %q is unreachable from %q\n", id, g.Start)
+	}
+	return 0
+}
+
+// runCheck validates and lints a game without running it, printing one
+// diagnostic per line ("file:line: severity: message") or, with --json, a
+// single JSON report for editor integration. Returns 1 if any errors were
+// found.
+func runCheck(args []string) int {
+	asJSON := false
+	deep := false
+	var gameDir string
+	for _, a := range args {
+		switch a {
+		case "--json":
+			asJSON = true
+		case "--deep":
+			deep = true
+		default:
+			if gameDir == "" {
+				gameDir = a
+			}
+		}
+	}
+	if gameDir == "" {
+		fmt.Fprintf(os.Stderr, "Usage: questcore check [--json] [--deep] <game_directory>\n")
+		return 1
+	}
+
+	check := loader.Check
+	if deep {
+		check = loader.CheckDeep
+	}
+	report, err := check(gameDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if asJSON {
+		if report.Diagnostics == nil {
+			report.Diagnostics = []loader.Diagnostic{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		for _, d := range report.Diagnostics {
+			pos := gameDir
+			if d.File != "" {
+				pos = d.File
+				if d.Line > 0 {
+					pos = fmt.Sprintf("%s:%d", d.File, d.Line)
+				}
+			}
+			fmt.Printf("%s: %s: %s\n", pos, d.Severity, d.Message)
+			if d.Excerpt != "" {
+				fmt.Printf("    %s\n", d.Excerpt)
+			}
+		}
+		fmt.Printf("%d error(s), %d warning(s)\n", report.Errors, report.Warnings)
+	}
+
+	if report.Errors > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runSchema prints a game's compiled definitions as canonical JSON together
+// with the JSON Schema that describes them, or just the schema if no game
+// directory is given. Returns the exit code.
+func runSchema(args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "Usage: questcore schema [<game_directory>]\n")
+		return 1
+	}
+
+	var defs *state.Defs
+	if len(args) == 1 {
+		var err error
+		defs, err = loader.Load(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading game: %v\n", err)
+			return 1
+		}
+	}
+
+	data, err := schema.New(defs).Marshal()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	os.Stdout.Write(data)
+	return 0
+}
+
+// runTest runs a game's tests/*.script files against their .golden
+// transcripts, or rewrites the transcripts with -update. Returns 1 if any
+// test failed.
+func runTest(args []string) int {
+	update := false
+	var opts testkit.Options
+	var gameDir string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-update", "--update":
+			update = true
+		case "-exact", "--exact":
+			opts.Exact = true
+		case "-seed", "--seed":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "%s requires a number\n", args[i])
+				return 1
+			}
+			seed, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid seed %q\n", args[i+1])
+				return 1
+			}
+			opts.Seed = seed
+			i++
+		default:
+			if gameDir == "" {
+				gameDir = args[i]
+			}
+		}
+	}
+	if gameDir == "" {
+		fmt.Fprintf(os.Stderr, "Usage: questcore test [-update] [-exact] [--seed <n>] <game_directory>\n")
+		return 1
+	}
+
+	results, err := testkit.RunDir(gameDir, update, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Printf("FAIL %s: %v\n", r.Script, r.Err)
+		case r.Updated:
+			fmt.Printf("UPDATED %s\n", r.Golden)
+		case r.Passed:
+			fmt.Printf("PASS %s\n", r.Script)
+		default:
+			failed++
+			fmt.Printf("FAIL %s: transcript differs from %s at %s\n", r.Script, r.Golden, r.Diff)
+		}
+	}
+	fmt.Printf("%d passed, %d failed\n", len(results)-failed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runPace replays playtest logs (one command per line, as in test scripts)
+// and prints each session's pacing. Returns 1 if any session ended without
+// reaching an ending.
+func runPace(args []string) int {
+	var opts pacing.Options
+	var gameDir string
+	var logs []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-seed", "--seed", "-window", "--window":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "%s requires a number\n", args[i])
+				return 1
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid number %q\n", args[i+1])
+				return 1
+			}
+			if strings.HasSuffix(args[i], "seed") {
+				opts.Seed = n
+			} else {
+				opts.Window = int(n)
+			}
+			i++
+		default:
+			if gameDir == "" {
+				gameDir = args[i]
+			} else {
+				logs = append(logs, args[i])
+			}
+		}
+	}
+	if gameDir == "" || len(logs) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: questcore pace [--seed <n>] [--window <n>] <game_directory> <log>...\n")
+		return 1
+	}
+
+	defs, err := loader.Load(gameDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading game: %v\n", err)
+		return 1
+	}
+
+	reports, err := pacing.AnalyzeFiles(defs, logs, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	unfinished := 0
+	for _, r := range reports {
+		fmt.Print(r.String())
+		if !r.Ended {
+			unfinished++
+		}
+	}
+	fmt.Printf("%d session(s), %d without an ending\n", len(reports), unfinished)
+	if unfinished > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runSimulate fights automated combats against enemies and reports how the
+// player fares against each.
+func runSimulate(args []string) int {
+	opts := simulate.Options{Stats: map[string]int{}}
+	var gameDir string
+	var enemies []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-runs", "--runs", "-seed", "--seed":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "%s requires a number\n", args[i])
+				return 1
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid number %q\n", args[i+1])
+				return 1
+			}
+			if strings.HasSuffix(args[i], "seed") {
+				opts.Seed = n
+			} else {
+				opts.Runs = int(n)
+			}
+			i++
+		case "-stat", "--stat":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "%s requires <name>=<n>\n", args[i])
+				return 1
+			}
+			name, value, _ := strings.Cut(args[i+1], "=")
+			n, err := strconv.Atoi(value)
+			if err != nil || name == "" {
+				fmt.Fprintf(os.Stderr, "invalid stat %q (want <name>=<n>)\n", args[i+1])
+				return 1
+			}
+			opts.Stats[name] = n
+			i++
+		default:
+			if gameDir == "" {
+				gameDir = args[i]
+			} else {
+				enemies = append(enemies, args[i])
+			}
+		}
+	}
+	if gameDir == "" || len(enemies) == 0 {
+		fmt.Fprintf(os.Stderr, "Usage: questcore simulate [--runs <n>] [--seed <n>] [--stat <name>=<n>]... <game_directory> <enemy>...\n")
+		return 1
+	}
+
+	defs, err := loader.Load(gameDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading game: %v\n", err)
+		return 1
+	}
+
+	for _, enemy := range enemies {
+		r, err := simulate.Run(defs, enemy, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Print(r.String())
+	}
+	return 0
+}
+
+// runBundle packages a game as self-contained executables, one zip archive
+// per platform. Returns the exit code.
+func runBundle(args []string) int {
+	platforms := "linux,windows,mac"
+	outDir := "dist"
+	var runtimeDir, gameDir string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--platforms", "--runtimes", "--out":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "%s requires a value\n", args[i])
+				return 1
+			}
+			switch args[i] {
+			case "--platforms":
+				platforms = args[i+1]
+			case "--runtimes":
+				runtimeDir = args[i+1]
+			case "--out":
+				outDir = args[i+1]
+			}
+			i++
+		default:
+			if gameDir == "" {
+				gameDir = args[i]
+			}
+		}
+	}
+	if gameDir == "" {
+		fmt.Fprintf(os.Stderr, "Usage: questcore bundle [--platforms linux,windows,mac] [--runtimes <dir>] [--out <dir>] <game_directory>\n")
+		return 1
+	}
+
+	targets, err := bundle.ParsePlatforms(platforms)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Refuse to ship a game that doesn't load.
+	defs, err := loader.Load(gameDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading game: %v\n", err)
+		return 1
+	}
+
+	name := strings.ToLower(filepath.Base(filepath.Clean(gameDir)))
+	archives, err := bundle.Build(bundle.Options{
+		GameDir:    gameDir,
+		Name:       name,
+		Version:    defs.Game.Version,
+		Platforms:  targets,
+		RuntimeDir: runtimeDir,
+		OutDir:     outDir,
+	})
+	for _, a := range archives {
+		fmt.Println(a)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
 }
 
 // isTerminal returns true if stdout is a terminal (not piped/redirected).
@@ -103,3 +784,175 @@ func isTerminal() bool {
 	}
 	return fi.Mode()&os.ModeCharDevice != 0
 }
+
+// runPack writes a game as a single-file .qcgame archive, named
+// <name>[-<version>].qcgame after its directory unless --out is given.
+// Returns the exit code.
+func runPack(args []string) int {
+	var out, gameDir string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--out":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "--out requires a file path\n")
+				return 1
+			}
+			i++
+			out = args[i]
+		default:
+			if gameDir == "" {
+				gameDir = args[i]
+			}
+		}
+	}
+	if gameDir == "" {
+		fmt.Fprintf(os.Stderr, "Usage: questcore pack [--out <file>] <game_directory>\n")
+		return 1
+	}
+
+	// Refuse to pack a game that doesn't load.
+	defs, err := loader.Load(gameDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading game: %v\n", err)
+		return 1
+	}
+	if out == "" {
+		out = strings.ToLower(filepath.Base(filepath.Clean(gameDir)))
+		if defs.Game.Version != "" {
+			out += "-" + defs.Game.Version
+		}
+		out += bundle.Ext
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	m, err := bundle.WriteArchive(f, gameDir, defs.Game.Title, defs.Game.Version)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%s (%s)\n", out, m.Hash)
+	return 0
+}
+
+// runBench measures how long the engine takes per turn: it loads the game
+// and plays the commands in a log, or else wanders for --turns turns,
+// looking, examining and moving, starting over whenever the game ends. It
+// is for engine developers and isn't in the usage. Returns the exit code.
+func runBench(args []string) int {
+	turns, seed := 1000, int64(0)
+	var gameDir, logFile string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--turns", "--seed":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "%s requires a number\n", args[i])
+				return 1
+			}
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil || n < 0 {
+				fmt.Fprintf(os.Stderr, "invalid number %q\n", args[i+1])
+				return 1
+			}
+			if args[i] == "--turns" {
+				turns = int(n)
+			} else {
+				seed = n
+			}
+			i++
+		default:
+			if gameDir == "" {
+				gameDir = args[i]
+			} else if logFile == "" {
+				logFile = args[i]
+			}
+		}
+	}
+	if gameDir == "" {
+		fmt.Fprintf(os.Stderr, "Usage: questcore bench [--turns <n>] [--seed <n>] <game_directory> [<log>]\n")
+		return 1
+	}
+
+	start := time.Now()
+	defs, err := loader.Load(gameDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading game: %v\n", err)
+		return 1
+	}
+	loaded := time.Since(start)
+
+	var commands []string
+	if logFile != "" {
+		data, err := os.ReadFile(logFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				commands = append(commands, line)
+			}
+		}
+		turns = len(commands)
+	}
+
+	eng := engine.New(defs, engine.WithSeed(seed))
+	times := make([]time.Duration, 0, turns)
+	for i := 0; i < turns; i++ {
+		if state.GetFlag(eng.State, "game_over") {
+			eng = engine.New(defs, engine.WithSeed(seed+int64(i)))
+		}
+		command := wanderCommand(eng, i)
+		if commands != nil {
+			command = commands[i]
+		}
+		t := time.Now()
+		eng.Step(command)
+		times = append(times, time.Since(t))
+	}
+	if len(times) == 0 {
+		fmt.Fprintf(os.Stderr, "no commands to run\n")
+		return 1
+	}
+
+	var total time.Duration
+	for _, t := range times {
+		total += t
+	}
+	slices.Sort(times)
+	pct := func(p int) time.Duration { return times[(len(times)-1)*p/100] }
+	fmt.Printf("load      %v\n", loaded)
+	fmt.Printf("turns     %d in %v\n", len(times), total)
+	fmt.Printf("mean      %v\n", total/time.Duration(len(times)))
+	fmt.Printf("p50       %v\n", pct(50))
+	fmt.Printf("p95       %v\n", pct(95))
+	fmt.Printf("p99       %v\n", pct(99))
+	fmt.Printf("max       %v\n", times[len(times)-1])
+	return 0
+}
+
+// wanderCommand returns the command for turn i of a bench without a log:
+// by turns, look around, examine something here, or take an exit, each
+// choice stepping through what the room offers.
+func wanderCommand(eng *engine.Engine, i int) string {
+	room := eng.State.Player.Location
+	switch i % 4 {
+	case 1:
+		if here := state.EntitiesInRoom(eng.State, eng.Defs, room); len(here) > 0 {
+			return "examine " + here[i/4%len(here)]
+		}
+	case 3:
+		exits := eng.Defs.Rooms[room].Exits
+		if dirs := slices.Sorted(maps.Keys(exits)); len(dirs) > 0 {
+			return "go " + dirs[i/4%len(dirs)]
+		}
+	}
+	return "look"
+}
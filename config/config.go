@@ -0,0 +1,180 @@
+// Package config reads the player's settings file (config.toml) and says
+// where QuestCore keeps its per-user files: settings in the config
+// directory, saves, profiles and history in the data directory. Both are
+// ~/.questcore unless XDG_CONFIG_HOME or XDG_DATA_HOME is set.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config is the player's defaults, each overridden by its command-line
+// flag.
+type Config struct {
+	SaveDir  string // where saves are kept, a subdirectory per game ("" = <data dir>/saves)
+	Theme    string // theme preset, as --theme
+	Color    string // auto, always or never, as --color
+	Autosave int    // turns between saves to the "autosave" slot (0 = off)
+}
+
+// Dir returns where the player's settings files are kept:
+// $XDG_CONFIG_HOME/questcore, or ~/.questcore.
+func Dir() string {
+	return xdgDir("XDG_CONFIG_HOME")
+}
+
+// DataDir returns where saves, profiles and history are kept:
+// $XDG_DATA_HOME/questcore, or ~/.questcore.
+func DataDir() string {
+	return xdgDir("XDG_DATA_HOME")
+}
+
+// xdgDir returns the questcore directory under the base directory named by
+// env, or ~/.questcore if env isn't set to an absolute path.
+func xdgDir(env string) string {
+	if base := os.Getenv(env); filepath.IsAbs(base) {
+		return filepath.Join(base, "questcore")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".questcore")
+}
+
+// DefaultPath returns where the player's settings file is kept:
+// config.toml in Dir.
+func DefaultPath() string {
+	return filepath.Join(Dir(), "config.toml")
+}
+
+// Saves returns the directory the saves of a game are kept in:
+// <save dir>/<title>[-<version>], named as FileName names it, so that games,
+// and versions of a game, don't see each other's saves.
+func (c Config) Saves(title, version string) string {
+	dir := c.SaveDir
+	if dir == "" {
+		dir = filepath.Join(DataDir(), "saves")
+	}
+	return filepath.Join(dir, FileName(strings.TrimSpace(title+" "+version)))
+}
+
+// FileName turns a game title into a safe file name: lowercased, with
+// anything but letters and digits replaced by dashes.
+func FileName(game string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(game) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "-")
+	if name == "" {
+		return "game"
+	}
+	return name
+}
+
+// Load reads the settings file at path. A missing file is the zero Config.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	c, err := Parse(string(data))
+	if err != nil {
+		return Config{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse reads a settings file. Like the theme file, it is TOML limited to
+// top-level keys, one per line:
+//
+//	save_dir = "~/Games/saves"
+//	theme = "light"
+//	color = "never"
+//	autosave = 10
+//
+// A save_dir starting with "~/" is under the home directory.
+func Parse(data string) (Config, error) {
+	var c Config
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		if err := c.set(strings.TrimSpace(key), strings.TrimSpace(value)); err != nil {
+			return Config{}, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return c, nil
+}
+
+// set sets the setting key to the TOML value v.
+func (c *Config) set(key, v string) error {
+	if key == "autosave" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("autosave must be a number of turns, not %s", v)
+		}
+		c.Autosave = n
+		return nil
+	}
+
+	if len(v) < 2 || (v[0] != '"' && v[0] != '\'') || v[len(v)-1] != v[0] {
+		return fmt.Errorf("%s must be a quoted string, not %s", key, v)
+	}
+	s := v[1 : len(v)-1]
+	switch key {
+	case "save_dir":
+		if rest, ok := strings.CutPrefix(s, "~/"); ok {
+			home, _ := os.UserHomeDir()
+			s = filepath.Join(home, rest)
+		}
+		c.SaveDir = s
+	case "theme":
+		c.Theme = s
+	case "color":
+		if s != "auto" && s != "always" && s != "never" {
+			return fmt.Errorf("color must be auto, always or never, not %q", s)
+		}
+		c.Color = s
+	default:
+		return fmt.Errorf("unknown setting %q (want save_dir, theme, color or autosave)", key)
+	}
+	return nil
+}
+
+// stripComment removes a "#" comment that is not inside quotes.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		title, want string
+	}{
+		{"The Lost Crown", "the-lost-crown"},
+		{"  Zork: Part II!", "zork-part-ii"},
+		{"???", "game"},
+		{"", "game"},
+	}
+	for _, tt := range tests {
+		if got := FileName(tt.title); got != tt.want {
+			t.Errorf("FileName(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	c, err := Parse(`
+# My settings.
+save_dir = "~/Games/saves"
+theme = 'light'   # easier on the eyes
+color = "never"
+autosave = 10
+`)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := Config{SaveDir: filepath.Join(home, "Games", "saves"), Theme: "light", Color: "never", Autosave: 10}
+	if c != want {
+		t.Errorf("Parse = %+v, want %+v", c, want)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []string{
+		`theme`,
+		`theme = light`,
+		`color = "sometimes"`,
+		`autosave = "often"`,
+		`autosave = -1`,
+		`volume = 11`,
+	}
+	for _, data := range tests {
+		if _, err := Parse(data); err == nil {
+			t.Errorf("Parse(%q) should fail", data)
+		}
+	}
+}
+
+func TestLoad_MissingFileIsZero(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "none.toml"))
+	if err != nil || c != (Config{}) {
+		t.Errorf("Load = %+v, %v; want zero Config", c, err)
+	}
+}
+
+func TestDirs_XDG(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/xdg/config")
+	t.Setenv("XDG_DATA_HOME", "/xdg/data")
+	if got, want := DefaultPath(), filepath.Join("/xdg/config", "questcore", "config.toml"); got != want {
+		t.Errorf("DefaultPath = %q, want %q", got, want)
+	}
+	if got, want := DataDir(), filepath.Join("/xdg/data", "questcore"); got != want {
+		t.Errorf("DataDir = %q, want %q", got, want)
+	}
+
+	// Relative paths are ignored, as the XDG spec says.
+	t.Setenv("XDG_DATA_HOME", "data")
+	home, _ := os.UserHomeDir()
+	if got, want := DataDir(), filepath.Join(home, ".questcore"); got != want {
+		t.Errorf("DataDir = %q, want %q", got, want)
+	}
+}
+
+func TestSaves_PerGameAndVersion(t *testing.T) {
+	c := Config{SaveDir: "/saves"}
+	tests := []struct {
+		title, version, want string
+	}{
+		{"The Lost Crown", "0.1.0", "/saves/the-lost-crown-0-1-0"},
+		{"The Lost Crown", "0.2.0", "/saves/the-lost-crown-0-2-0"},
+		{"Zork", "", "/saves/zork"},
+	}
+	for _, tt := range tests {
+		if got := c.Saves(tt.title, tt.version); got != filepath.FromSlash(tt.want) {
+			t.Errorf("Saves(%q, %q) = %q, want %q", tt.title, tt.version, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// Package console is a Lua prompt for game authors, opened with /lua when
+// the game is started with --dev. Authors inspect the running game and
+// change it — set a flag, move an item, jump to a room — to reach the
+// situation they want to test without playing up to it.
+//
+// The console has its own sandboxed VM, separate from the one that loads
+// the game, and reaches the game only through the functions below:
+//
+//	get_flag(name)            get_counter(name)       get_prop(entity, prop)
+//	get_stat(target, stat)    location()              where(entity)
+//	inventory()               entities([room])        turn()
+//	set_flag(name[, value])   set_counter(name, n)    set_prop(entity, prop, value)
+//	set_stat(target, stat, n) teleport(room)          move(entity, room)
+//	give(item)                remove(item)
+//
+// Changes are made as the effects of the same names would make them, but
+// without running the game's event handlers.
+package console
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/loader"
+	"github.com/nathoo/questcore/types"
+)
+
+// EvalTimeout is how long a chunk may run before it is stopped, so that a
+// stray infinite loop doesn't hang the game.
+const EvalTimeout = 2 * time.Second
+
+// Console evaluates Lua against a running game.
+type Console struct {
+	eng *engine.Engine
+	L   *lua.LState
+	out strings.Builder // what the chunk being evaluated printed
+}
+
+// New returns a console for eng. Close it when done.
+func New(eng *engine.Engine) *Console {
+	c := &Console{eng: eng, L: loader.NewSandbox()}
+	for name, fn := range map[string]lua.LGFunction{
+		"print":       c.print,
+		"get_flag":    c.getFlag,
+		"get_counter": c.getCounter,
+		"get_prop":    c.getProp,
+		"get_stat":    c.getStat,
+		"location":    c.location,
+		"where":       c.where,
+		"inventory":   c.inventory,
+		"entities":    c.entities,
+		"turn":        c.turn,
+		"set_flag":    c.setFlag,
+		"set_counter": c.setCounter,
+		"set_prop":    c.setProp,
+		"set_stat":    c.setStat,
+		"teleport":    c.teleport,
+		"move":        c.move,
+		"give":        c.give,
+		"remove":      c.remove,
+	} {
+		c.L.SetGlobal(name, c.L.NewFunction(fn))
+	}
+	return c
+}
+
+// Close releases the console's VM.
+func (c *Console) Close() {
+	c.L.Close()
+}
+
+// Eval runs code, an expression or statements, and returns what it
+// printed followed by the values of the expression, one line each.
+// Globals set by one chunk are kept for the next.
+func (c *Console) Eval(code string) (string, error) {
+	c.out.Reset()
+	fn, err := c.L.LoadString("return " + code)
+	if err != nil {
+		if fn, err = c.L.LoadString(code); err != nil {
+			return "", err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), EvalTimeout)
+	defer cancel()
+	c.L.SetContext(ctx)
+	defer c.L.RemoveContext()
+
+	top := c.L.GetTop()
+	c.L.Push(fn)
+	if err := c.L.PCall(0, lua.MultRet, nil); err != nil {
+		c.L.SetTop(top)
+		return strings.TrimSuffix(c.out.String(), "\n"), err
+	}
+	var values []string
+	for i := top + 1; i <= c.L.GetTop(); i++ {
+		values = append(values, describe(c.L.Get(i)))
+	}
+	c.L.SetTop(top)
+	if len(values) > 0 {
+		c.out.WriteString(strings.Join(values, "\t") + "\n")
+	}
+	return strings.TrimSuffix(c.out.String(), "\n"), nil
+}
+
+// describe renders a Lua value for the prompt.
+func describe(v lua.LValue) string {
+	switch v := v.(type) {
+	case lua.LString:
+		return fmt.Sprintf("%q", string(v))
+	case *lua.LTable:
+		return fmt.Sprint(loader.GoValue(v))
+	}
+	return v.String()
+}
+
+// toLua converts a game value (a prop, say) to a Lua value.
+func toLua(L *lua.LState, v any) lua.LValue {
+	switch v := v.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case int:
+		return lua.LNumber(v)
+	case float64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case []string:
+		t := L.NewTable()
+		for _, s := range v {
+			t.Append(lua.LString(s))
+		}
+		return t
+	case []any:
+		t := L.NewTable()
+		for _, x := range v {
+			t.Append(toLua(L, x))
+		}
+		return t
+	case map[string]any:
+		t := L.NewTable()
+		for k, x := range v {
+			t.RawSetString(k, toLua(L, x))
+		}
+		return t
+	}
+	return lua.LString(fmt.Sprint(v))
+}
+
+func (c *Console) print(L *lua.LState) int {
+	parts := make([]string, L.GetTop())
+	for i := range parts {
+		parts[i] = L.ToStringMeta(L.Get(i + 1)).String()
+	}
+	c.out.WriteString(strings.Join(parts, "\t") + "\n")
+	return 0
+}
+
+// entity returns argument n, checked to be an entity ID.
+func (c *Console) entity(L *lua.LState, n int) string {
+	id := L.CheckString(n)
+	if _, ok := c.eng.Defs.Entities[id]; !ok {
+		L.ArgError(n, fmt.Sprintf("unknown entity %q", id))
+	}
+	return id
+}
+
+// room returns argument n, checked to be a room ID.
+func (c *Console) room(L *lua.LState, n int) string {
+	id := L.CheckString(n)
+	if _, ok := c.eng.Defs.Rooms[id]; !ok {
+		L.ArgError(n, fmt.Sprintf("unknown room %q", id))
+	}
+	return id
+}
+
+// target returns argument n, checked to be "player" or an entity ID.
+func (c *Console) target(L *lua.LState, n int) string {
+	if L.CheckString(n) == "player" {
+		return "player"
+	}
+	return c.entity(L, n)
+}
+
+// apply makes a change to the game as the effect typ would.
+func (c *Console) apply(typ string, params map[string]any) {
+	effects.Apply(c.eng.State, c.eng.Defs, []types.Effect{{Type: typ, Params: params}}, effects.Context{Actor: "player"})
+}
+
+func (c *Console) getFlag(L *lua.LState) int {
+	L.Push(lua.LBool(state.GetFlag(c.eng.State, L.CheckString(1))))
+	return 1
+}
+
+func (c *Console) getCounter(L *lua.LState) int {
+	L.Push(lua.LNumber(state.GetCounter(c.eng.State, L.CheckString(1))))
+	return 1
+}
+
+func (c *Console) getProp(L *lua.LState) int {
+	v, _ := state.GetEntityProp(c.eng.State, c.eng.Defs, c.entity(L, 1), L.CheckString(2))
+	L.Push(toLua(L, v))
+	return 1
+}
+
+func (c *Console) getStat(L *lua.LState) int {
+	v, ok := state.GetStat(c.eng.State, c.eng.Defs, c.target(L, 1), L.CheckString(2))
+	if !ok {
+		L.Push(lua.LNil)
+		return 1
+	}
+	L.Push(lua.LNumber(v))
+	return 1
+}
+
+func (c *Console) location(L *lua.LState) int {
+	L.Push(lua.LString(c.eng.State.Player.Location))
+	return 1
+}
+
+func (c *Console) where(L *lua.LState) int {
+	L.Push(lua.LString(state.EntityLocation(c.eng.State, c.eng.Defs, c.entity(L, 1))))
+	return 1
+}
+
+func (c *Console) inventory(L *lua.LState) int {
+	L.Push(toLua(L, c.eng.State.Player.Inventory))
+	return 1
+}
+
+func (c *Console) entities(L *lua.LState) int {
+	room := c.eng.State.Player.Location
+	if L.GetTop() > 0 {
+		room = c.room(L, 1)
+	}
+	L.Push(toLua(L, state.EntitiesInRoom(c.eng.State, c.eng.Defs, room)))
+	return 1
+}
+
+func (c *Console) turn(L *lua.LState) int {
+	L.Push(lua.LNumber(c.eng.State.TurnCount))
+	return 1
+}
+
+func (c *Console) setFlag(L *lua.LState) int {
+	c.apply("set_flag", map[string]any{"flag": L.CheckString(1), "value": L.OptBool(2, true)})
+	return 0
+}
+
+func (c *Console) setCounter(L *lua.LState) int {
+	c.apply("set_counter", map[string]any{"counter": L.CheckString(1), "value": L.CheckInt(2)})
+	return 0
+}
+
+func (c *Console) setProp(L *lua.LState) int {
+	c.apply("set_prop", map[string]any{"entity": c.entity(L, 1), "prop": L.CheckString(2), "value": loader.GoValue(L.CheckAny(3))})
+	return 0
+}
+
+func (c *Console) setStat(L *lua.LState) int {
+	c.apply("set_stat", map[string]any{"target": c.target(L, 1), "stat": L.CheckString(2), "value": L.CheckInt(3)})
+	return 0
+}
+
+func (c *Console) teleport(L *lua.LState) int {
+	room := c.room(L, 1)
+	c.apply("move_player", map[string]any{"room": room})
+	c.apply("mark_visited", map[string]any{"room": room})
+	return 0
+}
+
+func (c *Console) move(L *lua.LState) int {
+	c.apply("move_entity", map[string]any{"entity": c.entity(L, 1), "room": c.room(L, 2)})
+	return 0
+}
+
+func (c *Console) give(L *lua.LState) int {
+	c.apply("give_item", map[string]any{"item": c.entity(L, 1)})
+	return 0
+}
+
+func (c *Console) remove(L *lua.LState) int {
+	c.apply("remove_item", map[string]any{"item": c.entity(L, 1)})
+	return 0
+}
@@ -0,0 +1,126 @@
+package console
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+func testDefs() *state.Defs {
+	return &state.Defs{
+		Game: types.GameDef{Title: "Test Game", Start: "hall"},
+		Rooms: map[string]types.RoomDef{
+			"hall":   {ID: "hall", Description: "A grand hall.", Exits: map[string]string{"north": "garden"}},
+			"garden": {ID: "garden", Description: "A peaceful garden.", Exits: map[string]string{"south": "hall"}},
+		},
+		Entities: map[string]types.EntityDef{
+			"key": {ID: "key", Kind: "item", Props: map[string]any{
+				"name": "rusty key", "location": "hall", "takeable": true,
+			}},
+		},
+	}
+}
+
+func newConsole(t *testing.T) (*Console, *engine.Engine) {
+	t.Helper()
+	eng := engine.New(testDefs())
+	c := New(eng)
+	t.Cleanup(c.Close)
+	return c, eng
+}
+
+func eval(t *testing.T, c *Console, code string) string {
+	t.Helper()
+	out, err := c.Eval(code)
+	if err != nil {
+		t.Fatalf("Eval(%q): %v", code, err)
+	}
+	return out
+}
+
+func TestEval_ExpressionsAndStatements(t *testing.T) {
+	c, _ := newConsole(t)
+	for code, want := range map[string]string{
+		"1 + 2":                   "3",
+		`"a" .. "b"`:              `"ab"`,
+		"location()":              `"hall"`,
+		"get_prop('key', 'name')": `"rusty key"`,
+		"where('key')":            `"hall"`,
+		"get_flag('door_open')":   "false",
+		"x = 5":                   "",
+		"print('hi', 2)":          "hi\t2",
+	} {
+		if got := eval(t, c, code); got != want {
+			t.Errorf("Eval(%q) = %q, want %q", code, got, want)
+		}
+	}
+	// Globals outlive the chunk that set them.
+	if got := eval(t, c, "x * 2"); got != "10" {
+		t.Errorf("x * 2 = %q, want 10", got)
+	}
+}
+
+func TestEval_Mutations(t *testing.T) {
+	c, eng := newConsole(t)
+	eval(t, c, "set_flag('door_open')")
+	eval(t, c, "set_counter('gold', 7)")
+	eval(t, c, "set_prop('key', 'name', 'shiny key')")
+	eval(t, c, "give('key')")
+	eval(t, c, "teleport('garden')")
+
+	s := eng.State
+	if !state.GetFlag(s, "door_open") || state.GetCounter(s, "gold") != 7 {
+		t.Errorf("flags = %v, counters = %v", s.Flags, s.Counters)
+	}
+	if name, _ := state.GetEntityProp(s, eng.Defs, "key", "name"); name != "shiny key" {
+		t.Errorf("key name = %v, want shiny key", name)
+	}
+	if !state.HasItem(s, "key") {
+		t.Errorf("inventory = %v, want the key", s.Player.Inventory)
+	}
+	if s.Player.Location != "garden" || !s.Visited["garden"] {
+		t.Errorf("location = %q, visited = %v", s.Player.Location, s.Visited)
+	}
+	if got := eval(t, c, "inventory()[1]"); got != `"key"` {
+		t.Errorf("inventory()[1] = %q", got)
+	}
+}
+
+func TestEval_Errors(t *testing.T) {
+	c, eng := newConsole(t)
+	if _, err := c.Eval("teleport('moon')"); err == nil || !strings.Contains(err.Error(), `unknown room "moon"`) {
+		t.Errorf("teleport to an unknown room: err = %v", err)
+	}
+	if eng.State.Player.Location != "hall" {
+		t.Errorf("location = %q after a failed teleport", eng.State.Player.Location)
+	}
+	if _, err := c.Eval("set_prop('ghost', 'name', 'x')"); err == nil || !strings.Contains(err.Error(), `unknown entity "ghost"`) {
+		t.Errorf("set_prop on an unknown entity: err = %v", err)
+	}
+	if _, err := c.Eval("if then"); err == nil {
+		t.Error("a syntax error gave no error")
+	}
+	// The sandbox is the loader's: no os or io.
+	if _, err := c.Eval("os.exit(1)"); err == nil {
+		t.Error("os is reachable from the console")
+	}
+}
+
+func TestEval_Timeout(t *testing.T) {
+	c, _ := newConsole(t)
+	start := time.Now()
+	if _, err := c.Eval("while true do end"); err == nil {
+		t.Fatal("an endless loop gave no error")
+	}
+	if d := time.Since(start); d > EvalTimeout+time.Second {
+		t.Errorf("the loop ran %v", d)
+	}
+	// The console still works afterwards.
+	if got := eval(t, c, "1"); got != "1" {
+		t.Errorf("after the timeout, 1 = %q", got)
+	}
+}
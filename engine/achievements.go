@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"sort"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// builtinAchievements lists the game's achievements in declaration order,
+// unlocked or not. Hidden achievements are only counted until unlocked.
+func (e *Engine) builtinAchievements() ([]types.Effect, []string) {
+	if len(e.Defs.Achievements) == 0 {
+		return nil, []string{e.msg("achievements_none")}
+	}
+
+	defs := make([]types.AchievementDef, 0, len(e.Defs.Achievements))
+	for _, def := range e.Defs.Achievements {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Order < defs[j].Order })
+
+	unlocked, hidden := 0, 0
+	var entries []string
+	for _, def := range defs {
+		mark := "[ ]"
+		switch {
+		case e.State.Achievements[def.ID]:
+			mark = "[x]"
+			unlocked++
+		case def.Hidden:
+			hidden++
+			continue
+		}
+		entry := mark + " " + def.Name
+		if def.Description != "" {
+			entry += " — " + def.Description
+		}
+		entries = append(entries, entry)
+	}
+
+	lines := []string{e.msg("achievements", "unlocked", unlocked, "total", len(defs))}
+	lines = append(lines, entries...)
+	if hidden == 1 {
+		lines = append(lines, e.msg("hidden_one"))
+	} else if hidden > 1 {
+		lines = append(lines, e.msg("hidden_many", "count", hidden))
+	}
+	return nil, lines
+}
+
+// achievementName returns an achievement's display name, or its ID if the
+// game does not define it.
+func (e *Engine) achievementName(id string) string {
+	if def, ok := e.Defs.Achievements[id]; ok {
+		return def.Name
+	}
+	return id
+}
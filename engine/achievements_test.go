@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func achievementEngine() *Engine {
+	defs := testDefs()
+	defs.Achievements = map[string]types.AchievementDef{
+		"explorer": {ID: "explorer", Name: "Explorer", Description: "Visit the garden.", Order: 0},
+		"reader":   {ID: "reader", Name: "Bookworm", Description: "Read the book.", Order: 1},
+		"secret":   {ID: "secret", Name: "Shh", Hidden: true, Order: 2},
+	}
+	defs.Handlers = append(defs.Handlers, types.EventHandler{
+		EventType: "room_entered",
+		Effects:   []types.Effect{{Type: "unlock_achievement", Params: map[string]any{"achievement": "explorer"}}},
+	})
+	return New(defs)
+}
+
+func TestAchievements_UnlockNotifiesOnce(t *testing.T) {
+	e := achievementEngine()
+
+	result := e.Step("north")
+	if len(result.Notifications) != 1 || result.Notifications[0] != "Achievement unlocked: Explorer" {
+		t.Errorf("Notifications = %v", result.Notifications)
+	}
+	e.Step("south")
+	result = e.Step("north")
+	if len(result.Notifications) != 0 {
+		t.Errorf("expected no second unlock, got %v", result.Notifications)
+	}
+}
+
+func TestAchievements_List(t *testing.T) {
+	e := achievementEngine()
+	e.Step("north")
+
+	result := e.Step("achievements")
+	want := []string{
+		"Achievements: 1 of 3 unlocked.",
+		"[x] Explorer — Visit the garden.",
+		"[ ] Bookworm — Read the book.",
+		"Plus 1 hidden achievement.",
+	}
+	if strings.Join(result.Output, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(result.Output, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAchievements_SurviveRestart(t *testing.T) {
+	e := achievementEngine()
+	e.Step("north")
+	e.Step("restart")
+	e.Step("yes")
+
+	if !e.State.Achievements["explorer"] {
+		t.Error("expected achievements to survive a restart")
+	}
+	if result := e.Step("north"); len(result.Notifications) != 0 {
+		t.Errorf("expected no unlock after restart, got %v", result.Notifications)
+	}
+}
+
+func TestAchievements_NoneDefined(t *testing.T) {
+	e := New(testDefs())
+	if !outputContains(e.Step("achievements").Output, "no achievements") {
+		t.Error("expected a message for a game without achievements")
+	}
+}
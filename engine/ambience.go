@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/engine/state"
+)
+
+// ambience rolls the player's room's ambience once, at the end of a turn
+// spent there, and returns the line to show, or "" for none. Nothing is
+// rolled in combat, while a prompt awaits an answer, or in the turns
+// Game.ambience_cooldown keeps quiet after a line was shown.
+func (e *Engine) ambience() string {
+	if e.State.AmbienceWait > 0 {
+		e.State.AmbienceWait--
+		return ""
+	}
+	if state.InCombat(e.State) || e.State.Pending != "" || state.GetFlag(e.State, "game_over") {
+		return ""
+	}
+	room := state.PlayerLocation(e.State)
+	lines := e.Defs.Rooms[room].Ambience
+	if len(lines) == 0 {
+		return ""
+	}
+	roll := e.RNG.RollFor("ambience: "+room, 100)
+	upTo := 0
+	for _, line := range lines {
+		if !rules.EvalAllConditions(line.Conditions, e.State, e.Defs) {
+			continue
+		}
+		upTo += line.Chance
+		if roll <= upTo {
+			e.State.AmbienceWait = e.Defs.Game.AmbienceCooldown
+			return line.Text
+		}
+	}
+	return ""
+}
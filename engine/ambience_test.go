@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func ambienceEngine(cooldown int) *Engine {
+	defs := testDefs()
+	hall := defs.Rooms["hall"]
+	hall.Ambience = []types.AmbienceDef{
+		{Chance: 100, Text: "A bat flits overhead.", Conditions: []types.Condition{
+			{Type: "flag_set", Params: map[string]any{"flag": "bats"}},
+		}},
+		{Chance: 100, Text: "A draught stirs the tapestries."},
+	}
+	defs.Rooms["hall"] = hall
+	defs.Game.AmbienceCooldown = cooldown
+	return New(defs)
+}
+
+func TestStep_AmbienceRolledEachTurn(t *testing.T) {
+	e := ambienceEngine(0)
+	for range 2 {
+		result := e.Step("look")
+		if !outputContains(result.Output, "A draught stirs the tapestries.") {
+			t.Fatalf("expected ambience, got %v", result.Output)
+		}
+	}
+
+	// A line whose conditions hold is rolled ahead of later ones.
+	e.State.Flags["bats"] = true
+	result := e.Step("look")
+	if !outputContains(result.Output, "A bat flits overhead.") || outputContains(result.Output, "draught") {
+		t.Errorf("expected only the bat line, got %v", result.Output)
+	}
+
+	// Other rooms have no ambience.
+	result = e.Step("go north")
+	if outputContains(result.Output, "bat") || outputContains(result.Output, "draught") {
+		t.Errorf("expected no ambience in the garden, got %v", result.Output)
+	}
+}
+
+func TestStep_AmbienceCooldown(t *testing.T) {
+	e := ambienceEngine(2)
+	var shown []bool
+	for range 4 {
+		result := e.Step("look")
+		shown = append(shown, outputContains(result.Output, "draught"))
+	}
+	want := []bool{true, false, false, true}
+	for i := range want {
+		if shown[i] != want[i] {
+			t.Fatalf("ambience shown on turns %v, want %v", shown, want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// pendingAnswerPrefix marks a pending free-text question; the answer ID
+// follows the prefix.
+const pendingAnswerPrefix = "answer:"
+
+// answerQuestion checks input against the pending question id. A right
+// answer runs the success effects; a wrong one counts as an attempt, and
+// once the attempts are used up the failure effects run. Until then the
+// question stays pending.
+func (e *Engine) answerQuestion(id, input string) types.Result {
+	var result types.Result
+	ctx := effects.Context{Verb: "answer", Actor: "player", Strict: e.Strict, Custom: e.config.Effects, Roll: e.RNG.RollFor}
+	closing := types.Effect{Type: "close_question", Params: map[string]any{"answer": id}}
+	def, ok := e.Defs.Answers[id]
+	if !ok {
+		// Question no longer defined (e.g. from an older save) — drop it.
+		effects.Apply(e.State, e.Defs, []types.Effect{closing}, ctx)
+		return e.step(input)
+	}
+	e.logCommand(input)
+	e.passTurn()
+
+	var effs []types.Effect
+	if matchesAnswer(input, def.Accept) {
+		effs = def.Success
+	} else {
+		attempt := types.Effect{Type: "use_attempt", Params: map[string]any{"answer": id}}
+		effects.Apply(e.State, e.Defs, []types.Effect{attempt}, ctx)
+		result.Effects = append(result.Effects, attempt)
+		if e.State.Attempts[id] < def.Attempts {
+			retry := def.Retry
+			if retry == "" {
+				retry = e.msg("wrong_answer")
+			}
+			result.Output = append(result.Output, retry)
+			return result
+		}
+		effs = def.Failure
+	}
+	effs = append([]types.Effect{closing}, effs...)
+
+	evts, output := effects.Apply(e.State, e.Defs, effs, ctx)
+	result.Effects = append(result.Effects, effs...)
+	result.Events = append(result.Events, evts...)
+	result.Output = append(result.Output, output...)
+
+	e.dispatch(evts, ctx, &result)
+
+	// Track RNG position for save/load, and report the draws, as step does.
+	e.State.RNGPosition = e.RNG.Position()
+	result.Events = append(result.Events, drawEvents(e.RNG.TakeDraws())...)
+
+	if state.GetFlag(e.State, "game_over") && e.State.Pending == "" {
+		e.offerEndingMenu(&result)
+	}
+	return result
+}
+
+// matchesAnswer reports whether input matches one of the accepted answers.
+func matchesAnswer(input string, accept []string) bool {
+	got := normalizeAnswer(input)
+	for _, a := range accept {
+		if normalizeAnswer(a) == got {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAnswer lowercases s, drops punctuation and the articles "a",
+// "an" and "the", and collapses whitespace, so "The Time!" matches "time".
+func normalizeAnswer(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+	kept := words[:0]
+	for _, w := range words {
+		switch w {
+		case "a", "an", "the":
+			continue
+		}
+		kept = append(kept, w)
+	}
+	return strings.Join(kept, " ")
+}
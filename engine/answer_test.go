@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// riddleEngine returns an engine whose "talk statue" command poses a riddle
+// with two attempts.
+func riddleEngine() *Engine {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID:    "statue_riddle",
+		Scope: "global",
+		When:  types.MatchCriteria{Verb: "talk", Object: "statue"},
+		Effects: []types.Effect{
+			{Type: "say", Params: map[string]any{"text": "What flies without wings?"}},
+			{Type: "ask", Params: map[string]any{"answer": "riddle_statue"}},
+		},
+	})
+	defs.Answers = map[string]types.AnswerDef{
+		"riddle_statue": {
+			ID:       "riddle_statue",
+			Accept:   []string{"time", "the time"},
+			Attempts: 2,
+			Retry:    "The statue frowns.",
+			Success: []types.Effect{
+				{Type: "say", Params: map[string]any{"text": "The statue bows."}},
+				{Type: "set_flag", Params: map[string]any{"flag": "riddle_solved", "value": true}},
+			},
+			Failure: []types.Effect{
+				{Type: "say", Params: map[string]any{"text": "The statue turns to stone."}},
+			},
+		},
+	}
+	return New(defs)
+}
+
+func TestAnswer_CorrectAnswerRunsSuccess(t *testing.T) {
+	tests := []string{"time", "Time", "The time!", "  a TIME  "}
+	for _, input := range tests {
+		t.Run(input, func(t *testing.T) {
+			e := riddleEngine()
+			e.Step("talk statue")
+			if e.State.Pending != "answer:riddle_statue" {
+				t.Fatalf("Pending = %q, want riddle pending", e.State.Pending)
+			}
+
+			result := e.Step(input)
+			if !outputContains(result.Output, "The statue bows.") {
+				t.Errorf("expected success output, got %v", result.Output)
+			}
+			if !e.State.Flags["riddle_solved"] {
+				t.Error("expected riddle_solved flag")
+			}
+			if e.State.Pending != "" {
+				t.Errorf("Pending = %q, want empty", e.State.Pending)
+			}
+		})
+	}
+}
+
+func TestAnswer_RollsSavedWithState(t *testing.T) {
+	e := riddleEngine()
+	answer := e.Defs.Answers["riddle_statue"]
+	answer.Success = append(answer.Success, types.Effect{Type: "heal", Params: map[string]any{"target": "player", "amount": "3d6"}})
+	e.Defs.Answers["riddle_statue"] = answer
+	e.Step("talk statue")
+
+	e.Step("time")
+	if e.State.RNGPosition == 0 || e.State.RNGPosition != e.RNG.Position() {
+		t.Fatalf("RNGPosition = %d, want the RNG's %d after the answer's rolls", e.State.RNGPosition, e.RNG.Position())
+	}
+	restored := New(e.Defs)
+	restored.Restore(e.Snapshot())
+	if got, want := restored.RNG.Roll(1000), e.RNG.Roll(1000); got != want {
+		t.Errorf("roll after restoring = %d, want %d", got, want)
+	}
+}
+
+func TestLook_LeavesQuestionPending(t *testing.T) {
+	e := riddleEngine()
+	e.Step("talk statue")
+	e.Step("a bird")
+	turns := e.State.TurnCount
+
+	result := e.Look()
+	if !outputContains(result.Output, "grand hall") {
+		t.Errorf("expected the room, got %v", result.Output)
+	}
+	if e.State.Attempts["riddle_statue"] != 1 || e.State.Pending != "answer:riddle_statue" || e.State.TurnCount != turns {
+		t.Errorf("Look answered the riddle: attempts %v, pending %q, turn %d", e.State.Attempts, e.State.Pending, e.State.TurnCount)
+	}
+}
+
+func TestAnswer_WrongAnswersUseAttempts(t *testing.T) {
+	e := riddleEngine()
+	e.Step("talk statue")
+
+	result := e.Step("a bird")
+	if !outputContains(result.Output, "The statue frowns.") {
+		t.Errorf("expected retry text, got %v", result.Output)
+	}
+	if e.State.Attempts["riddle_statue"] != 1 {
+		t.Errorf("Attempts = %d, want 1", e.State.Attempts["riddle_statue"])
+	}
+	if len(result.Effects) != 1 || result.Effects[0].Type != "use_attempt" {
+		t.Errorf("a wrong answer should count through use_attempt, got %v", result.Effects)
+	}
+
+	result = e.Step("go north")
+	if !outputContains(result.Output, "The statue turns to stone.") {
+		t.Errorf("expected failure output, got %v", result.Output)
+	}
+	if e.State.Pending != "" || e.State.Player.Location != "hall" {
+		t.Errorf("answer should be consumed, not parsed as a command")
+	}
+	if _, ok := e.State.Attempts["riddle_statue"]; ok {
+		t.Error("expected attempts to be cleared once the question is settled")
+	}
+}
+
+func TestAnswer_AskingAgainResetsAttempts(t *testing.T) {
+	e := riddleEngine()
+	e.Step("talk statue")
+	e.Step("wind")
+	e.Step("wind")
+
+	e.Step("talk statue")
+	result := e.Step("wind")
+	if !outputContains(result.Output, "The statue frowns.") {
+		t.Errorf("expected a fresh set of attempts, got %v", result.Output)
+	}
+}
+
+func TestAnswer_UndefinedQuestionIsDropped(t *testing.T) {
+	e := riddleEngine()
+	e.State.Pending = "answer:missing"
+
+	e.Step("go north")
+	if e.State.Player.Location != "garden" {
+		t.Errorf("location = %q, want input parsed as a command", e.State.Player.Location)
+	}
+}
+
+func TestNormalizeAnswer(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"Time", "time"},
+		{"the time.", "time"},
+		{"An Echo!", "echo"},
+		{"  a   map of the world ", "map of world"},
+		{"don't know", "don't know"},
+	}
+	for _, tt := range tests {
+		if got := normalizeAnswer(tt.input); got != tt.want {
+			t.Errorf("normalizeAnswer(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
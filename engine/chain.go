@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/parser"
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// Commands splits input into the commands chained in it with "and", "then"
+// or commas, such as "take key and open door then go north". Verbs the
+// game's rules use count as well as the parser's. While a prompt awaits an
+// answer, input is the answer, whole.
+func (e *Engine) Commands(input string) []string {
+	if e.State.Pending != "" {
+		return []string{input}
+	}
+	return parser.SplitCommands(input, func(verb string) bool {
+		return rules.KnownVerb(e.Defs, verb)
+	})
+}
+
+// StepAll runs the commands chained in input in order, as Commands splits
+// them, and returns the result of each one run. It stops after a command
+// that ends the chain; see EndsChain.
+func (e *Engine) StepAll(input string) []types.Result {
+	var results []types.Result
+	for _, command := range e.Commands(input) {
+		result := e.Step(command)
+		results = append(results, result)
+		if e.EndsChain(result) {
+			break
+		}
+	}
+	return results
+}
+
+// EndsChain reports whether the commands chained after the one that gave
+// result should be dropped: it failed, started a fight, asked a question
+// or ended the game.
+func (e *Engine) EndsChain(result types.Result) bool {
+	if result.Failed || e.State.Pending != "" || state.GetFlag(e.State, "game_over") {
+		return true
+	}
+	for _, line := range result.Output {
+		if markup.Parse(line).Kind == markup.Failure {
+			return true
+		}
+	}
+	for _, evt := range result.Events {
+		if evt.Type == "combat_started" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+func TestStepAll(t *testing.T) {
+	e := New(testDefs())
+	results := e.StepAll("take book then go north")
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if !state.HasItem(e.State, "book") || e.State.Player.Location != "garden" {
+		t.Errorf("expected book taken and player in garden, got %v in %s",
+			e.State.Player.Inventory, e.State.Player.Location)
+	}
+}
+
+func TestStepAll_StopsOnFailure(t *testing.T) {
+	e := New(testDefs())
+	results := e.StepAll("take statue and go north")
+	if len(results) != 1 {
+		t.Fatalf("expected the chain to stop after the failure, got %d results", len(results))
+	}
+	if e.State.Player.Location != "hall" {
+		t.Errorf("expected the player still in the hall, got %s", e.State.Player.Location)
+	}
+}
+
+func TestStepAll_PendingTakesInputWhole(t *testing.T) {
+	e := New(testDefs())
+	e.State.Pending = "answer:riddle"
+	if commands := e.Commands("bread and butter then wait"); len(commands) != 1 {
+		t.Errorf("expected the answer whole, got %q", commands)
+	}
+}
+
+func TestStepAll_StopsOnCombat(t *testing.T) {
+	defs := combatDefs()
+	defs.GlobalRules = []types.RuleDef{{
+		ID:      "ambush",
+		When:    types.MatchCriteria{Verb: "attack", Object: "goblin"},
+		Effects: []types.Effect{{Type: "start_combat", Params: map[string]any{"enemy": "goblin"}}},
+	}}
+	e := New(defs)
+	results := e.StepAll("attack goblin then wait")
+	if len(results) != 1 || !state.InCombat(e.State) {
+		t.Fatalf("expected the chain to stop as combat starts, got %d results", len(results))
+	}
+}
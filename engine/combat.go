@@ -1,8 +1,11 @@
 package engine
 
 import (
-	"fmt"
+	"strings"
 
+	"github.com/nathoo/questcore/engine/dice"
+	"github.com/nathoo/questcore/engine/messages"
+	"github.com/nathoo/questcore/engine/rules"
 	"github.com/nathoo/questcore/engine/state"
 	"github.com/nathoo/questcore/types"
 )
@@ -13,7 +16,9 @@ var combatVerbs = map[string]bool{
 	"defend":    true,
 	"flee":      true,
 	"use":       true,
+	"throw":     true,
 	"inventory": true,
+	"stats":     true,
 	"look":      true,
 }
 
@@ -22,10 +27,23 @@ func isCombatVerb(verb string) bool {
 	return combatVerbs[verb]
 }
 
+// baseDamage is the dice rolled for an attack that names none.
+var baseDamage = dice.Expr{Count: 1, Sides: 6}
+
 // DamageCalc computes damage: max(1, roll(1d6) + attack - defense).
 // If defending, defense gets +2 bonus. Returns (damage, dieRoll).
 func DamageCalc(attackerAttack, defenderDefense int, defending bool, rng *RNG) (damage, roll int) {
-	roll = rng.Roll(6)
+	return DiceDamage(baseDamage, attackerAttack, defenderDefense, defending, rng)
+}
+
+// DiceDamage is DamageCalc for an attack rolling d rather than 1d6:
+// max(1, roll(d) + attack - defense). The roll returned includes d's bonus.
+func DiceDamage(d dice.Expr, attackerAttack, defenderDefense int, defending bool, rng *RNG) (damage, roll int) {
+	purpose := "damage"
+	if d != baseDamage {
+		purpose += " (" + d.String() + ")"
+	}
+	roll = d.Roll(func(sides int) int { return rng.RollFor(purpose, sides) })
 	def := defenderDefense
 	if defending {
 		def += 2
@@ -37,24 +55,54 @@ func DamageCalc(attackerAttack, defenderDefense int, defending bool, rng *RNG) (
 	return damage, roll
 }
 
-// EnemyTurn selects an action for the enemy based on weighted behavior.
-// Returns an Intent for the enemy's action.
-func EnemyTurn(s *types.State, defs *state.Defs, rng *RNG) types.Intent {
+// EnemyTurn selects an action for the enemy based on weighted behavior:
+// one of the entries whose conditions now hold, by weight, or the next
+// step of a sequence picked on an earlier turn. Returns an Intent for the
+// enemy's action, and effects recording its progress through a sequence,
+// to apply along with the action.
+func EnemyTurn(s *types.State, defs *state.Defs, rng *RNG) (types.Intent, []types.Effect) {
 	enemyID := s.Combat.EnemyID
 	behavior := getEnemyBehavior(defs, enemyID)
 
-	if len(behavior) == 0 {
-		// No behavior defined — default to attack.
-		return types.Intent{Verb: "attack"}
+	// Carry on with a sequence under way.
+	if i, ok := state.GetStat(s, defs, enemyID, "behavior_sequence"); ok && i >= 0 && i < len(behavior) {
+		step, _ := state.GetStat(s, defs, enemyID, "behavior_step")
+		if seq := behavior[i].Sequence; step > 0 && step < len(seq) {
+			return types.Intent{Verb: seq[step]}, sequenceProgress(enemyID, i, step+1, len(seq))
+		}
 	}
 
-	weights := make([]int, len(behavior))
+	var entries []int
+	var weights []int
 	for i, b := range behavior {
-		weights[i] = b.Weight
+		if b.Weight > 0 && rules.EvalAllConditions(rules.BindSelf(b.When, enemyID), s, defs) {
+			entries = append(entries, i)
+			weights = append(weights, b.Weight)
+		}
+	}
+	if len(entries) == 0 {
+		// No behavior defined, or none that applies — default to attack.
+		return types.Intent{Verb: "attack"}, nil
 	}
 
-	idx := rng.WeightedSelect(weights)
-	return types.Intent{Verb: behavior[idx].Action}
+	i := entries[rng.SelectFor("enemy action", weights)]
+	if seq := behavior[i].Sequence; len(seq) > 0 {
+		return types.Intent{Verb: seq[0]}, sequenceProgress(enemyID, i, 1, len(seq))
+	}
+	return types.Intent{Verb: behavior[i].Action}, nil
+}
+
+// sequenceProgress returns the effects recording that enemyID takes step
+// next of the sequence in its behavior entry i, which has n steps; or that
+// it has finished the sequence, if step is n.
+func sequenceProgress(enemyID string, i, step, n int) []types.Effect {
+	set := func(prop string, value any) types.Effect {
+		return types.Effect{Type: "set_prop", Params: map[string]any{"entity": enemyID, "prop": prop, "value": value}}
+	}
+	if step >= n {
+		return []types.Effect{set("behavior_sequence", nil), set("behavior_step", nil)}
+	}
+	return []types.Effect{set("behavior_sequence", i), set("behavior_step", step)}
 }
 
 // getEnemyBehavior retrieves the behavior table from entity props.
@@ -98,49 +146,81 @@ func (e *Engine) defaultCombatAttack(actor string) ([]types.Effect, []string) {
 		}
 	}
 
-	damage, roll := DamageCalc(attackStat, defenseStat, defending, e.RNG)
+	attack, special := e.pickAttack(attackerID)
+	roller := baseDamage
+	if special {
+		roller, _ = dice.Parse(attack.Damage) // checked by the loader
+	}
+	damage, roll := DiceDamage(roller, attackStat, defenseStat, defending, e.RNG)
 
 	attackerName := e.combatantName(attackerID)
 	defenderName := e.combatantName(defenderID)
 
 	var output []string
-	if actor == "player" {
-		output = append(output, fmt.Sprintf("You strike the %s!", defenderName))
-	} else {
-		output = append(output, fmt.Sprintf("The %s attacks you!", attackerName))
+	switch {
+	case actor == "player":
+		output = append(output, e.msg("player_attacks", "enemy", defenderName))
+	case attack.Message != "":
+		output = append(output, strings.ReplaceAll(attack.Message, "{enemy}", attackerName))
+	case special:
+		output = append(output, e.msg("enemy_attacks_with", "enemy", attackerName, "attack", attack.Name))
+	default:
+		output = append(output, e.msg("enemy_attacks", "enemy", attackerName))
 	}
 
 	defDisplay := defenseStat
 	if defending {
 		defDisplay += 2
 	}
-	output = append(output, fmt.Sprintf("  Roll: 1d6+%d → [%d]+%d = %d vs defense %d → %d damage",
-		attackStat, roll, attackStat, roll+attackStat, defDisplay, damage))
+	output = append(output, e.msg("attack_roll", "dice", roller, "attack", attackStat, "roll", roll,
+		"total", roll+attackStat, "defense", defDisplay, "damage", damage))
 
 	effs := []types.Effect{
 		{Type: "damage", Params: map[string]any{"target": defenderID, "amount": damage}},
 	}
+	if attack.Effect != "" {
+		effs = append(effs, types.Effect{Type: "set_flag", Params: map[string]any{"flag": attack.Effect, "value": true}})
+		output = append(output, e.msg("status_inflicted", "status", attack.Effect))
+	}
 
 	return effs, output
 }
 
+// pickAttack chooses which of attackerID's attacks it makes, by chance,
+// or reports false if it has none: the player, and enemies without an
+// attacks table, make a plain attack.
+func (e *Engine) pickAttack(attackerID string) (types.AttackDef, bool) {
+	if attackerID == "player" {
+		return types.AttackDef{}, false
+	}
+	attacks, _ := e.Defs.Entities[attackerID].Props["attacks"].([]types.AttackDef)
+	if len(attacks) == 0 {
+		return types.AttackDef{}, false
+	}
+	weights := make([]int, len(attacks))
+	for i, a := range attacks {
+		weights[i] = a.Chance
+	}
+	return attacks[e.RNG.SelectFor("enemy attack", weights)], true
+}
+
 // defaultCombatDefend produces effects for a default defend action.
 func (e *Engine) defaultCombatDefend(actor string) ([]types.Effect, []string) {
 	if actor == "player" {
 		return []types.Effect{
 			{Type: "set_defending"},
-		}, []string{"You brace yourself. (+2 defense this round)"}
+		}, []string{e.msg("player_defends")}
 	}
 	// Enemy defending.
 	enemyID := actor
 	return []types.Effect{
 		{Type: "set_prop", Params: map[string]any{"entity": enemyID, "prop": "defending", "value": true}},
-	}, []string{fmt.Sprintf("The %s braces for your attack.", e.combatantName(enemyID))}
+	}, []string{e.msg("enemy_defends", "enemy", e.combatantName(enemyID))}
 }
 
 // defaultCombatFlee handles flee attempts. On 4+: escape. On fail: enemy free attack.
 func (e *Engine) defaultCombatFlee(actor string) ([]types.Effect, []string) {
-	roll := e.RNG.Roll(6)
+	roll := e.RNG.RollFor("flee", 6)
 
 	if actor == "player" {
 		if roll >= 4 {
@@ -154,13 +234,13 @@ func (e *Engine) defaultCombatFlee(actor string) ([]types.Effect, []string) {
 				{Type: "move_player", Params: map[string]any{"room": prevRoom}},
 			}
 			output := []string{
-				fmt.Sprintf("You turn and run! Roll: 1d6 → [%d] — you escape!", roll),
+				e.msg("flee_success", "roll", roll),
 			}
 			return effs, output
 		}
 		// Flee failed — enemy gets a free attack.
 		output := []string{
-			fmt.Sprintf("You try to run but can't escape! Roll: 1d6 → [%d]", roll),
+			e.msg("flee_fail", "roll", roll),
 		}
 		return nil, output
 	}
@@ -173,13 +253,77 @@ func (e *Engine) defaultCombatFlee(actor string) ([]types.Effect, []string) {
 			{Type: "end_combat"},
 			{Type: "move_entity", Params: map[string]any{"entity": enemyID, "room": ""}},
 		}
-		return effs, []string{fmt.Sprintf("The %s turns and flees! Roll: 1d6 → [%d]", enemyName, roll)}
+		return effs, []string{e.msg("enemy_flees", "enemy", enemyName, "roll", roll)}
 	}
-	return nil, []string{fmt.Sprintf("The %s tries to flee but fails! Roll: 1d6 → [%d]", enemyName, roll)}
+	return nil, []string{e.msg("enemy_flee_fail", "enemy", enemyName, "roll", roll)}
+}
+
+// defaultCombatUse handles the player using or throwing an item in a fight,
+// as the item's combat_use table says: it may hurt the enemy, heal the
+// player, or both, and may be used up. A thrown item that isn't lands in
+// the room.
+func (e *Engine) defaultCombatUse(verb, itemID, targetID string) ([]types.Effect, []string) {
+	if itemID == "" {
+		return nil, nil
+	}
+	enemyID := e.State.Combat.EnemyID
+	itemName, enemyName := e.entityName(itemID), e.entityName(enemyID)
+	if !state.HasItem(e.State, itemID) {
+		return nil, []string{e.fail("dont_have")}
+	}
+	use, ok := e.Defs.Entities[itemID].Props["combat_use"].(types.CombatUse)
+	if !ok {
+		return nil, []string{e.fail("combat_use_useless", "item", itemName)}
+	}
+	if targetID != "" && targetID != enemyID {
+		return nil, []string{e.fail("combat_use_target", "enemy", enemyName)}
+	}
+
+	var effs []types.Effect
+	var output []string
+	switch {
+	case use.Message != "":
+		output = append(output, strings.NewReplacer("{item}", itemName, "{enemy}", enemyName).Replace(use.Message))
+	case verb == "throw":
+		output = append(output, e.msg("combat_throw", "item", itemName, "enemy", enemyName))
+	case use.Damage != "":
+		output = append(output, e.msg("combat_use", "item", itemName, "enemy", enemyName))
+	default:
+		output = append(output, e.msg("combat_use_self", "item", itemName))
+	}
+
+	if use.Damage != "" {
+		d, _ := dice.Parse(use.Damage) // checked by the loader
+		defense, _ := state.GetStat(e.State, e.Defs, enemyID, "defense")
+		if v, _ := state.GetEntityProp(e.State, e.Defs, enemyID, "defending"); v == true {
+			defense += 2
+		}
+		damage, roll := DiceDamage(d, 0, defense, false, e.RNG)
+		output = append(output, e.msg("combat_use_roll", "dice", d, "roll", roll, "defense", defense, "damage", damage))
+		effs = append(effs, types.Effect{Type: "damage", Params: map[string]any{"target": enemyID, "amount": damage}})
+	}
+	if use.Heal != "" {
+		d, _ := dice.Parse(use.Heal) // checked by the loader
+		amount := max(0, d.Roll(func(sides int) int { return e.RNG.RollFor("heal ("+use.Heal+")", sides) }))
+		output = append(output, e.msg("combat_heal", "amount", amount))
+		effs = append(effs, types.Effect{Type: "heal", Params: map[string]any{"target": "player", "amount": amount}})
+	}
+
+	switch {
+	case use.Consumes:
+		effs = append(effs, types.Effect{Type: "consume_item", Params: map[string]any{"item": itemID}})
+	case verb == "throw":
+		effs = append(effs,
+			types.Effect{Type: "remove_item", Params: map[string]any{"item": itemID}},
+			types.Effect{Type: "move_entity", Params: map[string]any{"entity": itemID, "room": e.State.Player.Location}},
+		)
+	}
+	return effs, output
 }
 
 // ProcessLoot rolls for each item in the enemy's loot table and produces
-// effects for successful drops (give_item) and gold (inc_counter).
+// effects for the enemy's XP (gain_xp), successful drops (give_item) and
+// gold (inc_counter), which may be rolled.
 func ProcessLoot(s *types.State, defs *state.Defs, enemyID string, rng *RNG) ([]types.Effect, []string) {
 	def, ok := defs.Entities[enemyID]
 	if !ok {
@@ -189,10 +333,18 @@ func ProcessLoot(s *types.State, defs *state.Defs, enemyID string, rng *RNG) ([]
 	var effs []types.Effect
 	var output []string
 
+	// Experience for the kill.
+	if xp, ok := state.GetStat(s, defs, enemyID, "xp"); ok && xp > 0 {
+		effs = append(effs, types.Effect{
+			Type:   "gain_xp",
+			Params: map[string]any{"amount": xp},
+		})
+	}
+
 	// Roll for each loot item.
 	if lootItems, ok := def.Props["loot_items"].([]types.LootEntry); ok {
 		for _, item := range lootItems {
-			roll := rng.Roll(100)
+			roll := rng.RollFor("loot: "+item.ItemID, 100)
 			if roll <= item.Chance {
 				name := item.ItemID
 				if ent, ok := defs.Entities[item.ItemID]; ok {
@@ -204,19 +356,24 @@ func ProcessLoot(s *types.State, defs *state.Defs, enemyID string, rng *RNG) ([]
 					Type:   "give_item",
 					Params: map[string]any{"item": item.ItemID},
 				})
-				output = append(output, fmt.Sprintf("You found: %s!", name))
+				output = append(output, messages.Text(defs.Messages, "loot_item", "item", name))
 			}
 		}
 	}
 
 	// Gold drop.
 	if gold, ok := def.Props["loot_gold"]; ok {
+		if expr, ok := gold.(string); ok {
+			// A dice expression, such as "2d6": roll for the amount.
+			d, _ := dice.Parse(expr) // checked by the loader
+			gold = d.Roll(func(sides int) int { return rng.RollFor("loot: gold ("+expr+")", sides) })
+		}
 		if g, ok := gold.(int); ok && g > 0 {
 			effs = append(effs, types.Effect{
 				Type:   "inc_counter",
 				Params: map[string]any{"counter": "gold", "amount": g},
 			})
-			output = append(output, fmt.Sprintf("You found %d gold.", g))
+			output = append(output, messages.Text(defs.Messages, "loot_gold", "amount", g))
 		}
 	}
 
@@ -1,9 +1,12 @@
 package engine
 
 import (
+	"reflect"
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/nathoo/questcore/engine/effects"
 	"github.com/nathoo/questcore/engine/state"
 	"github.com/nathoo/questcore/types"
 )
@@ -131,7 +134,7 @@ func TestEnemyTurn_WeightedSelect(t *testing.T) {
 	// Run multiple turns and check distribution.
 	counts := map[string]int{}
 	for i := 0; i < 1000; i++ {
-		intent := EnemyTurn(s, defs, rng)
+		intent, _ := EnemyTurn(s, defs, rng)
 		counts[intent.Verb]++
 	}
 
@@ -154,7 +157,7 @@ func TestEnemyTurn_NoBehavior_DefaultsToAttack(t *testing.T) {
 	rng := NewRNG(42)
 
 	for i := 0; i < 10; i++ {
-		intent := EnemyTurn(s, defs, rng)
+		intent, _ := EnemyTurn(s, defs, rng)
 		if intent.Verb != "attack" {
 			t.Errorf("expected attack for enemy with no behavior, got %q", intent.Verb)
 		}
@@ -170,14 +173,77 @@ func TestEnemyTurn_Deterministic(t *testing.T) {
 	rng2 := NewRNG(42)
 
 	for i := 0; i < 20; i++ {
-		i1 := EnemyTurn(s, defs, rng1)
-		i2 := EnemyTurn(s, defs, rng2)
+		i1, _ := EnemyTurn(s, defs, rng1)
+		i2, _ := EnemyTurn(s, defs, rng2)
 		if i1.Verb != i2.Verb {
 			t.Fatalf("turn %d: %q != %q", i, i1.Verb, i2.Verb)
 		}
 	}
 }
 
+func TestEnemyTurn_ConditionalEntries(t *testing.T) {
+	defs := combatDefs()
+	goblin := defs.Entities["goblin"]
+	goblin.Props["behavior"] = []types.BehaviorEntry{
+		{Action: "attack", Weight: 100, When: []types.Condition{
+			{Type: "stat_gt", Params: map[string]any{"entity": "self", "stat": "hp", "value": 3}},
+		}},
+		{Action: "flee", Weight: 100, When: []types.Condition{
+			{Type: "stat_lt", Params: map[string]any{"entity": "self", "stat": "hp", "value": 4}},
+		}},
+	}
+	s := state.NewState(defs)
+	s.Combat = types.CombatState{Active: true, EnemyID: "goblin"}
+	rng := NewRNG(42)
+
+	for i := 0; i < 10; i++ {
+		if intent, _ := EnemyTurn(s, defs, rng); intent.Verb != "attack" {
+			t.Fatalf("healthy goblin chose %q, want attack", intent.Verb)
+		}
+	}
+	state.SetStat(s, "goblin", "hp", 2)
+	for i := 0; i < 10; i++ {
+		if intent, _ := EnemyTurn(s, defs, rng); intent.Verb != "flee" {
+			t.Fatalf("wounded goblin chose %q, want flee", intent.Verb)
+		}
+	}
+}
+
+func TestEnemyTurn_NoEntryApplies_DefaultsToAttack(t *testing.T) {
+	defs := combatDefs()
+	goblin := defs.Entities["goblin"]
+	goblin.Props["behavior"] = []types.BehaviorEntry{
+		{Action: "flee", Weight: 100, When: []types.Condition{{Type: "flag_set", Params: map[string]any{"flag": "scared"}}}},
+	}
+	s := state.NewState(defs)
+	s.Combat = types.CombatState{Active: true, EnemyID: "goblin"}
+
+	if intent, _ := EnemyTurn(s, defs, NewRNG(42)); intent.Verb != "attack" {
+		t.Errorf("got %q, want attack", intent.Verb)
+	}
+}
+
+func TestEnemyTurn_Sequence(t *testing.T) {
+	defs := combatDefs()
+	goblin := defs.Entities["goblin"]
+	goblin.Props["behavior"] = []types.BehaviorEntry{
+		{Sequence: []string{"defend", "attack", "attack"}, Weight: 100},
+	}
+	s := state.NewState(defs)
+	s.Combat = types.CombatState{Active: true, EnemyID: "goblin"}
+	rng := NewRNG(42)
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		intent, progress := EnemyTurn(s, defs, rng)
+		got = append(got, intent.Verb)
+		effects.Apply(s, defs, progress, effects.Context{})
+	}
+	if want := []string{"defend", "attack", "attack", "defend"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("turns = %v, want %v", got, want)
+	}
+}
+
 func TestIsCombatVerb(t *testing.T) {
 	tests := []struct {
 		verb string
@@ -187,6 +253,7 @@ func TestIsCombatVerb(t *testing.T) {
 		{"defend", true},
 		{"flee", true},
 		{"use", true},
+		{"throw", true},
 		{"inventory", true},
 		{"look", true},
 		{"go", false},
@@ -244,6 +311,56 @@ func TestDefaultCombatDefend_EnemyReturnsSetProp(t *testing.T) {
 	}
 }
 
+func TestDefaultCombatAttack_EnemyAttacks(t *testing.T) {
+	eng := combatEngine()
+	goblin := eng.Defs.Entities["goblin"]
+	goblin.Props["attacks"] = []types.AttackDef{
+		{Name: "bite", Damage: "2d4+10", Chance: 60, Effect: "poison"},
+		{Name: "claws", Damage: "1d4", Chance: 40, Message: "The {enemy} rakes you with its claws!"},
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 50; i++ {
+		effs, output := eng.defaultCombatAttack("goblin")
+		damage := effs[0].Params["amount"].(int)
+		switch {
+		case strings.Contains(output[0], "attacks you with its bite"):
+			seen["bite"] = true
+			// 2d4+10 rolls 12 to 18, plus attack 4, less defense 2.
+			if damage < 14 || damage > 20 {
+				t.Errorf("bite damage = %d, want 14 to 20", damage)
+			}
+			if !strings.Contains(output[1], "2d4+10+4") {
+				t.Errorf("roll line = %q, want the bite's dice", output[1])
+			}
+			if len(effs) != 2 || effs[1].Type != "set_flag" || effs[1].Params["flag"] != "poison" {
+				t.Errorf("bite effects = %v, want damage and the poison flag", effs)
+			}
+		case output[0] == "The Cave Goblin rakes you with its claws!":
+			seen["claws"] = true
+			if damage < 3 || damage > 6 {
+				t.Errorf("claws damage = %d, want 3 to 6", damage)
+			}
+			if len(effs) != 1 {
+				t.Errorf("claws effects = %v, want damage only", effs)
+			}
+		default:
+			t.Fatalf("unexpected attack output %q", output)
+		}
+	}
+	if !seen["bite"] || !seen["claws"] {
+		t.Errorf("attacks used = %v, want both", seen)
+	}
+}
+
+func TestDefaultCombatAttack_PlainAttackUnchanged(t *testing.T) {
+	eng := combatEngine()
+	_, output := eng.defaultCombatAttack("goblin")
+	if output[0] != "The Cave Goblin attacks you!" || !strings.Contains(output[1], "Roll: 1d6+4") {
+		t.Errorf("output = %q, want the plain 1d6 attack", output)
+	}
+}
+
 // --- Integration tests: full combat through Step() ---
 
 // combatEngine creates an engine with combat-ready defs and starts combat.
@@ -553,3 +670,402 @@ func TestStep_NoLootWhileEnemyAlive(t *testing.T) {
 func contains(s, substr string) bool {
 	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
 }
+
+func TestStep_RNGDrawsReportedAsEvents(t *testing.T) {
+	eng := combatEngine()
+	result := eng.Step("attack goblin")
+
+	var purposes []string
+	for _, e := range result.Events {
+		if e.Type == "rng_draw" {
+			purposes = append(purposes, e.Data["purpose"].(string))
+			if _, ok := e.Data["message"].(string); !ok {
+				t.Errorf("rng_draw event without message: %v", e.Data)
+			}
+		}
+	}
+	// Player damage, enemy action choice, then the enemy's action roll.
+	if len(purposes) < 2 || purposes[0] != "damage" || purposes[1] != "enemy action" {
+		t.Errorf("draw purposes = %v, want damage then enemy action", purposes)
+	}
+}
+
+func TestStep_DiceAmountsDrawnAndTraced(t *testing.T) {
+	defs := combatDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "trap", Scope: "global", When: types.MatchCriteria{Verb: "jump"},
+		Effects: []types.Effect{
+			{Type: "damage", Params: map[string]any{"target": "player", "amount": "2d4+1"}},
+		},
+	})
+	eng := New(defs)
+	eng.SetSeed(7)
+	result := eng.Step("jump")
+
+	var total int
+	var draws []string
+	for _, e := range result.Events {
+		if e.Type == "rng_draw" {
+			draws = append(draws, e.Data["purpose"].(string))
+			total += e.Data["roll"].(int)
+		}
+	}
+	if want := []string{"damage: player (2d4+1)", "damage: player (2d4+1)"}; !reflect.DeepEqual(draws, want) {
+		t.Fatalf("draws = %v, want one per die: %v", draws, want)
+	}
+	if hp := eng.State.Player.Stats["hp"]; hp != 20-(total+1) {
+		t.Errorf("hp = %d, want 20 less the dice (%d) and bonus", hp, total)
+	}
+}
+
+// combatItemEngine is combatEngine with the player carrying item, which
+// has the given combat use.
+func combatItemEngine(item string, use types.CombatUse) *Engine {
+	eng := combatEngine()
+	eng.Defs.Entities[item] = types.EntityDef{
+		ID: item, Kind: "item",
+		Props: map[string]any{"name": item, "takeable": true, "combat_use": use},
+	}
+	eng.State.Player.Inventory = append(eng.State.Player.Inventory, item)
+	return eng
+}
+
+func TestStep_CombatUseDamagesEnemy(t *testing.T) {
+	eng := combatItemEngine("bomb", types.CombatUse{Damage: "2d6", Consumes: true})
+
+	result := eng.Step("use bomb on goblin")
+
+	if !contains(strings.Join(result.Output, "\n"), "You use the bomb on the Cave Goblin!") {
+		t.Errorf("output = %v, want the item used on the enemy", result.Output)
+	}
+	if hp, _ := state.GetStat(eng.State, eng.Defs, "goblin", "hp"); hp < 1 || hp > 11 {
+		t.Errorf("goblin hp = %d, want 12 less 1 to 11", hp)
+	}
+	if state.HasItem(eng.State, "bomb") {
+		t.Error("a bomb that consumes should be used up")
+	}
+	var purposes []string
+	for _, e := range result.Events {
+		if e.Type == "rng_draw" {
+			purposes = append(purposes, e.Data["purpose"].(string))
+		}
+	}
+	if len(purposes) < 2 || purposes[0] != "damage (2d6)" || purposes[1] != "damage (2d6)" {
+		t.Errorf("draw purposes = %v, want the bomb's two dice first", purposes)
+	}
+}
+
+func TestStep_CombatThrowLandsInRoom(t *testing.T) {
+	eng := combatItemEngine("dagger", types.CombatUse{Damage: "1d4"})
+
+	result := eng.Step("throw dagger at goblin")
+
+	if !contains(strings.Join(result.Output, "\n"), "You throw the dagger at the Cave Goblin!") {
+		t.Errorf("output = %v, want the dagger thrown", result.Output)
+	}
+	if state.HasItem(eng.State, "dagger") {
+		t.Error("a thrown dagger should leave the inventory")
+	}
+	if loc := state.EntityLocation(eng.State, eng.Defs, "dagger"); loc != "cave" {
+		t.Errorf("dagger location = %q, want it on the floor of the cave", loc)
+	}
+}
+
+func TestStep_CombatUseHeals(t *testing.T) {
+	eng := combatItemEngine("potion", types.CombatUse{Heal: "10", Consumes: true})
+	eng.State.Player.Stats["hp"] = 5
+
+	result := eng.Step("use potion")
+
+	out := strings.Join(result.Output, "\n")
+	if !contains(out, "You use the potion.") || !contains(out, "You recover 10 HP.") {
+		t.Errorf("output = %v, want the potion drunk", result.Output)
+	}
+	// 15 HP, less whatever the goblin's turn did.
+	if hp := eng.State.Player.Stats["hp"]; hp <= 5 {
+		t.Errorf("hp = %d, want more than 5 after healing 10", hp)
+	}
+	if hp, _ := state.GetStat(eng.State, eng.Defs, "goblin", "hp"); hp != 12 {
+		t.Errorf("goblin hp = %d, want a healing potion to leave it be", hp)
+	}
+}
+
+func TestStep_CombatUseRefusals(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+	}{
+		{"no combat use", "use blade", "won't help you here"},
+		{"not the enemy", "throw bomb at blade", "You're fighting the Cave Goblin, not that."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := combatItemEngine("bomb", types.CombatUse{Damage: "2d6", Consumes: true})
+			eng.State.Player.Inventory = append(eng.State.Player.Inventory, "goblin_blade")
+
+			result := eng.Step(tt.input)
+
+			if !contains(strings.Join(result.Output, "\n"), tt.want) {
+				t.Errorf("output = %v, want %q", result.Output, tt.want)
+			}
+			if !state.HasItem(eng.State, "bomb") {
+				t.Error("the bomb should not be used up")
+			}
+		})
+	}
+}
+
+func TestProcessLoot_GoldDice(t *testing.T) {
+	defs := combatDefs()
+	defs.Entities["goblin"].Props["loot_gold"] = "3d6"
+	s := state.NewState(defs)
+	rng := NewRNG(1)
+
+	effs, _ := ProcessLoot(s, defs, "goblin", rng)
+	for _, eff := range effs {
+		if eff.Type != "inc_counter" {
+			continue
+		}
+		if gold := eff.Params["amount"].(int); gold < 3 || gold > 18 {
+			t.Errorf("gold = %d, want 3 to 18", gold)
+		}
+		return
+	}
+	t.Errorf("expected a gold drop, got %v", effs)
+}
+
+func TestSetSeed_ReproducesCombat(t *testing.T) {
+	play := func() []string {
+		eng := combatEngine()
+		eng.SetSeed(7)
+		var out []string
+		for i := 0; i < 3; i++ {
+			out = append(out, eng.Step("attack goblin").Output...)
+		}
+		return out
+	}
+
+	first, second := play(), play()
+	if strings.Join(first, "\n") != strings.Join(second, "\n") {
+		t.Errorf("same seed gave different combat:\n%v\n%v", first, second)
+	}
+
+	eng := combatEngine()
+	eng.Step("attack goblin")
+	eng.SetSeed(7)
+	if eng.State.RNGSeed != 7 || eng.RNG.Position() != 0 {
+		t.Errorf("SetSeed did not reset seed and position")
+	}
+}
+
+func TestStep_DefeatAwardsXPAndLevelsUp(t *testing.T) {
+	defs := combatDefs()
+	defs.Game.Levels = []types.LevelDef{
+		{XP: 10, Stats: map[string]int{"max_hp": 5, "attack": 1}},
+		{XP: 50, Stats: map[string]int{"attack": 1}},
+	}
+	goblin := defs.Entities["goblin"]
+	goblin.Props["xp"] = 15
+	defs.Entities["goblin"] = goblin
+	defs.Handlers = []types.EventHandler{{
+		ID: "leveled", EventType: "player_leveled",
+		Effects: []types.Effect{{Type: "set_flag", Params: map[string]any{"flag": "leveled", "value": true}}},
+	}}
+	eng := New(defs)
+	eng.State.Player.Location = "cave"
+	eng.State.Combat = types.CombatState{Active: true, EnemyID: "goblin", PreviousLocation: "cave"}
+	eng.State.Entities["goblin"] = types.EntityState{Props: map[string]any{"hp": 1, "alive": true}}
+
+	result := eng.Step("attack goblin")
+
+	stats := eng.State.Player.Stats
+	if stats["xp"] != 15 || stats["level"] != 2 {
+		t.Errorf("xp = %d, level = %d, want 15, 2", stats["xp"], stats["level"])
+	}
+	if stats["max_hp"] != 25 || stats["attack"] != 6 {
+		t.Errorf("max_hp = %d, attack = %d, want 25, 6", stats["max_hp"], stats["attack"])
+	}
+	if !outputContains(result.Output, "You have reached level 2!") {
+		t.Errorf("expected level-up message, got %v", result.Output)
+	}
+	if !state.GetFlag(eng.State, "leveled") {
+		t.Error("player_leveled handler should have fired")
+	}
+
+	result = eng.Step("stats")
+	if !outputContains(result.Output, "Level 2 (15/50 XP)") || !outputContains(result.Output, "Attack: 6") {
+		t.Errorf("stats = %v", result.Output)
+	}
+}
+
+// ambushEngine is an engine in which "jump" starts a fight with the
+// skeleton, which always attacks, taking it by surprise if surprise is set.
+func ambushEngine(surprise bool) *Engine {
+	defs := combatDefs()
+	defs.Entities["skeleton"].Props["hp"] = 50
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "ambush", Scope: "global", When: types.MatchCriteria{Verb: "jump"},
+		Effects: []types.Effect{
+			{Type: "start_combat", Params: map[string]any{"enemy": "skeleton", "surprise": surprise}},
+		},
+	})
+	return New(defs, WithSeed(3))
+}
+
+// enemyStruck reports whether result records the enemy hitting the player.
+func enemyStruck(result types.Result) bool {
+	for _, e := range result.Events {
+		if e.Type == "entity_damaged" && e.Data["target"] == "player" {
+			return true
+		}
+	}
+	return false
+}
+
+func TestStep_InitiativeDecidesWhoActsFirst(t *testing.T) {
+	tests := []struct {
+		name         string
+		playerSpeed  int
+		enemySpeed   int
+		wantFirst    string
+		wantStruck   bool
+		wantAnnounce string
+	}{
+		{"player quicker", 20, 0, "player", false, "You're quicker than the Skeleton."},
+		{"enemy quicker", 0, 20, "enemy", true, "The Skeleton is quicker than you!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := ambushEngine(false)
+			eng.State.Player.Stats["speed"] = tt.playerSpeed
+			eng.Defs.Entities["skeleton"].Props["speed"] = tt.enemySpeed
+
+			result := eng.Step("jump")
+
+			if eng.State.Combat.Initiative != tt.wantFirst {
+				t.Errorf("initiative = %q, want %q", eng.State.Combat.Initiative, tt.wantFirst)
+			}
+			if got := enemyStruck(result); got != tt.wantStruck {
+				t.Errorf("enemy struck = %v, want %v: %v", got, tt.wantStruck, result.Output)
+			}
+			if !outputContains(result.Output, tt.wantAnnounce) {
+				t.Errorf("expected %q in output, got %v", tt.wantAnnounce, result.Output)
+			}
+		})
+	}
+}
+
+func TestStep_NoSpeedEnemyStrikesFirst(t *testing.T) {
+	eng := ambushEngine(false)
+
+	result := eng.Step("jump")
+
+	if !enemyStruck(result) {
+		t.Errorf("without speed stats the enemy should strike at once, got %v", result.Output)
+	}
+	for _, e := range result.Events {
+		if e.Type == "rng_draw" && strings.HasPrefix(e.Data["purpose"].(string), "initiative") {
+			t.Errorf("no initiative should be rolled without speed stats, got draw %v", e.Data)
+		}
+	}
+}
+
+func TestStep_SurpriseGivesFreeRound(t *testing.T) {
+	eng := ambushEngine(true)
+	eng.Defs.Entities["skeleton"].Props["speed"] = 20 // surprise beats speed
+
+	result := eng.Step("jump")
+	if enemyStruck(result) || !outputContains(result.Output, "You catch the Skeleton by surprise!") {
+		t.Fatalf("surprised enemy should not act as the fight starts, got %v", result.Output)
+	}
+
+	result = eng.Step("attack")
+	if enemyStruck(result) || !outputContains(result.Output, "still reeling") {
+		t.Fatalf("surprised enemy should miss its first turn, got %v", result.Output)
+	}
+
+	result = eng.Step("attack")
+	if !enemyStruck(result) {
+		t.Errorf("enemy should act from the second round, got %v", result.Output)
+	}
+}
+
+func TestStep_EnemyBreaksBelowMorale(t *testing.T) {
+	tests := []struct {
+		onBreak   string
+		wantMsg   string
+		wantFled  bool
+		wantHere  bool
+		wantGiven bool
+	}{
+		{"", "begs for mercy", false, true, true},
+		{"flee", "loses its nerve", true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run("on_break="+tt.onBreak, func(t *testing.T) {
+			eng := combatEngine()
+			eng.Defs.Entities["goblin"].Props["morale"] = 40
+			if tt.onBreak != "" {
+				eng.Defs.Entities["goblin"].Props["on_break"] = tt.onBreak
+			}
+			es := eng.State.Entities["goblin"]
+			es.Props["hp"] = 45 // any hit takes it to morale or below
+			eng.State.Entities["goblin"] = es
+
+			result := eng.Step("attack")
+
+			if state.InCombat(eng.State) {
+				t.Fatal("expected the fight to end when the goblin breaks")
+			}
+			var surrendered *types.Event
+			for i, e := range result.Events {
+				if e.Type == "enemy_surrendered" {
+					surrendered = &result.Events[i]
+				}
+			}
+			if surrendered == nil || surrendered.Data["fled"] != tt.wantFled {
+				t.Fatalf("expected enemy_surrendered with fled=%v, got %v", tt.wantFled, result.Events)
+			}
+			if enemyStruck(result) {
+				t.Error("a broken enemy should not take its turn")
+			}
+			if !outputContains(result.Output, tt.wantMsg) {
+				t.Errorf("expected %q in output, got %v", tt.wantMsg, result.Output)
+			}
+			if here := slices.Contains(state.EntitiesInRoom(eng.State, eng.Defs, "cave"), "goblin"); here != tt.wantHere {
+				t.Errorf("goblin still in the cave = %v, want %v", here, tt.wantHere)
+			}
+			if given, _ := state.GetEntityProp(eng.State, eng.Defs, "goblin", "surrendered"); (given == true) != tt.wantGiven {
+				t.Errorf("surrendered = %v, want %v", given, tt.wantGiven)
+			}
+			if alive, _ := state.GetEntityProp(eng.State, eng.Defs, "goblin", "alive"); alive != true {
+				t.Errorf("a broken enemy should stay alive, got alive=%v", alive)
+			}
+		})
+	}
+}
+
+func TestStep_TalkDuringCombat(t *testing.T) {
+	eng := combatEngine()
+	goblin := eng.Defs.Entities["goblin"]
+	goblin.Topics = map[string]types.TopicDef{
+		"peace": {Text: "Fine, fine! No more fighting.", Effects: []types.Effect{{Type: "end_combat"}}},
+	}
+	eng.Defs.Entities["goblin"] = goblin
+
+	result := eng.Step("talk")
+	if !outputContains(result.Output, "middle of a fight") {
+		t.Fatalf("talk should be refused unless the enemy can negotiate, got %v", result.Output)
+	}
+
+	goblin.Props["can_negotiate"] = true
+	result = eng.Step("talk")
+	if !outputContains(result.Output, "No more fighting.") {
+		t.Errorf("expected the goblin's topic, got %v", result.Output)
+	}
+	if state.InCombat(eng.State) {
+		t.Error("the peace topic should have ended the fight")
+	}
+	if enemyStruck(result) {
+		t.Error("the enemy should not act once talked down")
+	}
+}
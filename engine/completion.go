@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/parser"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// Verbs returns, sorted, the verbs a player can type: those the parser
+// knows and those the game's rules respond to. Front ends use it for
+// completion.
+func (e *Engine) Verbs() []string {
+	seen := map[string]bool{}
+	for _, verb := range parser.Verbs() {
+		seen[verb] = true
+	}
+	addRules := func(rules []types.RuleDef) {
+		for _, r := range rules {
+			if r.When.Verb != "" {
+				seen[r.When.Verb] = true
+			}
+		}
+	}
+	addRules(e.Defs.GlobalRules)
+	for _, room := range e.Defs.Rooms {
+		addRules(room.Rules)
+	}
+	for _, ent := range e.Defs.Entities {
+		addRules(ent.Rules)
+	}
+	return sortedSet(seen)
+}
+
+// VisibleNames returns, sorted and in lower case, the names of the entities
+// in the player's room and inventory, as the player would type them: each
+// full name, and each word of three letters or more in it, since any word
+// of a name identifies the entity.
+func (e *Engine) VisibleNames() []string {
+	ids := state.EntitiesInRoom(e.State, e.Defs, e.State.Player.Location)
+	ids = append(ids, e.State.Player.Inventory...)
+	seen := map[string]bool{}
+	for _, id := range ids {
+		name := strings.ToLower(e.entityName(id))
+		seen[name] = true
+		for _, word := range strings.Fields(name) {
+			if len(word) >= 3 {
+				seen[word] = true
+			}
+		}
+	}
+	return sortedSet(seen)
+}
+
+func sortedSet(set map[string]bool) []string {
+	list := make([]string, 0, len(set))
+	for s := range set {
+		list = append(list, s)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func TestVerbs_IncludesRuleVerbs(t *testing.T) {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "dance", Scope: "global", When: types.MatchCriteria{Verb: "dance"},
+	})
+	verbs := New(defs).Verbs()
+	for _, want := range []string{"dance", "take", "examine", "north"} {
+		if !slices.Contains(verbs, want) {
+			t.Errorf("Verbs() is missing %q", want)
+		}
+	}
+}
+
+func TestVisibleNames(t *testing.T) {
+	defs := testDefs()
+	defs.Entities["lamp"] = types.EntityDef{ID: "lamp", Kind: "item", Props: map[string]any{
+		"name": "Old Brass Lamp", "location": "garden",
+	}}
+	e := New(defs)
+	e.Step("take key")
+	e.Step("north")
+
+	want := []string{"brass", "key", "lamp", "old", "old brass lamp"}
+	if got := e.VisibleNames(); !slices.Equal(got, want) {
+		t.Errorf("VisibleNames() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/types"
+)
+
+// pendingRestartConfirm is the pending-input kind for "Are you sure you
+// want to restart?".
+const pendingRestartConfirm = "restart_confirm"
+
+// playerDied reports whether the player was defeated this turn.
+func playerDied(evts []types.Event) bool {
+	for _, evt := range evts {
+		if evt.Type == "player_defeated" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyDeathPolicy runs after the player is defeated, according to
+// Game.OnDeath: "prompt" (the default) leaves the game over and the ending
+// menu up, "restart" starts a fresh game, and "respawn" brings the player
+// back in the respawn room after applying the death penalty. It reports
+// whether the game was restarted, in which case the rest of the turn is
+// skipped.
+func (e *Engine) applyDeathPolicy(result *types.Result, ctx effects.Context) bool {
+	switch e.Defs.Game.OnDeath {
+	case "restart":
+		restarted := e.restart()
+		result.Output = append(result.Output, "", e.msg("died_restart"), "")
+		result.Output = append(result.Output, restarted.Output...)
+		result.Effects = append(result.Effects, restarted.Effects...)
+		result.Events = append(result.Events, restarted.Events...)
+		return true
+	case "respawn":
+		effs := e.respawnEffects()
+		evts, out := effects.Apply(e.State, e.Defs, effs, ctx)
+		result.Effects = append(result.Effects, effs...)
+		result.Events = append(result.Events, evts...)
+		result.Output = append(result.Output, "", e.msg("died_respawn"))
+		result.Output = append(result.Output, out...)
+		result.Output = append(result.Output, "")
+		result.Output = append(result.Output, e.describeRoom(e.State.Player.Location)...)
+
+		// Handlers may react to the respawn, as for commands.
+		e.dispatch(evts, ctx, result)
+	}
+	return false
+}
+
+// respawnEffects revives the player in the respawn room with full health,
+// less the death penalty. Dropped items stay where the player fell.
+func (e *Engine) respawnEffects() []types.Effect {
+	g := e.Defs.Game
+	stats := e.State.Player.Stats
+	effs := []types.Effect{
+		{Type: "set_flag", Params: map[string]any{"flag": "game_over", "value": false}},
+	}
+
+	if g.DeathPenalty.DropItems {
+		for _, item := range e.State.Player.Inventory {
+			effs = append(effs,
+				types.Effect{Type: "remove_item", Params: map[string]any{"item": item}},
+				types.Effect{Type: "move_entity", Params: map[string]any{"entity": item, "room": e.State.Player.Location}},
+			)
+		}
+	}
+	if lost := min(g.DeathPenalty.Gold, e.State.Counters["gold"]); lost > 0 {
+		effs = append(effs, types.Effect{Type: "inc_counter", Params: map[string]any{"counter": "gold", "amount": -lost}})
+	}
+	if xp, ok := stats["xp"]; ok && g.DeathPenalty.XP > 0 {
+		// Lost XP never takes the player below the level they have reached.
+		floor := 0
+		if level := stats["level"]; level >= 2 && level-2 < len(g.Levels) {
+			floor = g.Levels[level-2].XP
+		}
+		effs = append(effs, types.Effect{Type: "set_stat", Params: map[string]any{
+			"target": "player", "stat": "xp", "value": max(floor, xp-g.DeathPenalty.XP),
+		}})
+	}
+
+	hp, ok := stats["max_hp"]
+	if !ok {
+		hp = g.PlayerStats["hp"]
+	}
+	effs = append(effs,
+		types.Effect{Type: "set_stat", Params: map[string]any{"target": "player", "stat": "hp", "value": hp}},
+		types.Effect{Type: "move_player", Params: map[string]any{"room": e.respawnRoom()}},
+		types.Effect{Type: "emit_event", Params: map[string]any{"event": "player_respawned"}},
+	)
+	return effs
+}
+
+// respawnRoom returns Game.RespawnRoom, or the start room if none is set.
+func (e *Engine) respawnRoom() string {
+	if e.Defs.Game.RespawnRoom != "" {
+		return e.Defs.Game.RespawnRoom
+	}
+	return e.Defs.Game.Start
+}
+
+// answerRestartConfirm handles the reply to "Are you sure you want to
+// restart?".
+func (e *Engine) answerRestartConfirm(input string) types.Result {
+	e.State.Pending = ""
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "y", "yes":
+		return e.restart()
+	default:
+		return types.Result{Output: []string{e.msg("restart_declined")}}
+	}
+}
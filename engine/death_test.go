@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// deathDefs returns testDefs with a player who can die by jumping into the
+// pit in the garden.
+func deathDefs(onDeath string) *state.Defs {
+	defs := testDefs()
+	defs.Game.PlayerStats = map[string]int{"hp": 10, "max_hp": 10, "xp": 30}
+	defs.Game.OnDeath = onDeath
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "jump_pit", Scope: "global",
+		When:    types.MatchCriteria{Verb: "jump"},
+		Effects: []types.Effect{{Type: "damage", Params: map[string]any{"target": "player", "amount": 50}}},
+	})
+	return defs
+}
+
+func TestStep_DeathPromptEndsGame(t *testing.T) {
+	e := New(deathDefs(""))
+	e.Step("jump")
+	if !state.GetFlag(e.State, "game_over") || e.State.Pending != pendingEndingMenu {
+		t.Errorf("game_over = %v, pending = %q, want the ending menu", state.GetFlag(e.State, "game_over"), e.State.Pending)
+	}
+}
+
+func TestStep_DeathRespawnAppliesPenalty(t *testing.T) {
+	defs := deathDefs("respawn")
+	defs.Game.RespawnRoom = "hall"
+	defs.Game.DeathPenalty = types.DeathPenaltyDef{Gold: 5, XP: 50, DropItems: true}
+	defs.Handlers = []types.EventHandler{{
+		ID: "revived", EventType: "player_respawned",
+		Effects: []types.Effect{{Type: "say", Params: map[string]any{"text": "A priest tends your wounds."}}},
+	}}
+	e := New(defs)
+	e.State.Counters["gold"] = 3
+	e.Step("take book")
+	e.Step("north")
+
+	result := e.Step("jump")
+
+	if state.GetFlag(e.State, "game_over") || e.State.Pending != "" {
+		t.Error("respawn should not end the game")
+	}
+	if e.State.Player.Location != "hall" || e.State.Player.Stats["hp"] != 10 {
+		t.Errorf("location = %q, hp = %d, want hall, 10", e.State.Player.Location, e.State.Player.Stats["hp"])
+	}
+	if e.State.Counters["gold"] != 0 || e.State.Player.Stats["xp"] != 0 {
+		t.Errorf("gold = %d, xp = %d, want both 0", e.State.Counters["gold"], e.State.Player.Stats["xp"])
+	}
+	if state.HasItem(e.State, "book") || state.EntityLocation(e.State, e.Defs, "book") != "garden" {
+		t.Error("book should be left in the garden")
+	}
+	if !outputContains(result.Output, "A priest tends your wounds.") {
+		t.Errorf("expected player_respawned handler output, got %v", result.Output)
+	}
+}
+
+func TestStep_DeathRestartStartsOver(t *testing.T) {
+	e := New(deathDefs("restart"))
+	e.State.Music = "dirge"
+	e.Step("north")
+	result := e.Step("jump")
+
+	if state.GetFlag(e.State, "game_over") || e.State.Player.Location != "hall" || e.State.TurnCount != 0 {
+		t.Errorf("expected a fresh game, got location %q, turn %d", e.State.Player.Location, e.State.TurnCount)
+	}
+	// The restart's own events, such as the music stopping, are reported.
+	var stopped bool
+	for _, evt := range result.Events {
+		stopped = stopped || evt.Type == "music_changed" && evt.Data["music"] == ""
+	}
+	if !stopped {
+		t.Errorf("expected the restart's music_changed event, got %v", result.Events)
+	}
+}
+
+func TestStep_RestartAsksForConfirmation(t *testing.T) {
+	e := New(testDefs())
+	e.Step("north")
+
+	e.Step("restart")
+	result := e.Step("no")
+	if e.State.Player.Location != "garden" || !outputContains(result.Output, "Okay, carrying on.") {
+		t.Errorf("declining should keep the game, got %v", result.Output)
+	}
+
+	e.Step("restart")
+	e.Step("yes")
+	if e.State.Player.Location != "hall" || e.State.TurnCount != 0 {
+		t.Errorf("expected a fresh game, got location %q, turn %d", e.State.Player.Location, e.State.TurnCount)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build questcore_debug
+
+package engine
+
+// checkDefs makes every step check that the game's definitions are
+// unchanged, failing the step with the error if not; see
+// state.Defs.CheckFrozen. It is on in builds with the questcore_debug tag.
+const checkDefs = true
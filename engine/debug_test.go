@@ -0,0 +1,48 @@
+//go:build questcore_debug
+
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/engine/trace"
+	"github.com/nathoo/questcore/types"
+)
+
+func TestStep_DefsChangedFailsStep(t *testing.T) {
+	// An effect that changes the game's definitions, as no effect should.
+	effs := effects.NewRegistry()
+	if err := effs.Add("test_rename_hall", func(s *types.State, defs *state.Defs, eff types.Effect, ctx effects.Context) ([]types.Event, []string) {
+		hall := defs.Rooms["hall"]
+		hall.Description = "A renamed hall."
+		defs.Rooms["hall"] = hall
+		return nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "sing_rename", Scope: "global",
+		When:    types.MatchCriteria{Verb: "sing"},
+		Effects: []types.Effect{{Type: "test_rename_hall"}},
+	})
+	defs.Freeze()
+	eng := New(defs, WithEffects(effs))
+	var mem trace.Memory
+	eng.Trace = &mem
+
+	result := eng.Step("sing")
+	if !result.Failed || !outputContains(result.Output, `Rooms["hall"]`) {
+		t.Errorf("the step should fail naming the changed definition: %v", result.Output)
+	}
+	var traced bool
+	for _, e := range mem.Take() {
+		traced = traced || strings.Contains(e.Message, "definitions changed after loading")
+	}
+	if !traced {
+		t.Error("the change should be traced")
+	}
+}
@@ -2,12 +2,15 @@
 package dialogue
 
 import (
+	"strings"
+
 	"github.com/nathoo/questcore/engine/rules"
 	"github.com/nathoo/questcore/engine/state"
 	"github.com/nathoo/questcore/types"
 )
 
-// AvailableTopics returns topic keys whose conditions are met.
+// AvailableTopics returns topic keys whose conditions are met, leaving out
+// once topics the player has already heard.
 func AvailableTopics(npcID string, s *types.State, defs *state.Defs) []string {
 	ent, ok := defs.Entities[npcID]
 	if !ok || ent.Topics == nil {
@@ -16,7 +19,7 @@ func AvailableTopics(npcID string, s *types.State, defs *state.Defs) []string {
 
 	var result []string
 	for key, topic := range ent.Topics {
-		if rules.EvalAllConditions(topic.Requires, s, defs) {
+		if available(npcID, key, topic, s, defs) {
 			result = append(result, key)
 		}
 	}
@@ -36,9 +39,53 @@ func SelectTopic(npcID, topicKey string, s *types.State, defs *state.Defs) (stri
 		return "", nil
 	}
 
-	if !rules.EvalAllConditions(topic.Requires, s, defs) {
+	if !available(npcID, topicKey, topic, s, defs) {
 		return "", nil
 	}
 
 	return topic.Text, topic.Effects
 }
+
+// Label returns the name a topic is listed by: its label, or else its key.
+func Label(npcID, topicKey string, defs *state.Defs) string {
+	if label := defs.Entities[npcID].Topics[topicKey].Label; label != "" {
+		return label
+	}
+	return topicKey
+}
+
+// FindTopic returns the key of the NPC's topic the player named, by key or
+// by label, or "" if there is none. Labels match ignoring case and
+// articles, which the parser strips from what the player types.
+func FindTopic(npcID, name string, defs *state.Defs) string {
+	topics := defs.Entities[npcID].Topics
+	if _, ok := topics[name]; ok {
+		return name
+	}
+	for key, topic := range topics {
+		if topic.Label != "" && withoutArticles(topic.Label) == withoutArticles(name) {
+			return key
+		}
+	}
+	return ""
+}
+
+// withoutArticles lower-cases s and drops the words "the", "a" and "an".
+func withoutArticles(s string) string {
+	var words []string
+	for _, w := range strings.Fields(strings.ToLower(s)) {
+		if w != "the" && w != "a" && w != "an" {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// available reports whether a topic's conditions are met and, for a once
+// topic, whether the player has yet to hear it.
+func available(npcID, key string, topic types.TopicDef, s *types.State, defs *state.Defs) bool {
+	if topic.Once && state.Heard(s, npcID, key) {
+		return false
+	}
+	return rules.EvalAllConditions(topic.Requires, s, defs)
+}
@@ -161,3 +161,44 @@ func TestAvailableTopics_UnknownEntity(t *testing.T) {
 		t.Errorf("expected nil for unknown entity, got %v", topics)
 	}
 }
+
+func TestAvailableTopics_OnceHiddenAfterHeard(t *testing.T) {
+	defs := testDefs()
+	barkeep := defs.Entities["barkeep"]
+	greeting := barkeep.Topics["greeting"]
+	greeting.Once = true
+	barkeep.Topics["greeting"] = greeting
+	s := state.NewState(defs)
+
+	state.MarkHeard(s, "barkeep", "greeting")
+	if topics := AvailableTopics("barkeep", s, defs); len(topics) != 0 {
+		t.Errorf("expected no topics once greeting is heard, got %v", topics)
+	}
+	if text, _ := SelectTopic("barkeep", "greeting", s, defs); text != "" {
+		t.Errorf("expected heard once topic refused, got %q", text)
+	}
+}
+
+func TestFindTopic_ByKeyOrLabel(t *testing.T) {
+	defs := testDefs()
+	barkeep := defs.Entities["barkeep"]
+	rumors := barkeep.Topics["rumors"]
+	rumors.Label = "The Cave Rumors"
+	barkeep.Topics["rumors"] = rumors
+
+	if got := FindTopic("barkeep", "cave rumors", defs); got != "rumors" {
+		t.Errorf("FindTopic by label = %q, want rumors", got)
+	}
+	if got := FindTopic("barkeep", "greeting", defs); got != "greeting" {
+		t.Errorf("FindTopic by key = %q, want greeting", got)
+	}
+	if got := FindTopic("barkeep", "weather", defs); got != "" {
+		t.Errorf("FindTopic unknown = %q, want empty", got)
+	}
+	if got := Label("barkeep", "rumors", defs); got != "The Cave Rumors" {
+		t.Errorf("Label = %q, want The Cave Rumors", got)
+	}
+	if got := Label("barkeep", "greeting", defs); got != "greeting" {
+		t.Errorf("Label without a label = %q, want the key", got)
+	}
+}
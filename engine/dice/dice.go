@@ -0,0 +1,102 @@
+// Package dice parses and rolls dice expressions such as "1d6+2": a number
+// of dice, each with a number of sides, plus or minus a fixed bonus.
+package dice
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxDice is the most dice an expression may roll at once.
+const MaxDice = 100
+
+// Expr is a parsed dice expression. Count dice of Sides sides are rolled
+// and Bonus added; an expression with no dice, such as "3", is a constant.
+type Expr struct {
+	Count int
+	Sides int
+	Bonus int
+}
+
+// Parse parses s as a dice expression: "NdS", "dS" (one die), either
+// followed by "+B" or "-B", or a plain number. Spaces are ignored and the
+// "d" may be capitalised.
+func Parse(s string) (Expr, error) {
+	text := strings.ToLower(strings.ReplaceAll(s, " ", ""))
+	if text == "" {
+		return Expr{}, fmt.Errorf("dice expression is empty")
+	}
+
+	var e Expr
+	dice := text
+	if i := strings.IndexAny(text, "+-"); i > 0 {
+		dice = text[:i]
+		n, err := strconv.Atoi(text[i+1:])
+		if err != nil || n < 0 || strings.ContainsAny(text[i+1:], "+-") {
+			return Expr{}, fmt.Errorf("dice expression %q: bad bonus %q", s, text[i:])
+		}
+		e.Bonus = n
+		if text[i] == '-' {
+			e.Bonus = -n
+		}
+	}
+
+	count, sides, ok := strings.Cut(dice, "d")
+	if !ok {
+		n, err := strconv.Atoi(dice)
+		if err != nil || n < 0 || dice != text {
+			return Expr{}, fmt.Errorf("dice expression %q: want NdS, NdS+B or a number", s)
+		}
+		return Expr{Bonus: n}, nil
+	}
+	e.Count = 1
+	if count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 || n > MaxDice {
+			return Expr{}, fmt.Errorf("dice expression %q: the number of dice must be 1 to %d", s, MaxDice)
+		}
+		e.Count = n
+	}
+	n, err := strconv.Atoi(sides)
+	if err != nil || n < 1 {
+		return Expr{}, fmt.Errorf("dice expression %q: dice need at least one side", s)
+	}
+	e.Sides = n
+	return e, nil
+}
+
+// String returns e in the form Parse reads, such as "2d6+1".
+func (e Expr) String() string {
+	if e.Count == 0 {
+		return strconv.Itoa(e.Bonus)
+	}
+	s := fmt.Sprintf("%dd%d", e.Count, e.Sides)
+	switch {
+	case e.Bonus > 0:
+		s += "+" + strconv.Itoa(e.Bonus)
+	case e.Bonus < 0:
+		s += strconv.Itoa(e.Bonus)
+	}
+	return s
+}
+
+// Min returns the least total e can roll.
+func (e Expr) Min() int {
+	return e.Count + e.Bonus
+}
+
+// Max returns the greatest total e can roll.
+func (e Expr) Max() int {
+	return e.Count*e.Sides + e.Bonus
+}
+
+// Roll rolls e, calling roll for each die with its number of sides, and
+// returns the total, bonus included.
+func (e Expr) Roll(roll func(sides int) int) int {
+	total := e.Bonus
+	for i := 0; i < e.Count; i++ {
+		total += roll(e.Sides)
+	}
+	return total
+}
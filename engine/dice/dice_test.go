@@ -0,0 +1,53 @@
+package dice
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Expr
+		str  string
+	}{
+		{"1d6", Expr{Count: 1, Sides: 6}, "1d6"},
+		{"d8", Expr{Count: 1, Sides: 8}, "1d8"},
+		{"2d4+3", Expr{Count: 2, Sides: 4, Bonus: 3}, "2d4+3"},
+		{"1D6 - 1", Expr{Count: 1, Sides: 6, Bonus: -1}, "1d6-1"},
+		{"5", Expr{Bonus: 5}, "5"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+		if got.String() != tt.str {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got.String(), tt.str)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, in := range []string{"", "d", "0d6", "1d0", "1d6+", "1d6+x", "x", "-3", "101d6", "1d6+2+1", "2+1"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) should fail", in)
+		}
+	}
+}
+
+func TestRoll(t *testing.T) {
+	e := Expr{Count: 3, Sides: 6, Bonus: 2}
+	var sides []int
+	got := e.Roll(func(n int) int {
+		sides = append(sides, n)
+		return 4
+	})
+	if got != 14 {
+		t.Errorf("Roll = %d, want 14", got)
+	}
+	if len(sides) != 3 || sides[0] != 6 {
+		t.Errorf("rolled %v, want three d6", sides)
+	}
+}
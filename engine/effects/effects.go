@@ -4,8 +4,13 @@ package effects
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/nathoo/questcore/engine/dice"
+	"github.com/nathoo/questcore/engine/messages"
+	"github.com/nathoo/questcore/engine/rules"
 	"github.com/nathoo/questcore/engine/state"
 	"github.com/nathoo/questcore/types"
 )
@@ -15,226 +20,1154 @@ type Context struct {
 	Verb     string
 	ObjectID string
 	TargetID string
-	Actor    string // "player" or entity ID of the acting combatant
+	Actor    string   // "player" or entity ID of the acting combatant
+	Strict   bool     // stop at effects that reference unknown entities or rooms, not just skip them
+	Custom   Registry // effect types the program adds for its games (nil = none)
+
+	// Roll draws a die of the given sides for an amount written as a dice
+	// expression, such as Damage("player", "2d4+1"); purpose says what the
+	// roll decides, for the trace. The engine draws from its RNG. If Roll
+	// is nil, each die rolls its average, rounded down.
+	Roll func(purpose string, sides int) int
 }
 
 // Apply applies a list of effects to the game state, mutating it.
-// Returns events emitted and output text collected.
+// Returns events emitted and output text collected. Each event records the
+// acting character ("actor") and, unless it names one already, the room the
+// player was in when it happened ("room"), so NPCs there can react to it.
 func Apply(s *types.State, defs *state.Defs, effects []types.Effect, ctx Context) ([]types.Event, []string) {
 	var events []types.Event
 	var output []string
 
 	for _, eff := range effects {
-		switch eff.Type {
-		case "say":
-			text, _ := eff.Params["text"].(string)
-			text = interpolate(text, s, defs, ctx)
-			output = append(output, text)
-
-		case "give_item":
-			item, _ := eff.Params["item"].(string)
-			item = resolveTemplate(item, ctx)
-			s.Player.Inventory = append(s.Player.Inventory, item)
-			// Remove from world by setting location to empty.
-			ensureEntityState(s, item)
-			es := s.Entities[item]
-			es.Location = " " // sentinel: "nowhere" (non-empty to override base)
-			s.Entities[item] = es
+		here, emitted := s.Player.Location, len(events)
+		if err := checkRefs(eff, defs, ctx); err != nil {
 			events = append(events, types.Event{
-				Type: "item_taken",
-				Data: map[string]any{"item": item},
+				Type: "invalid_reference",
+				Data: map[string]any{"effect": eff.Type, "message": err.Error()},
 			})
+			if ctx.Strict {
+				output = append(output, "Error: "+err.Error())
+				return events, output
+			}
+			continue // the rest of the list still applies
+		}
 
-		case "remove_item":
-			item, _ := eff.Params["item"].(string)
-			item = resolveTemplate(item, ctx)
-			s.Player.Inventory = removeFromSlice(s.Player.Inventory, item)
-			events = append(events, types.Event{
-				Type: "item_dropped",
-				Data: map[string]any{"item": item},
-			})
+		if eff.Type == "stop" {
+			return events, output
+		}
+		apply, ok := lookup(eff.Type, ctx.Custom)
+		if !ok {
+			continue // unknown effect type: ignore silently
+		}
+		evs, out := apply(s, defs, eff, ctx)
+		events = append(events, evs...)
+		output = append(output, out...)
+		locate(events[emitted:], here, ctx.Actor)
+	}
 
-		case "set_flag":
-			flag, _ := eff.Params["flag"].(string)
-			value, _ := eff.Params["value"].(bool)
-			s.Flags[flag] = value
-			events = append(events, types.Event{
-				Type: "flag_changed",
-				Data: map[string]any{"flag": flag, "value": value},
-			})
+	return events, output
+}
 
-		case "inc_counter":
-			counter, _ := eff.Params["counter"].(string)
-			amount := toInt(eff.Params["amount"])
-			s.Counters[counter] += amount
-
-		case "set_counter":
-			counter, _ := eff.Params["counter"].(string)
-			value := toInt(eff.Params["value"])
-			s.Counters[counter] = value
-
-		case "set_prop":
-			entity, _ := eff.Params["entity"].(string)
-			prop, _ := eff.Params["prop"].(string)
-			value := eff.Params["value"]
-			ensureEntityState(s, entity)
-			es := s.Entities[entity]
-			if es.Props == nil {
-				es.Props = map[string]any{}
-			}
-			es.Props[prop] = value
-			s.Entities[entity] = es
-
-		case "move_entity":
-			entity, _ := eff.Params["entity"].(string)
-			room, _ := eff.Params["room"].(string)
-			ensureEntityState(s, entity)
-			es := s.Entities[entity]
-			es.Location = room
-			s.Entities[entity] = es
-			events = append(events, types.Event{
-				Type: "entity_moved",
-				Data: map[string]any{"entity": entity, "room": room},
-			})
+// handlers holds the function applying each built-in effect type but
+// stop, where Apply itself stops.
+var handlers = map[string]Handler{
+	"say":                applySay,
+	"notify":             applyNotify,
+	"give_item":          applyGiveItem,
+	"remove_item":        applyRemoveItem,
+	"give_to":            applyGiveTo,
+	"show_item":          applyShowItem,
+	"transfer_item":      applyTransferItem,
+	"notice_theft":       applyNoticeTheft,
+	"wear_item":          applyWearItem,
+	"unwear_item":        applyUnwearItem,
+	"consume_item":       applyConsumeItem,
+	"enter_vehicle":      applyEnterVehicle,
+	"leave_vehicle":      applyLeaveVehicle,
+	"attach":             applyAttach,
+	"unlock":             applyUnlock,
+	"lock":               applyLock,
+	"fill_vessel":        applyFillVessel,
+	"pour_liquid":        applyPourLiquid,
+	"drink_liquid":       applyDrinkLiquid,
+	"detach":             applyDetach,
+	"set_flag":           applySetFlag,
+	"inc_counter":        applyIncCounter,
+	"dec_counter":        applyDecCounter,
+	"mul_counter":        applyMulCounter,
+	"set_counter":        applySetCounter,
+	"set_prop":           applySetProp,
+	"inc_prop":           applyIncProp,
+	"dec_prop":           applyDecProp,
+	"move_entity":        applyMoveEntity,
+	"move_player":        applyMovePlayer,
+	"switch_player":      applySwitchPlayer,
+	"open_exit":          applyOpenExit,
+	"close_exit":         applyCloseExit,
+	"cutaway":            applyCutaway,
+	"emit_event":         applyEmitEvent,
+	"ask":                applyAsk,
+	"use_attempt":        applyUseAttempt,
+	"close_question":     applyCloseQuestion,
+	"set_verbosity":      applySetVerbosity,
+	"start_dialogue":     applyStartDialogue,
+	"mark_fired":         applyMarkFired,
+	"mark_examined":      applyMarkExamined,
+	"mark_heard":         applyMarkHeard,
+	"mark_visited":       applyMarkVisited,
+	"set_defending":      applySetDefending,
+	"start_combat":       applyStartCombat,
+	"end_combat":         applyEndCombat,
+	"damage":             applyDamage,
+	"heal":               applyHeal,
+	"gain_xp":            applyGainXP,
+	"set_stat":           applySetStat,
+	"end_game":           applyEndGame,
+	"advance_time":       applyAdvanceTime,
+	"play_sound":         applyPlaySound,
+	"play_music":         applyPlayMusic,
+	"unlock_achievement": applyUnlockAchievement,
+	"learn_fact":         applyLearnFact,
+	"continue":           applyNothing,
+}
 
-		case "move_player":
-			room, _ := eff.Params["room"].(string)
-			s.Player.Location = room
-			events = append(events, types.Event{
-				Type: "room_entered",
-				Data: map[string]any{"room": room},
-			})
+// applyNothing applies continue, a marker read by the rules pipeline.
+func applyNothing(*types.State, *state.Defs, types.Effect, Context) ([]types.Event, []string) {
+	return nil, nil
+}
 
-		case "open_exit":
-			room, _ := eff.Params["room"].(string)
-			direction, _ := eff.Params["direction"].(string)
-			target, _ := eff.Params["target"].(string)
-			key := "room:" + room
-			ensureEntityState(s, key)
-			es := s.Entities[key]
-			if es.Props == nil {
-				es.Props = map[string]any{}
-			}
-			es.Props["exit:"+direction] = target
-			s.Entities[key] = es
-
-		case "close_exit":
-			room, _ := eff.Params["room"].(string)
-			direction, _ := eff.Params["direction"].(string)
-			key := "room:" + room
-			ensureEntityState(s, key)
-			es := s.Entities[key]
-			if es.Props == nil {
-				es.Props = map[string]any{}
-			}
-			es.Props["exit:"+direction] = ""
-			s.Entities[key] = es
+func applySay(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	text, _ := eff.Params["text"].(string)
+	text = interpolate(text, s, defs, ctx)
+	output = append(output, text)
+	return events, output
+}
 
-		case "emit_event":
-			event, _ := eff.Params["event"].(string)
-			events = append(events, types.Event{
-				Type: event,
-				Data: map[string]any{},
-			})
+func applyNotify(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	text, _ := eff.Params["text"].(string)
+	events = append(events, types.Event{
+		Type: "notification",
+		Data: map[string]any{"text": interpolate(text, s, defs, ctx)},
+	})
+	return events, output
+}
 
-		case "start_dialogue":
-			// Stub — dialogue system is layer 9.
-			npc, _ := eff.Params["npc"].(string)
-			events = append(events, types.Event{
-				Type: "dialogue_started",
-				Data: map[string]any{"npc": npc},
-			})
+func applyGiveItem(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	item, _ := eff.Params["item"].(string)
+	item = resolveTemplate(item, ctx)
+	if state.IsStackable(defs, item) {
+		return giveUnits(s, defs, item, toInt(eff.Params["amount"]))
+	}
+	if !state.CanCarry(s, defs, item) {
+		output = append(output, messages.Text(defs.Messages, "too_much"))
+		return events, output
+	}
+	s.Player.Inventory = append(s.Player.Inventory, item)
+	// Remove from world by setting location to empty.
+	state.SetEntityLocation(s, defs, item, " ") // sentinel: "nowhere" (non-empty to override base)
+	events = append(events, types.Event{
+		Type: "item_taken",
+		Data: map[string]any{"item": item},
+	})
+	return events, output
+}
+
+// giveUnits gives the player amount units of a stackable item, taken from
+// its stack in the world as far as that goes; amount 0 means the whole
+// stack. The stack leaves the world once it is used up.
+func giveUnits(s *types.State, defs *state.Defs, item string, amount int) (events []types.Event, output []string) {
+	inWorld := state.Quantity(s, defs, item)
+	if amount <= 0 {
+		amount = max(inWorld, 1)
+	}
+	if !state.CanCarryCount(s, defs, item, amount) {
+		output = append(output, messages.Text(defs.Messages, "too_much"))
+		return events, output
+	}
+	if left := max(inWorld-amount, 0); left > 0 {
+		state.SetStat(s, item, "quantity", left)
+	} else {
+		state.SetStat(s, item, "quantity", 0)
+		state.SetEntityLocation(s, defs, item, " ")
+	}
+	if !state.HasItem(s, item) {
+		s.Player.Inventory = append(s.Player.Inventory, item)
+	}
+	if s.Player.Quantities == nil {
+		s.Player.Quantities = map[string]int{}
+	}
+	s.Player.Quantities[item] += amount
+	events = append(events, types.Event{
+		Type: "item_taken",
+		Data: map[string]any{"item": item, "amount": amount},
+	})
+	return events, output
+}
+
+func applyRemoveItem(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	item, _ := eff.Params["item"].(string)
+	item = resolveTemplate(item, ctx)
+	if state.IsStackable(defs, item) {
+		return removeUnits(s, defs, item, toInt(eff.Params["amount"]))
+	}
+	s.Player.Inventory = removeFromSlice(s.Player.Inventory, item)
+	s.Player.Worn = removeFromSlice(s.Player.Worn, item)
+	events = append(events, types.Event{
+		Type: "item_dropped",
+		Data: map[string]any{"item": item},
+	})
+	return events, output
+}
+
+// removeUnits takes amount units of a stackable item from the player, all
+// of them if amount is 0 or more than they carry.
+func removeUnits(s *types.State, defs *state.Defs, item string, amount int) (events []types.Event, output []string) {
+	held := state.HeldCount(s, defs, item)
+	if held == 0 {
+		return events, output
+	}
+	if amount <= 0 || amount > held {
+		amount = held
+	}
+	if held -= amount; held > 0 {
+		s.Player.Quantities[item] = held
+	} else {
+		delete(s.Player.Quantities, item)
+		s.Player.Inventory = removeFromSlice(s.Player.Inventory, item)
+		s.Player.Worn = removeFromSlice(s.Player.Worn, item)
+	}
+	events = append(events, types.Event{
+		Type: "item_dropped",
+		Data: map[string]any{"item": item, "amount": amount},
+	})
+	return events, output
+}
+
+// applyGiveTo hands an item to an NPC, who then carries it: its location
+// is the NPC.
+func applyGiveTo(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	item, _ := eff.Params["item"].(string)
+	item = resolveTemplate(item, ctx)
+	npc, _ := eff.Params["npc"].(string)
+	npc = resolveTemplate(npc, ctx)
+	s.Player.Inventory = removeFromSlice(s.Player.Inventory, item)
+	s.Player.Worn = removeFromSlice(s.Player.Worn, item)
+	state.SetEntityLocation(s, defs, item, npc)
+	events = append(events, types.Event{
+		Type: "item_given",
+		Data: map[string]any{"item": item, "npc": npc},
+	})
+	return events, output
+}
+
+// applyTransferItem moves an item from one holder to another, where either
+// may be "player" or an entity that carries things, such as an NPC. It does
+// nothing unless from holds the item.
+func applyTransferItem(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	item, _ := eff.Params["item"].(string)
+	item = resolveTemplate(item, ctx)
+	from, _ := eff.Params["from"].(string)
+	from = resolveTemplate(from, ctx)
+	to, _ := eff.Params["to"].(string)
+	to = resolveTemplate(to, ctx)
+
+	if from == "player" && !state.HasItem(s, item) || from != "player" && state.EntityLocation(s, defs, item) != from {
+		return events, output
+	}
+	if to == "player" && !state.CanCarry(s, defs, item) {
+		output = append(output, messages.Text(defs.Messages, "too_much"))
+		return events, output
+	}
+	if from == "player" {
+		s.Player.Inventory = removeFromSlice(s.Player.Inventory, item)
+		s.Player.Worn = removeFromSlice(s.Player.Worn, item)
+	}
+	if to == "player" {
+		s.Player.Inventory = append(s.Player.Inventory, item)
+		state.SetEntityLocation(s, defs, item, " ")
+	} else {
+		state.SetEntityLocation(s, defs, item, to)
+	}
+	events = append(events, types.Event{
+		Type: "item_transferred",
+		Data: map[string]any{"item": item, "from": from, "to": to},
+	})
+	return events, output
+}
+
+// applyNoticeTheft changes nothing; it raises theft_noticed for handlers
+// when an NPC catches the player stealing from it.
+func applyNoticeTheft(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	item, _ := eff.Params["item"].(string)
+	npc, _ := eff.Params["npc"].(string)
+	events = append(events, types.Event{
+		Type: "theft_noticed",
+		Data: map[string]any{"item": item, "npc": npc},
+	})
+	return events, output
+}
+
+// applyShowItem changes nothing; it raises item_shown for handlers.
+func applyShowItem(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	item, _ := eff.Params["item"].(string)
+	npc, _ := eff.Params["npc"].(string)
+	events = append(events, types.Event{
+		Type: "item_shown",
+		Data: map[string]any{"item": item, "npc": npc},
+	})
+	return events, output
+}
+
+func applyWearItem(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	item, _ := eff.Params["item"].(string)
+	item = resolveTemplate(item, ctx)
+	if !state.HasItem(s, item) || state.IsWorn(s, item) {
+		return events, output // only carried items can be worn, and only once
+	}
+	s.Player.Worn = append(s.Player.Worn, item)
+	events = append(events, types.Event{
+		Type: "item_worn",
+		Data: map[string]any{"item": item},
+	})
+	return events, output
+}
+
+func applyUnwearItem(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	item, _ := eff.Params["item"].(string)
+	item = resolveTemplate(item, ctx)
+	if !state.IsWorn(s, item) {
+		return events, output
+	}
+	s.Player.Worn = removeFromSlice(s.Player.Worn, item)
+	events = append(events, types.Event{
+		Type: "item_unworn",
+		Data: map[string]any{"item": item},
+	})
+	return events, output
+}
+
+// applyEnterVehicle puts the player in an enterable entity, which then
+// moves with them.
+func applyEnterVehicle(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	entity, _ := eff.Params["entity"].(string)
+	entity = resolveTemplate(entity, ctx)
+	if s.Player.Vehicle == entity {
+		return events, output
+	}
+	if s.Player.Vehicle != "" {
+		events, _ = applyLeaveVehicle(s, defs, eff, ctx)
+	}
+	s.Player.Vehicle = entity
+	events = append(events, types.Event{
+		Type: "vehicle_entered",
+		Data: map[string]any{"entity": entity},
+	})
+	return events, output
+}
+
+func applyLeaveVehicle(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	entity := s.Player.Vehicle
+	if entity == "" {
+		return events, output
+	}
+	s.Player.Vehicle = ""
+	events = append(events, types.Event{
+		Type: "vehicle_left",
+		Data: map[string]any{"entity": entity},
+	})
+	return events, output
+}
+
+// applyAttach ties entity to another, untying it from what it was tied to.
+func applyAttach(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	entity, _ := eff.Params["entity"].(string)
+	to, _ := eff.Params["to"].(string)
+	entity, to = resolveTemplate(entity, ctx), resolveTemplate(to, ctx)
+	if entity == to || s.Attached[entity] == to {
+		return events, output
+	}
+	if s.Attached[entity] != "" {
+		events, _ = applyDetach(s, defs, eff, ctx)
+	}
+	if s.Attached == nil {
+		s.Attached = map[string]string{}
+	}
+	s.Attached[entity] = to
+	events = append(events, types.Event{
+		Type: "entity_attached",
+		Data: map[string]any{"entity": entity, "to": to},
+	})
+	return events, output
+}
+
+func applyUnlock(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	return setLocked(s, defs, eff, ctx, false), output
+}
+
+func applyLock(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	return setLocked(s, defs, eff, ctx, true), output
+}
+
+// setLocked locks or unlocks the effect's entity, raising locked or
+// unlocked with the key used, if any. An entity already so is left alone.
+func setLocked(s *types.State, defs *state.Defs, eff types.Effect, ctx Context, locked bool) []types.Event {
+	entity, _ := eff.Params["entity"].(string)
+	entity = resolveTemplate(entity, ctx)
+	if state.IsLocked(s, defs, entity) == locked {
+		return nil
+	}
+	ensureEntityState(s, entity)
+	es := s.Entities[entity]
+	if es.Props == nil {
+		es.Props = map[string]any{}
+	}
+	es.Props["locked"] = locked
+	s.Entities[entity] = es
+	key, _ := eff.Params["key"].(string)
+	evt := "unlocked"
+	if locked {
+		evt = "locked"
+	}
+	return []types.Event{{Type: evt, Data: map[string]any{"entity": entity, "key": resolveTemplate(key, ctx)}}}
+}
+
+// applyDetach unties entity from what it is tied to.
+func applyDetach(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	entity, _ := eff.Params["entity"].(string)
+	entity = resolveTemplate(entity, ctx)
+	from := s.Attached[entity]
+	if from == "" {
+		return events, output
+	}
+	delete(s.Attached, entity)
+	events = append(events, types.Event{
+		Type: "entity_detached",
+		Data: map[string]any{"entity": entity, "from": from},
+	})
+	return events, output
+}
+
+// applyFillVessel fills a vessel with amount units of a liquid, or as many
+// as it has room for when amount is 0. A vessel holding another liquid is
+// emptied first.
+func applyFillVessel(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	vessel, _ := eff.Params["vessel"].(string)
+	liquid, _ := eff.Params["liquid"].(string)
+	vessel, liquid = resolveTemplate(vessel, ctx), resolveTemplate(liquid, ctx)
+	held, units := state.VesselContents(s, defs, vessel)
+	if held != liquid {
+		units = 0
+	}
+	room := state.VesselCapacity(s, defs, vessel) - units
+	amount := toInt(eff.Params["amount"])
+	if amount <= 0 || amount > room {
+		amount = room
+	}
+	if amount <= 0 {
+		return events, output
+	}
+	setVessel(s, vessel, liquid, units+amount)
+	events = append(events, types.Event{
+		Type: "vessel_filled",
+		Data: map[string]any{"vessel": vessel, "liquid": liquid, "amount": amount},
+	})
+	return events, output
+}
+
+// applyPourLiquid pours amount units out of a vessel, or all of them when
+// amount is 0, onto the effect's target, if any.
+func applyPourLiquid(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	vessel, _ := eff.Params["vessel"].(string)
+	target, _ := eff.Params["target"].(string)
+	vessel, target = resolveTemplate(vessel, ctx), resolveTemplate(target, ctx)
+	liquid, amount := drain(s, defs, vessel, toInt(eff.Params["amount"]))
+	if amount == 0 {
+		return events, output
+	}
+	events = append(events, types.Event{
+		Type: "liquid_poured",
+		Data: map[string]any{"vessel": vessel, "liquid": liquid, "amount": amount, "target": target},
+	})
+	return events, output
+}
+
+// applyDrinkLiquid drinks amount units from a vessel, or one when amount
+// is 0. A liquid source, such as a fountain, never runs dry.
+func applyDrinkLiquid(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	vessel, _ := eff.Params["vessel"].(string)
+	vessel = resolveTemplate(vessel, ctx)
+	amount := max(toInt(eff.Params["amount"]), 1)
+	liquid, _ := state.GetEntityProp(s, defs, vessel, "liquid_source")
+	if liquid == nil {
+		liquid, amount = drain(s, defs, vessel, amount)
+	}
+	if amount == 0 {
+		return events, output
+	}
+	events = append(events, types.Event{
+		Type: "liquid_drunk",
+		Data: map[string]any{"vessel": vessel, "liquid": liquid, "amount": amount},
+	})
+	return events, output
+}
+
+// drain takes amount units of liquid out of a vessel, or all of them when
+// amount is 0, and returns the liquid and how much was taken.
+func drain(s *types.State, defs *state.Defs, vessel string, amount int) (string, int) {
+	liquid, units := state.VesselContents(s, defs, vessel)
+	if amount <= 0 || amount > units {
+		amount = units
+	}
+	if amount > 0 {
+		setVessel(s, vessel, liquid, units-amount)
+	}
+	return liquid, amount
+}
+
+// setVessel records that a vessel holds units of liquid, or is empty when
+// units is 0.
+func setVessel(s *types.State, vessel, liquid string, units int) {
+	if units <= 0 {
+		liquid, units = "", 0
+	}
+	ensureEntityState(s, vessel)
+	es := s.Entities[vessel]
+	if es.Props == nil {
+		es.Props = map[string]any{}
+	}
+	es.Props["filled_with"] = liquid
+	es.Props["units"] = units
+	s.Entities[vessel] = es
+}
+
+func applyConsumeItem(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	item, _ := eff.Params["item"].(string)
+	item = resolveTemplate(item, ctx)
+	if state.IsStackable(defs, item) {
+		// One unit is used up; the rest of the stack stays where it is.
+		removeUnits(s, defs, item, 1)
+	} else {
+		s.Player.Inventory = removeFromSlice(s.Player.Inventory, item)
+		s.Player.Worn = removeFromSlice(s.Player.Worn, item)
+		// Consumed items leave the world entirely.
+		state.SetEntityLocation(s, defs, item, " ")
+	}
+	events = append(events, types.Event{
+		Type: "item_consumed",
+		Data: map[string]any{"item": item, "verb": ctx.Verb},
+	})
+	return events, output
+}
+
+func applySetFlag(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	flag, _ := eff.Params["flag"].(string)
+	value, _ := eff.Params["value"].(bool)
+	s.Flags[flag] = value
+	events = append(events, types.Event{
+		Type: "flag_changed",
+		Data: map[string]any{"flag": flag, "value": value},
+	})
+	return events, output
+}
+
+func applyIncCounter(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	counter, _ := eff.Params["counter"].(string)
+	amount := roll(eff.Params["amount"], ctx, "counter: "+counter)
+	s.Counters[counter] += amount
+	return events, output
+}
+
+func applyDecCounter(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	counter, _ := eff.Params["counter"].(string)
+	amount := roll(eff.Params["amount"], ctx, "counter: "+counter)
+	s.Counters[counter] -= amount
+	return events, output
+}
+
+func applyMulCounter(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	counter, _ := eff.Params["counter"].(string)
+	s.Counters[counter] *= toInt(eff.Params["factor"])
+	return events, output
+}
+
+func applySetCounter(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	counter, _ := eff.Params["counter"].(string)
+	value := roll(eff.Params["value"], ctx, "counter: "+counter)
+	s.Counters[counter] = value
+	return events, output
+}
+
+func applySetProp(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	entity, _ := eff.Params["entity"].(string)
+	prop, _ := eff.Params["prop"].(string)
+	value := eff.Params["value"]
+	ensureEntityState(s, entity)
+	es := s.Entities[entity]
+	if es.Props == nil {
+		es.Props = map[string]any{}
+	}
+	es.Props[prop] = value
+	s.Entities[entity] = es
+	return events, output
+}
+
+func applyIncProp(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	return addToProp(s, defs, eff, ctx, 1), output
+}
+
+func applyDecProp(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	return addToProp(s, defs, eff, ctx, -1), output
+}
+
+// addToProp adds the effect's amount, times sign, to a numeric entity
+// property. A missing or non-numeric property counts as 0; the result is
+// always stored as an int.
+func addToProp(s *types.State, defs *state.Defs, eff types.Effect, ctx Context, sign int) []types.Event {
+	entity, _ := eff.Params["entity"].(string)
+	prop, _ := eff.Params["prop"].(string)
+	amount := roll(eff.Params["amount"], ctx, "prop: "+entity+"."+prop)
+	current, _ := state.GetEntityProp(s, defs, entity, prop)
+	ensureEntityState(s, entity)
+	es := s.Entities[entity]
+	if es.Props == nil {
+		es.Props = map[string]any{}
+	}
+	es.Props[prop] = toInt(current) + sign*amount
+	s.Entities[entity] = es
+	return nil
+}
+
+func applyMoveEntity(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	entity, _ := eff.Params["entity"].(string)
+	room, _ := eff.Params["room"].(string)
+	dest, capEvt := admit(s, defs, entity, room)
+	if capEvt != nil {
+		events = append(events, *capEvt)
+	}
+	if dest == "" && room != "" {
+		return events, output // room full, entity stays where it is
+	}
+	state.SetEntityLocation(s, defs, entity, dest)
+	events = append(events, types.Event{
+		Type: "entity_moved",
+		Data: map[string]any{"entity": entity, "room": dest},
+	})
+	return events, output
+}
 
-		case "set_defending":
-			s.Combat.Defending = true
-
-		case "start_combat":
-			enemyID, _ := eff.Params["enemy"].(string)
-			s.Combat.Active = true
-			s.Combat.EnemyID = enemyID
-			s.Combat.RoundCount = 0
-			s.Combat.Defending = false
-			s.Combat.PreviousLocation = s.Player.Location
-			// Initialize enemy runtime stats from base def if not already set.
-			initEnemyStats(s, defs, enemyID)
+func applyMovePlayer(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	room, _ := eff.Params["room"].(string)
+	s.Player.Location = room
+	// A player moved away from their vehicle, rather than in it, leaves it.
+	if v := s.Player.Vehicle; v != "" && state.EntityLocation(s, defs, v) != room {
+		events, _ = applyLeaveVehicle(s, defs, eff, ctx)
+	}
+	events = append(events, types.Event{
+		Type: "room_entered",
+		Data: map[string]any{"room": room},
+	})
+	return events, output
+}
+
+// applySwitchPlayer puts the player in control of another player
+// character. The one they leave waits where it is, with what it carries,
+// until switched back to.
+func applySwitchPlayer(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	id, _ := eff.Params["player"].(string)
+	next, ok := s.Players[id]
+	if !ok || id == s.ActivePlayer {
+		return events, output
+	}
+	from := s.ActivePlayer
+	s.Players[from] = s.Player
+	delete(s.Players, id)
+	s.Player = next
+	s.ActivePlayer = id
+	events = append(events, types.Event{
+		Type: "player_switched",
+		Data: map[string]any{"from": from, "to": id, "room": next.Location},
+	})
+	return events, output
+}
+
+func applyOpenExit(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	room, _ := eff.Params["room"].(string)
+	direction, _ := eff.Params["direction"].(string)
+	target, _ := eff.Params["target"].(string)
+	key := "room:" + room
+	ensureEntityState(s, key)
+	es := s.Entities[key]
+	if es.Props == nil {
+		es.Props = map[string]any{}
+	}
+	es.Props["exit:"+direction] = target
+	s.Entities[key] = es
+	return events, output
+}
+
+func applyCloseExit(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	room, _ := eff.Params["room"].(string)
+	direction, _ := eff.Params["direction"].(string)
+	key := "room:" + room
+	ensureEntityState(s, key)
+	es := s.Entities[key]
+	if es.Props == nil {
+		es.Props = map[string]any{}
+	}
+	es.Props["exit:"+direction] = ""
+	s.Entities[key] = es
+	return events, output
+}
+
+func applyCutaway(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	room, _ := eff.Params["room"].(string)
+	lines := toInt(eff.Params["lines"])
+	output = append(output, cutaway(s, defs, room, lines)...)
+	return events, output
+}
+
+func applyEmitEvent(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	event, _ := eff.Params["event"].(string)
+	events = append(events, types.Event{
+		Type: event,
+		Data: map[string]any{},
+	})
+	return events, output
+}
+
+func applyAsk(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	// The player's next input answers the question; see
+	// Engine.answerQuestion.
+	answer, _ := eff.Params["answer"].(string)
+	s.Pending = "answer:" + answer
+	delete(s.Attempts, answer)
+	return events, output
+}
+
+// applyUseAttempt counts a wrong answer to a question.
+func applyUseAttempt(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	answer, _ := eff.Params["answer"].(string)
+	if s.Attempts == nil {
+		s.Attempts = map[string]int{}
+	}
+	s.Attempts[answer]++
+	return events, output
+}
+
+// applyCloseQuestion ends a question, answered or out of attempts: the
+// player's next input is a command again.
+func applyCloseQuestion(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	answer, _ := eff.Params["answer"].(string)
+	s.Pending = ""
+	delete(s.Attempts, answer)
+	return events, output
+}
+
+// applySetVerbosity sets the player's description mode: "verbose", "brief"
+// or "superbrief".
+func applySetVerbosity(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	s.Verbosity, _ = eff.Params["mode"].(string)
+	return events, output
+}
+
+func applyStartDialogue(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	// Stub — dialogue system is layer 9.
+	npc, _ := eff.Params["npc"].(string)
+	events = append(events, types.Event{
+		Type: "dialogue_started",
+		Data: map[string]any{"npc": npc},
+	})
+	return events, output
+}
+
+func applyMarkFired(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	rule, _ := eff.Params["rule"].(string)
+	if s.Fired == nil {
+		s.Fired = map[string]bool{}
+	}
+	s.Fired[rule] = true
+	return events, output
+}
+
+// applyMarkExamined counts one more examine of an entity, which picks the
+// next of its descriptions.
+func applyMarkExamined(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	entity, _ := eff.Params["entity"].(string)
+	state.MarkExamined(s, entity)
+	return events, output
+}
+
+// applyMarkHeard records that the player has heard an NPC's topic.
+func applyMarkHeard(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	npc, _ := eff.Params["npc"].(string)
+	topic, _ := eff.Params["topic"].(string)
+	state.MarkHeard(s, npc, topic)
+	return events, output
+}
+
+// applyMarkVisited records that the player has been in a room, which
+// retires its first_description.
+func applyMarkVisited(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	room, _ := eff.Params["room"].(string)
+	state.MarkVisited(s, room)
+	return events, output
+}
+
+func applySetDefending(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	s.Combat.Defending = true
+	return events, output
+}
+
+func applyStartCombat(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	enemyID, _ := eff.Params["enemy"].(string)
+	s.Combat.Active = true
+	s.Combat.EnemyID = enemyID
+	s.Combat.RoundCount = 0
+	s.Combat.Defending = false
+	s.Combat.PreviousLocation = s.Player.Location
+	// Initialize enemy runtime stats from base def if not already set.
+	initEnemyStats(s, defs, enemyID)
+	// A new fight starts the enemy's behavior afresh, not partway through
+	// a sequence; see engine.EnemyTurn.
+	delete(s.Entities[enemyID].Props, "behavior_sequence")
+	delete(s.Entities[enemyID].Props, "behavior_step")
+	data := map[string]any{"enemy": enemyID, "initiative": "enemy", "surprise": false}
+	switch surprise, _ := eff.Params["surprise"].(bool); {
+	case surprise:
+		data["initiative"], data["surprise"] = "player", true
+	case hasSpeed(s, defs, enemyID):
+		player := speed(s, defs, "player") + roll("1d6", ctx, "initiative: player")
+		enemy := speed(s, defs, enemyID) + roll("1d6", ctx, "initiative: "+enemyID)
+		if player >= enemy {
+			data["initiative"] = "player"
+		}
+		data["player_roll"], data["enemy_roll"] = player, enemy
+	}
+	s.Combat.Initiative, _ = data["initiative"].(string)
+	s.Combat.Surprise, _ = data["surprise"].(bool)
+	events = append(events, types.Event{
+		Type: "combat_started",
+		Data: data,
+	})
+	return events, output
+}
+
+// hasSpeed reports whether the player or enemyID has a speed stat. Only
+// then is initiative rolled; otherwise the enemy acts first.
+func hasSpeed(s *types.State, defs *state.Defs, enemyID string) bool {
+	_, player := state.GetStat(s, defs, "player", "speed")
+	_, enemy := state.GetStat(s, defs, enemyID, "speed")
+	return player || enemy
+}
+
+// speed returns target's speed stat, or 0 if it has none.
+func speed(s *types.State, defs *state.Defs, target string) int {
+	v, _ := state.GetStat(s, defs, target, "speed")
+	return v
+}
+
+func applyEndCombat(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	s.Combat = types.CombatState{}
+	events = append(events, types.Event{
+		Type: "combat_ended",
+		Data: map[string]any{},
+	})
+	return events, output
+}
+
+func applyDamage(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	target, _ := eff.Params["target"].(string)
+	amount := max(0, roll(eff.Params["amount"], ctx, "damage: "+target))
+	remaining := damageTarget(s, defs, target, amount)
+	events = append(events, types.Event{
+		Type: "entity_damaged",
+		Data: map[string]any{"target": target, "amount": amount, "remaining": remaining},
+	})
+	// Check for death.
+	if remaining <= 0 {
+		if target == "player" {
+			enemyID := s.Combat.EnemyID // capture before clearing
+			s.Flags["game_over"] = true
+			s.Combat = types.CombatState{}
 			events = append(events, types.Event{
-				Type: "combat_started",
+				Type: "player_defeated",
 				Data: map[string]any{"enemy": enemyID},
 			})
-
-		case "end_combat":
+		} else {
+			// Enemy defeated.
+			ensureEntityState(s, target)
+			es := s.Entities[target]
+			if es.Props == nil {
+				es.Props = map[string]any{}
+			}
+			es.Props["alive"] = false
+			s.Entities[target] = es
+			// End combat when enemy is defeated.
 			s.Combat = types.CombatState{}
+			events = append(events, types.Event{
+				Type: "enemy_defeated",
+				Data: map[string]any{"enemy": target},
+			})
 			events = append(events, types.Event{
 				Type: "combat_ended",
 				Data: map[string]any{},
 			})
+		}
+	} else if target != "player" && s.Combat.Active && s.Combat.EnemyID == target {
+		evs, out := breakMorale(s, defs, target, remaining)
+		events = append(events, evs...)
+		output = append(output, out...)
+	}
+	return events, output
+}
 
-		case "damage":
-			target, _ := eff.Params["target"].(string)
-			amount := toInt(eff.Params["amount"])
-			remaining := applyDamage(s, defs, target, amount)
-			events = append(events, types.Event{
-				Type: "entity_damaged",
-				Data: map[string]any{"target": target, "amount": amount, "remaining": remaining},
-			})
-			// Check for death.
-			if remaining <= 0 {
-				if target == "player" {
-					enemyID := s.Combat.EnemyID // capture before clearing
-					s.Flags["game_over"] = true
-					s.Combat = types.CombatState{}
-					events = append(events, types.Event{
-						Type: "player_defeated",
-						Data: map[string]any{"enemy": enemyID},
-					})
-				} else {
-					// Enemy defeated.
-					ensureEntityState(s, target)
-					es := s.Entities[target]
-					if es.Props == nil {
-						es.Props = map[string]any{}
-					}
-					es.Props["alive"] = false
-					s.Entities[target] = es
-					// End combat when enemy is defeated.
-					s.Combat = types.CombatState{}
-					events = append(events, types.Event{
-						Type: "enemy_defeated",
-						Data: map[string]any{"enemy": target},
-					})
-					events = append(events, types.Event{
-						Type: "combat_ended",
-						Data: map[string]any{},
-					})
-				}
-			}
+// breakMorale ends the fight if enemyID, wounded down to hp, has lost its
+// nerve: at or below its morale stat it surrenders, staying where it is
+// with surrendered set, or flees the room if its on_break is "flee".
+func breakMorale(s *types.State, defs *state.Defs, enemyID string, hp int) (events []types.Event, output []string) {
+	morale, ok := state.GetStat(s, defs, enemyID, "morale")
+	if !ok || hp > morale {
+		return nil, nil
+	}
+	name := enemyID
+	if n, ok := state.GetEntityProp(s, defs, enemyID, "name"); ok {
+		if str, ok := n.(string); ok {
+			name = str
+		}
+	}
+	s.Combat = types.CombatState{}
+	onBreak, _ := state.GetEntityProp(s, defs, enemyID, "on_break")
+	fled := onBreak == "flee"
+	if fled {
+		state.SetEntityLocation(s, defs, enemyID, " ") // sentinel: "nowhere"
+		output = append(output, messages.Text(defs.Messages, "enemy_breaks", "enemy", name))
+	} else {
+		es := s.Entities[enemyID] // damageTarget has set its hp
+		es.Props["surrendered"] = true
+		s.Entities[enemyID] = es
+		output = append(output, messages.Text(defs.Messages, "enemy_surrenders", "enemy", name))
+	}
+	events = append(events,
+		types.Event{Type: "enemy_surrendered", Data: map[string]any{"enemy": enemyID, "fled": fled}},
+		types.Event{Type: "combat_ended", Data: map[string]any{}},
+	)
+	if fled {
+		events = append(events, types.Event{Type: "entity_moved", Data: map[string]any{"entity": enemyID, "room": ""}})
+	}
+	return events, output
+}
 
-		case "heal":
-			target, _ := eff.Params["target"].(string)
-			amount := toInt(eff.Params["amount"])
-			current := applyHeal(s, defs, target, amount)
-			events = append(events, types.Event{
-				Type: "entity_healed",
-				Data: map[string]any{"target": target, "amount": amount, "current": current},
-			})
+func applyHeal(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	target, _ := eff.Params["target"].(string)
+	amount := max(0, roll(eff.Params["amount"], ctx, "heal: "+target))
+	current := healTarget(s, defs, target, amount)
+	events = append(events, types.Event{
+		Type: "entity_healed",
+		Data: map[string]any{"target": target, "amount": amount, "current": current},
+	})
+	return events, output
+}
 
-		case "set_stat":
-			target, _ := eff.Params["target"].(string)
-			stat, _ := eff.Params["stat"].(string)
-			value := toInt(eff.Params["value"])
-			state.SetStat(s, target, stat, value)
+func applyGainXP(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	amount := toInt(eff.Params["amount"])
+	state.SetStat(s, "player", "xp", s.Player.Stats["xp"]+amount)
+	output = append(output, messages.Text(defs.Messages, "xp_gained", "amount", amount))
+	events = append(events, types.Event{
+		Type: "xp_gained",
+		Data: map[string]any{"amount": amount, "xp": s.Player.Stats["xp"]},
+	})
+	// Level up as many times as the new total allows.
+	for {
+		next, ok := state.NextLevel(s, defs)
+		if !ok || s.Player.Stats["xp"] < next.XP {
+			break
+		}
+		level := s.Player.Stats["level"]
+		if level < 1 {
+			level = 1
+		}
+		state.SetStat(s, "player", "level", level+1)
+		for _, stat := range sortedStats(next.Stats) {
+			state.SetStat(s, "player", stat, s.Player.Stats[stat]+next.Stats[stat])
+		}
+		output = append(output, messages.Text(defs.Messages, "level_up", "level", level+1))
+		events = append(events, types.Event{
+			Type: "player_leveled",
+			Data: map[string]any{"level": level + 1},
+		})
+	}
+	return events, output
+}
 
-		case "stop":
-			return events, output
+func applySetStat(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	target, _ := eff.Params["target"].(string)
+	stat, _ := eff.Params["stat"].(string)
+	value := toInt(eff.Params["value"])
+	state.SetStat(s, target, stat, value)
+	return events, output
+}
 
-		default:
-			// Unknown effect type — ignore silently.
-		}
+func applyEndGame(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	ending, _ := eff.Params["ending"].(string)
+	s.Ending = ending
+	s.Flags["game_over"] = true
+	events = append(events, types.Event{
+		Type: "game_ended",
+		Data: map[string]any{"ending": ending},
+	})
+	return events, output
+}
+
+func applyAdvanceTime(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	s.Clock += toInt(eff.Params["minutes"])
+	return events, output
+}
+
+func applyPlaySound(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	sound, _ := eff.Params["sound"].(string)
+	events = append(events, types.Event{
+		Type: "sound_played",
+		Data: map[string]any{"sound": sound},
+	})
+	return events, output
+}
+
+func applyPlayMusic(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	music, _ := eff.Params["music"].(string)
+	if music == s.Music {
+		return events, output // already playing; don't restart the track
 	}
+	s.Music = music
+	events = append(events, types.Event{
+		Type: "music_changed",
+		Data: map[string]any{"music": music},
+	})
+	return events, output
+}
+
+func applyUnlockAchievement(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	id, _ := eff.Params["achievement"].(string)
+	if s.Achievements[id] {
+		return events, output // already unlocked, perhaps in an earlier playthrough
+	}
+	if s.Achievements == nil {
+		s.Achievements = map[string]bool{}
+	}
+	s.Achievements[id] = true
+	events = append(events, types.Event{
+		Type: "achievement_unlocked",
+		Data: map[string]any{"achievement": id},
+	})
+	return events, output
+}
 
+func applyLearnFact(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) (events []types.Event, output []string) {
+	id, _ := eff.Params["fact"].(string)
+	if state.KnowsFact(s, id) {
+		return events, output
+	}
+	s.Facts = append(s.Facts, id)
+	events = append(events, types.Event{
+		Type: "fact_learned",
+		Data: map[string]any{"fact": id},
+	})
 	return events, output
 }
 
+// locate stamps events with where they happened and who caused them.
+func locate(events []types.Event, room, actor string) {
+	for i := range events {
+		if events[i].Data == nil {
+			events[i].Data = map[string]any{}
+		}
+		if _, ok := events[i].Data["room"]; !ok {
+			events[i].Data["room"] = room
+		}
+		if _, ok := events[i].Data["actor"]; !ok && actor != "" {
+			events[i].Data["actor"] = actor
+		}
+	}
+}
+
+// refKind says what an effect parameter must name.
+type refKind int
+
+const (
+	refEntity refKind = iota // an entity ID
+	refRoom                  // a room ID
+	refTarget                // "player" or an entity ID
+)
+
+// refParam is a parameter of an effect that names an entity or room.
+type refParam struct {
+	name     string
+	kind     refKind
+	template bool // the handler resolves {object} and {target} in it first
+}
+
+// refParams lists, per effect type, the parameters that name entities or rooms.
+var refParams = map[string][]refParam{
+	"give_item":      {{"item", refEntity, true}},
+	"remove_item":    {{"item", refEntity, true}},
+	"give_to":        {{"item", refEntity, true}, {"npc", refEntity, true}},
+	"transfer_item":  {{"item", refEntity, true}, {"from", refTarget, true}, {"to", refTarget, true}},
+	"wear_item":      {{"item", refEntity, true}},
+	"unwear_item":    {{"item", refEntity, true}},
+	"consume_item":   {{"item", refEntity, true}},
+	"enter_vehicle":  {{"entity", refEntity, true}},
+	"attach":         {{"entity", refEntity, true}, {"to", refEntity, true}},
+	"unlock":         {{"entity", refEntity, true}},
+	"lock":           {{"entity", refEntity, true}},
+	"detach":         {{"entity", refEntity, true}},
+	"fill_vessel":    {{"vessel", refEntity, true}, {"liquid", refEntity, true}},
+	"pour_liquid":    {{"vessel", refEntity, true}},
+	"drink_liquid":   {{"vessel", refEntity, true}},
+	"set_prop":       {{"entity", refEntity, false}},
+	"mark_examined":  {{"entity", refEntity, false}},
+	"mark_heard":     {{"npc", refEntity, false}},
+	"move_entity":    {{"entity", refEntity, false}, {"room", refRoom, false}},
+	"move_player":    {{"room", refRoom, false}},
+	"mark_visited":   {{"room", refRoom, false}},
+	"open_exit":      {{"room", refRoom, false}, {"target", refRoom, false}},
+	"close_exit":     {{"room", refRoom, false}},
+	"cutaway":        {{"room", refRoom, false}},
+	"start_dialogue": {{"npc", refEntity, false}},
+	"start_combat":   {{"enemy", refEntity, false}},
+	"damage":         {{"target", refTarget, false}},
+	"heal":           {{"target", refTarget, false}},
+	"set_stat":       {{"target", refTarget, false}},
+}
+
+// checkRefs reports an error if eff names an entity or room that does not
+// exist. Static IDs are caught by the loader; this catches templates such as
+// give_item "{object}" that resolve to an unknown ID at runtime.
+func checkRefs(eff types.Effect, defs *state.Defs, ctx Context) error {
+	for _, p := range refParams[eff.Type] {
+		id, _ := eff.Params[p.name].(string)
+		if p.template {
+			id = resolveTemplate(id, ctx)
+		}
+		switch p.kind {
+		case refEntity, refTarget:
+			if p.kind == refTarget && id == "player" {
+				continue
+			}
+			if _, ok := defs.Entities[id]; !ok {
+				return fmt.Errorf("%s: unknown entity %q in %s", eff.Type, id, p.name)
+			}
+		case refRoom:
+			if id == "" && eff.Type == "move_entity" {
+				continue // moving an entity nowhere is allowed
+			}
+			if _, ok := defs.Rooms[id]; !ok {
+				return fmt.Errorf("%s: unknown room %q in %s", eff.Type, id, p.name)
+			}
+		}
+	}
+	return nil
+}
+
 // interpolate replaces template variables in text.
 func interpolate(text string, s *types.State, defs *state.Defs, ctx Context) string {
 	r := strings.NewReplacer(
@@ -260,6 +1193,14 @@ func interpolate(text string, s *types.State, defs *state.Defs, ctx Context) str
 		text = strings.ReplaceAll(text, "{room.description}", desc)
 	}
 
+	// {clock.time}, {clock.day}
+	if strings.Contains(text, "{clock.") {
+		text = strings.NewReplacer(
+			"{clock.time}", state.FormatClock(state.TimeOfDay(s)),
+			"{clock.day}", strconv.Itoa(state.Day(s)),
+		).Replace(text)
+	}
+
 	// {object.name}, {object.description}
 	text = replaceEntityProp(text, "{object.name}", ctx.ObjectID, "name", s, defs)
 	text = replaceEntityProp(text, "{object.description}", ctx.ObjectID, "description", s, defs)
@@ -267,6 +1208,19 @@ func interpolate(text string, s *types.State, defs *state.Defs, ctx Context) str
 	// {target.name}
 	text = replaceEntityProp(text, "{target.name}", ctx.TargetID, "name", s, defs)
 
+	// {computed.<id>}
+	for id := range defs.Computed {
+		placeholder := "{computed." + id + "}"
+		if !strings.Contains(text, placeholder) {
+			continue
+		}
+		val := ""
+		if v, ok := rules.EvalComputed(id, s, defs); ok && v != nil {
+			val = fmt.Sprintf("%v", v)
+		}
+		text = strings.ReplaceAll(text, placeholder, val)
+	}
+
 	return text
 }
 
@@ -284,6 +1238,16 @@ func replaceEntityProp(text, placeholder, entityID, prop string, s *types.State,
 	return strings.ReplaceAll(text, placeholder, val)
 }
 
+// sortedStats returns the stat names in stats, sorted.
+func sortedStats(stats map[string]int) []string {
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // resolveTemplate handles {object} and {target} in effect params like GiveItem("{object}").
 func resolveTemplate(s string, ctx Context) string {
 	s = strings.ReplaceAll(s, "{object}", ctx.ObjectID)
@@ -294,7 +1258,7 @@ func resolveTemplate(s string, ctx Context) string {
 // formatInventory creates a human-readable inventory list.
 func formatInventory(items []string, defs *state.Defs) string {
 	if len(items) == 0 {
-		return "You are carrying nothing."
+		return messages.Text(defs.Messages, "inventory_empty")
 	}
 	var names []string
 	for _, id := range items {
@@ -309,6 +1273,57 @@ func formatInventory(items []string, defs *state.Defs) string {
 	return strings.Join(names, ", ")
 }
 
+// admit decides where an entity moving into room actually ends up. A room
+// at capacity rejects the entity (dest == "") with a room_full event, or,
+// when its overflow is "adjacent", sends it to the first neighbouring room
+// with space (exits in sorted order) with a room_overflow event.
+func admit(s *types.State, defs *state.Defs, entity, room string) (string, *types.Event) {
+	if state.HasRoomFor(s, defs, room, entity) {
+		return room, nil
+	}
+	if defs.Rooms[room].Overflow == "adjacent" {
+		exits := state.RoomExits(s, defs, room)
+		dirs := make([]string, 0, len(exits))
+		for dir := range exits {
+			dirs = append(dirs, dir)
+		}
+		sort.Strings(dirs)
+		for _, dir := range dirs {
+			next := exits[dir]
+			if state.HasRoomFor(s, defs, next, entity) {
+				return next, &types.Event{
+					Type: "room_overflow",
+					Data: map[string]any{"entity": entity, "room": room, "overflow": next},
+				}
+			}
+		}
+	}
+	return "", &types.Event{
+		Type: "room_full",
+		Data: map[string]any{"entity": entity, "room": room},
+	}
+}
+
+// cutaway narrates another room without moving the player: a "Meanwhile"
+// header followed by the room as the player would see it there. If
+// maxLines > 0 only that many lines follow the header.
+func cutaway(s *types.State, defs *state.Defs, roomID string, maxLines int) []string {
+	var vehicle string
+	if roomID == s.Player.Location {
+		vehicle = s.Player.Vehicle
+	}
+	lines := DescribeRoom(s, defs, roomID, true, vehicle)
+	if lines == nil {
+		return nil
+	}
+	if maxLines > 0 && len(lines) > maxLines {
+		lines = lines[:maxLines]
+	}
+
+	header := messages.Text(defs.Messages, "cutaway", "room", strings.ReplaceAll(roomID, "_", " "))
+	return append([]string{header}, lines...)
+}
+
 func ensureEntityState(s *types.State, entityID string) {
 	if _, ok := s.Entities[entityID]; !ok {
 		s.Entities[entityID] = types.EntityState{}
@@ -324,6 +1339,25 @@ func removeFromSlice(slice []string, item string) []string {
 	return slice
 }
 
+// roll returns an amount parameter as a whole number, rolling it first if
+// it is a dice expression such as "2d4+1".
+func roll(v any, ctx Context, purpose string) int {
+	expr, ok := v.(string)
+	if !ok {
+		return toInt(v)
+	}
+	d, err := dice.Parse(expr)
+	if err != nil {
+		return 0 // caught by the loader
+	}
+	return d.Roll(func(sides int) int {
+		if ctx.Roll == nil {
+			return (sides + 1) / 2
+		}
+		return ctx.Roll(purpose+" ("+expr+")", sides)
+	})
+}
+
 func toInt(v any) int {
 	switch n := v.(type) {
 	case int:
@@ -362,8 +1396,8 @@ func initEnemyStats(s *types.State, defs *state.Defs, enemyID string) {
 	s.Entities[enemyID] = es
 }
 
-// applyDamage decrements the target's HP, clamping to 0. Returns remaining HP.
-func applyDamage(s *types.State, defs *state.Defs, target string, amount int) int {
+// damageTarget decrements the target's HP, clamping to 0. Returns remaining HP.
+func damageTarget(s *types.State, defs *state.Defs, target string, amount int) int {
 	hp, _ := state.GetStat(s, defs, target, "hp")
 	hp -= amount
 	if hp < 0 {
@@ -373,8 +1407,8 @@ func applyDamage(s *types.State, defs *state.Defs, target string, amount int) in
 	return hp
 }
 
-// applyHeal increments the target's HP, clamping to max_hp. Returns current HP.
-func applyHeal(s *types.State, defs *state.Defs, target string, amount int) int {
+// healTarget increments the target's HP, clamping to max_hp. Returns current HP.
+func healTarget(s *types.State, defs *state.Defs, target string, amount int) int {
 	hp, _ := state.GetStat(s, defs, target, "hp")
 	maxHP, _ := state.GetStat(s, defs, target, "max_hp")
 	hp += amount
@@ -1,6 +1,7 @@
 package effects
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/nathoo/questcore/engine/state"
@@ -68,6 +69,21 @@ func TestApply_Say(t *testing.T) {
 	}
 }
 
+func TestApply_Notify(t *testing.T) {
+	s, defs, ctx := testSetup()
+	effects := []types.Effect{
+		{Type: "notify", Params: map[string]any{"text": "Quest updated: {object}"}},
+	}
+
+	events, output := Apply(s, defs, effects, ctx)
+	if len(output) != 0 {
+		t.Errorf("expected no narrative output, got %v", output)
+	}
+	if len(events) != 1 || events[0].Type != "notification" || events[0].Data["text"] != "Quest updated: rusty_key" {
+		t.Errorf("expected notification event, got %v", events)
+	}
+}
+
 func TestApply_Say_TemplateInterpolation(t *testing.T) {
 	s, defs, ctx := testSetup()
 	effects := []types.Effect{
@@ -81,6 +97,32 @@ func TestApply_Say_TemplateInterpolation(t *testing.T) {
 	}
 }
 
+func TestApply_Say_ComputedTemplate(t *testing.T) {
+	s, defs, ctx := testSetup()
+	defs.Computed = map[string]types.ComputedDef{
+		"mood": {
+			ID:   "mood",
+			Uses: []string{"flags.fed"},
+			Cases: []types.ComputedCase{
+				{When: []types.Condition{{Type: "flag_set", Params: map[string]any{"flag": "fed"}}}, Value: "content"},
+			},
+			Default: "hungry",
+		},
+	}
+	effs := []types.Effect{{Type: "say", Params: map[string]any{"text": "The dog looks {computed.mood}."}}}
+
+	_, output := Apply(s, defs, effs, ctx)
+	if len(output) != 1 || output[0] != "The dog looks hungry." {
+		t.Errorf("expected default value, got %v", output)
+	}
+
+	s.Flags["fed"] = true
+	_, output = Apply(s, defs, effs, ctx)
+	if len(output) != 1 || output[0] != "The dog looks content." {
+		t.Errorf("expected case value, got %v", output)
+	}
+}
+
 func TestApply_Say_RoomDescription(t *testing.T) {
 	s, defs, ctx := testSetup()
 	effects := []types.Effect{
@@ -144,6 +186,36 @@ func TestApply_GiveItem(t *testing.T) {
 	}
 }
 
+func TestApply_GiveItem_Amount(t *testing.T) {
+	s, defs, ctx := testSetup()
+	defs.Entities["coin"] = types.EntityDef{ID: "coin", Kind: "item", Props: map[string]any{
+		"location": "hall", "quantity": 5,
+	}}
+
+	Apply(s, defs, []types.Effect{{Type: "give_item", Params: map[string]any{"item": "coin", "amount": 3}}}, ctx)
+	if got := state.HeldCount(s, defs, "coin"); got != 3 {
+		t.Errorf("held = %d, want 3", got)
+	}
+	if got := state.Quantity(s, defs, "coin"); got != 2 || s.Entities["coin"].Location != "" {
+		t.Errorf("world stack = %d at %q, want 2 left in place", got, s.Entities["coin"].Location)
+	}
+
+	// No amount takes the rest of the stack, which leaves the world.
+	Apply(s, defs, []types.Effect{{Type: "give_item", Params: map[string]any{"item": "coin"}}}, ctx)
+	if got := state.HeldCount(s, defs, "coin"); got != 5 || state.Quantity(s, defs, "coin") != 0 {
+		t.Errorf("held = %d, want all 5", got)
+	}
+
+	Apply(s, defs, []types.Effect{{Type: "remove_item", Params: map[string]any{"item": "coin", "amount": 4}}}, ctx)
+	if got := state.HeldCount(s, defs, "coin"); got != 1 {
+		t.Errorf("held after removing 4 = %d, want 1", got)
+	}
+	Apply(s, defs, []types.Effect{{Type: "remove_item", Params: map[string]any{"item": "coin", "amount": 9}}}, ctx)
+	if state.HasItem(s, "coin") || len(s.Player.Quantities) != 0 {
+		t.Errorf("coin still carried: %v %v", s.Player.Inventory, s.Player.Quantities)
+	}
+}
+
 func TestApply_GiveItem_TemplateObject(t *testing.T) {
 	s, defs, ctx := testSetup()
 	effects := []types.Effect{
@@ -156,6 +228,56 @@ func TestApply_GiveItem_TemplateObject(t *testing.T) {
 	}
 }
 
+func TestApply_GiveTo(t *testing.T) {
+	s, defs, ctx := testSetup()
+	s.Player.Inventory = []string{"rusty_key", "sword"}
+	effects := []types.Effect{
+		{Type: "give_to", Params: map[string]any{"item": "{object}", "npc": "iron_door"}},
+	}
+
+	events, _ := Apply(s, defs, effects, ctx)
+
+	if len(s.Player.Inventory) != 1 || s.Player.Inventory[0] != "sword" {
+		t.Errorf("expected only sword left in inventory, got %v", s.Player.Inventory)
+	}
+	if loc := s.Entities["rusty_key"].Location; loc != "iron_door" {
+		t.Errorf("expected rusty_key carried by iron_door, got %q", loc)
+	}
+	if len(events) != 1 || events[0].Type != "item_given" || events[0].Data["npc"] != "iron_door" {
+		t.Errorf("expected item_given event, got %v", events)
+	}
+}
+
+func TestApply_TransferItem(t *testing.T) {
+	s, defs, ctx := testSetup()
+	state.SetEntityLocation(s, defs, "rusty_key", "iron_door")
+
+	// Nothing happens unless from holds the item.
+	events, _ := Apply(s, defs, []types.Effect{
+		{Type: "transfer_item", Params: map[string]any{"item": "rusty_key", "from": "player", "to": "iron_door"}},
+	}, ctx)
+	if len(events) != 0 {
+		t.Errorf("expected no events, got %v", events)
+	}
+
+	events, _ = Apply(s, defs, []types.Effect{
+		{Type: "transfer_item", Params: map[string]any{"item": "rusty_key", "from": "iron_door", "to": "player"}},
+	}, ctx)
+	if !state.HasItem(s, "rusty_key") {
+		t.Errorf("expected rusty_key in inventory, got %v", s.Player.Inventory)
+	}
+	if len(events) != 1 || events[0].Type != "item_transferred" || events[0].Data["from"] != "iron_door" {
+		t.Errorf("expected item_transferred event, got %v", events)
+	}
+
+	Apply(s, defs, []types.Effect{
+		{Type: "transfer_item", Params: map[string]any{"item": "rusty_key", "from": "player", "to": "iron_door"}},
+	}, ctx)
+	if state.HasItem(s, "rusty_key") || state.Holder(s, defs, "rusty_key") != "iron_door" {
+		t.Errorf("expected rusty_key back with iron_door, inventory %v", s.Player.Inventory)
+	}
+}
+
 func TestApply_RemoveItem(t *testing.T) {
 	s, defs, ctx := testSetup()
 	s.Player.Inventory = []string{"rusty_key", "sword"}
@@ -189,6 +311,71 @@ func TestApply_SetFlag(t *testing.T) {
 	}
 }
 
+func TestApply_MarkExamined(t *testing.T) {
+	s, defs, ctx := testSetup()
+	effects := []types.Effect{
+		{Type: "mark_examined", Params: map[string]any{"entity": "iron_door"}},
+		{Type: "mark_examined", Params: map[string]any{"entity": "iron_door"}},
+	}
+
+	events, output := Apply(s, defs, effects, ctx)
+
+	if got := state.Examined(s, "iron_door"); got != 2 {
+		t.Errorf("examined %d times, want 2", got)
+	}
+	if len(events) != 0 || len(output) != 0 {
+		t.Errorf("mark_examined should be silent, got %v %v", events, output)
+	}
+}
+
+func TestApply_MarkVisited(t *testing.T) {
+	s, defs, ctx := testSetup()
+	s.Visited = nil
+	Apply(s, defs, []types.Effect{
+		{Type: "mark_visited", Params: map[string]any{"room": "entrance"}},
+	}, ctx)
+
+	if !state.HasVisited(s, "entrance") {
+		t.Errorf("visited = %v, want entrance", s.Visited)
+	}
+}
+
+func TestApply_Questions(t *testing.T) {
+	s, defs, ctx := testSetup()
+	Apply(s, defs, []types.Effect{
+		{Type: "ask", Params: map[string]any{"answer": "riddle"}},
+		{Type: "use_attempt", Params: map[string]any{"answer": "riddle"}},
+		{Type: "use_attempt", Params: map[string]any{"answer": "riddle"}},
+	}, ctx)
+	if s.Pending != "answer:riddle" || s.Attempts["riddle"] != 2 {
+		t.Fatalf("pending %q, attempts %v; want answer:riddle, 2", s.Pending, s.Attempts)
+	}
+
+	Apply(s, defs, []types.Effect{{Type: "close_question", Params: map[string]any{"answer": "riddle"}}}, ctx)
+	if s.Pending != "" || s.Attempts["riddle"] != 0 {
+		t.Errorf("pending %q, attempts %v; want the question closed", s.Pending, s.Attempts)
+	}
+}
+
+func TestApply_SetVerbosity(t *testing.T) {
+	s, defs, ctx := testSetup()
+	Apply(s, defs, []types.Effect{{Type: "set_verbosity", Params: map[string]any{"mode": "brief"}}}, ctx)
+	if s.Verbosity != "brief" {
+		t.Errorf("Verbosity = %q, want brief", s.Verbosity)
+	}
+}
+
+func TestApply_MarkHeard(t *testing.T) {
+	s, defs, ctx := testSetup()
+	Apply(s, defs, []types.Effect{
+		{Type: "mark_heard", Params: map[string]any{"npc": "guard", "topic": "password"}},
+	}, ctx)
+
+	if !state.Heard(s, "guard", "password") || state.Heard(s, "guard", "weather") {
+		t.Errorf("heard = %v, want only guard.password", s.Heard)
+	}
+}
+
 func TestApply_IncCounter(t *testing.T) {
 	s, defs, ctx := testSetup()
 	s.Counters["score"] = 10
@@ -203,6 +390,35 @@ func TestApply_IncCounter(t *testing.T) {
 	}
 }
 
+func TestApply_DecAndMulCounter(t *testing.T) {
+	s, defs, ctx := testSetup()
+	s.Counters["score"] = 10
+	effects := []types.Effect{
+		{Type: "dec_counter", Params: map[string]any{"counter": "score", "amount": 4}},
+		{Type: "mul_counter", Params: map[string]any{"counter": "score", "factor": 3}},
+	}
+
+	Apply(s, defs, effects, ctx)
+
+	if s.Counters["score"] != 18 {
+		t.Errorf("expected score 18, got %d", s.Counters["score"])
+	}
+}
+
+func TestApply_IncAndDecProp(t *testing.T) {
+	s, defs, ctx := testSetup()
+	effects := []types.Effect{
+		{Type: "inc_prop", Params: map[string]any{"entity": "iron_door", "prop": "dents", "amount": 3}},
+		{Type: "dec_prop", Params: map[string]any{"entity": "iron_door", "prop": "dents", "amount": 1}},
+	}
+
+	Apply(s, defs, effects, ctx)
+
+	if got := s.Entities["iron_door"].Props["dents"]; got != 2 {
+		t.Errorf("expected dents 2 (an int), got %#v", got)
+	}
+}
+
 func TestApply_SetCounter(t *testing.T) {
 	s, defs, ctx := testSetup()
 	s.Counters["score"] = 10
@@ -251,6 +467,102 @@ func TestApply_MoveEntity(t *testing.T) {
 	}
 }
 
+// crowdedEntrance returns testSetup with the entrance holding one NPC at a
+// capacity of one.
+func crowdedEntrance(overflow string) (*types.State, *state.Defs, Context) {
+	_, defs, ctx := testSetup()
+	entrance := defs.Rooms["entrance"]
+	entrance.Capacity = 1
+	entrance.Overflow = overflow
+	defs.Rooms["entrance"] = entrance
+	defs.Entities["porter"] = types.EntityDef{
+		ID: "porter", Kind: "npc",
+		Props: map[string]any{"name": "Porter", "location": "entrance"},
+	}
+	return state.NewState(defs), defs, ctx
+}
+
+func TestApply_MoveEntity_RoomFull(t *testing.T) {
+	s, defs, ctx := crowdedEntrance("")
+	effects := []types.Effect{
+		{Type: "move_entity", Params: map[string]any{"entity": "guard", "room": "entrance"}},
+	}
+
+	events, _ := Apply(s, defs, effects, ctx)
+
+	if loc := state.EntityLocation(s, defs, "guard"); loc != "hall" {
+		t.Errorf("expected guard to stay in hall, got %q", loc)
+	}
+	if len(events) != 1 || events[0].Type != "room_full" {
+		t.Fatalf("expected room_full event, got %v", events)
+	}
+	if events[0].Data["room"] != "entrance" || events[0].Data["entity"] != "guard" {
+		t.Errorf("unexpected event data: %v", events[0].Data)
+	}
+}
+
+func TestApply_MoveEntity_Overflow(t *testing.T) {
+	s, defs, ctx := crowdedEntrance("adjacent")
+	s.Entities["guard"] = types.EntityState{Location: "nowhere"}
+	effects := []types.Effect{
+		{Type: "move_entity", Params: map[string]any{"entity": "guard", "room": "entrance"}},
+	}
+
+	events, _ := Apply(s, defs, effects, ctx)
+
+	if loc := state.EntityLocation(s, defs, "guard"); loc != "hall" {
+		t.Errorf("expected guard to overflow to hall, got %q", loc)
+	}
+	if len(events) != 2 || events[0].Type != "room_overflow" || events[1].Type != "entity_moved" {
+		t.Fatalf("expected room_overflow then entity_moved, got %v", events)
+	}
+	if events[0].Data["overflow"] != "hall" {
+		t.Errorf("expected overflow to hall, got %v", events[0].Data)
+	}
+}
+
+func TestApply_MoveEntity_CapacityIgnoresItemsAndDead(t *testing.T) {
+	s, defs, ctx := crowdedEntrance("")
+	s.Entities["porter"] = types.EntityState{Props: map[string]any{"alive": false}}
+	effects := []types.Effect{
+		{Type: "move_entity", Params: map[string]any{"entity": "rusty_key", "room": "entrance"}},
+		{Type: "move_entity", Params: map[string]any{"entity": "guard", "room": "entrance"}},
+	}
+
+	Apply(s, defs, effects, ctx)
+
+	for _, id := range []string{"rusty_key", "guard"} {
+		if loc := state.EntityLocation(s, defs, id); loc != "entrance" {
+			t.Errorf("expected %s in entrance, got %q", id, loc)
+		}
+	}
+}
+
+func TestApply_EventsRecordRoomAndActor(t *testing.T) {
+	s, defs, ctx := testSetup()
+	ctx.Actor = "player"
+	effects := []types.Effect{
+		{Type: "give_item", Params: map[string]any{"item": "rusty_key"}},
+		{Type: "move_player", Params: map[string]any{"room": "entrance"}},
+		{Type: "emit_event", Params: map[string]any{"event": "whistle"}},
+	}
+
+	events, _ := Apply(s, defs, effects, ctx)
+	want := []struct{ typ, room string }{
+		{"item_taken", "hall"},       // where the player was
+		{"room_entered", "entrance"}, // names its own room
+		{"whistle", "entrance"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d events, got %v", len(want), events)
+	}
+	for i, w := range want {
+		if events[i].Type != w.typ || events[i].Data["room"] != w.room || events[i].Data["actor"] != "player" {
+			t.Errorf("events[%d] = %v, want %s in %s by player", i, events[i], w.typ, w.room)
+		}
+	}
+}
+
 func TestApply_MovePlayer(t *testing.T) {
 	s, defs, ctx := testSetup()
 	effects := []types.Effect{
@@ -270,14 +582,14 @@ func TestApply_MovePlayer(t *testing.T) {
 func TestApply_OpenExit(t *testing.T) {
 	s, defs, ctx := testSetup()
 	effects := []types.Effect{
-		{Type: "open_exit", Params: map[string]any{"room": "hall", "direction": "west", "target": "treasury"}},
+		{Type: "open_exit", Params: map[string]any{"room": "hall", "direction": "west", "target": "entrance"}},
 	}
 
 	Apply(s, defs, effects, ctx)
 
 	exits := state.RoomExits(s, defs, "hall")
-	if exits["west"] != "treasury" {
-		t.Errorf("expected west→treasury, got %v", exits)
+	if exits["west"] != "entrance" {
+		t.Errorf("expected west→entrance, got %v", exits)
 	}
 	// Original exit should still exist.
 	if exits["south"] != "entrance" {
@@ -408,6 +720,54 @@ func TestApply_StartCombat(t *testing.T) {
 	}
 }
 
+func TestApply_StartCombatInitiative(t *testing.T) {
+	tests := []struct {
+		name        string
+		playerSpeed int
+		enemySpeed  int
+		surprise    bool
+		want        string
+		wantRolls   bool
+	}{
+		{"no speed stats", 0, 0, false, "enemy", false},
+		{"player quicker", 3, 1, false, "player", true},
+		{"tie goes to the player", 2, 2, false, "player", true},
+		{"enemy quicker", 1, 3, false, "enemy", true},
+		{"surprise", 0, 9, true, "player", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, defs, ctx := combatSetup()
+			if tt.playerSpeed > 0 {
+				s.Player.Stats["speed"] = tt.playerSpeed
+			}
+			if tt.enemySpeed > 0 {
+				defs.Entities["goblin"].Props["speed"] = tt.enemySpeed
+			}
+			effs := []types.Effect{
+				{Type: "start_combat", Params: map[string]any{"enemy": "goblin", "surprise": tt.surprise}},
+			}
+
+			events, _ := Apply(s, defs, effs, ctx) // no Roll: each die rolls 3
+
+			if s.Combat.Initiative != tt.want || s.Combat.Surprise != tt.surprise {
+				t.Errorf("initiative = %q, surprise = %v; want %q, %v",
+					s.Combat.Initiative, s.Combat.Surprise, tt.want, tt.surprise)
+			}
+			data := events[0].Data
+			if data["initiative"] != tt.want || data["surprise"] != tt.surprise {
+				t.Errorf("combat_started data = %v", data)
+			}
+			if _, rolled := data["player_roll"]; rolled != tt.wantRolls {
+				t.Errorf("rolled = %v, want %v: %v", rolled, tt.wantRolls, data)
+			}
+			if tt.wantRolls && (data["player_roll"] != tt.playerSpeed+3 || data["enemy_roll"] != tt.enemySpeed+3) {
+				t.Errorf("rolls = %v vs %v, want speed + 3", data["player_roll"], data["enemy_roll"])
+			}
+		})
+	}
+}
+
 func TestApply_EndCombat(t *testing.T) {
 	s, defs, ctx := combatSetup()
 	s.Combat = types.CombatState{Active: true, EnemyID: "goblin", RoundCount: 3}
@@ -702,3 +1062,272 @@ func TestApply_SetDefending(t *testing.T) {
 		t.Errorf("expected 0 output, got %d", len(output))
 	}
 }
+
+func TestApply_UnknownTemplateEntityWarns(t *testing.T) {
+	s, defs, ctx := testSetup()
+	ctx.ObjectID = "ghost"
+	effs := []types.Effect{
+		{Type: "give_item", Params: map[string]any{"item": "{object}"}},
+	}
+
+	events, _ := Apply(s, defs, effs, ctx)
+	if len(events) == 0 || events[0].Type != "invalid_reference" {
+		t.Fatalf("expected invalid_reference event first, got %v", events)
+	}
+	if msg, _ := events[0].Data["message"].(string); msg != `give_item: unknown entity "ghost" in item` {
+		t.Errorf("message = %q", msg)
+	}
+	// Non-strict: the effect is skipped.
+	if state.HasItem(s, "ghost") {
+		t.Error("expected the effect to be skipped outside strict mode")
+	}
+}
+
+func TestApply_UnknownRefSkipped(t *testing.T) {
+	tests := []struct {
+		name string
+		eff  types.Effect
+	}{
+		{"give_item", types.Effect{Type: "give_item", Params: map[string]any{"item": "ghost"}}},
+		{"move_player", types.Effect{Type: "move_player", Params: map[string]any{"room": "nowhere"}}},
+		{"move_entity", types.Effect{Type: "move_entity", Params: map[string]any{"entity": "guard", "room": "nowhere"}}},
+		{"set_prop", types.Effect{Type: "set_prop", Params: map[string]any{"entity": "ghost", "prop": "lit", "value": true}}},
+		{"open_exit", types.Effect{Type: "open_exit", Params: map[string]any{"room": "hall", "direction": "down", "target": "nowhere"}}},
+		{"damage", types.Effect{Type: "damage", Params: map[string]any{"target": "ghost", "amount": 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, defs, ctx := testSetup()
+			before := state.Clone(s)
+			effs := []types.Effect{tt.eff, {Type: "say", Params: map[string]any{"text": "Still here."}}}
+
+			events, output := Apply(s, defs, effs, ctx)
+			if len(events) != 1 || events[0].Type != "invalid_reference" {
+				t.Errorf("events = %v, want only invalid_reference", events)
+			}
+			if !reflect.DeepEqual(output, []string{"Still here."}) {
+				t.Errorf("output = %v, want the rest of the list applied", output)
+			}
+			if !reflect.DeepEqual(s, before) {
+				t.Errorf("state changed:\n got %+v\nwant %+v", s, before)
+			}
+		})
+	}
+}
+
+func TestCheckRefs_Templates(t *testing.T) {
+	_, defs, ctx := testSetup()
+	for typ, params := range refParams {
+		if params[0].kind == refRoom {
+			continue
+		}
+		eff := types.Effect{Type: typ, Params: map[string]any{}}
+		for _, p := range params {
+			eff.Params[p.name] = "{object}"
+		}
+		err := checkRefs(eff, defs, ctx)
+		if params[0].template && err != nil {
+			t.Errorf("%s: %v, want {object} resolved", typ, err)
+		}
+		if !params[0].template && err == nil {
+			t.Errorf("%s: {object} accepted, want it checked as given", typ)
+		}
+	}
+}
+
+func TestApply_UnknownRoomStrictStops(t *testing.T) {
+	s, defs, ctx := testSetup()
+	ctx.Strict = true
+	effs := []types.Effect{
+		{Type: "move_player", Params: map[string]any{"room": "nowhere"}},
+		{Type: "say", Params: map[string]any{"text": "Never printed."}},
+	}
+
+	events, output := Apply(s, defs, effs, ctx)
+	if s.Player.Location != "hall" {
+		t.Errorf("player moved to %q in strict mode", s.Player.Location)
+	}
+	if len(output) != 1 || output[0] != `Error: move_player: unknown room "nowhere" in room` {
+		t.Errorf("output = %v", output)
+	}
+	if len(events) != 1 || events[0].Type != "invalid_reference" {
+		t.Errorf("events = %v", events)
+	}
+}
+
+func TestApply_KnownRefsNoWarning(t *testing.T) {
+	s, defs, ctx := testSetup()
+	effs := []types.Effect{
+		{Type: "move_entity", Params: map[string]any{"entity": "guard", "room": "entrance"}},
+		{Type: "move_entity", Params: map[string]any{"entity": "rusty_key", "room": ""}},
+		{Type: "damage", Params: map[string]any{"target": "player", "amount": 0}},
+	}
+
+	events, _ := Apply(s, defs, effs, ctx)
+	for _, e := range events {
+		if e.Type == "invalid_reference" {
+			t.Errorf("unexpected warning: %v", e.Data)
+		}
+	}
+}
+
+func TestApply_Cutaway(t *testing.T) {
+	s, defs, ctx := testSetup()
+	s.Player.Location = "entrance"
+	effs := []types.Effect{
+		{Type: "cutaway", Params: map[string]any{"room": "hall", "lines": 0}},
+	}
+
+	_, output := Apply(s, defs, effs, ctx)
+	want := []string{
+		"Meanwhile, in the hall...",
+		"A grand hall with marble columns.",
+		"You see: *Old Guard*, *Iron Door*, *Rusty Key*.",
+		"~ Exits: south.",
+	}
+	if len(output) != len(want) {
+		t.Fatalf("output = %v, want %v", output, want)
+	}
+	for i := range want {
+		if output[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, output[i], want[i])
+		}
+	}
+	if s.Player.Location != "entrance" {
+		t.Errorf("cutaway moved the player to %q", s.Player.Location)
+	}
+}
+
+func TestApply_CutawayDescribesRoomAsSeen(t *testing.T) {
+	s, defs, ctx := testSetup()
+	hall := defs.Rooms["hall"]
+	hall.FirstDescription = "You have never seen such columns."
+	defs.Rooms["hall"] = hall
+	s.Player.Location = "entrance"
+
+	_, output := Apply(s, defs, []types.Effect{{Type: "cutaway", Params: map[string]any{"room": "hall"}}}, ctx)
+	want := append([]string{"Meanwhile, in the hall..."}, DescribeRoom(s, defs, "hall", true, "")...)
+	if !reflect.DeepEqual(output, want) {
+		t.Errorf("output = %v, want %v", output, want)
+	}
+	if output[1] != "You have never seen such columns." {
+		t.Errorf("expected the first description of an unvisited room, got %q", output[1])
+	}
+}
+
+func TestApply_CutawayLineLimit(t *testing.T) {
+	s, defs, ctx := testSetup()
+	effs := []types.Effect{
+		{Type: "cutaway", Params: map[string]any{"room": "hall", "lines": 1}},
+	}
+
+	_, output := Apply(s, defs, effs, ctx)
+	if len(output) != 2 || output[1] != "A grand hall with marble columns." {
+		t.Errorf("expected header and one line, got %v", output)
+	}
+}
+
+func TestApply_UnlockAchievement(t *testing.T) {
+	s, defs, ctx := testSetup()
+	effs := []types.Effect{{Type: "unlock_achievement", Params: map[string]any{"achievement": "pacifist"}}}
+
+	events, _ := Apply(s, defs, effs, ctx)
+	if !s.Achievements["pacifist"] {
+		t.Error("expected pacifist to be unlocked")
+	}
+	if len(events) != 1 || events[0].Type != "achievement_unlocked" || events[0].Data["achievement"] != "pacifist" {
+		t.Errorf("events = %v", events)
+	}
+
+	// Unlocking again is a no-op.
+	events, _ = Apply(s, defs, effs, ctx)
+	if len(events) != 0 {
+		t.Errorf("expected no events for an unlocked achievement, got %v", events)
+	}
+}
+
+func TestApply_PlaySoundAndMusic(t *testing.T) {
+	s, defs, ctx := testSetup()
+	effs := []types.Effect{
+		{Type: "play_sound", Params: map[string]any{"sound": "door_creak"}},
+		{Type: "play_music", Params: map[string]any{"music": "dungeon"}},
+	}
+
+	events, _ := Apply(s, defs, effs, ctx)
+	if s.Music != "dungeon" {
+		t.Errorf("Music = %q, want dungeon", s.Music)
+	}
+	if len(events) != 2 || events[0].Type != "sound_played" || events[0].Data["sound"] != "door_creak" ||
+		events[1].Type != "music_changed" || events[1].Data["music"] != "dungeon" {
+		t.Errorf("events = %v", events)
+	}
+
+	// The track already playing is not restarted.
+	events, _ = Apply(s, defs, effs[1:], ctx)
+	if len(events) != 0 {
+		t.Errorf("expected no events for the current track, got %v", events)
+	}
+
+	// An empty track stops the music.
+	events, _ = Apply(s, defs, []types.Effect{{Type: "play_music", Params: map[string]any{"music": ""}}}, ctx)
+	if s.Music != "" || len(events) != 1 || events[0].Data["music"] != "" {
+		t.Errorf("after stop: Music = %q, events = %v", s.Music, events)
+	}
+}
+
+func TestApply_AdvanceTime(t *testing.T) {
+	s, defs, ctx := testSetup()
+	defs.Game.Clock = &types.ClockDef{}
+	s.Clock = 23 * 60
+	effs := []types.Effect{
+		{Type: "advance_time", Params: map[string]any{"minutes": 90}},
+		{Type: "say", Params: map[string]any{"text": "It is {clock.time} on day {clock.day}."}},
+	}
+
+	_, output := Apply(s, defs, effs, ctx)
+	if s.Clock != 23*60+90 {
+		t.Errorf("Clock = %d, want %d", s.Clock, 23*60+90)
+	}
+	if len(output) != 1 || output[0] != "It is 00:30 on day 2." {
+		t.Errorf("output = %v", output)
+	}
+}
+
+func TestApply_DiceAmounts(t *testing.T) {
+	s, defs, ctx := testSetup()
+	s.Player.Stats = map[string]int{"hp": 20, "max_hp": 20}
+	var purposes []string
+	ctx.Roll = func(purpose string, sides int) int {
+		purposes = append(purposes, purpose)
+		return sides // every die rolls its highest
+	}
+
+	Apply(s, defs, []types.Effect{
+		{Type: "damage", Params: map[string]any{"target": "player", "amount": "2d6+1"}},
+		{Type: "inc_counter", Params: map[string]any{"counter": "gold", "amount": "1d10"}},
+	}, ctx)
+
+	if hp := s.Player.Stats["hp"]; hp != 7 {
+		t.Errorf("hp = %d, want 20 - 13 = 7", hp)
+	}
+	if gold := s.Counters["gold"]; gold != 10 {
+		t.Errorf("gold = %d, want 10", gold)
+	}
+	want := []string{"damage: player (2d6+1)", "damage: player (2d6+1)", "counter: gold (1d10)"}
+	if !reflect.DeepEqual(purposes, want) {
+		t.Errorf("purposes = %v, want %v", purposes, want)
+	}
+}
+
+func TestApply_DiceAmountsWithoutRoll(t *testing.T) {
+	s, defs, ctx := testSetup()
+	s.Player.Stats = map[string]int{"hp": 20, "max_hp": 20}
+	Apply(s, defs, []types.Effect{
+		{Type: "damage", Params: map[string]any{"target": "player", "amount": "2d6"}},
+		{Type: "damage", Params: map[string]any{"target": "player", "amount": "1d4-5"}},
+	}, ctx)
+	// Each d6 rolls 3 on average; a roll below zero deals no damage.
+	if hp := s.Player.Stats["hp"]; hp != 14 {
+		t.Errorf("hp = %d, want 14", hp)
+	}
+}
@@ -0,0 +1,28 @@
+package effects
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// FuzzInterpolate checks that template interpolation doesn't panic on any
+// text or resolved names, and leaves text without templates alone.
+func FuzzInterpolate(f *testing.F) {
+	f.Add("You use {object.name} on {target.name}.", "rusty_key", "iron_door")
+	f.Add("{player.inventory} {room.description} {clock.time} {clock.day}", "", "")
+	f.Add("{object.description}{object.name", "nowhere", "{object.name}")
+	f.Add("{computed.mood} {verb} {{object}}", "{target}", "{object}")
+	f.Fuzz(func(t *testing.T, text, object, target string) {
+		s, defs, ctx := testSetup()
+		ctx.ObjectID, ctx.TargetID = object, target
+		got := interpolate(text, s, defs, ctx)
+		if !strings.Contains(text, "{") && got != text {
+			t.Errorf("interpolate(%q) = %q, want it unchanged", text, got)
+		}
+
+		// Say goes through the same templates, and markup besides.
+		Apply(s, defs, []types.Effect{{Type: "say", Params: map[string]any{"text": text}}}, ctx)
+	})
+}
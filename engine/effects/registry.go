@@ -0,0 +1,60 @@
+package effects
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// Handler applies one effect of a type added to a Registry. Like Apply, it
+// may change s, and returns the events it emits and the text it shows; the
+// events are stamped with the room and actor as Apply's own are.
+type Handler func(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) ([]types.Event, []string)
+
+// Registry holds the effect types a program adds for its games to use,
+// such as "shake_screen", by name. Games name them like any other:
+// { type = "shake_screen", ... } in a rule's effects. Pass the registry to
+// the loader, which accepts its types with whatever parameters they are
+// given, and to the engine, which applies them through Context.Custom.
+//
+// Effects of every type apply in the order they are listed, a registered
+// handler running at its effect's turn, and stop still stops the list.
+type Registry map[string]Handler
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() Registry {
+	return Registry{}
+}
+
+// Add adds effect type name, applied by fn. It returns an error if name is
+// empty, is a built-in type or is already in r, or if fn is nil.
+func (r Registry) Add(name string, fn Handler) error {
+	if name == "" || fn == nil {
+		return errors.New("an effect type needs a name and a handler")
+	}
+	if _, builtin := handlers[name]; builtin || name == "stop" {
+		return fmt.Errorf("effect type %q is built in", name)
+	}
+	if _, dup := r[name]; dup {
+		return fmt.Errorf("effect type %q is already registered", name)
+	}
+	r[name] = fn
+	return nil
+}
+
+// Has reports whether name is an effect type added to r.
+func (r Registry) Has(name string) bool {
+	_, ok := r[name]
+	return ok
+}
+
+// lookup returns the handler for effect type name, built in or in custom.
+func lookup(name string, custom Registry) (Handler, bool) {
+	if fn, ok := handlers[name]; ok {
+		return fn, true
+	}
+	fn, ok := custom[name]
+	return fn, ok
+}
@@ -0,0 +1,76 @@
+package effects
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+func shake(s *types.State, defs *state.Defs, eff types.Effect, ctx Context) ([]types.Event, []string) {
+	s.Counters["shakes"]++
+	return []types.Event{{Type: "screen_shaken"}}, []string{"The ground shakes."}
+}
+
+func TestRegistry_AppliesInOrder(t *testing.T) {
+	s, defs, ctx := testSetup()
+	ctx.Actor = "player"
+	ctx.Custom = NewRegistry()
+	if err := ctx.Custom.Add("test_shake", shake); err != nil {
+		t.Fatal(err)
+	}
+	events, output := Apply(s, defs, []types.Effect{
+		{Type: "say", Params: map[string]any{"text": "Before."}},
+		{Type: "test_shake"},
+		{Type: "say", Params: map[string]any{"text": "After."}},
+		{Type: "stop"},
+		{Type: "test_shake"},
+	}, ctx)
+
+	if want := []string{"Before.", "The ground shakes.", "After."}; !reflect.DeepEqual(output, want) {
+		t.Errorf("output = %v, want %v", output, want)
+	}
+	if s.Counters["shakes"] != 1 {
+		t.Errorf("shakes = %d, want 1: stop should stop the second", s.Counters["shakes"])
+	}
+	if len(events) != 1 || events[0].Data["room"] != "hall" || events[0].Data["actor"] != "player" {
+		t.Errorf("events = %+v, want one screen_shaken stamped with room and actor", events)
+	}
+	if !ctx.Custom.Has("test_shake") || ctx.Custom.Has("say") || ctx.Custom.Has("shake") {
+		t.Error("Has should report only types added to the registry")
+	}
+}
+
+func TestRegistry_WithoutCustom(t *testing.T) {
+	s, defs, ctx := testSetup()
+	_, output := Apply(s, defs, []types.Effect{{Type: "test_shake"}}, ctx)
+	if len(output) != 0 || s.Counters["shakes"] != 0 {
+		t.Errorf("an effect type not in the context's registry should be ignored, got %v", output)
+	}
+}
+
+func TestRegistry_AddErrors(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Add("test_shake", shake); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		fn   Handler
+	}{
+		{"say", shake},
+		{"stop", shake},
+		{"test_shake", shake},
+		{"", shake},
+		{"test_quake", nil},
+	}
+	for _, tt := range tests {
+		if err := r.Add(tt.name, tt.fn); err == nil {
+			t.Errorf("Add(%q) should fail", tt.name)
+		}
+	}
+	if r.Has("test_quake") {
+		t.Error("a type that failed to add should not be in the registry")
+	}
+}
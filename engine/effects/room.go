@@ -0,0 +1,109 @@
+package effects
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/messages"
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// DescribeRoom lists a room's description (if withDescription), the
+// entities in it and its exits, as the player sees them there. Unvisited
+// rooms use their first_description if set, and rooms at night their
+// night_description. vehicle is the entity the player is in there, said
+// apart from the room's contents; "" if none. It returns nil for an
+// unknown room.
+func DescribeRoom(s *types.State, defs *state.Defs, roomID string, withDescription bool, vehicle string) []string {
+	room, ok := defs.Rooms[roomID]
+	if !ok {
+		return nil
+	}
+
+	var output []string
+	if withDescription {
+		if room.FirstDescription != "" && !state.HasVisited(s, roomID) {
+			output = append(output, room.FirstDescription)
+		} else if room.NightDescription != "" && state.IsNight(s, defs) {
+			output = append(output, room.NightDescription)
+		} else {
+			output = append(output, room.Description)
+		}
+	}
+
+	// Say what the player is in, and list the other visible entities.
+	if vehicle != "" {
+		output = append(output, messages.Text(defs.Messages, "in_vehicle", "vehicle", markup.Bold(entityName(s, defs, vehicle))))
+	}
+	var names []string
+	for _, id := range state.EntitiesInRoom(s, defs, roomID) {
+		if id != vehicle {
+			names = append(names, markup.Bold(entityName(s, defs, id)))
+		}
+	}
+	if len(names) > 0 {
+		output = append(output, messages.Text(defs.Messages, "room_contents", "list", strings.Join(names, ", ")))
+	}
+
+	// List exits.
+	if line := ExitsLine(s, defs, roomID); line != "" {
+		output = append(output, line)
+	}
+
+	return output
+}
+
+// ExitBlocked reports whether the exit in direction from roomID has a guard
+// that stops the player now, and returns the guard.
+func ExitBlocked(s *types.State, defs *state.Defs, roomID, direction string) (types.ExitGuardDef, bool) {
+	guard, ok := defs.Rooms[roomID].ExitGuards[direction]
+	if !ok {
+		return guard, false
+	}
+	if guard.Vehicle != "" && s.Player.Vehicle != guard.Vehicle {
+		return guard, true
+	}
+	if guard.Door != "" && state.IsLocked(s, defs, guard.Door) {
+		return guard, true
+	}
+	return guard, !rules.EvalAllConditions(guard.Requires, s, defs)
+}
+
+// ExitDirs returns the directions of the exits from roomID, sorted, less
+// those blocked by a guard that hides them while blocked.
+func ExitDirs(s *types.State, defs *state.Defs, roomID string) []string {
+	exits := state.RoomExits(s, defs, roomID)
+	dirs := make([]string, 0, len(exits))
+	for dir := range exits {
+		if guard, blocked := ExitBlocked(s, defs, roomID, dir); blocked && !guard.VisibleWhenBlocked {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs) // deterministic order
+	return dirs
+}
+
+// ExitsLine returns the line listing the exits from roomID, as shown when
+// the room is described, or "" if there are none.
+func ExitsLine(s *types.State, defs *state.Defs, roomID string) string {
+	dirs := ExitDirs(s, defs, roomID)
+	if len(dirs) == 0 {
+		return ""
+	}
+	return markup.Note(messages.Text(defs.Messages, "room_exits", "list", strings.Join(dirs, ", ")))
+}
+
+// entityName returns the display name of an entity: its name prop, or its
+// ID.
+func entityName(s *types.State, defs *state.Defs, entityID string) string {
+	if name, ok := state.GetEntityProp(s, defs, entityID, "name"); ok {
+		if str, ok := name.(string); ok {
+			return str
+		}
+	}
+	return entityID
+}
@@ -0,0 +1,162 @@
+package effects
+
+import (
+	"reflect"
+	"slices"
+	"sort"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// Delta is what applying a list of effects would change in a game state.
+// Only changes are recorded: a flag set to the value it already had, say,
+// does not appear.
+type Delta struct {
+	Location string                    // room the player would move to ("" = stays put)
+	Gained   []string                  // items that would enter the inventory
+	Lost     []string                  // items that would leave it, worn ones included
+	Flags    map[string]bool           // flags that would change, with their new values
+	Counters map[string]int            // counters that would change, with their new values
+	Moved    map[string]string         // entities that would change location ("" = out of the world)
+	Stats    []StatChange              // stats of the player and enemies, such as hp
+	Props    map[string]map[string]any // other entity properties; exits are props of "room:<id>"
+	Ending   string                    // ending the game would reach ("" = none)
+	Events   []types.Event             // events Apply would emit
+	Output   []string                  // text Apply would show
+}
+
+// StatChange is a stat that would go from one value to another.
+type StatChange struct {
+	Target string // "player" or an entity ID
+	Stat   string
+	From   int
+	To     int
+}
+
+// Empty reports whether d changes nothing.
+func (d Delta) Empty() bool {
+	return d.Location == "" && len(d.Gained) == 0 && len(d.Lost) == 0 &&
+		len(d.Flags) == 0 && len(d.Counters) == 0 && len(d.Moved) == 0 &&
+		len(d.Stats) == 0 && len(d.Props) == 0 && d.Ending == ""
+}
+
+// statProps are the entity properties reported as stats rather than props.
+var statProps = []string{"hp", "max_hp", "attack", "defense"}
+
+// Simulate works out what Apply would do to s without touching it: it
+// applies the effects to a clone of s and compares the two. Tools use it to
+// look ahead — to explain what a rule would do, or to hint at what an
+// action achieves — and it is cheap enough to call for many rules a turn.
+//
+// ctx.Roll is not called: dice roll their average, as when Roll is nil, so
+// that looking ahead draws nothing from the game's RNG.
+func Simulate(s *types.State, defs *state.Defs, effects []types.Effect, ctx Context) Delta {
+	ctx.Roll = nil
+	after := state.Clone(s)
+	events, output := Apply(after, defs, effects, ctx)
+	d := diff(s, after, defs)
+	d.Events, d.Output = events, output
+	return d
+}
+
+// diff returns the changes that take before to after.
+func diff(before, after *types.State, defs *state.Defs) Delta {
+	var d Delta
+	if after.Player.Location != before.Player.Location {
+		d.Location = after.Player.Location
+	}
+	if after.Ending != before.Ending {
+		d.Ending = after.Ending
+	}
+	held := func(s *types.State) []string {
+		return append(slices.Clone(s.Player.Inventory), s.Player.Worn...)
+	}
+	for _, id := range after.Player.Inventory {
+		if !slices.Contains(held(before), id) {
+			d.Gained = append(d.Gained, id)
+		}
+	}
+	for _, id := range held(before) {
+		if !slices.Contains(held(after), id) && !slices.Contains(d.Lost, id) {
+			d.Lost = append(d.Lost, id)
+		}
+	}
+
+	for _, flag := range unionKeys(before.Flags, after.Flags) {
+		if after.Flags[flag] != before.Flags[flag] {
+			if d.Flags == nil {
+				d.Flags = map[string]bool{}
+			}
+			d.Flags[flag] = after.Flags[flag]
+		}
+	}
+	for _, counter := range unionKeys(before.Counters, after.Counters) {
+		if after.Counters[counter] != before.Counters[counter] {
+			if d.Counters == nil {
+				d.Counters = map[string]int{}
+			}
+			d.Counters[counter] = after.Counters[counter]
+		}
+	}
+
+	for _, id := range unionKeys(defs.Entities, after.Entities) {
+		from, to := state.EntityLocation(before, defs, id), state.EntityLocation(after, defs, id)
+		if from == to || slices.Contains(d.Gained, id) {
+			continue
+		}
+		if to == " " {
+			to = "" // give_item's "nowhere"
+		}
+		if d.Moved == nil {
+			d.Moved = map[string]string{}
+		}
+		d.Moved[id] = to
+	}
+
+	for _, stat := range unionKeys(before.Player.Stats, after.Player.Stats) {
+		if from, to := before.Player.Stats[stat], after.Player.Stats[stat]; from != to {
+			d.Stats = append(d.Stats, StatChange{Target: "player", Stat: stat, From: from, To: to})
+		}
+	}
+	for _, id := range unionKeys(before.Entities, after.Entities) {
+		was, is := before.Entities[id].Props, after.Entities[id].Props
+		for _, prop := range unionKeys(was, is) {
+			if slices.Contains(statProps, prop) {
+				from, _ := state.GetStat(before, defs, id, prop)
+				to, _ := state.GetStat(after, defs, id, prop)
+				if from != to {
+					d.Stats = append(d.Stats, StatChange{Target: id, Stat: prop, From: from, To: to})
+				}
+				continue
+			}
+			v, _ := state.GetEntityProp(after, defs, id, prop)
+			if old, _ := state.GetEntityProp(before, defs, id, prop); reflect.DeepEqual(old, v) {
+				continue
+			}
+			if d.Props == nil {
+				d.Props = map[string]map[string]any{}
+			}
+			if d.Props[id] == nil {
+				d.Props[id] = map[string]any{}
+			}
+			d.Props[id][prop] = v
+		}
+	}
+	return d
+}
+
+// unionKeys returns the keys of a and b, sorted and without repeats.
+func unionKeys[V, W any](a map[string]V, b map[string]W) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
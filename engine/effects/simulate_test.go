@@ -0,0 +1,111 @@
+package effects
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+func TestSimulate_LeavesStateAlone(t *testing.T) {
+	s, defs, ctx := testSetup()
+	s.Player.Stats = map[string]int{"hp": 10, "max_hp": 10}
+	before := state.Clone(s)
+
+	d := Simulate(s, defs, []types.Effect{
+		{Type: "say", Params: map[string]any{"text": "Click."}},
+		{Type: "give_item", Params: map[string]any{"item": "{object}"}},
+		{Type: "set_flag", Params: map[string]any{"flag": "door_unlocked", "value": true}},
+		{Type: "inc_counter", Params: map[string]any{"counter": "turns", "amount": 2}},
+		{Type: "set_prop", Params: map[string]any{"entity": "iron_door", "prop": "locked", "value": false}},
+		{Type: "open_exit", Params: map[string]any{"room": "hall", "direction": "north", "target": "entrance"}},
+		{Type: "move_entity", Params: map[string]any{"entity": "guard", "room": "entrance"}},
+		{Type: "damage", Params: map[string]any{"target": "player", "amount": 3}},
+		{Type: "move_player", Params: map[string]any{"room": "entrance"}},
+	}, ctx)
+
+	if !reflect.DeepEqual(s, before) {
+		t.Error("Simulate changed the state")
+	}
+	want := Delta{
+		Location: "entrance",
+		Gained:   []string{"rusty_key"},
+		Flags:    map[string]bool{"door_unlocked": true},
+		Counters: map[string]int{"turns": 2},
+		Moved:    map[string]string{"guard": "entrance"},
+		Stats:    []StatChange{{Target: "player", Stat: "hp", From: 10, To: 7}},
+		Props: map[string]map[string]any{
+			"iron_door": {"locked": false},
+			"room:hall": {"exit:north": "entrance"},
+		},
+	}
+	got := d
+	got.Events, got.Output = nil, nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("delta = %+v\nwant    %+v", got, want)
+	}
+	if len(d.Output) != 1 || d.Output[0] != "Click." {
+		t.Errorf("output = %v", d.Output)
+	}
+	if len(d.Events) == 0 {
+		t.Error("expected the events Apply would emit")
+	}
+}
+
+func TestSimulate_EnemyStatsAndLosses(t *testing.T) {
+	s, defs, ctx := testSetup()
+	defs.Entities["goblin"] = types.EntityDef{ID: "goblin", Kind: "enemy", Props: map[string]any{"location": "hall", "hp": 5}}
+	s.Player.Inventory = []string{"rusty_key"}
+	s.Player.Worn = []string{"rusty_key"}
+
+	d := Simulate(s, defs, []types.Effect{
+		{Type: "consume_item", Params: map[string]any{"item": "rusty_key"}},
+		{Type: "damage", Params: map[string]any{"target": "goblin", "amount": 2}},
+	}, ctx)
+
+	if !reflect.DeepEqual(d.Lost, []string{"rusty_key"}) {
+		t.Errorf("lost = %v", d.Lost)
+	}
+	if loc, ok := d.Moved["rusty_key"]; !ok || loc != "" {
+		t.Errorf("moved = %v, want the key out of the world", d.Moved)
+	}
+	want := []StatChange{{Target: "goblin", Stat: "hp", From: 5, To: 3}}
+	if !reflect.DeepEqual(d.Stats, want) {
+		t.Errorf("stats = %+v, want %+v", d.Stats, want)
+	}
+}
+
+func TestSimulate_NoChange(t *testing.T) {
+	s, defs, ctx := testSetup()
+	s.Flags["lit"] = true
+	d := Simulate(s, defs, []types.Effect{
+		{Type: "set_flag", Params: map[string]any{"flag": "lit", "value": true}},
+		{Type: "say", Params: map[string]any{"text": "Still lit."}},
+	}, ctx)
+	if !d.Empty() {
+		t.Errorf("delta = %+v, want empty", d)
+	}
+}
+
+func TestSimulate_DrawsNoRolls(t *testing.T) {
+	s, defs, ctx := testSetup()
+	s.Player.Stats = map[string]int{"hp": 10, "max_hp": 10}
+	var rolls int
+	ctx.Roll = func(string, int) int {
+		rolls++
+		return 1
+	}
+
+	d := Simulate(s, defs, []types.Effect{
+		{Type: "damage", Params: map[string]any{"target": "player", "amount": "2d4+1"}},
+	}, ctx)
+
+	if rolls != 0 {
+		t.Errorf("Simulate rolled %d dice from the context", rolls)
+	}
+	want := []StatChange{{Target: "player", Stat: "hp", From: 10, To: 5}}
+	if !reflect.DeepEqual(d.Stats, want) {
+		t.Errorf("stats = %+v, want %+v: dice should roll their average", d.Stats, want)
+	}
+}
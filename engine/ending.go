@@ -0,0 +1,173 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// pendingEndingMenu is the pending-input kind for the post-ending menu.
+const pendingEndingMenu = "ending_menu"
+
+// answerPending routes input to the prompt currently awaiting an answer.
+func (e *Engine) answerPending(input string) types.Result {
+	if id, ok := strings.CutPrefix(e.State.Pending, pendingAnswerPrefix); ok {
+		return e.answerQuestion(id, input)
+	}
+	switch e.State.Pending {
+	case pendingEndingMenu:
+		return e.answerEndingMenu(input)
+	case pendingRestartConfirm:
+		return e.answerRestartConfirm(input)
+	default:
+		// Unknown prompt kind (e.g. from a newer save) — drop it.
+		e.State.Pending = ""
+		return e.step(input)
+	}
+}
+
+// offerEndingMenu puts up the post-ending menu once the game is over. If the
+// game ended through one of its endings, the menu comes with the epilogue
+// instead of in the output, so front ends can show the two together.
+func (e *Engine) offerEndingMenu(result *types.Result) {
+	e.State.Pending = pendingEndingMenu
+	e.choices = e.endingChoices()
+	if e.State.Ending != "" {
+		result.Epilogue = e.epilogue()
+		result.Epilogue.Prompt = e.endingPrompt()
+		return
+	}
+	result.Output = append(result.Output, "", e.endingPrompt())
+}
+
+// epilogue describes the ending the player reached.
+func (e *Engine) epilogue() *types.Epilogue {
+	ep := &types.Epilogue{
+		Ending:   e.State.Ending,
+		Title:    e.State.Ending,
+		Score:    e.State.Counters["score"],
+		MaxScore: e.Defs.Game.MaxScore,
+		Turns:    e.State.TurnCount,
+	}
+	if def, ok := e.Defs.Endings[e.State.Ending]; ok {
+		ep.Title = def.Title
+		ep.Text = def.Text
+	}
+	ep.ScoreLine = e.scoreLine(ep)
+	return ep
+}
+
+// EpilogueLines renders an epilogue as output lines for line-based front
+// ends: a title header, the epilogue text, the score line, and the ending
+// menu.
+func EpilogueLines(ep *types.Epilogue) []string {
+	lines := []string{"", "# " + markup.Escape(ep.Title), ""}
+	if ep.Text != "" {
+		lines = append(lines, ep.Text, "")
+	}
+	lines = append(lines, ep.ScoreLine)
+	if ep.Prompt != "" {
+		lines = append(lines, "", ep.Prompt)
+	}
+	return lines
+}
+
+// scoreLine summarizes the player's score and turn count for an epilogue.
+func (e *Engine) scoreLine(ep *types.Epilogue) string {
+	switch {
+	case ep.MaxScore > 0:
+		return e.msg("score_out_of", "score", ep.Score, "max", ep.MaxScore, "turns", ep.Turns)
+	case ep.Score != 0:
+		return e.msg("score", "score", ep.Score, "turns", ep.Turns)
+	default:
+		return e.msg("score_turns", "turns", ep.Turns)
+	}
+}
+
+// endingPrompt returns the post-ending menu text.
+func (e *Engine) endingPrompt() string {
+	if len(e.Defs.Game.Amusing) > 0 {
+		return e.msg("ending_menu_amusing")
+	}
+	return e.msg("ending_menu")
+}
+
+// endingChoices returns the options of the post-ending menu.
+func (e *Engine) endingChoices() []types.Choice {
+	choices := []types.Choice{
+		{Label: e.msg("choice_restart"), Command: "restart"},
+		{Label: e.msg("choice_restore"), Command: "restore"},
+	}
+	if len(e.Defs.Game.Amusing) > 0 {
+		choices = append(choices, types.Choice{Label: e.msg("choice_amusing"), Command: "amusing"})
+	}
+	return choices
+}
+
+// answerEndingMenu handles a reply to the post-ending menu. The menu stays
+// pending until the player restarts or loads a save.
+func (e *Engine) answerEndingMenu(input string) types.Result {
+	var result types.Result
+	switch strings.ToLower(strings.TrimSpace(input)) {
+	case "restart":
+		return e.restart()
+	case "restore":
+		result.Output = append(result.Output, e.msg("ending_restore"))
+	case "amusing":
+		if len(e.Defs.Game.Amusing) > 0 {
+			result.Output = append(result.Output, e.amusingLines()...)
+			result.Output = append(result.Output, "", e.endingPrompt())
+			e.choices = e.endingChoices()
+			return result
+		}
+		result.Output = append(result.Output, e.endingPrompt())
+		e.choices = e.endingChoices()
+	default:
+		result.Output = append(result.Output, e.endingPrompt())
+		e.choices = e.endingChoices()
+	}
+	return result
+}
+
+// amusingLines lists the amusing entries whose conditions hold.
+func (e *Engine) amusingLines() []string {
+	var lines []string
+	for _, entry := range e.Defs.Game.Amusing {
+		if rules.EvalAllConditions(entry.Conditions, e.State, e.Defs) {
+			lines = append(lines, "- "+entry.Text)
+		}
+	}
+	if len(lines) == 0 {
+		return []string{e.msg("amusing_none")}
+	}
+	return append([]string{e.msg("amusing")}, lines...)
+}
+
+// restart resets the game to its initial state, keeping the RNG seed and
+// the player's achievements.
+func (e *Engine) restart() types.Result {
+	var result types.Result
+	seed := e.State.RNGSeed
+	achievements := e.State.Achievements
+	music := e.State.Music
+	e.State = state.NewState(e.Defs)
+	e.State.RNGSeed = seed
+	e.State.Achievements = achievements
+	e.RNG = NewRNG(seed)
+	if music != "" {
+		// Nothing is playing yet in the new game.
+		result.Events = append(result.Events, types.Event{Type: "music_changed", Data: map[string]any{"music": ""}})
+	}
+
+	if e.Defs.Game.Intro != "" {
+		result.Output = append(result.Output, e.Defs.Game.Intro, "")
+	}
+	result.Output = append(result.Output, e.describeRoom(e.State.Player.Location)...)
+	ctx := effects.Context{Verb: "restart", Actor: "player", Strict: e.Strict, Custom: e.config.Effects, Roll: e.RNG.RollFor}
+	e.markVisited(ctx, &result)
+	return result
+}
@@ -0,0 +1,230 @@
+package engine
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// endingEngine returns an engine whose "wave" command ends the game.
+func endingEngine() *Engine {
+	defs := testDefs()
+	defs.Game.Intro = "Welcome back."
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID:    "wave_ends_game",
+		Scope: "global",
+		When:  types.MatchCriteria{Verb: "wave"},
+		Effects: []types.Effect{
+			{Type: "say", Params: map[string]any{"text": "You wave goodbye to the world."}},
+			{Type: "set_flag", Params: map[string]any{"flag": "game_over", "value": true}},
+		},
+	})
+	defs.Game.Amusing = []types.AmusingDef{
+		{Text: "Tried reading the book?", Conditions: []types.Condition{
+			{Type: "flag_not", Params: map[string]any{"flag": "read_book"}},
+		}},
+		{Text: "Found the secret cellar?", Conditions: []types.Condition{
+			{Type: "flag_set", Params: map[string]any{"flag": "found_cellar"}},
+		}},
+	}
+	return New(defs)
+}
+
+func TestEnding_MenuOfferedWhenGameEnds(t *testing.T) {
+	e := endingEngine()
+	result := e.Step("wave")
+
+	if !outputContains(result.Output, "RESTART, RESTORE a saved game, or see some AMUSING things?") {
+		t.Errorf("expected ending menu, got %v", result.Output)
+	}
+	if e.State.Pending != pendingEndingMenu {
+		t.Errorf("Pending = %q, want %q", e.State.Pending, pendingEndingMenu)
+	}
+}
+
+func TestEnding_MenuOffersChoices(t *testing.T) {
+	e := endingEngine()
+	result := e.Step("wave")
+	var commands []string
+	for _, c := range result.Choices {
+		commands = append(commands, c.Command)
+	}
+	if want := []string{"restart", "restore", "amusing"}; !slices.Equal(commands, want) {
+		t.Errorf("choices = %v, want %v", commands, want)
+	}
+	if got := e.Step("dance").Choices; len(got) != 3 {
+		t.Errorf("re-prompt choices = %v, want the menu again", got)
+	}
+}
+
+func TestEnding_MenuWithoutAmusingEntries(t *testing.T) {
+	e := endingEngine()
+	e.Defs.Game.Amusing = nil
+	result := e.Step("wave")
+
+	if !outputContains(result.Output, "Would you like to RESTART or RESTORE a saved game?") {
+		t.Errorf("expected menu without AMUSING, got %v", result.Output)
+	}
+}
+
+func TestEnding_AmusingListsOnlyPassingEntries(t *testing.T) {
+	e := endingEngine()
+	e.Step("wave")
+	result := e.Step("amusing")
+
+	if !outputContains(result.Output, "Tried reading the book?") {
+		t.Errorf("expected missed entry, got %v", result.Output)
+	}
+	if outputContains(result.Output, "secret cellar") {
+		t.Errorf("entry with failing conditions should be hidden, got %v", result.Output)
+	}
+	if e.State.Pending != pendingEndingMenu {
+		t.Error("menu should stay pending after AMUSING")
+	}
+}
+
+func TestEnding_OtherInputRepromptsAndBlocksGameplay(t *testing.T) {
+	e := endingEngine()
+	e.Step("wave")
+	result := e.Step("go north")
+
+	if e.State.Player.Location != "hall" {
+		t.Errorf("gameplay should be blocked, player moved to %q", e.State.Player.Location)
+	}
+	if !outputContains(result.Output, "Would you like to RESTART") {
+		t.Errorf("expected menu re-prompt, got %v", result.Output)
+	}
+}
+
+func TestEnding_RestoreHintsAtLoad(t *testing.T) {
+	e := endingEngine()
+	e.Step("wave")
+	result := e.Step("RESTORE")
+
+	if !outputContains(result.Output, "/load") {
+		t.Errorf("expected /load hint, got %v", result.Output)
+	}
+}
+
+func TestEnding_RestartResetsState(t *testing.T) {
+	e := endingEngine()
+	e.Step("take book")
+	e.Step("go north")
+	e.Step("wave")
+	result := e.Step("restart")
+
+	if e.State.Pending != "" {
+		t.Errorf("Pending = %q, want empty", e.State.Pending)
+	}
+	if e.State.Flags["game_over"] {
+		t.Error("game_over should be cleared after restart")
+	}
+	if e.State.Player.Location != "hall" || len(e.State.Player.Inventory) != 0 {
+		t.Errorf("expected fresh state, got location %q inventory %v",
+			e.State.Player.Location, e.State.Player.Inventory)
+	}
+	if !outputContains(result.Output, "Welcome back.") || !outputContains(result.Output, "grand hall") {
+		t.Errorf("expected intro and starting room, got %v", result.Output)
+	}
+
+	e.Step("go north")
+	if e.State.Player.Location != "garden" {
+		t.Error("gameplay should resume after restart")
+	}
+}
+
+func TestEnding_RestartMarksStartVisited(t *testing.T) {
+	defs := testDefs()
+	hall := defs.Rooms["hall"]
+	hall.FirstDescription = "You wake in the hall for the first time."
+	defs.Rooms["hall"] = hall
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "wave_ends_game", Scope: "global", When: types.MatchCriteria{Verb: "wave"},
+		Effects: []types.Effect{{Type: "set_flag", Params: map[string]any{"flag": "game_over", "value": true}}},
+	})
+	e := New(defs)
+	e.Step("look")
+	e.Step("wave")
+
+	result := e.Step("restart")
+	if !outputContains(result.Output, "for the first time") {
+		t.Errorf("expected first description on restarting, got %v", result.Output)
+	}
+	if !slices.ContainsFunc(result.Effects, func(eff types.Effect) bool { return eff.Type == "mark_visited" }) {
+		t.Errorf("restart should mark the start room visited through mark_visited, got %v", result.Effects)
+	}
+	result = e.Step("look")
+	if outputContains(result.Output, "for the first time") || !outputContains(result.Output, "grand hall") {
+		t.Errorf("expected regular description on the look after restarting, got %v", result.Output)
+	}
+}
+
+func TestEnding_GameOverWithoutPendingShowsMenu(t *testing.T) {
+	e := endingEngine()
+	e.State.Flags["game_over"] = true
+	result := e.Step("look")
+
+	if !outputContains(result.Output, "Would you like to RESTART") {
+		t.Errorf("expected ending menu, got %v", result.Output)
+	}
+	if e.State.Pending != pendingEndingMenu {
+		t.Error("expected ending menu to become pending")
+	}
+}
+
+func TestEnding_EndGameShowsEpilogue(t *testing.T) {
+	defs := testDefs()
+	defs.Game.MaxScore = 10
+	defs.Endings = map[string]types.EndingDef{
+		"good": {ID: "good", Title: "Victory", Text: "The kingdom rejoices."},
+	}
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "win", Scope: "global", When: types.MatchCriteria{Verb: "wave"},
+		Effects: []types.Effect{
+			{Type: "inc_counter", Params: map[string]any{"counter": "score", "amount": 7}},
+			{Type: "end_game", Params: map[string]any{"ending": "good"}},
+		},
+	})
+	e := New(defs)
+	e.Step("look")
+	result := e.Step("wave")
+
+	ep := result.Epilogue
+	if ep == nil {
+		t.Fatal("expected an epilogue")
+	}
+	if ep.Ending != "good" || ep.Title != "Victory" || ep.Text != "The kingdom rejoices." || ep.Prompt == "" {
+		t.Errorf("epilogue = %+v", ep)
+	}
+	if got := ep.ScoreLine; got != "You scored 7 out of a possible 10, in 2 turns." {
+		t.Errorf("ScoreLine = %q", got)
+	}
+	if e.State.Ending != "good" || e.State.Pending != pendingEndingMenu {
+		t.Errorf("Ending = %q, Pending = %q", e.State.Ending, e.State.Pending)
+	}
+
+	// The game stays over until the player restarts.
+	result = e.Step("north")
+	if e.State.Player.Location != "hall" || result.Epilogue != nil {
+		t.Errorf("expected the post-game state to stay locked, got %v", result.Output)
+	}
+	e.Step("restart")
+	if e.State.Ending != "" {
+		t.Errorf("restart should clear the ending, got %q", e.State.Ending)
+	}
+}
+
+func TestEnding_RestartStopsMusic(t *testing.T) {
+	e := endingEngine()
+	e.State.Music = "finale"
+	e.Step("wave")
+
+	result := e.Step("restart")
+	if want := []types.Cue{{Type: "music", Name: ""}}; !slices.Equal(result.Cues, want) {
+		t.Errorf("Cues = %v, want %v", result.Cues, want)
+	}
+	if e.State.Music != "" {
+		t.Errorf("Music = %q after restart", e.State.Music)
+	}
+}
@@ -3,72 +3,248 @@
 package engine
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/nathoo/questcore/engine/dialogue"
 	"github.com/nathoo/questcore/engine/effects"
 	"github.com/nathoo/questcore/engine/events"
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/messages"
 	"github.com/nathoo/questcore/engine/parser"
 	"github.com/nathoo/questcore/engine/resolve"
 	"github.com/nathoo/questcore/engine/rules"
 	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/engine/trace"
 	"github.com/nathoo/questcore/types"
 )
 
-// Engine holds the game definitions and mutable state.
+// Engine holds the game definitions and mutable state. An Engine is for one
+// goroutine at a time; wrap it in a Locked to share it.
 type Engine struct {
 	Defs  *state.Defs
 	State *types.State
 	RNG   *RNG
+
+	// Strict makes effects that reference unknown entities or rooms an
+	// error (the remaining effects are skipped) instead of a trace warning.
+	Strict bool
+
+	// LogWriter, if set, receives every logged command as a line, so a full
+	// transcript survives however short the in-memory command log is.
+	LogWriter io.Writer
+
+	// Trace, if set, receives a record of each decision made while running
+	// a command, for debugging a game; see the trace package.
+	Trace trace.Sink
+
+	config Config
+	hooks  hooks
+
+	// image is the illustration for the step in progress; see Result.Image.
+	image string
+	// choices are the options offered by the step in progress; see
+	// Result.Choices.
+	choices []types.Choice
+	// turn is the turn of the step in progress, for trace records.
+	turn int
+	// session is the statistics since the engine was made, across loads
+	// and restarts; see Stats.
+	session types.Stats
 }
 
-// New creates a new engine from definitions.
-func New(defs *state.Defs) *Engine {
+// New creates a new engine from definitions, configured by opts:
+//
+//	eng := engine.New(defs, engine.WithSeed(42), engine.WithBuiltinsDisabled("take"))
+func New(defs *state.Defs, opts ...Option) *Engine {
+	cfg := Config{MaxEventDepth: 1}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.MaxEventDepth = max(cfg.MaxEventDepth, 1)
+	if cfg.Clock != nil || cfg.Conditions != nil {
+		d := *defs
+		if cfg.Clock != nil {
+			d.Game.Clock = cfg.Clock
+		}
+		if cfg.Conditions != nil {
+			d.SetConditions(cfg.Conditions)
+		}
+		d.Freeze() // the copy is the engine's own, and as fixed as the original
+		defs = &d
+	}
+
 	s := state.NewState(defs)
+	s.RNGSeed = cfg.Seed
 	return &Engine{
-		Defs:  defs,
-		State: s,
-		RNG:   NewRNG(s.RNGSeed),
+		Defs:   defs,
+		State:  s,
+		RNG:    NewRNG(s.RNGSeed),
+		config: cfg,
 	}
 }
 
+// Config returns the engine's configuration.
+func (e *Engine) Config() Config {
+	return e.config
+}
+
+// SetSeed restarts the RNG from seed, so that a session can be replayed
+// with the same dice.
+func (e *Engine) SetSeed(seed int64) {
+	e.State.RNGSeed = seed
+	e.State.RNGPosition = 0
+	e.RNG = NewRNG(seed)
+}
+
 // RestoreRNG re-creates the RNG from seed and advances to the saved position.
 func (e *Engine) RestoreRNG(seed int64, position int64) {
 	e.RNG = RestoreRNG(seed, position)
 }
 
-// Step processes one player command and returns the result.
+// Snapshot returns a copy of the game's state, which Restore can return
+// the game to, as often as needed.
+func (e *Engine) Snapshot() *types.State {
+	return state.Clone(e.State)
+}
+
+// Restore returns the game to a state taken with Snapshot, dice included.
+func (e *Engine) Restore(snap *types.State) {
+	e.State = state.Clone(snap)
+	e.RNG = RestoreRNG(snap.RNGSeed, snap.RNGPosition)
+}
+
+// Look describes the player's room, as "look" does, without taking a turn.
+// Front ends show it after restoring a game, where stepping "look" would
+// answer a pending prompt, such as a riddle, instead. A pending ending menu
+// is offered again.
+func (e *Engine) Look() types.Result {
+	e.image = ""
+	result := types.Result{Output: e.describeRoom(state.PlayerLocation(e.State))}
+	result.Image = e.image
+	if e.State.Pending == pendingEndingMenu {
+		result.Output = append(result.Output, "", e.endingPrompt())
+		result.Choices = e.endingChoices()
+	}
+	return result
+}
+
+// Step processes one player command and returns the result. If a
+// BeforeStepHook vetoes the command, the result is a failure saying why.
 func (e *Engine) Step(input string) types.Result {
+	result, err := e.StepContext(context.Background(), input)
+	if err != nil {
+		return types.Result{Output: []string{markup.Fail(markup.Escape(err.Error()))}, Failed: true}
+	}
+	return result
+}
+
+// run processes one player command, without the hooks.
+func (e *Engine) run(input string) types.Result {
+	e.image, e.choices, e.turn = "", nil, e.State.TurnCount
+	visited, rng, pos := len(e.State.Visited), e.RNG, e.RNG.Position()
+	result := e.step(input)
+	e.tally(&result, visited, rng, pos)
+	if checkDefs {
+		if err := e.Defs.CheckFrozen(); err != nil {
+			e.record(trace.Effects, trace.Info, map[string]any{"input": input}, "step %q: %v", input, err)
+			result.Output = append(result.Output, markup.Fail(markup.Escape(err.Error())))
+			result.Failed = true
+		}
+	}
+	result.Image, result.Choices = e.image, e.choices
+	e.traceResult(result)
+	for _, evt := range result.Events {
+		switch evt.Type {
+		case "notification":
+			if text, ok := evt.Data["text"].(string); ok {
+				result.Notifications = append(result.Notifications, text)
+			}
+		case "achievement_unlocked":
+			if id, ok := evt.Data["achievement"].(string); ok {
+				result.Notifications = append(result.Notifications, e.msg("achievement_unlocked", "name", e.achievementName(id)))
+			}
+		case "fact_learned":
+			if id, ok := evt.Data["fact"].(string); ok {
+				if text := e.Defs.Facts[id].Text; text != "" {
+					result.Notifications = append(result.Notifications, e.msg("fact_learned", "text", text))
+				}
+			}
+		case "sound_played":
+			name, _ := evt.Data["sound"].(string)
+			result.Cues = append(result.Cues, types.Cue{Type: "sound", Name: name})
+		case "music_changed":
+			name, _ := evt.Data["music"].(string)
+			result.Cues = append(result.Cues, types.Cue{Type: "music", Name: name})
+		}
+	}
+	return result
+}
+
+func (e *Engine) step(input string) types.Result {
 	var result types.Result
 
-	// 0. Game over — block all gameplay commands.
+	// 0. Pending prompt — the input answers it instead of being parsed.
+	if e.State.Pending != "" {
+		e.record(trace.Parse, trace.Info, map[string]any{"input": input, "pending": e.State.Pending},
+			"%q answers the %s prompt", input, e.State.Pending)
+		return e.answerPending(input)
+	}
+
+	// 0a. Game over — only the ending menu is available.
 	if state.GetFlag(e.State, "game_over") {
-		result.Output = append(result.Output, "Game over. Use /load to restore a save or /quit to exit.")
+		e.State.Pending = pendingEndingMenu
+		result.Output = append(result.Output, e.endingPrompt())
+		e.choices = e.endingChoices()
 		return result
 	}
 
 	// 1. Parse input.
 	intent := parser.Parse(input)
+	e.traceParse(input, intent)
 
 	// 2. Log the command.
-	e.State.CommandLog = append(e.State.CommandLog, input)
+	e.logCommand(input)
 
 	// 3. Empty input.
 	if intent.Verb == "" {
-		result.Output = append(result.Output, "What do you want to do?")
+		result.Output = append(result.Output, e.fail("empty_input"))
+		result.Failed = true
+		return result
+	}
+
+	// 3a. Description mode commands are player preferences, not world actions.
+	if key, ok := verbosityModes[intent.Verb]; ok && intent.Object == "" {
+		eff := types.Effect{Type: "set_verbosity", Params: map[string]any{"mode": intent.Verb}}
+		ctx := effects.Context{Verb: intent.Verb, Actor: "player", Strict: e.Strict, Custom: e.config.Effects, Roll: e.RNG.RollFor}
+		effects.Apply(e.State, e.Defs, []types.Effect{eff}, ctx)
+		result.Effects = append(result.Effects, eff)
+		result.Output = append(result.Output, e.msg(key))
+		return result
+	}
+
+	// 3b. Restart starts a fresh game, once the player confirms.
+	if intent.Verb == "restart" && intent.Object == "" {
+		e.State.Pending = pendingRestartConfirm
+		result.Output = append(result.Output, e.msg("restart_confirm"))
+		e.choices = []types.Choice{{Label: e.msg("choice_yes"), Command: "yes"}, {Label: e.msg("choice_no"), Command: "no"}}
 		return result
 	}
 
-	// 3a. Combat mode: rewrite "go" → "flee" and restrict commands.
+	// 3c. Combat mode: rewrite "go" → "flee" and restrict commands.
 	if state.InCombat(e.State) {
 		if intent.Verb == "go" {
 			intent.Verb = "flee"
 			intent.Object = ""
 		}
-		if !isCombatVerb(intent.Verb) {
-			result.Output = append(result.Output, "You're in the middle of a fight! (attack, defend, use <item>, flee)")
+		if !isCombatVerb(intent.Verb) && (intent.Verb != "talk" || !e.canNegotiate()) {
+			result.Output = append(result.Output, e.fail("combat_only"))
 			return result
 		}
 	}
@@ -78,11 +254,11 @@ func (e *Engine) Step(input string) types.Result {
 	var resolveErr error
 
 	switch intent.Verb {
-	case "go":
+	case "go", "sneak":
 		// Direction is the object, no entity resolution needed.
 		objectID = intent.Object
 
-	case "inventory", "wait":
+	case "inventory", "wait", "stats", "achievements", "notes":
 		// No resolution needed.
 
 	case "attack":
@@ -96,15 +272,19 @@ func (e *Engine) Step(input string) types.Result {
 	case "defend", "flee":
 		// No resolution needed.
 
-	case "talk":
-		// Resolve only the NPC (object), not the topic (target).
-		if intent.Object != "" {
-			res, err := resolve.Resolve(e.State, e.Defs, types.Intent{Verb: "talk", Object: intent.Object})
+	case "talk", "topics":
+		// Resolve only the NPC (object), not the topic (target). In combat
+		// the player talks to the enemy unless they name someone else.
+		if state.InCombat(e.State) && intent.Object == "" {
+			objectID = e.State.Combat.EnemyID
+		} else if intent.Object != "" {
+			res, err := resolve.Resolve(e.State, e.Defs, types.Intent{Verb: intent.Verb, Object: intent.Object})
 			if err != nil {
 				resolveErr = err
 			} else {
 				objectID = res.ObjectID
 			}
+			e.traceResolve(intent, objectID, "", err)
 		}
 
 	case "look":
@@ -130,8 +310,38 @@ func (e *Engine) Step(input string) types.Result {
 		}
 	}
 
+	ctx := effects.Context{Verb: intent.Verb, ObjectID: objectID, TargetID: targetID, Actor: "player", Strict: e.Strict, Custom: e.config.Effects, Roll: e.RNG.RollFor}
+	var evts []types.Event
+
+	// 5a. Before-phase rules run ahead of the command; a Stop() in one
+	// vetoes the command.
+	vetoed := false
+	e.traceRules("before", intent, objectID, targetID, false)
+	if before := rules.EvaluatePhase(e.State, e.Defs, "before", intent, objectID, targetID, false); before != nil {
+		beforeEvts, beforeOut := effects.Apply(e.State, e.Defs, before, ctx)
+		result.Effects = append(result.Effects, before...)
+		result.Output = append(result.Output, beforeOut...)
+		evts = append(evts, beforeEvts...)
+		vetoed = hasStop(before)
+	}
+
 	// 6. Run rules pipeline.
-	effs, matched := rules.Evaluate(e.State, e.Defs, intent, objectID, targetID)
+	var pre, effs []types.Effect
+	matched := vetoed
+	if !vetoed {
+		e.traceRules("", intent, objectID, targetID, false)
+		pre, effs, matched = rules.EvaluateChain(e.State, e.Defs, intent, objectID, targetID)
+	}
+	succeeded := matched && !vetoed
+
+	// 6a. Rules that Continue() decorate whatever handles the command next,
+	// so their effects apply first.
+	if len(pre) > 0 {
+		preEvts, preOut := effects.Apply(e.State, e.Defs, pre, ctx)
+		result.Effects = append(result.Effects, pre...)
+		result.Output = append(result.Output, preOut...)
+		evts = append(evts, preEvts...)
+	}
 
 	// 7. If a rule matched, the resolution failure doesn't matter.
 	if matched {
@@ -141,11 +351,16 @@ func (e *Engine) Step(input string) types.Result {
 	// 7a. No rule matched AND resolution failed → scenery fallback or error.
 	if !matched && resolveErr != nil {
 		if msg := e.sceneryFallback(intent); msg != "" {
+			e.traceOutcome("the scenery fallback")
 			result.Output = append(result.Output, msg)
 		} else {
-			result.Output = append(result.Output, resolveErr.Error())
+			e.traceOutcome("the resolve failure")
+			result.Output = append(result.Output, markup.Fail(e.resolveMessage(resolveErr)))
+			e.choices = e.whichChoices(input, resolveErr)
 		}
-		e.State.TurnCount++
+		result.Events = append(result.Events, evts...)
+		result.Failed = true
+		e.passTurn()
 		return result
 	}
 
@@ -153,37 +368,48 @@ func (e *Engine) Step(input string) types.Result {
 	if !matched {
 		if state.InCombat(e.State) {
 			// Default combat behavior.
-			combatEffs, combatOut := e.defaultCombatBehavior(intent, "player")
+			e.traceOutcome("the default combat behavior")
+			combatEffs, combatOut := e.defaultCombatBehavior(intent, "player", objectID, targetID)
 			effs = combatEffs
+			succeeded = len(combatEffs) > 0
 			result.Output = append(result.Output, combatOut...)
 		} else {
-			builtinEffs, builtinOut := e.builtinBehavior(intent, objectID)
+			builtinEffs, builtinOut := e.builtinBehavior(intent, objectID, targetID)
 			if builtinOut != nil || builtinEffs != nil {
+				e.traceOutcome("the built-in " + intent.Verb)
 				// Built-in handled this verb. Use its output instead of fallback.
 				effs = builtinEffs
+				succeeded = len(builtinEffs) > 0
 				result.Output = append(result.Output, builtinOut...)
 			}
 			// If built-in didn't handle it either, fall through with fallback effs.
+			result.Failed = builtinOut == nil && builtinEffs == nil
+			if result.Failed && e.Trace != nil {
+				e.traceOutcome("the " + rules.FallbackSource(e.State, e.Defs, intent.Verb, objectID))
+			}
 		}
 	}
 
 	// 8. Apply effects.
-	ctx := effects.Context{Verb: intent.Verb, ObjectID: objectID, TargetID: targetID, Actor: "player"}
-	evts, output := effects.Apply(e.State, e.Defs, effs, ctx)
+	mainEvts, output := effects.Apply(e.State, e.Defs, effs, ctx)
+	evts = append(evts, mainEvts...)
 	result.Effects = append(result.Effects, effs...)
-	result.Events = append(result.Events, evts...)
 	result.Output = append(result.Output, output...)
 
-	// 9. Dispatch events (single pass).
-	eventEffs := events.Dispatch(evts, e.State, e.Defs)
-
-	// 10. Apply event effects (events NOT re-dispatched).
-	if len(eventEffs) > 0 {
-		evts2, output2 := effects.Apply(e.State, e.Defs, eventEffs, ctx)
-		result.Effects = append(result.Effects, eventEffs...)
-		result.Events = append(result.Events, evts2...)
-		result.Output = append(result.Output, output2...)
+	// 8a. After-phase rules react to the command's outcome.
+	if !vetoed {
+		e.traceRules("after", intent, objectID, targetID, succeeded)
+		if after := rules.EvaluatePhase(e.State, e.Defs, "after", intent, objectID, targetID, succeeded); after != nil {
+			afterEvts, afterOut := effects.Apply(e.State, e.Defs, after, ctx)
+			evts = append(evts, afterEvts...)
+			result.Effects = append(result.Effects, after...)
+			result.Output = append(result.Output, afterOut...)
+		}
 	}
+	result.Events = append(result.Events, evts...)
+
+	// 9-10. Dispatch events and apply their effects.
+	e.dispatch(evts, ctx, &result)
 
 	// 10a. Loot processing: if an enemy was defeated, roll for drops.
 	for _, evt := range result.Events {
@@ -195,6 +421,8 @@ func (e *Engine) Step(input string) types.Result {
 					result.Effects = append(result.Effects, lootEffs...)
 					result.Events = append(result.Events, lootEvts...)
 					result.Output = append(result.Output, lootOutput...)
+					// Handlers may react to the rewards (player_leveled, ...).
+					e.dispatch(lootEvts, ctx, &result)
 				}
 				result.Output = append(result.Output, lootOut...)
 			}
@@ -202,8 +430,9 @@ func (e *Engine) Step(input string) types.Result {
 		}
 	}
 
-	// 11. Enemy turn (if still in combat after player's action).
-	if state.InCombat(e.State) {
+	// 11. Enemy turn (if still in combat after player's action), unless
+	// the player won the initiative or took the enemy by surprise.
+	if state.InCombat(e.State) && e.enemyActs(&result) {
 		enemyResult := e.runEnemyTurn()
 		result.Effects = append(result.Effects, enemyResult.Effects...)
 		result.Events = append(result.Events, enemyResult.Events...)
@@ -224,54 +453,153 @@ func (e *Engine) Step(input string) types.Result {
 		}
 	}
 
-	// 13. Track RNG position for save/load.
+	// 12a. Death policy: respawn or restart instead of ending the game.
+	if playerDied(result.Events) && e.applyDeathPolicy(&result, ctx) {
+		return result
+	}
+
+	// 12b. Noise carries to the NPCs in earshot.
+	if succeeded {
+		e.makeNoise(intent.Verb, ctx, &result)
+	}
+
+	// 12c. Room ambience, rolled once the turn's events have played out.
+	if line := e.ambience(); line != "" {
+		result.Output = append(result.Output, line)
+	}
+
+	// 13. Track RNG position for save/load, and report this turn's draws.
 	e.State.RNGPosition = e.RNG.Position()
+	result.Events = append(result.Events, drawEvents(e.RNG.TakeDraws())...)
 
-	// 14. Increment turn count.
-	e.State.TurnCount++
+	// 14. Advance the turn (and clock) and record the room the player ends up in.
+	// Sneaking takes two turns.
+	e.passTurn()
+	if intent.Verb == "sneak" && succeeded {
+		e.passTurn()
+	}
+	e.markVisited(ctx, &result)
+
+	// 15. Ending reached this turn — offer the ending menu.
+	if state.GetFlag(e.State, "game_over") {
+		e.offerEndingMenu(&result)
+	}
 
 	return result
 }
 
+// passTurn increments the turn count and advances the clock, if the game
+// has one, by a turn's worth of minutes.
+func (e *Engine) passTurn() {
+	e.State.TurnCount++
+	if c := e.Defs.Game.Clock; c != nil {
+		e.State.Clock += c.MinutesPerTurn
+	}
+}
+
+// logCommand records input in the state's command log and streams it to
+// LogWriter. A failed write stops streaming rather than failing the turn.
+func (e *Engine) logCommand(input string) {
+	state.LogCommand(e.State, input, e.Defs.Game.CommandLogLimit)
+	if e.LogWriter != nil {
+		if _, err := fmt.Fprintln(e.LogWriter, input); err != nil {
+			e.LogWriter = nil
+		}
+	}
+}
+
+// dispatch hands evts to the event handlers and applies their effects to
+// result. The events those effects raise are dispatched in turn, up to the
+// configured MaxEventDepth; by default they are not.
+func (e *Engine) dispatch(evts []types.Event, ctx effects.Context, result *types.Result) {
+	for depth := 0; depth < e.config.MaxEventDepth; depth++ {
+		effs := events.Dispatch(evts, e.State, e.Defs)
+		if len(effs) == 0 {
+			return
+		}
+		var output []string
+		evts, output = effects.Apply(e.State, e.Defs, effs, ctx)
+		result.Effects = append(result.Effects, effs...)
+		result.Events = append(result.Events, evts...)
+		result.Output = append(result.Output, output...)
+	}
+}
+
+// hasStop reports whether effs contains a Stop().
+func hasStop(effs []types.Effect) bool {
+	for _, eff := range effs {
+		if eff.Type == "stop" {
+			return true
+		}
+	}
+	return false
+}
+
+// enemyActs reports whether the enemy takes its turn after the player's
+// action, announcing who has the initiative in the turn a fight starts.
+// An enemy that wins the initiative strikes at once; otherwise the player
+// acts first. An enemy taken by surprise also misses the turn after.
+func (e *Engine) enemyActs(result *types.Result) bool {
+	enemyName := e.combatantName(e.State.Combat.EnemyID)
+	for _, evt := range result.Events {
+		if evt.Type != "combat_started" || evt.Data["enemy"] != e.State.Combat.EnemyID {
+			continue
+		}
+		player, rolled := evt.Data["player_roll"].(int)
+		enemy, _ := evt.Data["enemy_roll"].(int)
+		switch {
+		case e.State.Combat.Surprise:
+			result.Output = append(result.Output, e.msg("combat_surprise", "enemy", enemyName))
+		case rolled && e.State.Combat.Initiative == "player":
+			result.Output = append(result.Output, e.msg("initiative_player", "enemy", enemyName, "player", player, "opponent", enemy))
+		case rolled:
+			result.Output = append(result.Output, e.msg("initiative_enemy", "enemy", enemyName, "player", player, "opponent", enemy))
+		}
+		return e.State.Combat.Initiative == "enemy"
+	}
+	if e.State.Combat.Surprise {
+		e.State.Combat.Surprise = false
+		result.Output = append(result.Output, e.msg("enemy_surprised", "enemy", enemyName))
+		return false
+	}
+	return true
+}
+
 // runEnemyTurn executes the enemy's turn through the same pipeline.
 func (e *Engine) runEnemyTurn() types.Result {
 	var result types.Result
 	enemyID := e.State.Combat.EnemyID
 
 	// Select enemy action.
-	enemyIntent := EnemyTurn(e.State, e.Defs, e.RNG)
+	enemyIntent, progress := EnemyTurn(e.State, e.Defs, e.RNG)
 
 	// Try rules pipeline with enemy as actor.
 	effs, matched := rules.Evaluate(e.State, e.Defs, enemyIntent, "", "")
 
 	if !matched {
 		// Use default combat behavior for enemy.
-		combatEffs, combatOut := e.defaultCombatBehavior(enemyIntent, enemyID)
+		combatEffs, combatOut := e.defaultCombatBehavior(enemyIntent, enemyID, "", "")
 		effs = combatEffs
 		result.Output = append(result.Output, combatOut...)
 	}
+	effs = append(progress, effs...)
 
 	// Apply enemy effects.
-	ctx := effects.Context{Verb: enemyIntent.Verb, Actor: enemyID}
+	ctx := effects.Context{Verb: enemyIntent.Verb, Actor: enemyID, Strict: e.Strict, Custom: e.config.Effects, Roll: e.RNG.RollFor}
 	evts, output := effects.Apply(e.State, e.Defs, effs, ctx)
 	result.Effects = append(result.Effects, effs...)
 	result.Events = append(result.Events, evts...)
 	result.Output = append(result.Output, output...)
 
 	// Dispatch events from enemy turn.
-	eventEffs := events.Dispatch(evts, e.State, e.Defs)
-	if len(eventEffs) > 0 {
-		evts2, output2 := effects.Apply(e.State, e.Defs, eventEffs, ctx)
-		result.Effects = append(result.Effects, eventEffs...)
-		result.Events = append(result.Events, evts2...)
-		result.Output = append(result.Output, output2...)
-	}
+	e.dispatch(evts, ctx, &result)
 
 	return result
 }
 
 // defaultCombatBehavior routes combat verbs to their default implementations.
-func (e *Engine) defaultCombatBehavior(intent types.Intent, actor string) ([]types.Effect, []string) {
+// objectID and targetID are the entities the player's command names.
+func (e *Engine) defaultCombatBehavior(intent types.Intent, actor, objectID, targetID string) ([]types.Effect, []string) {
 	switch intent.Verb {
 	case "attack":
 		return e.defaultCombatAttack(actor)
@@ -279,24 +607,112 @@ func (e *Engine) defaultCombatBehavior(intent types.Intent, actor string) ([]typ
 		return e.defaultCombatDefend(actor)
 	case "flee":
 		return e.defaultCombatFlee(actor)
+	case "use", "throw":
+		if actor != "player" {
+			return nil, nil
+		}
+		return e.defaultCombatUse(intent.Verb, objectID, targetID)
+	case "talk":
+		if actor != "player" {
+			return nil, nil
+		}
+		if enemyID := e.State.Combat.EnemyID; objectID != enemyID {
+			return nil, []string{e.fail("combat_use_target", "enemy", e.entityName(enemyID))}
+		}
+		return e.builtinTalk(intent, objectID)
+	case "stats":
+		return e.builtinStats()
 	default:
 		return nil, nil
 	}
 }
 
-// resolveEntities resolves intent object/target names to entity IDs.
+// canNegotiate reports whether the enemy the player is fighting will
+// listen to talk: its can_negotiate is true.
+func (e *Engine) canNegotiate() bool {
+	v, _ := state.GetEntityProp(e.State, e.Defs, e.State.Combat.EnemyID, "can_negotiate")
+	return v == true
+}
+
+// resolveEntities resolves intent object/target names to entity IDs. A
+// direction names no entity and passes through as itself, so rules can
+// match "throw rock to the north" with target "north".
 func (e *Engine) resolveEntities(intent types.Intent) (objectID, targetID string, err error) {
-	res, err := resolve.Resolve(e.State, e.Defs, intent)
+	lookup := intent
+	if _, ok := parser.Direction(intent.Object); ok {
+		lookup.Object = ""
+	}
+	if _, ok := parser.Direction(intent.Target); ok {
+		lookup.Target = ""
+	}
+	res, err := resolve.Resolve(e.State, e.Defs, lookup)
 	if err != nil {
+		e.traceResolve(intent, "", "", err)
 		return "", "", err
 	}
+	if lookup.Object == "" {
+		res.ObjectID = intent.Object
+	}
+	if lookup.Target == "" {
+		res.TargetID = intent.Target
+	}
+	e.traceResolve(intent, res.ObjectID, res.TargetID, nil)
 	return res.ObjectID, res.TargetID, nil
 }
 
+// resolveMessage words an entity resolution failure for the player.
+func (e *Engine) resolveMessage(err error) string {
+	var notFound *resolve.NotFoundError
+	var ambiguous *resolve.AmbiguityError
+	switch {
+	case errors.As(err, &notFound):
+		return e.msg("not_here", "name", notFound.Name)
+	case errors.As(err, &ambiguous):
+		return e.msg("which", "name", ambiguous.Name, "options", strings.Join(ambiguous.Candidates, ", "))
+	}
+	return err.Error()
+}
+
+// whichChoices offers the entities an ambiguous name could mean, each as
+// input with the name replaced by the entity's ID. It returns nil if err
+// isn't an ambiguity, or the name can't be found in input.
+func (e *Engine) whichChoices(input string, err error) []types.Choice {
+	var ambiguous *resolve.AmbiguityError
+	if !errors.As(err, &ambiguous) {
+		return nil
+	}
+	lower := strings.ToLower(input)
+	i := strings.Index(lower, strings.ToLower(ambiguous.Name))
+	if i < 0 || len(lower) != len(input) {
+		return nil
+	}
+	choices := make([]types.Choice, len(ambiguous.Candidates))
+	for j, id := range ambiguous.Candidates {
+		choices[j] = types.Choice{
+			Label:   e.entityName(id),
+			Command: input[:i] + id + input[i+len(ambiguous.Name):],
+		}
+	}
+	return choices
+}
+
+// msg returns the message for key from the catalog, as the game words it.
+func (e *Engine) msg(key string, args ...any) string {
+	return messages.Text(e.Defs.Messages, key, args...)
+}
+
+// fail is like msg, for a message saying the command did nothing.
+func (e *Engine) fail(key string, args ...any) string {
+	return markup.Fail(e.msg(key, args...))
+}
+
 // builtinBehavior provides default verb handling when no rule matched.
 // Returns effects to apply and direct output text.
 // Returns (nil, nil) if the verb is not a recognized built-in.
-func (e *Engine) builtinBehavior(intent types.Intent, objectID string) ([]types.Effect, []string) {
+func (e *Engine) builtinBehavior(intent types.Intent, objectID, targetID string) ([]types.Effect, []string) {
+	if e.builtinDisabled(intent.Verb) {
+		return nil, nil
+	}
 	switch intent.Verb {
 	case "go":
 		return e.builtinGo(objectID)
@@ -304,154 +720,745 @@ func (e *Engine) builtinBehavior(intent types.Intent, objectID string) ([]types.
 		if objectID == "" {
 			return e.builtinLook()
 		}
+		if _, ok := parser.Direction(intent.Object); ok {
+			return e.builtinLookDirection(intent.Object)
+		}
 		return nil, nil // look with object falls through to fallback
 	case "inventory":
 		return e.builtinInventory()
-	case "examine", "read":
+	case "stats":
+		return e.builtinStats()
+	case "achievements":
+		return e.builtinAchievements()
+	case "unlock", "lock":
+		return e.builtinLock(intent.Verb, objectID, targetID)
+	case "sneak":
+		if intent.Object == "" {
+			return nil, []string{e.fail("sneak_where")}
+		}
+		return e.builtinGo(intent.Object)
+	case "notes":
+		return e.builtinNotes()
+	case "examine":
 		return e.builtinExamine(objectID)
+	case "read":
+		return e.builtinRead(objectID)
 	case "take":
-		return e.builtinTake(objectID)
+		return e.builtinTake(objectID, intent.Count)
 	case "drop":
-		return e.builtinDrop(objectID)
+		return e.builtinDrop(objectID, intent.Count)
+	case "wear":
+		return e.builtinWear(objectID)
+	case "remove":
+		return e.builtinRemove(objectID)
+	case "eat", "drink":
+		return e.builtinConsume(intent.Verb, objectID)
 	case "talk":
 		return e.builtinTalk(intent, objectID)
+	case "topics":
+		return e.builtinTopics(objectID)
+	case "give", "show":
+		return e.builtinGiveOrShow(intent.Verb, objectID, targetID)
+	case "steal":
+		return e.builtinSteal(objectID, targetID)
+	case "fill":
+		return e.builtinFill(objectID, targetID)
+	case "pour":
+		return e.builtinPour(objectID, targetID)
+	case "tie":
+		return e.builtinTie(objectID, targetID)
+	case "untie":
+		return e.builtinUntie(objectID)
+	case "enter":
+		return e.builtinEnter(objectID)
+	case "exit":
+		return e.builtinExit(objectID)
 	case "wait":
-		return nil, []string{"Time passes."}
+		return nil, []string{e.msg("wait")}
 	default:
 		return nil, nil
 	}
 }
 
+// builtinDisabled reports whether verb's built-in behavior is turned off,
+// by the game's builtins table or by WithBuiltinsDisabled.
+func (e *Engine) builtinDisabled(verb string) bool {
+	on, set := e.Defs.Game.Builtins[verb]
+	return e.config.DisabledBuiltins[verb] || set && !on
+}
+
 func (e *Engine) builtinGo(direction string) ([]types.Effect, []string) {
 	if direction == "" {
-		return nil, []string{"Go where?"}
+		return nil, []string{e.fail("go_where")}
 	}
 
 	exits := state.RoomExits(e.State, e.Defs, e.State.Player.Location)
 	target, ok := exits[direction]
 	if !ok {
-		return nil, []string{"You can't go that way."}
+		return nil, []string{e.fail("cant_go")}
+	}
+	if guard, blocked := e.exitBlocked(e.State.Player.Location, direction); blocked {
+		switch {
+		case guard.BlockedText != "":
+			return nil, []string{markup.Fail(guard.BlockedText)}
+		case guard.Vehicle != "" && e.State.Player.Vehicle != guard.Vehicle:
+			return nil, []string{e.fail("needs_vehicle", "vehicle", e.entityName(guard.Vehicle))}
+		case guard.Door != "" && state.IsLocked(e.State, e.Defs, guard.Door):
+			return nil, []string{e.fail("locked", "item", e.entityName(guard.Door))}
+		}
+		return nil, []string{e.fail("cant_go")}
 	}
 
+	// A vehicle goes where the player goes, ahead of them.
+	var effs []types.Effect
+	if v := e.State.Player.Vehicle; v != "" {
+		effs = append(effs, types.Effect{Type: "move_entity", Params: map[string]any{"entity": v, "room": target}})
+	}
+	effs = append(effs, types.Effect{Type: "move_player", Params: map[string]any{"room": target}})
+	return effs, e.describeRoomOnEntry(target)
+}
+
+// exitBlocked reports whether the exit in direction from roomID has a guard
+// that keeps the player from taking it. It returns the guard.
+func (e *Engine) exitBlocked(roomID, direction string) (types.ExitGuardDef, bool) {
+	return effects.ExitBlocked(e.State, e.Defs, roomID, direction)
+}
+
+// builtinTie ties a tieable entity, such as a rope, to another within reach.
+// A carried one is put down to tie it to something that isn't.
+func (e *Engine) builtinTie(objectID, targetID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, []string{e.fail("tie_what")}
+	}
+	tieable, _ := state.GetEntityProp(e.State, e.Defs, objectID, "tieable")
+	if tieable != true || objectID == targetID {
+		return nil, []string{e.fail("cant_tie")}
+	}
+	item := e.entityName(objectID)
+	if targetID == "" {
+		return nil, []string{e.fail("tie_to_what", "item", item)}
+	}
+	if _, ok := e.Defs.Entities[targetID]; !ok {
+		return nil, []string{e.fail("cant_tie")}
+	}
+	if state.AttachedTo(e.State, objectID) == targetID {
+		return nil, []string{e.fail("already_tied", "item", item, "target", e.entityName(targetID))}
+	}
+	var effs []types.Effect
+	if state.HasItem(e.State, objectID) && !state.HasItem(e.State, targetID) {
+		effs = append(effs,
+			types.Effect{Type: "remove_item", Params: map[string]any{"item": objectID}},
+			types.Effect{Type: "move_entity", Params: map[string]any{"entity": objectID, "room": e.State.Player.Location}})
+	}
+	effs = append(effs, types.Effect{Type: "attach", Params: map[string]any{"entity": objectID, "to": targetID}})
+	return effs, []string{e.msg("tie", "item", item, "target", e.entityName(targetID))}
+}
+
+// builtinUntie unties an entity from what it is tied to, or, if nothing,
+// the first thing tied to it.
+func (e *Engine) builtinUntie(objectID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, []string{e.fail("tie_what")}
+	}
+	entity, from := objectID, state.AttachedTo(e.State, objectID)
+	if from == "" {
+		tied := state.TiedTo(e.State, objectID)
+		if len(tied) == 0 {
+			return nil, []string{e.fail("not_tied", "item", e.entityName(objectID))}
+		}
+		entity, from = tied[0], objectID
+	}
+	effs := []types.Effect{
+		{Type: "detach", Params: map[string]any{"entity": entity}},
+	}
+	return effs, []string{e.msg("untie", "item", e.entityName(entity), "target", e.entityName(from))}
+}
+
+// builtinEnter gets the player into an enterable entity in the room, such
+// as a boat or a horse, which then goes where they go.
+func (e *Engine) builtinEnter(objectID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, []string{e.fail("enter_what")}
+	}
+	enterable, _ := state.GetEntityProp(e.State, e.Defs, objectID, "enterable")
+	if enterable != true || state.EntityLocation(e.State, e.Defs, objectID) != e.State.Player.Location {
+		return nil, []string{e.fail("cant_enter")}
+	}
+	if e.State.Player.Vehicle == objectID {
+		return nil, []string{e.fail("already_in", "vehicle", e.entityName(objectID))}
+	}
 	effs := []types.Effect{
-		{Type: "move_player", Params: map[string]any{"room": target}},
+		{Type: "enter_vehicle", Params: map[string]any{"entity": objectID}},
 	}
-	return effs, e.describeRoom(target)
+	return effs, []string{e.msg("enter", "vehicle", e.entityName(objectID))}
+}
+
+// builtinExit gets the player out of the vehicle they are in. Naming
+// another entity gets them out of nothing.
+func (e *Engine) builtinExit(objectID string) ([]types.Effect, []string) {
+	v := e.State.Player.Vehicle
+	if v == "" || objectID != "" && objectID != v {
+		return nil, []string{e.fail("not_in_vehicle")}
+	}
+	effs := []types.Effect{
+		{Type: "leave_vehicle", Params: map[string]any{}},
+	}
+	return effs, []string{e.msg("exit", "vehicle", e.entityName(v))}
 }
 
 func (e *Engine) builtinLook() ([]types.Effect, []string) {
 	return nil, e.describeRoom(e.State.Player.Location)
 }
 
+// builtinLookDirection peeks through the exit in a direction: the room it
+// leads to and the first sentence of that room's description.
+func (e *Engine) builtinLookDirection(direction string) ([]types.Effect, []string) {
+	target, ok := state.RoomExits(e.State, e.Defs, e.State.Player.Location)[direction]
+	if guard, blocked := e.exitBlocked(e.State.Player.Location, direction); !ok || blocked && !guard.VisibleWhenBlocked {
+		return nil, []string{e.fail("look_direction_none", "direction", direction)}
+	}
+	lines := []string{e.msg("look_direction", "direction", direction, "room", strings.ReplaceAll(target, "_", " "))}
+	room := e.Defs.Rooms[target]
+	text := room.Description
+	if room.NightDescription != "" && state.IsNight(e.State, e.Defs) {
+		text = room.NightDescription
+	}
+	if brief := firstSentence(text); brief != "" {
+		lines = append(lines, brief)
+	}
+	return nil, lines
+}
+
+// firstSentence returns text up to the end of its first sentence.
+func firstSentence(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexAny(text, ".!?"); i >= 0 {
+		return text[:i+1]
+	}
+	return text
+}
+
 func (e *Engine) builtinInventory() ([]types.Effect, []string) {
 	inv := e.State.Player.Inventory
 	gold := e.State.Counters["gold"]
 
 	if len(inv) == 0 && gold <= 0 {
-		return nil, []string{"You are carrying nothing."}
+		return nil, []string{e.msg("inventory_empty")}
 	}
 
 	var lines []string
 	if len(inv) > 0 {
-		var names []string
-		for _, id := range inv {
-			names = append(names, e.entityName(id))
-		}
-		lines = append(lines, "You are carrying: "+strings.Join(names, ", ")+".")
+		lines = append(lines, e.inventoryLines(inv)...)
 	}
 	if gold > 0 {
-		lines = append(lines, fmt.Sprintf("Gold: %d", gold))
+		lines = append(lines, e.msg("gold", "amount", gold))
+	}
+	if limit, ok := e.State.Player.Stats["max_weight"]; ok {
+		lines = append(lines, e.msg("weight", "carried", state.Carried(e.State, e.Defs, "weight"), "limit", limit))
+	}
+	if limit, ok := e.State.Player.Stats["max_size"]; ok {
+		lines = append(lines, e.msg("size", "carried", state.Carried(e.State, e.Defs, "size"), "limit", limit))
 	}
 	return nil, lines
 }
 
+// builtinStats shows the player's level and XP (when the game has levels),
+// HP, and the remaining stats alphabetically.
+func (e *Engine) builtinStats() ([]types.Effect, []string) {
+	stats := e.State.Player.Stats
+	var lines []string
+	if len(e.Defs.Game.Levels) > 0 {
+		if next, ok := state.NextLevel(e.State, e.Defs); ok {
+			lines = append(lines, e.msg("level", "level", stats["level"], "xp", stats["xp"], "next", next.XP))
+		} else {
+			lines = append(lines, e.msg("level_max", "level", stats["level"], "xp", stats["xp"]))
+		}
+	}
+	if hp, ok := stats["hp"]; ok {
+		if maxHP, ok := stats["max_hp"]; ok {
+			lines = append(lines, e.msg("hp", "hp", hp, "max", maxHP))
+		} else {
+			lines = append(lines, e.msg("hp_only", "hp", hp))
+		}
+	}
+	var rest []string
+	for name := range stats {
+		switch name {
+		case "hp", "max_hp", "level", "xp":
+			continue
+		}
+		rest = append(rest, name)
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		lines = append(lines, fmt.Sprintf("%s: %d", categoryLabel(name), stats[name]))
+	}
+	if len(lines) == 0 {
+		return nil, []string{e.msg("no_stats")}
+	}
+	return nil, lines
+}
+
+// inventoryLines formats carried items. Items without a "category" prop are
+// listed flat; once any item has a category, output is grouped by category
+// ("Weapons: sword, dagger. Keys: rusty key."), ordered by
+// Game.InventoryCategories, then alphabetically, with uncategorized items
+// last.
+func (e *Engine) inventoryLines(inv []string) []string {
+	groups := map[string][]string{}
+	for _, id := range inv {
+		cat := ""
+		if v, ok := state.GetEntityProp(e.State, e.Defs, id, "category"); ok {
+			cat, _ = v.(string)
+		}
+		name := e.entityName(id)
+		if state.IsStackable(e.Defs, id) {
+			name = fmt.Sprintf("%s (%d)", name, state.HeldCount(e.State, e.Defs, id))
+		} else if e.isVessel(id) {
+			name = e.vesselName(id)
+		}
+		groups[cat] = append(groups[cat], name)
+	}
+
+	if len(groups) == 1 && groups[""] != nil {
+		return []string{e.msg("inventory", "list", strings.Join(groups[""], ", "))}
+	}
+
+	// Configured order first, then remaining categories alphabetically.
+	var order []string
+	seen := map[string]bool{"": true}
+	for _, cat := range e.Defs.Game.InventoryCategories {
+		if _, ok := groups[cat]; ok && !seen[cat] {
+			order = append(order, cat)
+			seen[cat] = true
+		}
+	}
+	var rest []string
+	for cat := range groups {
+		if !seen[cat] {
+			rest = append(rest, cat)
+		}
+	}
+	sort.Strings(rest)
+	order = append(order, rest...)
+
+	var groupsText []string
+	for _, cat := range order {
+		groupsText = append(groupsText, e.msg("inventory_category", "category", categoryLabel(cat), "list", strings.Join(groups[cat], ", ")))
+	}
+	if other, ok := groups[""]; ok {
+		groupsText = append(groupsText, e.msg("inventory_other", "list", strings.Join(other, ", ")))
+	}
+	return []string{strings.Join(groupsText, " ")}
+}
+
+// categoryLabel turns a category key into a display label: "magic_items" -> "Magic items".
+func categoryLabel(cat string) string {
+	label := strings.ReplaceAll(cat, "_", " ")
+	if label == "" {
+		return label
+	}
+	r, size := utf8.DecodeRuneInString(label)
+	return string(unicode.ToUpper(r)) + label[size:]
+}
+
 func (e *Engine) builtinExamine(objectID string) ([]types.Effect, []string) {
 	if objectID == "" {
 		return nil, nil
 	}
-	desc, ok := state.GetEntityProp(e.State, e.Defs, objectID, "description")
-	if !ok {
-		return nil, []string{"You see nothing special about it."}
+	effs := []types.Effect{{Type: "mark_examined", Params: map[string]any{"entity": objectID}}}
+	output := []string{e.examineText(objectID)}
+	if e.isLockable(objectID) {
+		output = append(output, e.lockLines(objectID)...)
+	} else if line := e.carryingLine(objectID); line != "" {
+		output = append(output, line)
+	}
+	output = append(output, e.tiedLines(objectID)...)
+	output = append(output, e.vesselLines(objectID)...)
+	return effs, output
+}
+
+// tiedLines say what objectID is tied to, and what is tied to it.
+func (e *Engine) tiedLines(objectID string) []string {
+	var lines []string
+	if to := state.AttachedTo(e.State, objectID); to != "" {
+		lines = append(lines, e.msg("tied_to", "target", e.entityName(to)))
+	}
+	var names []string
+	for _, id := range state.TiedTo(e.State, objectID) {
+		names = append(names, markup.Bold(e.entityName(id)))
+	}
+	if len(names) > 0 {
+		lines = append(lines, e.msg("tied_here", "list", strings.Join(names, ", ")))
+	}
+	return lines
+}
+
+// examineText returns what examining an entity shows of it.
+func (e *Engine) examineText(objectID string) string {
+	if image, ok := state.GetEntityProp(e.State, e.Defs, objectID, "image"); ok {
+		e.image, _ = image.(string)
+	}
+	seen := state.Examined(e.State, objectID) // counted by builtinExamine's mark_examined
+	// A list of descriptions shows one per examine, the last repeating,
+	// unless a rule has since set the description outright.
+	_, overridden := e.State.Entities[objectID].Props["description"]
+	if texts, ok := e.Defs.Entities[objectID].Props["descriptions"].([]string); ok && len(texts) > 0 && !overridden {
+		return texts[min(seen, len(texts)-1)]
 	}
-	if s, ok := desc.(string); ok {
-		return nil, []string{s}
+	if desc, ok := state.GetEntityProp(e.State, e.Defs, objectID, "description"); ok {
+		if s, ok := desc.(string); ok {
+			return s
+		}
+	}
+	return e.msg("examine_nothing")
+}
+
+// carryingLine lists the items an entity visibly carries, or returns "" if
+// it carries none. Concealed items go unmentioned.
+func (e *Engine) carryingLine(holderID string) string {
+	var names []string
+	for _, id := range state.Holding(e.State, e.Defs, holderID) {
+		if concealed, _ := state.GetEntityProp(e.State, e.Defs, id, "concealed"); concealed != true {
+			names = append(names, markup.Bold(e.entityName(id)))
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return e.msg("carrying", "npc", e.entityName(holderID), "list", strings.Join(names, ", "))
+}
+
+// builtinRead shows an item's "text" prop, or its description if it has
+// nothing written on it.
+func (e *Engine) builtinRead(objectID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, nil
+	}
+	if text, ok := state.GetEntityProp(e.State, e.Defs, objectID, "text"); ok {
+		if s, ok := text.(string); ok && s != "" {
+			return nil, []string{s}
+		}
 	}
-	return nil, []string{"You see nothing special about it."}
+	return e.builtinExamine(objectID)
 }
 
-func (e *Engine) builtinTake(objectID string) ([]types.Effect, []string) {
+func (e *Engine) builtinTake(objectID string, count int) ([]types.Effect, []string) {
 	if objectID == "" {
 		return nil, nil
 	}
+	if holder := state.Holder(e.State, e.Defs, objectID); holder != "" {
+		switch {
+		case !e.isLockable(holder):
+			return nil, []string{e.fail("held_by", "npc", e.entityName(holder))}
+		case state.IsLocked(e.State, e.Defs, holder):
+			return nil, []string{e.fail("locked", "item", e.entityName(holder))}
+		}
+	}
 	takeable, _ := state.GetEntityProp(e.State, e.Defs, objectID, "takeable")
 	if takeable != true {
-		return nil, []string{"You can't take that."}
+		return nil, []string{e.fail("cant_take")}
+	}
+	if to := state.AttachedTo(e.State, objectID); to != "" && !state.HasItem(e.State, to) {
+		return nil, []string{e.fail("tied_down", "item", e.entityName(objectID), "target", e.entityName(to))}
+	}
+	if state.IsStackable(e.Defs, objectID) {
+		return e.takeUnits(objectID, count)
+	}
+	if count > 1 {
+		return nil, []string{e.fail("only_one", "item", e.entityName(objectID))}
 	}
 	if state.HasItem(e.State, objectID) {
-		return nil, []string{"You already have that."}
+		return nil, []string{e.fail("already_have")}
+	}
+	if !state.CanCarry(e.State, e.Defs, objectID) {
+		return nil, []string{e.fail("too_much")}
 	}
 	effs := []types.Effect{
 		{Type: "give_item", Params: map[string]any{"item": objectID}},
 	}
-	return effs, []string{fmt.Sprintf("You take the %s.", e.entityName(objectID))}
+	return effs, []string{e.msg("take", "item", e.entityName(objectID))}
 }
 
-func (e *Engine) builtinDrop(objectID string) ([]types.Effect, []string) {
+// takeUnits takes count units from a stackable item's stack in the room,
+// or the whole stack when no count was given.
+func (e *Engine) takeUnits(objectID string, count int) ([]types.Effect, []string) {
+	here := state.Quantity(e.State, e.Defs, objectID)
+	if count > here {
+		return nil, []string{e.fail("not_enough", "item", e.entityName(objectID))}
+	}
+	if count == 0 {
+		count = here
+	}
+	if count == 0 || !state.CanCarryCount(e.State, e.Defs, objectID, count) {
+		return nil, []string{e.fail("too_much")}
+	}
+	effs := []types.Effect{
+		{Type: "give_item", Params: map[string]any{"item": objectID, "amount": count}},
+	}
+	return effs, []string{e.msg("take_some", "count", count, "item", e.entityName(objectID))}
+}
+
+func (e *Engine) builtinDrop(objectID string, count int) ([]types.Effect, []string) {
 	if objectID == "" {
 		return nil, nil
 	}
 	if !state.HasItem(e.State, objectID) {
-		return nil, []string{"You don't have that."}
+		return nil, []string{e.fail("dont_have")}
+	}
+	if state.IsStackable(e.Defs, objectID) {
+		return e.dropUnits(objectID, count)
 	}
 	effs := []types.Effect{
 		{Type: "remove_item", Params: map[string]any{"item": objectID}},
 		{Type: "move_entity", Params: map[string]any{"entity": objectID, "room": e.State.Player.Location}},
 	}
-	return effs, []string{fmt.Sprintf("You drop the %s.", e.entityName(objectID))}
+	return effs, []string{e.msg("drop", "item", e.entityName(objectID))}
+}
+
+// dropUnits drops count units of a stackable item, or all of them when no
+// count was given. The dropped units join the item's one world stack, which
+// moves to the player's room.
+func (e *Engine) dropUnits(objectID string, count int) ([]types.Effect, []string) {
+	held := state.HeldCount(e.State, e.Defs, objectID)
+	if count > held {
+		return nil, []string{e.fail("not_enough_held", "item", e.entityName(objectID))}
+	}
+	if count == 0 {
+		count = held
+	}
+	effs := []types.Effect{
+		{Type: "remove_item", Params: map[string]any{"item": objectID, "amount": count}},
+		{Type: "move_entity", Params: map[string]any{"entity": objectID, "room": e.State.Player.Location}},
+		{Type: "set_prop", Params: map[string]any{
+			"entity": objectID, "prop": "quantity",
+			"value": state.Quantity(e.State, e.Defs, objectID) + count,
+		}},
+	}
+	return effs, []string{e.msg("drop_some", "count", count, "item", e.entityName(objectID))}
+}
+
+func (e *Engine) builtinWear(objectID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, nil
+	}
+	wearable, _ := state.GetEntityProp(e.State, e.Defs, objectID, "wearable")
+	if wearable != true {
+		return nil, []string{e.fail("cant_wear")}
+	}
+	if !state.HasItem(e.State, objectID) {
+		return nil, []string{e.fail("dont_have")}
+	}
+	if state.IsWorn(e.State, objectID) {
+		return nil, []string{e.fail("already_wearing")}
+	}
+	effs := []types.Effect{
+		{Type: "wear_item", Params: map[string]any{"item": objectID}},
+	}
+	return effs, []string{e.msg("wear", "item", e.entityName(objectID))}
+}
+
+func (e *Engine) builtinRemove(objectID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, nil
+	}
+	if !state.IsWorn(e.State, objectID) {
+		return nil, []string{e.fail("not_wearing")}
+	}
+	effs := []types.Effect{
+		{Type: "unwear_item", Params: map[string]any{"item": objectID}},
+	}
+	return effs, []string{e.msg("remove", "item", e.entityName(objectID))}
+}
+
+// builtinConsume eats or drinks an edible item, carried or within reach. The
+// item is used up, and its "nutrition" prop, if any, heals the player.
+func (e *Engine) builtinConsume(verb, objectID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, nil
+	}
+	if verb == "drink" && (e.isVessel(objectID) || e.isLiquid(objectID) || state.LiquidIn(e.State, e.Defs, objectID) != "") {
+		return e.builtinDrink(objectID)
+	}
+	edible, _ := state.GetEntityProp(e.State, e.Defs, objectID, "edible")
+	if edible != true {
+		return nil, []string{e.fail("cant_consume", "verb", verb)}
+	}
+	effs := []types.Effect{
+		{Type: "consume_item", Params: map[string]any{"item": objectID}},
+	}
+	if nutrition, ok := state.GetEntityProp(e.State, e.Defs, objectID, "nutrition"); ok {
+		effs = append(effs, types.Effect{Type: "heal", Params: map[string]any{"target": "player", "amount": nutrition}})
+	}
+	return effs, []string{e.msg("consume", "verb", verb, "item", e.entityName(objectID))}
 }
 
 func (e *Engine) builtinTalk(intent types.Intent, npcID string) ([]types.Effect, []string) {
 	if npcID == "" {
-		return nil, []string{"Talk to whom?"}
+		return nil, []string{e.fail("talk_whom")}
 	}
 
 	// Check entity has topics.
 	ent, ok := e.Defs.Entities[npcID]
 	if !ok || ent.Topics == nil || len(ent.Topics) == 0 {
-		return nil, []string{"You can't talk to that."}
+		return nil, []string{e.fail("cant_talk")}
 	}
 
 	npcName := e.entityName(npcID)
 	topicKey := intent.Target
 
 	if topicKey != "" {
-		// Player specified a topic.
+		// Player specified a topic, by key or by label.
+		topicKey = dialogue.FindTopic(npcID, topicKey, e.Defs)
 		text, effs := dialogue.SelectTopic(npcID, topicKey, e.State, e.Defs)
 		if text == "" {
 			// Topic not found — hint at what's available.
-			available := dialogue.AvailableTopics(npcID, e.State, e.Defs)
-			if len(available) > 0 {
-				sort.Strings(available)
-				return nil, []string{fmt.Sprintf("%s has nothing to say about that. You could ask about: %s.", npcName, strings.Join(available, ", "))}
+			if labels := e.topicLabels(npcID); len(labels) > 0 {
+				e.choices = topicChoices(npcID, labels)
+				return nil, []string{e.fail("unknown_topic", "npc", npcName, "topics", strings.Join(labels, ", "))}
 			}
-			return nil, []string{fmt.Sprintf("%s has nothing to say right now.", npcName)}
+			return nil, []string{e.msg("nothing_to_say", "npc", npcName)}
 		}
-		return effs, []string{text}
+		return append([]types.Effect{markHeard(npcID, topicKey)}, effs...), []string{markup.Said(npcName, text)}
 	}
 
 	// No topic specified — auto-play first available topic.
 	available := dialogue.AvailableTopics(npcID, e.State, e.Defs)
 	if len(available) == 0 {
-		return nil, []string{fmt.Sprintf("%s has nothing to say right now.", npcName)}
+		return nil, []string{e.msg("nothing_to_say", "npc", npcName)}
 	}
 
 	// Pick first available (stable: sort for determinism).
 	sort.Strings(available)
 	text, effs := dialogue.SelectTopic(npcID, available[0], e.State, e.Defs)
 	if text == "" {
-		return nil, []string{fmt.Sprintf("%s has nothing to say right now.", npcName)}
+		return nil, []string{e.msg("nothing_to_say", "npc", npcName)}
 	}
-	return effs, []string{text}
+	return append([]types.Effect{markHeard(npcID, available[0])}, effs...), []string{markup.Said(npcName, text)}
+}
+
+// markHeard returns the effect recording that the player has heard an
+// NPC's topic. It comes before the topic's own effects, so that a stop
+// among them cannot skip it.
+func markHeard(npcID, topic string) types.Effect {
+	return types.Effect{Type: "mark_heard", Params: map[string]any{"npc": npcID, "topic": topic}}
+}
+
+// builtinGiveOrShow gives or shows a carried item to an NPC, which responds
+// as its on_receive or on_show entry for the item says. Without an entry
+// whose conditions hold, an NPC declines a gift and glances at an item shown.
+func (e *Engine) builtinGiveOrShow(verb, objectID, targetID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, []string{e.fail(verb + "_what")}
+	}
+	if !state.HasItem(e.State, objectID) {
+		return nil, []string{e.fail("dont_have")}
+	}
+	if targetID == "" {
+		return nil, []string{e.fail(verb + "_whom")}
+	}
+	npc, ok := e.Defs.Entities[targetID]
+	if !ok || npc.Kind != "npc" {
+		return nil, []string{e.fail("cant_" + verb)}
+	}
+	item, npcName := e.entityName(objectID), e.entityName(targetID)
+	responses := npc.OnReceive
+	if verb == "show" {
+		responses = npc.OnShow
+	}
+	resp, ok := responses[objectID]
+	if !ok || !rules.EvalAllConditions(resp.Conditions, e.State, e.Defs) {
+		if verb == "give" {
+			return nil, []string{e.fail("give_declined", "npc", npcName, "item", item)}
+		}
+		resp = types.ItemResponseDef{}
+	}
+
+	effType := "give_to"
+	if verb == "show" {
+		effType = "show_item"
+	}
+	effs := append([]types.Effect{
+		{Type: effType, Params: map[string]any{"item": objectID, "npc": targetID}},
+	}, resp.Effects...)
+	if resp.Text != "" {
+		return effs, []string{markup.Said(npcName, resp.Text)}
+	}
+	return effs, []string{e.msg(verb, "npc", npcName, "item", item)}
+}
+
+// builtinSteal tries to take an item from whoever in the room carries it.
+// A d20 plus the player's stealth stat must beat the holder's perception
+// (10 if it has none); otherwise the holder notices.
+func (e *Engine) builtinSteal(objectID, targetID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, []string{e.fail("steal_what")}
+	}
+	if state.HasItem(e.State, objectID) {
+		return nil, []string{e.fail("already_have")}
+	}
+	holder := state.Holder(e.State, e.Defs, objectID)
+	if targetID != "" && targetID != holder {
+		return nil, []string{e.fail("not_carrying", "npc", e.entityName(targetID))}
+	}
+	if holder == "" || state.EntityLocation(e.State, e.Defs, holder) != e.State.Player.Location {
+		return nil, []string{e.fail("cant_steal")}
+	}
+
+	item, npcName := e.entityName(objectID), e.entityName(holder)
+	perception, ok := state.GetStat(e.State, e.Defs, holder, "perception")
+	if !ok {
+		perception = 10
+	}
+	roll := e.RNG.RollFor("steal: "+objectID, 20)
+	if roll+e.State.Player.Stats["stealth"] > perception {
+		effs := []types.Effect{
+			{Type: "transfer_item", Params: map[string]any{"item": objectID, "from": holder, "to": "player"}},
+		}
+		return effs, []string{e.msg("steal", "npc", npcName, "item", item)}
+	}
+	effs := []types.Effect{
+		{Type: "notice_theft", Params: map[string]any{"item": objectID, "npc": holder}},
+	}
+	return effs, []string{e.msg("steal_caught", "npc", npcName, "item", item)}
+}
+
+// builtinTopics lists what the player could talk to an NPC about.
+func (e *Engine) builtinTopics(npcID string) ([]types.Effect, []string) {
+	if npcID == "" {
+		return nil, []string{e.fail("topics_whom")}
+	}
+	if ent, ok := e.Defs.Entities[npcID]; !ok || len(ent.Topics) == 0 {
+		return nil, []string{e.fail("cant_talk")}
+	}
+	npcName := e.entityName(npcID)
+	labels := e.topicLabels(npcID)
+	if len(labels) == 0 {
+		return nil, []string{e.msg("nothing_to_say", "npc", npcName)}
+	}
+	e.choices = topicChoices(npcID, labels)
+	return nil, []string{e.msg("topics", "npc", npcName, "topics", strings.Join(labels, ", "))}
+}
+
+// topicChoices offers an NPC's topics, by label, as "ask" commands.
+func topicChoices(npcID string, labels []string) []types.Choice {
+	choices := make([]types.Choice, len(labels))
+	for i, label := range labels {
+		choices[i] = types.Choice{Label: label, Command: "ask " + npcID + " about " + label}
+	}
+	return choices
+}
+
+// topicLabels returns the labels of an NPC's available topics, in the
+// order of their keys.
+func (e *Engine) topicLabels(npcID string) []string {
+	available := dialogue.AvailableTopics(npcID, e.State, e.Defs)
+	sort.Strings(available)
+	labels := make([]string, len(available))
+	for i, key := range available {
+		labels[i] = dialogue.Label(npcID, key, e.Defs)
+	}
+	return labels
 }
 
 // sceneryFallback checks if the object noun appears in descriptions the player
@@ -510,47 +1517,77 @@ func (e *Engine) sceneryFallback(intent types.Intent) string {
 func (e *Engine) sceneryMessage(verb, object string) string {
 	switch verb {
 	case "examine", "look":
-		return fmt.Sprintf("You see nothing special about the %s.", object)
+		return e.msg("scenery_examine", "object", object)
 	case "take", "get":
-		return fmt.Sprintf("You can't take the %s.", object)
+		return e.msg("scenery_take", "object", object)
 	default:
-		return fmt.Sprintf("You can't do anything useful with the %s.", object)
+		return e.msg("scenery_other", "object", object)
 	}
 }
 
-// describeRoom produces the standard room description output.
+// verbosityModes maps description mode commands to the message key of
+// their confirmation text.
+var verbosityModes = map[string]string{
+	"verbose":    "mode_verbose",
+	"brief":      "mode_brief",
+	"superbrief": "mode_superbrief",
+}
+
+// describeRoom produces the standard room description output. Used by "look",
+// which always prints the full description.
 func (e *Engine) describeRoom(roomID string) []string {
-	room, ok := e.Defs.Rooms[roomID]
-	if !ok {
-		return []string{"You are somewhere unknown."}
-	}
+	return e.roomOutput(roomID, true)
+}
 
-	var output []string
-	output = append(output, room.Description)
+// markVisited records, through mark_visited, that the player has been in
+// the room they are in, once it has been described to them.
+func (e *Engine) markVisited(ctx effects.Context, result *types.Result) {
+	room := state.PlayerLocation(e.State)
+	if state.HasVisited(e.State, room) {
+		return
+	}
+	eff := types.Effect{Type: "mark_visited", Params: map[string]any{"room": room}}
+	effects.Apply(e.State, e.Defs, []types.Effect{eff}, ctx)
+	result.Effects = append(result.Effects, eff)
+}
 
-	// List visible entities.
-	entities := state.EntitiesInRoom(e.State, e.Defs, roomID)
-	if len(entities) > 0 {
-		sort.Strings(entities) // deterministic order
-		var names []string
-		for _, id := range entities {
-			names = append(names, e.entityName(id))
-		}
-		output = append(output, "You see: "+strings.Join(names, ", ")+".")
+// describeRoomOnEntry produces the room output for moving into a room,
+// honoring the player's description mode. Verbose always prints the
+// description; brief prints it on the first visit only; superbrief never does.
+func (e *Engine) describeRoomOnEntry(roomID string) []string {
+	switch e.State.Verbosity {
+	case "brief":
+		return e.roomOutput(roomID, !state.HasVisited(e.State, roomID))
+	case "superbrief":
+		return e.roomOutput(roomID, false)
+	default:
+		return e.roomOutput(roomID, true)
 	}
+}
 
-	// List exits.
-	exits := state.RoomExits(e.State, e.Defs, roomID)
-	if len(exits) > 0 {
-		dirs := make([]string, 0, len(exits))
-		for dir := range exits {
-			dirs = append(dirs, dir)
-		}
-		sort.Strings(dirs) // deterministic order
-		output = append(output, "Exits: "+strings.Join(dirs, ", ")+".")
+// roomOutput lists the room description (if withDescription), visible
+// entities, and exits, and makes the room's image the step's illustration.
+// The room is always the one the player is in, or going to with their
+// vehicle.
+func (e *Engine) roomOutput(roomID string, withDescription bool) []string {
+	room, ok := e.Defs.Rooms[roomID]
+	if !ok {
+		return []string{e.msg("room_unknown")}
 	}
+	e.image = room.Image
+	return effects.DescribeRoom(e.State, e.Defs, roomID, withDescription, e.State.Player.Vehicle)
+}
+
+// ExitDirs returns the directions of the exits from roomID, sorted, less
+// those blocked by a guard that hides them while blocked.
+func (e *Engine) ExitDirs(roomID string) []string {
+	return effects.ExitDirs(e.State, e.Defs, roomID)
+}
 
-	return output
+// ExitsLine returns the line listing the exits from roomID, as shown when
+// the room is described, or "" if there are none.
+func (e *Engine) ExitsLine(roomID string) string {
+	return effects.ExitsLine(e.State, e.Defs, roomID)
 }
 
 // entityName returns the display name of an entity.
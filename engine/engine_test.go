@@ -1,9 +1,11 @@
 package engine
 
 import (
+	"slices"
 	"strings"
 	"testing"
 
+	"github.com/nathoo/questcore/engine/rules"
 	"github.com/nathoo/questcore/engine/state"
 	"github.com/nathoo/questcore/types"
 )
@@ -120,6 +122,28 @@ func TestStep_GoInvalidDirection(t *testing.T) {
 	}
 }
 
+func TestStep_MessageOverrides(t *testing.T) {
+	defs := testDefs()
+	defs.Messages = map[string]string{
+		"cant_go":  "Impossible d'aller par là.",
+		"not_here": "vous ne voyez pas « {name} » ici",
+	}
+	e := New(defs)
+
+	result := e.Step("go east")
+	if !outputContains(result.Output, "Impossible d'aller par là.") {
+		t.Errorf("expected overridden cant_go, got %v", result.Output)
+	}
+	result = e.Step("take dragon")
+	if !outputContains(result.Output, "« dragon »") {
+		t.Errorf("expected overridden not_here, got %v", result.Output)
+	}
+	result = e.Step("go north")
+	if !outputContains(result.Output, "beautiful garden") {
+		t.Errorf("messages without overrides should be unchanged, got %v", result.Output)
+	}
+}
+
 func TestStep_DirectionShortcut(t *testing.T) {
 	e := New(testDefs())
 	result := e.Step("n")
@@ -175,6 +199,33 @@ func TestStep_Look_DescribesRoom(t *testing.T) {
 	}
 }
 
+func TestStep_LookDirection(t *testing.T) {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "throw_north", Scope: "global",
+		When:    types.MatchCriteria{Verb: "throw", Object: "key", Target: "north"},
+		Effects: []types.Effect{{Type: "say", Params: map[string]any{"text": "The key sails into the garden."}}},
+	})
+	e := New(defs)
+
+	result := e.Step("look n")
+	if !outputContains(result.Output, "Looking north, you see the garden.") {
+		t.Errorf("expected the garden, got %v", result.Output)
+	}
+	if !outputContains(result.Output, "A beautiful garden with flowers.") {
+		t.Errorf("expected the garden's description, got %v", result.Output)
+	}
+	result = e.Step("look west")
+	if !outputContains(result.Output, "no way west") {
+		t.Errorf("expected no way west, got %v", result.Output)
+	}
+
+	result = e.Step("throw key to the north")
+	if !outputContains(result.Output, "sails into the garden") {
+		t.Errorf("expected the direction to reach the rule, got %v", result.Output)
+	}
+}
+
 func TestStep_Inventory_Empty(t *testing.T) {
 	e := New(testDefs())
 	result := e.Step("inventory")
@@ -194,6 +245,56 @@ func TestStep_Inventory_WithItems(t *testing.T) {
 	}
 }
 
+func TestStep_Inventory_GroupedByCategory(t *testing.T) {
+	defs := testDefs()
+	defs.Game.InventoryCategories = []string{"weapons", "keys"}
+	defs.Entities["sword"] = types.EntityDef{
+		ID: "sword", Kind: "item",
+		Props: map[string]any{"name": "Sword", "category": "weapons"},
+	}
+	defs.Entities["scroll"] = types.EntityDef{
+		ID: "scroll", Kind: "item",
+		Props: map[string]any{"name": "Scroll", "category": "documents"},
+	}
+	key := defs.Entities["key"]
+	key.Props["category"] = "keys"
+	e := New(defs)
+	e.State.Player.Inventory = []string{"key", "book", "scroll", "sword"}
+
+	result := e.Step("inventory")
+
+	want := "Weapons: Sword. Keys: Key. Documents: Scroll. Other: Book."
+	if len(result.Output) != 1 || result.Output[0] != want {
+		t.Errorf("expected %q, got %v", want, result.Output)
+	}
+}
+
+func TestCategoryLabel(t *testing.T) {
+	tests := []struct {
+		cat, want string
+	}{
+		{"weapons", "Weapons"},
+		{"magic_items", "Magic items"},
+		{"épées", "Épées"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := categoryLabel(tt.cat); got != tt.want {
+			t.Errorf("categoryLabel(%q) = %q, want %q", tt.cat, got, tt.want)
+		}
+	}
+}
+
+func TestStep_Inventory_NoCategoriesIsFlat(t *testing.T) {
+	e := New(testDefs())
+	e.State.Player.Inventory = []string{"key", "book"}
+	result := e.Step("inventory")
+
+	if len(result.Output) != 1 || result.Output[0] != "You are carrying: Key, Book." {
+		t.Errorf("expected flat inventory line, got %v", result.Output)
+	}
+}
+
 func TestStep_Examine(t *testing.T) {
 	e := New(testDefs())
 	result := e.Step("examine statue")
@@ -203,6 +304,31 @@ func TestStep_Examine(t *testing.T) {
 	}
 }
 
+func TestStep_ExamineCyclesDescriptions(t *testing.T) {
+	defs := testDefs()
+	defs.Entities["statue"].Props["descriptions"] = []string{"A statue.", "Its eyes are rubies.", "A weathered statue."}
+	e := New(defs)
+
+	for _, want := range []string{"A statue.", "Its eyes are rubies.", "A weathered statue.", "A weathered statue."} {
+		result := e.Step("examine statue")
+		if !outputContains(result.Output, want) {
+			t.Errorf("expected %q, got %v", want, result.Output)
+		}
+		if len(result.Effects) == 0 || result.Effects[0].Type != "mark_examined" {
+			t.Errorf("examining should count through mark_examined, got %v", result.Effects)
+		}
+	}
+	if got := state.Examined(e.State, "statue"); got != 4 {
+		t.Errorf("examined %d times, want 4", got)
+	}
+
+	// A description set by a rule takes over from the list.
+	e.State.Entities["statue"] = types.EntityState{Props: map[string]any{"description": "A toppled statue."}}
+	if result := e.Step("examine statue"); !outputContains(result.Output, "A toppled statue.") {
+		t.Errorf("expected the overriding description, got %v", result.Output)
+	}
+}
+
 func TestStep_EventHandler_Fires(t *testing.T) {
 	e := New(testDefs())
 	result := e.Step("take book")
@@ -286,6 +412,25 @@ func TestStep_UnknownEntity(t *testing.T) {
 	}
 }
 
+func TestStep_Failed(t *testing.T) {
+	tests := []struct {
+		input  string
+		failed bool
+	}{
+		{"", true},
+		{"take dragon", true},
+		{"dance", true},
+		{"look", false},
+		{"take key", false},
+	}
+	for _, tt := range tests {
+		e := New(testDefs())
+		if got := e.Step(tt.input).Failed; got != tt.failed {
+			t.Errorf("Step(%q).Failed = %v, want %v", tt.input, got, tt.failed)
+		}
+	}
+}
+
 func TestStep_Drop(t *testing.T) {
 	e := New(testDefs())
 	e.State.Player.Inventory = []string{"key"}
@@ -427,6 +572,277 @@ func TestStep_Talk_TopicNotFound_ShowsAvailable(t *testing.T) {
 	}
 }
 
+func TestStep_Topics(t *testing.T) {
+	defs := talkTestDefs()
+	barkeep := defs.Entities["barkeep"]
+	barkeep.Topics["rumors"] = types.TopicDef{Text: "Treasure in the caves, they say.", Label: "Local rumors", Once: true}
+	defs.Entities["barkeep"] = barkeep
+	e := New(defs)
+
+	result := e.Step("topics barkeep")
+	if !outputContains(result.Output, "You could ask Barkeep about: greeting, Local rumors.") {
+		t.Fatalf("expected labelled topic list, got %v", result.Output)
+	}
+
+	// A label names the topic as well as its key; a once topic is then hidden.
+	result = e.Step("ask barkeep about local rumors")
+	if !outputContains(result.Output, "Treasure in the caves") {
+		t.Fatalf("expected rumors by label, got %v", result.Output)
+	}
+	if !state.Heard(e.State, "barkeep", "rumors") {
+		t.Error("expected rumors to be marked heard")
+	}
+	if len(result.Effects) == 0 || result.Effects[0].Type != "mark_heard" || result.Effects[0].Params["topic"] != "rumors" {
+		t.Errorf("hearing a topic should be recorded through mark_heard, got %v", result.Effects)
+	}
+	result = e.Step("talk to barkeep topics")
+	if outputContains(result.Output, "rumors") || !outputContains(result.Output, "greeting") {
+		t.Errorf("expected heard once topic hidden, got %v", result.Output)
+	}
+	result = e.Step("ask barkeep about rumors")
+	if !outputContains(result.Output, "nothing to say about that") {
+		t.Errorf("expected heard once topic refused, got %v", result.Output)
+	}
+
+	result = e.Step("topics")
+	if !outputContains(result.Output, "Whose topics?") {
+		t.Errorf("expected topics_whom, got %v", result.Output)
+	}
+}
+
+func TestStep_TopicsOfferChoices(t *testing.T) {
+	e := New(talkTestDefs())
+	e.State.Flags["met_barkeep"] = true
+	result := e.Step("topics barkeep")
+	want := []types.Choice{
+		{Label: "greeting", Command: "ask barkeep about greeting"},
+		{Label: "rumors", Command: "ask barkeep about rumors"},
+	}
+	if !slices.Equal(result.Choices, want) {
+		t.Fatalf("choices = %v, want %v", result.Choices, want)
+	}
+	result = e.Step(result.Choices[1].Command)
+	if !outputContains(result.Output, "treasure in the caves") {
+		t.Errorf("picking a topic: got %v", result.Output)
+	}
+	if result.Choices != nil {
+		t.Errorf("choices = %v, want none after an answer", result.Choices)
+	}
+}
+
+func TestStep_AmbiguousNameOffersChoices(t *testing.T) {
+	defs := testDefs()
+	for _, id := range []string{"brass_lamp", "oil_lamp"} {
+		defs.Entities[id] = types.EntityDef{ID: id, Kind: "item", Props: map[string]any{
+			"name": strings.ReplaceAll(id, "_", " "), "location": "hall",
+		}}
+	}
+	e := New(defs)
+	result := e.Step("examine the Lamp")
+	var commands []string
+	for _, c := range result.Choices {
+		commands = append(commands, c.Command)
+	}
+	slices.Sort(commands)
+	if want := []string{"examine the brass_lamp", "examine the oil_lamp"}; !slices.Equal(commands, want) {
+		t.Errorf("choices = %v, want %v", commands, want)
+	}
+}
+
+func TestStep_RestartOffersYesNo(t *testing.T) {
+	e := New(testDefs())
+	result := e.Step("restart")
+	if len(result.Choices) != 2 || result.Choices[0].Command != "yes" || result.Choices[1].Command != "no" {
+		t.Errorf("choices = %v, want yes/no", result.Choices)
+	}
+}
+
+func giveTestEngine() *Engine {
+	defs := talkTestDefs()
+	for _, id := range []string{"coin", "letter"} {
+		defs.Entities[id] = types.EntityDef{
+			ID:    id,
+			Kind:  "item",
+			Props: map[string]any{"name": id, "location": "tavern", "takeable": true},
+		}
+	}
+	barkeep := defs.Entities["barkeep"]
+	barkeep.OnReceive = map[string]types.ItemResponseDef{
+		"coin": {
+			Text:    "Much obliged.",
+			Effects: []types.Effect{{Type: "set_flag", Params: map[string]any{"flag": "paid", "value": true}}},
+		},
+	}
+	barkeep.OnShow = map[string]types.ItemResponseDef{
+		"letter": {Text: "That's my brother's hand."},
+	}
+	defs.Entities["barkeep"] = barkeep
+	e := New(defs)
+	e.Step("take coin")
+	e.Step("take letter")
+	return e
+}
+
+func TestStep_Give(t *testing.T) {
+	e := giveTestEngine()
+
+	// An item the NPC has no response for is declined.
+	result := e.Step("give letter to barkeep")
+	if !outputContains(result.Output, "doesn't want the letter") {
+		t.Errorf("expected gift declined, got %v", result.Output)
+	}
+	if !slices.Contains(e.State.Player.Inventory, "letter") {
+		t.Error("expected declined letter to stay in inventory")
+	}
+
+	result = e.Step("give coin to barkeep")
+	if !outputContains(result.Output, "Much obliged.") {
+		t.Errorf("expected barkeep's response, got %v", result.Output)
+	}
+	if slices.Contains(e.State.Player.Inventory, "coin") {
+		t.Error("expected coin to leave inventory")
+	}
+	if loc := e.State.Entities["coin"].Location; loc != "barkeep" {
+		t.Errorf("expected coin carried by barkeep, got %q", loc)
+	}
+	if !e.State.Flags["paid"] {
+		t.Error("expected response effects to apply")
+	}
+	if len(result.Events) == 0 || result.Events[0].Type != "item_given" {
+		t.Errorf("expected item_given event, got %v", result.Events)
+	}
+
+	result = e.Step("give letter to chair")
+	if !outputContains(result.Output, "can't give") {
+		t.Errorf("expected cant_give for a non-NPC, got %v", result.Output)
+	}
+}
+
+func TestStep_Show(t *testing.T) {
+	e := giveTestEngine()
+
+	result := e.Step("show letter to barkeep")
+	if !outputContains(result.Output, "brother's hand") {
+		t.Errorf("expected barkeep's response, got %v", result.Output)
+	}
+	if !slices.Contains(e.State.Player.Inventory, "letter") {
+		t.Error("expected shown letter to stay in inventory")
+	}
+	if len(result.Events) == 0 || result.Events[0].Type != "item_shown" {
+		t.Errorf("expected item_shown event, got %v", result.Events)
+	}
+
+	// Without a response the NPC just glances at it.
+	result = e.Step("show coin to barkeep")
+	if !outputContains(result.Output, "glances at the coin") {
+		t.Errorf("expected default show message, got %v", result.Output)
+	}
+}
+
+func stealTestEngine() *Engine {
+	defs := talkTestDefs()
+	defs.Entities["purse"] = types.EntityDef{ID: "purse", Kind: "item",
+		Props: map[string]any{"name": "coin purse", "location": "barkeep", "takeable": true}}
+	defs.Entities["ring"] = types.EntityDef{ID: "ring", Kind: "item",
+		Props: map[string]any{"name": "signet ring", "location": "barkeep", "concealed": true}}
+	barkeep := defs.Entities["barkeep"]
+	barkeep.Props["perception"] = 100
+	return New(defs)
+}
+
+func TestStep_ExamineShowsCarriedItems(t *testing.T) {
+	e := stealTestEngine()
+	result := e.Step("examine barkeep")
+	if !outputContains(result.Output, "Barkeep is carrying:") || !outputContains(result.Output, "coin purse") {
+		t.Errorf("expected the purse listed, got %v", result.Output)
+	}
+	if outputContains(result.Output, "signet ring") {
+		t.Errorf("expected the concealed ring left out, got %v", result.Output)
+	}
+
+	result = e.Step("take purse")
+	if !outputContains(result.Output, "Barkeep has that.") {
+		t.Errorf("expected held_by, got %v", result.Output)
+	}
+}
+
+func TestStep_TakeAndDropQuantity(t *testing.T) {
+	defs := testDefs()
+	defs.Entities["coin"] = types.EntityDef{ID: "coin", Kind: "item", Props: map[string]any{
+		"name": "gold coin", "location": "hall", "takeable": true, "quantity": 5,
+	}}
+	e := New(defs)
+
+	result := e.Step("take 3 coins")
+	if !outputContains(result.Output, "You take 3 gold coin") {
+		t.Errorf("expected to take 3, got %v", result.Output)
+	}
+	if got := state.HeldCount(e.State, defs, "coin"); got != 3 {
+		t.Errorf("held = %d, want 3", got)
+	}
+	result = e.Step("inventory")
+	if !outputContains(result.Output, "gold coin (3)") {
+		t.Errorf("expected a count in the inventory, got %v", result.Output)
+	}
+	result = e.Step("take 7 coins")
+	if !outputContains(result.Output, "aren't that many") {
+		t.Errorf("expected not_enough, got %v", result.Output)
+	}
+	result = e.Step("take 2 books")
+	if !outputContains(result.Output, "only one") {
+		t.Errorf("expected only_one, got %v", result.Output)
+	}
+
+	e.Step("take coins")
+	if got := state.HeldCount(e.State, defs, "coin"); got != 5 {
+		t.Errorf("held = %d after taking the rest, want 5", got)
+	}
+
+	e.Step("north")
+	result = e.Step("drop two coins")
+	if !outputContains(result.Output, "You drop 2 gold coin") {
+		t.Errorf("expected to drop 2, got %v", result.Output)
+	}
+	if got := state.HeldCount(e.State, defs, "coin"); got != 3 {
+		t.Errorf("held = %d after dropping, want 3", got)
+	}
+	if loc := state.EntityLocation(e.State, defs, "coin"); loc != "garden" || state.Quantity(e.State, defs, "coin") != 2 {
+		t.Errorf("stack = %d in %q, want 2 in the garden", state.Quantity(e.State, defs, "coin"), loc)
+	}
+}
+
+func TestStep_Steal(t *testing.T) {
+	e := stealTestEngine()
+
+	result := e.Step("steal purse from barkeep")
+	if !outputContains(result.Output, "catches you") {
+		t.Errorf("expected to be caught, got %v", result.Output)
+	}
+	if len(result.Events) == 0 || result.Events[0].Type != "theft_noticed" {
+		t.Errorf("expected theft_noticed event, got %v", result.Events)
+	}
+	if state.HasItem(e.State, "purse") {
+		t.Error("expected the purse to stay with the barkeep")
+	}
+
+	state.SetStat(e.State, "barkeep", "perception", 0)
+	result = e.Step("steal purse")
+	if !outputContains(result.Output, "unnoticed") {
+		t.Errorf("expected a clean theft, got %v", result.Output)
+	}
+	if !state.HasItem(e.State, "purse") {
+		t.Error("expected the purse in inventory")
+	}
+	if len(result.Events) == 0 || result.Events[0].Type != "item_transferred" {
+		t.Errorf("expected item_transferred event, got %v", result.Events)
+	}
+
+	result = e.Step("steal chair")
+	if !outputContains(result.Output, "no one here to steal that from") {
+		t.Errorf("expected cant_steal, got %v", result.Output)
+	}
+}
+
 func TestStep_Wait(t *testing.T) {
 	e := New(testDefs())
 	result := e.Step("wait")
@@ -535,3 +951,901 @@ func TestStep_UnresolvedNoun_RuleStillFires(t *testing.T) {
 		t.Errorf("expected rule to fire for 'push wall', got %v", result.Output)
 	}
 }
+
+func TestStep_FirstDescription_ShownOnFirstVisitOnly(t *testing.T) {
+	defs := testDefs()
+	garden := defs.Rooms["garden"]
+	garden.FirstDescription = "You step into the garden for the first time."
+	defs.Rooms["garden"] = garden
+	e := New(defs)
+
+	result := e.Step("go north")
+	if !outputContains(result.Output, "for the first time") {
+		t.Errorf("expected first description, got %v", result.Output)
+	}
+	if !e.State.Visited["garden"] {
+		t.Error("expected garden marked visited")
+	}
+
+	e.Step("go south")
+	result = e.Step("go north")
+	if outputContains(result.Output, "for the first time") || !outputContains(result.Output, "beautiful garden") {
+		t.Errorf("expected regular description on repeat visit, got %v", result.Output)
+	}
+}
+
+func TestStep_FirstDescription_StartRoom(t *testing.T) {
+	defs := testDefs()
+	hall := defs.Rooms["hall"]
+	hall.FirstDescription = "You wake in the hall for the first time."
+	defs.Rooms["hall"] = hall
+	e := New(defs)
+
+	result := e.Step("look")
+	if !outputContains(result.Output, "for the first time") {
+		t.Errorf("expected first description on the opening look, got %v", result.Output)
+	}
+	result = e.Step("look")
+	if outputContains(result.Output, "for the first time") || !outputContains(result.Output, "grand hall") {
+		t.Errorf("expected regular description on the second look, got %v", result.Output)
+	}
+}
+
+func TestStep_Verbosity(t *testing.T) {
+	tests := []struct {
+		name          string
+		mode          string
+		wantFirst     bool // description on first entry to garden
+		wantRepeat    bool // description on second entry to garden
+		wantModeReply string
+	}{
+		{"verbose", "verbose", true, true, "Maximum verbosity"},
+		{"brief", "brief", true, false, "Brief descriptions"},
+		{"superbrief", "superbrief", false, false, "Superbrief descriptions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(testDefs())
+			result := e.Step(tt.mode)
+			if !outputContains(result.Output, tt.wantModeReply) {
+				t.Errorf("expected mode confirmation, got %v", result.Output)
+			}
+			if e.State.TurnCount != 0 {
+				t.Errorf("mode change should not take a turn, TurnCount = %d", e.State.TurnCount)
+			}
+			if len(result.Effects) != 1 || result.Effects[0].Type != "set_verbosity" {
+				t.Errorf("mode change should go through set_verbosity, got %v", result.Effects)
+			}
+
+			result = e.Step("go north")
+			if got := outputContains(result.Output, "beautiful garden"); got != tt.wantFirst {
+				t.Errorf("first visit description shown = %v, want %v (%v)", got, tt.wantFirst, result.Output)
+			}
+			if !outputContains(result.Output, "Exits:") {
+				t.Errorf("exits should always be listed, got %v", result.Output)
+			}
+
+			e.Step("go south")
+			result = e.Step("go north")
+			if got := outputContains(result.Output, "beautiful garden"); got != tt.wantRepeat {
+				t.Errorf("repeat visit description shown = %v, want %v (%v)", got, tt.wantRepeat, result.Output)
+			}
+
+			result = e.Step("look")
+			if !outputContains(result.Output, "beautiful garden") {
+				t.Errorf("look should always describe the room, got %v", result.Output)
+			}
+		})
+	}
+}
+
+func TestStep_OnceRuleFiresOnce(t *testing.T) {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID:    "first_statue_look",
+		Scope: "global",
+		When:  types.MatchCriteria{Verb: "examine", Object: "statue"},
+		Once:  true,
+		Effects: []types.Effect{
+			{Type: "say", Params: map[string]any{"text": "A chill runs down your spine."}},
+			{Type: "stop"},
+		},
+	})
+	e := New(defs)
+
+	result := e.Step("examine statue")
+	if !outputContains(result.Output, "A chill runs down your spine.") {
+		t.Errorf("expected once rule output, got %v", result.Output)
+	}
+	if !e.State.Fired["first_statue_look"] {
+		t.Error("expected rule to be recorded as fired")
+	}
+
+	result = e.Step("examine statue")
+	if outputContains(result.Output, "A chill runs down your spine.") {
+		t.Errorf("once rule fired twice: %v", result.Output)
+	}
+	if !outputContains(result.Output, "A weathered statue of a knight.") {
+		t.Errorf("expected built-in examine, got %v", result.Output)
+	}
+}
+
+func TestStep_ContinueDecoratesBuiltin(t *testing.T) {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID:    "book_dust",
+		Scope: "global",
+		When:  types.MatchCriteria{Verb: "take", Object: "book"},
+		Effects: []types.Effect{
+			{Type: "say", Params: map[string]any{"text": "A cloud of dust rises."}},
+			{Type: "continue"},
+		},
+	})
+	e := New(defs)
+
+	result := e.Step("take book")
+	if len(result.Output) < 2 || result.Output[0] != "A cloud of dust rises." {
+		t.Fatalf("expected decoration before built-in output, got %v", result.Output)
+	}
+	if !slices.Contains(e.State.Player.Inventory, "book") {
+		t.Errorf("expected built-in take to run, inventory = %v", e.State.Player.Inventory)
+	}
+}
+
+func phaseRule(id, phase, object string, succeeded *bool, effs ...types.Effect) types.RuleDef {
+	return types.RuleDef{
+		ID:      id,
+		Scope:   "global",
+		When:    types.MatchCriteria{Verb: "take", Object: object, Phase: phase, Succeeded: succeeded},
+		Effects: effs,
+	}
+}
+
+func say(text string) types.Effect {
+	return types.Effect{Type: "say", Params: map[string]any{"text": text}}
+}
+
+func TestStep_BeforePhaseRunsAheadOfBuiltin(t *testing.T) {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules,
+		phaseRule("book_before", "before", "book", nil, say("You blow the dust off.")))
+	e := New(defs)
+
+	result := e.Step("take book")
+	if len(result.Output) < 2 || result.Output[0] != "You blow the dust off." {
+		t.Fatalf("expected before text first, got %v", result.Output)
+	}
+	if !slices.Contains(e.State.Player.Inventory, "book") {
+		t.Error("expected built-in take to run after the before rule")
+	}
+}
+
+func TestStep_BeforePhaseStopVetoes(t *testing.T) {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules,
+		phaseRule("book_cursed", "before", "book", nil,
+			say("The book burns your fingers."), types.Effect{Type: "stop"}),
+		phaseRule("book_after", "after", "book", nil, say("after")))
+	e := New(defs)
+
+	result := e.Step("take book")
+	if slices.Contains(e.State.Player.Inventory, "book") {
+		t.Error("Stop() in a before rule should veto the take")
+	}
+	if outputContains(result.Output, "after") {
+		t.Errorf("after rules should not run for a vetoed command: %v", result.Output)
+	}
+}
+
+func TestStep_AfterPhaseSeesOutcome(t *testing.T) {
+	yes, no := true, false
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules,
+		phaseRule("book_taken", "after", "book", &yes, say("Something falls out of the book.")),
+		phaseRule("statue_failed", "after", "statue", &no, say("The statue seems amused.")),
+		phaseRule("statue_taken", "after", "statue", &yes, say("wrong outcome")))
+	e := New(defs)
+
+	result := e.Step("take book")
+	if len(result.Output) < 2 || result.Output[1] != "Something falls out of the book." {
+		t.Errorf("expected after text following the take, got %v", result.Output)
+	}
+
+	result = e.Step("take statue")
+	if !outputContains(result.Output, "You can't take that.") || !outputContains(result.Output, "The statue seems amused.") {
+		t.Errorf("expected failed take followed by after text, got %v", result.Output)
+	}
+	if outputContains(result.Output, "wrong outcome") {
+		t.Errorf("succeeded = true rule fired on failure: %v", result.Output)
+	}
+}
+
+func TestStep_PhaseRulesDoNotReplaceBuiltin(t *testing.T) {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules,
+		phaseRule("book_after", "after", "book", nil, say("Done.")))
+	e := New(defs)
+
+	result := e.Step("take book")
+	if !outputContains(result.Output, "You take the Book.") {
+		t.Errorf("expected built-in take output, got %v", result.Output)
+	}
+}
+
+func TestStep_Notifications(t *testing.T) {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID:    "sing_achievement",
+		Scope: "global",
+		When:  types.MatchCriteria{Verb: "sing"},
+		Effects: []types.Effect{
+			{Type: "say", Params: map[string]any{"text": "You sing a verse."}},
+			{Type: "notify", Params: map[string]any{"text": "Achievement: Bard"}},
+		},
+	})
+	e := New(defs)
+
+	result := e.Step("sing")
+	if outputContains(result.Output, "Achievement: Bard") {
+		t.Errorf("notification leaked into narrative output: %v", result.Output)
+	}
+	if len(result.Notifications) != 1 || result.Notifications[0] != "Achievement: Bard" {
+		t.Errorf("Notifications = %v, want [Achievement: Bard]", result.Notifications)
+	}
+}
+
+func TestStep_NPCReactsToEventInRoom(t *testing.T) {
+	defs := testDefs()
+	defs.Entities["guard"] = types.EntityDef{
+		ID: "guard", Kind: "npc",
+		Props: map[string]any{"name": "Guard", "location": "hall"},
+		Reactions: []types.EventHandler{{
+			ID: "entity:guard:item_taken", EventType: "item_taken",
+			Effects: []types.Effect{{Type: "say", Params: map[string]any{"text": "The guard frowns at you."}}},
+		}},
+	}
+	e := New(defs)
+
+	result := e.Step("take key")
+	if !outputContains(result.Output, "The guard frowns at you.") {
+		t.Errorf("expected guard reaction, got %v", result.Output)
+	}
+}
+
+func TestStep_CarryCapacity(t *testing.T) {
+	defs := testDefs()
+	defs.Game.PlayerStats = map[string]int{"max_weight": 5}
+	book := defs.Entities["book"]
+	book.Props["weight"] = 4.0
+	defs.Entities["book"] = book
+	key := defs.Entities["key"]
+	key.Props["weight"] = 2.0
+	defs.Entities["key"] = key
+	e := New(defs)
+
+	e.Step("take book")
+	result := e.Step("inventory")
+	if !outputContains(result.Output, "Weight: 4/5") {
+		t.Errorf("expected weight in inventory, got %v", result.Output)
+	}
+
+	// The key goes through a give_item rule rather than the built-in take.
+	result = e.Step("take key")
+	if !outputContains(result.Output, "You're carrying too much.") || state.HasItem(e.State, "key") {
+		t.Errorf("expected key to be refused, got %v", result.Output)
+	}
+
+	e.Step("drop book")
+	e.Step("take key")
+	result = e.Step("take book")
+	if !outputContains(result.Output, "You're carrying too much.") || state.HasItem(e.State, "book") {
+		t.Errorf("expected built-in take to be refused, got %v", result.Output)
+	}
+}
+
+func TestStep_CommandLogStreamsToWriter(t *testing.T) {
+	defs := testDefs()
+	defs.Game.CommandLogLimit = 1
+	e := New(defs)
+	var buf strings.Builder
+	e.LogWriter = &buf
+
+	e.Step("look")
+	e.Step("go north")
+
+	if buf.String() != "look\ngo north\n" {
+		t.Errorf("log file = %q, want both commands", buf.String())
+	}
+	if len(e.State.CommandLog) != 1 || e.State.CommandLog[0] != "go north" || e.State.CommandCount != 2 {
+		t.Errorf("CommandLog = %v (count %d), want only the latest of 2", e.State.CommandLog, e.State.CommandCount)
+	}
+}
+
+func TestStep_WearAndRemove(t *testing.T) {
+	defs := testDefs()
+	defs.Entities["cloak"] = types.EntityDef{ID: "cloak", Kind: "item", Props: map[string]any{
+		"name": "Cloak", "location": "hall", "takeable": true, "wearable": true,
+	}}
+	e := New(defs)
+
+	result := e.Step("wear cloak")
+	if !outputContains(result.Output, "You don't have that.") {
+		t.Errorf("expected refusal before taking, got %v", result.Output)
+	}
+	e.Step("take cloak")
+	result = e.Step("put on cloak")
+	if !outputContains(result.Output, "You put on the Cloak.") || !state.IsWorn(e.State, "cloak") {
+		t.Fatalf("expected cloak worn, got %v", result.Output)
+	}
+	if len(result.Events) == 0 || result.Events[0].Type != "item_worn" {
+		t.Errorf("expected item_worn event, got %v", result.Events)
+	}
+	if result = e.Step("wear book"); !outputContains(result.Output, "You can't wear that.") {
+		t.Errorf("expected book to be unwearable, got %v", result.Output)
+	}
+
+	result = e.Step("take off cloak")
+	if !outputContains(result.Output, "You take off the Cloak.") || state.IsWorn(e.State, "cloak") {
+		t.Errorf("expected cloak removed, got %v", result.Output)
+	}
+
+	// Dropping a worn item takes it off.
+	e.Step("wear cloak")
+	e.Step("drop cloak")
+	if state.IsWorn(e.State, "cloak") {
+		t.Error("dropped cloak should no longer be worn")
+	}
+}
+
+func TestStep_EatConsumesAndHeals(t *testing.T) {
+	defs := testDefs()
+	defs.Game.PlayerStats = map[string]int{"hp": 5, "max_hp": 10}
+	defs.Entities["apple"] = types.EntityDef{ID: "apple", Kind: "item", Props: map[string]any{
+		"name": "Apple", "location": "hall", "takeable": true, "edible": true, "nutrition": 3,
+	}}
+	defs.Handlers = []types.EventHandler{{
+		ID: "ate", EventType: "item_consumed",
+		Effects: []types.Effect{{Type: "set_flag", Params: map[string]any{"flag": "fed", "value": true}}},
+	}}
+	e := New(defs)
+
+	if result := e.Step("drink book"); !outputContains(result.Output, "You can't drink that.") {
+		t.Errorf("expected book to be undrinkable, got %v", result.Output)
+	}
+	e.Step("take apple")
+	result := e.Step("eat apple")
+	if !outputContains(result.Output, "You eat the Apple.") {
+		t.Errorf("expected eat message, got %v", result.Output)
+	}
+	if state.HasItem(e.State, "apple") || state.EntityLocation(e.State, e.Defs, "apple") == "hall" {
+		t.Error("apple should be used up")
+	}
+	if hp := e.State.Player.Stats["hp"]; hp != 8 {
+		t.Errorf("hp = %d, want 8", hp)
+	}
+	if !state.GetFlag(e.State, "fed") {
+		t.Error("item_consumed handler should have fired")
+	}
+}
+
+func TestStep_ReadShowsText(t *testing.T) {
+	defs := testDefs()
+	book := defs.Entities["book"]
+	book.Props["text"] = "It was a dark and stormy night."
+	defs.Entities["book"] = book
+	e := New(defs)
+
+	if result := e.Step("read book"); !outputContains(result.Output, "It was a dark and stormy night.") {
+		t.Errorf("expected book text, got %v", result.Output)
+	}
+	if result := e.Step("read statue"); !outputContains(result.Output, "A weathered statue of a knight.") {
+		t.Errorf("expected description without text, got %v", result.Output)
+	}
+}
+
+func TestClock_AdvancesEachTurnAndNightDescription(t *testing.T) {
+	defs := testDefs()
+	defs.Game.Clock = &types.ClockDef{Start: 19*60 + 50, MinutesPerTurn: 5, Dawn: 6 * 60, Dusk: 20 * 60}
+	hall := defs.Rooms["hall"]
+	hall.NightDescription = "The hall is dark."
+	defs.Rooms["hall"] = hall
+	e := New(defs)
+
+	if outputContains(e.Step("look").Output, "dark") {
+		t.Error("expected the day description before dusk")
+	}
+	e.Step("xyzzy_unknown") // failed commands take a turn too
+	if e.State.Clock != 20*60 {
+		t.Errorf("Clock = %d, want %d after two turns", e.State.Clock, 20*60)
+	}
+	if !outputContains(e.Step("look").Output, "The hall is dark.") {
+		t.Error("expected the night description after dusk")
+	}
+}
+
+func TestStep_Cues(t *testing.T) {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "ring_bell", Scope: "global", When: types.MatchCriteria{Verb: "ring"},
+		Effects: []types.Effect{
+			{Type: "play_sound", Params: map[string]any{"sound": "bell"}},
+			{Type: "play_music", Params: map[string]any{"music": "chimes"}},
+		},
+	})
+	e := New(defs)
+
+	result := e.Step("ring")
+	want := []types.Cue{{Type: "sound", Name: "bell"}, {Type: "music", Name: "chimes"}}
+	if !slices.Equal(result.Cues, want) {
+		t.Errorf("Cues = %v, want %v", result.Cues, want)
+	}
+
+	// The music keeps playing; only the sound repeats.
+	result = e.Step("ring")
+	if want := []types.Cue{{Type: "sound", Name: "bell"}}; !slices.Equal(result.Cues, want) {
+		t.Errorf("Cues = %v, want %v", result.Cues, want)
+	}
+}
+
+func TestStep_Image(t *testing.T) {
+	defs := testDefs()
+	hall := defs.Rooms["hall"]
+	hall.Image = "art/hall.png"
+	defs.Rooms["hall"] = hall
+	defs.Entities["book"].Props["image"] = "art/book.png"
+	e := New(defs)
+
+	tests := []struct{ input, want string }{
+		{"look", "art/hall.png"},
+		{"examine book", "art/book.png"},
+		{"inventory", ""},
+		{"go north", ""}, // the garden has no image
+		{"go south", "art/hall.png"},
+	}
+	for _, tt := range tests {
+		if got := e.Step(tt.input).Image; got != tt.want {
+			t.Errorf("%s: Image = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	eng := New(testDefs(), WithSeed(9))
+	snap := eng.Snapshot()
+	eng.Step("take book")
+	eng.Step("north")
+	if eng.State.Player.Location != "garden" || snap.Player.Location != "hall" || len(snap.Player.Inventory) != 0 {
+		t.Fatalf("snapshot changed with the game: %+v", snap.Player)
+	}
+
+	eng.Restore(snap)
+	if eng.State.Player.Location != "hall" || len(eng.State.Player.Inventory) != 0 || eng.State.TurnCount != 0 {
+		t.Errorf("restored player %+v, turn %d", eng.State.Player, eng.State.TurnCount)
+	}
+	roll := eng.RNG.Roll(100)
+	eng.Step("take book")
+	eng.Restore(snap)
+	if again := eng.RNG.Roll(100); again != roll {
+		t.Errorf("dice after restoring = %d, want %d", again, roll)
+	}
+}
+
+func TestStep_GameBuiltinsTurnedOff(t *testing.T) {
+	defs := testDefs()
+	defs.Game.Builtins = map[string]bool{"take": false, "drop": true}
+	eng := New(defs)
+
+	result := eng.Step("take book")
+	if !result.Failed || state.HasItem(eng.State, "book") {
+		t.Errorf("take should have no built-in behavior: %v", result.Output)
+	}
+	eng.Step("take key")
+	if !state.HasItem(eng.State, "key") {
+		t.Fatal("the rule for taking the key should still run")
+	}
+	eng.Step("drop key")
+	if state.HasItem(eng.State, "key") {
+		t.Error("drop = true should leave the built-in on")
+	}
+}
+
+func TestExitGuards(t *testing.T) {
+	defs := testDefs()
+	hall := defs.Rooms["hall"]
+	hall.Exits = map[string]string{"north": "garden", "east": "garden", "down": "garden"}
+	lowered := []types.Condition{{Type: "flag_set", Params: map[string]any{"flag": "bridge_lowered"}}}
+	hall.ExitGuards = map[string]types.ExitGuardDef{
+		"north": {Requires: lowered, BlockedText: "The bridge is raised.", VisibleWhenBlocked: true},
+		"east":  {Requires: lowered},
+		"down":  {},
+	}
+	defs.Rooms["hall"] = hall
+	eng := New(defs)
+
+	if got := eng.ExitDirs("hall"); !slices.Equal(got, []string{"down", "north"}) {
+		t.Errorf("ExitDirs while blocked = %v, want [down north]", got)
+	}
+	result := eng.Step("north")
+	if !outputContains(result.Output, "The bridge is raised.") || eng.State.Player.Location != "hall" {
+		t.Errorf("go north while blocked: %v, in %s", result.Output, eng.State.Player.Location)
+	}
+	result = eng.Step("east")
+	if !outputContains(result.Output, "You can't go that way.") || eng.State.Player.Location != "hall" {
+		t.Errorf("go east while blocked: %v, in %s", result.Output, eng.State.Player.Location)
+	}
+	if result = eng.Step("look east"); !outputContains(result.Output, "You see no way east.") {
+		t.Errorf("look at a hidden blocked exit: %v", result.Output)
+	}
+
+	eng.State.Flags["bridge_lowered"] = true
+	if got := eng.ExitDirs("hall"); !slices.Equal(got, []string{"down", "east", "north"}) {
+		t.Errorf("ExitDirs once open = %v, want [down east north]", got)
+	}
+	eng.Step("east")
+	if eng.State.Player.Location != "garden" {
+		t.Errorf("go east once open: in %s, want garden", eng.State.Player.Location)
+	}
+}
+
+func TestVehicles(t *testing.T) {
+	defs := testDefs()
+	defs.Entities["boat"] = types.EntityDef{ID: "boat", Kind: "entity",
+		Props: map[string]any{"name": "boat", "location": "hall", "enterable": true}}
+	garden := defs.Rooms["garden"]
+	garden.Exits = map[string]string{"south": "hall", "west": "hall"}
+	garden.ExitGuards = map[string]types.ExitGuardDef{"west": {Vehicle: "boat", VisibleWhenBlocked: true}}
+	defs.Rooms["garden"] = garden
+	eng := New(defs)
+
+	if result := eng.Step("enter book"); !outputContains(result.Output, "You can't get into that.") {
+		t.Errorf("enter a book: %v", result.Output)
+	}
+	result := eng.Step("get in boat")
+	if eng.State.Player.Vehicle != "boat" || len(result.Events) == 0 || result.Events[0].Type != "vehicle_entered" {
+		t.Fatalf("get in boat: vehicle %q, output %v", eng.State.Player.Vehicle, result.Output)
+	}
+	result = eng.Step("north")
+	if loc := state.EntityLocation(eng.State, eng.Defs, "boat"); loc != "garden" {
+		t.Errorf("boat in %q after going north, want garden", loc)
+	}
+	if !outputContains(result.Output, "You are in the *boat*.") || outputContains(result.Output, "You see: *boat*") {
+		t.Errorf("description should say the player is in the boat: %v", result.Output)
+	}
+	eng.Step("exit")
+	if eng.State.Player.Vehicle != "" {
+		t.Errorf("still in %q after exit", eng.State.Player.Vehicle)
+	}
+	result = eng.Step("west")
+	if !outputContains(result.Output, "You'd need the boat to go that way.") || eng.State.Player.Location != "garden" {
+		t.Errorf("go west on foot: %v, in %s", result.Output, eng.State.Player.Location)
+	}
+	if !slices.Contains(eng.ExitDirs("garden"), "west") {
+		t.Errorf("west should be listed while blocked: %v", eng.ExitDirs("garden"))
+	}
+	eng.Step("board boat")
+	eng.Step("west")
+	if eng.State.Player.Location != "hall" || state.EntityLocation(eng.State, eng.Defs, "boat") != "hall" {
+		t.Errorf("go west by boat: player in %s", eng.State.Player.Location)
+	}
+	if !rules.EvalCondition(types.Condition{Type: "in_vehicle", Params: map[string]any{"entity": "boat"}}, eng.State, eng.Defs) {
+		t.Error("in_vehicle boat should hold")
+	}
+}
+
+func TestSwitchPlayer(t *testing.T) {
+	defs := testDefs()
+	defs.Game.Players = map[string]types.PlayerDef{
+		"alice": {},
+		"bob":   {Start: "garden", Stats: map[string]int{"hp": 7}, Inventory: []string{"book"}},
+	}
+	defs.Game.StartPlayer = "alice"
+	book := defs.Entities["book"]
+	book.Props = map[string]any{"name": "Book", "takeable": true}
+	defs.Entities["book"] = book
+	for _, id := range []string{"alice", "bob"} {
+		defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+			ID: "switch_" + id, Scope: "global",
+			When:    types.MatchCriteria{Verb: "switch", Object: id},
+			Effects: []types.Effect{{Type: "switch_player", Params: map[string]any{"player": id}}},
+		})
+	}
+	eng := New(defs)
+
+	if eng.State.ActivePlayer != "alice" || eng.State.Player.Location != "hall" {
+		t.Fatalf("start as %q in %s, want alice in hall", eng.State.ActivePlayer, eng.State.Player.Location)
+	}
+	eng.Step("take key")
+	result := eng.Step("switch bob")
+	if len(result.Events) == 0 || result.Events[0].Type != "player_switched" || result.Events[0].Data["from"] != "alice" {
+		t.Fatalf("switch bob: events %v", result.Events)
+	}
+	p := eng.State.Player
+	if eng.State.ActivePlayer != "bob" || p.Location != "garden" || p.Stats["hp"] != 7 || !state.HasItem(eng.State, "book") || state.HasItem(eng.State, "key") {
+		t.Errorf("as bob: %+v", p)
+	}
+	if !rules.EvalCondition(types.Condition{Type: "is_player", Params: map[string]any{"player": "bob"}}, eng.State, eng.Defs) {
+		t.Error("is_player bob should hold")
+	}
+	if eng.Step("take key"); state.HasItem(eng.State, "key") {
+		t.Error("bob took the key alice carries")
+	}
+
+	eng.Step("switch alice")
+	if eng.State.ActivePlayer != "alice" || eng.State.Player.Location != "hall" || !state.HasItem(eng.State, "key") {
+		t.Errorf("back as alice: %+v", eng.State.Player)
+	}
+	if bob := eng.State.Players["bob"]; bob.Location != "garden" || len(bob.Inventory) != 1 {
+		t.Errorf("bob left as %+v", bob)
+	}
+}
+
+func TestLiquids(t *testing.T) {
+	defs := testDefs()
+	defs.Entities["water"] = types.EntityDef{ID: "water", Kind: "entity",
+		Props: map[string]any{"name": "water", "liquid": true, "nutrition": 1}}
+	defs.Entities["fountain"] = types.EntityDef{ID: "fountain", Kind: "entity",
+		Props: map[string]any{"name": "fountain", "location": "hall", "liquid_source": "water"}}
+	defs.Entities["bottle"] = types.EntityDef{ID: "bottle", Kind: "item",
+		Props: map[string]any{"name": "bottle", "location": "hall", "takeable": true, "holds_liquid": true, "capacity": 3}}
+	defs.Entities["fire"] = types.EntityDef{ID: "fire", Kind: "entity",
+		Props: map[string]any{"name": "fire", "location": "hall"}}
+	eng := New(defs)
+
+	if result := eng.Step("fill key"); !outputContains(result.Output, "You can't fill that.") {
+		t.Errorf("fill a key: %v", result.Output)
+	}
+	eng.Step("take bottle")
+	if result := eng.Step("drink from bottle"); !outputContains(result.Output, "The bottle is empty.") {
+		t.Errorf("drink from an empty bottle: %v", result.Output)
+	}
+	result := eng.Step("fill bottle from fountain")
+	if !outputContains(result.Output, "You fill the bottle with water.") || len(result.Events) == 0 || result.Events[0].Type != "vessel_filled" {
+		t.Fatalf("fill bottle from fountain: %v", result.Output)
+	}
+	if liquid, units := state.VesselContents(eng.State, eng.Defs, "bottle"); liquid != "water" || units != 3 {
+		t.Errorf("bottle holds %d %q, want 3 water", units, liquid)
+	}
+	if result := eng.Step("inventory"); !outputContains(result.Output, "bottle of water (3/3)") {
+		t.Errorf("inventory: %v", result.Output)
+	}
+	if result := eng.Step("fill bottle"); !outputContains(result.Output, "The bottle is already full.") {
+		t.Errorf("fill a full bottle: %v", result.Output)
+	}
+
+	result = eng.Step("drink water")
+	if !outputContains(result.Output, "You take a drink of water.") || len(result.Events) == 0 || result.Events[0].Type != "liquid_drunk" {
+		t.Errorf("drink water: %v", result.Output)
+	}
+	if _, units := state.VesselContents(eng.State, eng.Defs, "bottle"); units != 2 {
+		t.Errorf("bottle holds %d units after a drink, want 2", units)
+	}
+	if !rules.EvalCondition(types.Condition{Type: "has_liquid", Params: map[string]any{"liquid": "water"}}, eng.State, eng.Defs) {
+		t.Error("has_liquid water should hold")
+	}
+
+	result = eng.Step("pour water on fire")
+	if !outputContains(result.Output, "You pour the water on the fire.") {
+		t.Errorf("pour water on fire: %v", result.Output)
+	}
+	if len(result.Events) == 0 || result.Events[0].Type != "liquid_poured" || result.Events[0].Data["target"] != "fire" {
+		t.Errorf("events = %v, want liquid_poured on the fire", result.Events)
+	}
+	if liquid, _ := state.VesselContents(eng.State, eng.Defs, "bottle"); liquid != "" {
+		t.Errorf("bottle still holds %q after pouring", liquid)
+	}
+	if result := eng.Step("inventory"); !outputContains(result.Output, "bottle (empty)") {
+		t.Errorf("inventory: %v", result.Output)
+	}
+}
+
+func TestTie(t *testing.T) {
+	defs := testDefs()
+	defs.Entities["rope"] = types.EntityDef{ID: "rope", Kind: "item",
+		Props: map[string]any{"name": "rope", "location": "hall", "takeable": true, "tieable": true}}
+	eng := New(defs)
+	isAttached := func(entity, to string) bool {
+		return rules.EvalCondition(types.Condition{Type: "is_attached",
+			Params: map[string]any{"entity": entity, "to": to}}, eng.State, eng.Defs)
+	}
+
+	if result := eng.Step("tie book to statue"); !outputContains(result.Output, "You can't tie that.") {
+		t.Errorf("tie a book: %v", result.Output)
+	}
+	eng.Step("take rope")
+	result := eng.Step("tie rope to statue")
+	if !outputContains(result.Output, "You tie the rope to the Statue.") || !isAttached("rope", "statue") {
+		t.Fatalf("tie rope to statue: %v", result.Output)
+	}
+	if len(result.Events) == 0 || result.Events[len(result.Events)-1].Type != "entity_attached" {
+		t.Errorf("events = %v, want entity_attached last", result.Events)
+	}
+	if state.HasItem(eng.State, "rope") {
+		t.Error("rope should be put down as it is tied")
+	}
+	if !isAttached("statue", "rope") || !isAttached("rope", "") || isAttached("key", "") {
+		t.Error("is_attached should hold either way round, and only for tied entities")
+	}
+	if result := eng.Step("take rope"); !outputContains(result.Output, "The rope is tied to the Statue.") || state.HasItem(eng.State, "rope") {
+		t.Errorf("take a tied rope: %v", result.Output)
+	}
+	if result := eng.Step("examine statue"); !outputContains(result.Output, "Tied to it: *rope*.") {
+		t.Errorf("examine statue: %v", result.Output)
+	}
+
+	result = eng.Step("untie statue")
+	if !outputContains(result.Output, "You untie the rope from the Statue.") || isAttached("rope", "") {
+		t.Errorf("untie statue: %v", result.Output)
+	}
+	if result := eng.Step("untie rope"); !outputContains(result.Output, "The rope isn't tied to anything.") {
+		t.Errorf("untie an untied rope: %v", result.Output)
+	}
+}
+
+func TestFacts(t *testing.T) {
+	defs := testDefs()
+	defs.Facts = map[string]types.FactDef{
+		"password": {ID: "password", Text: "The password is swordfish."},
+		"secret":   {ID: "secret"},
+	}
+	learn := func(fact string) types.Effect {
+		return types.Effect{Type: "learn_fact", Params: map[string]any{"fact": fact}}
+	}
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "read_book", Scope: "global",
+		When:    types.MatchCriteria{Verb: "read", Object: "book"},
+		Effects: []types.Effect{learn("password"), learn("secret")},
+	})
+	e := New(defs)
+	knows := func(fact string) bool {
+		return rules.EvalCondition(types.Condition{Type: "knows_fact",
+			Params: map[string]any{"fact": fact}}, e.State, e.Defs)
+	}
+
+	if result := e.Step("notes"); !outputContains(result.Output, "You haven't learned anything worth noting yet.") {
+		t.Errorf("notes before learning: %v", result.Output)
+	}
+	result := e.Step("read book")
+	if !knows("password") || !knows("secret") || e.State.Flags["password"] {
+		t.Fatalf("facts = %v, want both learned apart from the flags", e.State.Facts)
+	}
+	if len(result.Notifications) != 1 || result.Notifications[0] != "Noted: The password is swordfish." {
+		t.Errorf("notifications = %v", result.Notifications)
+	}
+	if result := e.Step("read book"); len(result.Notifications) != 0 || len(e.State.Facts) != 2 {
+		t.Errorf("learning again: %v, facts %v", result.Notifications, e.State.Facts)
+	}
+
+	result = e.Step("recall")
+	if !outputContains(result.Output, "You have learned:") || !outputContains(result.Output, "  - The password is swordfish.") {
+		t.Errorf("recall: %v", result.Output)
+	}
+	if len(result.Output) != 2 {
+		t.Errorf("a fact without text should not be listed: %v", result.Output)
+	}
+}
+
+func TestNoise(t *testing.T) {
+	defs := testDefs()
+	defs.Game.Noise = map[string]int{"take": 8}
+	defs.Entities["guard"] = types.EntityDef{ID: "guard", Kind: "npc",
+		Props: map[string]any{"name": "Guard", "location": "garden", "alertness": 5}}
+	defs.Entities["cat"] = types.EntityDef{ID: "cat", Kind: "npc",
+		Props: map[string]any{"name": "Cat", "location": "hall", "alertness": 7}}
+	e := New(defs)
+	alert := func(npc string) any {
+		v, _ := state.GetEntityProp(e.State, e.Defs, npc, "alert")
+		return v
+	}
+	alerted := func(result types.Result) []string {
+		var npcs []string
+		for _, evt := range result.Events {
+			if evt.Type == "npc_alerted" {
+				npcs = append(npcs, evt.Data["npc"].(string))
+			}
+		}
+		return npcs
+	}
+
+	result := e.Step("take book")
+	if !outputContains(result.Output, "Cat looks up, suspicious.") {
+		t.Errorf("take book: %v", result.Output)
+	}
+	if got := alerted(result); len(got) != 2 || alert("cat") != "suspicious" || alert("guard") != "suspicious" {
+		t.Errorf("alerted %v, cat %v, guard %v", got, alert("cat"), alert("guard"))
+	}
+	if result := e.Step("drop book"); len(alerted(result)) != 0 {
+		t.Errorf("a drop should not carry to the garden: %v", alerted(result))
+	}
+	if result := e.Step("drop key"); len(alerted(result)) != 0 {
+		t.Errorf("a failed command should make no noise: %v", alerted(result))
+	}
+
+	result = e.Step("take book")
+	if !outputContains(result.Output, "Cat has heard enough, and turns on you!") || alert("guard") != "hostile" {
+		t.Errorf("take book again: %v, guard %v", result.Output, alert("guard"))
+	}
+
+	turns := e.State.TurnCount
+	if result := e.Step("sneak to the north"); e.State.Player.Location != "garden" || len(alerted(result)) != 0 {
+		t.Fatalf("sneak north: %v", result.Output)
+	}
+	if e.State.TurnCount != turns+2 {
+		t.Errorf("sneaking took %d turns, want 2", e.State.TurnCount-turns)
+	}
+	if result := e.Step("sneak"); !outputContains(result.Output, "Sneak where?") {
+		t.Errorf("sneak: %v", result.Output)
+	}
+}
+
+func TestLocks(t *testing.T) {
+	defs := testDefs()
+	hall := defs.Rooms["hall"]
+	hall.ExitGuards = map[string]types.ExitGuardDef{"north": {Door: "door", VisibleWhenBlocked: true}}
+	defs.Rooms["hall"] = hall
+	defs.Entities["door"] = types.EntityDef{ID: "door", Kind: "entity",
+		Props: map[string]any{"name": "Iron Door", "location": "hall", "lockable": true, "lock_key": "key", "locked": true}}
+	defs.Entities["chest"] = types.EntityDef{ID: "chest", Kind: "entity",
+		Props: map[string]any{"name": "Chest", "location": "hall", "lockable": true, "lock_key": "key", "locked": true}}
+	defs.Entities["coin"] = types.EntityDef{ID: "coin", Kind: "item",
+		Props: map[string]any{"name": "coin", "location": "chest", "takeable": true}}
+	e := New(defs)
+	lastEvent := func(result types.Result) types.Event {
+		for i := len(result.Events) - 1; i >= 0; i-- {
+			if evt := result.Events[i]; evt.Type == "locked" || evt.Type == "unlocked" {
+				return evt
+			}
+		}
+		return types.Event{}
+	}
+
+	if result := e.Step("go north"); !outputContains(result.Output, "The Iron Door is locked.") {
+		t.Errorf("go through a locked door: %v", result.Output)
+	}
+	if result := e.Step("look"); !outputContains(result.Output, "north") {
+		t.Errorf("a locked door's exit should be listed: %v", result.Output)
+	}
+	if result := e.Step("unlock door"); !outputContains(result.Output, "You have no key that fits the Iron Door.") {
+		t.Errorf("unlock without the key: %v", result.Output)
+	}
+	if e.Step("take coin"); state.HasItem(e.State, "coin") {
+		t.Error("the coin should be out of reach in the locked chest")
+	}
+
+	e.Step("take key")
+	e.Step("take book")
+	if result := e.Step("unlock door with book"); !outputContains(result.Output, "The Book doesn't fit the Iron Door.") {
+		t.Errorf("unlock with the book: %v", result.Output)
+	}
+	result := e.Step("unlock door")
+	if !outputContains(result.Output, "You unlock the Iron Door with the Key.") || state.IsLocked(e.State, e.Defs, "door") {
+		t.Fatalf("unlock door: %v", result.Output)
+	}
+	if evt := lastEvent(result); evt.Type != "unlocked" || evt.Data["entity"] != "door" || evt.Data["key"] != "key" {
+		t.Errorf("event = %v, want unlocked", evt)
+	}
+	if result := e.Step("unlock door"); !outputContains(result.Output, "The Iron Door isn't locked.") {
+		t.Errorf("unlock again: %v", result.Output)
+	}
+
+	if result := e.Step("examine chest"); !outputContains(result.Output, "The Chest is locked.") {
+		t.Errorf("examine a locked chest: %v", result.Output)
+	}
+	e.Step("unlock chest with key")
+	if result := e.Step("examine chest"); !outputContains(result.Output, "Inside: *coin*.") {
+		t.Errorf("examine an unlocked chest: %v", result.Output)
+	}
+	if e.Step("take coin"); !state.HasItem(e.State, "coin") {
+		t.Error("the coin should be taken from the unlocked chest")
+	}
+
+	result = e.Step("lock door")
+	if !outputContains(result.Output, "You lock the Iron Door with the Key.") || lastEvent(result).Type != "locked" {
+		t.Errorf("lock door: %v", result.Output)
+	}
+	if e.Step("go north"); e.State.Player.Location != "hall" {
+		t.Error("the locked door should bar the way again")
+	}
+	if result := e.Step("lock statue"); !outputContains(result.Output, "That has no lock.") {
+		t.Errorf("lock statue: %v", result.Output)
+	}
+}
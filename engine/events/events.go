@@ -3,27 +3,98 @@
 package events
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/nathoo/questcore/engine/rules"
 	"github.com/nathoo/questcore/engine/state"
 	"github.com/nathoo/questcore/types"
 )
 
 // Dispatch runs event handlers against the emitted events. Single pass —
-// no recursion. Returns additional effects produced by matching handlers.
+// no recursion. For each event, matching handlers run in priority order
+// (highest first, ties in declaration order), followed by the reactions of
+// entities in the room where the event happened. Once handlers that have
+// fired are skipped, and those firing now are recorded. Returns additional
+// effects produced by matching handlers.
 func Dispatch(events []types.Event, s *types.State, defs *state.Defs) []types.Effect {
 	var result []types.Effect
 
+	handlers := byPriority(defs.Handlers)
+	reactors := reactingEntities(defs)
+
+	fired := map[string]bool{}
+	run := func(handler types.EventHandler, event types.Event) {
+		if !matches(handler.EventType, event.Type) {
+			return
+		}
+		if handler.Once && (s.Fired[handler.ID] || fired[handler.ID]) {
+			return
+		}
+		if !rules.EvalAllConditions(handler.Conditions, s, defs) {
+			return
+		}
+		if handler.Once {
+			fired[handler.ID] = true
+			result = append(result, types.Effect{Type: "mark_fired", Params: map[string]any{"rule": handler.ID}})
+		}
+		result = append(result, handler.Effects...)
+	}
+
 	for _, event := range events {
-		for _, handler := range defs.Handlers {
-			if handler.EventType != event.Type {
+		for _, handler := range handlers {
+			run(handler, event)
+		}
+
+		room, _ := event.Data["room"].(string)
+		if room == "" {
+			continue
+		}
+		for _, id := range reactors {
+			if state.EntityLocation(s, defs, id) != room {
 				continue
 			}
-			if !rules.EvalAllConditions(handler.Conditions, s, defs) {
+			if alive, _ := state.GetEntityProp(s, defs, id, "alive"); alive == false {
 				continue
 			}
-			result = append(result, handler.Effects...)
+			for _, reaction := range byPriority(defs.Entities[id].Reactions) {
+				run(reaction, event)
+			}
 		}
 	}
 
 	return result
 }
+
+// byPriority returns handlers sorted highest priority first, keeping
+// declaration order for ties.
+func byPriority(handlers []types.EventHandler) []types.EventHandler {
+	sorted := make([]types.EventHandler, len(handlers))
+	copy(sorted, handlers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+	return sorted
+}
+
+// reactingEntities returns the IDs of entities with reactions, sorted.
+func reactingEntities(defs *state.Defs) []string {
+	var ids []string
+	for id, def := range defs.Entities {
+		if len(def.Reactions) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// matches reports whether a handler's event pattern matches an event type.
+// A pattern ending in "*" matches any event type with that prefix, so "*"
+// alone matches every event.
+func matches(pattern, eventType string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(eventType, prefix)
+	}
+	return pattern == eventType
+}
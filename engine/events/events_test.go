@@ -145,3 +145,85 @@ func TestDispatch_MultipleEvents(t *testing.T) {
 		t.Fatalf("expected 3 effects from multiple events, got %d", len(effs))
 	}
 }
+
+func TestDispatch_Priority(t *testing.T) {
+	defs := testDefs()
+	defs.Handlers[2].Priority = 10
+	s := state.NewState(defs)
+
+	effs := Dispatch([]types.Event{{Type: "item_taken"}}, s, defs)
+	if len(effs) != 2 || effs[0].Type != "inc_counter" || effs[1].Type != "say" {
+		t.Errorf("expected higher priority handler first, got %v", effs)
+	}
+}
+
+func TestDispatch_Wildcard(t *testing.T) {
+	tests := []struct {
+		pattern, event string
+		want           bool
+	}{
+		{"*", "room_entered", true},
+		{"item_*", "item_taken", true},
+		{"item_*", "item_dropped", true},
+		{"item_*", "flag_changed", false},
+		{"item_taken", "item_taken", true},
+		{"item_taken", "item_taken_twice", false},
+	}
+	for _, tt := range tests {
+		if got := matches(tt.pattern, tt.event); got != tt.want {
+			t.Errorf("matches(%q, %q) = %v, want %v", tt.pattern, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestDispatch_Once(t *testing.T) {
+	defs := testDefs()
+	defs.Handlers = []types.EventHandler{{
+		ID: "first_item", EventType: "item_*", Once: true,
+		Effects: []types.Effect{{Type: "say", Params: map[string]any{"text": "First!"}}},
+	}}
+	s := state.NewState(defs)
+
+	// Two matching events in one pass fire the handler once.
+	effs := Dispatch([]types.Event{{Type: "item_taken"}, {Type: "item_dropped"}}, s, defs)
+	if len(effs) != 2 || effs[0].Type != "mark_fired" || effs[0].Params["rule"] != "first_item" {
+		t.Fatalf("expected mark_fired then say, got %v", effs)
+	}
+
+	// Once recorded in state, it stays disabled.
+	s.Fired = map[string]bool{"first_item": true}
+	if effs := Dispatch([]types.Event{{Type: "item_taken"}}, s, defs); len(effs) != 0 {
+		t.Errorf("expected fired once handler to be skipped, got %v", effs)
+	}
+}
+
+func TestDispatch_Reactions(t *testing.T) {
+	defs := testDefs()
+	defs.Handlers = nil
+	defs.Rooms["room2"] = types.RoomDef{ID: "room2"}
+	complain := types.EventHandler{
+		ID: "entity:guard:item_taken", EventType: "item_taken",
+		Effects: []types.Effect{{Type: "say", Params: map[string]any{"text": "Hey! Put that back."}}},
+	}
+	defs.Entities["guard"] = types.EntityDef{
+		ID: "guard", Kind: "npc",
+		Props:     map[string]any{"location": "room1"},
+		Reactions: []types.EventHandler{complain},
+	}
+	s := state.NewState(defs)
+
+	taken := []types.Event{{Type: "item_taken", Data: map[string]any{"item": "gem", "room": "room1"}}}
+	if effs := Dispatch(taken, s, defs); len(effs) != 1 || effs[0].Params["text"] != "Hey! Put that back." {
+		t.Errorf("expected guard to react, got %v", effs)
+	}
+
+	elsewhere := []types.Event{{Type: "item_taken", Data: map[string]any{"item": "gem", "room": "room2"}}}
+	if effs := Dispatch(elsewhere, s, defs); len(effs) != 0 {
+		t.Errorf("expected no reaction to an event in another room, got %v", effs)
+	}
+
+	s.Entities["guard"] = types.EntityState{Props: map[string]any{"alive": false}}
+	if effs := Dispatch(taken, s, defs); len(effs) != 0 {
+		t.Errorf("expected defeated guard not to react, got %v", effs)
+	}
+}
@@ -0,0 +1,20 @@
+package engine
+
+import (
+	"github.com/nathoo/questcore/types"
+)
+
+// builtinNotes lists the facts the player has learned, in the order
+// learned. Facts without text are known but not listed.
+func (e *Engine) builtinNotes() ([]types.Effect, []string) {
+	var entries []string
+	for _, id := range e.State.Facts {
+		if text := e.Defs.Facts[id].Text; text != "" {
+			entries = append(entries, "  - "+text)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, []string{e.msg("notes_none")}
+	}
+	return nil, append([]string{e.msg("notes")}, entries...)
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// A BeforeStepHook is called with each command before it is run. Returning
+// an error vetoes the command: the turn is not taken and StepContext
+// returns the error.
+type BeforeStepHook func(ctx context.Context, input string) error
+
+// An AfterStepHook is called with each command and its result once it has
+// run.
+type AfterStepHook func(ctx context.Context, input string, result types.Result)
+
+// An EventHook is called with each event a command raises, in order, after
+// the command has run.
+type EventHook func(ctx context.Context, evt types.Event)
+
+// hooks are the functions embedders have registered to watch steps.
+type hooks struct {
+	before []BeforeStepHook
+	after  []AfterStepHook
+	event  []EventHook
+}
+
+// OnBeforeStep registers h to be called before each step; see
+// BeforeStepHook. Hooks are called in the order registered, and the first
+// veto stops the rest.
+func (e *Engine) OnBeforeStep(h BeforeStepHook) {
+	e.hooks.before = append(e.hooks.before, h)
+}
+
+// OnAfterStep registers h to be called after each step.
+func (e *Engine) OnAfterStep(h AfterStepHook) {
+	e.hooks.after = append(e.hooks.after, h)
+}
+
+// OnEvent registers h to be called with each event of each step.
+func (e *Engine) OnEvent(h EventHook) {
+	e.hooks.event = append(e.hooks.event, h)
+}
+
+// StepContext runs one player command, as Step does, and passes ctx to the
+// hooks. It returns an error, without taking the turn, if ctx is done or a
+// BeforeStepHook vetoes the command. A step that has begun runs to the end,
+// so that the state is never left half-changed.
+func (e *Engine) StepContext(ctx context.Context, input string) (types.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return types.Result{}, err
+	}
+	for _, h := range e.hooks.before {
+		if err := h(ctx, input); err != nil {
+			return types.Result{}, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return types.Result{}, err
+	}
+
+	result := e.run(input)
+	for _, evt := range result.Events {
+		for _, h := range e.hooks.event {
+			h(ctx, evt)
+		}
+	}
+	for _, h := range e.hooks.after {
+		h(ctx, input, result)
+	}
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func TestStepContext_Hooks(t *testing.T) {
+	eng := New(testDefs())
+	var calls []string
+	eng.OnBeforeStep(func(_ context.Context, input string) error {
+		calls = append(calls, "before "+input)
+		return nil
+	})
+	eng.OnEvent(func(_ context.Context, evt types.Event) {
+		calls = append(calls, "event "+evt.Type)
+	})
+	eng.OnAfterStep(func(_ context.Context, input string, result types.Result) {
+		calls = append(calls, "after "+input)
+	})
+
+	if _, err := eng.StepContext(context.Background(), "take book"); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) < 3 || calls[0] != "before take book" || calls[len(calls)-1] != "after take book" {
+		t.Fatalf("calls = %q", calls)
+	}
+	found := false
+	for _, c := range calls {
+		found = found || c == "event item_taken"
+	}
+	if !found {
+		t.Errorf("calls = %q, want the item_taken event", calls)
+	}
+}
+
+func TestStepContext_Veto(t *testing.T) {
+	eng := New(testDefs())
+	errNoTaking := errors.New("no taking in this demo")
+	eng.OnBeforeStep(func(_ context.Context, input string) error {
+		if input == "take book" {
+			return errNoTaking
+		}
+		return nil
+	})
+	after := 0
+	eng.OnAfterStep(func(context.Context, string, types.Result) { after++ })
+
+	if _, err := eng.StepContext(context.Background(), "take book"); !errors.Is(err, errNoTaking) {
+		t.Fatalf("err = %v, want the veto", err)
+	}
+	if eng.State.TurnCount != 0 || after != 0 {
+		t.Errorf("vetoed turn taken: turn %d, %d after hooks", eng.State.TurnCount, after)
+	}
+
+	result := eng.Step("take book")
+	if !result.Failed || !outputContains(result.Output, "no taking in this demo") {
+		t.Errorf("Step should report the veto as a failure: %v", result.Output)
+	}
+	if result := eng.Step("look"); result.Failed || after != 1 {
+		t.Errorf("other commands should run: %v, %d after hooks", result.Output, after)
+	}
+}
+
+func TestStepContext_Canceled(t *testing.T) {
+	eng := New(testDefs())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := eng.StepContext(ctx, "north"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if eng.State.Player.Location != "hall" {
+		t.Errorf("canceled step moved the player to %q", eng.State.Player.Location)
+	}
+}
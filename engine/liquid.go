@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// isVessel reports whether an entity holds liquid: holds_liquid = true.
+func (e *Engine) isVessel(id string) bool {
+	v, _ := state.GetEntityProp(e.State, e.Defs, id, "holds_liquid")
+	return v == true
+}
+
+// isLiquid reports whether an entity is a liquid: liquid = true.
+func (e *Engine) isLiquid(id string) bool {
+	v, _ := state.GetEntityProp(e.State, e.Defs, id, "liquid")
+	return v == true
+}
+
+// builtinFill fills a vessel from a source: a liquid source such as a
+// fountain, another vessel, or a liquid named outright, had from whatever
+// in reach offers it. With no source named, the first liquid source in
+// the room is used.
+func (e *Engine) builtinFill(objectID, sourceID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, []string{e.fail("fill_what")}
+	}
+	if !e.isVessel(objectID) {
+		return nil, []string{e.fail("cant_fill")}
+	}
+	item := e.entityName(objectID)
+	if sourceID == "" {
+		for _, id := range state.LiquidSources(e.State, e.Defs, "") {
+			if !e.isVessel(id) {
+				sourceID = id
+				break
+			}
+		}
+		if sourceID == "" {
+			return nil, []string{e.fail("fill_from_what", "item", item)}
+		}
+	}
+	if e.isLiquid(sourceID) {
+		liquid := sourceID
+		sourceID = ""
+		for _, id := range state.LiquidSources(e.State, e.Defs, liquid) {
+			if id != objectID {
+				sourceID = id
+				break
+			}
+		}
+		if sourceID == "" {
+			return nil, []string{e.fail("no_liquid", "liquid", e.entityName(liquid))}
+		}
+	}
+	if sourceID == objectID {
+		return nil, []string{e.fail("cant_fill_from", "source", item)}
+	}
+
+	liquid := state.LiquidIn(e.State, e.Defs, sourceID)
+	if liquid == "" {
+		if e.isVessel(sourceID) {
+			return nil, []string{e.fail("vessel_empty", "item", e.entityName(sourceID))}
+		}
+		return nil, []string{e.fail("cant_fill_from", "source", e.entityName(sourceID))}
+	}
+	held, units := state.VesselContents(e.State, e.Defs, objectID)
+	if held != "" && held != liquid {
+		return nil, []string{e.fail("other_liquid", "liquid", e.entityName(held), "item", item)}
+	}
+	amount := state.VesselCapacity(e.State, e.Defs, objectID) - units
+	if amount <= 0 {
+		return nil, []string{e.fail("vessel_full", "item", item)}
+	}
+
+	var effs []types.Effect
+	if e.isVessel(sourceID) {
+		_, left := state.VesselContents(e.State, e.Defs, sourceID)
+		amount = min(amount, left)
+		effs = append(effs, types.Effect{Type: "pour_liquid", Params: map[string]any{"vessel": sourceID, "target": objectID, "amount": amount}})
+	}
+	effs = append(effs, types.Effect{Type: "fill_vessel", Params: map[string]any{"vessel": objectID, "liquid": liquid, "amount": amount}})
+	return effs, []string{e.msg("fill", "item", item, "liquid", e.entityName(liquid))}
+}
+
+// builtinPour pours out a carried vessel, or the liquid in one, onto
+// targetID if given. Pouring into another vessel fills it.
+func (e *Engine) builtinPour(objectID, targetID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, []string{e.fail("pour_what")}
+	}
+	vessel := objectID
+	if e.isLiquid(objectID) {
+		vessel = ""
+		for _, id := range e.State.Player.Inventory {
+			if liquid, _ := state.VesselContents(e.State, e.Defs, id); liquid == objectID {
+				vessel = id
+				break
+			}
+		}
+		if vessel == "" {
+			return nil, []string{e.fail("no_liquid", "liquid", e.entityName(objectID))}
+		}
+	}
+	if !e.isVessel(vessel) {
+		return nil, []string{e.fail("cant_pour")}
+	}
+	if !state.HasItem(e.State, vessel) {
+		return nil, []string{e.fail("dont_have")}
+	}
+	liquid, _ := state.VesselContents(e.State, e.Defs, vessel)
+	if liquid == "" {
+		return nil, []string{e.fail("vessel_empty", "item", e.entityName(vessel))}
+	}
+	if targetID != "" && e.isVessel(targetID) {
+		return e.builtinFill(targetID, vessel)
+	}
+
+	effs := []types.Effect{
+		{Type: "pour_liquid", Params: map[string]any{"vessel": vessel, "target": targetID}},
+	}
+	if targetID == "" {
+		return effs, []string{e.msg("pour_out", "liquid", e.entityName(liquid))}
+	}
+	return effs, []string{e.msg("pour", "liquid", e.entityName(liquid), "target", e.entityName(targetID))}
+}
+
+// builtinDrink drinks a unit of liquid from a vessel or liquid source, or
+// of a liquid named outright, from whatever in reach offers it. The
+// liquid's "nutrition" prop, if any, heals the player.
+func (e *Engine) builtinDrink(objectID string) ([]types.Effect, []string) {
+	source := objectID
+	if e.isLiquid(objectID) {
+		sources := state.LiquidSources(e.State, e.Defs, objectID)
+		if len(sources) == 0 {
+			return nil, []string{e.fail("no_liquid", "liquid", e.entityName(objectID))}
+		}
+		source = sources[0]
+	}
+	liquid := state.LiquidIn(e.State, e.Defs, source)
+	if liquid == "" {
+		return nil, []string{e.fail("vessel_empty", "item", e.entityName(source))}
+	}
+	if drinkable, _ := state.GetEntityProp(e.State, e.Defs, liquid, "drinkable"); drinkable == false {
+		return nil, []string{e.fail("cant_consume", "verb", "drink")}
+	}
+	effs := []types.Effect{
+		{Type: "drink_liquid", Params: map[string]any{"vessel": source}},
+	}
+	if nutrition, ok := state.GetEntityProp(e.State, e.Defs, liquid, "nutrition"); ok {
+		effs = append(effs, types.Effect{Type: "heal", Params: map[string]any{"target": "player", "amount": nutrition}})
+	}
+	return effs, []string{e.msg("drink", "liquid", e.entityName(liquid))}
+}
+
+// vesselName is a vessel's name as the inventory lists it, with what it
+// holds.
+func (e *Engine) vesselName(id string) string {
+	liquid, units := state.VesselContents(e.State, e.Defs, id)
+	if liquid == "" {
+		return e.msg("inventory_empty_vessel", "item", e.entityName(id))
+	}
+	return e.msg("inventory_vessel", "item", e.entityName(id), "liquid", e.entityName(liquid),
+		"units", units, "capacity", state.VesselCapacity(e.State, e.Defs, id))
+}
+
+// vesselLines say what a vessel holds, for examining it.
+func (e *Engine) vesselLines(id string) []string {
+	if !e.isVessel(id) {
+		return nil
+	}
+	liquid, units := state.VesselContents(e.State, e.Defs, id)
+	if liquid == "" {
+		return []string{e.msg("vessel_empty", "item", e.entityName(id))}
+	}
+	return []string{e.msg("vessel_holds", "liquid", e.entityName(liquid),
+		"units", units, "capacity", state.VesselCapacity(e.State, e.Defs, id))}
+}
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"strings"
+
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// isLockable reports whether an entity has a lock: lock = { ... }.
+func (e *Engine) isLockable(id string) bool {
+	v, _ := state.GetEntityProp(e.State, e.Defs, id, "lockable")
+	return v == true
+}
+
+// builtinLock unlocks or locks (verb) a door or container with keyID, or,
+// with no key named, with the key to it the player carries.
+func (e *Engine) builtinLock(verb, objectID, keyID string) ([]types.Effect, []string) {
+	if objectID == "" {
+		return nil, []string{e.fail(verb + "_what")}
+	}
+	if !e.isLockable(objectID) {
+		return nil, []string{e.fail("cant_lock")}
+	}
+	item := e.entityName(objectID)
+	locked := state.IsLocked(e.State, e.Defs, objectID)
+	if verb == "unlock" && !locked {
+		return nil, []string{e.fail("already_unlocked", "item", item)}
+	}
+	if verb == "lock" && locked {
+		return nil, []string{e.fail("already_locked", "item", item)}
+	}
+
+	fits, _ := state.GetEntityProp(e.State, e.Defs, objectID, "lock_key")
+	if keyID == "" {
+		key, _ := fits.(string)
+		if key == "" || !state.HasItem(e.State, key) {
+			return nil, []string{e.fail("no_key", "item", item)}
+		}
+		keyID = key
+	}
+	if !state.HasItem(e.State, keyID) {
+		return nil, []string{e.fail("dont_have")}
+	}
+	if keyID != fits {
+		return nil, []string{e.fail("wrong_key", "key", e.entityName(keyID), "item", item)}
+	}
+
+	effs := []types.Effect{
+		{Type: verb, Params: map[string]any{"entity": objectID, "key": keyID}},
+	}
+	return effs, []string{e.msg(verb, "item", item, "key", e.entityName(keyID))}
+}
+
+// lockLines say, for examining a door or container, whether it is locked
+// or, if not, what it holds.
+func (e *Engine) lockLines(id string) []string {
+	if state.IsLocked(e.State, e.Defs, id) {
+		return []string{e.msg("locked", "item", e.entityName(id))}
+	}
+	var names []string
+	for _, held := range state.Holding(e.State, e.Defs, id) {
+		if concealed, _ := state.GetEntityProp(e.State, e.Defs, held, "concealed"); concealed != true {
+			names = append(names, markup.Bold(e.entityName(held)))
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return []string{e.msg("lock_contents", "list", strings.Join(names, ", "))}
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// Locked is an Engine that several goroutines may use at once, as when a
+// server reads a session's commands on one goroutine and saves it on
+// another. An Engine is not: a step changes the state's maps in place.
+// Each call holds the engine until it returns, so steps run one at a time
+// and in full. Hooks run with the engine held, and must not call back into
+// the Locked that runs them.
+//
+// Engines made from the same Defs don't share state, only the Defs, which
+// they only read, so each session's Locked runs in parallel with the rest.
+type Locked struct {
+	mu sync.Mutex
+	e  *Engine
+}
+
+// NewLocked wraps e. Once wrapped, e should be used only through the Locked.
+func NewLocked(e *Engine) *Locked {
+	return &Locked{e: e}
+}
+
+// Step runs one player command; see Engine.Step.
+func (l *Locked) Step(input string) types.Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.e.Step(input)
+}
+
+// StepContext runs one player command; see Engine.StepContext.
+func (l *Locked) StepContext(ctx context.Context, input string) (types.Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.e.StepContext(ctx, input)
+}
+
+// StepAll runs a line of chained commands, with no other step in between;
+// see Engine.StepAll.
+func (l *Locked) StepAll(input string) []types.Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.e.StepAll(input)
+}
+
+// MetaCommand runs one of the game's own meta-commands; see
+// Engine.MetaCommand.
+func (l *Locked) MetaCommand(input string) (result types.Result, command string, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.e.MetaCommand(input)
+}
+
+// Snapshot returns a copy of the game's state; see Engine.Snapshot.
+func (l *Locked) Snapshot() *types.State {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.e.Snapshot()
+}
+
+// Restore returns the game to a snapshot; see Engine.Restore.
+func (l *Locked) Restore(snap *types.State) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.e.Restore(snap)
+}
+
+// Do calls f with the engine held, for anything else, such as saving:
+//
+//	l.Do(func(e *engine.Engine) { data, err = save.Save(e.State, e.Defs) })
+//
+// f must not keep e, or the state, once it returns.
+func (l *Locked) Do(f func(e *Engine)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f(l.e)
+}
+
+// Games makes engines for games by name, loading each game once: every
+// session of a game shares its Defs. A server keeps one Games and calls New
+// for each player who joins.
+type Games struct {
+	load func(name string) (*state.Defs, error)
+
+	mu    sync.Mutex
+	games map[string]*game
+}
+
+// game is a game Games has loaded, or is loading.
+type game struct {
+	ready chan struct{} // closed once loaded
+	defs  *state.Defs
+	err   error
+}
+
+// NewGames returns a Games that loads games with load, given the name
+// passed to New, e.g. NewGames(loader.Load) for names that are game
+// directories.
+func NewGames(load func(name string) (*state.Defs, error)) *Games {
+	return &Games{load: load, games: map[string]*game{}}
+}
+
+// Defs returns the definitions of the game called name, loading them if
+// this is the first time it is asked for. Callers asking while it loads
+// wait for it. A game that fails to load is tried again next time.
+func (g *Games) Defs(name string) (*state.Defs, error) {
+	g.mu.Lock()
+	gm, ok := g.games[name]
+	if !ok {
+		gm = &game{ready: make(chan struct{})}
+		g.games[name] = gm
+	}
+	g.mu.Unlock()
+
+	if ok {
+		<-gm.ready
+		return gm.defs, gm.err
+	}
+	// Should load panic, the callers waiting get this error rather than
+	// waiting forever.
+	gm.err = fmt.Errorf("loading game %q failed", name)
+	defer func() {
+		if gm.err != nil {
+			g.mu.Lock()
+			delete(g.games, name)
+			g.mu.Unlock()
+		}
+		close(gm.ready)
+	}()
+	gm.defs, gm.err = g.load(name)
+	return gm.defs, gm.err
+}
+
+// New starts a session of the game called name, configured by opts, as
+// engine.New does.
+func (g *Games) New(name string, opts ...Option) (*Locked, error) {
+	defs, err := g.Defs(name)
+	if err != nil {
+		return nil, err
+	}
+	return NewLocked(New(defs, opts...)), nil
+}
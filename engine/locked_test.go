@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+)
+
+// Run with -race: the goroutines below share an engine, or a Defs.
+
+func TestLocked_ConcurrentSteps(t *testing.T) {
+	l := NewLocked(New(testDefs()))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				l.Step("wait")
+				l.Snapshot()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var turns int
+	l.Do(func(e *Engine) { turns = e.State.TurnCount })
+	if turns != 200 {
+		t.Errorf("turns = %d, want 200", turns)
+	}
+}
+
+func TestGames_SessionsShareDefs(t *testing.T) {
+	var loads atomic.Int32
+	games := NewGames(func(name string) (*state.Defs, error) {
+		loads.Add(1)
+		return testDefs(), nil
+	})
+
+	sessions := make([]*Locked, 8)
+	var wg sync.WaitGroup
+	for i := range sessions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := games.New("test")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			sessions[i] = l
+			for _, cmd := range []string{"take key", "go north", "go south", "drop key", "look"} {
+				l.Step(cmd)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("game loaded %d times, want once", n)
+	}
+	for i, l := range sessions {
+		l.Do(func(e *Engine) {
+			if e.State.TurnCount != 5 {
+				t.Errorf("session %d: turns = %d, want 5", i, e.State.TurnCount)
+			}
+			if e.Defs != sessions[0].e.Defs {
+				t.Errorf("session %d has its own Defs", i)
+			}
+		})
+	}
+}
+
+func TestGames_LoadErrorIsRetried(t *testing.T) {
+	fail := true
+	games := NewGames(func(name string) (*state.Defs, error) {
+		if fail {
+			return nil, errors.New("broken")
+		}
+		return testDefs(), nil
+	})
+	if _, err := games.New("test"); err == nil {
+		t.Fatal("expected the load error")
+	}
+	fail = false
+	if _, err := games.New("test"); err != nil {
+		t.Errorf("second try: %v", err)
+	}
+}
+
+func TestGames_LoadPanicReleasesWaiters(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int32
+	games := NewGames(func(name string) (*state.Defs, error) {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+			panic("lua error escaped")
+		}
+		return testDefs(), nil
+	})
+
+	panicked := make(chan any)
+	go func() {
+		defer func() { panicked <- recover() }()
+		games.Defs("test")
+	}()
+	<-started
+	games.mu.Lock()
+	gm := games.games["test"] // what a caller arriving now would wait on
+	games.mu.Unlock()
+	close(release)
+
+	if r := <-panicked; r == nil {
+		t.Fatal("expected the loader's panic")
+	}
+	select {
+	case <-gm.ready:
+		if gm.err == nil {
+			t.Error("callers waiting on a load that panicked should get an error")
+		}
+	default:
+		t.Fatal("callers waiting on a load that panicked would wait forever")
+	}
+	if _, err := games.Defs("test"); err != nil {
+		t.Errorf("after the panic: %v, want the game loaded afresh", err)
+	}
+}
@@ -0,0 +1,190 @@
+// Package markup is the lightweight markup of game output. Every output line
+// is one block; a prefix gives its kind and asterisks mark bold text:
+//
+//	# The Throne Room           header
+//	> Carved above the door...  quote
+//	@Elara: 'Welcome.'          dialogue, attributed to Elara
+//	! You can't go that way.    failure: the command did nothing
+//	~ Exits: north, south.      aside: supporting detail
+//	A *rusty key* lies here.    paragraph, with "rusty key" in bold
+//
+// A backslash before a prefix or an asterisk makes it literal. Front ends
+// either style the parsed blocks or flatten them with Plain.
+package markup
+
+import "strings"
+
+// Kind is what a block of output is.
+type Kind string
+
+// Block kinds.
+const (
+	Paragraph Kind = "paragraph"
+	Header    Kind = "header"
+	Quote     Kind = "quote"
+	Dialogue  Kind = "dialogue"
+	Failure   Kind = "failure"
+	Aside     Kind = "aside"
+)
+
+// prefixes maps the one-character block prefixes (followed by a space) to
+// their kinds. Dialogue's "@Speaker: " prefix is handled separately.
+var prefixes = map[byte]Kind{
+	'#': Header,
+	'>': Quote,
+	'!': Failure,
+	'~': Aside,
+}
+
+// Span is a run of text with a single style.
+type Span struct {
+	Text string `json:"text"`
+	Bold bool   `json:"bold,omitempty"`
+}
+
+// Block is one parsed line of output.
+type Block struct {
+	Kind    Kind   `json:"kind"`
+	Speaker string `json:"speaker,omitempty"` // who is talking, for Dialogue; may be empty
+	Spans   []Span `json:"spans"`
+}
+
+// Text returns the block's text without styling.
+func (b Block) Text() string {
+	var sb strings.Builder
+	for _, span := range b.Spans {
+		sb.WriteString(span.Text)
+	}
+	return sb.String()
+}
+
+// Parse parses one line of output.
+func Parse(line string) Block {
+	b := Block{Kind: Paragraph}
+	switch {
+	case strings.HasPrefix(line, `\`) && len(line) > 1 && isPrefix(line[1]):
+		line = line[1:]
+	case len(line) > 1 && line[1] == ' ' && prefixes[line[0]] != "":
+		b.Kind = prefixes[line[0]]
+		line = line[2:]
+	case strings.HasPrefix(line, "@"):
+		if speaker, text, ok := strings.Cut(line[1:], ": "); ok {
+			b.Kind = Dialogue
+			b.Speaker = speaker
+			line = text
+		}
+	}
+	b.Spans = parseSpans(line)
+	return b
+}
+
+// Plain renders a line as plain text: the markup is dropped, headers are
+// set off with asterisks, quotes are indented, and dialogue is shown
+// without its speaker, as written.
+func Plain(line string) string {
+	b := Parse(line)
+	switch b.Kind {
+	case Header:
+		return "*** " + b.Text() + " ***"
+	case Quote:
+		return "  " + b.Text()
+	}
+	return b.Text()
+}
+
+// Escape makes text literal, so that markup characters in it (from an entity
+// name, say) are shown as written.
+func Escape(text string) string {
+	text = strings.NewReplacer(`\`, `\\`, `*`, `\*`).Replace(text)
+	if text != "" && isPrefix(text[0]) {
+		text = `\` + text
+	}
+	return text
+}
+
+// Bold marks text as bold.
+func Bold(text string) string {
+	return "*" + Escape(text) + "*"
+}
+
+// Fail marks line as a failure, unless it already has a block prefix.
+func Fail(line string) string {
+	return withPrefix("! ", line)
+}
+
+// Note marks line as an aside, unless it already has a block prefix.
+func Note(line string) string {
+	return withPrefix("~ ", line)
+}
+
+// Said attributes line to speaker as dialogue, unless it already has a
+// block prefix.
+func Said(speaker, line string) string {
+	return withPrefix("@"+speaker+": ", line)
+}
+
+func withPrefix(prefix, line string) string {
+	switch {
+	case line == "":
+		return line
+	case line[0] == '\\' && len(line) > 1 && isPrefix(line[1]):
+		// Only the first prefix counts, so the escape is no longer needed.
+		return prefix + line[1:]
+	case Parse(line).Kind != Paragraph:
+		return line
+	}
+	return prefix + line
+}
+
+func isPrefix(c byte) bool {
+	return c == '@' || prefixes[c] != ""
+}
+
+// parseSpans splits text into plain and bold spans. An asterisk opens bold
+// text only if it is followed by a non-space and closed later by an asterisk
+// that follows a non-space; any other asterisk is literal.
+func parseSpans(text string) []Span {
+	var spans []Span
+	var cur strings.Builder
+	bold := false
+	flush := func() {
+		if cur.Len() > 0 {
+			spans = append(spans, Span{Text: cur.String(), Bold: bold})
+			cur.Reset()
+		}
+	}
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\\' && i+1 < len(text) && (text[i+1] == '*' || text[i+1] == '\\'):
+			i++
+			cur.WriteByte(text[i])
+		case c == '*' && !bold && opensBold(text, i):
+			flush()
+			bold = true
+		case c == '*' && bold && text[i-1] != ' ':
+			flush()
+			bold = false
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return spans
+}
+
+// opensBold reports whether the asterisk at text[i] starts bold text.
+func opensBold(text string, i int) bool {
+	if i+1 >= len(text) || text[i+1] == ' ' {
+		return false
+	}
+	for j := i + 2; j < len(text); j++ {
+		switch {
+		case text[j] == '\\':
+			j++
+		case text[j] == '*' && text[j-1] != ' ':
+			return true
+		}
+	}
+	return false
+}
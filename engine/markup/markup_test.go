@@ -0,0 +1,95 @@
+package markup
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse_Kinds(t *testing.T) {
+	tests := []struct {
+		line    string
+		kind    Kind
+		speaker string
+		text    string
+	}{
+		{"A grand hall.", Paragraph, "", "A grand hall."},
+		{"# The Throne Room", Header, "", "The Throne Room"},
+		{"> Carved above the door.", Quote, "", "Carved above the door."},
+		{"@Scholar Elara: 'Welcome.'", Dialogue, "Scholar Elara", "'Welcome.'"},
+		{"! You can't go that way.", Failure, "", "You can't go that way."},
+		{"~ Exits: north.", Aside, "", "Exits: north."},
+		{`\# not a header`, Paragraph, "", "# not a header"},
+		{"#hashtag", Paragraph, "", "#hashtag"},
+		{"@ the inn", Paragraph, "", "@ the inn"},
+		{"", Paragraph, "", ""},
+	}
+	for _, tt := range tests {
+		b := Parse(tt.line)
+		if b.Kind != tt.kind || b.Speaker != tt.speaker || b.Text() != tt.text {
+			t.Errorf("Parse(%q) = %s %q %q, want %s %q %q",
+				tt.line, b.Kind, b.Speaker, b.Text(), tt.kind, tt.speaker, tt.text)
+		}
+	}
+}
+
+func TestParse_Bold(t *testing.T) {
+	tests := []struct {
+		line  string
+		spans []Span
+	}{
+		{"A *rusty key* lies here.", []Span{{"A ", false}, {"rusty key", true}, {" lies here.", false}}},
+		{"*lamp*", []Span{{"lamp", true}}},
+		{"5 * 3 * 2", []Span{{"5 * 3 * 2", false}}},
+		{"a *dangling star", []Span{{"a *dangling star", false}}},
+		{`a \*literal\* star`, []Span{{"a *literal* star", false}}},
+		{`C:\games`, []Span{{`C:\games`, false}}},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.line).Spans; !reflect.DeepEqual(got, tt.spans) {
+			t.Errorf("Parse(%q).Spans = %v, want %v", tt.line, got, tt.spans)
+		}
+	}
+}
+
+func TestPlain(t *testing.T) {
+	tests := []struct{ line, want string }{
+		{"You see: *key*, *book*.", "You see: key, book."},
+		{"! You can't do that.", "You can't do that."},
+		{"> Here lies the king.", "  Here lies the king."},
+		{"# The End", "*** The End ***"},
+		{"@Elara: 'Hello.'", "'Hello.'"},
+	}
+	for _, tt := range tests {
+		if got := Plain(tt.line); got != tt.want {
+			t.Errorf("Plain(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestEscape_RoundTrips(t *testing.T) {
+	for _, text := range []string{"# of coins", "*star*", `back\slash`, "! bang", "@ home: now", "plain"} {
+		if got := Plain(Escape(text)); got != text {
+			t.Errorf("Plain(Escape(%q)) = %q", text, got)
+		}
+	}
+}
+
+func TestPrefixHelpers(t *testing.T) {
+	tests := []struct{ got, want string }{
+		{Fail("You can't."), "! You can't."},
+		{Fail("# Title"), "# Title"},
+		{Fail(`\# of coins`), "! # of coins"},
+		{Fail(""), ""},
+		{Note("Exits: north."), "~ Exits: north."},
+		{Said("Elara", "'Hi.'"), "@Elara: 'Hi.'"},
+		{Bold("key"), "*key*"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+	if b := Parse(Fail(`\# of coins`)); b.Kind != Failure || b.Text() != "# of coins" {
+		t.Errorf("escaped failure parsed as %s %q", b.Kind, b.Text())
+	}
+}
@@ -0,0 +1,254 @@
+// Package messages is the catalog of text the engine itself shows players —
+// "You can't go that way.", "Taken.", combat rolls — so a game can reword or
+// translate it with Messages {} in Lua instead of forking the engine.
+package messages
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Defaults is the built-in English catalog, by key. A placeholder such as
+// {item} is filled in by Text from the arguments given for it.
+var Defaults = map[string]string{
+	// Commands.
+	"empty_input":      "What do you want to do?",
+	"cant_do":          "You can't do that.",
+	"not_here":         "you don't see \"{name}\" here",
+	"which":            "which {name}? ({options})",
+	"restart_confirm":  "Are you sure you want to restart? (yes/no)",
+	"restart_declined": "Okay, carrying on.",
+	"choice_yes":       "Yes",
+	"choice_no":        "No",
+	"combat_only":      "You're in the middle of a fight! (attack, defend, use <item>, flee)",
+	"wait":             "Time passes.",
+	"mode_verbose":     "Maximum verbosity: full descriptions on every visit.",
+	"mode_brief":       "Brief descriptions: full descriptions on first visits only.",
+	"mode_superbrief":  "Superbrief descriptions: room descriptions are never shown when moving.",
+
+	// Rooms.
+	"go_where":            "Go where?",
+	"cant_go":             "You can't go that way.",
+	"sneak_where":         "Sneak where?",
+	"room_unknown":        "You are somewhere unknown.",
+	"room_contents":       "You see: {list}.",
+	"room_exits":          "Exits: {list}.",
+	"look_direction":      "Looking {direction}, you see the {room}.",
+	"look_direction_none": "You see no way {direction}.",
+	"needs_vehicle":       "You'd need the {vehicle} to go that way.",
+	"locked":              "The {item} is locked.",
+	"unlock_what":         "Unlock what?",
+	"lock_what":           "Lock what?",
+	"cant_lock":           "That has no lock.",
+	"already_unlocked":    "The {item} isn't locked.",
+	"already_locked":      "The {item} is already locked.",
+	"no_key":              "You have no key that fits the {item}.",
+	"wrong_key":           "The {key} doesn't fit the {item}.",
+	"unlock":              "You unlock the {item} with the {key}.",
+	"lock":                "You lock the {item} with the {key}.",
+	"lock_contents":       "Inside: {list}.",
+	"in_vehicle":          "You are in the {vehicle}.",
+	"enter_what":          "Get into what?",
+	"cant_enter":          "You can't get into that.",
+	"already_in":          "You're already in the {vehicle}.",
+	"enter":               "You get into the {vehicle}.",
+	"not_in_vehicle":      "You aren't in anything.",
+	"exit":                "You get out of the {vehicle}.",
+	"cutaway":             "Meanwhile, in the {room}...",
+
+	// Scenery mentioned in descriptions.
+	"scenery_examine": "You see nothing special about the {object}.",
+	"scenery_take":    "You can't take the {object}.",
+	"scenery_other":   "You can't do anything useful with the {object}.",
+
+	// Items.
+	"examine_nothing": "You see nothing special about it.",
+	"cant_take":       "You can't take that.",
+	"already_have":    "You already have that.",
+	"take":            "You take the {item}.",
+	"take_some":       "You take {count} {item}.",
+	"not_enough":      "There aren't that many {item} here.",
+	"only_one":        "There's only one {item}.",
+	"held_by":         "{npc} has that.",
+	"dont_have":       "You don't have that.",
+	"drop":            "You drop the {item}.",
+	"drop_some":       "You drop {count} {item}.",
+	"not_enough_held": "You don't have that many {item}.",
+	"cant_wear":       "You can't wear that.",
+	"already_wearing": "You're already wearing that.",
+	"wear":            "You put on the {item}.",
+	"not_wearing":     "You're not wearing that.",
+	"remove":          "You take off the {item}.",
+	"cant_consume":    "You can't {verb} that.",
+	"consume":         "You {verb} the {item}.",
+	"too_much":        "You're carrying too much.",
+	"tie_what":        "Tie what?",
+	"tie_to_what":     "Tie the {item} to what?",
+	"cant_tie":        "You can't tie that.",
+	"already_tied":    "The {item} is already tied to the {target}.",
+	"tie":             "You tie the {item} to the {target}.",
+	"not_tied":        "The {item} isn't tied to anything.",
+	"untie":           "You untie the {item} from the {target}.",
+	"tied_to":         "It is tied to the {target}.",
+	"tied_here":       "Tied to it: {list}.",
+	"tied_down":       "The {item} is tied to the {target}.",
+	"fill_what":       "Fill what?",
+	"fill_from_what":  "Fill the {item} from what?",
+	"cant_fill":       "You can't fill that.",
+	"cant_fill_from":  "You can't fill anything from the {source}.",
+	"no_liquid":       "There's no {liquid} here.",
+	"vessel_full":     "The {item} is already full.",
+	"other_liquid":    "There's already {liquid} in the {item}.",
+	"fill":            "You fill the {item} with {liquid}.",
+	"pour_what":       "Pour what?",
+	"cant_pour":       "You can't pour that.",
+	"vessel_empty":    "The {item} is empty.",
+	"pour":            "You pour the {liquid} on the {target}.",
+	"pour_out":        "You pour out the {liquid}.",
+	"drink":           "You take a drink of {liquid}.",
+	"vessel_holds":    "It holds {liquid} ({units}/{capacity}).",
+
+	// Inventory and stats.
+	"inventory_empty":        "You are carrying nothing.",
+	"inventory":              "You are carrying: {list}.",
+	"inventory_category":     "{category}: {list}.",
+	"inventory_other":        "Other: {list}.",
+	"inventory_vessel":       "{item} of {liquid} ({units}/{capacity})",
+	"inventory_empty_vessel": "{item} (empty)",
+	"gold":                   "Gold: {amount}",
+	"weight":                 "Weight: {carried}/{limit}",
+	"size":                   "Size: {carried}/{limit}",
+	"level":                  "Level {level} ({xp}/{next} XP)",
+	"level_max":              "Level {level} ({xp} XP)",
+	"hp":                     "HP: {hp}/{max}",
+	"hp_only":                "HP: {hp}",
+	"no_stats":               "You have no stats to speak of.",
+	"xp_gained":              "You gain {amount} experience.",
+	"level_up":               "You have reached level {level}!",
+
+	// Dialogue.
+	"talk_whom":            "Talk to whom?",
+	"cant_talk":            "You can't talk to that.",
+	"nothing_to_say":       "{npc} has nothing to say right now.",
+	"unknown_topic":        "{npc} has nothing to say about that. You could ask about: {topics}.",
+	"give_what":            "Give what?",
+	"give_whom":            "Give it to whom?",
+	"cant_give":            "You can't give things to that.",
+	"give_declined":        "{npc} doesn't want the {item}.",
+	"give":                 "You give the {item} to {npc}.",
+	"show_what":            "Show what?",
+	"show_whom":            "Show it to whom?",
+	"cant_show":            "You can't show things to that.",
+	"show":                 "{npc} glances at the {item}.",
+	"carrying":             "{npc} is carrying: {list}.",
+	"steal_what":           "Steal what?",
+	"cant_steal":           "There's no one here to steal that from.",
+	"not_carrying":         "{npc} doesn't have that.",
+	"steal":                "You lift the {item} from {npc} unnoticed.",
+	"steal_caught":         "{npc} catches you reaching for the {item}!",
+	"npc_suspicious":       "{npc} looks up, suspicious.",
+	"npc_hostile":          "{npc} has heard enough, and turns on you!",
+	"topics_whom":          "Whose topics? Try \"topics <someone>\".",
+	"topics":               "You could ask {npc} about: {topics}.",
+	"wrong_answer":         "That is not the answer.",
+	"idle_nudge":           "Stuck? Try looking around, or examine the things you see.",
+	"achievements":         "Achievements: {unlocked} of {total} unlocked.",
+	"achievements_none":    "This game has no achievements.",
+	"hidden_one":           "Plus 1 hidden achievement.",
+	"hidden_many":          "Plus {count} hidden achievements.",
+	"achievement_unlocked": "Achievement unlocked: {name}",
+	"notes":                "You have learned:",
+	"notes_none":           "You haven't learned anything worth noting yet.",
+	"fact_learned":         "Noted: {text}",
+
+	// Combat.
+	"initiative_player":  "You're quicker than the {enemy}. Initiative: [{player}] vs [{opponent}]",
+	"initiative_enemy":   "The {enemy} is quicker than you! Initiative: [{player}] vs [{opponent}]",
+	"combat_surprise":    "You catch the {enemy} by surprise!",
+	"enemy_surprised":    "The {enemy} is still reeling from the surprise.",
+	"player_attacks":     "You strike the {enemy}!",
+	"enemy_attacks":      "The {enemy} attacks you!",
+	"enemy_attacks_with": "The {enemy} attacks you with its {attack}!",
+	"attack_roll":        "  Roll: {dice}+{attack} → [{roll}]+{attack} = {total} vs defense {defense} → {damage} damage",
+	"status_inflicted":   "You are afflicted: {status}.",
+	"combat_use":         "You use the {item} on the {enemy}!",
+	"combat_use_self":    "You use the {item}.",
+	"combat_throw":       "You throw the {item} at the {enemy}!",
+	"combat_use_roll":    "  Roll: {dice} → [{roll}] vs defense {defense} → {damage} damage",
+	"combat_heal":        "You recover {amount} HP.",
+	"combat_use_useless": "The {item} won't help you here.",
+	"combat_use_target":  "You're fighting the {enemy}, not that.",
+	"player_defends":     "You brace yourself. (+2 defense this round)",
+	"enemy_defends":      "The {enemy} braces for your attack.",
+	"flee_success":       "You turn and run! Roll: 1d6 → [{roll}] — you escape!",
+	"flee_fail":          "You try to run but can't escape! Roll: 1d6 → [{roll}]",
+	"enemy_flees":        "The {enemy} turns and flees! Roll: 1d6 → [{roll}]",
+	"enemy_flee_fail":    "The {enemy} tries to flee but fails! Roll: 1d6 → [{roll}]",
+	"enemy_surrenders":   "The {enemy} throws down its weapon and begs for mercy!",
+	"enemy_breaks":       "The {enemy} loses its nerve and flees!",
+	"loot_item":          "You found: {item}!",
+	"loot_gold":          "You found {amount} gold.",
+
+	// Death and endings.
+	"died_restart":        "You have died. The story begins again.",
+	"died_respawn":        "You have died... but your story is not over.",
+	"ending_menu":         "Would you like to RESTART or RESTORE a saved game?",
+	"ending_menu_amusing": "Would you like to RESTART, RESTORE a saved game, or see some AMUSING things?",
+	"ending_restore":      "Use /load <name> to restore a saved game.",
+	"choice_restart":      "Restart",
+	"choice_restore":      "Restore a saved game",
+	"choice_amusing":      "See some amusing things",
+	"amusing":             "Have you ever:",
+	"amusing_none":        "You seem to have found everything already.",
+	"score_out_of":        "You scored {score} out of a possible {max}, in {turns} turns.",
+	"score":               "You scored {score} points, in {turns} turns.",
+	"score_turns":         "You finished in {turns} turns.",
+
+	// Accessible mode, which puts in words what is otherwise shown.
+	"list_and":     "{list} and {last}",
+	"exits_are":    "Exits are {list}.",
+	"exit_is":      "The only exit is {dir}.",
+	"speaker_says": "{speaker} says: {text}",
+	"hp_lost":      "You lose {n} health, leaving {hp} of {max}.",
+	"hp_gained":    "You gain {n} health, for {hp} of {max}.",
+	"score_up":     "Your score rises by {n} to {score}.",
+	"score_down":   "Your score falls by {n} to {score}.",
+}
+
+// Text returns the message for key: the game's override if overrides has
+// one, otherwise the default, or the key itself if there is neither. args are placeholder names and values in
+// pairs, e.g. Text(o, "take", "item", "lamp"); each {name} in the message is
+// replaced by its value.
+func Text(overrides map[string]string, key string, args ...any) string {
+	text, ok := overrides[key]
+	if !ok {
+		if text, ok = Defaults[key]; !ok {
+			return key
+		}
+	}
+	if len(args) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		pairs = append(pairs, "{"+fmt.Sprint(args[i])+"}", fmt.Sprint(args[i+1]))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Placeholders returns the names of the {placeholders} in text, in order.
+func Placeholders(text string) []string {
+	var names []string
+	for {
+		start := strings.IndexByte(text, '{')
+		if start < 0 {
+			return names
+		}
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			return names
+		}
+		names = append(names, text[start+1:start+end])
+		text = text[start+end+1:]
+	}
+}
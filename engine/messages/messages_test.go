@@ -0,0 +1,58 @@
+package messages
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestText_Default(t *testing.T) {
+	if got := Text(nil, "take", "item", "lamp"); got != "You take the lamp." {
+		t.Errorf("Text(take) = %q", got)
+	}
+	if got := Text(nil, "cant_go"); got != "You can't go that way." {
+		t.Errorf("Text(cant_go) = %q", got)
+	}
+}
+
+func TestText_FillsPlaceholders(t *testing.T) {
+	got := Text(nil, "score_out_of", "score", 7, "max", 10, "turns", 2)
+	if got != "You scored 7 out of a possible 10, in 2 turns." {
+		t.Errorf("Text = %q", got)
+	}
+}
+
+func TestText_Override(t *testing.T) {
+	overrides := map[string]string{"consume": "Tu {verb} le {item}."}
+	if got := Text(overrides, "consume", "verb", "manges", "item", "pain"); got != "Tu manges le pain." {
+		t.Errorf("Text = %q", got)
+	}
+	if got := Text(overrides, "cant_go"); got != Defaults["cant_go"] {
+		t.Errorf("keys without an override should use the default, got %q", got)
+	}
+}
+
+func TestText_UnknownKey(t *testing.T) {
+	if got := Text(nil, "no_such_key"); got != "no_such_key" {
+		t.Errorf("Text = %q, want the key itself", got)
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	got := Placeholders("{npc} has nothing to say about {topic}. {unclosed")
+	if want := []string{"npc", "topic"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Placeholders = %v, want %v", got, want)
+	}
+	if got := Placeholders("Taken."); got != nil {
+		t.Errorf("Placeholders = %v, want none", got)
+	}
+}
+
+func TestDefaults_PlaceholdersClosed(t *testing.T) {
+	for key, text := range Defaults {
+		for _, name := range Placeholders(text) {
+			if name == "" {
+				t.Errorf("%s has an empty placeholder", key)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/types"
+)
+
+// MetaCommand runs input if it is one of the game's own meta-commands,
+// declared with MetaCommand() in Lua, and reports whether it was. One with
+// effects runs them outside the turn: the turn count, clock and command log
+// are left alone. One mapped to a verb runs nothing; command is the game
+// command to run in its place, the verb followed by the meta-command's
+// arguments, for the front end to run as if typed.
+func (e *Engine) MetaCommand(input string) (result types.Result, command string, ok bool) {
+	name, args, _ := strings.Cut(strings.TrimSpace(input), " ")
+	def, ok := e.Defs.MetaCommands[name]
+	if !ok {
+		return types.Result{}, "", false
+	}
+	if def.Verb != "" {
+		return types.Result{}, strings.TrimSpace(def.Verb + " " + strings.TrimSpace(args)), true
+	}
+
+	ctx := effects.Context{Verb: name, Actor: "player", Strict: e.Strict, Custom: e.config.Effects, Roll: e.RNG.RollFor}
+	evts, output := effects.Apply(e.State, e.Defs, def.Effects, ctx)
+	result.Effects = append(result.Effects, def.Effects...)
+	result.Events = append(result.Events, evts...)
+	result.Output = append(result.Output, output...)
+	e.dispatch(evts, ctx, &result)
+	return result, "", true
+}
+
+// MetaCommandHelp returns a /help line for each of the game's meta-commands
+// that has help text, in name order.
+func (e *Engine) MetaCommandHelp() []string {
+	var lines []string
+	for _, name := range slices.Sorted(maps.Keys(e.Defs.MetaCommands)) {
+		if help := e.Defs.MetaCommands[name].Help; help != "" {
+			lines = append(lines, "  "+name+" — "+help)
+		}
+	}
+	return lines
+}
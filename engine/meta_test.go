@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// metaEngine returns an engine with a /stars meta-command that runs
+// effects and a /peek one that stands for "look".
+func metaEngine() *Engine {
+	defs := testDefs()
+	defs.MetaCommands = map[string]types.MetaCommandDef{
+		"/stars": {Name: "/stars", Help: "Count the stars", Effects: []types.Effect{
+			{Type: "say", Params: map[string]any{"text": "You count the stars."}},
+			{Type: "inc_counter", Params: map[string]any{"counter": "stars", "amount": 1}},
+		}},
+		"/peek": {Name: "/peek", Verb: "look"},
+	}
+	return New(defs)
+}
+
+func TestMetaCommand_RunsEffectsOutsideTheTurn(t *testing.T) {
+	e := metaEngine()
+	result, command, ok := e.MetaCommand("/stars")
+	if !ok || command != "" {
+		t.Fatalf("MetaCommand = %q, %v; want effects run", command, ok)
+	}
+	if !outputContains(result.Output, "You count the stars.") {
+		t.Errorf("output = %v", result.Output)
+	}
+	if e.State.Counters["stars"] != 1 {
+		t.Errorf("stars = %d, want 1", e.State.Counters["stars"])
+	}
+	if e.State.TurnCount != 0 || len(e.State.CommandLog) != 0 {
+		t.Errorf("turn %d, log %v: the meta-command took a turn", e.State.TurnCount, e.State.CommandLog)
+	}
+}
+
+func TestMetaCommand_VerbStandsForGameCommand(t *testing.T) {
+	e := metaEngine()
+	_, command, ok := e.MetaCommand("/peek  north ")
+	if !ok || command != "look north" {
+		t.Errorf("MetaCommand = %q, %v; want %q", command, ok, "look north")
+	}
+	if _, command, _ := e.MetaCommand("/peek"); command != "look" {
+		t.Errorf("without arguments command = %q, want look", command)
+	}
+}
+
+func TestMetaCommand_Unknown(t *testing.T) {
+	e := metaEngine()
+	if _, _, ok := e.MetaCommand("/save"); ok {
+		t.Error("expected /save not to be a game meta-command")
+	}
+}
+
+func TestMetaCommandHelp(t *testing.T) {
+	got := metaEngine().MetaCommandHelp()
+	if len(got) != 1 || got[0] != "  /stars — Count the stars" {
+		t.Errorf("MetaCommandHelp = %q", got)
+	}
+}
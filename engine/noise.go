@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// verbNoise is how much noise a verb makes, unless Game.noise says
+// otherwise. Verbs not listed are silent.
+var verbNoise = map[string]int{
+	"go": 2, "take": 1, "drop": 2, "open": 1, "close": 2,
+	"push": 3, "pull": 3, "throw": 4, "attack": 6, "knock": 4,
+	"climb": 2, "jump": 3, "sing": 5, "yell": 8, "pour": 1,
+}
+
+// noiseFalloff is how much quieter a noise is in each room further from
+// where it was made.
+const noiseFalloff = 2
+
+// alertLevel is what a noise heard by an NPC, plus its alertness, must
+// exceed to alert it.
+const alertLevel = 10
+
+// noiseOf returns how much noise verb makes.
+func (e *Engine) noiseOf(verb string) int {
+	if n, ok := e.Defs.Game.Noise[verb]; ok {
+		return n
+	}
+	return verbNoise[verb]
+}
+
+// makeNoise carries the noise of the player's verb from the room they end
+// the turn in to the rooms around it, quieter in each, and alerts the NPCs
+// with alertness who hear enough of it. Nothing is heard in combat.
+func (e *Engine) makeNoise(verb string, ctx effects.Context, result *types.Result) {
+	noise := e.noiseOf(verb)
+	if noise <= 0 || state.InCombat(e.State) {
+		return
+	}
+	room := state.PlayerLocation(e.State)
+	heard := map[string]int{room: noise}
+	queue := []string{room}
+	for len(queue) > 0 {
+		at := queue[0]
+		queue = queue[1:]
+		next := heard[at] - noiseFalloff
+		if next <= 0 {
+			continue
+		}
+		for _, to := range state.RoomExits(e.State, e.Defs, at) {
+			if _, ok := heard[to]; !ok {
+				heard[to] = next
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	var effs []types.Effect
+	var evts []types.Event
+	for _, r := range slices.Sorted(maps.Keys(heard)) {
+		for _, npc := range state.EntitiesInRoom(e.State, e.Defs, r) {
+			alertness, ok := state.GetStat(e.State, e.Defs, npc, "alertness")
+			if !ok || !state.IsOccupant(e.State, e.Defs, npc) || heard[r]+alertness <= alertLevel {
+				continue
+			}
+			alert, _ := state.GetEntityProp(e.State, e.Defs, npc, "alert")
+			if alert == "hostile" {
+				continue
+			}
+			next := "suspicious"
+			if alert == "suspicious" {
+				next = "hostile"
+			}
+			effs = append(effs, types.Effect{Type: "set_prop", Params: map[string]any{"entity": npc, "prop": "alert", "value": next}})
+			evts = append(evts, types.Event{
+				Type: "npc_alerted",
+				Data: map[string]any{"npc": npc, "alert": next, "noise": heard[r], "room": r},
+			})
+			if r == room {
+				result.Output = append(result.Output, e.msg("npc_"+next, "npc", e.entityName(npc)))
+			}
+		}
+	}
+	if len(evts) == 0 {
+		return
+	}
+	effects.Apply(e.State, e.Defs, effs, ctx)
+	result.Effects = append(result.Effects, effs...)
+	result.Events = append(result.Events, evts...)
+	e.dispatch(evts, ctx, result)
+}
@@ -0,0 +1,22 @@
+package engine
+
+import (
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/engine/state"
+)
+
+// IdleNudge returns the hint to show a player who has been idle, or "" if
+// the game has idle nudges turned off or has ended. It does not change
+// state or consume a turn.
+func (e *Engine) IdleNudge() string {
+	idle := e.Defs.Game.IdleNudge
+	if idle == nil || state.GetFlag(e.State, "game_over") {
+		return ""
+	}
+	for _, n := range idle.Nudges {
+		if rules.EvalAllConditions(n.Conditions, e.State, e.Defs) {
+			return n.Text
+		}
+	}
+	return e.msg("idle_nudge") // no configured nudge applies
+}
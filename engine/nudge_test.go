@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/engine/messages"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func nudgeEngine() *Engine {
+	defs := testDefs()
+	defs.Game.IdleNudge = &types.IdleNudgeDef{
+		Minutes: 3,
+		Nudges: []types.NudgeDef{
+			{Text: "The garden lies to the north.", Conditions: []types.Condition{
+				{Type: "in_room", Params: map[string]any{"room": "hall"}},
+			}},
+		},
+	}
+	return New(defs)
+}
+
+func TestIdleNudge_FirstMatchingNudge(t *testing.T) {
+	e := nudgeEngine()
+	if got := e.IdleNudge(); got != "The garden lies to the north." {
+		t.Errorf("IdleNudge() = %q, want the hall nudge", got)
+	}
+}
+
+func TestIdleNudge_DefaultWhenNoneMatch(t *testing.T) {
+	e := nudgeEngine()
+	e.Step("go north")
+	if got := e.IdleNudge(); got != messages.Defaults["idle_nudge"] {
+		t.Errorf("IdleNudge() = %q, want default %q", got, messages.Defaults["idle_nudge"])
+	}
+}
+
+func TestIdleNudge_DoesNotConsumeTurn(t *testing.T) {
+	e := nudgeEngine()
+	turn := e.State.TurnCount
+	e.IdleNudge()
+	if e.State.TurnCount != turn {
+		t.Errorf("TurnCount = %d, want %d", e.State.TurnCount, turn)
+	}
+}
+
+func TestIdleNudge_Disabled(t *testing.T) {
+	e := New(testDefs())
+	if got := e.IdleNudge(); got != "" {
+		t.Errorf("IdleNudge() = %q, want empty without idle_nudge", got)
+	}
+}
+
+func TestIdleNudge_GameOver(t *testing.T) {
+	e := nudgeEngine()
+	e.State.Flags["game_over"] = true
+	if got := e.IdleNudge(); got != "" {
+		t.Errorf("IdleNudge() = %q, want empty after game over", got)
+	}
+}
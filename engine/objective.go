@@ -0,0 +1,15 @@
+package engine
+
+import "github.com/nathoo/questcore/engine/rules"
+
+// Objective returns the player's current goal: the text of the first of the
+// game's objectives whose conditions hold, or "" if none does. It does not
+// change state.
+func (e *Engine) Objective() string {
+	for _, o := range e.Defs.Game.Objectives {
+		if rules.EvalAllConditions(o.Conditions, e.State, e.Defs) {
+			return o.Text
+		}
+	}
+	return ""
+}
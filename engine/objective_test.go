@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func TestObjective_FirstThatHolds(t *testing.T) {
+	defs := testDefs()
+	defs.Game.Objectives = []types.ObjectiveDef{
+		{Text: "Read the book.", Conditions: []types.Condition{
+			{Type: "has_item", Params: map[string]any{"item": "book"}},
+			{Type: "flag_not", Params: map[string]any{"flag": "read_book"}},
+		}},
+		{Text: "Find the book.", Conditions: []types.Condition{
+			{Type: "flag_not", Params: map[string]any{"flag": "read_book"}},
+		}},
+	}
+	e := New(defs)
+
+	if got := e.Objective(); got != "Find the book." {
+		t.Errorf("Objective() = %q, want Find the book.", got)
+	}
+	e.Step("take book")
+	if got := e.Objective(); got != "Read the book." {
+		t.Errorf("after taking the book, Objective() = %q", got)
+	}
+	e.State.Flags["read_book"] = true
+	if got := e.Objective(); got != "" {
+		t.Errorf("with every objective done, Objective() = %q", got)
+	}
+}
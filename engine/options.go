@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/types"
+)
+
+// Config is an engine's configuration, set by the options passed to New.
+// The zero value of each field leaves the engine as a game would expect.
+type Config struct {
+	// Seed starts the RNG, so that a session can be replayed with the same
+	// dice.
+	Seed int64
+
+	// MaxEventDepth is how many rounds of event dispatch a step makes.
+	// Handlers' effects can raise events of their own; at the default of 1
+	// these are reported but not dispatched again.
+	MaxEventDepth int
+
+	// DisabledBuiltins are verbs whose built-in behavior is turned off, so
+	// that they do only what the game's rules make them do.
+	DisabledBuiltins map[string]bool
+
+	// Clock replaces the game's in-game clock (nil = the game's own).
+	Clock *types.ClockDef
+
+	// Effects and Conditions are the effect and condition types the
+	// program adds for its games (nil = none). The loader must be given
+	// the same ones to accept games that use them.
+	Effects    effects.Registry
+	Conditions rules.Registry
+}
+
+// Option configures an engine; see New.
+type Option func(*Config)
+
+// WithConfig sets the whole configuration at once. Options after it
+// change it further.
+func WithConfig(cfg Config) Option {
+	return func(c *Config) {
+		*c = cfg
+	}
+}
+
+// WithSeed starts the RNG from seed.
+func WithSeed(seed int64) Option {
+	return func(c *Config) {
+		c.Seed = seed
+	}
+}
+
+// WithMaxEventDepth lets events raised by event handlers be dispatched in
+// turn, up to depth rounds per step.
+func WithMaxEventDepth(depth int) Option {
+	return func(c *Config) {
+		c.MaxEventDepth = depth
+	}
+}
+
+// WithBuiltinsDisabled turns off the built-in behavior of verbs, such as
+// "take" or "go".
+func WithBuiltinsDisabled(verbs ...string) Option {
+	return func(c *Config) {
+		disabled := map[string]bool{} // a copy, not to change a map from WithConfig
+		for verb := range c.DisabledBuiltins {
+			disabled[verb] = true
+		}
+		for _, verb := range verbs {
+			disabled[verb] = true
+		}
+		c.DisabledBuiltins = disabled
+	}
+}
+
+// WithClock runs the game with clock as its in-game clock, in place of the
+// one the game defines (if any), to start it at another time of day, say.
+func WithClock(clock *types.ClockDef) Option {
+	return func(c *Config) {
+		c.Clock = clock
+	}
+}
+
+// WithEffects lets games use the effect types in r.
+func WithEffects(r effects.Registry) Option {
+	return func(c *Config) {
+		c.Effects = r
+	}
+}
+
+// WithConditions lets games use the condition types in r.
+func WithConditions(r rules.Registry) Option {
+	return func(c *Config) {
+		c.Conditions = r
+	}
+}
@@ -0,0 +1,108 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+func TestNew_WithSeed(t *testing.T) {
+	eng := New(testDefs(), WithSeed(42))
+	if eng.State.RNGSeed != 42 || eng.Config().Seed != 42 {
+		t.Fatalf("seed = %d, config seed %d, want 42", eng.State.RNGSeed, eng.Config().Seed)
+	}
+	want := NewRNG(42).Roll(1000)
+	if got := eng.RNG.Roll(1000); got != want {
+		t.Errorf("first roll = %d, want %d as from seed 42", got, want)
+	}
+}
+
+func TestNew_WithMaxEventDepth(t *testing.T) {
+	defs := testDefs()
+	// Taking the first item sets first_item_msg, whose flag_changed event
+	// has a handler of its own.
+	defs.Handlers = append(defs.Handlers, types.EventHandler{
+		EventType: "flag_changed",
+		Effects:   []types.Effect{{Type: "say", Params: map[string]any{"text": "A chime sounds."}}},
+	})
+
+	result := New(defs).Step("take book")
+	if !outputContains(result.Output, "Your first treasure!") || outputContains(result.Output, "A chime sounds.") {
+		t.Errorf("default depth should dispatch one round of events: %v", result.Output)
+	}
+	result = New(defs, WithMaxEventDepth(2)).Step("take book")
+	if !outputContains(result.Output, "A chime sounds.") {
+		t.Errorf("depth 2 should dispatch events raised by handlers: %v", result.Output)
+	}
+}
+
+func TestNew_WithBuiltinsDisabled(t *testing.T) {
+	eng := New(testDefs(), WithBuiltinsDisabled("take"))
+	result := eng.Step("take book")
+	if !result.Failed || state.HasItem(eng.State, "book") {
+		t.Errorf("take should have no built-in behavior: %v", result.Output)
+	}
+	// Rules still apply.
+	eng.Step("take key")
+	if !state.HasItem(eng.State, "key") {
+		t.Error("the rule for taking the key should still run")
+	}
+
+	cfg := Config{DisabledBuiltins: map[string]bool{"go": true}}
+	eng = New(testDefs(), WithConfig(cfg), WithBuiltinsDisabled("drop"))
+	if got := eng.Config().DisabledBuiltins; !got["go"] || !got["drop"] || cfg.DisabledBuiltins["drop"] {
+		t.Errorf("disabled = %v, and WithConfig's map changed: %v", got, cfg.DisabledBuiltins)
+	}
+}
+
+func TestNew_WithClock(t *testing.T) {
+	defs := testDefs()
+	eng := New(defs, WithClock(&types.ClockDef{Start: 9 * 60, MinutesPerTurn: 10}))
+	if eng.State.Clock != 9*60 {
+		t.Fatalf("clock = %d, want 9:00", eng.State.Clock)
+	}
+	eng.Step("wait")
+	if eng.State.Clock != 9*60+10 {
+		t.Errorf("clock after a turn = %d, want 9:10", eng.State.Clock)
+	}
+	if defs.Game.Clock != nil {
+		t.Error("WithClock changed the game's definitions")
+	}
+}
+
+func TestNew_WithEffectsAndConditions(t *testing.T) {
+	effs := effects.NewRegistry()
+	if err := effs.Add("test_glow", func(s *types.State, defs *state.Defs, eff types.Effect, ctx effects.Context) ([]types.Event, []string) {
+		return nil, []string{"The statue glows."}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	conds := rules.NewRegistry()
+	if err := conds.Add("test_holding_book", func(c types.Condition, s *types.State, defs *state.Defs) bool {
+		return state.HasItem(s, "book")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "sing_glow", Scope: "global",
+		When:       types.MatchCriteria{Verb: "sing"},
+		Conditions: []types.Condition{{Type: "test_holding_book"}},
+		Effects:    []types.Effect{{Type: "test_glow"}},
+	})
+
+	eng := New(defs, WithEffects(effs), WithConditions(conds))
+	if result := eng.Step("sing"); outputContains(result.Output, "The statue glows.") {
+		t.Errorf("the custom condition should not hold without the book: %v", result.Output)
+	}
+	eng.Step("take book")
+	if result := eng.Step("sing"); !outputContains(result.Output, "The statue glows.") {
+		t.Errorf("the custom effect should apply: %v", result.Output)
+	}
+	if _, ok := defs.Condition("test_holding_book"); ok {
+		t.Error("WithConditions changed the game's definitions")
+	}
+}
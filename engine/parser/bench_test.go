@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"strings"
+	"testing"
+)
+
+var benchInputs = []string{
+	"look",
+	"n",
+	"take the rusty key",
+	"take 3 gold coins",
+	"put the small brass key in the wooden box",
+	"ask the old captain about the lost crown",
+	"pick up lamp",
+}
+
+func BenchmarkParse(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Parse(benchInputs[i%len(benchInputs)])
+	}
+}
+
+func BenchmarkSplitCommands(b *testing.B) {
+	input := "take key, go north and then open the door. look"
+	for i := 0; i < b.N; i++ {
+		SplitCommands(input, IsVerb)
+	}
+}
+
+// FuzzParse checks that Parse doesn't panic on any input, and that what it
+// returns is lowercase, trimmed and empty only for blank input.
+func FuzzParse(f *testing.F) {
+	for _, in := range benchInputs {
+		f.Add(in)
+	}
+	f.Add("")
+	f.Add("  \t ")
+	f.Add("talk to guard about topics")
+	f.Add("pick")
+	f.Add("take 0 coins")
+	f.Add("put in")
+	f.Fuzz(func(t *testing.T, input string) {
+		intent := Parse(input)
+		if strings.TrimSpace(input) == "" {
+			if intent.Verb != "" {
+				t.Errorf("Parse(%q) = %+v, want no verb", input, intent)
+			}
+			return
+		}
+		if intent.Verb == "" {
+			t.Errorf("Parse(%q) has no verb", input)
+		}
+		for _, s := range []string{intent.Verb, intent.Object, intent.Target} {
+			if s != strings.TrimSpace(s) {
+				t.Errorf("Parse(%q) = %+v, untrimmed %q", input, intent, s)
+			}
+		}
+		if intent.Count < 0 {
+			t.Errorf("Parse(%q) count = %d", input, intent.Count)
+		}
+	})
+}
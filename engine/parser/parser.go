@@ -3,6 +3,9 @@
 package parser
 
 import (
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/nathoo/questcore/types"
@@ -47,8 +50,17 @@ var verbAliases = map[string]string{
 	"move":    "go",
 	"head":    "go",
 	"proceed": "go",
-	"enter":   "go",
 	"travel":  "go",
+	"tiptoe":  "sneak",
+	"creep":   "sneak",
+
+	// Enter / Exit (vehicles and mounts)
+	"board":     "enter",
+	"embark":    "enter",
+	"mount":     "enter",
+	"disembark": "exit",
+	"dismount":  "exit",
+	"leave":     "exit",
 
 	// Take / Get
 	"get":   "take",
@@ -104,6 +116,11 @@ var verbAliases = map[string]string{
 	"hand":  "give",
 	"feed":  "give",
 
+	// Steal
+	"steal":  "steal",
+	"pilfer": "steal",
+	"filch":  "steal",
+
 	// Throw
 	"toss": "throw",
 	"hurl": "throw",
@@ -118,10 +135,20 @@ var verbAliases = map[string]string{
 	"swallow": "drink",
 	"quaff":   "drink",
 
+	// Fill / Pour
+	"fill":   "fill",
+	"refill": "fill",
+	"pour":   "pour",
+	"spill":  "pour",
+	"empty":  "pour",
+
 	// Miscellaneous
 	"inv":      "inventory",
 	"i":        "inventory",
 	"z":        "wait",
+	"notes":    "notes",
+	"recall":   "notes",
+	"facts":    "notes",
 	"smell":    "smell",
 	"sniff":    "smell",
 	"listen":   "listen",
@@ -135,6 +162,7 @@ var verbAliases = map[string]string{
 	"leap":     "jump",
 	"hop":      "jump",
 	"unlock":   "unlock",
+	"lock":     "lock",
 	"tie":      "tie",
 	"fasten":   "tie",
 	"attach":   "tie",
@@ -160,9 +188,111 @@ var verbAliases = map[string]string{
 	"purchase": "buy",
 }
 
+// baseVerbs are verbs with built-in meaning that have no aliases, so they
+// appear nowhere in verbAliases.
+var baseVerbs = []string{
+	"open", "use", "read", "remove", "stats", "achievements", "topics", "show", "enter", "exit", "sneak",
+	"restart", "verbose", "brief", "superbrief",
+}
+
+// Verbs returns, sorted, the single words the parser knows as commands: the
+// verbs and their aliases (except one-letter abbreviations) and the
+// direction names. Games may use other verbs in their rules.
+func Verbs() []string {
+	seen := map[string]bool{}
+	for alias, verb := range verbAliases {
+		if len(alias) > 1 {
+			seen[alias] = true
+		}
+		seen[verb] = true
+	}
+	for _, verb := range baseVerbs {
+		seen[verb] = true
+	}
+	for dir := range directionNames {
+		seen[dir] = true
+	}
+	verbs := make([]string, 0, len(seen))
+	for verb := range seen {
+		verbs = append(verbs, verb)
+	}
+	sort.Strings(verbs)
+	return verbs
+}
+
+// IsVerb reports whether Parse can produce verb: it is a verb or alias the
+// parser knows, or one of its multi-word phrases, such as "turn on", means
+// it. Any other verb comes from what the player typed as is.
+func IsVerb(verb string) bool {
+	if _, ok := verbAliases[verb]; ok || slices.Contains(baseVerbs, verb) {
+		return true
+	}
+	for _, v := range verbAliases {
+		if v == verb {
+			return true
+		}
+	}
+	return verb == "activate" || verb == "deactivate"
+}
+
+// phraseStarts are the first words of multi-word verb phrases, such as
+// "pick up", that are not verbs themselves.
+var phraseStarts = map[string]bool{"pick": true, "put": true, "turn": true, "switch": true, "get": true}
+
+// SplitCommands splits input into the commands chained in it with "then",
+// "and" or commas: "take key and open door, then go north" is three
+// commands. "and" and commas separate commands only before a word that
+// starts one, a verb as isVerb says or a direction, so "take bread and
+// butter" stays one. isVerb nil means IsVerb.
+func SplitCommands(input string, isVerb func(string) bool) []string {
+	if isVerb == nil {
+		isVerb = IsVerb
+	}
+	startsCommand := func(word string) bool {
+		return isVerb(word) || phraseStarts[word] || directionNames[word] || directionExpansions[word] != ""
+	}
+
+	words := strings.Fields(strings.ReplaceAll(input, ",", " , "))
+	var commands []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			commands = append(commands, strings.Join(current, " "))
+			current = nil
+		}
+	}
+	for i := 0; i < len(words); i++ {
+		w := strings.ToLower(words[i])
+		if w != "then" && w != "and" && w != "," {
+			current = append(current, words[i])
+			continue
+		}
+		// A run of separators, such as ", and then", is one.
+		j := i
+		then := false
+		for j < len(words) && slices.Contains([]string{"then", "and", ","}, strings.ToLower(words[j])) {
+			then = then || strings.ToLower(words[j]) == "then"
+			j++
+		}
+		if then || j < len(words) && startsCommand(strings.ToLower(words[j])) {
+			flush()
+			i = j - 1
+			continue
+		}
+		if w == "," && len(current) > 0 {
+			current[len(current)-1] += ","
+		} else {
+			current = append(current, words[i])
+		}
+	}
+	flush()
+	return commands
+}
+
 var prepositions = map[string]bool{
 	"on": true, "at": true, "to": true,
 	"with": true, "in": true, "from": true,
+	"into": true, "onto": true, "over": true,
 	"about": true,
 }
 
@@ -203,14 +333,79 @@ func Parse(input string) types.Intent {
 	// Strip articles ("the", "a", "an").
 	rest = stripArticles(rest)
 
+	// A number before the object is how many: "take 3 coins".
+	count := 0
+	if len(rest) > 1 {
+		if n, ok := quantity(rest[0]); ok {
+			count, rest = n, rest[1:]
+		}
+	}
+
 	// Use the first preposition as a delimiter between object and target.
 	object, target := splitOnPreposition(rest)
 
+	// A direction is a noun of its own, spelled out: "look n" looks north.
+	if dir, ok := Direction(object); ok {
+		object = dir
+	}
+	if dir, ok := Direction(target); ok {
+		target = dir
+	}
+
+	// "enter north" is a way to go, not something to get into.
+	if verb == "enter" && directionNames[object] && target == "" {
+		verb = "go"
+	}
+
+	// "sneak to the north" sneaks north.
+	if verb == "sneak" && object == "" {
+		object, target = target, ""
+	}
+
+	// "drink from the bottle" drinks what is in it.
+	if verb == "drink" && object == "" {
+		object, target = target, ""
+	}
+
+	// "talk <npc> topics" and "talk to <npc> about topics" list topics.
+	if verb == "talk" {
+		if target == "topics" {
+			verb, target = "topics", ""
+		} else if npc, ok := strings.CutSuffix(object, " topics"); ok && target == "" {
+			verb, object = "topics", npc
+		}
+	}
+
 	return types.Intent{
 		Verb:   verb,
 		Object: object,
 		Target: target,
+		Count:  count,
+	}
+}
+
+// Direction returns the direction a word names, spelled out ("north" for
+// "n" or "north"), and whether it names one.
+func Direction(word string) (string, bool) {
+	if dir, ok := directionExpansions[word]; ok {
+		return dir, true
 	}
+	return word, directionNames[word]
+}
+
+var numberWords = map[string]int{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+}
+
+// quantity reads a word as a count of things: a positive number, in digits
+// or as a word from "one" to "twelve".
+func quantity(word string) (int, bool) {
+	if n, ok := numberWords[word]; ok {
+		return n, true
+	}
+	n, err := strconv.Atoi(word)
+	return n, err == nil && n > 0
 }
 
 // expandMultiWordVerbs handles "look at", "pick up", "talk to" etc.
@@ -243,6 +438,17 @@ func expandMultiWordVerbs(words []string) []string {
 		if words[1] == "off" {
 			return append([]string{"remove"}, words[2:]...)
 		}
+	case "get":
+		switch words[1] {
+		case "in", "into", "on", "onto", "aboard":
+			return append([]string{"enter"}, words[2:]...)
+		case "out", "off":
+			rest := words[2:]
+			if len(rest) > 0 && rest[0] == "of" {
+				rest = rest[1:]
+			}
+			return append([]string{"exit"}, rest...)
+		}
 	case "turn", "switch":
 		if words[1] == "on" {
 			return append([]string{"activate"}, words[2:]...)
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"slices"
+	"sort"
 	"testing"
 
 	"github.com/nathoo/questcore/types"
@@ -57,6 +59,41 @@ func TestParse(t *testing.T) {
 			input: "get key",
 			want:  types.Intent{Verb: "take", Object: "key"},
 		},
+		{
+			name:  "get in boat → enter boat",
+			input: "get in the boat",
+			want:  types.Intent{Verb: "enter", Object: "boat"},
+		},
+		{
+			name:  "get out of boat → exit boat",
+			input: "get out of boat",
+			want:  types.Intent{Verb: "exit", Object: "boat"},
+		},
+		{
+			name:  "tiptoe to the north → sneak north",
+			input: "tiptoe to the n",
+			want:  types.Intent{Verb: "sneak", Object: "north"},
+		},
+		{
+			name:  "drink from bottle → drink bottle",
+			input: "drink from the bottle",
+			want:  types.Intent{Verb: "drink", Object: "bottle"},
+		},
+		{
+			name:  "empty bottle into basin → pour",
+			input: "empty the bottle into the basin",
+			want:  types.Intent{Verb: "pour", Object: "bottle", Target: "basin"},
+		},
+		{
+			name:  "board boat → enter boat",
+			input: "board boat",
+			want:  types.Intent{Verb: "enter", Object: "boat"},
+		},
+		{
+			name:  "enter north → go north",
+			input: "enter n",
+			want:  types.Intent{Verb: "go", Object: "north"},
+		},
 		{
 			name:  "hit goblin → attack goblin",
 			input: "hit goblin",
@@ -194,6 +231,31 @@ func TestParse(t *testing.T) {
 			input: "take the key",
 			want:  types.Intent{Verb: "take", Object: "key"},
 		},
+		{
+			name:  "look n → direction spelled out",
+			input: "look n",
+			want:  types.Intent{Verb: "look", Object: "north"},
+		},
+		{
+			name:  "throw rock to the north → direction target",
+			input: "throw rock to the north",
+			want:  types.Intent{Verb: "throw", Object: "rock", Target: "north"},
+		},
+		{
+			name:  "take 3 coins → count",
+			input: "take 3 coins",
+			want:  types.Intent{Verb: "take", Object: "coins", Count: 3},
+		},
+		{
+			name:  "drop the two coins → count word",
+			input: "drop the two coins",
+			want:  types.Intent{Verb: "drop", Object: "coins", Count: 2},
+		},
+		{
+			name:  "take 3 → number is the object",
+			input: "take 3",
+			want:  types.Intent{Verb: "take", Object: "3"},
+		},
 		{
 			name:  "take a sword → article stripped",
 			input: "take a sword",
@@ -308,6 +370,21 @@ func TestParse(t *testing.T) {
 			input: "talk guard",
 			want:  types.Intent{Verb: "talk", Object: "guard"},
 		},
+		{
+			name:  "topics guard",
+			input: "topics guard",
+			want:  types.Intent{Verb: "topics", Object: "guard"},
+		},
+		{
+			name:  "talk guard topics",
+			input: "talk to the old guard topics",
+			want:  types.Intent{Verb: "topics", Object: "old guard"},
+		},
+		{
+			name:  "ask guard about topics",
+			input: "ask guard about topics",
+			want:  types.Intent{Verb: "topics", Object: "guard"},
+		},
 
 		// Case insensitivity
 		{
@@ -343,3 +420,47 @@ func TestParse(t *testing.T) {
 		})
 	}
 }
+
+func TestVerbs(t *testing.T) {
+	verbs := Verbs()
+	if !sort.StringsAreSorted(verbs) {
+		t.Error("Verbs() is not sorted")
+	}
+	for _, want := range []string{"examine", "inspect", "go", "open", "north", "inventory", "inv"} {
+		if !slices.Contains(verbs, want) {
+			t.Errorf("Verbs() is missing %q", want)
+		}
+	}
+	for _, abbrev := range []string{"x", "l", "i"} {
+		if slices.Contains(verbs, abbrev) {
+			t.Errorf("Verbs() includes the abbreviation %q", abbrev)
+		}
+	}
+}
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"look", []string{"look"}},
+		{"take key and open door then go north", []string{"take key", "open door", "go north"}},
+		{"take key, open door, n", []string{"take key", "open door", "n"}},
+		{"take key, and then pick up lamp", []string{"take key", "pick up lamp"}},
+		{"take bread and butter", []string{"take bread and butter"}},
+		{"take key, lamp", []string{"take key, lamp"}},
+		{"Take Key THEN wait", []string{"Take Key", "wait"}},
+		{"and then", nil},
+	}
+	for _, tt := range tests {
+		if got := SplitCommands(tt.input, nil); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitCommands(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	// isVerb adds verbs that games use.
+	ring := func(verb string) bool { return verb == "ring" || IsVerb(verb) }
+	if got := SplitCommands("take bell and ring it", ring); !slices.Equal(got, []string{"take bell", "ring it"}) {
+		t.Errorf("with a game verb, got %q", got)
+	}
+}
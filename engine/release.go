@@ -0,0 +1,6 @@
+//go:build !questcore_debug
+
+package engine
+
+// checkDefs is off outside questcore_debug builds; see debug.go.
+const checkDefs = false
@@ -0,0 +1,58 @@
+package resolve
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// largeDefs returns a game with n rooms holding ten entities each, every
+// room with a "brass lamp" and a "lamp" of its own, so names are partly
+// shared.
+func largeDefs(n int) *state.Defs {
+	defs := &state.Defs{
+		Game:     types.GameDef{Start: "room0"},
+		Rooms:    map[string]types.RoomDef{},
+		Entities: map[string]types.EntityDef{},
+	}
+	for i := 0; i < n; i++ {
+		room := fmt.Sprintf("room%d", i)
+		defs.Rooms[room] = types.RoomDef{ID: room}
+		for j := 0; j < 10; j++ {
+			id := fmt.Sprintf("%s_thing%d", room, j)
+			name := fmt.Sprintf("thing %d", j)
+			switch j {
+			case 0:
+				name = "brass lamp"
+			case 1:
+				name = "oil lamp"
+			}
+			defs.Entities[id] = types.EntityDef{ID: id, Kind: "item", Props: map[string]any{"name": name, "location": room}}
+		}
+	}
+	return defs
+}
+
+func benchmarkResolve(b *testing.B, n int, intent types.Intent) {
+	defs := largeDefs(n)
+	s := state.NewState(defs)
+	state.IndexLocations(s, defs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Resolve(s, defs, intent)
+	}
+}
+
+func BenchmarkResolve_ByName500(b *testing.B) {
+	benchmarkResolve(b, 500, types.Intent{Verb: "take", Object: "thing 7"})
+}
+
+func BenchmarkResolve_Ambiguous500(b *testing.B) {
+	benchmarkResolve(b, 500, types.Intent{Verb: "take", Object: "lamp"})
+}
+
+func BenchmarkResolve_ObjectAndTarget500(b *testing.B) {
+	benchmarkResolve(b, 500, types.Intent{Verb: "put", Object: "brass lamp", Target: "thing 3"})
+}
@@ -59,8 +59,8 @@ func Resolve(s *types.State, defs *state.Defs, intent types.Intent) (Result, err
 
 // resolveName resolves a single name string to an entity ID.
 func resolveName(s *types.State, defs *state.Defs, name string) (string, error) {
-	// 1. Exact entity ID match.
-	if _, ok := defs.Entities[name]; ok {
+	// 1. Exact entity ID match, unless another player character has it.
+	if _, ok := defs.Entities[name]; ok && !state.HeldByOtherPlayer(s, name) {
 		return name, nil
 	}
 
@@ -91,6 +91,33 @@ func resolveName(s *types.State, defs *state.Defs, name string) (string, error)
 		}
 	}
 
+	// Check the liquids in reach, in vessels or flowing from sources.
+	for _, source := range state.LiquidSources(s, defs, "") {
+		liquid := state.LiquidIn(s, defs, source)
+		if containsStr(matches, liquid) {
+			continue
+		}
+		if def, ok := defs.Entities[liquid]; ok && matchesName(s, defs, liquid, def, nameLower) {
+			matches = append(matches, liquid)
+		}
+	}
+
+	// Check what the entities in the room carry, save concealed items and
+	// the contents of locked containers.
+	for _, holder := range state.EntitiesInRoom(s, defs, s.Player.Location) {
+		if state.IsLocked(s, defs, holder) {
+			continue
+		}
+		for _, itemID := range state.Holding(s, defs, holder) {
+			if concealed, _ := state.GetEntityProp(s, defs, itemID, "concealed"); concealed == true {
+				continue
+			}
+			if matchesName(s, defs, itemID, defs.Entities[itemID], nameLower) {
+				matches = append(matches, itemID)
+			}
+		}
+	}
+
 	switch len(matches) {
 	case 0:
 		return "", &NotFoundError{Name: name}
@@ -114,14 +141,14 @@ func matchesName(s *types.State, defs *state.Defs, id string, def types.EntityDe
 	if nameVal, ok := state.GetEntityProp(s, defs, id, "name"); ok {
 		if nameStr, ok := nameVal.(string); ok {
 			entityNameLower := strings.ToLower(nameStr)
-			// Exact match.
-			if entityNameLower == nameLower {
+			// Exact match, singular or plural: "coins" matches "coin".
+			if singular(entityNameLower) == singular(nameLower) {
 				return true
 			}
 			// Word-based partial match: query matches any word in the name.
 			// e.g. "key" matches "rusty key", "guard" matches "castle guard".
 			for _, word := range strings.Fields(entityNameLower) {
-				if word == nameLower {
+				if singular(word) == singular(nameLower) {
 					return true
 				}
 			}
@@ -139,6 +166,12 @@ func matchesName(s *types.State, defs *state.Defs, id string, def types.EntityDe
 	return false
 }
 
+// singular drops a plural "s", so that "3 coins" names the item "coin"
+// and "1 coin" the item "coins".
+func singular(name string) string {
+	return strings.TrimSuffix(name, "s")
+}
+
 func containsStr(slice []string, s string) bool {
 	for _, v := range slice {
 		if v == s {
@@ -277,3 +277,31 @@ func TestResolve_UnderscoreNormalization_Target(t *testing.T) {
 		t.Errorf("expected iron_door, got %q", res.TargetID)
 	}
 }
+
+func TestResolve_ItemCarriedByNPC(t *testing.T) {
+	defs := testDefs()
+	defs.Entities["guard_purse"] = types.EntityDef{ID: "guard_purse", Kind: "item",
+		Props: map[string]any{"name": "coin purse", "location": "guard"}}
+	defs.Entities["guard_dagger"] = types.EntityDef{ID: "guard_dagger", Kind: "item",
+		Props: map[string]any{"name": "hidden dagger", "location": "guard", "concealed": true}}
+	s := state.NewState(defs)
+
+	res, err := Resolve(s, defs, types.Intent{Verb: "steal", Object: "purse"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.ObjectID != "guard_purse" {
+		t.Errorf("expected guard_purse, got %q", res.ObjectID)
+	}
+
+	// Concealed items aren't found by name.
+	if _, err := Resolve(s, defs, types.Intent{Verb: "steal", Object: "dagger"}); err == nil {
+		t.Error("expected a concealed item not to resolve by name")
+	}
+
+	// Nor is anything carried by someone in another room.
+	s.Player.Location = "entrance"
+	if _, err := Resolve(s, defs, types.Intent{Verb: "steal", Object: "purse"}); err == nil {
+		t.Error("expected an item carried elsewhere not to resolve")
+	}
+}
@@ -1,13 +1,27 @@
 package engine
 
-import "math/rand"
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/nathoo/questcore/types"
+)
 
 // RNG wraps math/rand.Rand with deterministic position tracking.
 // Position increments with every call, enabling save/restore.
 type RNG struct {
-	seed int64
-	src  *rand.Rand
-	pos  int64
+	seed  int64
+	src   *rand.Rand
+	pos   int64
+	draws []Draw
+}
+
+// Draw records one RNG call for the trace audit trail.
+type Draw struct {
+	Purpose  string // what the draw decides, e.g. "flee" or "loot: potion"
+	Sides    int    // the roll is in [1, Sides]; for weighted picks, the total weight
+	Roll     int    // value drawn
+	Position int64  // RNG position after the draw
 }
 
 // NewRNG creates a new deterministic RNG from a seed.
@@ -20,19 +34,33 @@ func NewRNG(seed int64) *RNG {
 
 // Roll returns a random integer in [1, sides].
 func (r *RNG) Roll(sides int) int {
+	return r.RollFor("", sides)
+}
+
+// RollFor is Roll with the purpose of the draw recorded for auditing.
+func (r *RNG) RollFor(purpose string, sides int) int {
 	r.pos++
-	return r.src.Intn(sides) + 1
+	roll := r.src.Intn(sides) + 1
+	r.record(purpose, sides, roll)
+	return roll
 }
 
 // WeightedSelect returns an index chosen by weighted random selection.
 // weights must be non-empty with all positive values.
 func (r *RNG) WeightedSelect(weights []int) int {
+	return r.SelectFor("", weights)
+}
+
+// SelectFor is WeightedSelect with the purpose of the draw recorded for
+// auditing.
+func (r *RNG) SelectFor(purpose string, weights []int) int {
 	total := 0
 	for _, w := range weights {
 		total += w
 	}
 	r.pos++
 	roll := r.src.Intn(total)
+	r.record(purpose, total, roll+1)
 	cumulative := 0
 	for i, w := range weights {
 		cumulative += w
@@ -43,6 +71,40 @@ func (r *RNG) WeightedSelect(weights []int) int {
 	return len(weights) - 1
 }
 
+func (r *RNG) record(purpose string, sides, roll int) {
+	r.draws = append(r.draws, Draw{Purpose: purpose, Sides: sides, Roll: roll, Position: r.pos})
+}
+
+// TakeDraws returns the draws made since the last call and clears the log.
+func (r *RNG) TakeDraws() []Draw {
+	draws := r.draws
+	r.draws = nil
+	return draws
+}
+
+// drawEvents turns draws into "rng_draw" events, which trace output shows
+// by their message.
+func drawEvents(draws []Draw) []types.Event {
+	var evts []types.Event
+	for _, d := range draws {
+		purpose := d.Purpose
+		if purpose == "" {
+			purpose = "roll"
+		}
+		evts = append(evts, types.Event{
+			Type: "rng_draw",
+			Data: map[string]any{
+				"purpose":  purpose,
+				"roll":     d.Roll,
+				"sides":    d.Sides,
+				"position": d.Position,
+				"message":  fmt.Sprintf("%s: rolled %d of %d (position %d)", purpose, d.Roll, d.Sides, d.Position),
+			},
+		})
+	}
+	return evts
+}
+
 // Position returns the number of RNG calls made since creation.
 func (r *RNG) Position() int64 {
 	return r.pos
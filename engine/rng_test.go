@@ -152,3 +152,28 @@ func TestRNG_DifferentSeeds_DifferentResults(t *testing.T) {
 		t.Error("expected different seeds to produce different results")
 	}
 }
+
+func TestRNG_TakeDrawsRecordsAndClears(t *testing.T) {
+	rng := NewRNG(42)
+	roll := rng.RollFor("flee", 6)
+	idx := rng.SelectFor("enemy action", []int{30, 70})
+
+	draws := rng.TakeDraws()
+	if len(draws) != 2 {
+		t.Fatalf("expected 2 draws, got %d", len(draws))
+	}
+	if d := draws[0]; d.Purpose != "flee" || d.Sides != 6 || d.Roll != roll || d.Position != 1 {
+		t.Errorf("draw 0 = %+v, want flee roll %d of 6 at position 1", d, roll)
+	}
+	d := draws[1]
+	if d.Purpose != "enemy action" || d.Sides != 100 || d.Position != 2 {
+		t.Errorf("draw 1 = %+v, want enemy action of 100 at position 2", d)
+	}
+	if (d.Roll <= 30) != (idx == 0) {
+		t.Errorf("roll %d inconsistent with selected index %d", d.Roll, idx)
+	}
+
+	if len(rng.TakeDraws()) != 0 {
+		t.Error("expected draw log to be cleared")
+	}
+}
@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/parser"
+	"github.com/nathoo/questcore/engine/resolve"
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/types"
+)
+
+// ApplicableRules lists the rules that can apply where the player is, for
+// debugging a game. query narrows them down: a verb (or one of its
+// synonyms) to the rules for it, an entity (named as the player would, or
+// by ID) to the rules about it, "" to none.
+func (e *Engine) ApplicableRules(query string) ([]rules.Entry, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return rules.Applicable(e.State, e.Defs, nil), nil
+	}
+
+	if verb := parser.Parse(query).Verb; !strings.Contains(query, " ") && e.isVerb(verb) {
+		return rules.Applicable(e.State, e.Defs, func(_ string, rule types.RuleDef) bool {
+			return rule.When.Verb == verb
+		}), nil
+	}
+
+	id := query
+	if res, err := resolve.Resolve(e.State, e.Defs, types.Intent{Verb: "examine", Object: query}); err == nil {
+		id = res.ObjectID
+	} else if _, ok := e.Defs.Entities[query]; !ok {
+		return nil, fmt.Errorf("%q is neither a verb nor anything here", query)
+	}
+	return rules.Applicable(e.State, e.Defs, func(scope string, rule types.RuleDef) bool {
+		return scope == "entity:"+id || rule.When.Object == id || rule.When.Target == id
+	}), nil
+}
+
+// isVerb reports whether verb is one the parser knows or one a rule
+// matches.
+func (e *Engine) isVerb(verb string) bool {
+	if slices.Contains(parser.Verbs(), verb) {
+		return true
+	}
+	found := false
+	rules.Applicable(e.State, e.Defs, func(_ string, rule types.RuleDef) bool {
+		found = found || rule.When.Verb == verb
+		return false
+	})
+	return found
+}
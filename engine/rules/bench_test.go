@@ -0,0 +1,55 @@
+package rules
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// largeDefs returns a game with n rooms, each with a rule for each of a
+// dozen verbs, and n global rules spread over the same verbs.
+func largeDefs(n int) *state.Defs {
+	verbs := []string{"take", "drop", "open", "close", "push", "pull", "read", "eat", "wear", "use", "give", "talk"}
+	defs := &state.Defs{
+		Game:     types.GameDef{Start: "room0"},
+		Rooms:    map[string]types.RoomDef{},
+		Entities: map[string]types.EntityDef{},
+	}
+	say := []types.Effect{{Type: "say", Params: map[string]any{"text": "Done."}}}
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("room%d", i)
+		room := types.RoomDef{ID: id}
+		for j, verb := range verbs {
+			room.Rules = append(room.Rules, types.RuleDef{
+				ID: fmt.Sprintf("%s_%s", id, verb), When: types.MatchCriteria{Verb: verb, Object: fmt.Sprintf("thing%d", j)},
+				Effects: say,
+			})
+		}
+		defs.Rooms[id] = room
+		defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+			ID: fmt.Sprintf("global%d", i), When: types.MatchCriteria{Verb: verbs[i%len(verbs)], Object: fmt.Sprintf("thing%d", i)},
+			Effects: say, SourceOrder: i,
+		})
+	}
+	return defs
+}
+
+func benchmarkEvaluate(b *testing.B, n int, indexed bool) {
+	defs := largeDefs(n)
+	if indexed {
+		defs.IndexRules()
+	}
+	s := state.NewState(defs)
+	intent := types.Intent{Verb: "read", Object: "thing5"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Evaluate(s, defs, intent, "thing5", "")
+	}
+}
+
+func BenchmarkEvaluate_Scan100(b *testing.B)     { benchmarkEvaluate(b, 100, false) }
+func BenchmarkEvaluate_Indexed100(b *testing.B)  { benchmarkEvaluate(b, 100, true) }
+func BenchmarkEvaluate_Scan5000(b *testing.B)    { benchmarkEvaluate(b, 5000, false) }
+func BenchmarkEvaluate_Indexed5000(b *testing.B) { benchmarkEvaluate(b, 5000, true) }
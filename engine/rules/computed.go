@@ -0,0 +1,22 @@
+package rules
+
+import (
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// EvalComputed returns the current value of a computed property: the value of
+// the first case whose conditions all hold, otherwise the default. The bool
+// is false if no computed property with that ID is defined.
+func EvalComputed(id string, s *types.State, defs *state.Defs) (any, bool) {
+	def, ok := defs.Computed[id]
+	if !ok {
+		return nil, false
+	}
+	for _, c := range def.Cases {
+		if EvalAllConditions(c.When, s, defs) {
+			return c.Value, true
+		}
+	}
+	return def.Default, true
+}
@@ -0,0 +1,83 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func TestEvalComputed(t *testing.T) {
+	s, defs := condTestState()
+	defs.Computed = map[string]types.ComputedDef{
+		"door_status": {
+			ID:   "door_status",
+			Uses: []string{"flags.door_open", "entities.door.locked"},
+			Cases: []types.ComputedCase{
+				{When: []types.Condition{{Type: "flag_set", Params: map[string]any{"flag": "door_open"}}}, Value: "open"},
+				{When: []types.Condition{{Type: "prop_is", Params: map[string]any{"entity": "door", "prop": "locked", "value": true}}}, Value: "locked"},
+			},
+			Default: "closed",
+		},
+	}
+
+	if v, ok := EvalComputed("door_status", s, defs); !ok || v != "locked" {
+		t.Errorf("door_status = %v, %v; want locked", v, ok)
+	}
+
+	s.Flags["door_open"] = true
+	if v, _ := EvalComputed("door_status", s, defs); v != "open" {
+		t.Errorf("door_status = %v; want open (first matching case wins)", v)
+	}
+
+	s.Flags["door_open"] = false
+	s.Entities["door"] = types.EntityState{Props: map[string]any{"locked": false}}
+	if v, _ := EvalComputed("door_status", s, defs); v != "closed" {
+		t.Errorf("door_status = %v; want default closed", v)
+	}
+
+	if _, ok := EvalComputed("missing", s, defs); ok {
+		t.Error("expected ok=false for undefined computed property")
+	}
+}
+
+func TestEvalCondition_ComputedIs(t *testing.T) {
+	s, defs := condTestState()
+	defs.Computed = map[string]types.ComputedDef{
+		"rank": {
+			ID: "rank",
+			Cases: []types.ComputedCase{
+				{When: []types.Condition{{Type: "counter_gt", Params: map[string]any{"counter": "score", "value": 40}}}, Value: "hero"},
+			},
+			Default: "novice",
+		},
+	}
+
+	hero := types.Condition{Type: "computed_is", Params: map[string]any{"computed": "rank", "value": "hero"}}
+	if !EvalCondition(hero, s, defs) {
+		t.Error("expected rank == hero with score 50")
+	}
+	s.Counters["score"] = 10
+	if EvalCondition(hero, s, defs) {
+		t.Error("expected rank != hero with score 10")
+	}
+	missing := types.Condition{Type: "computed_is", Params: map[string]any{"computed": "nope", "value": nil}}
+	if EvalCondition(missing, s, defs) {
+		t.Error("undefined computed property should never match")
+	}
+}
+
+func TestEvalCondition_ComputedIsTable(t *testing.T) {
+	s, defs := condTestState()
+	defs.Computed = map[string]types.ComputedDef{
+		"loot": {ID: "loot", Default: map[string]any{"gold": 3, "gems": []any{"ruby"}}},
+	}
+
+	same := types.Condition{Type: "computed_is", Params: map[string]any{"computed": "loot", "value": map[string]any{"gold": 3, "gems": []any{"ruby"}}}}
+	if !EvalCondition(same, s, defs) {
+		t.Error("expected equal tables to match")
+	}
+	other := types.Condition{Type: "computed_is", Params: map[string]any{"computed": "loot", "value": []any{"ruby"}}}
+	if EvalCondition(other, s, defs) {
+		t.Error("expected different tables not to match")
+	}
+}
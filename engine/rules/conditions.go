@@ -2,84 +2,264 @@
 package rules
 
 import (
+	"reflect"
+
 	"github.com/nathoo/questcore/engine/state"
 	"github.com/nathoo/questcore/types"
 )
 
 // EvalCondition evaluates a single condition against the current state.
+// Types other than the built-in ones are looked up with defs.Condition.
 func EvalCondition(c types.Condition, s *types.State, defs *state.Defs) bool {
 	switch c.Type {
-	case "has_item":
-		item, _ := c.Params["item"].(string)
-		return state.HasItem(s, item)
-
-	case "flag_set":
-		flag, _ := c.Params["flag"].(string)
-		return state.GetFlag(s, flag)
-
-	case "flag_not":
-		flag, _ := c.Params["flag"].(string)
-		return !state.GetFlag(s, flag)
-
-	case "flag_is":
-		flag, _ := c.Params["flag"].(string)
-		value, _ := c.Params["value"].(bool)
-		return state.GetFlag(s, flag) == value
-
-	case "counter_gt":
-		counter, _ := c.Params["counter"].(string)
-		value := toInt(c.Params["value"])
-		return state.GetCounter(s, counter) > value
-
-	case "counter_lt":
-		counter, _ := c.Params["counter"].(string)
-		value := toInt(c.Params["value"])
-		return state.GetCounter(s, counter) < value
-
-	case "in_room":
-		room, _ := c.Params["room"].(string)
-		return state.PlayerLocation(s) == room
-
-	case "prop_is":
-		entity, _ := c.Params["entity"].(string)
-		prop, _ := c.Params["prop"].(string)
-		expected := c.Params["value"]
-		actual, ok := state.GetEntityProp(s, defs, entity, prop)
-		if !ok {
-			return expected == nil
-		}
-		return actual == expected
-
 	case "not":
-		if c.Inner == nil {
-			return true
-		}
-		return !EvalCondition(*c.Inner, s, defs)
+		return evalNot(c, s, defs)
+	case "computed_is":
+		return evalComputedIs(c, s, defs)
+	}
+	eval, ok := conditions[c.Type]
+	if !ok {
+		eval, ok = defs.Condition(c.Type)
+	}
+	if !ok {
+		return false // unknown condition type
+	}
+	return eval(c, s, defs)
+}
 
-	case "in_combat":
-		return state.InCombat(s)
+// conditions holds the function evaluating each built-in condition type
+// but not and computed_is, which evaluate conditions in turn and so are
+// dispatched by EvalCondition itself.
+var conditions = map[string]ConditionFunc{
+	"has_item":        evalHasItem,
+	"flag_set":        evalFlagSet,
+	"flag_not":        evalFlagNot,
+	"flag_is":         evalFlagIs,
+	"counter_gt":      evalCounterGT,
+	"counter_lt":      evalCounterLT,
+	"counter_eq":      evalCounterEq,
+	"counter_between": evalCounterBetween,
+	"counter_cmp":     evalCounterCmp,
+	"in_room":         evalInRoom,
+	"prop_is":         evalPropIs,
+	"prop_gt":         evalPropGT,
+	"prop_lt":         evalPropLT,
+	"in_combat":       evalInCombat,
+	"in_combat_with":  evalInCombatWith,
+	"in_vehicle":      evalInVehicle,
+	"is_attached":     evalIsAttached,
+	"has_liquid":      evalHasLiquid,
+	"is_player":       evalIsPlayer,
+	"knows_fact":      evalKnowsFact,
+	"stat_gt":         evalStatGT,
+	"stat_lt":         evalStatLT,
+	"time_is":         evalTimeIs,
+	"time_between":    evalTimeBetween,
+}
 
-	case "in_combat_with":
-		entity, _ := c.Params["entity"].(string)
-		return state.InCombat(s) && s.Combat.EnemyID == entity
+func evalHasItem(c types.Condition, s *types.State, defs *state.Defs) bool {
+	item, _ := c.Params["item"].(string)
+	return state.HasItem(s, item)
+}
 
-	case "stat_gt":
-		entity, _ := c.Params["entity"].(string)
-		stat, _ := c.Params["stat"].(string)
-		value := toInt(c.Params["value"])
-		actual, ok := state.GetStat(s, defs, entity, stat)
-		return ok && actual > value
+func evalFlagSet(c types.Condition, s *types.State, defs *state.Defs) bool {
+	flag, _ := c.Params["flag"].(string)
+	return state.GetFlag(s, flag)
+}
 
-	case "stat_lt":
-		entity, _ := c.Params["entity"].(string)
-		stat, _ := c.Params["stat"].(string)
-		value := toInt(c.Params["value"])
-		actual, ok := state.GetStat(s, defs, entity, stat)
-		return ok && actual < value
+func evalFlagNot(c types.Condition, s *types.State, defs *state.Defs) bool {
+	flag, _ := c.Params["flag"].(string)
+	return !state.GetFlag(s, flag)
+}
 
-	default:
+func evalFlagIs(c types.Condition, s *types.State, defs *state.Defs) bool {
+	flag, _ := c.Params["flag"].(string)
+	value, _ := c.Params["value"].(bool)
+	return state.GetFlag(s, flag) == value
+}
+
+func evalCounterGT(c types.Condition, s *types.State, defs *state.Defs) bool {
+	counter, _ := c.Params["counter"].(string)
+	value := toInt(c.Params["value"])
+	return counterValue(s, defs, counter) > value
+}
+
+func evalCounterLT(c types.Condition, s *types.State, defs *state.Defs) bool {
+	counter, _ := c.Params["counter"].(string)
+	value := toInt(c.Params["value"])
+	return counterValue(s, defs, counter) < value
+}
+
+func evalCounterEq(c types.Condition, s *types.State, defs *state.Defs) bool {
+	counter, _ := c.Params["counter"].(string)
+	return counterValue(s, defs, counter) == toInt(c.Params["value"])
+}
+
+// evalCounterBetween is inclusive at both ends.
+func evalCounterBetween(c types.Condition, s *types.State, defs *state.Defs) bool {
+	counter, _ := c.Params["counter"].(string)
+	v := counterValue(s, defs, counter)
+	return v >= toInt(c.Params["min"]) && v <= toInt(c.Params["max"])
+}
+
+// evalCounterCmp compares two counters, as in CounterCmp("gold", ">=", "price").
+func evalCounterCmp(c types.Condition, s *types.State, defs *state.Defs) bool {
+	counter, _ := c.Params["counter"].(string)
+	op, _ := c.Params["op"].(string)
+	other, _ := c.Params["other"].(string)
+	a, b := counterValue(s, defs, counter), counterValue(s, defs, other)
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false // unknown operator, caught by the loader
+}
+
+func evalInRoom(c types.Condition, s *types.State, defs *state.Defs) bool {
+	room, _ := c.Params["room"].(string)
+	return state.PlayerLocation(s) == room
+}
+
+func evalPropIs(c types.Condition, s *types.State, defs *state.Defs) bool {
+	entity, _ := c.Params["entity"].(string)
+	prop, _ := c.Params["prop"].(string)
+	expected := c.Params["value"]
+	actual, ok := state.GetEntityProp(s, defs, entity, prop)
+	if !ok {
+		return expected == nil
+	}
+	return actual == expected
+}
+
+func evalPropGT(c types.Condition, s *types.State, defs *state.Defs) bool {
+	actual, ok := propNumber(c, s, defs)
+	return ok && actual > toInt(c.Params["value"])
+}
+
+func evalPropLT(c types.Condition, s *types.State, defs *state.Defs) bool {
+	actual, ok := propNumber(c, s, defs)
+	return ok && actual < toInt(c.Params["value"])
+}
+
+// propNumber returns the numeric entity property a prop_gt or prop_lt
+// condition reads; false if it is unset or not a number.
+func propNumber(c types.Condition, s *types.State, defs *state.Defs) (int, bool) {
+	entity, _ := c.Params["entity"].(string)
+	prop, _ := c.Params["prop"].(string)
+	v, _ := state.GetEntityProp(s, defs, entity, prop)
+	switch v.(type) {
+	case int, int64, float64:
+		return toInt(v), true
+	}
+	return 0, false
+}
+
+func evalNot(c types.Condition, s *types.State, defs *state.Defs) bool {
+	if c.Inner == nil {
+		return true
+	}
+	return !EvalCondition(*c.Inner, s, defs)
+}
+
+func evalInCombat(c types.Condition, s *types.State, defs *state.Defs) bool {
+	return state.InCombat(s)
+}
+
+func evalInCombatWith(c types.Condition, s *types.State, defs *state.Defs) bool {
+	entity, _ := c.Params["entity"].(string)
+	return state.InCombat(s) && s.Combat.EnemyID == entity
+}
+
+// evalInVehicle holds while the player is in the vehicle the condition
+// names, or in any if it names none.
+func evalInVehicle(c types.Condition, s *types.State, defs *state.Defs) bool {
+	entity, _ := c.Params["entity"].(string)
+	if entity == "" {
+		return s.Player.Vehicle != ""
+	}
+	return s.Player.Vehicle == entity
+}
+
+// evalIsAttached holds while the entity is tied to the one named "to", or
+// to anything, either way round, if "to" is empty.
+func evalIsAttached(c types.Condition, s *types.State, defs *state.Defs) bool {
+	entity, _ := c.Params["entity"].(string)
+	to, _ := c.Params["to"].(string)
+	return state.IsAttached(s, entity, to)
+}
+
+// evalHasLiquid holds if the vessel named, or else any the player
+// carries, holds some of the liquid.
+func evalHasLiquid(c types.Condition, s *types.State, defs *state.Defs) bool {
+	liquid, _ := c.Params["liquid"].(string)
+	if vessel, _ := c.Params["vessel"].(string); vessel != "" {
+		held, _ := state.VesselContents(s, defs, vessel)
+		return held == liquid
+	}
+	return state.HasLiquid(s, defs, liquid)
+}
+
+func evalKnowsFact(c types.Condition, s *types.State, defs *state.Defs) bool {
+	fact, _ := c.Params["fact"].(string)
+	return state.KnowsFact(s, fact)
+}
+
+func evalIsPlayer(c types.Condition, s *types.State, defs *state.Defs) bool {
+	player, _ := c.Params["player"].(string)
+	return s.ActivePlayer == player
+}
+
+func evalStatGT(c types.Condition, s *types.State, defs *state.Defs) bool {
+	entity, _ := c.Params["entity"].(string)
+	stat, _ := c.Params["stat"].(string)
+	value := toInt(c.Params["value"])
+	actual, ok := state.GetStat(s, defs, entity, stat)
+	return ok && actual > value
+}
+
+func evalStatLT(c types.Condition, s *types.State, defs *state.Defs) bool {
+	entity, _ := c.Params["entity"].(string)
+	stat, _ := c.Params["stat"].(string)
+	value := toInt(c.Params["value"])
+	actual, ok := state.GetStat(s, defs, entity, stat)
+	return ok && actual < value
+}
+
+func evalTimeIs(c types.Condition, s *types.State, defs *state.Defs) bool {
+	period, _ := c.Params["period"].(string)
+	if defs.Game.Clock == nil {
+		return false
+	}
+	return (period == "night") == state.IsNight(s, defs)
+}
+
+func evalTimeBetween(c types.Condition, s *types.State, defs *state.Defs) bool {
+	fromStr, _ := c.Params["from"].(string)
+	toStr, _ := c.Params["to"].(string)
+	from, okFrom := state.ParseClock(fromStr)
+	to, okTo := state.ParseClock(toStr)
+	if defs.Game.Clock == nil || !okFrom || !okTo {
 		return false
 	}
+	return state.TimeBetween(state.TimeOfDay(s), from, to)
+}
+
+func evalComputedIs(c types.Condition, s *types.State, defs *state.Defs) bool {
+	name, _ := c.Params["computed"].(string)
+	expected := c.Params["value"]
+	actual, ok := EvalComputed(name, s, defs)
+	// Values from Lua tables are maps or slices, which == can't compare.
+	return ok && reflect.DeepEqual(actual, expected)
 }
 
 // EvalAllConditions returns true if all conditions pass (AND logic).
@@ -93,6 +273,34 @@ func EvalAllConditions(conditions []types.Condition, s *types.State, defs *state
 	return true
 }
 
+// BindSelf returns conds with every parameter "self" replaced by id, the
+// entity the conditions are about: an enemy, in its behavior table. conds
+// itself is left as it was.
+func BindSelf(conds []types.Condition, id string) []types.Condition {
+	if conds == nil {
+		return nil
+	}
+	bound := make([]types.Condition, len(conds))
+	for i, c := range conds {
+		if c.Params != nil {
+			params := make(map[string]any, len(c.Params))
+			for k, v := range c.Params {
+				if v == "self" {
+					v = id
+				}
+				params[k] = v
+			}
+			c.Params = params
+		}
+		if c.Inner != nil {
+			inner := BindSelf([]types.Condition{*c.Inner}, id)[0]
+			c.Inner = &inner
+		}
+		bound[i] = c
+	}
+	return bound
+}
+
 // toInt converts an any value to int, handling float64 from JSON/Lua.
 func toInt(v any) int {
 	switch n := v.(type) {
@@ -106,3 +314,16 @@ func toInt(v any) int {
 		return 0
 	}
 }
+
+// counterValue returns a counter's value. The pseudo-counters
+// carried_weight and carried_size total the weight and size of the
+// player's inventory.
+func counterValue(s *types.State, defs *state.Defs, name string) int {
+	switch name {
+	case "carried_weight":
+		return state.Carried(s, defs, "weight")
+	case "carried_size":
+		return state.Carried(s, defs, "size")
+	}
+	return state.GetCounter(s, name)
+}
@@ -20,6 +20,7 @@ func condTestState() (*types.State, *state.Defs) {
 				Props: map[string]any{
 					"locked":   true,
 					"location": "hall",
+					"dents":    2,
 				},
 			},
 		},
@@ -104,6 +105,51 @@ func TestEvalCondition(t *testing.T) {
 			cond: types.Condition{Type: "counter_lt", Params: map[string]any{"counter": "score", "value": 10}},
 			want: false,
 		},
+		{
+			name: "counter_eq: passes",
+			cond: types.Condition{Type: "counter_eq", Params: map[string]any{"counter": "score", "value": 50}},
+			want: true,
+		},
+		{
+			name: "counter_eq: fails",
+			cond: types.Condition{Type: "counter_eq", Params: map[string]any{"counter": "score", "value": 49}},
+			want: false,
+		},
+		{
+			name: "counter_between: inclusive",
+			cond: types.Condition{Type: "counter_between", Params: map[string]any{"counter": "score", "min": 10, "max": 50}},
+			want: true,
+		},
+		{
+			name: "counter_between: outside",
+			cond: types.Condition{Type: "counter_between", Params: map[string]any{"counter": "score", "min": 51, "max": 60}},
+			want: false,
+		},
+		{
+			name: "counter_cmp: unset counter is zero",
+			cond: types.Condition{Type: "counter_cmp", Params: map[string]any{"counter": "score", "op": ">=", "other": "price"}},
+			want: true,
+		},
+		{
+			name: "counter_cmp: fails",
+			cond: types.Condition{Type: "counter_cmp", Params: map[string]any{"counter": "score", "op": "<", "other": "price"}},
+			want: false,
+		},
+		{
+			name: "prop_gt: passes",
+			cond: types.Condition{Type: "prop_gt", Params: map[string]any{"entity": "door", "prop": "dents", "value": 1}},
+			want: true,
+		},
+		{
+			name: "prop_lt: fails (equal)",
+			cond: types.Condition{Type: "prop_lt", Params: map[string]any{"entity": "door", "prop": "dents", "value": 2}},
+			want: false,
+		},
+		{
+			name: "prop_lt: non-numeric prop never compares",
+			cond: types.Condition{Type: "prop_lt", Params: map[string]any{"entity": "door", "prop": "locked", "value": 5}},
+			want: false,
+		},
 		{
 			name: "in_room: matches",
 			cond: types.Condition{Type: "in_room", Params: map[string]any{"room": "hall"}},
@@ -286,3 +332,58 @@ func TestCombatConditions_NotInCombat(t *testing.T) {
 		t.Error("expected in_combat_with to be false when not in combat")
 	}
 }
+
+func TestEvalCondition_CarriedPseudoCounters(t *testing.T) {
+	s, defs := condTestState()
+	defs.Entities["anvil"] = types.EntityDef{ID: "anvil", Kind: "item", Props: map[string]any{"weight": 40.0, "size": 3.0}}
+	s.Player.Inventory = append(s.Player.Inventory, "anvil")
+
+	heavy := types.Condition{Type: "counter_gt", Params: map[string]any{"counter": "carried_weight", "value": 30}}
+	small := types.Condition{Type: "counter_lt", Params: map[string]any{"counter": "carried_size", "value": 5}}
+	if !EvalCondition(heavy, s, defs) || !EvalCondition(small, s, defs) {
+		t.Error("expected carried_weight 40 > 30 and carried_size 3 < 5")
+	}
+}
+
+func TestTimeConditions(t *testing.T) {
+	s, defs := condTestState()
+	defs.Game.Clock = &types.ClockDef{Dawn: 6 * 60, Dusk: 20 * 60}
+	timeIs := func(period string) types.Condition {
+		return types.Condition{Type: "time_is", Params: map[string]any{"period": period}}
+	}
+	between := func(from, to string) types.Condition {
+		return types.Condition{Type: "time_between", Params: map[string]any{"from": from, "to": to}}
+	}
+
+	tests := []struct {
+		name  string
+		clock int
+		cond  types.Condition
+		want  bool
+	}{
+		{"noon is day", 12 * 60, timeIs("day"), true},
+		{"noon is not night", 12 * 60, timeIs("night"), false},
+		{"dusk is night", 20 * 60, timeIs("night"), true},
+		{"second day's morning", 24*60 + 7*60, timeIs("day"), true},
+		{"between same day", 9 * 60, between("08:00", "17:00"), true},
+		{"end is exclusive", 17 * 60, between("08:00", "17:00"), false},
+		{"wraps midnight, late", 23 * 60, between("22:00", "04:00"), true},
+		{"wraps midnight, early", 3 * 60, between("22:00", "04:00"), true},
+		{"wraps midnight, outside", 12 * 60, between("22:00", "04:00"), false},
+		{"invalid time", 12 * 60, between("noon", "13:00"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.Clock = tt.clock
+			if got := EvalCondition(tt.cond, s, defs); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Without a clock, time conditions never hold.
+	defs.Game.Clock = nil
+	if EvalCondition(timeIs("day"), s, defs) {
+		t.Error("time_is should be false without a clock")
+	}
+}
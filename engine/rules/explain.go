@@ -0,0 +1,177 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// Verdict is what became of one rule considered for a command.
+type Verdict struct {
+	Scope  string // where the rule is defined: "room:<id>", "entity:<id>" or "global"
+	Rule   types.RuleDef
+	Fired  bool   // the rule's effects were produced
+	Reason string // why it fired or didn't, in words
+}
+
+// Explain returns what became of each rule considered for a command in
+// phase ("" for the main rules, or "before" or "after"), in the order
+// EvaluateChain or EvaluatePhase considers them. It applies nothing, so it
+// must be called before the command's effects to tell what they did.
+func Explain(s *types.State, defs *state.Defs, phase string,
+	intent types.Intent, objectID, targetID string, succeeded bool) []Verdict {
+	var verdicts []Verdict
+	var fired *types.RuleDef // the rule that ended the search
+	for _, b := range collect(s, defs, intent.Verb, objectID, targetID) {
+		var matching []int // indexes into verdicts
+		for _, rule := range b.rules {
+			if rule.When.Phase != phase {
+				continue
+			}
+			v := Verdict{Scope: b.scope, Rule: rule}
+			if v.Reason = reject(rule, s, defs, intent.Verb, objectID, targetID, succeeded); v.Reason == "" {
+				matching = append(matching, len(verdicts))
+			}
+			verdicts = append(verdicts, v)
+		}
+
+		sort.SliceStable(matching, func(i, j int) bool {
+			return outranks(verdicts[matching[i]].Rule, verdicts[matching[j]].Rule)
+		})
+		for _, i := range matching {
+			v := &verdicts[i]
+			switch {
+			case fired != nil:
+				v.Reason = fmt.Sprintf("it matched, but rule %s fired first", fired.ID)
+			case phase == "" && continues(v.Rule):
+				v.Fired, v.Reason = true, "it matched and continued"
+			default:
+				v.Fired, v.Reason = true, "it matched"
+				fired = &v.Rule
+			}
+		}
+	}
+	return verdicts
+}
+
+// DescribeCondition renders c as its type and parameters, such as
+// "flag_set(flag=door_open)".
+func DescribeCondition(c types.Condition) string {
+	if c.Type == "not" && c.Inner != nil {
+		return "not " + DescribeCondition(*c.Inner)
+	}
+	keys := make([]string, 0, len(c.Params))
+	for k := range c.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	params := make([]string, len(keys))
+	for i, k := range keys {
+		params[i] = fmt.Sprintf("%s=%v", k, c.Params[k])
+	}
+	return c.Type + "(" + strings.Join(params, ", ") + ")"
+}
+
+// Entry is a rule that can apply where the player is; see Applicable.
+type Entry struct {
+	Scope   string // where the rule is defined, as in Verdict
+	Rule    types.RuleDef
+	Blocked string // why the rule can't fire now whatever the command, or "" if it can
+}
+
+// Applicable returns the rules that can apply where the player is, in
+// resolution order: the room's, those of the entities in it and carried,
+// and the global ones, except those with an in_room condition for another
+// room. Only rules that keep reports true for are returned; a nil keep
+// keeps them all.
+func Applicable(s *types.State, defs *state.Defs, keep func(scope string, rule types.RuleDef) bool) []Entry {
+	scopes := []string{"room:" + s.Player.Location}
+	ids := append(state.EntitiesInRoom(s, defs, s.Player.Location), s.Player.Inventory...)
+	sort.Strings(ids)
+	for _, id := range ids {
+		scopes = append(scopes, "entity:"+id)
+	}
+	scopes = append(scopes, "global")
+
+	var entries []Entry
+	for _, scope := range scopes {
+		for _, rule := range scopeRules(defs, scope) {
+			if elsewhere(rule, s.Player.Location) || keep != nil && !keep(scope, rule) {
+				continue
+			}
+			entry := Entry{Scope: scope, Rule: rule}
+			if rule.Once && s.Fired[rule.ID] {
+				entry.Blocked = "it already fired once"
+			} else {
+				for _, c := range rule.Conditions {
+					if !EvalCondition(c, s, defs) {
+						entry.Blocked = "condition " + DescribeCondition(c) + " is false"
+						break
+					}
+				}
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// String renders the entry as a line of a rule listing, such as
+// "open_chest (entity:chest): open chest — condition flag_set(flag=unlocked) is false".
+func (e Entry) String() string {
+	line := fmt.Sprintf("%s (%s): %s", e.Rule.ID, e.Scope, DescribeWhen(e.Rule.When))
+	if e.Blocked != "" {
+		line += " — " + e.Blocked
+	}
+	return line
+}
+
+// elsewhere reports whether rule can only fire in a room other than here.
+func elsewhere(rule types.RuleDef, here string) bool {
+	for _, c := range rule.Conditions {
+		if room, _ := c.Params["room"].(string); c.Type == "in_room" && room != here {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeRules returns all the rules of scope, whatever their verb.
+func scopeRules(defs *state.Defs, scope string) []types.RuleDef {
+	if id, ok := strings.CutPrefix(scope, "room:"); ok {
+		return defs.Rooms[id].Rules
+	}
+	if id, ok := strings.CutPrefix(scope, "entity:"); ok {
+		return defs.Entities[id].Rules
+	}
+	return defs.GlobalRules
+}
+
+// DescribeWhen renders what a rule matches as a command pattern, such as
+// "use key on door" or "after take (succeeded)".
+func DescribeWhen(w types.MatchCriteria) string {
+	parts := []string{w.Verb}
+	if w.Phase != "" {
+		parts = append([]string{w.Phase}, parts...)
+	}
+	switch {
+	case w.Object != "":
+		parts = append(parts, w.Object)
+	case w.ObjectKind != "":
+		parts = append(parts, "any "+w.ObjectKind)
+	}
+	if w.Target != "" {
+		parts = append(parts, "on", w.Target)
+	}
+	if w.Succeeded != nil {
+		if *w.Succeeded {
+			parts = append(parts, "(succeeded)")
+		} else {
+			parts = append(parts, "(failed)")
+		}
+	}
+	return strings.Join(parts, " ")
+}
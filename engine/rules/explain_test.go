@@ -0,0 +1,126 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+func TestExplain(t *testing.T) {
+	rule := func(id, scope string, prio int, effs ...types.Effect) types.RuleDef {
+		return types.RuleDef{ID: id, Scope: scope, When: types.MatchCriteria{Verb: "open", Object: "chest"}, Priority: prio, Effects: effs}
+	}
+	say := types.Effect{Type: "say", Params: map[string]any{"text": "..."}}
+	locked := rule("locked", "room:hall", 0, say)
+	locked.Conditions = []types.Condition{{Type: "flag_set", Params: map[string]any{"flag": "unlocked"}}}
+	other := rule("other", "room:hall", 0, say)
+	other.When.Object = "door"
+	defs := &state.Defs{
+		Game: types.GameDef{Start: "hall"},
+		Rooms: map[string]types.RoomDef{"hall": {ID: "hall", Rules: []types.RuleDef{
+			locked, other, rule("creak", "room:hall", 5, types.Effect{Type: "continue"}),
+		}}},
+		Entities: map[string]types.EntityDef{"chest": {ID: "chest", Props: map[string]any{"location": "hall"}, Rules: []types.RuleDef{
+			rule("open", "entity:chest", 0, say), rule("also", "entity:chest", 0, say),
+		}}},
+		GlobalRules: []types.RuleDef{rule("global", "global", 0, say)},
+	}
+	s := state.NewState(defs)
+	intent := types.Intent{Verb: "open", Object: "chest"}
+
+	want := map[string]struct {
+		fired  bool
+		reason string
+	}{
+		"locked": {false, "condition flag_set(flag=unlocked) is false"},
+		"other":  {false, "it is for object door"},
+		"creak":  {true, "it matched and continued"},
+		"open":   {true, "it matched"},
+		"also":   {false, "it matched, but rule open fired first"},
+		"global": {false, "it matched, but rule open fired first"},
+	}
+	verdicts := Explain(s, defs, "", intent, "chest", "", false)
+	if len(verdicts) != len(want) {
+		t.Fatalf("got %d verdicts, want %d: %+v", len(verdicts), len(want), verdicts)
+	}
+	for _, v := range verdicts {
+		if w := want[v.Rule.ID]; v.Fired != w.fired || v.Reason != w.reason {
+			t.Errorf("%s (%s): fired %v, %q; want %v, %q", v.Rule.ID, v.Scope, v.Fired, v.Reason, w.fired, w.reason)
+		}
+	}
+	if verdicts[0].Scope != "room:hall" || verdicts[len(verdicts)-1].Scope != "global" {
+		t.Errorf("verdicts out of resolution order: %+v", verdicts)
+	}
+
+	// Explain agrees with EvaluateChain.
+	pre, effs, matched := EvaluateChain(s, defs, intent, "chest", "")
+	if !matched || len(pre) != 1 || pre[0].Type != "continue" || len(effs) != 1 {
+		t.Errorf("EvaluateChain = %v, %v, %v", pre, effs, matched)
+	}
+
+	// Rules of other phases aren't considered.
+	if got := Explain(s, defs, "after", intent, "chest", "", true); len(got) != 0 {
+		t.Errorf("after phase: %+v, want none", got)
+	}
+}
+
+func TestDescribeCondition(t *testing.T) {
+	inner := types.Condition{Type: "prop_is", Params: map[string]any{"entity": "door", "prop": "open", "value": true}}
+	c := types.Condition{Type: "not", Inner: &inner}
+	if got, want := DescribeCondition(c), "not prop_is(entity=door, prop=open, value=true)"; got != want {
+		t.Errorf("DescribeCondition = %q, want %q", got, want)
+	}
+}
+
+func TestApplicable(t *testing.T) {
+	defs := pipelineDefs()
+	s := state.NewState(defs)
+	defs.GlobalRules = append(defs.GlobalRules,
+		types.RuleDef{ID: "elsewhere", When: types.MatchCriteria{Verb: "dance"},
+			Conditions: []types.Condition{{Type: "in_room", Params: map[string]any{"room": "entrance"}}}},
+		types.RuleDef{ID: "needs_key", When: types.MatchCriteria{Verb: "dance"},
+			Conditions: []types.Condition{{Type: "has_item", Params: map[string]any{"item": "rusty_key"}}}},
+	)
+
+	var got []string
+	for _, e := range Applicable(s, defs, nil) {
+		got = append(got, e.String())
+	}
+	joined := strings.Join(got, "\n")
+	for _, want := range []string{
+		"room_take_key (room:hall): take rusty_key",
+		"entity_examine_key (entity:rusty_key): examine rusty_key",
+		"needs_key (global): dance — condition has_item(item=rusty_key) is false",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("listing lacks %q:\n%s", want, joined)
+		}
+	}
+	if strings.Contains(joined, "elsewhere") {
+		t.Errorf("listing has a rule for another room:\n%s", joined)
+	}
+
+	dance := Applicable(s, defs, func(_ string, rule types.RuleDef) bool { return rule.When.Verb == "dance" })
+	if len(dance) != 1 || dance[0].Rule.ID != "needs_key" {
+		t.Errorf("dance rules = %+v", dance)
+	}
+}
+
+func TestFallbackSource(t *testing.T) {
+	defs := pipelineDefs()
+	s := state.NewState(defs)
+	for verb, want := range map[string]string{
+		"push": "room:hall fallback for push",
+		"sing": "room:hall default fallback",
+	} {
+		if got := FallbackSource(s, defs, verb, ""); got != want {
+			t.Errorf("FallbackSource(%s) = %q, want %q", verb, got, want)
+		}
+	}
+	s.Player.Location = "nowhere"
+	if got := FallbackSource(s, defs, "push", ""); got != "cant_do message" {
+		t.Errorf("FallbackSource elsewhere = %q", got)
+	}
+}
@@ -0,0 +1,50 @@
+package rules
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nathoo/questcore/engine/state"
+)
+
+// ConditionFunc evaluates one condition of a type added to a Registry. It
+// must not change s.
+type ConditionFunc = state.ConditionFunc
+
+// Registry holds the condition types a program adds for its games to use,
+// such as "is_raining", by name. Games name them like any other:
+// { type = "is_raining", ... } in a rule's conditions. Pass the registry
+// to the loader, which accepts its types with whatever parameters they are
+// given, and to the engine, which evaluates them through Defs.Condition.
+//
+// Conditions of every type are evaluated in the order they are listed,
+// stopping at the first that fails, so a registered one runs only once
+// those before it hold.
+type Registry map[string]ConditionFunc
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() Registry {
+	return Registry{}
+}
+
+// Add adds condition type name, evaluated by fn. It returns an error if
+// name is empty, is a built-in type or is already in r, or if fn is nil.
+func (r Registry) Add(name string, fn ConditionFunc) error {
+	if name == "" || fn == nil {
+		return errors.New("a condition type needs a name and a function")
+	}
+	if _, builtin := conditions[name]; builtin || name == "not" || name == "computed_is" {
+		return fmt.Errorf("condition type %q is built in", name)
+	}
+	if _, dup := r[name]; dup {
+		return fmt.Errorf("condition type %q is already registered", name)
+	}
+	r[name] = fn
+	return nil
+}
+
+// Has reports whether name is a condition type added to r.
+func (r Registry) Has(name string) bool {
+	_, ok := r[name]
+	return ok
+}
@@ -0,0 +1,64 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+func counterEven(c types.Condition, s *types.State, defs *state.Defs) bool {
+	counter, _ := c.Params["counter"].(string)
+	return s.Counters[counter]%2 == 0
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Add("test_counter_even", counterEven); err != nil {
+		t.Fatal(err)
+	}
+	s := &types.State{Counters: map[string]int{"steps": 3}}
+	defs := &state.Defs{}
+	defs.SetConditions(r)
+	even := types.Condition{Type: "test_counter_even", Params: map[string]any{"counter": "steps"}}
+
+	if EvalCondition(even, s, defs) {
+		t.Error("3 steps is not even")
+	}
+	s.Counters["steps"] = 4
+	if !EvalCondition(even, s, defs) {
+		t.Error("4 steps is even")
+	}
+	if EvalCondition(types.Condition{Type: "not", Inner: &even}, s, defs) {
+		t.Error("not should negate a registered condition")
+	}
+	if EvalCondition(even, s, &state.Defs{}) {
+		t.Error("a condition type not in the defs should not hold")
+	}
+	if !r.Has("test_counter_even") || r.Has("has_item") {
+		t.Error("Has should report only types added to the registry")
+	}
+}
+
+func TestRegistry_AddErrors(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Add("test_counter_even", counterEven); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		fn   ConditionFunc
+	}{
+		{"has_item", counterEven},
+		{"not", counterEven},
+		{"computed_is", counterEven},
+		{"test_counter_even", counterEven},
+		{"", counterEven},
+		{"test_counter_odd", nil},
+	}
+	for _, tt := range tests {
+		if err := r.Add(tt.name, tt.fn); err == nil {
+			t.Errorf("Add(%q) should fail", tt.name)
+		}
+	}
+}
@@ -3,6 +3,9 @@ package rules
 import (
 	"sort"
 
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/messages"
+	"github.com/nathoo/questcore/engine/parser"
 	"github.com/nathoo/questcore/engine/state"
 	"github.com/nathoo/questcore/types"
 )
@@ -11,22 +14,77 @@ import (
 // effects. The bool indicates whether a rule actually matched (true) vs.
 // fallback was used (false). Step() uses this to decide built-in behavior.
 // Step 1 (resolve) is handled by the resolve package before calling this.
+// Effects of rules that Continue() come first.
 func Evaluate(s *types.State, defs *state.Defs,
 	intent types.Intent, objectID, targetID string) ([]types.Effect, bool) {
+	pre, effs, matched := EvaluateChain(s, defs, intent, objectID, targetID)
+	return append(pre, effs...), matched
+}
+
+// EvaluateChain is Evaluate with the effects of rules that fell through via
+// Continue() returned separately in pre, so the caller can apply them ahead
+// of built-in behavior. effs and matched are those of the first matching
+// rule that doesn't continue, or the fallback if there is none.
+func EvaluateChain(s *types.State, defs *state.Defs,
+	intent types.Intent, objectID, targetID string) (pre, effs []types.Effect, matched bool) {
 
 	// Step 2: Collect candidate rules in resolution order buckets.
-	buckets := collect(s, defs, objectID, targetID)
+	buckets := collect(s, defs, intent.Verb, objectID, targetID)
 
-	// Steps 3-5: Filter, rank, and select.
+	// Steps 3-5: Filter, rank, and select — falling through continuing rules.
 	for _, bucket := range buckets {
-		if winner := filterRankSelect(bucket, s, defs, intent.Verb, objectID, targetID); winner != nil {
+		for _, rule := range filterRank(bucket.rules, s, defs, intent.Verb, objectID, targetID, "", false) {
 			// Step 6: Produce effects.
-			return winner.Effects, true
+			if !continues(rule) {
+				return pre, ruleEffects(rule), true
+			}
+			pre = append(pre, ruleEffects(rule)...)
 		}
 	}
 
 	// No rule matched — produce fallback.
-	return fallback(s, defs, intent.Verb, objectID), false
+	return pre, fallback(s, defs, intent.Verb, objectID), false
+}
+
+// EvaluatePhase returns the effects of the first rule in phase ("before" or
+// "after") that matches the command, or nil if none does. succeeded tells
+// after-phase rules whether the command itself succeeded. Phase rules never
+// fall back and never replace built-in behavior.
+func EvaluatePhase(s *types.State, defs *state.Defs, phase string,
+	intent types.Intent, objectID, targetID string, succeeded bool) []types.Effect {
+	for _, bucket := range collect(s, defs, intent.Verb, objectID, targetID) {
+		if ranked := filterRank(bucket.rules, s, defs, intent.Verb, objectID, targetID, phase, succeeded); len(ranked) > 0 {
+			return ruleEffects(ranked[0])
+		}
+	}
+	return nil
+}
+
+// ruleEffects returns the effects of a rule that fired. A once rule records
+// that it fired first, so a Stop() in its effects can't skip the record.
+func ruleEffects(rule types.RuleDef) []types.Effect {
+	if rule.Once {
+		mark := types.Effect{Type: "mark_fired", Params: map[string]any{"rule": rule.ID}}
+		return append([]types.Effect{mark}, rule.Effects...)
+	}
+	return rule.Effects
+}
+
+// continues reports whether rule hands the command on to the next matching
+// rule or built-in behavior after its own effects.
+func continues(rule types.RuleDef) bool {
+	for _, eff := range rule.Effects {
+		if eff.Type == "continue" {
+			return true
+		}
+	}
+	return false
+}
+
+// bucket is the candidate rules of one scope ("room:<id>", ...).
+type bucket struct {
+	scope string
+	rules []types.RuleDef
 }
 
 // collect gathers candidate rules in resolution order (DESIGN.md §6.6):
@@ -34,51 +92,45 @@ func Evaluate(s *types.State, defs *state.Defs,
 // 2. Target entity rules
 // 3. Object entity rules
 // 4. Global rules
-func collect(s *types.State, defs *state.Defs, objectID, targetID string) [][]types.RuleDef {
-	var buckets [][]types.RuleDef
+// Where the rules are indexed, only those for verb are gathered.
+func collect(s *types.State, defs *state.Defs, verb, objectID, targetID string) []bucket {
+	var buckets []bucket
+	add := func(scope string) {
+		if rules := defs.RulesFor(scope, verb); len(rules) > 0 {
+			buckets = append(buckets, bucket{scope, rules})
+		}
+	}
 
 	// 1. Current room's rules.
-	if room, ok := defs.Rooms[s.Player.Location]; ok && len(room.Rules) > 0 {
-		buckets = append(buckets, room.Rules)
-	}
+	add("room:" + s.Player.Location)
 
 	// 2. Target entity's rules.
 	if targetID != "" {
-		if ent, ok := defs.Entities[targetID]; ok && len(ent.Rules) > 0 {
-			buckets = append(buckets, ent.Rules)
-		}
+		add("entity:" + targetID)
 	}
 
 	// 3. Object entity's rules.
 	if objectID != "" && objectID != targetID {
-		if ent, ok := defs.Entities[objectID]; ok && len(ent.Rules) > 0 {
-			buckets = append(buckets, ent.Rules)
-		}
+		add("entity:" + objectID)
 	}
 
 	// 4. Global rules.
-	if len(defs.GlobalRules) > 0 {
-		buckets = append(buckets, defs.GlobalRules)
-	}
+	add("global")
 
 	return buckets
 }
 
-// filterRankSelect filters a bucket of rules, ranks them, and returns the
-// top-ranked matching rule, or nil if none match.
-func filterRankSelect(rules []types.RuleDef, s *types.State, defs *state.Defs,
-	verb, objectID, targetID string) *types.RuleDef {
+// filterRank filters a bucket of rules in phase and returns the matching
+// ones in rank order.
+func filterRank(rules []types.RuleDef, s *types.State, defs *state.Defs,
+	verb, objectID, targetID, phase string, succeeded bool) []types.RuleDef {
 
-	// Step 3: Filter — When match + conditions.
+	// Step 3: Filter — phase, When match + conditions.
 	var candidates []types.RuleDef
 	for _, rule := range rules {
-		if !MatchesIntent(rule.When, verb, objectID, targetID, s, defs) {
-			continue
+		if rule.When.Phase == phase && reject(rule, s, defs, verb, objectID, targetID, succeeded) == "" {
+			candidates = append(candidates, rule)
 		}
-		if !EvalAllConditions(rule.Conditions, s, defs) {
-			continue
-		}
-		candidates = append(candidates, rule)
 	}
 
 	if len(candidates) == 0 {
@@ -87,33 +139,80 @@ func filterRankSelect(rules []types.RuleDef, s *types.State, defs *state.Defs,
 
 	// Step 4: Rank — specificity (desc) → priority (desc) → source order (asc).
 	sort.SliceStable(candidates, func(i, j int) bool {
-		si, sj := Specificity(candidates[i]), Specificity(candidates[j])
-		if si != sj {
-			return si > sj
-		}
-		if candidates[i].Priority != candidates[j].Priority {
-			return candidates[i].Priority > candidates[j].Priority
-		}
-		return candidates[i].SourceOrder < candidates[j].SourceOrder
+		return outranks(candidates[i], candidates[j])
 	})
 
-	// Step 5: Select first.
-	return &candidates[0]
+	return candidates
+}
+
+// outranks reports whether rule a is preferred to b when both match.
+func outranks(a, b types.RuleDef) bool {
+	if sa, sb := Specificity(a), Specificity(b); sa != sb {
+		return sa > sb
+	}
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	return a.SourceOrder < b.SourceOrder
+}
+
+// reject returns why rule can't match the command, or "" if it can. The
+// rule's phase is not checked.
+func reject(rule types.RuleDef, s *types.State, defs *state.Defs,
+	verb, objectID, targetID string, succeeded bool) string {
+	if rule.When.Succeeded != nil && *rule.When.Succeeded != succeeded {
+		if succeeded {
+			return "the command succeeded"
+		}
+		return "the command failed"
+	}
+	if rule.Once && s.Fired[rule.ID] {
+		return "it already fired once"
+	}
+	if !MatchesIntent(rule.When, verb, objectID, targetID, s, defs) {
+		switch {
+		case rule.When.Object != "" && rule.When.Object != objectID:
+			return "it is for object " + rule.When.Object
+		case rule.When.Target != "" && rule.When.Target != targetID:
+			return "it is for target " + rule.When.Target
+		}
+		return "its when doesn't match the command"
+	}
+	for _, c := range rule.Conditions {
+		if !EvalCondition(c, s, defs) {
+			return "condition " + DescribeCondition(c) + " is false"
+		}
+	}
+	return ""
 }
 
 // fallback produces effects when no rule matched.
-// Resolution: entity fallback → room fallback (verb) → room fallback (default) → global default.
 func fallback(s *types.State, defs *state.Defs, verb, objectID string) []types.Effect {
+	text, _ := fallbackText(s, defs, verb, objectID)
+	return []types.Effect{sayEffect(text)}
+}
+
+// FallbackSource names the fallback used when no rule matches a command,
+// such as "room:hall fallback for push".
+func FallbackSource(s *types.State, defs *state.Defs, verb, objectID string) string {
+	_, source := fallbackText(s, defs, verb, objectID)
+	return source
+}
+
+// fallbackText returns the fallback text for a command no rule matched,
+// and where it comes from.
+// Resolution: entity fallback → room fallback (verb) → room fallback (default) → global default.
+func fallbackText(s *types.State, defs *state.Defs, verb, objectID string) (text, source string) {
 	// 1. Entity fallback.
 	if objectID != "" {
 		if def, ok := defs.Entities[objectID]; ok {
 			if fb, ok := def.Props["fallbacks"]; ok {
 				if fbMap, ok := fb.(map[string]any); ok {
 					if text, ok := fbMap[verb].(string); ok {
-						return []types.Effect{sayEffect(text)}
+						return text, "entity:" + objectID + " fallback for " + verb
 					}
 					if text, ok := fbMap["default"].(string); ok {
-						return []types.Effect{sayEffect(text)}
+						return text, "entity:" + objectID + " default fallback"
 					}
 				}
 			}
@@ -123,16 +222,73 @@ func fallback(s *types.State, defs *state.Defs, verb, objectID string) []types.E
 	// 2. Room fallback (verb-specific).
 	if room, ok := defs.Rooms[s.Player.Location]; ok {
 		if text, ok := room.Fallbacks[verb]; ok {
-			return []types.Effect{sayEffect(text)}
+			return text, "room:" + s.Player.Location + " fallback for " + verb
 		}
 		// 3. Room fallback (default).
 		if text, ok := room.Fallbacks["default"]; ok {
-			return []types.Effect{sayEffect(text)}
+			return text, "room:" + s.Player.Location + " default fallback"
 		}
 	}
 
-	// 4. Global default.
-	return []types.Effect{sayEffect("You can't do that.")}
+	// 4. A verb nothing knows gets the next unknown-verb response.
+	if responses := unknownVerbResponses(defs); len(responses) > 0 && !KnownVerb(defs, verb) {
+		return markup.Fail(responses[s.TurnCount%len(responses)]), "unknown verb responses"
+	}
+
+	// 5. Global default.
+	return markup.Fail(messages.Text(defs.Messages, "cant_do")), "cant_do message"
+}
+
+// DefaultUnknownVerbResponses are the replies to verbs nothing knows, taken
+// in turn, for games that neither set their own nor reword cant_do.
+var DefaultUnknownVerbResponses = []string{
+	"That's not a verb I recognise.",
+	"You consider doing that, then think better of it.",
+	"Nothing in your experience suggests how to do that.",
+	"That word means nothing here. Try something else.",
+	"You'd need to explain that one to yourself first.",
+}
+
+// unknownVerbResponses returns the game's unknown-verb responses, or the
+// defaults. A game that rewords cant_do, say in another language, gets
+// cant_do instead of the English defaults.
+func unknownVerbResponses(defs *state.Defs) []string {
+	if len(defs.Game.UnknownVerbResponses) > 0 {
+		return defs.Game.UnknownVerbResponses
+	}
+	if _, reworded := defs.Messages["cant_do"]; reworded {
+		return nil
+	}
+	return DefaultUnknownVerbResponses
+}
+
+// KnownVerb reports whether the parser knows verb or some rule uses it.
+func KnownVerb(defs *state.Defs, verb string) bool {
+	if parser.IsVerb(verb) {
+		return true
+	}
+	uses := func(rules []types.RuleDef) bool {
+		for _, r := range rules {
+			if r.When.Verb == verb {
+				return true
+			}
+		}
+		return false
+	}
+	if uses(defs.GlobalRules) {
+		return true
+	}
+	for _, room := range defs.Rooms {
+		if uses(room.Rules) {
+			return true
+		}
+	}
+	for _, ent := range defs.Entities {
+		if uses(ent.Rules) {
+			return true
+		}
+	}
+	return false
 }
 
 func sayEffect(text string) types.Effect {
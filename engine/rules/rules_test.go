@@ -263,7 +263,7 @@ func TestEvaluate_Fallback_GlobalDefault(t *testing.T) {
 		Entities: map[string]types.EntityDef{},
 	}
 	s := state.NewState(defs)
-	intent := types.Intent{Verb: "dance"}
+	intent := types.Intent{Verb: "push"}
 
 	effects, matched := Evaluate(s, defs, intent, "", "")
 	if matched {
@@ -273,8 +273,51 @@ func TestEvaluate_Fallback_GlobalDefault(t *testing.T) {
 		t.Fatalf("expected 1 effect, got %d", len(effects))
 	}
 	text, _ := effects[0].Params["text"].(string)
-	if text != "You can't do that." {
-		t.Errorf("expected global default fallback, got %q", text)
+	if text != "! You can't do that." {
+		t.Errorf("expected global default fallback, marked as a failure, got %q", text)
+	}
+}
+
+func TestEvaluate_Fallback_UnknownVerb(t *testing.T) {
+	defs := &state.Defs{
+		Game:     types.GameDef{Start: "empty_room"},
+		Rooms:    map[string]types.RoomDef{"empty_room": {ID: "empty_room"}},
+		Entities: map[string]types.EntityDef{},
+	}
+	s := state.NewState(defs)
+	say := func(verb string) string {
+		effects, _ := Evaluate(s, defs, types.Intent{Verb: verb}, "", "")
+		text, _ := effects[0].Params["text"].(string)
+		return text
+	}
+
+	// The default responses, taken in turn.
+	for turn := range 2 {
+		s.TurnCount = turn
+		if got, want := say("dance"), "! "+DefaultUnknownVerbResponses[turn]; got != want {
+			t.Errorf("turn %d: got %q, want %q", turn, got, want)
+		}
+	}
+
+	// The game's own responses cycle.
+	defs.Game.UnknownVerbResponses = []string{"Eh?", "Pardon?"}
+	s.TurnCount = 3
+	if got := say("dance"); got != "! Pardon?" {
+		t.Errorf("got %q, want the game's second response", got)
+	}
+
+	// A verb some rule uses isn't unknown.
+	defs.GlobalRules = []types.RuleDef{{ID: "r", When: types.MatchCriteria{Verb: "dance", Object: "bear"}}}
+	if got := say("dance"); got != "! You can't do that." {
+		t.Errorf("got %q, want cant_do for a verb a rule uses", got)
+	}
+
+	// A game that rewords cant_do keeps it, without responses of its own.
+	defs.GlobalRules = nil
+	defs.Game.UnknownVerbResponses = nil
+	defs.Messages = map[string]string{"cant_do": "Impossible."}
+	if got := say("dance"); got != "! Impossible." {
+		t.Errorf("got %q, want the reworded cant_do", got)
 	}
 }
 
@@ -385,3 +428,96 @@ func TestEvaluate_SourceOrderBreaksTie(t *testing.T) {
 		t.Errorf("expected earlier source order to win, got %q", text)
 	}
 }
+
+func TestEvaluate_OnceRule(t *testing.T) {
+	defs := pipelineDefs()
+	room := defs.Rooms["hall"]
+	room.Rules[0].Once = true
+	defs.Rooms["hall"] = room
+	s := state.NewState(defs)
+	intent := types.Intent{Verb: "take", Object: "rusty_key"}
+
+	effects, matched := Evaluate(s, defs, intent, "rusty_key", "")
+	if !matched {
+		t.Fatal("expected matched=true")
+	}
+	if len(effects) != 2 || effects[0].Type != "mark_fired" || effects[0].Params["rule"] != "room_take_key" {
+		t.Fatalf("expected mark_fired before the rule's effects, got %v", effects)
+	}
+
+	// Once fired, the rule no longer matches and the next one wins.
+	s.Fired["room_take_key"] = true
+	effects, _ = Evaluate(s, defs, intent, "rusty_key", "")
+	if text, _ := effects[0].Params["text"].(string); text == "You carefully pick up the rusty key." {
+		t.Error("expected fired once rule to be skipped")
+	}
+}
+
+func TestEvaluateChain_ContinueFallsThrough(t *testing.T) {
+	defs := pipelineDefs()
+	room := defs.Rooms["hall"]
+	room.Rules[0].Effects = append(room.Rules[0].Effects, types.Effect{Type: "continue"})
+	defs.Rooms["hall"] = room
+	s := state.NewState(defs)
+	intent := types.Intent{Verb: "take", Object: "rusty_key"}
+
+	pre, effs, matched := EvaluateChain(s, defs, intent, "rusty_key", "")
+	if len(pre) != 2 || pre[0].Params["text"] != "You carefully pick up the rusty key." {
+		t.Errorf("pre = %v, want the continuing room rule's effects", pre)
+	}
+	if !matched || len(effs) == 0 {
+		t.Fatalf("expected a lower-priority rule to match, got %v %v", effs, matched)
+	}
+	if effs[0].Params["text"] == "You carefully pick up the rusty key." {
+		t.Error("continuing rule must not also be the final match")
+	}
+
+	flat, _ := Evaluate(s, defs, intent, "rusty_key", "")
+	if len(flat) != len(pre)+len(effs) {
+		t.Errorf("Evaluate returned %d effects, want pre followed by effs", len(flat))
+	}
+}
+
+func TestEvaluateChain_ContinueToBuiltin(t *testing.T) {
+	defs := pipelineDefs()
+	defs.GlobalRules = nil
+	room := defs.Rooms["hall"]
+	room.Rules[0].Effects = append(room.Rules[0].Effects, types.Effect{Type: "continue"})
+	defs.Rooms["hall"] = room
+	s := state.NewState(defs)
+
+	pre, _, matched := EvaluateChain(s, defs, types.Intent{Verb: "take", Object: "rusty_key"}, "rusty_key", "")
+	if matched {
+		t.Error("expected no final match, so built-in behavior runs")
+	}
+	if len(pre) != 2 {
+		t.Errorf("pre = %v, want the continuing rule's effects", pre)
+	}
+}
+
+func TestEvaluate_IndexedMatchesScan(t *testing.T) {
+	indexed := pipelineDefs()
+	indexed.IndexRules()
+	scanned := pipelineDefs()
+
+	s := state.NewState(scanned)
+	s.Player.Inventory = []string{"rusty_key"}
+	tests := []struct {
+		verb, object, target string
+	}{
+		{"take", "rusty_key", ""},
+		{"examine", "rusty_key", ""},
+		{"use", "rusty_key", "iron_door"},
+		{"look", "", ""},
+		{"push", "iron_door", ""},
+		{"dance", "", ""},
+	}
+	for _, tt := range tests {
+		intent := types.Intent{Verb: tt.verb, Object: tt.object, Target: tt.target}
+		want, wantMatched := Evaluate(s, scanned, intent, tt.object, tt.target)
+		got, matched := Evaluate(s, indexed, intent, tt.object, tt.target)
+		if matched != wantMatched || len(got) != len(want) || (len(got) > 0 && got[0].Params["text"] != want[0].Params["text"]) {
+			t.Errorf("%s %s %s: indexed %v %v, scanned %v %v", tt.verb, tt.object, tt.target, got, matched, want, wantMatched)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package rules
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// ForEachDef calls fn with the conditions and effects of every rule,
+// topic, reaction, item response, enemy behavior and attack, handler, exit
+// guard, amusing entry, idle nudge, computed case, answer and meta-command,
+// in a deterministic order. subject names the definition as the loader's
+// diagnostics do, such as "rule:open_gate" or "handler:2".
+func ForEachDef(defs *state.Defs, fn func(subject string, conds []types.Condition, effs []types.Effect)) {
+	for _, r := range AllRules(defs) {
+		fn("rule:"+r.ID, r.Conditions, r.Effects)
+	}
+	for _, id := range slices.Sorted(maps.Keys(defs.Entities)) {
+		ent := defs.Entities[id]
+		for _, key := range slices.Sorted(maps.Keys(ent.Topics)) {
+			fn("entity:"+id, ent.Topics[key].Requires, ent.Topics[key].Effects)
+		}
+		for _, r := range ent.Reactions {
+			fn("entity:"+id, r.Conditions, r.Effects)
+		}
+		for _, responses := range []map[string]types.ItemResponseDef{ent.OnReceive, ent.OnShow} {
+			for _, item := range slices.Sorted(maps.Keys(responses)) {
+				fn("entity:"+id, responses[item].Conditions, responses[item].Effects)
+			}
+		}
+		behavior, _ := ent.Props["behavior"].([]types.BehaviorEntry)
+		for _, b := range behavior {
+			fn("entity:"+id, BindSelf(b.When, id), nil)
+		}
+		if effs := AttackEffects(ent); effs != nil {
+			fn("entity:"+id, nil, effs)
+		}
+	}
+	for i, h := range defs.Handlers {
+		fn(fmt.Sprintf("handler:%d", i), h.Conditions, h.Effects)
+	}
+	for _, id := range slices.Sorted(maps.Keys(defs.Rooms)) {
+		guards := defs.Rooms[id].ExitGuards
+		for _, dir := range slices.Sorted(maps.Keys(guards)) {
+			fn("room:"+id, guards[dir].Requires, nil)
+		}
+	}
+	for _, a := range defs.Game.Amusing {
+		fn("game", a.Conditions, nil)
+	}
+	if idle := defs.Game.IdleNudge; idle != nil {
+		for _, n := range idle.Nudges {
+			fn("game", n.Conditions, nil)
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(defs.Computed)) {
+		for _, c := range defs.Computed[id].Cases {
+			fn("computed:"+id, c.When, nil)
+		}
+	}
+	for _, id := range slices.Sorted(maps.Keys(defs.Answers)) {
+		a := defs.Answers[id]
+		fn("answer:"+id, nil, append(append([]types.Effect{}, a.Success...), a.Failure...))
+	}
+	for _, name := range slices.Sorted(maps.Keys(defs.MetaCommands)) {
+		fn("meta:"+name, nil, defs.MetaCommands[name].Effects)
+	}
+}
+
+// AllRules returns the rules of every scope: the global ones, then each
+// room's and each entity's, by ID.
+func AllRules(defs *state.Defs) []types.RuleDef {
+	var all []types.RuleDef
+	all = append(all, defs.GlobalRules...)
+	for _, id := range slices.Sorted(maps.Keys(defs.Rooms)) {
+		all = append(all, defs.Rooms[id].Rules...)
+	}
+	for _, id := range slices.Sorted(maps.Keys(defs.Entities)) {
+		all = append(all, defs.Entities[id].Rules...)
+	}
+	return all
+}
+
+// AttackEffects returns the effects of an enemy's attacks beyond damage:
+// setting the flags of the statuses they inflict.
+func AttackEffects(ent types.EntityDef) []types.Effect {
+	attacks, _ := ent.Props["attacks"].([]types.AttackDef)
+	var effs []types.Effect
+	for _, a := range attacks {
+		if a.Effect != "" {
+			effs = append(effs, types.Effect{Type: "set_flag", Params: map[string]any{"flag": a.Effect, "value": true}})
+		}
+	}
+	return effs
+}
@@ -1,48 +1,238 @@
 // Package save implements JSON serialization and deserialization of game state.
+//
+// Saves are versioned by Format. Each names the game it belongs to and the
+// engine that wrote it, and holds only the entity state that differs from
+// the game's definitions. Older saves are upgraded on load by Migrate;
+// fields a newer engine added are ignored.
 package save
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
 
 	"github.com/nathoo/questcore/engine/state"
 	"github.com/nathoo/questcore/types"
 )
 
+// Format is the version of the save format that Save writes.
+const Format = 2
+
+// EngineVersion is recorded in saves as the version of the engine that wrote
+// them. The questcore command sets it to its own version.
+var EngineVersion = "dev"
+
 // SaveData is the JSON-serializable save format.
 type SaveData struct {
-	Version     string                       `json:"version"`
-	Game        string                       `json:"game"`
-	Turn        int                          `json:"turn"`
-	Player      types.Player                 `json:"player"`
-	Flags       map[string]bool              `json:"flags"`
-	Counters    map[string]int               `json:"counters"`
-	EntityState map[string]types.EntityState `json:"entity_state"`
-	RNGSeed     int64                        `json:"rng_seed"`
-	RNGPosition int64                        `json:"rng_position"`
-	Combat      types.CombatState            `json:"combat"`
-	CommandLog  []string                     `json:"command_log"`
+	Format       int                          `json:"format"`
+	Game         GameID                       `json:"game"`
+	Engine       string                       `json:"engine"` // EngineVersion of the writer ("" = unknown)
+	Turn         int                          `json:"turn"`
+	Player       types.Player                 `json:"player"`
+	Flags        map[string]bool              `json:"flags"`
+	Counters     map[string]int               `json:"counters"`
+	EntityState  map[string]types.EntityState `json:"entity_state"`
+	RNGSeed      int64                        `json:"rng_seed"`
+	RNGPosition  int64                        `json:"rng_position"`
+	Combat       types.CombatState            `json:"combat"`
+	CommandLog   []string                     `json:"command_log,omitempty"`
+	CommandCount int                          `json:"command_count"`
+	CommandHash  string                       `json:"command_hash,omitempty"`
+	Ending       string                       `json:"ending,omitempty"`
+	Pending      string                       `json:"pending,omitempty"`
+	Attempts     map[string]int               `json:"attempts,omitempty"`
+	Fired        map[string]bool              `json:"fired,omitempty"`
+	Visited      map[string]bool              `json:"visited"`
+	Examined     map[string]int               `json:"examined,omitempty"`
+	Heard        map[string]bool              `json:"heard,omitempty"`
+	Attached     map[string]string            `json:"attached,omitempty"`
+	Facts        []string                     `json:"facts,omitempty"`
+	ActivePlayer string                       `json:"active_player,omitempty"`
+	Players      map[string]types.Player      `json:"players,omitempty"`
+	Verbosity    string                       `json:"verbosity,omitempty"`
+	Clock        int                          `json:"clock,omitempty"`
+	Music        string                       `json:"music,omitempty"`
+	AmbienceWait int                          `json:"ambience_wait,omitempty"`
+	Stats        types.Stats                  `json:"stats"`
+}
+
+// GameID identifies the game a save belongs to.
+type GameID struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// CheckGame reports an error if the save belongs to a game other than
+// defs. Saves from other versions of the same game are accepted.
+func (sd *SaveData) CheckGame(defs *state.Defs) error {
+	if sd.Game.Title != "" && sd.Game.Title != defs.Game.Title {
+		return fmt.Errorf("the save is for %q, not %q", sd.Game.Title, defs.Game.Title)
+	}
+	return nil
 }
 
 // Save serializes game state to JSON bytes.
 func Save(s *types.State, defs *state.Defs) ([]byte, error) {
-	data := SaveData{
-		Version:     defs.Game.Version,
-		Game:        defs.Game.Title,
-		Turn:        s.TurnCount,
-		Player:      s.Player,
-		Flags:       s.Flags,
-		Counters:    s.Counters,
-		EntityState: s.Entities,
-		RNGSeed:     s.RNGSeed,
-		RNGPosition: s.RNGPosition,
-		Combat:      s.Combat,
-		CommandLog:  s.CommandLog,
-	}
-	return json.MarshalIndent(data, "", "  ")
+	return json.MarshalIndent(newSaveData(s, defs), "", "  ")
+}
+
+// Export serializes game state to a compact code — gzipped JSON in URL-safe
+// base64 — that players can copy and paste instead of keeping a save file.
+func Export(s *types.State, defs *state.Defs) (string, error) {
+	data, err := json.Marshal(newSaveData(s, defs))
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := zw.Write(data); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// Import decodes a code produced by Export. Whitespace in the code is
+// ignored, so codes that were wrapped across lines still load.
+func Import(code string) (*SaveData, error) {
+	code = strings.Join(strings.Fields(code), "")
+	raw, err := base64.RawURLEncoding.DecodeString(code)
+	if err != nil {
+		return nil, errors.New("invalid save code")
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, errors.New("invalid save code")
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, errors.New("invalid save code")
+	}
+	return Load(data)
+}
+
+func newSaveData(s *types.State, defs *state.Defs) SaveData {
+	log := s.CommandLog
+	if defs.Game.CompactSaves {
+		log = nil // the count and hash still identify the session
+	}
+	return SaveData{
+		Format:       Format,
+		Game:         GameID{Title: defs.Game.Title, Version: defs.Game.Version},
+		Engine:       EngineVersion,
+		Turn:         s.TurnCount,
+		Player:       s.Player,
+		Flags:        s.Flags,
+		Counters:     s.Counters,
+		EntityState:  entityDiff(s, defs),
+		RNGSeed:      s.RNGSeed,
+		RNGPosition:  s.RNGPosition,
+		Combat:       s.Combat,
+		CommandLog:   log,
+		CommandCount: s.CommandCount,
+		CommandHash:  s.CommandHash,
+		Ending:       s.Ending,
+		Pending:      s.Pending,
+		Attempts:     s.Attempts,
+		Fired:        s.Fired,
+		Visited:      s.Visited,
+		Examined:     s.Examined,
+		Heard:        s.Heard,
+		Attached:     s.Attached,
+		Facts:        s.Facts,
+		ActivePlayer: s.ActivePlayer,
+		Players:      s.Players,
+		Verbosity:    s.Verbosity,
+		Clock:        s.Clock,
+		Music:        s.Music,
+		AmbienceWait: s.AmbienceWait,
+		Stats:        s.Stats,
+	}
+}
+
+// entityDiff returns the entity state that differs from the definitions:
+// locations other than the base one, and props other than the base values.
+// Entities left as defined are omitted.
+func entityDiff(s *types.State, defs *state.Defs) map[string]types.EntityState {
+	diff := map[string]types.EntityState{}
+	for id, es := range s.Entities {
+		def := defs.Entities[id]
+		var d types.EntityState
+		if base, _ := def.Props["location"].(string); es.Location != base {
+			d.Location = es.Location
+		}
+		for prop, value := range es.Props {
+			if base, ok := def.Props[prop]; ok && reflect.DeepEqual(base, value) {
+				continue
+			}
+			if d.Props == nil {
+				d.Props = map[string]any{}
+			}
+			d.Props[prop] = value
+		}
+		if d.Location != "" || d.Props != nil {
+			diff[id] = d
+		}
+	}
+	return diff
 }
 
-// Load deserializes JSON bytes into SaveData.
+// Migrate upgrades a save of any earlier format to the current one. It
+// returns an error for a save from a newer engine, whose format it can't
+// know.
+//
+// Format 1 saves had no format field, and named the game by its title in
+// "game" and its version in "version".
+func Migrate(data []byte) ([]byte, error) {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	format := 1
+	if raw, ok := m["format"]; ok {
+		if err := json.Unmarshal(raw, &format); err != nil {
+			return nil, fmt.Errorf("invalid save format: %w", err)
+		}
+	}
+	switch {
+	case format > Format:
+		return nil, fmt.Errorf("the save is in format %d, from a newer engine (this one reads up to %d)", format, Format)
+	case format == Format:
+		return data, nil
+	}
+
+	// 1 → 2.
+	var title, version string
+	json.Unmarshal(m["game"], &title)
+	json.Unmarshal(m["version"], &version)
+	game, err := json.Marshal(GameID{Title: title, Version: version})
+	if err != nil {
+		return nil, err
+	}
+	m["game"] = game
+	delete(m, "version")
+	m["format"] = json.RawMessage("2")
+	return json.Marshal(m)
+}
+
+// Load deserializes JSON bytes into SaveData, upgrading older formats with
+// Migrate.
 func Load(data []byte) (*SaveData, error) {
+	data, err := Migrate(data)
+	if err != nil {
+		return nil, err
+	}
 	var sd SaveData
 	if err := json.Unmarshal(data, &sd); err != nil {
 		return nil, err
@@ -66,6 +256,28 @@ func Load(data []byte) (*SaveData, error) {
 	if sd.CommandLog == nil {
 		sd.CommandLog = []string{}
 	}
+	if sd.CommandCount == 0 && len(sd.CommandLog) > 0 {
+		// Saves from before the count and hash hold the full log.
+		sd.CommandCount = len(sd.CommandLog)
+		for _, cmd := range sd.CommandLog {
+			sd.CommandHash = state.HashCommand(sd.CommandHash, cmd)
+		}
+	}
+	if sd.Visited == nil {
+		sd.Visited = map[string]bool{}
+	}
+	if sd.Examined == nil {
+		sd.Examined = map[string]int{}
+	}
+	if sd.Heard == nil {
+		sd.Heard = map[string]bool{}
+	}
+	if sd.Attempts == nil {
+		sd.Attempts = map[string]int{}
+	}
+	if sd.Fired == nil {
+		sd.Fired = map[string]bool{}
+	}
 	return &sd, nil
 }
 
@@ -75,9 +287,28 @@ func ApplySave(s *types.State, sd *SaveData) {
 	s.Flags = sd.Flags
 	s.Counters = sd.Counters
 	s.Entities = sd.EntityState
+	s.Locations = nil // rebuilt from the loaded entities when next needed
 	s.TurnCount = sd.Turn
 	s.RNGSeed = sd.RNGSeed
 	s.RNGPosition = sd.RNGPosition
 	s.Combat = sd.Combat
 	s.CommandLog = sd.CommandLog
+	s.CommandCount = sd.CommandCount
+	s.CommandHash = sd.CommandHash
+	s.Ending = sd.Ending
+	s.Pending = sd.Pending
+	s.Attempts = sd.Attempts
+	s.Fired = sd.Fired
+	s.Visited = sd.Visited
+	s.Examined = sd.Examined
+	s.Heard = sd.Heard
+	s.Attached = sd.Attached
+	s.Facts = sd.Facts
+	s.ActivePlayer = sd.ActivePlayer
+	s.Players = sd.Players
+	s.Verbosity = sd.Verbosity
+	s.Clock = sd.Clock
+	s.Music = sd.Music
+	s.AmbienceWait = sd.AmbienceWait
+	s.Stats = sd.Stats
 }
@@ -2,6 +2,7 @@ package save
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/nathoo/questcore/engine/state"
@@ -37,6 +38,18 @@ func TestRoundTrip(t *testing.T) {
 	s.TurnCount = 7
 	s.RNGSeed = 42
 	s.CommandLog = []string{"go north", "take key"}
+	s.Pending = "ending_menu"
+	s.Attempts["riddle"] = 2
+	s.Fired["first_visit"] = true
+	s.Visited["garden"] = true
+	s.Examined["key"] = 2
+	s.Heard["guard.gate"] = true
+	s.Verbosity = "brief"
+	s.Clock = 1930
+	s.Music = "garden_theme"
+	s.ActivePlayer = "alice"
+	s.Facts = []string{"password"}
+	s.Players = map[string]types.Player{"bob": {Location: "hall", Inventory: []string{"lamp"}}}
 	s.Entities["key"] = types.EntityState{
 		Location: " ",
 		Props:    map[string]any{"shiny": true},
@@ -74,6 +87,36 @@ func TestRoundTrip(t *testing.T) {
 	if s2.Counters["visits"] != 3 {
 		t.Errorf("expected visits 3, got %d", s2.Counters["visits"])
 	}
+	if !s2.Visited["garden"] || s2.Verbosity != "brief" {
+		t.Errorf("expected visited garden and brief mode, got %v %q", s2.Visited, s2.Verbosity)
+	}
+	if s2.Examined["key"] != 2 {
+		t.Errorf("expected key examined twice, got %v", s2.Examined)
+	}
+	if !s2.Heard["guard.gate"] {
+		t.Errorf("expected guard.gate heard, got %v", s2.Heard)
+	}
+	if s2.Clock != 1930 {
+		t.Errorf("expected clock 1930, got %d", s2.Clock)
+	}
+	if !state.KnowsFact(s2, "password") {
+		t.Errorf("expected the password known, got %v", s2.Facts)
+	}
+	if bob := s2.Players["bob"]; s2.ActivePlayer != "alice" || bob.Location != "hall" || len(bob.Inventory) != 1 {
+		t.Errorf("expected alice active and bob in hall with the lamp, got %q %+v", s2.ActivePlayer, s2.Players)
+	}
+	if s2.Music != "garden_theme" {
+		t.Errorf("expected music garden_theme, got %q", s2.Music)
+	}
+	if s2.Pending != "ending_menu" {
+		t.Errorf("expected pending 'ending_menu', got %q", s2.Pending)
+	}
+	if s2.Attempts["riddle"] != 2 {
+		t.Errorf("expected 2 riddle attempts, got %d", s2.Attempts["riddle"])
+	}
+	if !s2.Fired["first_visit"] {
+		t.Error("expected once rule first_visit to stay fired")
+	}
 	if s2.TurnCount != 7 {
 		t.Errorf("expected turn 7, got %d", s2.TurnCount)
 	}
@@ -111,11 +154,98 @@ func TestSave_ProducesValidJSON(t *testing.T) {
 	// Verify game metadata.
 	var raw map[string]any
 	json.Unmarshal(data, &raw)
-	if raw["version"] != "1.0" {
-		t.Errorf("expected version '1.0', got %v", raw["version"])
+	if raw["format"] != float64(Format) {
+		t.Errorf("expected format %d, got %v", Format, raw["format"])
+	}
+	game, _ := raw["game"].(map[string]any)
+	if game["title"] != "Test Game" || game["version"] != "1.0" {
+		t.Errorf("expected game 'Test Game' 1.0, got %v", raw["game"])
+	}
+	if raw["engine"] != EngineVersion {
+		t.Errorf("expected engine %q, got %v", EngineVersion, raw["engine"])
+	}
+}
+
+func TestSave_EntityDiff(t *testing.T) {
+	defs := testDefs()
+	s := state.NewState(defs)
+	// Unchanged: set to the values they were defined with.
+	s.Entities["key"] = types.EntityState{Location: "hall", Props: map[string]any{"name": "Key"}}
+
+	data, _ := Save(s, defs)
+	sd, err := Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sd.EntityState["key"]; ok {
+		t.Errorf("unchanged entity saved: %+v", sd.EntityState["key"])
+	}
+
+	// Changed: only the differences are saved.
+	s.Entities["key"] = types.EntityState{Location: "garden", Props: map[string]any{"name": "Key", "rusty": true}}
+	data, _ = Save(s, defs)
+	sd, _ = Load(data)
+	key := sd.EntityState["key"]
+	if key.Location != "garden" || len(key.Props) != 1 || key.Props["rusty"] != true {
+		t.Errorf("key = %+v, want the garden and rusty only", key)
+	}
+
+	s2 := state.NewState(defs)
+	ApplySave(s2, sd)
+	if got := state.EntityLocation(s2, defs, "key"); got != "garden" {
+		t.Errorf("key location = %q, want garden", got)
 	}
-	if raw["game"] != "Test Game" {
-		t.Errorf("expected game 'Test Game', got %v", raw["game"])
+	if v, _ := state.GetEntityProp(s2, defs, "key", "name"); v != "Key" {
+		t.Errorf("key name = %v, want Key from the definition", v)
+	}
+}
+
+func TestMigrate_V1(t *testing.T) {
+	v1 := []byte(`{"version":"1.0","game":"Test Game","turn":3,"player":{"Location":"hall"},
+		"entity_state":{"key":{"Location":"","Props":{"rusty":true}}},"later_field":true}`)
+	data, err := Migrate(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sd.Format != Format || sd.Game != (GameID{Title: "Test Game", Version: "1.0"}) || sd.Engine != "" {
+		t.Errorf("envelope = %d %+v %q", sd.Format, sd.Game, sd.Engine)
+	}
+	if sd.Turn != 3 || sd.EntityState["key"].Props["rusty"] != true {
+		t.Errorf("state lost: turn %d, entities %v", sd.Turn, sd.EntityState)
+	}
+
+	// Load migrates by itself, and a current save passes through as is.
+	direct, _ := Load(v1)
+	if direct.Game != sd.Game {
+		t.Errorf("Load game = %+v, want %+v", direct.Game, sd.Game)
+	}
+	again, _ := Migrate(data)
+	if string(again) != string(data) {
+		t.Error("migrating a current save changed it")
+	}
+}
+
+func TestMigrate_NewerFormat(t *testing.T) {
+	_, err := Load([]byte(`{"format":99,"game":{"title":"Test Game"}}`))
+	if err == nil || !strings.Contains(err.Error(), "format 99") {
+		t.Errorf("err = %v, want a newer-format error", err)
+	}
+}
+
+func TestCheckGame(t *testing.T) {
+	defs := testDefs()
+	if err := (&SaveData{Game: GameID{Title: "Test Game", Version: "0.9"}}).CheckGame(defs); err != nil {
+		t.Errorf("another version of the same game refused: %v", err)
+	}
+	if err := (&SaveData{}).CheckGame(defs); err != nil {
+		t.Errorf("untitled save refused: %v", err)
+	}
+	if err := (&SaveData{Game: GameID{Title: "Other"}}).CheckGame(defs); err == nil {
+		t.Error("another game's save accepted")
 	}
 }
 
@@ -264,3 +394,101 @@ func TestEntityState_PreservedThroughRoundTrip(t *testing.T) {
 		t.Errorf("expected visible=false, got %v", es.Props["visible"])
 	}
 }
+
+func TestApplySave_RebuildsLocationIndex(t *testing.T) {
+	defs := testDefs()
+	s := state.NewState(defs)
+	state.SetEntityLocation(s, defs, "key", "garden")
+	data, err := Save(s, defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd, err := Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2 := state.NewState(defs)
+	if got := state.EntitiesInRoom(s2, defs, "hall"); len(got) != 1 {
+		t.Fatalf("hall = %v before loading, want the key", got)
+	}
+	ApplySave(s2, sd)
+	if got := state.EntitiesInRoom(s2, defs, "garden"); len(got) != 1 || got[0] != "key" {
+		t.Errorf("garden = %v after loading, want [key]", got)
+	}
+	if got := state.EntitiesInRoom(s2, defs, "hall"); len(got) != 0 {
+		t.Errorf("hall = %v after loading, want it empty", got)
+	}
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	defs := testDefs()
+	s := state.NewState(defs)
+	s.Player.Location = "garden"
+	s.Flags["door_open"] = true
+	s.TurnCount = 12
+
+	code, err := Export(s, defs)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if strings.ContainsAny(code, " \n+/=") {
+		t.Errorf("code %q should be URL-safe base64 without padding", code)
+	}
+
+	// Codes wrapped across lines by a chat client still import.
+	wrapped := code[:10] + "\n  " + code[10:]
+	sd, err := Import(wrapped)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	s2 := state.NewState(defs)
+	ApplySave(s2, sd)
+	if s2.Player.Location != "garden" || !s2.Flags["door_open"] || s2.TurnCount != 12 {
+		t.Errorf("imported state = %+v", s2)
+	}
+}
+
+func TestImport_InvalidCode(t *testing.T) {
+	for _, code := range []string{"", "not a code!", "aGVsbG8"} {
+		if _, err := Import(code); err == nil {
+			t.Errorf("Import(%q): expected error", code)
+		}
+	}
+}
+
+func TestSave_CompactSavesOmitCommandLog(t *testing.T) {
+	defs := testDefs()
+	defs.Game.CompactSaves = true
+	s := state.NewState(defs)
+	state.LogCommand(s, "look", 0)
+	state.LogCommand(s, "north", 0)
+
+	data, err := Save(s, defs)
+	if err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if strings.Contains(string(data), "command_log") {
+		t.Errorf("compact save should not include the command log:\n%s", data)
+	}
+
+	sd, err := Load(data)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(sd.CommandLog) != 0 || sd.CommandCount != 2 || sd.CommandHash != s.CommandHash {
+		t.Errorf("got log %v, count %d, hash %q; want empty, 2, %q",
+			sd.CommandLog, sd.CommandCount, sd.CommandHash, s.CommandHash)
+	}
+}
+
+func TestLoad_OldSaveBackfillsCommandHash(t *testing.T) {
+	sd, err := Load([]byte(`{"command_log": ["look", "north"]}`))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	want := state.HashCommand(state.HashCommand("", "look"), "north")
+	if sd.CommandCount != 2 || sd.CommandHash != want {
+		t.Errorf("count %d, hash %q; want 2, %q", sd.CommandCount, sd.CommandHash, want)
+	}
+}
@@ -0,0 +1,58 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// minutesPerDay is the length of an in-game day.
+const minutesPerDay = 24 * 60
+
+// TimeOfDay returns the clock's time of day in minutes after midnight.
+func TimeOfDay(s *types.State) int {
+	return s.Clock % minutesPerDay
+}
+
+// Day returns the current in-game day, starting at 1.
+func Day(s *types.State) int {
+	return s.Clock/minutesPerDay + 1
+}
+
+// IsNight reports whether it is night: from dusk until dawn. Games without
+// a clock are never at night.
+func IsNight(s *types.State, defs *Defs) bool {
+	c := defs.Game.Clock
+	if c == nil {
+		return false
+	}
+	return !TimeBetween(TimeOfDay(s), c.Dawn, c.Dusk)
+}
+
+// TimeBetween reports whether the time of day t is in [from, to), wrapping
+// past midnight when from is later than to ("22:00" to "04:00").
+func TimeBetween(t, from, to int) bool {
+	if from <= to {
+		return t >= from && t < to
+	}
+	return t >= from || t < to
+}
+
+// ParseClock parses a time of day written "HH:MM" (24-hour) into minutes
+// after midnight.
+func ParseClock(str string) (int, bool) {
+	var h, m int
+	if n, err := fmt.Sscanf(str, "%d:%d", &h, &m); err != nil || n != 2 {
+		return 0, false
+	}
+	if len(str) != 5 || h < 0 || h > 23 || m < 0 || m > 59 {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+// FormatClock writes minutes after midnight as "HH:MM".
+func FormatClock(minutes int) string {
+	minutes %= minutesPerDay
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
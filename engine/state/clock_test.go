@@ -0,0 +1,43 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+		ok   bool
+	}{
+		{"00:00", 0, true},
+		{"08:30", 8*60 + 30, true},
+		{"23:59", 23*60 + 59, true},
+		{"24:00", 0, false},
+		{"8:30", 0, false},
+		{"08:60", 0, false},
+		{"noon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseClock(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseClock(%q) = %d, %v; want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestClock_DayAndTimeOfDay(t *testing.T) {
+	defs := &Defs{Game: types.GameDef{Start: "hall", Clock: &types.ClockDef{Start: 22 * 60, MinutesPerTurn: 5, Dawn: 6 * 60, Dusk: 20 * 60}}}
+	s := NewState(defs)
+	if s.Clock != 22*60 || Day(s) != 1 || !IsNight(s, defs) {
+		t.Errorf("start: clock %d, day %d, night %v", s.Clock, Day(s), IsNight(s, defs))
+	}
+
+	s.Clock += 9 * 60 // 07:00 the next morning
+	if Day(s) != 2 || FormatClock(TimeOfDay(s)) != "07:00" || IsNight(s, defs) {
+		t.Errorf("next morning: day %d, time %s, night %v", Day(s), FormatClock(TimeOfDay(s)), IsNight(s, defs))
+	}
+}
@@ -0,0 +1,85 @@
+package state
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// Clone returns a deep copy of s: changing either afterwards leaves the
+// other as it was. It is cheap next to a save's JSON round trip, so a clone
+// can be taken every turn, for undo, say, or to try a command out and see
+// what it would do.
+func Clone(s *types.State) *types.State {
+	c := *s
+	c.Player = clonePlayer(s.Player)
+	if s.Players != nil {
+		c.Players = make(map[string]types.Player, len(s.Players))
+		for id, p := range s.Players {
+			c.Players[id] = clonePlayer(p)
+		}
+	}
+	if s.Entities != nil {
+		c.Entities = make(map[string]types.EntityState, len(s.Entities))
+		for id, es := range s.Entities {
+			es.Props = cloneProps(es.Props)
+			c.Entities[id] = es
+		}
+	}
+	c.Flags = maps.Clone(s.Flags)
+	c.Counters = maps.Clone(s.Counters)
+	c.CommandLog = slices.Clone(s.CommandLog)
+	c.Attempts = maps.Clone(s.Attempts)
+	c.Fired = maps.Clone(s.Fired)
+	c.Visited = maps.Clone(s.Visited)
+	c.Examined = maps.Clone(s.Examined)
+	c.Heard = maps.Clone(s.Heard)
+	c.Attached = maps.Clone(s.Attached)
+	c.Facts = slices.Clone(s.Facts)
+	c.Achievements = maps.Clone(s.Achievements)
+	if s.Locations != nil {
+		c.Locations = make(map[string]map[string]bool, len(s.Locations))
+		for room, ids := range s.Locations {
+			c.Locations[room] = maps.Clone(ids)
+		}
+	}
+	return &c
+}
+
+// clonePlayer deep-copies a player character.
+func clonePlayer(p types.Player) types.Player {
+	p.Inventory = slices.Clone(p.Inventory)
+	p.Worn = slices.Clone(p.Worn)
+	p.Stats = maps.Clone(p.Stats)
+	p.Quantities = maps.Clone(p.Quantities)
+	return p
+}
+
+// cloneProps deep-copies entity props, whose values may be tables from Lua.
+func cloneProps(props map[string]any) map[string]any {
+	if props == nil {
+		return nil
+	}
+	c := make(map[string]any, len(props))
+	for k, v := range props {
+		c[k] = cloneValue(v)
+	}
+	return c
+}
+
+func cloneValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return cloneProps(v)
+	case []any:
+		c := make([]any, len(v))
+		for i, x := range v {
+			c[i] = cloneValue(x)
+		}
+		return c
+	case []string:
+		return slices.Clone(v)
+	}
+	return v
+}
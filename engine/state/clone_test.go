@@ -0,0 +1,90 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func TestClone_Isolated(t *testing.T) {
+	defs := testDefs()
+	s := NewState(defs)
+	s.Player.Inventory = []string{"rusty_key"}
+	s.Player.Worn = []string{"rusty_key"}
+	s.Entities["guard"] = types.EntityState{Location: "hall", Props: map[string]any{
+		"mood": "calm", "notes": map[string]any{"seen": []any{"hall"}},
+	}}
+	s.Flags["door_open"] = true
+	s.Counters["gold"] = 5
+	s.CommandLog = []string{"look"}
+	s.Attempts = map[string]int{"riddle": 1}
+	s.Fired = map[string]bool{"intro": true}
+	s.Visited = map[string]bool{"entrance": true}
+	s.Achievements = map[string]bool{"first": true}
+	EntitiesInRoom(s, defs, "hall")
+
+	c := Clone(s)
+	c.Player.Inventory[0] = "x"
+	c.Player.Worn = append(c.Player.Worn[:0], "y")
+	c.Player.Stats["hp"] = 99
+	c.Entities["guard"].Props["mood"] = "angry"
+	c.Entities["guard"].Props["notes"].(map[string]any)["seen"].([]any)[0] = "cellar"
+	c.Flags["door_open"] = false
+	c.Counters["gold"] = 0
+	c.CommandLog[0] = "xyzzy"
+	c.Attempts["riddle"] = 3
+	c.Fired["intro"] = false
+	c.Visited["hall"] = true
+	c.Achievements["first"] = false
+	SetEntityLocation(c, defs, "guard", "entrance")
+
+	if s.Player.Inventory[0] != "rusty_key" || s.Player.Worn[0] != "rusty_key" || s.Player.Stats["hp"] == 99 {
+		t.Errorf("player changed: %+v", s.Player)
+	}
+	props := s.Entities["guard"].Props
+	if props["mood"] != "calm" || props["notes"].(map[string]any)["seen"].([]any)[0] != "hall" {
+		t.Errorf("entity props changed: %v", props)
+	}
+	if !s.Flags["door_open"] || s.Counters["gold"] != 5 || s.CommandLog[0] != "look" {
+		t.Error("flags, counters or command log changed")
+	}
+	if s.Attempts["riddle"] != 1 || !s.Fired["intro"] || s.Visited["hall"] || !s.Achievements["first"] {
+		t.Error("attempts, fired, visited or achievements changed")
+	}
+	if got := EntitiesInRoom(s, defs, "hall"); len(got) != 2 {
+		t.Errorf("hall = %v in the original, want the guard still there", got)
+	}
+}
+
+// TestClone_CopiesEveryField fails when State gains a map or slice that
+// Clone doesn't copy.
+func TestClone_CopiesEveryField(t *testing.T) {
+	defs := testDefs()
+	s := NewState(defs)
+	s.Attempts, s.Fired, s.Visited, s.Achievements = map[string]int{}, map[string]bool{}, map[string]bool{}, map[string]bool{}
+	s.Player.Inventory, s.Player.Worn, s.CommandLog = []string{"a"}, []string{"a"}, []string{"a"}
+	s.Player.Quantities = map[string]int{"a": 1}
+	s.Attached = map[string]string{"a": "b"}
+	s.Players = map[string]types.Player{"b": {}}
+	s.Facts = []string{"a"}
+	EntitiesInRoom(s, defs, "hall")
+	c := Clone(s)
+
+	var check func(path string, a, b reflect.Value)
+	check = func(path string, a, b reflect.Value) {
+		switch a.Kind() {
+		case reflect.Struct:
+			for i := 0; i < a.NumField(); i++ {
+				check(path+"."+a.Type().Field(i).Name, a.Field(i), b.Field(i))
+			}
+		case reflect.Map, reflect.Slice:
+			if a.IsNil() {
+				t.Errorf("%s is nil; set it above so the test covers it", path)
+			} else if a.UnsafePointer() == b.UnsafePointer() {
+				t.Errorf("%s is shared with the clone", path)
+			}
+		}
+	}
+	check("State", reflect.ValueOf(*s), reflect.ValueOf(*c))
+}
@@ -0,0 +1,77 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Freeze records a fingerprint of the definitions, for CheckFrozen to
+// compare against. The loader freezes every game it loads: from then on the
+// Defs may be shared by any number of engines, which only read them.
+func (d *Defs) Freeze() {
+	d.frozen = d.fingerprint()
+}
+
+// CheckFrozen reports an error naming the definitions that have changed
+// since Freeze, e.g. `Rooms["hall"]`, or nil if none have or the Defs were
+// never frozen. Engines built with the questcore_debug tag check after
+// every step.
+func (d *Defs) CheckFrozen() error {
+	if d.frozen == nil {
+		return nil
+	}
+	now := d.fingerprint()
+	var changed []string
+	for name, sum := range now {
+		if d.frozen[name] != sum {
+			changed = append(changed, name)
+		}
+	}
+	for name := range d.frozen {
+		if _, ok := now[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	slices.Sort(changed)
+	return fmt.Errorf("definitions changed after loading: %s", strings.Join(changed, ", "))
+}
+
+// fingerprint hashes each of the definitions: each entry of the map fields,
+// as `Rooms["hall"]`, and each other field whole.
+func (d *Defs) fingerprint() map[string][sha256.Size]byte {
+	sums := map[string][sha256.Size]byte{}
+	v := reflect.ValueOf(d).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := v.Field(i)
+		if fv.Kind() != reflect.Map {
+			sums[field.Name] = hash(fv.Interface())
+			continue
+		}
+		iter := fv.MapRange()
+		for iter.Next() {
+			sums[fmt.Sprintf("%s[%q]", field.Name, iter.Key())] = hash(iter.Value().Interface())
+		}
+	}
+	return sums
+}
+
+// hash returns a digest of v's JSON, which has map keys in order. Values
+// JSON can't hold hash as their Go syntax.
+func hash(v any) [sha256.Size]byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%#v", v))
+	}
+	return sha256.Sum256(data)
+}
@@ -0,0 +1,54 @@
+package state
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func freezeDefs() *Defs {
+	return &Defs{
+		Game:  types.GameDef{Title: "T", Start: "hall"},
+		Rooms: map[string]types.RoomDef{"hall": {ID: "hall", Exits: map[string]string{"north": "yard"}}, "yard": {ID: "yard"}},
+		Entities: map[string]types.EntityDef{
+			"lamp": {ID: "lamp", Props: map[string]any{"name": "lamp", "lines": []any{"a", "b"}}},
+		},
+	}
+}
+
+func TestCheckFrozen(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(d *Defs)
+		want   string // in the error ("" = none)
+	}{
+		{"unchanged", func(d *Defs) {}, ""},
+		{"exit added", func(d *Defs) { d.Rooms["hall"].Exits["up"] = "attic" }, `Rooms["hall"]`},
+		{"nested prop", func(d *Defs) { d.Entities["lamp"].Props["lines"].([]any)[0] = "z" }, `Entities["lamp"]`},
+		{"entry removed", func(d *Defs) { delete(d.Rooms, "yard") }, `Rooms["yard"]`},
+		{"field", func(d *Defs) { d.Game.Start = "yard" }, "Game"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := freezeDefs()
+			d.Freeze()
+			tt.change(d)
+			err := d.CheckFrozen()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("CheckFrozen = %v, want nil", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("CheckFrozen = %v, want it to name %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFrozen_NotFrozen(t *testing.T) {
+	d := freezeDefs()
+	d.Game.Start = "yard"
+	if err := d.CheckFrozen(); err != nil {
+		t.Errorf("CheckFrozen of unfrozen Defs = %v, want nil", err)
+	}
+}
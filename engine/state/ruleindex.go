@@ -0,0 +1,56 @@
+package state
+
+import (
+	"strings"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// ruleIndex holds each scope's rules grouped by verb, in declaration order.
+// Scopes are keyed as in RulesFor.
+type ruleIndex map[string]map[string][]types.RuleDef
+
+// IndexRules groups the rules of every scope by verb, so that RulesFor
+// returns only the rules that can match a command instead of all of them.
+// The loader calls it once a game is compiled. The rules must not change
+// afterwards unless IndexRules is called again.
+func (d *Defs) IndexRules() {
+	idx := ruleIndex{}
+	add := func(scope string, rules []types.RuleDef) {
+		if len(rules) == 0 {
+			return
+		}
+		byVerb := map[string][]types.RuleDef{}
+		for _, rule := range rules {
+			byVerb[rule.When.Verb] = append(byVerb[rule.When.Verb], rule)
+		}
+		idx[scope] = byVerb
+	}
+	add("global", d.GlobalRules)
+	for id, room := range d.Rooms {
+		add("room:"+id, room.Rules)
+	}
+	for id, ent := range d.Entities {
+		add("entity:"+id, ent.Rules)
+	}
+	d.rules = idx
+}
+
+// RulesFor returns the rules of scope ("global", "room:<id>" or
+// "entity:<id>") that may match verb, in declaration order. Once the rules
+// are indexed these are the scope's rules for verb; before, all of them.
+func (d *Defs) RulesFor(scope, verb string) []types.RuleDef {
+	if d.rules != nil {
+		return d.rules[scope][verb]
+	}
+	if id, ok := strings.CutPrefix(scope, "room:"); ok {
+		return d.Rooms[id].Rules
+	}
+	if id, ok := strings.CutPrefix(scope, "entity:"); ok {
+		return d.Entities[id].Rules
+	}
+	if scope == "global" {
+		return d.GlobalRules
+	}
+	return nil
+}
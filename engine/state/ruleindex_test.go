@@ -0,0 +1,57 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func TestRulesFor(t *testing.T) {
+	rule := func(id, verb string) types.RuleDef {
+		return types.RuleDef{ID: id, When: types.MatchCriteria{Verb: verb}}
+	}
+	defs := &Defs{
+		Rooms: map[string]types.RoomDef{
+			"hall": {ID: "hall", Rules: []types.RuleDef{rule("hall_take", "take"), rule("hall_look", "look")}},
+		},
+		Entities: map[string]types.EntityDef{
+			"box": {ID: "box", Rules: []types.RuleDef{rule("box_open", "open")}},
+		},
+		GlobalRules: []types.RuleDef{rule("take1", "take"), rule("drop", "drop"), rule("take2", "take")},
+	}
+
+	ids := func(rules []types.RuleDef) []string {
+		var out []string
+		for _, r := range rules {
+			out = append(out, r.ID)
+		}
+		return out
+	}
+	if got := ids(defs.RulesFor("global", "take")); len(got) != 3 {
+		t.Errorf("unindexed global rules = %v, want all of them", got)
+	}
+
+	defs.IndexRules()
+	tests := []struct {
+		scope, verb string
+		want        []string
+	}{
+		{"global", "take", []string{"take1", "take2"}},
+		{"room:hall", "look", []string{"hall_look"}},
+		{"entity:box", "open", []string{"box_open"}},
+		{"entity:box", "take", nil},
+		{"room:cellar", "take", nil},
+	}
+	for _, tt := range tests {
+		got := ids(defs.RulesFor(tt.scope, tt.verb))
+		if len(got) != len(tt.want) {
+			t.Errorf("RulesFor(%q, %q) = %v, want %v", tt.scope, tt.verb, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("RulesFor(%q, %q) = %v, want %v", tt.scope, tt.verb, got, tt.want)
+			}
+		}
+	}
+}
@@ -2,36 +2,147 @@
 // with override layering (runtime state overrides base definitions).
 package state
 
-import "github.com/nathoo/questcore/types"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	"github.com/nathoo/questcore/types"
+)
 
 // Defs holds the immutable game definitions loaded from Lua.
 type Defs struct {
-	Game        types.GameDef
-	Rooms       map[string]types.RoomDef
-	Entities    map[string]types.EntityDef
-	GlobalRules []types.RuleDef
-	Handlers    []types.EventHandler
+	Game         types.GameDef
+	Rooms        map[string]types.RoomDef
+	Entities     map[string]types.EntityDef
+	GlobalRules  []types.RuleDef
+	Handlers     []types.EventHandler
+	Computed     map[string]types.ComputedDef
+	Answers      map[string]types.AnswerDef
+	Endings      map[string]types.EndingDef
+	Achievements map[string]types.AchievementDef
+	Facts        map[string]types.FactDef
+	Messages     map[string]string               // overrides of the built-in text, by messages key
+	MetaCommands map[string]types.MetaCommandDef // the game's own slash-commands, by name
+
+	rules      ruleIndex                    // see IndexRules (nil = not indexed)
+	frozen     map[string][sha256.Size]byte // see Freeze (nil = not frozen)
+	conditions map[string]ConditionFunc     // see SetConditions (nil = none)
+}
+
+// ConditionFunc evaluates a condition of a type that the program embedding
+// the engine adds for its games. It must not change s.
+type ConditionFunc func(c types.Condition, s *types.State, defs *Defs) bool
+
+// SetConditions lets the game use the condition types in fns, by name,
+// beyond the built-in ones. The engine calls it on its own copy of the
+// definitions; see engine.WithConditions.
+func (d *Defs) SetConditions(fns map[string]ConditionFunc) {
+	d.conditions = fns
+}
+
+// Condition returns the function evaluating condition type name, given to
+// SetConditions.
+func (d *Defs) Condition(name string) (ConditionFunc, bool) {
+	fn, ok := d.conditions[name]
+	return fn, ok
 }
 
 // NewState creates a fresh game state from definitions.
 func NewState(defs *Defs) *types.State {
-	stats := map[string]int{}
-	for k, v := range defs.Game.PlayerStats {
-		stats[k] = v
+	clock := 0
+	if defs.Game.Clock != nil {
+		clock = defs.Game.Clock.Start
 	}
-	return &types.State{
-		Player: types.Player{
-			Location:  defs.Game.Start,
-			Inventory: []string{},
-			Stats:     stats,
-		},
+	s := &types.State{
+		Player:     newPlayer(defs, types.PlayerDef{}),
 		Entities:   map[string]types.EntityState{},
 		Flags:      map[string]bool{},
 		Counters:   map[string]int{},
 		TurnCount:  0,
 		RNGSeed:    0,
 		CommandLog: []string{},
+		Visited:    map[string]bool{},
+		Examined:   map[string]int{},
+		Heard:      map[string]bool{},
+		Attempts:   map[string]int{},
+		Fired:      map[string]bool{},
+		Clock:      clock,
 	}
+	if len(defs.Game.Players) > 0 {
+		s.ActivePlayer = defs.Game.StartPlayer
+		s.Players = map[string]types.Player{}
+		for id, def := range defs.Game.Players {
+			if id == s.ActivePlayer {
+				s.Player = newPlayer(defs, def)
+			} else {
+				s.Players[id] = newPlayer(defs, def)
+			}
+		}
+	}
+	return s
+}
+
+// newPlayer returns a player character as the game begins, as def
+// describes it, falling back on Game.Start and Game.PlayerStats.
+func newPlayer(defs *Defs, def types.PlayerDef) types.Player {
+	start := def.Start
+	if start == "" {
+		start = defs.Game.Start
+	}
+	base := def.Stats
+	if base == nil {
+		base = defs.Game.PlayerStats
+	}
+	stats := map[string]int{}
+	for k, v := range base {
+		stats[k] = v
+	}
+	if len(defs.Game.Levels) > 0 {
+		if _, ok := stats["level"]; !ok {
+			stats["level"] = 1
+		}
+		if _, ok := stats["xp"]; !ok {
+			stats["xp"] = 0
+		}
+	}
+	p := types.Player{
+		Location:  start,
+		Inventory: append([]string{}, def.Inventory...),
+		Stats:     stats,
+	}
+	for _, id := range def.Inventory {
+		if IsStackable(defs, id) {
+			if p.Quantities == nil {
+				p.Quantities = map[string]int{}
+			}
+			n, _ := toInt(defs.Entities[id].Props["quantity"])
+			p.Quantities[id] = max(n, 1)
+		}
+	}
+	return p
+}
+
+// StartRooms returns, sorted, the rooms the game's player characters start
+// in: Game.Start, or with several characters each one's start room.
+func StartRooms(defs *Defs) []string {
+	if len(defs.Game.Players) == 0 {
+		return []string{defs.Game.Start}
+	}
+	seen := map[string]bool{}
+	for _, def := range defs.Game.Players {
+		start := def.Start
+		if start == "" {
+			start = defs.Game.Start
+		}
+		seen[start] = true
+	}
+	rooms := make([]string, 0, len(seen))
+	for room := range seen {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
 }
 
 // GetFlag returns the value of a flag. Unset flags return false.
@@ -54,6 +165,198 @@ func HasItem(s *types.State, itemID string) bool {
 	return false
 }
 
+// HeldByOtherPlayer reports whether one of the player characters the player
+// isn't controlling carries itemID.
+func HeldByOtherPlayer(s *types.State, itemID string) bool {
+	for _, p := range s.Players {
+		for _, id := range p.Inventory {
+			if id == itemID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsStackable reports whether itemID is a stackable item: one with a
+// quantity prop, whose units are taken and dropped by number.
+func IsStackable(defs *Defs, itemID string) bool {
+	_, ok := defs.Entities[itemID].Props["quantity"]
+	return ok
+}
+
+// Quantity returns how many units of a stackable item lie in its stack in
+// the world, outside the player's inventory.
+func Quantity(s *types.State, defs *Defs, itemID string) int {
+	v, _ := GetEntityProp(s, defs, itemID, "quantity")
+	n, _ := toInt(v)
+	return n
+}
+
+// HeldCount returns how many of itemID the player carries: its quantity
+// for a stackable item, otherwise 1 or 0.
+func HeldCount(s *types.State, defs *Defs, itemID string) int {
+	if !HasItem(s, itemID) {
+		return 0
+	}
+	if IsStackable(defs, itemID) {
+		return s.Player.Quantities[itemID]
+	}
+	return 1
+}
+
+// VesselContents returns the liquid a vessel (an entity with holds_liquid
+// = true) holds, named by its filled_with prop, and how many units of it
+// its units prop says there are; "" and 0 if it is empty.
+func VesselContents(s *types.State, defs *Defs, vesselID string) (liquid string, units int) {
+	v, _ := GetEntityProp(s, defs, vesselID, "filled_with")
+	liquid, _ = v.(string)
+	v, _ = GetEntityProp(s, defs, vesselID, "units")
+	units, _ = toInt(v)
+	if liquid == "" || units <= 0 {
+		return "", 0
+	}
+	return liquid, units
+}
+
+// VesselCapacity returns how many units of liquid a vessel holds when full:
+// its capacity prop, or 1.
+func VesselCapacity(s *types.State, defs *Defs, vesselID string) int {
+	v, _ := GetEntityProp(s, defs, vesselID, "capacity")
+	if n, ok := toInt(v); ok && n > 0 {
+		return n
+	}
+	return 1
+}
+
+// HasLiquid reports whether a vessel the player carries holds some of the
+// given liquid.
+func HasLiquid(s *types.State, defs *Defs, liquidID string) bool {
+	for _, id := range s.Player.Inventory {
+		if liquid, _ := VesselContents(s, defs, id); liquid == liquidID {
+			return true
+		}
+	}
+	return false
+}
+
+// LiquidIn returns the liquid to be had from an entity: what a vessel
+// holds, or what a liquid source, such as a fountain, offers as its
+// liquid_source prop; "" if none.
+func LiquidIn(s *types.State, defs *Defs, entityID string) string {
+	if v, _ := GetEntityProp(s, defs, entityID, "liquid_source"); v != nil {
+		source, _ := v.(string)
+		return source
+	}
+	liquid, _ := VesselContents(s, defs, entityID)
+	return liquid
+}
+
+// LiquidSources returns the entities within the player's reach that a
+// liquid can be had from: the vessels they carry, then the vessels and
+// liquid sources in their room. liquidID "" means any liquid.
+func LiquidSources(s *types.State, defs *Defs, liquidID string) []string {
+	var sources []string
+	reach := append(append([]string{}, s.Player.Inventory...), EntitiesInRoom(s, defs, s.Player.Location)...)
+	for _, id := range reach {
+		if liquid := LiquidIn(s, defs, id); liquid != "" && (liquidID == "" || liquid == liquidID) {
+			sources = append(sources, id)
+		}
+	}
+	return sources
+}
+
+// IsWorn returns true if the player is wearing the given item.
+func IsWorn(s *types.State, itemID string) bool {
+	for _, id := range s.Player.Worn {
+		if id == itemID {
+			return true
+		}
+	}
+	return false
+}
+
+// HasVisited returns true if the player has been in the given room.
+func HasVisited(s *types.State, roomID string) bool {
+	return s.Visited[roomID]
+}
+
+// Examined returns how many times the player has examined an entity.
+func Examined(s *types.State, entityID string) int {
+	return s.Examined[entityID]
+}
+
+// MarkVisited records that the player has been in a room.
+func MarkVisited(s *types.State, roomID string) {
+	if s.Visited == nil {
+		s.Visited = map[string]bool{}
+	}
+	s.Visited[roomID] = true
+}
+
+// MarkExamined records that the player has examined an entity once more.
+func MarkExamined(s *types.State, entityID string) {
+	if s.Examined == nil {
+		s.Examined = map[string]int{}
+	}
+	s.Examined[entityID]++
+}
+
+// Heard reports whether the player has heard an NPC's topic.
+func Heard(s *types.State, npcID, topic string) bool {
+	return s.Heard[npcID+"."+topic]
+}
+
+// MarkHeard records that the player has heard an NPC's topic.
+func MarkHeard(s *types.State, npcID, topic string) {
+	if s.Heard == nil {
+		s.Heard = map[string]bool{}
+	}
+	s.Heard[npcID+"."+topic] = true
+}
+
+// KnowsFact reports whether the player has learned a fact.
+func KnowsFact(s *types.State, factID string) bool {
+	for _, id := range s.Facts {
+		if id == factID {
+			return true
+		}
+	}
+	return false
+}
+
+// IsLocked reports whether an entity with a lock is locked.
+func IsLocked(s *types.State, defs *Defs, entityID string) bool {
+	locked, _ := GetEntityProp(s, defs, entityID, "locked")
+	return locked == true
+}
+
+// AttachedTo returns the entity entityID is tied to, or "" if none.
+func AttachedTo(s *types.State, entityID string) string {
+	return s.Attached[entityID]
+}
+
+// TiedTo returns the entities tied to entityID, sorted.
+func TiedTo(s *types.State, entityID string) []string {
+	var ids []string
+	for id, to := range s.Attached {
+		if to == entityID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// IsAttached reports whether a is tied to b or b to a. With b "", it
+// reports whether a is tied to anything, or anything to a.
+func IsAttached(s *types.State, a, b string) bool {
+	if b == "" {
+		return s.Attached[a] != "" || len(TiedTo(s, a)) > 0
+	}
+	return s.Attached[a] == b || s.Attached[b] == a
+}
+
 // PlayerLocation returns the player's current room ID.
 func PlayerLocation(s *types.State) string {
 	return s.Player.Location
@@ -95,15 +398,175 @@ func EntityLocation(s *types.State, defs *Defs, entityID string) string {
 }
 
 // EntitiesInRoom returns the IDs of all entities whose effective location
-// matches the given room ID.
+// matches the given room ID, sorted. It looks them up in s.Locations,
+// building the index first if need be.
 func EntitiesInRoom(s *types.State, defs *Defs, roomID string) []string {
-	var result []string
+	if s.Locations == nil {
+		IndexLocations(s, defs)
+	}
+	here := s.Locations[roomID]
+	if len(here) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(here))
+	for id := range here {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Holding returns the IDs of the entities that entityID carries, such as
+// the items an NPC holds, sorted. A carried entity's location is its holder.
+func Holding(s *types.State, defs *Defs, entityID string) []string {
+	return EntitiesInRoom(s, defs, entityID)
+}
+
+// Holder returns the entity that carries entityID, or "" if it is in a
+// room, in the player's inventory or nowhere.
+func Holder(s *types.State, defs *Defs, entityID string) string {
+	loc := EntityLocation(s, defs, entityID)
+	if _, ok := defs.Entities[loc]; ok {
+		return loc
+	}
+	return ""
+}
+
+// IndexLocations rebuilds s.Locations from every entity's location. Code
+// that replaces s.Entities wholesale, such as loading a save, should call
+// it or set s.Locations to nil.
+func IndexLocations(s *types.State, defs *Defs) {
+	s.Locations = map[string]map[string]bool{}
 	for id := range defs.Entities {
-		if EntityLocation(s, defs, id) == roomID {
-			result = append(result, id)
+		if loc := EntityLocation(s, defs, id); loc != "" {
+			addLocation(s, loc, id)
 		}
 	}
-	return result
+}
+
+// SetEntityLocation moves an entity to loc, which may be a room ID or " "
+// (nowhere: carried or consumed), and keeps s.Locations up to date.
+func SetEntityLocation(s *types.State, defs *Defs, entityID, loc string) {
+	if s.Locations != nil {
+		delete(s.Locations[EntityLocation(s, defs, entityID)], entityID)
+	}
+	es := s.Entities[entityID]
+	es.Location = loc
+	s.Entities[entityID] = es
+	// An empty loc restores the base location.
+	if now := EntityLocation(s, defs, entityID); s.Locations != nil && now != "" {
+		addLocation(s, now, entityID)
+	}
+}
+
+func addLocation(s *types.State, loc, entityID string) {
+	if s.Locations[loc] == nil {
+		s.Locations[loc] = map[string]bool{}
+	}
+	s.Locations[loc][entityID] = true
+}
+
+// IsOccupant reports whether an entity counts against room capacity: living
+// NPCs and enemies do, items and scenery don't.
+func IsOccupant(s *types.State, defs *Defs, entityID string) bool {
+	def, ok := defs.Entities[entityID]
+	if !ok || (def.Kind != "npc" && def.Kind != "enemy") {
+		return false
+	}
+	alive, _ := GetEntityProp(s, defs, entityID, "alive")
+	return alive != false
+}
+
+// Occupants returns the number of entities in a room that count against its
+// capacity.
+func Occupants(s *types.State, defs *Defs, roomID string) int {
+	n := 0
+	for _, id := range EntitiesInRoom(s, defs, roomID) {
+		if IsOccupant(s, defs, id) {
+			n++
+		}
+	}
+	return n
+}
+
+// HasRoomFor reports whether entityID may enter roomID without exceeding
+// the room's capacity.
+func HasRoomFor(s *types.State, defs *Defs, roomID, entityID string) bool {
+	room, ok := defs.Rooms[roomID]
+	if !ok || room.Capacity <= 0 || !IsOccupant(s, defs, entityID) {
+		return true
+	}
+	if EntityLocation(s, defs, entityID) == roomID {
+		return true
+	}
+	return Occupants(s, defs, roomID) < room.Capacity
+}
+
+// CarryLimits are the player stats that cap the total weight and size of the
+// inventory.
+var CarryLimits = map[string]string{"weight": "max_weight", "size": "max_size"}
+
+// Carried returns the total of an item prop ("weight" or "size") over the
+// player's inventory. Items without the prop count as 0.
+func Carried(s *types.State, defs *Defs, prop string) int {
+	total := 0
+	for _, id := range s.Player.Inventory {
+		if v, ok := GetEntityProp(s, defs, id, prop); ok {
+			n, _ := toInt(v)
+			total += n * HeldCount(s, defs, id)
+		}
+	}
+	return total
+}
+
+// CanCarry reports whether the player can pick up itemID without going over
+// the max_weight or max_size player stats. A missing stat means no limit.
+func CanCarry(s *types.State, defs *Defs, itemID string) bool {
+	return CanCarryCount(s, defs, itemID, 1)
+}
+
+// CanCarryCount is CanCarry for count units of a stackable item.
+func CanCarryCount(s *types.State, defs *Defs, itemID string, count int) bool {
+	for prop, stat := range CarryLimits {
+		limit, ok := s.Player.Stats[stat]
+		if !ok {
+			continue
+		}
+		v, _ := GetEntityProp(s, defs, itemID, prop)
+		n, _ := toInt(v)
+		if Carried(s, defs, prop)+n*count > limit {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultCommandLogLimit is how many recent commands are kept when the game
+// doesn't set Game.CommandLogLimit.
+const DefaultCommandLogLimit = 1000
+
+// LogCommand records a command: it is appended to the command log, which
+// keeps only the most recent limit entries (limit <= 0 means
+// DefaultCommandLogLimit), and folded into the running count and hash, which
+// cover every command ever logged.
+func LogCommand(s *types.State, input string, limit int) {
+	if limit <= 0 {
+		limit = DefaultCommandLogLimit
+	}
+	if len(s.CommandLog) >= limit {
+		// Slide the window; append reuses the backing array until it fills,
+		// so memory stays within twice the limit.
+		s.CommandLog = s.CommandLog[len(s.CommandLog)-limit+1:]
+	}
+	s.CommandLog = append(s.CommandLog, input)
+	s.CommandCount++
+	s.CommandHash = HashCommand(s.CommandHash, input)
+}
+
+// HashCommand extends a command log hash with one more command.
+func HashCommand(prev, input string) string {
+	sum := sha256.Sum256([]byte(prev + "\n" + input))
+	return hex.EncodeToString(sum[:])
 }
 
 // InCombat returns true if the player is currently in combat.
@@ -127,6 +590,19 @@ func GetStat(s *types.State, defs *Defs, target string, stat string) (int, bool)
 	return toInt(val)
 }
 
+// NextLevel returns the level after the player's current one, or false if
+// the player is at the top level or the game has no levels.
+func NextLevel(s *types.State, defs *Defs) (types.LevelDef, bool) {
+	level := s.Player.Stats["level"]
+	if level < 1 {
+		level = 1
+	}
+	if level-1 >= len(defs.Game.Levels) {
+		return types.LevelDef{}, false
+	}
+	return defs.Game.Levels[level-1], true
+}
+
 // SetStat writes a stat for the given target. Target is "player" or an entity ID.
 func SetStat(s *types.State, target string, stat string, value int) {
 	if target == "player" {
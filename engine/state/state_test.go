@@ -1,6 +1,7 @@
 package state
 
 import (
+	"fmt"
 	"sort"
 	"testing"
 
@@ -289,6 +290,60 @@ func TestEntitiesInRoom_EmptyRoom(t *testing.T) {
 	}
 }
 
+func TestSetEntityLocation_KeepsIndex(t *testing.T) {
+	defs := testDefs()
+	s := NewState(defs)
+	EntitiesInRoom(s, defs, "hall") // builds the index
+
+	SetEntityLocation(s, defs, "guard", "hall")
+	SetEntityLocation(s, defs, "rusty_key", " ")
+	if got := EntitiesInRoom(s, defs, "hall"); len(got) != 1 || got[0] != "guard" {
+		t.Errorf("hall = %v, want [guard]", got)
+	}
+	if got := EntitiesInRoom(s, defs, "entrance"); len(got) != 1 || got[0] != "golden_key" {
+		t.Errorf("entrance = %v, want [golden_key]", got)
+	}
+
+	// An empty location restores the base one.
+	SetEntityLocation(s, defs, "guard", "")
+	if got := EntitiesInRoom(s, defs, "entrance"); len(got) != 2 {
+		t.Errorf("entrance = %v, want the guard back", got)
+	}
+
+	// The index, rebuilt, agrees with the one kept up to date.
+	kept := s.Locations
+	IndexLocations(s, defs)
+	for _, room := range []string{"entrance", "hall", " "} {
+		if len(kept[room]) != len(s.Locations[room]) {
+			t.Errorf("%q: kept %v, rebuilt %v", room, kept[room], s.Locations[room])
+		}
+	}
+}
+
+func BenchmarkEntitiesInRoom(b *testing.B) {
+	defs := &Defs{Game: types.GameDef{Start: "room0"}, Entities: map[string]types.EntityDef{}}
+	for i := 0; i < 5000; i++ {
+		id := fmt.Sprintf("thing%d", i)
+		defs.Entities[id] = types.EntityDef{ID: id, Props: map[string]any{"location": fmt.Sprintf("room%d", i%500)}}
+	}
+	s := NewState(defs)
+	b.Run("scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var here []string
+			for id := range defs.Entities {
+				if EntityLocation(s, defs, id) == "room7" {
+					here = append(here, id)
+				}
+			}
+		}
+	})
+	b.Run("indexed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			EntitiesInRoom(s, defs, "room7")
+		}
+	})
+}
+
 func TestRoomExits_BaseExits(t *testing.T) {
 	defs := testDefs()
 	s := NewState(defs)
@@ -543,3 +598,50 @@ func TestNewState_NoPlayerStats(t *testing.T) {
 		t.Errorf("expected empty Stats, got %v", s.Player.Stats)
 	}
 }
+
+func TestCanCarry(t *testing.T) {
+	defs := testDefs()
+	defs.Entities["anvil"] = types.EntityDef{ID: "anvil", Kind: "item", Props: map[string]any{"weight": 15.0}}
+	defs.Entities["feather"] = types.EntityDef{ID: "feather", Kind: "item", Props: map[string]any{"weight": 1.0, "size": 1}}
+	s := NewState(defs)
+
+	if !CanCarry(s, defs, "anvil") {
+		t.Error("no max_weight should mean no limit")
+	}
+
+	s.Player.Stats["max_weight"] = 15
+	s.Player.Stats["max_size"] = 0
+	s.Player.Inventory = []string{"anvil"}
+	if got := Carried(s, defs, "weight"); got != 15 {
+		t.Errorf("Carried(weight) = %d, want 15", got)
+	}
+	if CanCarry(s, defs, "feather") {
+		t.Error("expected feather to go over max_weight")
+	}
+	if !CanCarry(s, defs, "rusty_key") {
+		t.Error("items without weight or size should always fit")
+	}
+}
+
+func TestLogCommand_KeepsRecentWindow(t *testing.T) {
+	s := NewState(testDefs())
+	for _, cmd := range []string{"look", "north", "take key", "south"} {
+		LogCommand(s, cmd, 2)
+	}
+
+	if len(s.CommandLog) != 2 || s.CommandLog[0] != "take key" || s.CommandLog[1] != "south" {
+		t.Errorf("CommandLog = %v, want the last 2 commands", s.CommandLog)
+	}
+	if s.CommandCount != 4 {
+		t.Errorf("CommandCount = %d, want 4", s.CommandCount)
+	}
+
+	// The hash covers every command, including dropped ones.
+	want := ""
+	for _, cmd := range []string{"look", "north", "take key", "south"} {
+		want = HashCommand(want, cmd)
+	}
+	if s.CommandHash != want {
+		t.Errorf("CommandHash = %q, want %q", s.CommandHash, want)
+	}
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/types"
+)
+
+// milestones are the totals at which a statistic raises a milestone event.
+var milestones = []int{1, 10, 25, 50, 100, 250, 500, 1000}
+
+// stat is one of the totals in types.Stats.
+type stat struct {
+	name  string // in milestone events, e.g. "enemies_defeated"
+	label string // in /stats
+	n     *int
+}
+
+// stats lists the totals in s, in /stats order.
+func stats(s *types.Stats) []stat {
+	return []stat{
+		{"turns", "Turns played", &s.Turns},
+		{"rooms_visited", "Rooms visited", &s.RoomsVisited},
+		{"items_collected", "Items collected", &s.ItemsCollected},
+		{"enemies_defeated", "Enemies defeated", &s.EnemiesDefeated},
+		{"damage_dealt", "Damage dealt", &s.DamageDealt},
+		{"damage_taken", "Damage taken", &s.DamageTaken},
+		{"deaths", "Deaths", &s.Deaths},
+		{"commands", "Commands", &s.Commands},
+		{"failed_commands", "Commands failed", &s.Failed},
+		{"rng_draws", "Random draws", &s.Draws},
+	}
+}
+
+// tally adds the step that produced result to the playthrough's and the
+// session's statistics. visited is how many rooms had been visited before
+// the step, and rng the RNG and its position before it: draws are counted
+// over the whole step, milestone handlers included. Each playthrough total that reaches a milestone raises a
+// "milestone_<stat>_<n>" event, e.g. milestone_enemies_defeated_10, which
+// handlers hear like any other.
+func (e *Engine) tally(result *types.Result, visited int, rng *RNG, pos int64) {
+	var d types.Stats
+	d.Commands = 1
+	if result.Failed {
+		d.Failed = 1
+	}
+	// A restart starts the counts over, so they can go down.
+	d.Turns = max(0, e.State.TurnCount-e.turn)
+	d.RoomsVisited = max(0, len(e.State.Visited)-visited)
+	if e.RNG != rng {
+		pos = 0 // a restart starts a new RNG
+	}
+	d.Draws = int(e.RNG.Position() - pos)
+	for _, evt := range result.Events {
+		switch evt.Type {
+		case "item_taken":
+			d.ItemsCollected++
+		case "enemy_defeated":
+			d.EnemiesDefeated++
+		case "player_defeated":
+			d.Deaths++
+		case "entity_damaged":
+			amount, _ := evt.Data["amount"].(int)
+			if evt.Data["target"] == "player" {
+				d.DamageTaken += amount
+			} else {
+				d.DamageDealt += amount
+			}
+		}
+	}
+
+	var evts []types.Event
+	add := stats(&d)
+	for i, st := range stats(&e.State.Stats) {
+		before := *st.n
+		*st.n += *add[i].n
+		for _, m := range milestones {
+			if before < m && *st.n >= m {
+				evts = append(evts, types.Event{
+					Type: "milestone_" + st.name + "_" + strconv.Itoa(m),
+					Data: map[string]any{"stat": st.name, "value": *st.n, "milestone": m},
+				})
+			}
+		}
+	}
+	for i, st := range stats(&e.session) {
+		*st.n += *add[i].n
+	}
+
+	if len(evts) > 0 {
+		result.Events = append(result.Events, evts...)
+		ctx := effects.Context{Actor: "player", Strict: e.Strict, Custom: e.config.Effects, Roll: e.RNG.RollFor}
+		before := e.RNG.Position()
+		e.dispatch(evts, ctx, result)
+		draws := int(e.RNG.Position() - before)
+		e.State.Stats.Draws += draws
+		e.session.Draws += draws
+		e.State.RNGPosition = e.RNG.Position()
+		result.Events = append(result.Events, drawEvents(e.RNG.TakeDraws())...)
+	}
+}
+
+// Stats returns the statistics of this session, since the engine was made,
+// and of this playthrough, which are kept in saves and start over on
+// restart.
+func (e *Engine) Stats() (session, game types.Stats) {
+	return e.session, e.State.Stats
+}
+
+// StatsReport returns the lines /stats shows: each statistic for this
+// session and this playthrough.
+func (e *Engine) StatsReport() []string {
+	session, game := e.Stats()
+	lines := []string{fmt.Sprintf("%-18s %8s %8s", "Statistics", "session", "game")}
+	g := stats(&game)
+	for i, st := range stats(&session) {
+		lines = append(lines, fmt.Sprintf("  %-16s %8d %8d", st.label, *st.n, *g[i].n))
+	}
+	return lines
+}
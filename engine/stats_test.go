@@ -0,0 +1,124 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/engine/save"
+	"github.com/nathoo/questcore/types"
+)
+
+func TestStats_TallyCommands(t *testing.T) {
+	e := New(testDefs())
+	for _, cmd := range []string{"take key", "go north", "xyzzy", "wait"} {
+		e.Step(cmd)
+	}
+
+	session, game := e.Stats()
+	if session != game {
+		t.Errorf("session %+v and game %+v should agree before any load", session, game)
+	}
+	if game.Commands != 4 || game.Failed != 1 {
+		t.Errorf("commands %d, failed %d; want 4, 1", game.Commands, game.Failed)
+	}
+	if game.ItemsCollected != 1 {
+		t.Errorf("items collected = %d, want 1", game.ItemsCollected)
+	}
+	if game.RoomsVisited != 2 || game.Turns != e.State.TurnCount {
+		t.Errorf("rooms %d, turns %d; want 2, %d", game.RoomsVisited, game.Turns, e.State.TurnCount)
+	}
+}
+
+func TestStats_SavedWithTheGameButNotTheSession(t *testing.T) {
+	e := New(testDefs())
+	e.Step("take key")
+	data, err := save.Save(e.State, e.Defs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e2 := New(testDefs())
+	e2.Step("wait")
+	sd, err := save.Load(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	save.ApplySave(e2.State, sd)
+
+	session, game := e2.Stats()
+	if game.ItemsCollected != 1 || game.Commands != 1 {
+		t.Errorf("game stats after load = %+v, want the saved ones", game)
+	}
+	if session.ItemsCollected != 0 || session.Commands != 1 {
+		t.Errorf("session stats after load = %+v, want this session's", session)
+	}
+}
+
+func TestStats_MilestoneEvents(t *testing.T) {
+	defs := testDefs()
+	defs.Handlers = append(defs.Handlers, types.EventHandler{
+		EventType: "milestone_items_collected_1",
+		Effects:   []types.Effect{{Type: "set_flag", Params: map[string]any{"flag": "first_find", "value": true}}},
+	})
+	e := New(defs)
+
+	result := e.Step("take key")
+	var found bool
+	for _, evt := range result.Events {
+		if evt.Type == "milestone_items_collected_1" {
+			found = evt.Data["stat"] == "items_collected" && evt.Data["value"] == 1
+		}
+	}
+	if !found {
+		t.Errorf("expected a milestone_items_collected_1 event, got %v", result.Events)
+	}
+	if !e.State.Flags["first_find"] {
+		t.Error("the milestone handler should have run")
+	}
+
+	// A milestone is reached once.
+	result = e.Step("wait")
+	for _, evt := range result.Events {
+		if evt.Type == "milestone_items_collected_1" {
+			t.Error("milestone_items_collected_1 raised again")
+		}
+	}
+}
+
+func TestStats_DrawsCountedOverTheWholeStep(t *testing.T) {
+	defs := testDefs()
+	defs.Handlers = append(defs.Handlers, types.EventHandler{
+		EventType: "milestone_items_collected_1",
+		Effects:   []types.Effect{{Type: "inc_counter", Params: map[string]any{"counter": "luck", "amount": "2d6"}}},
+	})
+	e := New(defs)
+
+	result := e.Step("take key")
+	_, game := e.Stats()
+	if game.Draws != 2 {
+		t.Errorf("draws = %d, want the milestone handler's 2", game.Draws)
+	}
+	var draws int
+	for _, evt := range result.Events {
+		if evt.Type == "rng_draw" {
+			draws++
+		}
+	}
+	if draws != 2 {
+		t.Errorf("rng_draw events = %d, want 2 in the step that drew them", draws)
+	}
+	if e.State.RNGPosition != e.RNG.Position() {
+		t.Errorf("RNGPosition = %d, want %d", e.State.RNGPosition, e.RNG.Position())
+	}
+}
+
+func TestStatsReport(t *testing.T) {
+	e := New(testDefs())
+	e.Step("take key")
+	lines := e.StatsReport()
+	if len(lines) != 11 {
+		t.Fatalf("report has %d lines, want a header and 10 stats:\n%v", len(lines), lines)
+	}
+	if !outputContains(lines, "Items collected") {
+		t.Errorf("report = %v", lines)
+	}
+}
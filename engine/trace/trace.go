@@ -0,0 +1,232 @@
+// Package trace records what the engine decides while it runs a command:
+// how the input parsed, which entities it named, which rules were
+// considered and why each did or didn't fire, and the effects and events
+// that followed. Records go to a Sink: a writer such as stderr, a file of
+// JSON lines, or memory, for a front end to show.
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Subsystem names the part of the engine a record comes from.
+type Subsystem string
+
+const (
+	Parse   Subsystem = "parse"   // the input, split into verb, object and target
+	Resolve Subsystem = "resolve" // the entities the object and target named
+	Rules   Subsystem = "rules"   // the rules considered, and which fired
+	Effects Subsystem = "effects" // the effects applied
+	Events  Subsystem = "events"  // the events raised
+)
+
+// Subsystems lists every subsystem, in the order a command goes through
+// them.
+var Subsystems = []Subsystem{Parse, Resolve, Rules, Effects, Events}
+
+// ParseSubsystems parses a comma-separated list of subsystem names, such
+// as "rules,effects". "all" stands for every subsystem, and parses as nil.
+func ParseSubsystems(list string) ([]Subsystem, error) {
+	if list == "all" {
+		return nil, nil
+	}
+	var subs []Subsystem
+	for _, name := range strings.Split(list, ",") {
+		sub := Subsystem(strings.TrimSpace(name))
+		if !slices.Contains(Subsystems, sub) {
+			return nil, fmt.Errorf("unknown trace subsystem %q (want all or %s)", sub, joinSubsystems())
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+func joinSubsystems() string {
+	names := make([]string, len(Subsystems))
+	for i, sub := range Subsystems {
+		names[i] = string(sub)
+	}
+	return strings.Join(names, ", ")
+}
+
+// Level is how much detail a record gives.
+type Level int
+
+const (
+	// Info records say what was decided: the rule that fired, the effects
+	// applied.
+	Info Level = iota
+	// Debug records say why: each rule considered, and what it failed on.
+	Debug
+)
+
+func (l Level) String() string {
+	if l == Debug {
+		return "debug"
+	}
+	return "info"
+}
+
+// MarshalText makes levels read as their names in JSON.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// Event is one record of a decision.
+type Event struct {
+	Turn      int            `json:"turn"`
+	Subsystem Subsystem      `json:"subsystem"`
+	Level     Level          `json:"level"`
+	Message   string         `json:"message"`        // the decision, in words
+	Data      map[string]any `json:"data,omitempty"` // the decision's particulars, by name
+}
+
+// String renders the event as a line of trace output.
+func (e Event) String() string {
+	return fmt.Sprintf("[trace] %s: %s", e.Subsystem, e.Message)
+}
+
+// A Sink receives trace events. The engine calls Record as it runs a
+// command, so a sink should be quick.
+type Sink interface {
+	Record(Event)
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc func(Event)
+
+// Record calls f(e).
+func (f SinkFunc) Record(e Event) { f(e) }
+
+// Writer is a Sink that writes each event to w, as a line of text or, if
+// JSON is set, as a JSON object per line. Write errors are dropped, so that
+// a failing trace never fails a game.
+type Writer struct {
+	mu   sync.Mutex
+	w    io.Writer
+	JSON bool
+}
+
+// NewWriter returns a Writer writing lines of text to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Stderr returns a Writer writing lines of text to standard error.
+func Stderr() *Writer {
+	return NewWriter(os.Stderr)
+}
+
+// Record writes e.
+func (w *Writer) Record(e Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.JSON {
+		data, err := json.Marshal(e)
+		if err != nil {
+			data, _ = json.Marshal(Event{Turn: e.Turn, Subsystem: e.Subsystem, Level: e.Level, Message: e.Message})
+		}
+		fmt.Fprintf(w.w, "%s\n", data)
+		return
+	}
+	fmt.Fprintln(w.w, e.String())
+}
+
+// File is a Writer of JSON lines to a file.
+type File struct {
+	*Writer
+	f *os.File
+}
+
+// OpenFile creates (or truncates) the file at path and returns a sink
+// writing JSON lines to it. Close it when done.
+func OpenFile(path string) (*File, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Writer: &Writer{w: f, JSON: true}, f: f}, nil
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	return f.f.Close()
+}
+
+// Memory is a Sink that keeps the events it receives.
+type Memory struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// Record keeps e.
+func (m *Memory) Record(e Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, e)
+}
+
+// Events returns the events kept, oldest first.
+func (m *Memory) Events() []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.events)
+}
+
+// Take returns the events kept and forgets them.
+func (m *Memory) Take() []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	events := m.events
+	m.events = nil
+	return events
+}
+
+// Filter is a Sink that passes on to Sink only the events up to Level in
+// detail, from Subsystems (all when empty).
+type Filter struct {
+	Sink       Sink
+	Level      Level
+	Subsystems []Subsystem
+}
+
+// Allows reports whether the filter passes e on.
+func (f Filter) Allows(e Event) bool {
+	return e.Level <= f.Level && (len(f.Subsystems) == 0 || slices.Contains(f.Subsystems, e.Subsystem))
+}
+
+// Record passes e on if the filter allows it.
+func (f Filter) Record(e Event) {
+	if f.Allows(e) {
+		f.Sink.Record(e)
+	}
+}
+
+// Multi returns a Sink that records each event in every one of sinks. Nil
+// sinks are skipped.
+func Multi(sinks ...Sink) Sink {
+	var all multi
+	for _, s := range sinks {
+		if s != nil {
+			all = append(all, s)
+		}
+	}
+	if len(all) == 1 {
+		return all[0]
+	}
+	return all
+}
+
+type multi []Sink
+
+func (m multi) Record(e Event) {
+	for _, s := range m {
+		s.Record(e)
+	}
+}
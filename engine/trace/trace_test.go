@@ -0,0 +1,82 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseSubsystems(t *testing.T) {
+	subs, err := ParseSubsystems("rules, effects")
+	if err != nil || len(subs) != 2 || subs[0] != Rules || subs[1] != Effects {
+		t.Errorf("ParseSubsystems = %v, %v", subs, err)
+	}
+	if subs, err := ParseSubsystems("all"); err != nil || subs != nil {
+		t.Errorf("all = %v, %v, want nil", subs, err)
+	}
+	if _, err := ParseSubsystems("rules,magic"); err == nil || !strings.Contains(err.Error(), `"magic"`) {
+		t.Errorf("err = %v, want unknown subsystem", err)
+	}
+}
+
+func TestFilterAndMulti(t *testing.T) {
+	var all, some Memory
+	sink := Multi(&all, nil, Filter{Sink: &some, Level: Info, Subsystems: []Subsystem{Rules}})
+	sink.Record(Event{Subsystem: Rules, Level: Info, Message: "fired"})
+	sink.Record(Event{Subsystem: Rules, Level: Debug, Message: "skipped"})
+	sink.Record(Event{Subsystem: Parse, Level: Info, Message: "parsed"})
+
+	if got := len(all.Events()); got != 3 {
+		t.Errorf("unfiltered sink got %d events, want 3", got)
+	}
+	got := some.Take()
+	if len(got) != 1 || got[0].Message != "fired" {
+		t.Errorf("filtered sink got %v, want the info rules event", got)
+	}
+	if len(some.Take()) != 0 {
+		t.Error("Take didn't forget the events")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var text, lines bytes.Buffer
+	e := Event{Turn: 3, Subsystem: Rules, Level: Debug, Message: "rule x (global): it matched", Data: map[string]any{"rule": "x"}}
+
+	NewWriter(&text).Record(e)
+	if got := text.String(); got != "[trace] rules: rule x (global): it matched\n" {
+		t.Errorf("text = %q", got)
+	}
+
+	(&Writer{w: &lines, JSON: true}).Record(e)
+	var got map[string]any
+	if err := json.Unmarshal(lines.Bytes(), &got); err != nil {
+		t.Fatalf("not a JSON line: %q", lines.String())
+	}
+	if got["turn"] != 3.0 || got["subsystem"] != "rules" || got["level"] != "debug" || got["data"].(map[string]any)["rule"] != "x" {
+		t.Errorf("JSON = %v", got)
+	}
+}
+
+func TestWhy(t *testing.T) {
+	if got := Why(nil); len(got) != 1 || got[0] != "No command to explain yet." {
+		t.Errorf("Why(nil) = %v", got)
+	}
+	events := []Event{
+		{Subsystem: Parse, Message: `"open chest" → verb open, object chest`},
+		{Subsystem: Resolve, Message: "object chest"},
+		{Subsystem: Rules, Level: Debug, Message: "rule locked (room:hall): condition flag_set(flag=unlocked) is false", Data: map[string]any{"fired": false}},
+		{Subsystem: Rules, Message: "handled by the built-in open", Data: map[string]any{"handler": "the built-in open"}},
+		{Subsystem: Effects, Message: "say text=Opened."},
+	}
+	want := []string{
+		`Parsed: "open chest" → verb open, object chest`,
+		"Resolved: object chest",
+		"Rules considered:",
+		"  ✗ rule locked (room:hall): condition flag_set(flag=unlocked) is false",
+		"Handled by the built-in open.",
+	}
+	if got := Why(events); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Why =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,39 @@
+package trace
+
+// Why words the records of one command for an author asking why it did
+// what it did: how it parsed, what it resolved to, each rule considered
+// and what became of it, and what handled the command if no rule did.
+// Effects and events are left out; the command's output shows them.
+func Why(events []Event) []string {
+	var lines []string
+	considered := false
+	for _, e := range events {
+		switch e.Subsystem {
+		case Parse:
+			lines = append(lines, "Parsed: "+e.Message)
+		case Resolve:
+			lines = append(lines, "Resolved: "+e.Message)
+		case Rules:
+			if handler, ok := e.Data["handler"].(string); ok {
+				if !considered {
+					lines = append(lines, "No rule for it here.")
+				}
+				lines = append(lines, "Handled by "+handler+".")
+				continue
+			}
+			if !considered {
+				lines = append(lines, "Rules considered:")
+				considered = true
+			}
+			mark := "✗"
+			if fired, _ := e.Data["fired"].(bool); fired {
+				mark = "✓"
+			}
+			lines = append(lines, "  "+mark+" "+e.Message)
+		}
+	}
+	if len(lines) == 0 {
+		return []string{"No command to explain yet."}
+	}
+	return lines
+}
@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/engine/trace"
+	"github.com/nathoo/questcore/types"
+)
+
+// record sends a trace event to the engine's Trace sink, if it has one.
+func (e *Engine) record(sub trace.Subsystem, level trace.Level, data map[string]any, format string, args ...any) {
+	if e.Trace == nil {
+		return
+	}
+	e.Trace.Record(trace.Event{
+		Turn:      e.turn,
+		Subsystem: sub,
+		Level:     level,
+		Message:   fmt.Sprintf(format, args...),
+		Data:      data,
+	})
+}
+
+// traceParse records how input parsed.
+func (e *Engine) traceParse(input string, intent types.Intent) {
+	e.record(trace.Parse, trace.Info,
+		map[string]any{"input": input, "verb": intent.Verb, "object": intent.Object, "target": intent.Target},
+		"%q → %s", input, describeIntent(intent.Verb, intent.Object, intent.Target))
+}
+
+// traceResolve records the entities a command's object and target named.
+func (e *Engine) traceResolve(intent types.Intent, objectID, targetID string, err error) {
+	if e.Trace == nil {
+		return
+	}
+	data := map[string]any{"object": intent.Object, "target": intent.Target}
+	if err != nil {
+		data["error"] = err.Error()
+		e.record(trace.Resolve, trace.Info, data, "failed: %v", err)
+		return
+	}
+	data["object_id"], data["target_id"] = objectID, targetID
+	e.record(trace.Resolve, trace.Info, data, "%s", describeIntent("", objectID, targetID))
+}
+
+// traceRules records what became of each rule considered for a command in
+// phase; see rules.Explain. It must be called before the rules' effects
+// are applied.
+func (e *Engine) traceRules(phase string, intent types.Intent, objectID, targetID string, succeeded bool) {
+	if e.Trace == nil {
+		return
+	}
+	prefix := ""
+	if phase != "" {
+		prefix = phase + " "
+	}
+	for _, v := range rules.Explain(e.State, e.Defs, phase, intent, objectID, targetID, succeeded) {
+		level := trace.Debug
+		if v.Fired {
+			level = trace.Info
+		}
+		e.record(trace.Rules, level,
+			map[string]any{"phase": phase, "rule": v.Rule.ID, "scope": v.Scope, "fired": v.Fired, "reason": v.Reason},
+			"%srule %s (%s): %s", prefix, v.Rule.ID, v.Scope, v.Reason)
+	}
+}
+
+// traceOutcome records what handled a command once its rules are done.
+func (e *Engine) traceOutcome(handler string) {
+	e.record(trace.Rules, trace.Info, map[string]any{"handler": handler}, "handled by %s", handler)
+}
+
+// traceResult records the effects a step applied and the events it raised.
+func (e *Engine) traceResult(result types.Result) {
+	if e.Trace == nil {
+		return
+	}
+	for _, eff := range result.Effects {
+		e.record(trace.Effects, trace.Info, map[string]any{"type": eff.Type, "params": eff.Params},
+			"%s", strings.TrimSpace(eff.Type+" "+describeParams(eff.Params)))
+	}
+	for _, evt := range result.Events {
+		msg := strings.TrimSpace(evt.Type + " " + describeParams(evt.Data))
+		if text, ok := evt.Data["message"].(string); ok {
+			msg = evt.Type + ": " + text // invalid_reference, rng_draw, ...
+		}
+		e.record(trace.Events, trace.Info, map[string]any{"type": evt.Type, "data": evt.Data}, "%s", msg)
+	}
+}
+
+// describeIntent renders the parts of a command that are set, such as
+// "verb use, object key, target door".
+func describeIntent(verb, object, target string) string {
+	var parts []string
+	for _, p := range [][2]string{{"verb", verb}, {"object", object}, {"target", target}} {
+		if p[1] != "" {
+			parts = append(parts, p[0]+" "+p[1])
+		}
+	}
+	if len(parts) == 0 {
+		return "nothing"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeParams renders params as "key=value ..." in key order.
+func describeParams(params map[string]any) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", k, params[k])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/trace"
+)
+
+func TestTrace(t *testing.T) {
+	eng := New(testDefs())
+	var mem trace.Memory
+	eng.Trace = &mem
+
+	eng.Step("take key")
+	var lines []string
+	for _, e := range mem.Take() {
+		if e.Turn != 0 {
+			t.Errorf("%v: turn %d, want 0", e, e.Turn)
+		}
+		lines = append(lines, e.String())
+	}
+	got := strings.Join(lines, "\n")
+	for _, want := range []string{
+		`[trace] parse: "take key" → verb take, object key`,
+		"[trace] resolve: object key",
+		"[trace] rules: rule hall_take_key (room:hall): it matched",
+		"[trace] effects: give_item item=key",
+		"[trace] events: item_taken",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("trace lacks %q:\n%s", want, got)
+		}
+	}
+
+	eng.Step("push sky")
+	got = ""
+	for _, e := range mem.Take() {
+		got += e.String() + "\n"
+	}
+	if !strings.Contains(got, "[trace] resolve: failed:") || !strings.Contains(got, "[trace] rules: handled by") {
+		t.Errorf("trace of an unresolved command:\n%s", got)
+	}
+}
+
+func TestApplicableRules(t *testing.T) {
+	eng := New(testDefs())
+	ids := func(query string) []string {
+		t.Helper()
+		entries, err := eng.ApplicableRules(query)
+		if err != nil {
+			t.Fatalf("ApplicableRules(%q): %v", query, err)
+		}
+		var ids []string
+		for _, e := range entries {
+			ids = append(ids, e.Rule.ID)
+		}
+		return ids
+	}
+
+	for _, query := range []string{"", "take", "get", "key", "Key"} {
+		if got := ids(query); !slices.Contains(got, "hall_take_key") {
+			t.Errorf("%q: %v, want hall_take_key", query, got)
+		}
+	}
+	if got := ids("open"); len(got) != 0 {
+		t.Errorf("open: %v, want none", got)
+	}
+	if _, err := eng.ApplicableRules("unicorn"); err == nil {
+		t.Error("unknown query accepted")
+	}
+}
@@ -0,0 +1,196 @@
+// Package graph builds the room graph of a loaded game and renders it as
+// Graphviz DOT or Mermaid for authoring and debugging.
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// Edge is a directed exit from one room to another.
+type Edge struct {
+	From      string
+	To        string
+	Direction string
+	Dynamic   bool // opened at runtime by an OpenExit effect
+}
+
+// Graph is the room graph of a game.
+type Graph struct {
+	Start       string
+	Rooms       []string // sorted room IDs
+	Edges       []Edge   // sorted by From, Direction, To
+	Unreachable []string // rooms not reachable from Start, any player character's start or a MovePlayer target, sorted
+}
+
+// Build constructs the room graph from definitions. Base exits become static
+// edges; OpenExit effects anywhere in the content become dynamic edges, since
+// rules may open them during play. Rooms a MovePlayer effect takes the
+// player to count as reachable, wherever the effect is.
+func Build(defs *state.Defs) *Graph {
+	g := &Graph{Start: defs.Game.Start}
+
+	for id := range defs.Rooms {
+		g.Rooms = append(g.Rooms, id)
+	}
+	sort.Strings(g.Rooms)
+
+	for _, id := range g.Rooms {
+		for dir, target := range defs.Rooms[id].Exits {
+			g.Edges = append(g.Edges, Edge{From: id, To: target, Direction: dir})
+		}
+	}
+	starts := state.StartRooms(defs)
+	for _, eff := range allEffects(defs) {
+		switch eff.Type {
+		case "open_exit":
+			room, _ := eff.Params["room"].(string)
+			dir, _ := eff.Params["direction"].(string)
+			target, _ := eff.Params["target"].(string)
+			if room != "" && target != "" {
+				g.Edges = append(g.Edges, Edge{From: room, To: target, Direction: dir, Dynamic: true})
+			}
+		case "move_player":
+			if room, _ := eff.Params["room"].(string); room != "" {
+				starts = append(starts, room)
+			}
+		}
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.Direction != b.Direction {
+			return a.Direction < b.Direction
+		}
+		return a.To < b.To
+	})
+
+	g.Unreachable = unreachable(g, starts)
+	return g
+}
+
+// unreachable returns rooms that cannot be reached from starts by following
+// static or dynamic edges.
+func unreachable(g *Graph, starts []string) []string {
+	adj := map[string][]string{}
+	for _, e := range g.Edges {
+		adj[e.From] = append(adj[e.From], e.To)
+	}
+	seen := map[string]bool{}
+	queue := append([]string{}, starts...)
+	for _, start := range starts {
+		seen[start] = true
+	}
+	for len(queue) > 0 {
+		room := queue[0]
+		queue = queue[1:]
+		for _, next := range adj[room] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	var out []string
+	for _, id := range g.Rooms {
+		if !seen[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// DOT renders the graph in Graphviz DOT format. The start room is drawn
+// double-bordered, unreachable rooms in red, and dynamic exits dashed.
+func (g *Graph) DOT() string {
+	unreach := toSet(g.Unreachable)
+	var b strings.Builder
+	b.WriteString("digraph rooms {\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, id := range g.Rooms {
+		var attrs []string
+		if id == g.Start {
+			attrs = append(attrs, "peripheries=2")
+		}
+		if unreach[id] {
+			attrs = append(attrs, "color=red", `xlabel="unreachable"`)
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, "  %q [%s];\n", id, strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(&b, "  %q;\n", id)
+		}
+	}
+	for _, e := range g.Edges {
+		if e.Dynamic {
+			fmt.Fprintf(&b, "  %q -> %q [label=%q, style=dashed];\n", e.From, e.To, e.Direction)
+		} else {
+			fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", e.From, e.To, e.Direction)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart. Dynamic exits use dotted
+// arrows and unreachable rooms get the "unreachable" class. Nodes are named
+// n0, n1, ... and labeled with their room IDs, which may be Mermaid
+// keywords, such as "end", or hold characters it doesn't allow in names.
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	nodes := map[string]string{}
+	node := func(room string) string {
+		n, ok := nodes[room]
+		if !ok {
+			n = fmt.Sprintf("n%d", len(nodes))
+			nodes[room] = n
+			fmt.Fprintf(&b, "  %s[%q]\n", n, room)
+		}
+		return n
+	}
+	for _, id := range g.Rooms {
+		node(id)
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Dynamic {
+			arrow = "-.->"
+		}
+		from, to := node(e.From), node(e.To)
+		fmt.Fprintf(&b, "  %s %s|%s| %s\n", from, arrow, e.Direction, to)
+	}
+	if len(g.Unreachable) > 0 {
+		names := make([]string, len(g.Unreachable))
+		for i, id := range g.Unreachable {
+			names[i] = node(id)
+		}
+		b.WriteString("  classDef unreachable stroke:#f00,color:#f00\n")
+		fmt.Fprintf(&b, "  class %s unreachable\n", strings.Join(names, ","))
+	}
+	return b.String()
+}
+
+// allEffects gathers every effect declared anywhere in the content.
+func allEffects(defs *state.Defs) []types.Effect {
+	var effs []types.Effect
+	rules.ForEachDef(defs, func(_ string, _ []types.Condition, e []types.Effect) {
+		effs = append(effs, e...)
+	})
+	return effs
+}
+
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, s := range items {
+		set[s] = true
+	}
+	return set
+}
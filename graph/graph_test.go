@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+func testDefs() *state.Defs {
+	return &state.Defs{
+		Game: types.GameDef{Start: "hall"},
+		Rooms: map[string]types.RoomDef{
+			"hall":   {ID: "hall", Exits: map[string]string{"north": "garden"}},
+			"garden": {ID: "garden", Exits: map[string]string{"south": "hall"}},
+			"vault":  {ID: "vault"},
+			"attic":  {ID: "attic", Exits: map[string]string{"down": "hall"}},
+		},
+		Entities: map[string]types.EntityDef{},
+		GlobalRules: []types.RuleDef{
+			{ID: "open_vault", Effects: []types.Effect{
+				{Type: "open_exit", Params: map[string]any{"room": "garden", "direction": "down", "target": "vault"}},
+			}},
+		},
+	}
+}
+
+func TestBuild_EdgesAndReachability(t *testing.T) {
+	g := Build(testDefs())
+
+	if len(g.Edges) != 4 {
+		t.Fatalf("expected 4 edges, got %+v", g.Edges)
+	}
+	var dynamic int
+	for _, e := range g.Edges {
+		if e.Dynamic {
+			dynamic++
+			if e.From != "garden" || e.To != "vault" || e.Direction != "down" {
+				t.Errorf("unexpected dynamic edge %+v", e)
+			}
+		}
+	}
+	if dynamic != 1 {
+		t.Errorf("expected 1 dynamic edge, got %d", dynamic)
+	}
+	// The vault is reachable through the dynamic exit; the attic only leads out.
+	if len(g.Unreachable) != 1 || g.Unreachable[0] != "attic" {
+		t.Errorf("Unreachable = %v, want [attic]", g.Unreachable)
+	}
+}
+
+func TestBuild_MovePlayerReaches(t *testing.T) {
+	defs := testDefs()
+	// The attic is reached only by the player being moved there, by an
+	// answer rather than a rule.
+	defs.Answers = map[string]types.AnswerDef{
+		"riddle": {Success: []types.Effect{{Type: "move_player", Params: map[string]any{"room": "attic"}}}},
+	}
+	g := Build(defs)
+
+	if len(g.Unreachable) != 0 {
+		t.Errorf("Unreachable = %v, want none: move_player reaches the attic", g.Unreachable)
+	}
+	if len(g.Edges) != 4 {
+		t.Errorf("move_player should add no edge, got %+v", g.Edges)
+	}
+}
+
+func TestDOT(t *testing.T) {
+	out := Build(testDefs()).DOT()
+
+	for _, want := range []string{
+		"digraph rooms {",
+		`"hall" [peripheries=2];`,
+		`"attic" [color=red, xlabel="unreachable"];`,
+		`"hall" -> "garden" [label="north"];`,
+		`"garden" -> "vault" [label="down", style=dashed];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	out := Build(testDefs()).Mermaid()
+
+	for _, want := range []string{
+		"flowchart LR",
+		`n0["attic"]`,
+		`n2["hall"]`,
+		"n2 -->|north| n1",
+		"n1 -.->|down| n3",
+		"class n0 unreachable",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMermaid_KeywordRoomIDs(t *testing.T) {
+	defs := testDefs()
+	defs.Rooms["hall"] = types.RoomDef{ID: "hall", Exits: map[string]string{"north": "end"}}
+	defs.Rooms["end"] = types.RoomDef{ID: "end", Exits: map[string]string{"west": "west-wing.1"}}
+	defs.Rooms["west-wing.1"] = types.RoomDef{ID: "west-wing.1"}
+	out := Build(defs).Mermaid()
+
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && (fields[0] == "end" || strings.HasPrefix(fields[0], "west")) {
+			t.Errorf("room ID used as a node name: %q", line)
+		}
+	}
+	for _, want := range []string{`["end"]`, `["west-wing.1"]`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Mermaid output missing label %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestDOT_Deterministic(t *testing.T) {
+	defs := testDefs()
+	first := Build(defs).DOT()
+	for i := 0; i < 10; i++ {
+		if Build(defs).DOT() != first {
+			t.Fatal("DOT output is not deterministic")
+		}
+	}
+}
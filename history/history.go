@@ -0,0 +1,116 @@
+// Package history keeps the commands a player has typed, for recalling them
+// with the arrow keys in the TUI and the plain CLI, and saves them between
+// sessions (see DefaultPath).
+package history
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nathoo/questcore/config"
+)
+
+// DefaultMax is how many commands the front ends remember.
+const DefaultMax = 100
+
+// History is a ring buffer for command history with cursor-based navigation.
+type History struct {
+	entries []string
+	max     int
+	cursor  int // -1 = not navigating, 0..len-1 = position in entries
+}
+
+// New creates a history buffer with the given maximum size.
+func New(max int) *History {
+	return &History{
+		entries: make([]string, 0, max),
+		max:     max,
+		cursor:  -1,
+	}
+}
+
+// Push adds a command to history. Consecutive duplicates are skipped.
+func (h *History) Push(cmd string) {
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == cmd {
+		return
+	}
+	h.entries = append(h.entries, cmd)
+	if len(h.entries) > h.max {
+		h.entries = h.entries[1:]
+	}
+}
+
+// Prev returns the previous (older) history entry.
+// Returns ("", false) if history is empty.
+func (h *History) Prev() (string, bool) {
+	if len(h.entries) == 0 {
+		return "", false
+	}
+	if h.cursor == -1 {
+		h.cursor = len(h.entries) - 1
+	} else if h.cursor > 0 {
+		h.cursor--
+	}
+	return h.entries[h.cursor], true
+}
+
+// Next returns the next (newer) history entry.
+// Returns ("", false) when past the most recent entry (back to fresh input).
+func (h *History) Next() (string, bool) {
+	if h.cursor == -1 {
+		return "", false
+	}
+	h.cursor++
+	if h.cursor >= len(h.entries) {
+		h.cursor = -1
+		return "", false
+	}
+	return h.entries[h.cursor], true
+}
+
+// ResetCursor resets the navigation cursor to the "not navigating" state.
+func (h *History) ResetCursor() {
+	h.cursor = -1
+}
+
+// DefaultPath returns where the history is kept between sessions: history
+// in config.DataDir.
+func DefaultPath() string {
+	return filepath.Join(config.DataDir(), "history")
+}
+
+// Load creates a history buffer of the given maximum size holding the most
+// recent commands in the file at path, one per line. A missing file is an
+// empty history.
+func Load(path string, max int) (*History, error) {
+	h := New(max)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, cmd := range strings.Split(string(data), "\n") {
+		if cmd != "" {
+			h.Push(cmd)
+		}
+	}
+	return h, nil
+}
+
+// Save writes the history to the file at path, one command per line,
+// creating its directory if needed.
+func (h *History) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var sb strings.Builder
+	for _, cmd := range h.entries {
+		sb.WriteString(cmd + "\n")
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0o644)
+}
@@ -0,0 +1,144 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestHistory_PushAndPrev(t *testing.T) {
+	h := New(5)
+	h.Push("look")
+	h.Push("go north")
+	h.Push("take key")
+
+	prev, ok := h.Prev()
+	if !ok || prev != "take key" {
+		t.Errorf("expected 'take key', got %q (ok=%v)", prev, ok)
+	}
+
+	prev, ok = h.Prev()
+	if !ok || prev != "go north" {
+		t.Errorf("expected 'go north', got %q (ok=%v)", prev, ok)
+	}
+
+	prev, ok = h.Prev()
+	if !ok || prev != "look" {
+		t.Errorf("expected 'look', got %q (ok=%v)", prev, ok)
+	}
+
+	// At oldest, stays there.
+	prev, ok = h.Prev()
+	if !ok || prev != "look" {
+		t.Errorf("expected 'look' at boundary, got %q (ok=%v)", prev, ok)
+	}
+}
+
+func TestHistory_Next(t *testing.T) {
+	h := New(5)
+	h.Push("look")
+	h.Push("go north")
+
+	h.Prev() // "go north"
+	h.Prev() // "look"
+
+	next, ok := h.Next()
+	if !ok || next != "go north" {
+		t.Errorf("expected 'go north', got %q (ok=%v)", next, ok)
+	}
+
+	_, ok = h.Next()
+	if ok {
+		t.Error("expected false when past newest entry")
+	}
+}
+
+func TestHistory_Empty(t *testing.T) {
+	h := New(5)
+	_, ok := h.Prev()
+	if ok {
+		t.Error("expected false on empty history")
+	}
+	_, ok = h.Next()
+	if ok {
+		t.Error("expected false on empty history")
+	}
+}
+
+func TestHistory_MaxSize(t *testing.T) {
+	h := New(2)
+	h.Push("a")
+	h.Push("b")
+	h.Push("c") // "a" evicted
+
+	prev, _ := h.Prev()
+	if prev != "c" {
+		t.Errorf("expected 'c', got %q", prev)
+	}
+	prev, _ = h.Prev()
+	if prev != "b" {
+		t.Errorf("expected 'b', got %q", prev)
+	}
+	// "a" is gone.
+	prev, _ = h.Prev()
+	if prev != "b" {
+		t.Errorf("expected 'b' at boundary, got %q", prev)
+	}
+}
+
+func TestHistory_NoDuplicates(t *testing.T) {
+	h := New(5)
+	h.Push("look")
+	h.Push("look") // skipped
+	h.Push("look") // skipped
+
+	if len(h.entries) != 1 {
+		t.Errorf("expected 1 entry, got %d", len(h.entries))
+	}
+}
+
+func TestHistory_ResetCursor(t *testing.T) {
+	h := New(5)
+	h.Push("look")
+	h.Push("go north")
+
+	h.Prev() // "go north"
+	h.ResetCursor()
+
+	// After reset, Prev starts from the end again.
+	prev, ok := h.Prev()
+	if !ok || prev != "go north" {
+		t.Errorf("expected 'go north' after reset, got %q", prev)
+	}
+}
+
+func TestLoadAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "questcore", "history")
+	h, err := Load(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := h.Prev(); ok {
+		t.Fatal("a missing file should load as an empty history")
+	}
+
+	h.Push("look")
+	h.Push("go north")
+	h.Push("take key")
+	if err := h.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev, _ := loaded.Prev(); prev != "take key" {
+		t.Errorf("newest = %q, want %q", prev, "take key")
+	}
+	if prev, _ := loaded.Prev(); prev != "go north" {
+		t.Errorf("next = %q, want %q", prev, "go north")
+	}
+	if len(loaded.entries) != 2 {
+		t.Errorf("loaded %d entries, want the 2 kept", len(loaded.entries))
+	}
+}
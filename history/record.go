@@ -0,0 +1,76 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nathoo/questcore/engine"
+)
+
+// A Recorder writes the game commands a player types to a script file, in
+// the format --script plays back, so that a playtester who hits a bug can
+// hand over the commands that lead to it.
+type Recorder struct {
+	f    *os.File
+	path string
+	n    int // commands written
+}
+
+// Record starts a script at path for the game eng is playing. The script
+// opens with comments saying how to replay it. If the engine's command log
+// holds every command played so far, they come first, so that the script
+// replays the game from the start; if not, the script starts here.
+func Record(path string, eng *engine.Engine) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{f: f, path: path}
+
+	s, g := eng.State, eng.Defs.Game
+	header := []string{
+		fmt.Sprintf("# %s %s, recorded with /record. Replay with:", g.Title, g.Version),
+		fmt.Sprintf("#   questcore --seed %d --script %s <game_directory>", s.RNGSeed, path),
+	}
+	past := s.CommandLog
+	if s.CommandCount != len(s.CommandLog) {
+		header = append(header, fmt.Sprintf("# Recorded from turn %d; the commands before weren't kept.", s.TurnCount))
+		past = nil
+	}
+	if _, err := fmt.Fprintln(f, strings.Join(header, "\n")); err != nil {
+		f.Close()
+		return nil, err
+	}
+	for _, cmd := range past {
+		if err := r.Add(cmd); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Add appends a command to the script.
+func (r *Recorder) Add(command string) error {
+	if _, err := fmt.Fprintln(r.f, command); err != nil {
+		return err
+	}
+	r.n++
+	return nil
+}
+
+// Path returns the script's file name.
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Count returns how many commands the script holds.
+func (r *Recorder) Count() int {
+	return r.n
+}
+
+// Close finishes the script.
+func (r *Recorder) Close() error {
+	return r.f.Close()
+}
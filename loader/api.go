@@ -16,6 +16,7 @@ func registerConstructors(L *lua.LState, coll *collector) {
 	L.SetGlobal("Game", L.NewFunction(func(L *lua.LState) int {
 		tbl := L.CheckTable(1)
 		coll.game = tbl
+		coll.mark(L, "game")
 		return 0
 	}))
 
@@ -24,7 +25,7 @@ func registerConstructors(L *lua.LState, coll *collector) {
 		id := L.CheckString(1)
 		L.Push(L.NewFunction(func(L *lua.LState) int {
 			tbl := L.CheckTable(1)
-			coll.rooms = append(coll.rooms, rawRoom{id: id, table: tbl})
+			coll.rooms = append(coll.rooms, rawRoom{id: id, table: tbl, pos: coll.mark(L, "room:"+id)})
 			return 0
 		}))
 		return 1
@@ -35,7 +36,7 @@ func registerConstructors(L *lua.LState, coll *collector) {
 		id := L.CheckString(1)
 		L.Push(L.NewFunction(func(L *lua.LState) int {
 			tbl := L.CheckTable(1)
-			coll.entities = append(coll.entities, rawEntity{id: id, kind: "item", table: tbl})
+			coll.entities = append(coll.entities, rawEntity{id: id, kind: "item", table: tbl, pos: coll.mark(L, "entity:"+id)})
 			return 0
 		}))
 		return 1
@@ -46,7 +47,7 @@ func registerConstructors(L *lua.LState, coll *collector) {
 		id := L.CheckString(1)
 		L.Push(L.NewFunction(func(L *lua.LState) int {
 			tbl := L.CheckTable(1)
-			coll.entities = append(coll.entities, rawEntity{id: id, kind: "npc", table: tbl})
+			coll.entities = append(coll.entities, rawEntity{id: id, kind: "npc", table: tbl, pos: coll.mark(L, "entity:"+id)})
 			return 0
 		}))
 		return 1
@@ -57,7 +58,7 @@ func registerConstructors(L *lua.LState, coll *collector) {
 		id := L.CheckString(1)
 		L.Push(L.NewFunction(func(L *lua.LState) int {
 			tbl := L.CheckTable(1)
-			coll.entities = append(coll.entities, rawEntity{id: id, kind: "entity", table: tbl})
+			coll.entities = append(coll.entities, rawEntity{id: id, kind: "entity", table: tbl, pos: coll.mark(L, "entity:"+id)})
 			return 0
 		}))
 		return 1
@@ -68,7 +69,7 @@ func registerConstructors(L *lua.LState, coll *collector) {
 		id := L.CheckString(1)
 		L.Push(L.NewFunction(func(L *lua.LState) int {
 			tbl := L.CheckTable(1)
-			coll.entities = append(coll.entities, rawEntity{id: id, kind: "enemy", table: tbl})
+			coll.entities = append(coll.entities, rawEntity{id: id, kind: "enemy", table: tbl, pos: coll.mark(L, "entity:"+id)})
 			return 0
 		}))
 		return 1
@@ -99,6 +100,7 @@ func registerConstructors(L *lua.LState, coll *collector) {
 			thenTbl = L.CheckTable(3)
 		}
 
+		pos := coll.mark(L, "rule:"+id)
 		order := coll.nextSourceOrder()
 		coll.rules = append(coll.rules, rawRule{
 			id:         id,
@@ -107,6 +109,7 @@ func registerConstructors(L *lua.LState, coll *collector) {
 			then:       thenTbl,
 			scope:      "global",
 			order:      order,
+			pos:        pos,
 		})
 
 		// Return a marker table so rooms/entities can reference this rule.
@@ -120,7 +123,80 @@ func registerConstructors(L *lua.LState, coll *collector) {
 	L.SetGlobal("On", L.NewFunction(func(L *lua.LState) int {
 		eventType := L.CheckString(1)
 		tbl := L.CheckTable(2)
-		coll.handlers = append(coll.handlers, rawHandler{eventType: eventType, table: tbl})
+		pos := coll.mark(L, handlerSubject(len(coll.handlers)))
+		coll.handlers = append(coll.handlers, rawHandler{eventType: eventType, table: tbl, pos: pos})
+		return 0
+	}))
+
+	// Computed("id", { uses = {...}, cases = {...}, default = value })
+	L.SetGlobal("Computed", L.NewFunction(func(L *lua.LState) int {
+		id := L.CheckString(1)
+		tbl := L.CheckTable(2)
+		coll.computed = append(coll.computed, rawComputed{id: id, table: tbl, pos: coll.mark(L, "computed:"+id)})
+		return 0
+	}))
+
+	// Answer("id", { accept = {...}, success = {...}, failure = {...}, attempts = n })
+	L.SetGlobal("Answer", L.NewFunction(func(L *lua.LState) int {
+		id := L.CheckString(1)
+		tbl := L.CheckTable(2)
+		coll.answers = append(coll.answers, rawAnswer{id: id, table: tbl, pos: coll.mark(L, "answer:"+id)})
+		return 0
+	}))
+
+	// Endings { id = { title = "...", text = "..." }, ... }
+	L.SetGlobal("Endings", L.NewFunction(func(L *lua.LState) int {
+		tbl := L.CheckTable(1)
+		coll.endings = append(coll.endings, tbl)
+		tbl.ForEach(func(k, _ lua.LValue) {
+			if id, ok := k.(lua.LString); ok {
+				coll.mark(L, "ending:"+string(id))
+			}
+		})
+		return 0
+	}))
+
+	// Achievement "id" { name = "...", description = "...", hidden = bool } — curried.
+	L.SetGlobal("Achievement", L.NewFunction(func(L *lua.LState) int {
+		id := L.CheckString(1)
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			tbl := L.CheckTable(1)
+			coll.achieves = append(coll.achieves, rawAchievement{id: id, table: tbl, pos: coll.mark(L, "achievement:"+id)})
+			return 0
+		}))
+		return 1
+	}))
+
+	// Fact "id" { text = "..." } — curried.
+	L.SetGlobal("Fact", L.NewFunction(func(L *lua.LState) int {
+		id := L.CheckString(1)
+		L.Push(L.NewFunction(func(L *lua.LState) int {
+			tbl := L.CheckTable(1)
+			coll.facts = append(coll.facts, rawFact{id: id, table: tbl, pos: coll.mark(L, "fact:"+id)})
+			return 0
+		}))
+		return 1
+	}))
+
+	// MetaCommand("/name", { help = "...", effects = {...} } or { verb = "..." })
+	L.SetGlobal("MetaCommand", L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		tbl := L.CheckTable(2)
+		coll.metas = append(coll.metas, rawMetaCommand{name: name, table: tbl, pos: coll.mark(L, "meta:"+name)})
+		return 0
+	}))
+
+	// Messages { key = "text", ... } — later calls override earlier ones.
+	L.SetGlobal("Messages", L.NewFunction(func(L *lua.LState) int {
+		tbl := L.CheckTable(1)
+		if coll.messages == nil {
+			coll.messages = map[string]string{}
+		}
+		tbl.ForEach(func(k, v lua.LValue) {
+			if key, ok := k.(lua.LString); ok {
+				coll.messages[string(key)] = lua.LVAsString(v)
+			}
+		})
 		return 0
 	}))
 
@@ -192,6 +268,28 @@ func registerConditionHelpers(L *lua.LState) {
 		return 1
 	}))
 
+	// TimeIs("day" | "night")
+	L.SetGlobal("TimeIs", L.NewFunction(func(L *lua.LState) int {
+		period := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("time_is"))
+		tbl.RawSetString("period", lua.LString(period))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// TimeBetween("HH:MM", "HH:MM")
+	L.SetGlobal("TimeBetween", L.NewFunction(func(L *lua.LState) int {
+		from := L.CheckString(1)
+		to := L.CheckString(2)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("time_between"))
+		tbl.RawSetString("from", lua.LString(from))
+		tbl.RawSetString("to", lua.LString(to))
+		L.Push(tbl)
+		return 1
+	}))
+
 	// PropIs("entity", "prop", value)
 	L.SetGlobal("PropIs", L.NewFunction(func(L *lua.LState) int {
 		entity := L.CheckString(1)
@@ -206,6 +304,34 @@ func registerConditionHelpers(L *lua.LState) {
 		return 1
 	}))
 
+	// PropGt("entity", "prop", value)
+	L.SetGlobal("PropGt", L.NewFunction(func(L *lua.LState) int {
+		entity := L.CheckString(1)
+		prop := L.CheckString(2)
+		value := L.CheckNumber(3)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("prop_gt"))
+		tbl.RawSetString("entity", lua.LString(entity))
+		tbl.RawSetString("prop", lua.LString(prop))
+		tbl.RawSetString("value", value)
+		L.Push(tbl)
+		return 1
+	}))
+
+	// PropLt("entity", "prop", value)
+	L.SetGlobal("PropLt", L.NewFunction(func(L *lua.LState) int {
+		entity := L.CheckString(1)
+		prop := L.CheckString(2)
+		value := L.CheckNumber(3)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("prop_lt"))
+		tbl.RawSetString("entity", lua.LString(entity))
+		tbl.RawSetString("prop", lua.LString(prop))
+		tbl.RawSetString("value", value)
+		L.Push(tbl)
+		return 1
+	}))
+
 	// CounterGt("counter", value)
 	L.SetGlobal("CounterGt", L.NewFunction(func(L *lua.LState) int {
 		counter := L.CheckString(1)
@@ -230,6 +356,46 @@ func registerConditionHelpers(L *lua.LState) {
 		return 1
 	}))
 
+	// CounterEq("counter", value)
+	L.SetGlobal("CounterEq", L.NewFunction(func(L *lua.LState) int {
+		counter := L.CheckString(1)
+		value := L.CheckNumber(2)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("counter_eq"))
+		tbl.RawSetString("counter", lua.LString(counter))
+		tbl.RawSetString("value", value)
+		L.Push(tbl)
+		return 1
+	}))
+
+	// CounterBetween("counter", min, max) -- inclusive
+	L.SetGlobal("CounterBetween", L.NewFunction(func(L *lua.LState) int {
+		counter := L.CheckString(1)
+		lo := L.CheckNumber(2)
+		hi := L.CheckNumber(3)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("counter_between"))
+		tbl.RawSetString("counter", lua.LString(counter))
+		tbl.RawSetString("min", lo)
+		tbl.RawSetString("max", hi)
+		L.Push(tbl)
+		return 1
+	}))
+
+	// CounterCmp("counter", "op", "other") -- op is ==, !=, <, <=, > or >=
+	L.SetGlobal("CounterCmp", L.NewFunction(func(L *lua.LState) int {
+		counter := L.CheckString(1)
+		op := L.CheckString(2)
+		other := L.CheckString(3)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("counter_cmp"))
+		tbl.RawSetString("counter", lua.LString(counter))
+		tbl.RawSetString("op", lua.LString(op))
+		tbl.RawSetString("other", lua.LString(other))
+		L.Push(tbl)
+		return 1
+	}))
+
 	// Not(condition)
 	L.SetGlobal("Not", L.NewFunction(func(L *lua.LState) int {
 		inner := L.CheckTable(1)
@@ -258,6 +424,63 @@ func registerConditionHelpers(L *lua.LState) {
 		return 1
 	}))
 
+	// InVehicle() or InVehicle("entity_id")
+	L.SetGlobal("InVehicle", L.NewFunction(func(L *lua.LState) int {
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("in_vehicle"))
+		if L.GetTop() >= 1 {
+			tbl.RawSetString("entity", lua.LString(L.CheckString(1)))
+		}
+		L.Push(tbl)
+		return 1
+	}))
+
+	// IsAttached("entity_id") or IsAttached("entity_id", "to_entity_id")
+	L.SetGlobal("IsAttached", L.NewFunction(func(L *lua.LState) int {
+		entity := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("is_attached"))
+		tbl.RawSetString("entity", lua.LString(entity))
+		if L.GetTop() >= 2 {
+			tbl.RawSetString("to", lua.LString(L.CheckString(2)))
+		}
+		L.Push(tbl)
+		return 1
+	}))
+
+	// HasLiquid("liquid_id") or HasLiquid("liquid_id", "vessel_id")
+	L.SetGlobal("HasLiquid", L.NewFunction(func(L *lua.LState) int {
+		liquid := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("has_liquid"))
+		tbl.RawSetString("liquid", lua.LString(liquid))
+		if L.GetTop() >= 2 {
+			tbl.RawSetString("vessel", lua.LString(L.CheckString(2)))
+		}
+		L.Push(tbl)
+		return 1
+	}))
+
+	// IsPlayer("player_id")
+	L.SetGlobal("IsPlayer", L.NewFunction(func(L *lua.LState) int {
+		player := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("is_player"))
+		tbl.RawSetString("player", lua.LString(player))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// KnowsFact("fact_id")
+	L.SetGlobal("KnowsFact", L.NewFunction(func(L *lua.LState) int {
+		fact := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("knows_fact"))
+		tbl.RawSetString("fact", lua.LString(fact))
+		L.Push(tbl)
+		return 1
+	}))
+
 	// StatGt("entity_or_player", "stat", value)
 	L.SetGlobal("StatGt", L.NewFunction(func(L *lua.LState) int {
 		entity := L.CheckString(1)
@@ -272,6 +495,18 @@ func registerConditionHelpers(L *lua.LState) {
 		return 1
 	}))
 
+	// ComputedIs("computed_id", value)
+	L.SetGlobal("ComputedIs", L.NewFunction(func(L *lua.LState) int {
+		computed := L.CheckString(1)
+		value := L.Get(2)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("computed_is"))
+		tbl.RawSetString("computed", lua.LString(computed))
+		tbl.RawSetString("value", value)
+		L.Push(tbl)
+		return 1
+	}))
+
 	// StatLt("entity_or_player", "stat", value)
 	L.SetGlobal("StatLt", L.NewFunction(func(L *lua.LState) int {
 		entity := L.CheckString(1)
@@ -298,22 +533,236 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 
-	// GiveItem("id")
+	// Notify("text")
+	L.SetGlobal("Notify", L.NewFunction(func(L *lua.LState) int {
+		text := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("notify"))
+		tbl.RawSetString("text", lua.LString(text))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// GiveItem("id" [, amount])
 	L.SetGlobal("GiveItem", L.NewFunction(func(L *lua.LState) int {
 		item := L.CheckString(1)
+		amount := L.OptInt(2, 0)
 		tbl := L.NewTable()
 		tbl.RawSetString("type", lua.LString("give_item"))
 		tbl.RawSetString("item", lua.LString(item))
+		if amount > 0 {
+			tbl.RawSetString("amount", lua.LNumber(amount))
+		}
 		L.Push(tbl)
 		return 1
 	}))
 
-	// RemoveItem("id")
+	// RemoveItem("id" [, amount])
 	L.SetGlobal("RemoveItem", L.NewFunction(func(L *lua.LState) int {
 		item := L.CheckString(1)
+		amount := L.OptInt(2, 0)
 		tbl := L.NewTable()
 		tbl.RawSetString("type", lua.LString("remove_item"))
 		tbl.RawSetString("item", lua.LString(item))
+		if amount > 0 {
+			tbl.RawSetString("amount", lua.LNumber(amount))
+		}
+		L.Push(tbl)
+		return 1
+	}))
+
+	// WearItem("id")
+	L.SetGlobal("WearItem", L.NewFunction(func(L *lua.LState) int {
+		item := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("wear_item"))
+		tbl.RawSetString("item", lua.LString(item))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// UnwearItem("id")
+	L.SetGlobal("UnwearItem", L.NewFunction(func(L *lua.LState) int {
+		item := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("unwear_item"))
+		tbl.RawSetString("item", lua.LString(item))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// EnterVehicle("entity_id")
+	L.SetGlobal("EnterVehicle", L.NewFunction(func(L *lua.LState) int {
+		entity := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("enter_vehicle"))
+		tbl.RawSetString("entity", lua.LString(entity))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// LeaveVehicle()
+	L.SetGlobal("LeaveVehicle", L.NewFunction(func(L *lua.LState) int {
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("leave_vehicle"))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// Attach("entity_id", "to_entity_id")
+	L.SetGlobal("Attach", L.NewFunction(func(L *lua.LState) int {
+		entity := L.CheckString(1)
+		to := L.CheckString(2)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("attach"))
+		tbl.RawSetString("entity", lua.LString(entity))
+		tbl.RawSetString("to", lua.LString(to))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// Detach("entity_id")
+	L.SetGlobal("Detach", L.NewFunction(func(L *lua.LState) int {
+		entity := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("detach"))
+		tbl.RawSetString("entity", lua.LString(entity))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// Unlock("entity_id") or Unlock("entity_id", "key_id")
+	L.SetGlobal("Unlock", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lockEffect(L, "unlock"))
+		return 1
+	}))
+
+	// Lock("entity_id") or Lock("entity_id", "key_id")
+	L.SetGlobal("Lock", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lockEffect(L, "lock"))
+		return 1
+	}))
+
+	// FillVessel("vessel_id", "liquid_id") or FillVessel("vessel_id", "liquid_id", units)
+	L.SetGlobal("FillVessel", L.NewFunction(func(L *lua.LState) int {
+		vessel := L.CheckString(1)
+		liquid := L.CheckString(2)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("fill_vessel"))
+		tbl.RawSetString("vessel", lua.LString(vessel))
+		tbl.RawSetString("liquid", lua.LString(liquid))
+		if L.GetTop() >= 3 {
+			tbl.RawSetString("amount", lua.LNumber(L.CheckInt(3)))
+		}
+		L.Push(tbl)
+		return 1
+	}))
+
+	// PourLiquid("vessel_id"), PourLiquid("vessel_id", "target_id") or
+	// PourLiquid("vessel_id", "target_id", units)
+	L.SetGlobal("PourLiquid", L.NewFunction(func(L *lua.LState) int {
+		vessel := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("pour_liquid"))
+		tbl.RawSetString("vessel", lua.LString(vessel))
+		if L.GetTop() >= 2 {
+			tbl.RawSetString("target", lua.LString(L.CheckString(2)))
+		}
+		if L.GetTop() >= 3 {
+			tbl.RawSetString("amount", lua.LNumber(L.CheckInt(3)))
+		}
+		L.Push(tbl)
+		return 1
+	}))
+
+	// DrinkLiquid("vessel_id") or DrinkLiquid("vessel_id", units)
+	L.SetGlobal("DrinkLiquid", L.NewFunction(func(L *lua.LState) int {
+		vessel := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("drink_liquid"))
+		tbl.RawSetString("vessel", lua.LString(vessel))
+		if L.GetTop() >= 2 {
+			tbl.RawSetString("amount", lua.LNumber(L.CheckInt(2)))
+		}
+		L.Push(tbl)
+		return 1
+	}))
+
+	// ConsumeItem("id")
+	L.SetGlobal("ConsumeItem", L.NewFunction(func(L *lua.LState) int {
+		item := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("consume_item"))
+		tbl.RawSetString("item", lua.LString(item))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// EndGame("ending_id")
+	L.SetGlobal("EndGame", L.NewFunction(func(L *lua.LState) int {
+		ending := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("end_game"))
+		tbl.RawSetString("ending", lua.LString(ending))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// AdvanceTime(minutes)
+	L.SetGlobal("AdvanceTime", L.NewFunction(func(L *lua.LState) int {
+		minutes := L.CheckNumber(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("advance_time"))
+		tbl.RawSetString("minutes", minutes)
+		L.Push(tbl)
+		return 1
+	}))
+
+	// PlaySound("sound")
+	L.SetGlobal("PlaySound", L.NewFunction(func(L *lua.LState) int {
+		sound := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("play_sound"))
+		tbl.RawSetString("sound", lua.LString(sound))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// PlayMusic("track")
+	L.SetGlobal("PlayMusic", L.NewFunction(func(L *lua.LState) int {
+		music := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("play_music"))
+		tbl.RawSetString("music", lua.LString(music))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// StopMusic()
+	L.SetGlobal("StopMusic", L.NewFunction(func(L *lua.LState) int {
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("play_music"))
+		tbl.RawSetString("music", lua.LString(""))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// UnlockAchievement("achievement_id")
+	L.SetGlobal("UnlockAchievement", L.NewFunction(func(L *lua.LState) int {
+		achievement := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("unlock_achievement"))
+		tbl.RawSetString("achievement", lua.LString(achievement))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// LearnFact("fact_id")
+	L.SetGlobal("LearnFact", L.NewFunction(func(L *lua.LState) int {
+		fact := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("learn_fact"))
+		tbl.RawSetString("fact", lua.LString(fact))
 		L.Push(tbl)
 		return 1
 	}))
@@ -330,10 +779,10 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 
-	// IncCounter("counter", amount)
+	// IncCounter("counter", amount or "dice")
 	L.SetGlobal("IncCounter", L.NewFunction(func(L *lua.LState) int {
 		counter := L.CheckString(1)
-		amount := L.CheckNumber(2)
+		amount := checkAmount(L, 2)
 		tbl := L.NewTable()
 		tbl.RawSetString("type", lua.LString("inc_counter"))
 		tbl.RawSetString("counter", lua.LString(counter))
@@ -342,10 +791,34 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 
-	// SetCounter("counter", value)
+	// DecCounter("counter", amount or "dice")
+	L.SetGlobal("DecCounter", L.NewFunction(func(L *lua.LState) int {
+		counter := L.CheckString(1)
+		amount := checkAmount(L, 2)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("dec_counter"))
+		tbl.RawSetString("counter", lua.LString(counter))
+		tbl.RawSetString("amount", amount)
+		L.Push(tbl)
+		return 1
+	}))
+
+	// MulCounter("counter", factor)
+	L.SetGlobal("MulCounter", L.NewFunction(func(L *lua.LState) int {
+		counter := L.CheckString(1)
+		factor := L.CheckNumber(2)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("mul_counter"))
+		tbl.RawSetString("counter", lua.LString(counter))
+		tbl.RawSetString("factor", factor)
+		L.Push(tbl)
+		return 1
+	}))
+
+	// SetCounter("counter", value or "dice")
 	L.SetGlobal("SetCounter", L.NewFunction(func(L *lua.LState) int {
 		counter := L.CheckString(1)
-		value := L.CheckNumber(2)
+		value := checkAmount(L, 2)
 		tbl := L.NewTable()
 		tbl.RawSetString("type", lua.LString("set_counter"))
 		tbl.RawSetString("counter", lua.LString(counter))
@@ -354,6 +827,29 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 
+	// GiveTo("item", "npc")
+	L.SetGlobal("GiveTo", L.NewFunction(func(L *lua.LState) int {
+		item := L.CheckString(1)
+		npc := L.CheckString(2)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("give_to"))
+		tbl.RawSetString("item", lua.LString(item))
+		tbl.RawSetString("npc", lua.LString(npc))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// TransferItem("item", "from", "to") — "player" or an entity either end
+	L.SetGlobal("TransferItem", L.NewFunction(func(L *lua.LState) int {
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("transfer_item"))
+		tbl.RawSetString("item", lua.LString(L.CheckString(1)))
+		tbl.RawSetString("from", lua.LString(L.CheckString(2)))
+		tbl.RawSetString("to", lua.LString(L.CheckString(3)))
+		L.Push(tbl)
+		return 1
+	}))
+
 	// SetProp("entity", "prop", value)
 	L.SetGlobal("SetProp", L.NewFunction(func(L *lua.LState) int {
 		entity := L.CheckString(1)
@@ -368,6 +864,34 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 
+	// IncProp("entity", "prop", amount or "dice")
+	L.SetGlobal("IncProp", L.NewFunction(func(L *lua.LState) int {
+		entity := L.CheckString(1)
+		prop := L.CheckString(2)
+		amount := checkAmount(L, 3)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("inc_prop"))
+		tbl.RawSetString("entity", lua.LString(entity))
+		tbl.RawSetString("prop", lua.LString(prop))
+		tbl.RawSetString("amount", amount)
+		L.Push(tbl)
+		return 1
+	}))
+
+	// DecProp("entity", "prop", amount or "dice")
+	L.SetGlobal("DecProp", L.NewFunction(func(L *lua.LState) int {
+		entity := L.CheckString(1)
+		prop := L.CheckString(2)
+		amount := checkAmount(L, 3)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("dec_prop"))
+		tbl.RawSetString("entity", lua.LString(entity))
+		tbl.RawSetString("prop", lua.LString(prop))
+		tbl.RawSetString("amount", amount)
+		L.Push(tbl)
+		return 1
+	}))
+
 	// MoveEntity("entity", "room")
 	L.SetGlobal("MoveEntity", L.NewFunction(func(L *lua.LState) int {
 		entity := L.CheckString(1)
@@ -390,6 +914,28 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 
+	// SwitchPlayer("player")
+	L.SetGlobal("SwitchPlayer", L.NewFunction(func(L *lua.LState) int {
+		player := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("switch_player"))
+		tbl.RawSetString("player", lua.LString(player))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// Cutaway("room", n_lines) — n_lines is optional (default: all).
+	L.SetGlobal("Cutaway", L.NewFunction(func(L *lua.LState) int {
+		room := L.CheckString(1)
+		lines := L.OptInt(2, 0)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("cutaway"))
+		tbl.RawSetString("room", lua.LString(room))
+		tbl.RawSetString("lines", lua.LNumber(lines))
+		L.Push(tbl)
+		return 1
+	}))
+
 	// OpenExit("room", "direction", "target")
 	L.SetGlobal("OpenExit", L.NewFunction(func(L *lua.LState) int {
 		room := L.CheckString(1)
@@ -426,6 +972,16 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 
+	// Ask("answer_id")
+	L.SetGlobal("Ask", L.NewFunction(func(L *lua.LState) int {
+		answer := L.CheckString(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("ask"))
+		tbl.RawSetString("answer", lua.LString(answer))
+		L.Push(tbl)
+		return 1
+	}))
+
 	// StartDialogue("npc")
 	L.SetGlobal("StartDialogue", L.NewFunction(func(L *lua.LState) int {
 		npc := L.CheckString(1)
@@ -444,12 +1000,24 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 
-	// StartCombat("enemy_id")
+	// Continue()
+	L.SetGlobal("Continue", L.NewFunction(func(L *lua.LState) int {
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("continue"))
+		L.Push(tbl)
+		return 1
+	}))
+
+	// StartCombat("enemy_id") or StartCombat("enemy_id", { surprise = true })
 	L.SetGlobal("StartCombat", L.NewFunction(func(L *lua.LState) int {
 		enemy := L.CheckString(1)
+		opts := L.OptTable(2, L.NewTable())
 		tbl := L.NewTable()
 		tbl.RawSetString("type", lua.LString("start_combat"))
 		tbl.RawSetString("enemy", lua.LString(enemy))
+		if surprise := opts.RawGetString("surprise"); surprise != lua.LNil {
+			tbl.RawSetString("surprise", surprise)
+		}
 		L.Push(tbl)
 		return 1
 	}))
@@ -462,10 +1030,10 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 
-	// Damage("target", amount)
+	// Damage("target", amount or "dice")
 	L.SetGlobal("Damage", L.NewFunction(func(L *lua.LState) int {
 		target := L.CheckString(1)
-		amount := L.CheckNumber(2)
+		amount := checkAmount(L, 2)
 		tbl := L.NewTable()
 		tbl.RawSetString("type", lua.LString("damage"))
 		tbl.RawSetString("target", lua.LString(target))
@@ -474,10 +1042,10 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 
-	// Heal("target", amount)
+	// Heal("target", amount or "dice")
 	L.SetGlobal("Heal", L.NewFunction(func(L *lua.LState) int {
 		target := L.CheckString(1)
-		amount := L.CheckNumber(2)
+		amount := checkAmount(L, 2)
 		tbl := L.NewTable()
 		tbl.RawSetString("type", lua.LString("heal"))
 		tbl.RawSetString("target", lua.LString(target))
@@ -486,6 +1054,16 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 
+	// GainXP(amount)
+	L.SetGlobal("GainXP", L.NewFunction(func(L *lua.LState) int {
+		amount := L.CheckNumber(1)
+		tbl := L.NewTable()
+		tbl.RawSetString("type", lua.LString("gain_xp"))
+		tbl.RawSetString("amount", amount)
+		L.Push(tbl)
+		return 1
+	}))
+
 	// SetStat("target", "stat", value)
 	L.SetGlobal("SetStat", L.NewFunction(func(L *lua.LState) int {
 		target := L.CheckString(1)
@@ -500,3 +1078,25 @@ func registerEffectHelpers(L *lua.LState) {
 		return 1
 	}))
 }
+
+// checkAmount returns argument n of an effect helper, an amount: a number,
+// or a dice expression such as "2d4+1", rolled each time the effect runs.
+func checkAmount(L *lua.LState, n int) lua.LValue {
+	if s, ok := L.Get(n).(lua.LString); ok {
+		return s
+	}
+	return L.CheckNumber(n)
+}
+
+// lockEffect builds an unlock or lock effect from the call's arguments: the
+// entity, and optionally the key used.
+func lockEffect(L *lua.LState, typ string) *lua.LTable {
+	entity := L.CheckString(1)
+	tbl := L.NewTable()
+	tbl.RawSetString("type", lua.LString(typ))
+	tbl.RawSetString("entity", lua.LString(entity))
+	if L.GetTop() >= 2 {
+		tbl.RawSetString("key", lua.LString(L.CheckString(2)))
+	}
+	return tbl
+}
@@ -0,0 +1,340 @@
+package loader
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/graph"
+	"github.com/nathoo/questcore/types"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Report is the result of checking a game directory.
+type Report struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	Errors      int          `json:"errors"`
+	Warnings    int          `json:"warnings"`
+}
+
+// Check loads the game in dir without running it and reports every problem
+// found: the validation errors and warnings Load would produce, plus lint
+// analyses (unreachable rooms, unreferenced entities, rules that can never
+// fire, flags that are set but never read). Diagnostics carry the file and
+// line of the definition they concern where known.
+//
+// The returned error is non-nil only if the directory could not be read;
+// Lua and compile failures are reported as error diagnostics.
+func Check(dir string, with ...Option) (*Report, error) {
+	return check(dir, false, with)
+}
+
+// CheckDeep is like Check, and also follows the game from its start room
+// to find rooms that can never be reached, items that can never be
+// obtained and flags that can never be set, given the items and flags each
+// way forward depends on.
+func CheckDeep(dir string, with ...Option) (*Report, error) {
+	return check(dir, true, with)
+}
+
+func check(dir string, deep bool, with []Option) (*Report, error) {
+	report := &Report{}
+
+	coll, err := collect(os.DirFS(dir), dir)
+	if err != nil {
+		var fe *fileError
+		if !errors.As(err, &fe) {
+			return nil, err
+		}
+		report.add(luaErrorDiagnostic(fe))
+		return report, nil
+	}
+
+	defs, err := compile(coll)
+	if err != nil {
+		d := Diagnostic{Severity: "error", Message: err.Error()}
+		var se *sourceError
+		if errors.As(err, &se) {
+			d.Message, d.File, d.Line, d.Excerpt = se.err.Error(), se.pos.file, se.pos.line, se.excerpt
+		}
+		report.add(d)
+		return report, nil
+	}
+
+	ve := analyze(defs, with...)
+	validateImages(os.DirFS(dir), defs, ve)
+	lint(defs, ve)
+	if deep {
+		deepLint(defs, ve)
+	}
+	coll.locate(ve)
+	for _, d := range ve.Diagnostics {
+		report.add(d)
+	}
+	sortDiagnostics(report.Diagnostics)
+	return report, nil
+}
+
+func (r *Report) add(d Diagnostic) {
+	r.Diagnostics = append(r.Diagnostics, d)
+	if d.Severity == "error" {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// luaPosition matches the "<file>:<line>: " prefix of a Lua runtime error;
+// luaSyntaxPosition matches the "<file> line:<n>(column:<n>) " prefix of a
+// parse error.
+var (
+	luaPosition       = regexp.MustCompile(`^(.+?):(\d+): `)
+	luaSyntaxPosition = regexp.MustCompile(`^(.+?) line:(\d+)\(column:\d+\) `)
+)
+
+// luaErrorPosition returns the position a Lua error's text starts with, or
+// the zero position if it has none.
+func luaErrorPosition(err error) position {
+	var apiErr *lua.ApiError
+	if !errors.As(err, &apiErr) {
+		return position{}
+	}
+	msg := apiErr.Object.String()
+	for _, re := range []*regexp.Regexp{luaPosition, luaSyntaxPosition} {
+		if m := re.FindStringSubmatch(msg); m != nil {
+			line, _ := strconv.Atoi(m[2])
+			return position{file: m[1], line: line}
+		}
+	}
+	return position{}
+}
+
+// luaErrorDiagnostic converts a Lua execution failure into a diagnostic,
+// recovering the line number from the error text when Lua supplies one.
+func luaErrorDiagnostic(fe *fileError) Diagnostic {
+	d := Diagnostic{Severity: "error", Message: fe.err.Error(), File: fe.path, Excerpt: fe.excerpt}
+	var apiErr *lua.ApiError
+	if errors.As(fe.err, &apiErr) {
+		msg := apiErr.Object.String()
+		for _, re := range []*regexp.Regexp{luaPosition, luaSyntaxPosition} {
+			if m := re.FindStringSubmatch(msg); m != nil {
+				d.Line, _ = strconv.Atoi(m[2])
+				msg = msg[len(m[0]):]
+				break
+			}
+		}
+		d.Message = strings.Join(strings.Fields(msg), " ")
+	}
+	return d
+}
+
+// sortDiagnostics orders diagnostics by file and line; diagnostics without a
+// position keep their relative order and come first.
+func sortDiagnostics(ds []Diagnostic) {
+	sort.SliceStable(ds, func(i, j int) bool {
+		if ds[i].File != ds[j].File {
+			return ds[i].File < ds[j].File
+		}
+		return ds[i].Line < ds[j].Line
+	})
+}
+
+// lint runs the analyses that go beyond Load's validation. All findings are
+// warnings: the game still runs, but probably not as the author intended.
+func lint(defs *state.Defs, ve *ValidationError) {
+	// Unreachable rooms.
+	if _, ok := defs.Rooms[defs.Game.Start]; ok {
+		g := graph.Build(defs)
+		for _, id := range g.Unreachable {
+			ve.addWarning("room:"+id, fmt.Sprintf(
+				"room %q is unreachable from start room %q", id, g.Start))
+		}
+	}
+
+	var conds []types.Condition
+	writers := map[string]string{}                 // flag → subject of its first set_flag
+	settable := map[string]bool{"game_over": true} // also set by the engine on player death
+	referenced := map[string]bool{}
+	rules.ForEachDef(defs, func(subject string, cs []types.Condition, effs []types.Effect) {
+		for _, c := range cs {
+			for {
+				conds = append(conds, c)
+				collectStrings(c.Params, referenced)
+				if c.Inner == nil {
+					break
+				}
+				c = *c.Inner
+			}
+		}
+		for _, e := range effs {
+			collectStrings(e.Params, referenced)
+			if e.Type != "set_flag" {
+				continue
+			}
+			flag, _ := e.Params["flag"].(string)
+			if _, ok := writers[flag]; !ok {
+				writers[flag] = subject
+			}
+			if v, ok := e.Params["value"].(bool); !ok || v {
+				settable[flag] = true
+			}
+		}
+	})
+
+	// Unreferenced entities: never placed anywhere and never mentioned.
+	for _, r := range rules.AllRules(defs) {
+		referenced[r.When.Object] = true
+		referenced[r.When.Target] = true
+	}
+	for _, id := range sortedKeys(defs.Entities) {
+		if loc, _ := defs.Entities[id].Props["location"].(string); loc != "" {
+			continue
+		}
+		if !referenced[id] {
+			ve.addWarning("entity:"+id, fmt.Sprintf(
+				"entity %q has no location and is never referenced", id))
+		}
+	}
+
+	// Rules whose conditions can never pass.
+	for _, rule := range rules.AllRules(defs) {
+		if reason := impossibleReason(rule, settable); reason != "" {
+			ve.addWarning("rule:"+rule.ID, fmt.Sprintf(
+				"rule %q can never fire: %s", rule.ID, reason))
+		}
+	}
+
+	// Flags set but never read.
+	read := map[string]bool{"game_over": true} // read by the engine to end the game
+	for _, c := range conds {
+		if flag, ok := c.Params["flag"].(string); ok {
+			read[flag] = true
+		}
+	}
+	for _, flag := range sortedKeys(writers) {
+		if !read[flag] {
+			ve.addWarning(writers[flag], fmt.Sprintf(
+				"flag %q is set but never read by any condition", flag))
+		}
+	}
+}
+
+// collectStrings adds every string value in params to set.
+func collectStrings(params map[string]any, set map[string]bool) {
+	for _, v := range params {
+		if s, ok := v.(string); ok {
+			set[s] = true
+		}
+	}
+}
+
+// impossibleReason returns why rule's conditions can never all hold, or ""
+// if they might. It only looks at the rule's top-level conditions.
+func impossibleReason(rule types.RuleDef, settable map[string]bool) string {
+	flags := map[string]bool{} // flag → required value
+	rooms := map[string]bool{}
+	if room, ok := strings.CutPrefix(rule.Scope, "room:"); ok {
+		rooms[room] = true
+	}
+	lower := map[string]int{} // counter must be > lower
+	upper := map[string]int{} // counter must be < upper
+
+	for _, c := range rule.Conditions {
+		typ, params := c.Type, c.Params
+		negate := false
+		if typ == "not" && c.Inner != nil {
+			typ, params, negate = c.Inner.Type, c.Inner.Params, true
+		}
+
+		switch typ {
+		case "flag_set", "flag_not", "flag_is":
+			flag, _ := params["flag"].(string)
+			want := typ != "flag_not"
+			if typ == "flag_is" {
+				want, _ = params["value"].(bool)
+			}
+			if negate {
+				want = !want
+			}
+			if prev, ok := flags[flag]; ok && prev != want {
+				return fmt.Sprintf("flag %q is required to be both set and unset", flag)
+			}
+			flags[flag] = want
+			if want && !settable[flag] {
+				return fmt.Sprintf("flag %q is never set to true", flag)
+			}
+
+		case "in_room":
+			if negate {
+				continue
+			}
+			room, _ := params["room"].(string)
+			if len(rooms) > 0 && !rooms[room] {
+				return fmt.Sprintf("player cannot be in room %q and %q at once", room, firstKey(rooms))
+			}
+			rooms[room] = true
+
+		case "counter_gt", "counter_lt", "counter_eq", "counter_between":
+			if negate {
+				continue
+			}
+			counter, _ := params["counter"].(string)
+			// Bounds are exclusive: counter_eq 5 is greater than 4 and
+			// less than 6.
+			raise := func(value int) {
+				if prev, ok := lower[counter]; !ok || value > prev {
+					lower[counter] = value
+				}
+			}
+			drop := func(value int) {
+				if prev, ok := upper[counter]; !ok || value < prev {
+					upper[counter] = value
+				}
+			}
+			value, hasValue := params["value"].(int)
+			switch typ {
+			case "counter_gt":
+				if !hasValue {
+					continue
+				}
+				raise(value)
+			case "counter_lt":
+				if !hasValue {
+					continue
+				}
+				drop(value)
+			case "counter_eq":
+				if !hasValue {
+					continue
+				}
+				raise(value - 1)
+				drop(value + 1)
+			case "counter_between":
+				lo, okLo := params["min"].(int)
+				hi, okHi := params["max"].(int)
+				if !okLo || !okHi {
+					continue
+				}
+				raise(lo - 1)
+				drop(hi + 1)
+			}
+			lo, hasLo := lower[counter]
+			hi, hasHi := upper[counter]
+			if hasLo && hasHi && hi <= lo+1 {
+				return fmt.Sprintf("counter %q cannot be greater than %d and less than %d", counter, lo, hi)
+			}
+		}
+	}
+	return ""
+}
+
+// firstKey returns the only (or lexically first) key of a non-empty set.
+func firstKey(set map[string]bool) string {
+	return sortedKeys(set)[0]
+}
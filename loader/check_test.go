@@ -0,0 +1,138 @@
+package loader
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// findDiagnostic returns the first diagnostic whose message contains substr.
+func findDiagnostic(t *testing.T, r *Report, substr string) Diagnostic {
+	t.Helper()
+	for _, d := range r.Diagnostics {
+		if strings.Contains(d.Message, substr) {
+			return d
+		}
+	}
+	t.Fatalf("no diagnostic containing %q in %+v", substr, r.Diagnostics)
+	return Diagnostic{}
+}
+
+func TestCheck_CleanGame(t *testing.T) {
+	r, err := Check("testdata/minimal")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if r.Errors != 0 || r.Warnings != 0 {
+		t.Errorf("expected no diagnostics, got %+v", r.Diagnostics)
+	}
+}
+
+func TestCheck_ValidationErrorHasPosition(t *testing.T) {
+	r, err := Check("testdata/invalid_refs")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if r.Errors != 1 {
+		t.Fatalf("Errors = %d, want 1", r.Errors)
+	}
+	d := findDiagnostic(t, r, "nonexistent_room")
+	if d.Severity != "error" || !strings.HasSuffix(d.File, "rooms.lua") || d.Line != 1 {
+		t.Errorf("got %+v, want error at rooms.lua:1", d)
+	}
+	if d.Excerpt != `1 | Room "hall" {` {
+		t.Errorf("Excerpt = %q", d.Excerpt)
+	}
+}
+
+func TestCheck_LuaSyntaxError(t *testing.T) {
+	r, err := Check("testdata/bad_lua")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if r.Errors != 1 {
+		t.Fatalf("Errors = %d, want 1", r.Errors)
+	}
+	d := r.Diagnostics[0]
+	if !strings.HasSuffix(d.File, "game.lua") || d.Line != 1 {
+		t.Errorf("got %+v, want position game.lua:1", d)
+	}
+}
+
+func TestCheck_MissingDirectory(t *testing.T) {
+	if _, err := Check("testdata/does_not_exist"); err == nil {
+		t.Fatal("expected error for missing directory")
+	}
+}
+
+func TestCheck_LintAnalyses(t *testing.T) {
+	r, err := Check("testdata/lint")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if r.Errors != 0 {
+		t.Errorf("Errors = %d, want 0: %+v", r.Errors, r.Diagnostics)
+	}
+
+	tests := []struct {
+		substr string
+		file   string
+		line   int
+	}{
+		{`room "island" is unreachable`, "rooms.lua", 11},
+		{`entity "ghost_key" has no location and is never referenced`, "items.lua", 7},
+		{`rule "contradiction" can never fire: flag "lit"`, "rules.lua", 1},
+		{`rule "needs_unset_flag" can never fire: flag "nobody_sets_this" is never set`, "rules.lua", 12},
+		{`rule "counter_gap" can never fire: counter "jumps"`, "rules.lua", 18},
+		{`flag "write_only" is set but never read`, "rules.lua", 12},
+	}
+	for _, tt := range tests {
+		d := findDiagnostic(t, r, tt.substr)
+		if d.Severity != "warning" || !strings.HasSuffix(d.File, tt.file) || d.Line != tt.line {
+			t.Errorf("%s: got %+v, want warning at %s:%d", tt.substr, d, tt.file, tt.line)
+		}
+	}
+
+	// Entities with a location and flags that are read are not reported.
+	for _, d := range r.Diagnostics {
+		if strings.Contains(d.Message, `"lamp"`) || strings.Contains(d.Message, `flag "lit" is set`) {
+			t.Errorf("unexpected diagnostic: %+v", d)
+		}
+	}
+}
+
+func TestImpossibleReason_RoomScope(t *testing.T) {
+	inRoom := func(room string) types.RuleDef {
+		return types.RuleDef{
+			ID:    "r",
+			Scope: "room:hall",
+			Conditions: []types.Condition{
+				{Type: "in_room", Params: map[string]any{"room": room}},
+			},
+		}
+	}
+
+	if reason := impossibleReason(inRoom("cellar"), nil); !strings.Contains(reason, "cellar") {
+		t.Errorf("reason = %q, expected room conflict", reason)
+	}
+	if reason := impossibleReason(inRoom("hall"), nil); reason != "" {
+		t.Errorf("reason = %q, expected rule to be possible", reason)
+	}
+}
+
+func TestImpossibleReason_CounterRanges(t *testing.T) {
+	rule := func(conds ...types.Condition) types.RuleDef {
+		return types.RuleDef{ID: "r", Conditions: conds}
+	}
+	eq := types.Condition{Type: "counter_eq", Params: map[string]any{"counter": "keys", "value": 3}}
+	gt := types.Condition{Type: "counter_gt", Params: map[string]any{"counter": "keys", "value": 3}}
+	between := types.Condition{Type: "counter_between", Params: map[string]any{"counter": "keys", "min": 1, "max": 3}}
+
+	if reason := impossibleReason(rule(eq, gt), nil); !strings.Contains(reason, `counter "keys"`) {
+		t.Errorf("reason = %q, expected counter conflict", reason)
+	}
+	if reason := impossibleReason(rule(eq, between), nil); reason != "" {
+		t.Errorf("reason = %q, expected rule to be possible", reason)
+	}
+}
@@ -0,0 +1,76 @@
+package loader
+
+import (
+	"sort"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// classicResponse is a stock reply to a classic adventure-game input.
+type classicResponse struct {
+	verbs []string
+	text  string
+}
+
+// classicResponses is the built-in response pack, keyed by the name authors
+// use to override an entry in Game { classic_responses = {...} }.
+var classicResponses = map[string]classicResponse{
+	"xyzzy": {[]string{"xyzzy"}, `A hollow voice says "Fool."`},
+	"plugh": {[]string{"plugh"}, "Nothing happens."},
+	"pray":  {[]string{"pray"}, "If you pray for help, nobody seems to be listening."},
+	"sing":  {[]string{"sing"}, "You hum a few bars. Nobody applauds."},
+	"dance": {[]string{"dance"}, "You shuffle your feet. Nobody is impressed."},
+	"sleep": {[]string{"sleep"}, "You're not tired enough to sleep now."},
+	"yell":  {[]string{"yell"}, "Aaaarrrrgggghhhh!"},
+	"swear": {[]string{"fuck", "shit", "damn", "crap", "bugger", "bother"}, "Such language in a high-class establishment like this!"},
+}
+
+// classicRules returns the global rules answering classic inputs, with the
+// game's overrides applied and silenced entries left out, or nil if the game turned them off. They run at
+// the lowest priority, so any rule the author writes for the same verb wins.
+func classicRules(g types.GameDef, sourceOrder int) []types.RuleDef {
+	if g.NoClassicResponses {
+		return nil
+	}
+	keys := make([]string, 0, len(classicResponses))
+	for key := range classicResponses {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var out []types.RuleDef
+	for _, key := range keys {
+		resp := classicResponses[key]
+		text := resp.text
+		if override, ok := g.ClassicResponses[key]; ok {
+			text = override
+		}
+		if text == "" {
+			continue
+		}
+		for _, verb := range resp.verbs {
+			sourceOrder++
+			out = append(out, types.RuleDef{
+				ID:          "classic_" + verb,
+				Scope:       "global",
+				When:        types.MatchCriteria{Verb: verb},
+				Effects:     []types.Effect{{Type: "say", Params: map[string]any{"text": text}}},
+				Priority:    -1,
+				SourceOrder: sourceOrder,
+			})
+		}
+	}
+	return out
+}
+
+// isClassicVerb reports whether verb is answered by the classic response pack.
+func isClassicVerb(verb string) bool {
+	for _, resp := range classicResponses {
+		for _, v := range resp.verbs {
+			if v == verb {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func TestClassicRules_Default(t *testing.T) {
+	rules := classicRules(types.GameDef{}, 10)
+	byVerb := map[string]types.RuleDef{}
+	for _, r := range rules {
+		byVerb[r.When.Verb] = r
+	}
+	xyzzy, ok := byVerb["xyzzy"]
+	if !ok {
+		t.Fatal("expected a rule for xyzzy")
+	}
+	if xyzzy.Scope != "global" || xyzzy.Priority != -1 || xyzzy.SourceOrder <= 10 {
+		t.Errorf("xyzzy rule = %+v, want global, priority -1, after author rules", xyzzy)
+	}
+	if byVerb["damn"].Effects[0].Params["text"] != byVerb["fuck"].Effects[0].Params["text"] {
+		t.Error("expected swear words to share one response")
+	}
+}
+
+func TestClassicRules_Overrides(t *testing.T) {
+	g := types.GameDef{ClassicResponses: map[string]string{
+		"sing":  "The bard glares at you.",
+		"swear": "",
+	}}
+	for _, r := range classicRules(g, 0) {
+		switch r.When.Verb {
+		case "sing":
+			if r.Effects[0].Params["text"] != "The bard glares at you." {
+				t.Errorf("sing = %v, want override", r.Effects[0].Params["text"])
+			}
+		case "damn", "fuck":
+			t.Errorf("silenced entry still produced rule %q", r.ID)
+		}
+	}
+}
+
+func TestClassicRules_Off(t *testing.T) {
+	if rules := classicRules(types.GameDef{NoClassicResponses: true}, 0); rules != nil {
+		t.Errorf("expected no rules, got %d", len(rules))
+	}
+}
+
+func TestCompileGame_ClassicResponses(t *testing.T) {
+	L, _ := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`return { classic_responses = { sing = "La la.", swear = false } }`); err != nil {
+		t.Fatal(err)
+	}
+	game := compileGame(L.CheckTable(-1))
+	if game.NoClassicResponses {
+		t.Error("table should not turn the pack off")
+	}
+	if game.ClassicResponses["sing"] != "La la." || game.ClassicResponses["swear"] != "" {
+		t.Errorf("ClassicResponses = %v", game.ClassicResponses)
+	}
+
+	if err := L.DoString(`return { classic_responses = false }`); err != nil {
+		t.Fatal(err)
+	}
+	if game := compileGame(L.CheckTable(-1)); !game.NoClassicResponses {
+		t.Error("expected classic_responses = false to turn the pack off")
+	}
+}
+
+func TestValidate_UnknownClassicResponse(t *testing.T) {
+	defs := validDefs()
+	defs.Game.ClassicResponses = map[string]string{"xyzzy": "Hm.", "frobozz": "Hm."}
+
+	ve := analyze(defs)
+	if len(ve.Errors) != 0 {
+		t.Fatalf("unknown classic response should be warning only, got %v", ve.Errors)
+	}
+	if len(ve.Warnings) != 1 {
+		t.Errorf("expected 1 warning, got %v", ve.Warnings)
+	}
+	assertContains(t, ve.Warnings, `no entry "frobozz"`)
+}
@@ -15,6 +15,7 @@ import (
 type rawRoom struct {
 	id    string
 	table *lua.LTable
+	pos   position // where it was defined
 }
 
 // rawEntity holds an entity table before compilation.
@@ -22,6 +23,7 @@ type rawEntity struct {
 	id    string
 	kind  string
 	table *lua.LTable
+	pos   position // where it was defined
 }
 
 // rawRule holds a rule before compilation.
@@ -32,12 +34,49 @@ type rawRule struct {
 	then       *lua.LTable
 	scope      string
 	order      int
+	pos        position // where it was defined
 }
 
 // rawHandler holds an event handler before compilation.
 type rawHandler struct {
 	eventType string
 	table     *lua.LTable
+	pos       position // where it was defined
+}
+
+// rawComputed holds a computed property before compilation.
+type rawComputed struct {
+	id    string
+	table *lua.LTable
+	pos   position // where it was defined
+}
+
+// rawAnswer holds a free-text question before compilation.
+type rawAnswer struct {
+	id    string
+	table *lua.LTable
+	pos   position // where it was defined
+}
+
+// rawAchievement holds an achievement before compilation.
+type rawAchievement struct {
+	id    string
+	table *lua.LTable
+	pos   position // where it was defined
+}
+
+// rawFact holds a fact before compilation.
+type rawFact struct {
+	id    string
+	table *lua.LTable
+	pos   position // where it was defined
+}
+
+// rawMetaCommand holds a game meta-command before compilation.
+type rawMetaCommand struct {
+	name  string
+	table *lua.LTable
+	pos   position // where it was defined
 }
 
 // getString returns a string field from a Lua table, or "" if missing.
@@ -63,6 +102,15 @@ func getInt(tbl *lua.LTable, key string) int {
 	return int(getNumber(tbl, key))
 }
 
+// getDice returns a dice expression field, such as damage = "1d6+2", as a
+// string; a fixed amount, such as damage = 3, becomes "3".
+func getDice(tbl *lua.LTable, key string) string {
+	if n, ok := tbl.RawGetString(key).(lua.LNumber); ok {
+		return n.String()
+	}
+	return getString(tbl, key)
+}
+
 // getTable returns a table field from a Lua table, or nil if missing.
 func getTable(tbl *lua.LTable, key string) *lua.LTable {
 	v := tbl.RawGetString(key)
@@ -72,8 +120,10 @@ func getTable(tbl *lua.LTable, key string) *lua.LTable {
 	return nil
 }
 
-// toGoValue converts a Lua value to a Go value recursively.
-func toGoValue(v lua.LValue) any {
+// GoValue converts a Lua value to a Go value recursively: whole numbers
+// become ints, sequences []any and other tables map[string]any, as game
+// data is compiled.
+func GoValue(v lua.LValue) any {
 	switch val := v.(type) {
 	case lua.LBool:
 		return bool(val)
@@ -93,7 +143,7 @@ func toGoValue(v lua.LValue) any {
 		if maxN > 0 {
 			arr := make([]any, 0, maxN)
 			for i := 1; i <= maxN; i++ {
-				arr = append(arr, toGoValue(val.RawGetInt(i)))
+				arr = append(arr, GoValue(val.RawGetInt(i)))
 			}
 			return arr
 		}
@@ -101,7 +151,7 @@ func toGoValue(v lua.LValue) any {
 		m := map[string]any{}
 		val.ForEach(func(k, v lua.LValue) {
 			if ks, ok := k.(lua.LString); ok {
-				m[string(ks)] = toGoValue(v)
+				m[string(ks)] = GoValue(v)
 			}
 		})
 		return m
@@ -126,6 +176,21 @@ func tableToStringMap(tbl *lua.LTable) map[string]string {
 	return m
 }
 
+// tableToStringSlice converts the array part of a Lua table to a []string.
+// Non-string elements are skipped.
+func tableToStringSlice(tbl *lua.LTable) []string {
+	if tbl == nil {
+		return nil
+	}
+	var out []string
+	for i := 1; i <= tbl.MaxN(); i++ {
+		if s, ok := tbl.RawGetInt(i).(lua.LString); ok {
+			out = append(out, string(s))
+		}
+	}
+	return out
+}
+
 // tableToAnyMap converts a Lua table to a map[string]any.
 func tableToAnyMap(tbl *lua.LTable) map[string]any {
 	if tbl == nil {
@@ -134,7 +199,7 @@ func tableToAnyMap(tbl *lua.LTable) map[string]any {
 	m := map[string]any{}
 	tbl.ForEach(func(k, v lua.LValue) {
 		if ks, ok := k.(lua.LString); ok {
-			m[string(ks)] = toGoValue(v)
+			m[string(ks)] = GoValue(v)
 		}
 	})
 	return m
@@ -157,7 +222,7 @@ func compile(coll *collector) (*state.Defs, error) {
 	for _, raw := range coll.rooms {
 		room, scopedIDs, err := compileRoom(raw)
 		if err != nil {
-			return nil, fmt.Errorf("compiling room %s: %w", raw.id, err)
+			return nil, coll.errorAt(raw.pos, fmt.Errorf("compiling room %s: %w", raw.id, err))
 		}
 		defs.Rooms[room.ID] = room
 		markScopedRules(coll, scopedIDs, "room:"+raw.id)
@@ -167,17 +232,29 @@ func compile(coll *collector) (*state.Defs, error) {
 	for _, raw := range coll.entities {
 		entity, scopedIDs, err := compileEntity(raw)
 		if err != nil {
-			return nil, fmt.Errorf("compiling entity %s: %w", raw.id, err)
+			return nil, coll.errorAt(raw.pos, fmt.Errorf("compiling entity %s: %w", raw.id, err))
 		}
 		defs.Entities[entity.ID] = entity
 		markScopedRules(coll, scopedIDs, "entity:"+raw.id)
 	}
 
+	// An entity's starting inventory is where its items start out, unless
+	// they say otherwise; validation reports the conflict.
+	for _, id := range sortedKeys(defs.Entities) {
+		for _, item := range defs.Entities[id].Inventory {
+			if def, ok := defs.Entities[item]; ok {
+				if _, placed := def.Props["location"]; !placed {
+					def.Props["location"] = id
+				}
+			}
+		}
+	}
+
 	// Rules.
 	for i := range coll.rules {
 		rule, err := compileRule(coll.rules[i])
 		if err != nil {
-			return nil, fmt.Errorf("compiling rule %s: %w", coll.rules[i].id, err)
+			return nil, coll.errorAt(coll.rules[i].pos, fmt.Errorf("compiling rule %s: %w", coll.rules[i].id, err))
 		}
 		switch {
 		case rule.Scope == "global":
@@ -196,19 +273,209 @@ func compile(coll *collector) (*state.Defs, error) {
 			}
 		}
 	}
+	defs.GlobalRules = append(defs.GlobalRules, classicRules(defs.Game, coll.order)...)
 
 	// Handlers.
-	for _, raw := range coll.handlers {
-		handler, err := compileHandler(raw)
+	for i, raw := range coll.handlers {
+		handler, err := compileHandler(raw, i)
 		if err != nil {
-			return nil, fmt.Errorf("compiling handler: %w", err)
+			return nil, coll.errorAt(raw.pos, fmt.Errorf("compiling handler: %w", err))
 		}
 		defs.Handlers = append(defs.Handlers, handler)
 	}
 
+	// Computed properties.
+	for _, raw := range coll.computed {
+		if _, dup := defs.Computed[raw.id]; dup {
+			return nil, coll.errorAt(raw.pos, fmt.Errorf("duplicate computed property %q%s", raw.id, coll.firstDefined("computed:"+raw.id)))
+		}
+		if defs.Computed == nil {
+			defs.Computed = map[string]types.ComputedDef{}
+		}
+		defs.Computed[raw.id] = compileComputed(raw)
+	}
+
+	// Free-text questions.
+	for _, raw := range coll.answers {
+		if _, dup := defs.Answers[raw.id]; dup {
+			return nil, coll.errorAt(raw.pos, fmt.Errorf("duplicate answer %q%s", raw.id, coll.firstDefined("answer:"+raw.id)))
+		}
+		if defs.Answers == nil {
+			defs.Answers = map[string]types.AnswerDef{}
+		}
+		defs.Answers[raw.id] = compileAnswer(raw)
+	}
+
+	// Endings.
+	for _, tbl := range coll.endings {
+		var ids []string
+		tbl.ForEach(func(k, _ lua.LValue) {
+			if id, ok := k.(lua.LString); ok {
+				ids = append(ids, string(id))
+			}
+		})
+		sort.Strings(ids)
+		for _, id := range ids {
+			if _, dup := defs.Endings[id]; dup {
+				return nil, fmt.Errorf("duplicate ending %q%s", id, coll.firstDefined("ending:"+id))
+			}
+			if defs.Endings == nil {
+				defs.Endings = map[string]types.EndingDef{}
+			}
+			defs.Endings[id] = compileEnding(id, getTable(tbl, id))
+		}
+	}
+
+	// Achievements.
+	for i, raw := range coll.achieves {
+		if _, dup := defs.Achievements[raw.id]; dup {
+			return nil, coll.errorAt(raw.pos, fmt.Errorf("duplicate achievement %q%s", raw.id, coll.firstDefined("achievement:"+raw.id)))
+		}
+		if defs.Achievements == nil {
+			defs.Achievements = map[string]types.AchievementDef{}
+		}
+		defs.Achievements[raw.id] = compileAchievement(raw, i)
+	}
+
+	// Facts.
+	for _, raw := range coll.facts {
+		if _, dup := defs.Facts[raw.id]; dup {
+			return nil, coll.errorAt(raw.pos, fmt.Errorf("duplicate fact %q%s", raw.id, coll.firstDefined("fact:"+raw.id)))
+		}
+		if defs.Facts == nil {
+			defs.Facts = map[string]types.FactDef{}
+		}
+		defs.Facts[raw.id] = types.FactDef{ID: raw.id, Text: getString(raw.table, "text")}
+	}
+
+	// Meta-commands.
+	for _, raw := range coll.metas {
+		if _, dup := defs.MetaCommands[raw.name]; dup {
+			return nil, coll.errorAt(raw.pos, fmt.Errorf("duplicate meta-command %q%s", raw.name, coll.firstDefined("meta:"+raw.name)))
+		}
+		if defs.MetaCommands == nil {
+			defs.MetaCommands = map[string]types.MetaCommandDef{}
+		}
+		defs.MetaCommands[raw.name] = compileMetaCommand(raw)
+	}
+
+	defs.Messages = coll.messages
+
 	return defs, nil
 }
 
+// compileAchievement compiles an Achievement declaration. The name defaults
+// to the achievement's ID.
+func compileAchievement(raw rawAchievement, order int) types.AchievementDef {
+	def := types.AchievementDef{
+		ID:          raw.id,
+		Name:        raw.id,
+		Description: getString(raw.table, "description"),
+		Hidden:      lua.LVAsBool(raw.table.RawGetString("hidden")),
+		Order:       order,
+	}
+	if name := getString(raw.table, "name"); name != "" {
+		def.Name = name
+	}
+	return def
+}
+
+// compileMetaCommand compiles a MetaCommand() declaration.
+func compileMetaCommand(raw rawMetaCommand) types.MetaCommandDef {
+	def := types.MetaCommandDef{
+		Name: raw.name,
+		Help: getString(raw.table, "help"),
+		Verb: getString(raw.table, "verb"),
+	}
+	if tbl := getTable(raw.table, "effects"); tbl != nil {
+		def.Effects = compileEffects(tbl)
+	}
+	return def
+}
+
+// compileEnding compiles one entry of an Endings{} table. The title
+// defaults to the ending's ID.
+func compileEnding(id string, tbl *lua.LTable) types.EndingDef {
+	def := types.EndingDef{ID: id, Title: id}
+	if tbl == nil {
+		return def
+	}
+	if title := getString(tbl, "title"); title != "" {
+		def.Title = title
+	}
+	def.Text = getString(tbl, "text")
+	return def
+}
+
+// compileComputed compiles a Computed() declaration. Cases without a "when"
+// table always match.
+func compileComputed(raw rawComputed) types.ComputedDef {
+	def := types.ComputedDef{
+		ID:      raw.id,
+		Uses:    tableToStringSlice(getTable(raw.table, "uses")),
+		Default: GoValue(raw.table.RawGetString("default")),
+	}
+	if casesTbl := getTable(raw.table, "cases"); casesTbl != nil {
+		for i := 1; i <= casesTbl.MaxN(); i++ {
+			caseTbl, ok := casesTbl.RawGetInt(i).(*lua.LTable)
+			if !ok {
+				continue
+			}
+			c := types.ComputedCase{Value: GoValue(caseTbl.RawGetString("value"))}
+			if whenTbl := getTable(caseTbl, "when"); whenTbl != nil {
+				c.When = compileConditions(whenTbl)
+			}
+			def.Cases = append(def.Cases, c)
+		}
+	}
+	return def
+}
+
+// compileAnswer compiles an Answer() declaration. Attempts defaults to 1.
+func compileAnswer(raw rawAnswer) types.AnswerDef {
+	def := types.AnswerDef{
+		ID:       raw.id,
+		Accept:   tableToStringSlice(getTable(raw.table, "accept")),
+		Attempts: 1,
+		Retry:    getString(raw.table, "retry"),
+	}
+	if raw.table.RawGetString("attempts") != lua.LNil {
+		def.Attempts = getInt(raw.table, "attempts")
+	}
+	if tbl := getTable(raw.table, "success"); tbl != nil {
+		def.Success = compileEffects(tbl)
+	}
+	if tbl := getTable(raw.table, "failure"); tbl != nil {
+		def.Failure = compileEffects(tbl)
+	}
+	return def
+}
+
+// compileClock compiles Game.clock. Times are written "HH:MM"; one that
+// does not parse compiles to -1, which validation reports.
+func compileClock(tbl *lua.LTable) *types.ClockDef {
+	clockTime := func(key, def string) int {
+		str := getString(tbl, key)
+		if str == "" {
+			str = def
+		}
+		if minutes, ok := state.ParseClock(str); ok {
+			return minutes
+		}
+		return -1
+	}
+	c := &types.ClockDef{
+		Start:          clockTime("start", "08:00"),
+		MinutesPerTurn: 1,
+		Dawn:           clockTime("dawn", "06:00"),
+		Dusk:           clockTime("dusk", "20:00"),
+	}
+	if tbl.RawGetString("minutes_per_turn") != lua.LNil {
+		c.MinutesPerTurn = getInt(tbl, "minutes_per_turn")
+	}
+	return c
+}
+
 func compileGame(tbl *lua.LTable) types.GameDef {
 	g := types.GameDef{
 		Title:   getString(tbl, "title"),
@@ -216,6 +483,7 @@ func compileGame(tbl *lua.LTable) types.GameDef {
 		Version: getString(tbl, "version"),
 		Start:   getString(tbl, "start"),
 		Intro:   getString(tbl, "intro"),
+		Locale:  getString(tbl, "locale"),
 	}
 	// Player stats for combat.
 	if statsTbl := getTable(tbl, "player_stats"); statsTbl != nil {
@@ -228,17 +496,245 @@ func compileGame(tbl *lua.LTable) types.GameDef {
 			}
 		})
 	}
+	// Level thresholds, from level 2 up.
+	if levelsTbl := getTable(tbl, "levels"); levelsTbl != nil {
+		for i := 1; i <= levelsTbl.MaxN(); i++ {
+			levelTbl, ok := levelsTbl.RawGetInt(i).(*lua.LTable)
+			if !ok {
+				continue
+			}
+			level := types.LevelDef{XP: getInt(levelTbl, "xp"), Stats: map[string]int{}}
+			if statsTbl := getTable(levelTbl, "stats"); statsTbl != nil {
+				statsTbl.ForEach(func(k, v lua.LValue) {
+					if ks, ok := k.(lua.LString); ok {
+						if n, ok := v.(lua.LNumber); ok {
+							level.Stats[string(ks)] = int(n)
+						}
+					}
+				})
+			}
+			g.Levels = append(g.Levels, level)
+		}
+	}
+	// Player characters to switch between.
+	if playersTbl := getTable(tbl, "players"); playersTbl != nil {
+		g.Players = map[string]types.PlayerDef{}
+		playersTbl.ForEach(func(k, v lua.LValue) {
+			id, ok := k.(lua.LString)
+			playerTbl, isTbl := v.(*lua.LTable)
+			if !ok || !isTbl {
+				return
+			}
+			def := types.PlayerDef{
+				Start:     getString(playerTbl, "start"),
+				Inventory: tableToStringSlice(getTable(playerTbl, "inventory")),
+			}
+			if statsTbl := getTable(playerTbl, "stats"); statsTbl != nil {
+				def.Stats = map[string]int{}
+				statsTbl.ForEach(func(k, v lua.LValue) {
+					if ks, ok := k.(lua.LString); ok {
+						if n, ok := v.(lua.LNumber); ok {
+							def.Stats[string(ks)] = int(n)
+						}
+					}
+				})
+			}
+			g.Players[string(id)] = def
+		})
+	}
+	g.StartPlayer = getString(tbl, "start_player")
+	g.MaxScore = getInt(tbl, "max_score")
+	if clockTbl := getTable(tbl, "clock"); clockTbl != nil {
+		g.Clock = compileClock(clockTbl)
+	}
+	// Death policy.
+	g.OnDeath = getString(tbl, "on_death")
+	g.RespawnRoom = getString(tbl, "respawn_room")
+	if penaltyTbl := getTable(tbl, "death_penalty"); penaltyTbl != nil {
+		g.DeathPenalty = types.DeathPenaltyDef{
+			Gold:      getInt(penaltyTbl, "gold"),
+			XP:        getInt(penaltyTbl, "xp"),
+			DropItems: lua.LVAsBool(penaltyTbl.RawGetString("drop_items")),
+		}
+	}
+	// Inventory category display order.
+	g.InventoryCategories = tableToStringSlice(getTable(tbl, "inventory_categories"))
+	// Post-ending "amusing things".
+	if amusingTbl := getTable(tbl, "amusing"); amusingTbl != nil {
+		for i := 1; i <= amusingTbl.MaxN(); i++ {
+			entryTbl, ok := amusingTbl.RawGetInt(i).(*lua.LTable)
+			if !ok {
+				continue
+			}
+			entry := types.AmusingDef{Text: getString(entryTbl, "text")}
+			if condTbl := getTable(entryTbl, "conditions"); condTbl != nil {
+				entry.Conditions = compileConditions(condTbl)
+			}
+			g.Amusing = append(g.Amusing, entry)
+		}
+	}
+	if objectivesTbl := getTable(tbl, "objectives"); objectivesTbl != nil {
+		for i := 1; i <= objectivesTbl.MaxN(); i++ {
+			entryTbl, ok := objectivesTbl.RawGetInt(i).(*lua.LTable)
+			if !ok {
+				continue
+			}
+			objective := types.ObjectiveDef{Text: getString(entryTbl, "text")}
+			if condTbl := getTable(entryTbl, "conditions"); condTbl != nil {
+				objective.Conditions = compileConditions(condTbl)
+			}
+			g.Objectives = append(g.Objectives, objective)
+		}
+	}
+	// Theme: colors may be given as strings or as 256-color numbers.
+	if themeTbl := getTable(tbl, "theme"); themeTbl != nil {
+		g.Theme = map[string]string{}
+		themeTbl.ForEach(func(k, v lua.LValue) {
+			if ks, ok := k.(lua.LString); ok {
+				g.Theme[string(ks)] = v.String()
+			}
+		})
+	}
+	g.AmbienceCooldown = getInt(tbl, "ambience_cooldown")
+	// Noise levels by verb.
+	if noiseTbl := getTable(tbl, "noise"); noiseTbl != nil {
+		g.Noise = map[string]int{}
+		noiseTbl.ForEach(func(k, v lua.LValue) {
+			if ks, ok := k.(lua.LString); ok {
+				if n, ok := v.(lua.LNumber); ok {
+					g.Noise[string(ks)] = int(n)
+				}
+			}
+		})
+	}
+	g.UnknownVerbResponses = tableToStringSlice(getTable(tbl, "unknown_verb_responses"))
+	g.CommandLogLimit = getInt(tbl, "command_log_limit")
+	g.CompactSaves = lua.LVAsBool(tbl.RawGetString("compact_saves"))
+	// Classic responses: false turns the pack off, a table overrides
+	// entries (a false entry silences it).
+	switch v := tbl.RawGetString("classic_responses").(type) {
+	case lua.LBool:
+		g.NoClassicResponses = !bool(v)
+	case *lua.LTable:
+		g.ClassicResponses = map[string]string{}
+		v.ForEach(func(k, val lua.LValue) {
+			if ks, ok := k.(lua.LString); ok {
+				if text, ok := val.(lua.LString); ok {
+					g.ClassicResponses[string(ks)] = string(text)
+				} else {
+					g.ClassicResponses[string(ks)] = ""
+				}
+			}
+		})
+	}
+	// Built-ins: false turns off a verb's built-in behavior.
+	if builtinsTbl := getTable(tbl, "builtins"); builtinsTbl != nil {
+		g.Builtins = map[string]bool{}
+		builtinsTbl.ForEach(func(k, v lua.LValue) {
+			if ks, ok := k.(lua.LString); ok {
+				g.Builtins[string(ks)] = lua.LVAsBool(v)
+			}
+		})
+	}
+	// Idle nudges.
+	if nudgeTbl := getTable(tbl, "idle_nudge"); nudgeTbl != nil {
+		idle := &types.IdleNudgeDef{Minutes: getInt(nudgeTbl, "minutes")}
+		if listTbl := getTable(nudgeTbl, "nudges"); listTbl != nil {
+			for i := 1; i <= listTbl.MaxN(); i++ {
+				entryTbl, ok := listTbl.RawGetInt(i).(*lua.LTable)
+				if !ok {
+					continue
+				}
+				nudge := types.NudgeDef{Text: getString(entryTbl, "text")}
+				if condTbl := getTable(entryTbl, "conditions"); condTbl != nil {
+					nudge.Conditions = compileConditions(condTbl)
+				}
+				idle.Nudges = append(idle.Nudges, nudge)
+			}
+		}
+		g.IdleNudge = idle
+	}
 	return g
 }
 
+// compileExits compiles a room's exits table. An exit is a room ID, or a
+// table naming the room with "to" and guarding it:
+//
+//	east = { to = "bridge", requires = { FlagSet("bridge_lowered") },
+//	         blocked_text = "The bridge is raised.", visible_when_blocked = true }
+//	west = { to = "lake", requires_vehicle = "boat" }
+//	north = { to = "vault", door = "vault_door" }
+//
+// An exit with a door is listed while the door is locked, unless
+// visible_when_blocked says otherwise.
+func compileExits(tbl *lua.LTable) (map[string]string, map[string]types.ExitGuardDef) {
+	if tbl == nil {
+		return nil, nil
+	}
+	exits := map[string]string{}
+	var guards map[string]types.ExitGuardDef
+	tbl.ForEach(func(k, v lua.LValue) {
+		dir, ok := k.(lua.LString)
+		if !ok {
+			return
+		}
+		switch v := v.(type) {
+		case lua.LString:
+			exits[string(dir)] = string(v)
+		case *lua.LTable:
+			exits[string(dir)] = getString(v, "to")
+			guard := types.ExitGuardDef{
+				BlockedText:        getString(v, "blocked_text"),
+				VisibleWhenBlocked: lua.LVAsBool(v.RawGetString("visible_when_blocked")),
+				Vehicle:            getString(v, "requires_vehicle"),
+				Door:               getString(v, "door"),
+			}
+			if guard.Door != "" && v.RawGetString("visible_when_blocked") == lua.LNil {
+				guard.VisibleWhenBlocked = true
+			}
+			if reqTbl := getTable(v, "requires"); reqTbl != nil {
+				guard.Requires = compileConditions(reqTbl)
+			}
+			if guards == nil {
+				guards = map[string]types.ExitGuardDef{}
+			}
+			guards[string(dir)] = guard
+		}
+	})
+	return exits, guards
+}
+
 // compileRoom compiles a raw room into a RoomDef and returns rule IDs scoped to it.
 func compileRoom(raw rawRoom) (types.RoomDef, []string, error) {
 	tbl := raw.table
 	room := types.RoomDef{
-		ID:          raw.id,
-		Description: getString(tbl, "description"),
-		Exits:       tableToStringMap(getTable(tbl, "exits")),
-		Fallbacks:   tableToStringMap(getTable(tbl, "fallbacks")),
+		ID:               raw.id,
+		Description:      getString(tbl, "description"),
+		FirstDescription: getString(tbl, "first_description"),
+		NightDescription: getString(tbl, "night_description"),
+		Image:            getString(tbl, "image"),
+		Fallbacks:        tableToStringMap(getTable(tbl, "fallbacks")),
+		Capacity:         getInt(tbl, "capacity"),
+		Overflow:         getString(tbl, "overflow"),
+	}
+
+	room.Exits, room.ExitGuards = compileExits(getTable(tbl, "exits"))
+
+	if ambienceTbl := getTable(tbl, "ambience"); ambienceTbl != nil {
+		for i := 1; i <= ambienceTbl.MaxN(); i++ {
+			entryTbl, ok := ambienceTbl.RawGetInt(i).(*lua.LTable)
+			if !ok {
+				continue
+			}
+			line := types.AmbienceDef{
+				Chance: getInt(entryTbl, "chance"),
+				Text:   getString(entryTbl, "text"),
+			}
+			if condTbl := getTable(entryTbl, "conditions"); condTbl != nil {
+				line.Conditions = compileConditions(condTbl)
+			}
+			room.Ambience = append(room.Ambience, line)
+		}
 	}
 
 	// Collect scoped rule IDs from the rules field.
@@ -268,12 +764,18 @@ func compileEntity(raw rawEntity) (types.EntityDef, []string, error) {
 
 	// Special fields that don't go into Props (handled separately).
 	skip := map[string]bool{
-		"rules": true, "topics": true,
+		"rules": true, "topics": true, "reactions": true,
+		"on_receive": true, "on_show": true, "inventory": true, "lock": true,
+	}
+	// An item's combat_use, and an enemy's stats/behavior/loot, are
+	// compiled into typed structs.
+	if raw.kind == "item" {
+		skip["combat_use"] = true
 	}
-	// For enemies, stats/behavior/loot are compiled into typed structs.
 	if raw.kind == "enemy" {
 		skip["stats"] = true
 		skip["behavior"] = true
+		skip["attacks"] = true
 		skip["loot"] = true
 	}
 
@@ -282,7 +784,7 @@ func compileEntity(raw rawEntity) (types.EntityDef, []string, error) {
 		if ks, ok := k.(lua.LString); ok {
 			key := string(ks)
 			if !skip[key] {
-				entity.Props[key] = toGoValue(v)
+				entity.Props[key] = GoValue(v)
 			}
 		}
 	})
@@ -294,6 +796,37 @@ func compileEntity(raw rawEntity) (types.EntityDef, []string, error) {
 		}
 	}
 
+	if useTbl := getTable(tbl, "combat_use"); useTbl != nil && raw.kind == "item" {
+		entity.Props["combat_use"] = types.CombatUse{
+			Damage:   getDice(useTbl, "damage"),
+			Heal:     getDice(useTbl, "heal"),
+			Consumes: lua.LVAsBool(useTbl.RawGetString("consumes")),
+			Message:  getString(useTbl, "message"),
+		}
+	}
+
+	// A lock = { key = "...", locked = bool } makes a door or container
+	// lockable, locked unless it says otherwise.
+	if lockTbl := getTable(tbl, "lock"); lockTbl != nil {
+		entity.Props["lockable"] = true
+		entity.Props["lock_key"] = getString(lockTbl, "key")
+		entity.Props["locked"] = lockTbl.RawGetString("locked") != lua.LFalse
+	}
+
+	// A description may be a list of texts, shown by successive examines
+	// with the last repeating; description holds that last one.
+	if descTbl := getTable(tbl, "description"); descTbl != nil {
+		texts := []string{}
+		for i := 1; i <= descTbl.MaxN(); i++ {
+			texts = append(texts, lua.LVAsString(descTbl.RawGetInt(i)))
+		}
+		entity.Props["descriptions"] = texts
+		delete(entity.Props, "description")
+		if len(texts) > 0 {
+			entity.Props["description"] = texts[len(texts)-1]
+		}
+	}
+
 	// Enemy: compile stats, behavior, loot into Props.
 	if raw.kind == "enemy" {
 		compileEnemyProps(tbl, entity.Props)
@@ -304,6 +837,31 @@ func compileEntity(raw rawEntity) (types.EntityDef, []string, error) {
 		entity.Topics = compileTopics(topicsTbl)
 	}
 
+	// Items the entity starts out carrying.
+	if invTbl := getTable(tbl, "inventory"); invTbl != nil {
+		entity.Inventory = tableToStringSlice(invTbl)
+	}
+
+	// Responses to being given or shown items.
+	entity.OnReceive = compileItemResponses(getTable(tbl, "on_receive"))
+	entity.OnShow = compileItemResponses(getTable(tbl, "on_show"))
+
+	// Reactions to events in the entity's room, in event-type order.
+	if reactTbl := getTable(tbl, "reactions"); reactTbl != nil {
+		handlers := map[string]*lua.LTable{}
+		reactTbl.ForEach(func(k, v lua.LValue) {
+			ks, ok := k.(lua.LString)
+			vt, isTbl := v.(*lua.LTable)
+			if ok && isTbl {
+				handlers[string(ks)] = vt
+			}
+		})
+		for _, eventType := range sortedKeys(handlers) {
+			id := "entity:" + raw.id + ":" + eventType
+			entity.Reactions = append(entity.Reactions, compileHandlerTable(id, eventType, handlers[eventType]))
+		}
+	}
+
 	// Collect scoped rule IDs.
 	var scopedIDs []string
 	if rulesTable := getTable(tbl, "rules"); rulesTable != nil {
@@ -350,12 +908,38 @@ func compileEnemyProps(tbl *lua.LTable, props map[string]any) {
 					Action: getString(entryTbl, "action"),
 					Weight: getInt(entryTbl, "weight"),
 				}
+				if whenTbl := getTable(entryTbl, "when"); whenTbl != nil {
+					entry.When = compileConditions(whenTbl)
+				}
+				if seqTbl := getTable(entryTbl, "sequence"); seqTbl != nil {
+					entry.Sequence = tableToStringSlice(seqTbl)
+				}
 				behavior = append(behavior, entry)
 			}
 		})
 		props["behavior"] = behavior
 	}
 
+	// Attacks: compile to []types.AttackDef.
+	if attacksTbl := getTable(tbl, "attacks"); attacksTbl != nil {
+		var attacks []types.AttackDef
+		attacksTbl.ForEach(func(k, v lua.LValue) {
+			if _, ok := k.(lua.LNumber); !ok {
+				return
+			}
+			if entryTbl, ok := v.(*lua.LTable); ok {
+				attacks = append(attacks, types.AttackDef{
+					Name:    getString(entryTbl, "name"),
+					Damage:  getDice(entryTbl, "damage"),
+					Chance:  getInt(entryTbl, "chance"),
+					Message: getString(entryTbl, "message"),
+					Effect:  getString(entryTbl, "effect"),
+				})
+			}
+		})
+		props["attacks"] = attacks
+	}
+
 	// Loot: compile to []types.LootEntry + loot_gold.
 	if lootTbl := getTable(tbl, "loot"); lootTbl != nil {
 		if itemsTbl := getTable(lootTbl, "items"); itemsTbl != nil {
@@ -374,8 +958,9 @@ func compileEnemyProps(tbl *lua.LTable, props map[string]any) {
 			})
 			props["loot_items"] = lootItems
 		}
-		gold := getInt(lootTbl, "gold")
-		if gold > 0 {
+		if expr, ok := lootTbl.RawGetString("gold").(lua.LString); ok {
+			props["loot_gold"] = string(expr) // dice, such as "2d6"
+		} else if gold := getInt(lootTbl, "gold"); gold > 0 {
 			props["loot_gold"] = gold
 		}
 	}
@@ -393,7 +978,9 @@ func compileTopics(tbl *lua.LTable) map[string]types.TopicDef {
 			return
 		}
 		topic := types.TopicDef{
-			Text: getString(topicTbl, "text"),
+			Text:  getString(topicTbl, "text"),
+			Label: getString(topicTbl, "label"),
+			Once:  lua.LVAsBool(topicTbl.RawGetString("once")),
 		}
 		if reqTbl := getTable(topicTbl, "requires"); reqTbl != nil {
 			topic.Requires = compileConditions(reqTbl)
@@ -406,6 +993,34 @@ func compileTopics(tbl *lua.LTable) map[string]types.TopicDef {
 	return topics
 }
 
+// compileItemResponses compiles an on_receive or on_show table, keyed by
+// item ID. A nil table gives a nil map.
+func compileItemResponses(tbl *lua.LTable) map[string]types.ItemResponseDef {
+	if tbl == nil {
+		return nil
+	}
+	responses := map[string]types.ItemResponseDef{}
+	tbl.ForEach(func(k, v lua.LValue) {
+		item, ok := k.(lua.LString)
+		if !ok {
+			return
+		}
+		respTbl, ok := v.(*lua.LTable)
+		if !ok {
+			return
+		}
+		resp := types.ItemResponseDef{Text: getString(respTbl, "text")}
+		if condTbl := getTable(respTbl, "conditions"); condTbl != nil {
+			resp.Conditions = compileConditions(condTbl)
+		}
+		if effTbl := getTable(respTbl, "effects"); effTbl != nil {
+			resp.Effects = compileEffects(effTbl)
+		}
+		responses[string(item)] = resp
+	})
+	return responses
+}
+
 func compileRule(raw rawRule) (types.RuleDef, error) {
 	rule := types.RuleDef{
 		ID:          raw.id,
@@ -417,8 +1032,9 @@ func compileRule(raw rawRule) (types.RuleDef, error) {
 	if raw.conditions != nil {
 		rule.Conditions = compileConditions(raw.conditions)
 	}
-	// Check for priority in the When table.
+	// Check for priority and once in the When table.
 	rule.Priority = getInt(raw.when, "priority")
+	rule.Once = lua.LVAsBool(raw.when.RawGetString("once"))
 	return rule, nil
 }
 
@@ -428,6 +1044,11 @@ func compileMatchCriteria(tbl *lua.LTable) types.MatchCriteria {
 		Object:     getString(tbl, "object"),
 		Target:     getString(tbl, "target"),
 		ObjectKind: getString(tbl, "object_kind"),
+		Phase:      getString(tbl, "phase"),
+	}
+	if v := tbl.RawGetString("succeeded"); v != lua.LNil {
+		succeeded := lua.LVAsBool(v)
+		mc.Succeeded = &succeeded
 	}
 	if tp := getTable(tbl, "target_prop"); tp != nil {
 		mc.TargetProp = tableToAnyMap(tp)
@@ -472,7 +1093,7 @@ func compileCondition(tbl *lua.LTable) types.Condition {
 		if ks, ok := k.(lua.LString); ok {
 			key := string(ks)
 			if key != "type" {
-				params[key] = toGoValue(v)
+				params[key] = GoValue(v)
 			}
 		}
 	})
@@ -503,7 +1124,7 @@ func compileEffect(tbl *lua.LTable) types.Effect {
 		if ks, ok := k.(lua.LString); ok {
 			key := string(ks)
 			if key != "type" {
-				params[key] = toGoValue(v)
+				params[key] = GoValue(v)
 			}
 		}
 	})
@@ -513,20 +1134,31 @@ func compileEffect(tbl *lua.LTable) types.Effect {
 	}
 }
 
-func compileHandler(raw rawHandler) (types.EventHandler, error) {
+func compileHandler(raw rawHandler, i int) (types.EventHandler, error) {
+	return compileHandlerTable(handlerSubject(i), raw.eventType, raw.table), nil
+}
+
+// compileHandlerTable compiles the table of an On() handler or an entity
+// reaction. defaultID names the handler if the table doesn't.
+func compileHandlerTable(defaultID, eventType string, tbl *lua.LTable) types.EventHandler {
 	handler := types.EventHandler{
-		EventType: raw.eventType,
+		ID:        getString(tbl, "id"),
+		EventType: eventType,
+		Priority:  getInt(tbl, "priority"),
+		Once:      lua.LVAsBool(tbl.RawGetString("once")),
+	}
+	if handler.ID == "" {
+		handler.ID = defaultID
 	}
 
 	// The handler table has conditions and effects.
-	if condTbl := getTable(raw.table, "conditions"); condTbl != nil {
+	if condTbl := getTable(tbl, "conditions"); condTbl != nil {
 		handler.Conditions = compileConditions(condTbl)
 	}
-	if effTbl := getTable(raw.table, "effects"); effTbl != nil {
+	if effTbl := getTable(tbl, "effects"); effTbl != nil {
 		handler.Effects = compileEffects(effTbl)
 	}
-
-	return handler, nil
+	return handler
 }
 
 // markScopedRules updates raw rules in the collector to set their scope.
@@ -26,7 +26,31 @@ func TestCompileGame(t *testing.T) {
 			author = "Author",
 			version = "1.0",
 			start = "hall",
-			intro = "Welcome!"
+			intro = "Welcome!",
+			inventory_categories = { "weapons", "keys" },
+			command_log_limit = 200,
+			levels = { { xp = 100, stats = { attack = 1 } } },
+			compact_saves = true,
+			start_player = "alice",
+			players = {
+				alice = {},
+				bob = { start = "cellar", stats = { hp = 7 }, inventory = { "lamp" } }
+			},
+			unknown_verb_responses = { "Eh?", "Pardon?" },
+			amusing = {
+				{ text = "Tried singing?", conditions = { FlagNot("sang") } },
+				{ text = "Petted the dog?" }
+			},
+			objectives = {
+				{ text = "Find the key.", conditions = { FlagNot("has_key") } }
+			},
+			theme = { preset = "light", header = "#8b0000", dialogue = 130 },
+			idle_nudge = {
+				minutes = 5,
+				nudges = {
+					{ text = "Try the door.", conditions = { InRoom("hall") } }
+				}
+			}
 		}
 	`); err != nil {
 		t.Fatal(err)
@@ -50,6 +74,59 @@ func TestCompileGame(t *testing.T) {
 	if game.Intro != "Welcome!" {
 		t.Errorf("Intro = %q, want %q", game.Intro, "Welcome!")
 	}
+	if len(game.InventoryCategories) != 2 || game.InventoryCategories[0] != "weapons" || game.InventoryCategories[1] != "keys" {
+		t.Errorf("InventoryCategories = %v, want [weapons keys]", game.InventoryCategories)
+	}
+	if len(game.Levels) != 1 || game.Levels[0].XP != 100 || game.Levels[0].Stats["attack"] != 1 {
+		t.Errorf("Levels = %v, want [{100 map[attack:1]}]", game.Levels)
+	}
+	if bob := game.Players["bob"]; game.StartPlayer != "alice" || len(game.Players) != 2 ||
+		bob.Start != "cellar" || bob.Stats["hp"] != 7 || len(bob.Inventory) != 1 || bob.Inventory[0] != "lamp" {
+		t.Errorf("StartPlayer = %q, Players = %v", game.StartPlayer, game.Players)
+	}
+	if game.CommandLogLimit != 200 || !game.CompactSaves {
+		t.Errorf("CommandLogLimit = %d, CompactSaves = %v, want 200, true", game.CommandLogLimit, game.CompactSaves)
+	}
+	if r := game.UnknownVerbResponses; len(r) != 2 || r[0] != "Eh?" || r[1] != "Pardon?" {
+		t.Errorf("UnknownVerbResponses = %v, want [Eh? Pardon?]", r)
+	}
+	if len(game.Amusing) != 2 {
+		t.Fatalf("Amusing len = %d, want 2", len(game.Amusing))
+	}
+	if game.Amusing[0].Text != "Tried singing?" || len(game.Amusing[0].Conditions) != 1 {
+		t.Errorf("Amusing[0] = %+v, want text with one condition", game.Amusing[0])
+	}
+	if game.Amusing[1].Text != "Petted the dog?" || len(game.Amusing[1].Conditions) != 0 {
+		t.Errorf("Amusing[1] = %+v, want text without conditions", game.Amusing[1])
+	}
+	if game.IdleNudge == nil || game.IdleNudge.Minutes != 5 || len(game.IdleNudge.Nudges) != 1 {
+		t.Fatalf("IdleNudge = %+v, want 5 minutes with one nudge", game.IdleNudge)
+	}
+	if n := game.IdleNudge.Nudges[0]; n.Text != "Try the door." || len(n.Conditions) != 1 {
+		t.Errorf("IdleNudge.Nudges[0] = %+v, want text with one condition", n)
+	}
+	if len(game.Objectives) != 1 || game.Objectives[0].Text != "Find the key." || len(game.Objectives[0].Conditions) != 1 {
+		t.Errorf("Objectives = %+v, want one with a condition", game.Objectives)
+	}
+	if th := game.Theme; len(th) != 3 || th["preset"] != "light" || th["header"] != "#8b0000" || th["dialogue"] != "130" {
+		t.Errorf("Theme = %v, want light with header and dialogue", th)
+	}
+}
+
+func TestCompileGame_Builtins(t *testing.T) {
+	L, _ := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`return { builtins = { take = false, go = true } }`); err != nil {
+		t.Fatal(err)
+	}
+	game := compileGame(L.CheckTable(-1))
+	if on, set := game.Builtins["take"]; on || !set {
+		t.Errorf("take = %v (set %v), want false", on, set)
+	}
+	if !game.Builtins["go"] {
+		t.Errorf("go should stay on: %v", game.Builtins)
+	}
 }
 
 func TestCompileRoom_WithExitsAndFallbacks(t *testing.T) {
@@ -63,8 +140,22 @@ func TestCompileRoom_WithExitsAndFallbacks(t *testing.T) {
 		)
 		Room "hall" {
 			description = "A grand hall.",
-			exits = { north = "garden", south = "cellar" },
+			first_description = "You enter the grand hall for the first time.",
+			image = "art/hall.png",
+			exits = {
+				north = "garden", south = "cellar",
+				east = { to = "bridge", requires = { FlagSet("bridge_lowered") },
+				         blocked_text = "The bridge is raised.", visible_when_blocked = true },
+				west = { to = "lake", requires_vehicle = "boat" },
+				up = { to = "vault", door = "hatch" },
+			},
 			fallbacks = { push = "Nothing to push." },
+			capacity = 3,
+			overflow = "adjacent",
+			ambience = {
+				{ chance = 20, text = "A rat scurries past." },
+				{ chance = 5, text = "Bats stir.", conditions = { FlagSet("night") } },
+			},
 			rules = { r }
 		}
 	`); err != nil {
@@ -86,15 +177,46 @@ func TestCompileRoom_WithExitsAndFallbacks(t *testing.T) {
 	if room.Description != "A grand hall." {
 		t.Errorf("Description = %q, want %q", room.Description, "A grand hall.")
 	}
+	if room.FirstDescription != "You enter the grand hall for the first time." {
+		t.Errorf("FirstDescription = %q", room.FirstDescription)
+	}
+	if room.Image != "art/hall.png" {
+		t.Errorf("Image = %q", room.Image)
+	}
 	if room.Exits["north"] != "garden" {
 		t.Errorf("Exits[north] = %q, want %q", room.Exits["north"], "garden")
 	}
 	if room.Exits["south"] != "cellar" {
 		t.Errorf("Exits[south] = %q, want %q", room.Exits["south"], "cellar")
 	}
+	if room.Exits["east"] != "bridge" {
+		t.Errorf("Exits[east] = %q, want %q", room.Exits["east"], "bridge")
+	}
+	guard, ok := room.ExitGuards["east"]
+	if !ok || len(guard.Requires) != 1 || guard.Requires[0].Type != "flag_set" ||
+		guard.BlockedText != "The bridge is raised." || !guard.VisibleWhenBlocked {
+		t.Errorf("ExitGuards[east] = %+v", guard)
+	}
+	if guard := room.ExitGuards["west"]; room.Exits["west"] != "lake" || guard.Vehicle != "boat" {
+		t.Errorf("west exit = %q, guard %+v", room.Exits["west"], guard)
+	}
+	if guard := room.ExitGuards["up"]; guard.Door != "hatch" || !guard.VisibleWhenBlocked {
+		t.Errorf("up exit guard = %+v, want the hatch, listed while locked", guard)
+	}
+	if _, ok := room.ExitGuards["north"]; ok {
+		t.Error("a plain exit should have no guard")
+	}
 	if room.Fallbacks["push"] != "Nothing to push." {
 		t.Errorf("Fallbacks[push] = %q, want %q", room.Fallbacks["push"], "Nothing to push.")
 	}
+	if room.Capacity != 3 || room.Overflow != "adjacent" {
+		t.Errorf("Capacity/Overflow = %d/%q, want 3/adjacent", room.Capacity, room.Overflow)
+	}
+	if len(room.Ambience) != 2 || room.Ambience[0].Chance != 20 || room.Ambience[0].Text != "A rat scurries past." {
+		t.Errorf("Ambience = %+v", room.Ambience)
+	} else if len(room.Ambience[1].Conditions) != 1 || room.Ambience[1].Conditions[0].Type != "flag_set" {
+		t.Errorf("Ambience[1].Conditions = %+v", room.Ambience[1].Conditions)
+	}
 	if len(scopedIDs) != 1 || scopedIDs[0] != "room_rule" {
 		t.Errorf("scopedIDs = %v, want [room_rule]", scopedIDs)
 	}
@@ -153,6 +275,36 @@ func TestCompileEntity_ItemExplicitTakeableFalse(t *testing.T) {
 	}
 }
 
+func TestCompileEntity_Lock(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Entity "chest" { name = "chest", lock = { key = "rusty_key" } }
+		Entity "gate" { name = "gate", lock = { key = "iron_key", locked = false } }
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	chest, _, err := compileEntity(coll.entities[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chest.Props["lockable"] != true || chest.Props["lock_key"] != "rusty_key" || chest.Props["locked"] != true {
+		t.Errorf("chest props = %v, want locked with the rusty key", chest.Props)
+	}
+	if _, ok := chest.Props["lock"]; ok {
+		t.Error("the lock table should not be kept as a prop")
+	}
+	gate, _, err := compileEntity(coll.entities[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gate.Props["locked"] != false {
+		t.Errorf("gate locked = %v, want false", gate.Props["locked"])
+	}
+}
+
 func TestCompileEntity_NPCWithTopics(t *testing.T) {
 	L, coll := newTestVM()
 	defer L.Close()
@@ -168,6 +320,8 @@ func TestCompileEntity_NPCWithTopics(t *testing.T) {
 				},
 				quest = {
 					text = "Find the gem.",
+					label = "The lost gem",
+					once = true,
 					requires = { FlagSet("met_guard") },
 					effects = { SetFlag("quest_given", true) }
 				}
@@ -194,6 +348,9 @@ func TestCompileEntity_NPCWithTopics(t *testing.T) {
 	if entity.Topics["quest"].Text != "Find the gem." {
 		t.Errorf("quest.Text = %q, want %q", entity.Topics["quest"].Text, "Find the gem.")
 	}
+	if q := entity.Topics["quest"]; q.Label != "The lost gem" || !q.Once {
+		t.Errorf("quest Label/Once = %q/%v, want \"The lost gem\"/true", q.Label, q.Once)
+	}
 	if len(entity.Topics["quest"].Requires) != 1 {
 		t.Fatalf("quest.Requires length = %d, want 1", len(entity.Topics["quest"].Requires))
 	}
@@ -203,6 +360,88 @@ func TestCompileEntity_NPCWithTopics(t *testing.T) {
 	}
 }
 
+func TestCompileEntity_NPCItemResponses(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		NPC "guard" {
+			name = "guard",
+			location = "hall",
+			on_receive = {
+				coin = {
+					text = "Off you go, then.",
+					conditions = { FlagNot("alarm") },
+					effects = { OpenExit("hall", "north", "vault") }
+				}
+			},
+			on_show = {
+				badge = { text = "Sorry, sir." }
+			}
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	entity, _, err := compileEntity(coll.entities[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coin, ok := entity.OnReceive["coin"]
+	if !ok {
+		t.Fatalf("expected on_receive coin, got %v", entity.OnReceive)
+	}
+	if coin.Text != "Off you go, then." || len(coin.Conditions) != 1 || len(coin.Effects) != 1 {
+		t.Errorf("coin response = %+v", coin)
+	}
+	if entity.OnShow["badge"].Text != "Sorry, sir." {
+		t.Errorf("on_show badge = %+v", entity.OnShow["badge"])
+	}
+	if _, ok := entity.Props["on_receive"]; ok {
+		t.Error("on_receive should not be compiled as a prop")
+	}
+}
+
+func TestCompileEntity_NPCWithReactions(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		NPC "guard" {
+			name = "guard",
+			location = "hall",
+			reactions = {
+				item_taken = {
+					conditions = { HasItem("gem") },
+					effects = { Say("Hey! Put that back.") }
+				},
+				combat_started = { once = true, effects = { Say("The guard draws a sword.") } }
+			}
+		}
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	entity, _, err := compileEntity(coll.entities[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entity.Props["reactions"]; ok {
+		t.Error("reactions should not be compiled into Props")
+	}
+	if len(entity.Reactions) != 2 {
+		t.Fatalf("expected 2 reactions, got %d", len(entity.Reactions))
+	}
+	combat, taken := entity.Reactions[0], entity.Reactions[1]
+	if combat.EventType != "combat_started" || !combat.Once || combat.ID != "entity:guard:combat_started" {
+		t.Errorf("combat reaction = %+v", combat)
+	}
+	if taken.EventType != "item_taken" || len(taken.Conditions) != 1 || len(taken.Effects) != 1 {
+		t.Errorf("item_taken reaction = %+v", taken)
+	}
+}
+
 func TestCompileEntity_GenericEntity(t *testing.T) {
 	L, coll := newTestVM()
 	defer L.Close()
@@ -253,7 +492,13 @@ func TestCompileConditions_AllTypes(t *testing.T) {
 		{`PropIs("door", "locked", true)`, "prop_is", "entity", "door"},
 		{`CounterGt("turns", 5)`, "counter_gt", "counter", "turns"},
 		{`CounterLt("health", 3)`, "counter_lt", "counter", "health"},
+		{`CounterEq("keys", 2)`, "counter_eq", "counter", "keys"},
+		{`PropGt("plant", "growth", 2)`, "prop_gt", "prop", "growth"},
+		{`PropLt("boiler", "pressure", 9)`, "prop_lt", "entity", "boiler"},
+		{`CounterBetween("gold", 10, 20)`, "counter_between", "counter", "gold"},
+		{`CounterCmp("gold", ">=", "price")`, "counter_cmp", "other", "price"},
 		{`Not(FlagSet("done"))`, "not", "", nil},
+		{`ComputedIs("door_status", "open")`, "computed_is", "computed", "door_status"},
 	}
 
 	for _, tt := range tests {
@@ -300,11 +545,17 @@ func TestCompileEffects_AllTypes(t *testing.T) {
 		wantVal  any
 	}{
 		{`Say("hello")`, "say", "text", "hello"},
+		{`Notify("+10 points")`, "notify", "text", "+10 points"},
 		{`GiveItem("key")`, "give_item", "item", "key"},
 		{`RemoveItem("key")`, "remove_item", "item", "key"},
+		{`GiveItem("coin", 3)`, "give_item", "amount", 3},
 		{`SetFlag("done", true)`, "set_flag", "flag", "done"},
 		{`IncCounter("score", 10)`, "inc_counter", "counter", "score"},
 		{`SetCounter("lives", 3)`, "set_counter", "counter", "lives"},
+		{`DecCounter("gold", "1d4")`, "dec_counter", "counter", "gold"},
+		{`MulCounter("gold", 2)`, "mul_counter", "counter", "gold"},
+		{`IncProp("plant", "growth", 1)`, "inc_prop", "prop", "growth"},
+		{`DecProp("boiler", "pressure", "1d3")`, "dec_prop", "entity", "boiler"},
 		{`SetProp("door", "locked", false)`, "set_prop", "entity", "door"},
 		{`MoveEntity("guard", "hall")`, "move_entity", "entity", "guard"},
 		{`MovePlayer("garden")`, "move_player", "room", "garden"},
@@ -479,6 +730,57 @@ func TestCompileRule_WithConditions(t *testing.T) {
 	}
 }
 
+func TestCompileRule_Once(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Rule("first_look", When { verb = "look", once = true }, Then { Say("Dust swirls.") })
+		Rule("every_look", When { verb = "look" }, Then { Say("Dust.") })
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := compileRule(coll.rules[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	every, err := compileRule(coll.rules[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Once || every.Once {
+		t.Errorf("Once = %v, %v; want true, false", first.Once, every.Once)
+	}
+}
+
+func TestCompileRule_Phase(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Rule("after_take", When { verb = "take", phase = "after", succeeded = false }, Then { Say("Oops.") })
+		Rule("plain_take", When { verb = "take" }, Then { Say("Taken.") })
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := compileRule(coll.rules[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.When.Phase != "after" || after.When.Succeeded == nil || *after.When.Succeeded {
+		t.Errorf("When = %+v, want after phase with succeeded = false", after.When)
+	}
+	plain, err := compileRule(coll.rules[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain.When.Phase != "" || plain.When.Succeeded != nil {
+		t.Errorf("When = %+v, want no phase", plain.When)
+	}
+}
+
 func TestCompileRule_WithoutConditions(t *testing.T) {
 	L, coll := newTestVM()
 	defer L.Close()
@@ -525,7 +827,7 @@ func TestCompileHandler(t *testing.T) {
 		t.Fatalf("expected 1 handler, got %d", len(coll.handlers))
 	}
 
-	handler, err := compileHandler(coll.handlers[0])
+	handler, err := compileHandler(coll.handlers[0], 0)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -533,6 +835,9 @@ func TestCompileHandler(t *testing.T) {
 	if handler.EventType != "door_opened" {
 		t.Errorf("EventType = %q, want %q", handler.EventType, "door_opened")
 	}
+	if handler.ID != "handler:0" || handler.Priority != 0 || handler.Once {
+		t.Errorf("ID/Priority/Once = %q/%d/%v, want handler:0/0/false", handler.ID, handler.Priority, handler.Once)
+	}
 	if len(handler.Conditions) != 1 {
 		t.Fatalf("expected 1 condition, got %d", len(handler.Conditions))
 	}
@@ -544,6 +849,30 @@ func TestCompileHandler(t *testing.T) {
 	}
 }
 
+func TestCompileHandler_PriorityOnce(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		On("item_*", {
+			id = "first_find",
+			priority = 5,
+			once = true,
+			effects = { Say("Your first find!") }
+		})
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	handler, err := compileHandler(coll.handlers[0], 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handler.ID != "first_find" || handler.EventType != "item_*" || handler.Priority != 5 || !handler.Once {
+		t.Errorf("handler = %+v", handler)
+	}
+}
+
 func TestSourceOrder_AutoIncrement(t *testing.T) {
 	L, coll := newTestVM()
 	defer L.Close()
@@ -566,3 +895,292 @@ func TestSourceOrder_AutoIncrement(t *testing.T) {
 		}
 	}
 }
+
+func TestCompile_Computed(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Game { title = "T", start = "hall" }
+		Computed("door_status", {
+			uses = { "flags.door_open" },
+			cases = {
+				{ when = { FlagSet("door_open") }, value = "open" },
+			},
+			default = "closed"
+		})
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := compile(coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := defs.Computed["door_status"]
+	if !ok {
+		t.Fatal("expected computed property door_status")
+	}
+	if len(c.Uses) != 1 || c.Uses[0] != "flags.door_open" {
+		t.Errorf("Uses = %v, want [flags.door_open]", c.Uses)
+	}
+	if len(c.Cases) != 1 || c.Cases[0].Value != "open" || len(c.Cases[0].When) != 1 {
+		t.Errorf("Cases = %+v, want one case with value open", c.Cases)
+	}
+	if c.Default != "closed" {
+		t.Errorf("Default = %v, want closed", c.Default)
+	}
+}
+
+func TestCompile_DuplicateComputed_Fails(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Game { title = "T", start = "hall" }
+		Computed("x", { cases = { { value = 1 } } })
+		Computed("x", { cases = { { value = 2 } } })
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := compile(coll); err == nil {
+		t.Fatal("expected error for duplicate computed property")
+	}
+}
+
+func TestCompile_MetaCommand(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Game { title = "T", start = "hall" }
+		MetaCommand("/spells", { help = "List your spells", effects = { Say("You know no spells.") } })
+		MetaCommand("/map", { verb = "map" })
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := compile(coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spells := defs.MetaCommands["/spells"]
+	if spells.Name != "/spells" || spells.Help != "List your spells" || len(spells.Effects) != 1 || spells.Effects[0].Type != "say" {
+		t.Errorf("/spells = %+v", spells)
+	}
+	if got := defs.MetaCommands["/map"].Verb; got != "map" {
+		t.Errorf("/map verb = %q, want map", got)
+	}
+}
+
+func TestCompile_DuplicateMetaCommand(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Game { title = "T", start = "hall" }
+		MetaCommand("/map", { verb = "map" })
+		MetaCommand("/map", { verb = "look" })
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := compile(coll); err == nil {
+		t.Fatal("expected error for duplicate meta-command")
+	}
+}
+
+func TestCompile_Answer(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Game { title = "T", start = "hall" }
+		Answer("riddle_sphinx", {
+			accept = { "time", "the time" },
+			attempts = 3,
+			retry = "The sphinx waits.",
+			success = { SetFlag("sphinx_solved", true) },
+			failure = { Say("The sphinx pounces!") }
+		})
+		Answer("password", { accept = { "swordfish" } })
+		Rule("ask_sphinx", When { verb = "talk", object = "sphinx" }, { Ask("riddle_sphinx") })
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := compile(coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, ok := defs.Answers["riddle_sphinx"]
+	if !ok {
+		t.Fatal("expected answer riddle_sphinx")
+	}
+	if len(a.Accept) != 2 || a.Attempts != 3 || a.Retry != "The sphinx waits." {
+		t.Errorf("answer = %+v, want two accepted answers, 3 attempts and retry text", a)
+	}
+	if len(a.Success) != 1 || a.Success[0].Type != "set_flag" || len(a.Failure) != 1 {
+		t.Errorf("Success = %v, Failure = %v", a.Success, a.Failure)
+	}
+	if got := defs.Answers["password"].Attempts; got != 1 {
+		t.Errorf("default Attempts = %d, want 1", got)
+	}
+	eff := defs.GlobalRules[0].Effects[0]
+	if eff.Type != "ask" || eff.Params["answer"] != "riddle_sphinx" {
+		t.Errorf("Ask effect = %+v", eff)
+	}
+}
+
+func TestCompile_Endings(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Game { title = "T", start = "hall", max_score = 50 }
+		Endings {
+			good = { title = "Victory", text = "The crown is restored." },
+			bad  = { text = "The kingdom falls." },
+		}
+		Rule("wear_crown", When { verb = "wear", object = "crown" }, { EndGame("good") })
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := compile(coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if defs.Game.MaxScore != 50 {
+		t.Errorf("MaxScore = %d, want 50", defs.Game.MaxScore)
+	}
+	if good := defs.Endings["good"]; good.Title != "Victory" || good.Text != "The crown is restored." {
+		t.Errorf("good = %+v", good)
+	}
+	if bad := defs.Endings["bad"]; bad.Title != "bad" {
+		t.Errorf("bad title = %q, want the ID", bad.Title)
+	}
+	eff := defs.GlobalRules[0].Effects[0]
+	if eff.Type != "end_game" || eff.Params["ending"] != "good" {
+		t.Errorf("EndGame effect = %+v", eff)
+	}
+}
+
+func TestCompile_Achievements(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Game { title = "T", start = "hall" }
+		Achievement "pacifist" { name = "Pacifist", description = "Win without a fight." }
+		Achievement "secret" { hidden = true }
+		Rule("spare", When { verb = "wave" }, { UnlockAchievement("pacifist") })
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := compile(coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := defs.Achievements["pacifist"]
+	if p.Name != "Pacifist" || p.Description != "Win without a fight." || p.Hidden || p.Order != 0 {
+		t.Errorf("pacifist = %+v", p)
+	}
+	if s := defs.Achievements["secret"]; s.Name != "secret" || !s.Hidden || s.Order != 1 {
+		t.Errorf("secret = %+v", s)
+	}
+	eff := defs.GlobalRules[0].Effects[0]
+	if eff.Type != "unlock_achievement" || eff.Params["achievement"] != "pacifist" {
+		t.Errorf("UnlockAchievement effect = %+v", eff)
+	}
+}
+
+func TestCompile_Facts(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Game { title = "T", start = "hall" }
+		Fact "password" { text = "The password is swordfish." }
+		Rule("overhear", When { verb = "listen" }, { Not(KnowsFact("password")) }, { LearnFact("password") })
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := compile(coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f := defs.Facts["password"]; f.ID != "password" || f.Text != "The password is swordfish." {
+		t.Errorf("password = %+v", f)
+	}
+	r := defs.GlobalRules[0]
+	if eff := r.Effects[0]; eff.Type != "learn_fact" || eff.Params["fact"] != "password" {
+		t.Errorf("LearnFact effect = %+v", eff)
+	}
+	if c := r.Conditions[0]; c.Type != "not" {
+		t.Errorf("condition = %+v", c)
+	}
+}
+
+func TestCompile_Clock(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Game { title = "T", start = "hall", clock = { start = "21:30", minutes_per_turn = 10, dusk = "19:00" } }
+		Room "hall" { description = "A hall.", night_description = "A dark hall." }
+		Rule("sleep", When { verb = "sleep" }, { TimeIs("night"), TimeBetween("22:00", "04:00") }, { AdvanceTime(480) })
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := compile(coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := struct{ Start, MinutesPerTurn, Dawn, Dusk int }{21*60 + 30, 10, 6 * 60, 19 * 60}
+	if c := defs.Game.Clock; c == nil || c.Start != want.Start || c.MinutesPerTurn != want.MinutesPerTurn || c.Dawn != want.Dawn || c.Dusk != want.Dusk {
+		t.Errorf("Clock = %+v, want %+v", defs.Game.Clock, want)
+	}
+	if defs.Rooms["hall"].NightDescription != "A dark hall." {
+		t.Errorf("NightDescription = %q", defs.Rooms["hall"].NightDescription)
+	}
+	rule := defs.GlobalRules[0]
+	if rule.Conditions[0].Type != "time_is" || rule.Conditions[0].Params["period"] != "night" {
+		t.Errorf("TimeIs = %+v", rule.Conditions[0])
+	}
+	if c := rule.Conditions[1]; c.Type != "time_between" || c.Params["from"] != "22:00" || c.Params["to"] != "04:00" {
+		t.Errorf("TimeBetween = %+v", c)
+	}
+	if eff := rule.Effects[0]; eff.Type != "advance_time" || eff.Params["minutes"] != 480 {
+		t.Errorf("AdvanceTime = %+v", eff)
+	}
+}
+
+func TestCompile_SoundCues(t *testing.T) {
+	L, coll := newTestVM()
+	defer L.Close()
+
+	if err := L.DoString(`
+		Game { title = "T", start = "hall" }
+		Room "hall" { description = "A hall." }
+		Rule("ring", When { verb = "ring" }, { PlaySound("bell"), PlayMusic("chimes"), StopMusic() })
+	`); err != nil {
+		t.Fatal(err)
+	}
+	defs, err := compile(coll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	effs := defs.GlobalRules[0].Effects
+	if effs[0].Type != "play_sound" || effs[0].Params["sound"] != "bell" {
+		t.Errorf("PlaySound = %+v", effs[0])
+	}
+	if effs[1].Type != "play_music" || effs[1].Params["music"] != "chimes" {
+		t.Errorf("PlayMusic = %+v", effs[1])
+	}
+	if effs[2].Type != "play_music" || effs[2].Params["music"] != "" {
+		t.Errorf("StopMusic = %+v", effs[2])
+	}
+}
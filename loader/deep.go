@@ -0,0 +1,388 @@
+package loader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/rules"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/graph"
+	"github.com/nathoo/questcore/types"
+)
+
+// action is a piece of content that applies effects when its conditions
+// hold: a rule, topic, reaction, handler or answer.
+type action struct {
+	scope  string // "global", "room:<id>" or "entity:<id>"
+	object string // entity the command must name, if any
+	target string
+	conds  []types.Condition
+	effs   []types.Effect
+}
+
+// progress is what the player can bring about in some playthrough, as far
+// as deep analysis can tell. It over-approximates: conditions it can't
+// judge (counters, props, negations) are taken to hold, and rules that
+// block a command are taken not to. So what lies outside it can never
+// happen, while what lies inside it may still be impossible.
+type progress struct {
+	defs     *state.Defs
+	rooms    map[string]bool            // rooms the player can reach
+	items    map[string]bool            // items the player can hold
+	flags    map[string]bool            // flags that can become true
+	takeable map[string]bool            // entities a set_prop can make takeable
+	moved    map[string]map[string]bool // entity → rooms effects can move it to
+	unlocked map[string]bool            // locked doors and containers that can be unlocked
+}
+
+// deepLint runs the analyses of check --deep: it works out, from the start
+// room, which rooms the player can reach, which items they can get and
+// which flags they can set — following exits whose guards can be passed,
+// exits opened by effects, and rules, topics and handlers whose conditions
+// can be met — and warns about what is left out.
+func deepLint(defs *state.Defs, ve *ValidationError) {
+	if _, ok := defs.Rooms[defs.Game.Start]; !ok {
+		return
+	}
+	p := explore(defs)
+
+	// Rooms unreachable even along every exit are reported by lint.
+	unreachable := map[string]bool{}
+	for _, id := range graph.Build(defs).Unreachable {
+		unreachable[id] = true
+	}
+	for _, id := range sortedKeys(defs.Rooms) {
+		if !p.rooms[id] && !unreachable[id] {
+			ve.addWarning("room:"+id, fmt.Sprintf(
+				"room %q can never be reached: every way in depends on items or flags the player can't get", id))
+		}
+	}
+
+	// Items meant to be picked up or handed out, that never can be.
+	given := map[string]bool{}
+	for _, a := range actions(defs) {
+		for _, e := range a.effs {
+			if item, ok := e.Params["item"].(string); ok && (e.Type == "give_item" || e.Type == "transfer_item" && e.Params["to"] == "player") {
+				given[item] = true
+			}
+		}
+	}
+	for _, id := range sortedKeys(defs.Entities) {
+		ent := defs.Entities[id]
+		for _, loot := range lootOf(ent) {
+			given[loot] = true
+		}
+	}
+	for _, id := range sortedKeys(defs.Entities) {
+		ent := defs.Entities[id]
+		if ent.Kind != "item" || p.items[id] {
+			continue
+		}
+		if ent.Props["takeable"] == true || given[id] {
+			ve.addWarning("entity:"+id, fmt.Sprintf(
+				"item %q can never be obtained", id))
+		}
+	}
+
+	// Flags that are set somewhere, but only where the player can't get.
+	writers := map[string]string{}
+	rules.ForEachDef(defs, func(subject string, _ []types.Condition, effs []types.Effect) {
+		for _, e := range effs {
+			flag, _ := e.Params["flag"].(string)
+			if v, _ := e.Params["value"].(bool); e.Type == "set_flag" && v {
+				if _, ok := writers[flag]; !ok {
+					writers[flag] = subject
+				}
+			}
+		}
+	})
+	for _, flag := range sortedKeys(writers) {
+		if !p.flags[flag] {
+			ve.addWarning(writers[flag], fmt.Sprintf(
+				"flag %q can never be set: everything that sets it depends on items or flags the player can't get", flag))
+		}
+	}
+}
+
+// explore computes the progress possible in defs, by applying every
+// action that can run until nothing more changes.
+func explore(defs *state.Defs) *progress {
+	p := &progress{
+		defs:     defs,
+		rooms:    map[string]bool{},
+		items:    map[string]bool{},
+		flags:    map[string]bool{},
+		takeable: map[string]bool{},
+		moved:    map[string]map[string]bool{},
+		unlocked: map[string]bool{},
+	}
+	for _, room := range state.StartRooms(defs) {
+		p.rooms[room] = true
+	}
+	for _, def := range defs.Game.Players {
+		for _, item := range def.Inventory {
+			p.items[item] = true
+		}
+	}
+	all := actions(defs)
+	for changed := true; changed; {
+		changed = false
+		add := func(set map[string]bool, id string) {
+			if id != "" && !set[id] {
+				set[id] = true
+				changed = true
+			}
+		}
+
+		for _, room := range sortedKeys(p.rooms) {
+			for _, dir := range sortedKeys(defs.Rooms[room].Exits) {
+				if guard, ok := defs.Rooms[room].ExitGuards[dir]; ok && !p.passable(room, guard) {
+					continue
+				}
+				add(p.rooms, defs.Rooms[room].Exits[dir])
+			}
+		}
+		for _, id := range sortedKeys(defs.Entities) {
+			ent := defs.Entities[id]
+			if !p.accessible(id) {
+				continue
+			}
+			if ent.Props["takeable"] == true || p.takeable[id] || p.stealable(id) {
+				add(p.items, id)
+			}
+			if key, _ := ent.Props["lock_key"].(string); key != "" && p.items[key] {
+				add(p.unlocked, id)
+			}
+			for _, loot := range lootOf(ent) {
+				add(p.items, loot)
+			}
+		}
+
+		for _, a := range all {
+			if !p.enabled(a) {
+				continue
+			}
+			for _, e := range a.effs {
+				switch e.Type {
+				case "give_item":
+					item, _ := e.Params["item"].(string)
+					for _, id := range p.templateIDs(item, a) {
+						add(p.items, id)
+					}
+				case "transfer_item":
+					if e.Params["to"] == "player" {
+						item, _ := e.Params["item"].(string)
+						for _, id := range p.templateIDs(item, a) {
+							add(p.items, id)
+						}
+					}
+				case "set_flag":
+					if v, _ := e.Params["value"].(bool); v {
+						flag, _ := e.Params["flag"].(string)
+						add(p.flags, flag)
+					}
+				case "move_player":
+					room, _ := e.Params["room"].(string)
+					add(p.rooms, room)
+				case "open_exit":
+					room, _ := e.Params["room"].(string)
+					if p.rooms[room] {
+						target, _ := e.Params["target"].(string)
+						add(p.rooms, target)
+					}
+				case "move_entity":
+					entity, _ := e.Params["entity"].(string)
+					room, _ := e.Params["room"].(string)
+					if p.moved[entity] == nil {
+						p.moved[entity] = map[string]bool{}
+					}
+					add(p.moved[entity], room)
+				case "unlock":
+					entity, _ := e.Params["entity"].(string)
+					add(p.unlocked, entity)
+				case "set_prop":
+					entity, _ := e.Params["entity"].(string)
+					switch prop, _ := e.Params["prop"].(string); {
+					case prop == "takeable" && e.Params["value"] == true:
+						add(p.takeable, entity)
+					case prop == "locked" && e.Params["value"] == false:
+						add(p.unlocked, entity)
+					}
+				}
+			}
+		}
+	}
+	return p
+}
+
+// accessible reports whether the player can get to entity id: it is in a
+// room they can reach, inside or carried by an entity they can get to, in
+// their hands, or moved by an effect to one of those places.
+func (p *progress) accessible(id string) bool {
+	return p.accessibleWithin(id, len(p.defs.Entities))
+}
+
+func (p *progress) accessibleWithin(id string, depth int) bool {
+	if depth < 0 {
+		return false // a location cycle
+	}
+	if p.items[id] {
+		return true
+	}
+	places := []string{}
+	if loc, _ := p.defs.Entities[id].Props["location"].(string); loc != "" {
+		places = append(places, loc)
+	}
+	places = append(places, sortedKeys(p.moved[id])...)
+	for _, loc := range places {
+		if p.rooms[loc] {
+			return true
+		}
+		if _, ok := p.defs.Entities[loc]; ok && !p.locked(loc) && p.accessibleWithin(loc, depth-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// locked reports whether id is a door or container that starts out locked
+// and can't be unlocked in any playthrough.
+func (p *progress) locked(id string) bool {
+	return p.defs.Entities[id].Props["locked"] == true && !p.unlocked[id]
+}
+
+// stealable reports whether item id starts out carried by an NPC or enemy,
+// from whom the player may steal it.
+func (p *progress) stealable(id string) bool {
+	loc, _ := p.defs.Entities[id].Props["location"].(string)
+	holder, ok := p.defs.Entities[loc]
+	return ok && p.defs.Entities[id].Kind == "item" && (holder.Kind == "npc" || holder.Kind == "enemy")
+}
+
+// enabled reports whether a can run in some playthrough: its scope and the
+// entities it names are within reach, and each condition can hold.
+func (p *progress) enabled(a action) bool {
+	if room, ok := strings.CutPrefix(a.scope, "room:"); ok && !p.rooms[room] {
+		return false
+	}
+	if id, ok := strings.CutPrefix(a.scope, "entity:"); ok && !p.accessible(id) {
+		return false
+	}
+	for _, id := range []string{a.object, a.target} {
+		if _, ok := p.defs.Entities[id]; ok && !p.accessible(id) {
+			return false
+		}
+	}
+	for _, c := range a.conds {
+		if !p.canHold(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// passable reports whether the player can get past the guard on an exit
+// from room in some playthrough.
+func (p *progress) passable(room string, guard types.ExitGuardDef) bool {
+	if guard.Vehicle != "" && !p.accessible(guard.Vehicle) {
+		return false
+	}
+	if guard.Door != "" && p.locked(guard.Door) {
+		return false
+	}
+	return p.enabled(action{scope: "room:" + room, conds: guard.Requires})
+}
+
+// canHold reports whether c can hold in some playthrough. Conditions it
+// can't judge are taken to.
+func (p *progress) canHold(c types.Condition) bool {
+	switch c.Type {
+	case "has_item":
+		item, _ := c.Params["item"].(string)
+		return isTemplate(item) || p.items[item]
+	case "flag_set":
+		flag, _ := c.Params["flag"].(string)
+		return p.flags[flag]
+	case "flag_is":
+		flag, _ := c.Params["flag"].(string)
+		v, _ := c.Params["value"].(bool)
+		return !v || p.flags[flag]
+	case "in_room":
+		room, _ := c.Params["room"].(string)
+		return p.rooms[room]
+	case "in_combat_with", "in_vehicle":
+		entity, _ := c.Params["entity"].(string)
+		return entity == "" || p.accessible(entity)
+	case "is_attached":
+		entity, _ := c.Params["entity"].(string)
+		to, _ := c.Params["to"].(string)
+		return isTemplate(entity) || p.accessible(entity) && (to == "" || isTemplate(to) || p.accessible(to))
+	}
+	return true
+}
+
+// templateIDs returns the entities id may stand for in a: id itself, or
+// for a template such as "{object}", the entity a's command names, or any
+// item within reach if it names none.
+func (p *progress) templateIDs(id string, a action) []string {
+	if !isTemplate(id) {
+		return []string{id}
+	}
+	switch {
+	case id == "{object}" && a.object != "":
+		return []string{a.object}
+	case id == "{target}" && a.target != "":
+		return []string{a.target}
+	}
+	var ids []string
+	for _, e := range sortedKeys(p.defs.Entities) {
+		if p.defs.Entities[e].Kind == "item" && p.accessible(e) {
+			ids = append(ids, e)
+		}
+	}
+	return ids
+}
+
+// actions lists every rule, topic, reaction, enemy attack, handler and
+// answer of defs.
+func actions(defs *state.Defs) []action {
+	var all []action
+	for _, r := range rules.AllRules(defs) {
+		all = append(all, action{scope: r.Scope, object: r.When.Object, target: r.When.Target, conds: r.Conditions, effs: r.Effects})
+	}
+	for _, id := range sortedKeys(defs.Entities) {
+		ent := defs.Entities[id]
+		for _, key := range sortedKeys(ent.Topics) {
+			all = append(all, action{scope: "entity:" + id, conds: ent.Topics[key].Requires, effs: ent.Topics[key].Effects})
+		}
+		for _, r := range ent.Reactions {
+			all = append(all, action{scope: "entity:" + id, conds: r.Conditions, effs: r.Effects})
+		}
+		if effs := rules.AttackEffects(ent); effs != nil {
+			all = append(all, action{scope: "entity:" + id, effs: effs})
+		}
+	}
+	for _, h := range defs.Handlers {
+		all = append(all, action{scope: "global", conds: h.Conditions, effs: h.Effects})
+	}
+	for _, id := range sortedKeys(defs.Answers) {
+		a := defs.Answers[id]
+		all = append(all, action{scope: "global", effs: append(append([]types.Effect{}, a.Success...), a.Failure...)})
+	}
+	for _, name := range sortedKeys(defs.MetaCommands) {
+		all = append(all, action{scope: "global", effs: defs.MetaCommands[name].Effects})
+	}
+	return all
+}
+
+// lootOf returns the items an enemy may drop.
+func lootOf(ent types.EntityDef) []string {
+	var ids []string
+	loot, _ := ent.Props["loot_items"].([]types.LootEntry)
+	for _, l := range loot {
+		if l.Chance > 0 {
+			ids = append(ids, l.ItemID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,135 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func TestDeepLint(t *testing.T) {
+	defs := validDefs()
+	defs.Rooms["hall"] = types.RoomDef{ID: "hall", Exits: map[string]string{"north": "garden"}}
+	defs.Rooms["garden"] = types.RoomDef{ID: "garden", Exits: map[string]string{"south": "hall"}}
+	defs.Rooms["vault"] = types.RoomDef{ID: "vault"}
+	defs.Rooms["tower"] = types.RoomDef{ID: "tower"}
+	defs.Rooms["island"] = types.RoomDef{ID: "island"}
+	item := func(id, loc string) types.EntityDef {
+		return types.EntityDef{ID: id, Kind: "item", Props: map[string]any{"location": loc, "takeable": true}}
+	}
+	defs.Entities["key"] = item("key", "garden")
+	defs.Entities["gold"] = item("gold", "vault")
+	defs.Entities["crown"] = item("crown", "tower")
+	defs.Entities["statue"] = types.EntityDef{ID: "statue", Kind: "item", Props: map[string]any{"location": "tower"}}
+	defs.GlobalRules = []types.RuleDef{
+		{ID: "unlock_vault", Scope: "global", When: types.MatchCriteria{Verb: "unlock"},
+			Conditions: []types.Condition{{Type: "has_item", Params: map[string]any{"item": "key"}}},
+			Effects:    []types.Effect{{Type: "open_exit", Params: map[string]any{"room": "hall", "direction": "down", "target": "vault"}}}},
+		{ID: "crown_magic", Scope: "global", When: types.MatchCriteria{Verb: "wave"},
+			Conditions: []types.Condition{{Type: "has_item", Params: map[string]any{"item": "crown"}}},
+			Effects:    []types.Effect{{Type: "set_flag", Params: map[string]any{"flag": "tower_unlocked", "value": true}}}},
+		{ID: "open_tower", Scope: "global", When: types.MatchCriteria{Verb: "open"},
+			Conditions: []types.Condition{{Type: "flag_set", Params: map[string]any{"flag": "tower_unlocked"}}},
+			Effects:    []types.Effect{{Type: "open_exit", Params: map[string]any{"room": "garden", "direction": "up", "target": "tower"}}}},
+	}
+
+	ve := &ValidationError{}
+	deepLint(defs, ve)
+
+	for _, want := range []string{
+		`room "tower" can never be reached`,
+		`item "crown" can never be obtained`,
+		`flag "tower_unlocked" can never be set`,
+	} {
+		assertContains(t, ve.Warnings, want)
+	}
+	for _, w := range ve.Warnings {
+		for _, ok := range []string{`"vault"`, `"gold"`, `"key"`, `"statue"`, `"island"`} {
+			if contains(w, ok) {
+				t.Errorf("unexpected warning %q", w)
+			}
+		}
+	}
+	if len(ve.Errors) != 0 {
+		t.Errorf("errors = %v", ve.Errors)
+	}
+}
+
+func TestDeepLint_ExitGuards(t *testing.T) {
+	defs := validDefs()
+	defs.Rooms["hall"] = types.RoomDef{ID: "hall",
+		Exits: map[string]string{"north": "garden", "east": "bridge"},
+		ExitGuards: map[string]types.ExitGuardDef{
+			"north": {Requires: []types.Condition{{Type: "has_item", Params: map[string]any{"item": "key"}}}},
+			"east":  {Requires: []types.Condition{{Type: "flag_set", Params: map[string]any{"flag": "bridge_lowered"}}}},
+		}}
+	defs.Rooms["garden"] = types.RoomDef{ID: "garden"}
+	defs.Rooms["bridge"] = types.RoomDef{ID: "bridge"}
+	defs.Entities["key"] = types.EntityDef{ID: "key", Kind: "item", Props: map[string]any{"location": "hall", "takeable": true}}
+
+	ve := &ValidationError{}
+	deepLint(defs, ve)
+
+	assertContains(t, ve.Warnings, `room "bridge" can never be reached`)
+	for _, w := range ve.Warnings {
+		if contains(w, `"garden"`) {
+			t.Errorf("unexpected warning %q", w)
+		}
+	}
+}
+
+func TestDeepLint_Locks(t *testing.T) {
+	defs := validDefs()
+	defs.Rooms["hall"] = types.RoomDef{ID: "hall",
+		Exits: map[string]string{"north": "garden", "east": "crypt"},
+		ExitGuards: map[string]types.ExitGuardDef{
+			"north": {Door: "gate"},
+			"east":  {Door: "crypt_door"},
+		}}
+	defs.Rooms["garden"] = types.RoomDef{ID: "garden"}
+	defs.Rooms["crypt"] = types.RoomDef{ID: "crypt"}
+	lock := func(id, key string) types.EntityDef {
+		return types.EntityDef{ID: id, Kind: "entity",
+			Props: map[string]any{"location": "hall", "lockable": true, "lock_key": key, "locked": true}}
+	}
+	defs.Entities["gate"] = lock("gate", "key")
+	defs.Entities["crypt_door"] = lock("crypt_door", "bone_key")
+	defs.Entities["chest"] = lock("chest", "bone_key")
+	defs.Entities["key"] = types.EntityDef{ID: "key", Kind: "item", Props: map[string]any{"location": "hall", "takeable": true}}
+	defs.Entities["bone_key"] = types.EntityDef{ID: "bone_key", Kind: "item", Props: map[string]any{"location": "chest", "takeable": true}}
+
+	ve := &ValidationError{}
+	deepLint(defs, ve)
+
+	assertContains(t, ve.Warnings, `room "crypt" can never be reached`)
+	assertContains(t, ve.Warnings, `item "bone_key" can never be obtained`)
+	for _, w := range ve.Warnings {
+		if contains(w, `"garden"`) {
+			t.Errorf("unexpected warning %q", w)
+		}
+	}
+}
+
+func TestExplore_MovesAndTemplates(t *testing.T) {
+	defs := validDefs()
+	defs.Rooms["cellar"] = types.RoomDef{ID: "cellar"}
+	defs.Entities["chest"] = types.EntityDef{ID: "chest", Kind: "entity", Props: map[string]any{"location": "hall"}}
+	defs.Entities["coin"] = types.EntityDef{ID: "coin", Kind: "item", Props: map[string]any{"location": "chest", "takeable": true}}
+	defs.Entities["ring"] = types.EntityDef{ID: "ring", Kind: "item", Props: map[string]any{"location": "cellar"}}
+	defs.Entities["guard"] = types.EntityDef{ID: "guard", Kind: "npc", Props: map[string]any{"location": "cellar"},
+		Topics: map[string]types.TopicDef{"ring": {Effects: []types.Effect{{Type: "give_item", Params: map[string]any{"item": "ring"}}}}}}
+	defs.GlobalRules = []types.RuleDef{
+		{ID: "trapdoor", Scope: "room:hall", When: types.MatchCriteria{Verb: "pull", Object: "coin"},
+			Effects: []types.Effect{{Type: "move_entity", Params: map[string]any{"entity": "guard", "room": "hall"}}}},
+	}
+
+	p := explore(defs)
+	if !p.items["coin"] {
+		t.Error("the coin in the chest in the hall should be obtainable")
+	}
+	if p.rooms["cellar"] {
+		t.Error("the cellar has no way in")
+	}
+	if !p.accessible("guard") || !p.items["ring"] {
+		t.Error("the guard, moved to the hall, should be reachable and hand over the ring")
+	}
+}
@@ -1,9 +1,15 @@
 package loader
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/nathoo/questcore/engine/state"
@@ -17,7 +23,121 @@ type collector struct {
 	entities []rawEntity
 	rules    []rawRule
 	handlers []rawHandler
+	computed []rawComputed
+	answers  []rawAnswer
+	endings  []*lua.LTable
+	achieves []rawAchievement
+	facts    []rawFact
+	metas    []rawMetaCommand
+	messages map[string]string
 	order    int
+
+	// ran holds the files executed so far, by name within the game
+	// directory, so that each runs once whether Included or not.
+	ran map[string]bool
+	// failed names the innermost file whose execution failed, when it was
+	// run by Include from another.
+	failed, failedPath string
+
+	// positions maps a diagnostic subject ("room:<id>", "rule:<id>", ...) to
+	// the Lua source position of the constructor call that defined it.
+	positions map[string]position
+	// sources holds the lines of each file run, by chunk name, for quoting
+	// in errors.
+	sources map[string][]string
+}
+
+// position is a location in a Lua source file. The zero position is
+// unknown.
+type position struct {
+	file string
+	line int
+}
+
+// String renders p as "file:line", or "" if unknown.
+func (p position) String() string {
+	if p.file == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", p.file, p.line)
+}
+
+// mark records the position of the Lua code calling the current Go function
+// as the definition site of subject, and returns it. The first definition
+// of a subject is the one kept.
+func (c *collector) mark(L *lua.LState, subject string) position {
+	// Where returns "<chunkname>:<line>:" for Lua callers.
+	where := strings.TrimSuffix(L.Where(1), ":")
+	i := strings.LastIndex(where, ":")
+	if i < 0 {
+		return position{}
+	}
+	line, err := strconv.Atoi(where[i+1:])
+	if err != nil {
+		return position{}
+	}
+	pos := position{file: where[:i], line: line}
+	if c.positions == nil {
+		c.positions = map[string]position{}
+	}
+	if _, ok := c.positions[subject]; !ok {
+		c.positions[subject] = pos
+	}
+	return pos
+}
+
+// excerpt returns the source line at pos, numbered, as in
+// "12 | Room \"hall\" {", or "" if it isn't known.
+func (c *collector) excerpt(pos position) string {
+	lines := c.sources[pos.file]
+	if pos.line < 1 || pos.line > len(lines) {
+		return ""
+	}
+	return fmt.Sprintf("%d | %s", pos.line, strings.TrimRight(lines[pos.line-1], " \t\r"))
+}
+
+// sourceError is an error in the definition at pos.
+type sourceError struct {
+	pos     position
+	excerpt string // the source line at pos, if known
+	err     error
+}
+
+func (e *sourceError) Error() string {
+	if e.excerpt != "" {
+		return fmt.Sprintf("%s: %v\n    %s", e.pos, e.err, e.excerpt)
+	}
+	return fmt.Sprintf("%s: %v", e.pos, e.err)
+}
+
+func (e *sourceError) Unwrap() error { return e.err }
+
+// errorAt places err at pos, unless pos is unknown.
+func (c *collector) errorAt(pos position, err error) error {
+	if pos.file == "" {
+		return err
+	}
+	return &sourceError{pos: pos, excerpt: c.excerpt(pos), err: err}
+}
+
+// firstDefined returns " (first defined at <file:line>)" for subject, for
+// errors about a second definition, or "" if its first isn't known.
+func (c *collector) firstDefined(subject string) string {
+	if pos, ok := c.positions[subject]; ok {
+		return fmt.Sprintf(" (first defined at %s)", pos)
+	}
+	return ""
+}
+
+// locate fills in the file, line and source excerpt of each diagnostic
+// whose subject's definition was seen.
+func (c *collector) locate(ve *ValidationError) {
+	for i, d := range ve.Diagnostics {
+		if pos, ok := c.positions[d.Subject]; ok {
+			ve.Diagnostics[i].File, ve.Diagnostics[i].Line = pos.file, pos.line
+			ve.Diagnostics[i].Excerpt = c.excerpt(pos)
+		}
+	}
 }
 
 func (c *collector) nextSourceOrder() int {
@@ -28,60 +148,239 @@ func (c *collector) nextSourceOrder() int {
 // Load reads all .lua files from dir, compiles them into game definitions,
 // validates references, and returns the immutable Defs. The Lua VM is
 // discarded after loading.
-func Load(dir string) (*state.Defs, error) {
-	// Discover .lua files.
-	entries, err := os.ReadDir(dir)
+func Load(dir string, with ...Option) (*state.Defs, error) {
+	return load(os.DirFS(dir), dir, true, with)
+}
+
+// LoadFS is like Load but reads the game's .lua files from the root of
+// fsys, e.g. a game bundled into the executable or embedded into a Go
+// program with go:embed. An embed.FS holds the embedded directory itself,
+// so pass fs.Sub(content, "mygame"). Bundles carry only the Lua files, so
+// image paths are not checked.
+func LoadFS(fsys fs.FS, with ...Option) (*state.Defs, error) {
+	return load(fsys, "", false, with)
+}
+
+func load(fsys fs.FS, dir string, checkImages bool, with []Option) (*state.Defs, error) {
+	coll, err := collect(fsys, dir)
 	if err != nil {
-		return nil, fmt.Errorf("reading game directory %s: %w", dir, err)
+		return nil, err
 	}
 
-	var luaFiles []string
-	for _, e := range entries {
-		if !e.IsDir() && strings.HasSuffix(e.Name(), ".lua") {
-			luaFiles = append(luaFiles, e.Name())
+	// Compile.
+	defs, err := compile(coll)
+	if err != nil {
+		return nil, fmt.Errorf("compiling game data: %w", err)
+	}
+
+	// Validate.
+	ve := analyze(defs, with...)
+	if checkImages {
+		validateImages(fsys, defs, ve)
+	}
+	coll.locate(ve)
+	if err := finish(ve); err != nil {
+		return nil, err
+	}
+
+	defs.IndexRules()
+	defs.Freeze()
+	return defs, nil
+}
+
+// fileError reports a failure while executing one of the game's Lua files.
+type fileError struct {
+	name    string // the file's name within the game directory, e.g. "rooms/castle.lua"
+	path    string
+	err     error
+	excerpt string // the source line the error points at, if known
+}
+
+func (e *fileError) Error() string {
+	msg := e.err.Error()
+	if e.excerpt != "" {
+		// The excerpt goes under the error's first line, above any stack
+		// traceback.
+		first, rest, _ := strings.Cut(msg, "\n")
+		msg = first + "\n    " + e.excerpt
+		if rest != "" {
+			msg += "\n" + rest
 		}
 	}
+	return fmt.Sprintf("executing %s: %s", e.name, msg)
+}
+
+func (e *fileError) Unwrap() error { return e.err }
+
+// collect runs every .lua file in fsys, in the order of GameFiles, in a
+// sandboxed VM and returns the raw definitions they declared. dir is the
+// directory fsys was opened from; it prefixes file names in errors and
+// source positions. The VM is closed before returning.
+func collect(fsys fs.FS, dir string) (*collector, error) {
+	luaFiles, err := GameFiles(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("reading game directory %s: %w", dir, err)
+	}
 	if len(luaFiles) == 0 {
 		return nil, fmt.Errorf("no .lua files found in %s", dir)
 	}
 
-	// Sort: game.lua first, rest alphabetical.
-	luaFiles = sortedLuaFiles(luaFiles)
-
 	// Create sandboxed VM.
-	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	L := NewSandbox()
 	defer L.Close()
 
-	// Open safe libs only.
-	openSafeLibs(L)
-
-	// Sandbox: remove dangerous globals.
-	sandbox(L)
-
 	// Register API.
-	coll := &collector{}
+	coll := &collector{ran: map[string]bool{}}
 	registerAPI(L, coll)
+	registerInclude(L, coll, fsys, dir)
 
-	// Execute each file.
+	// Execute each file not already run by Include.
 	for _, f := range luaFiles {
-		path := filepath.Join(dir, f)
-		if err := L.DoFile(path); err != nil {
-			return nil, fmt.Errorf("executing %s: %w", f, err)
+		if coll.ran[f] {
+			continue
+		}
+		coll.ran[f] = true
+		path := filepath.Join(dir, filepath.FromSlash(f))
+		if err := coll.run(L, fsys, f, path); err != nil {
+			return nil, coll.fileError(f, path, err)
 		}
 	}
 
-	// Compile.
-	defs, err := compile(coll)
-	if err != nil {
-		return nil, fmt.Errorf("compiling game data: %w", err)
+	// The locale file runs last, so its Messages override the game's.
+	if coll.game != nil {
+		if locale := getString(coll.game, "locale"); locale != "" {
+			name := "locales/" + locale + ".lua"
+			path := filepath.Join(dir, "locales", locale+".lua")
+			if _, err := fs.Stat(fsys, name); err != nil {
+				return nil, fmt.Errorf("Game.locale %q: %s not found", locale, path)
+			}
+			if err := coll.run(L, fsys, name, path); err != nil {
+				return nil, coll.fileError(name, path, err)
+			}
+		}
 	}
 
-	// Validate.
-	if err := validate(defs); err != nil {
+	return coll, nil
+}
+
+// fileError reports err from running the file name at path, or from the
+// file it Included that failed.
+func (c *collector) fileError(name, path string, err error) *fileError {
+	fe := &fileError{name: name, path: path, err: err}
+	if c.failed != "" {
+		fe.name, fe.path = c.failed, c.failedPath
+	}
+	if pos := luaErrorPosition(err); pos.file == fe.path {
+		fe.excerpt = c.excerpt(pos)
+	}
+	return fe
+}
+
+// GameFiles returns the names of a game's .lua files in fsys, in the order
+// they run: game.lua first, then the other files at the root in
+// alphabetical order, then each subdirectory's files the same way,
+// subdirectories in alphabetical order. The locales directory, loaded only
+// for Game.locale, and hidden directories are skipped. Names are
+// slash-separated paths within fsys, such as "rooms/castle.lua".
+func GameFiles(fsys fs.FS) ([]string, error) {
+	return gameFiles(fsys, ".")
+}
+
+func gameFiles(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
 		return nil, err
 	}
+	var files, subdirs []string
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case e.IsDir():
+			if !strings.HasPrefix(name, ".") && !(dir == "." && name == "locales") {
+				subdirs = append(subdirs, name)
+			}
+		case strings.HasSuffix(name, ".lua"):
+			files = append(files, name)
+		}
+	}
+	if dir == "." {
+		files = sortedLuaFiles(files)
+	} else {
+		sort.Strings(files)
+		for i, f := range files {
+			files[i] = path.Join(dir, f)
+		}
+	}
+	sort.Strings(subdirs)
+	for _, sub := range subdirs {
+		more, err := gameFiles(fsys, path.Join(dir, sub))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, more...)
+	}
+	return files, nil
+}
 
-	return defs, nil
+// registerInclude defines Include "file.lua", which runs another of the
+// game's files on the spot, for files that need what another defines
+// first. Names are relative to the game directory. A file runs once: an
+// Included file is not run again in its turn, and Including it again does
+// nothing.
+func registerInclude(L *lua.LState, coll *collector, fsys fs.FS, dir string) {
+	L.SetGlobal("Include", L.NewFunction(func(L *lua.LState) int {
+		name := L.CheckString(1)
+		if !strings.HasSuffix(name, ".lua") || !fs.ValidPath(name) {
+			L.ArgError(1, fmt.Sprintf("Include %q: want a .lua file within the game directory, such as \"rooms/castle.lua\"", name))
+		}
+		if coll.ran[name] {
+			return 0
+		}
+		if _, err := fs.Stat(fsys, name); err != nil {
+			L.RaiseError("Include %q: %s not found", name, filepath.Join(dir, filepath.FromSlash(name)))
+		}
+		coll.ran[name] = true
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := coll.run(L, fsys, name, path); err != nil {
+			if coll.failed == "" {
+				coll.failed, coll.failedPath = name, path
+			}
+			var apiErr *lua.ApiError
+			if errors.As(err, &apiErr) {
+				L.Error(apiErr.Object, 0)
+			}
+			L.RaiseError("%v", err)
+		}
+		return 0
+	}))
+}
+
+// run executes the Lua file name from fsys, using path as its chunk name.
+func (c *collector) run(L *lua.LState, fsys fs.FS, name, path string) error {
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	if c.sources == nil {
+		c.sources = map[string][]string{}
+	}
+	c.sources[path] = strings.Split(string(src), "\n")
+	fn, err := L.Load(bytes.NewReader(src), path)
+	if err != nil {
+		return err
+	}
+	L.Push(fn)
+	return L.PCall(0, lua.MultRet, nil)
+}
+
+// NewSandbox returns a Lua VM with only the safe subset of the standard
+// libraries, without the functions that could load code or break
+// determinism. Close it when done.
+func NewSandbox() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	openSafeLibs(L)
+	sandbox(L)
+	return L
 }
 
 // openSafeLibs opens only the safe subset of Lua standard libraries.
@@ -1,8 +1,13 @@
 package loader
 
 import (
+	"embed"
+	"io/fs"
+	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/nathoo/questcore/types"
 )
@@ -342,3 +347,376 @@ func TestLoad_FileOrdering(t *testing.T) {
 		t.Errorf("second file = %q, want items.lua", files[1])
 	}
 }
+
+func TestLoadFS(t *testing.T) {
+	defs, err := LoadFS(os.DirFS("testdata/minimal"))
+	if err != nil {
+		t.Fatalf("LoadFS failed: %v", err)
+	}
+	if defs.Game.Title == "" {
+		t.Error("expected game title from fs.FS")
+	}
+}
+
+//go:embed testdata/minimal
+var embedded embed.FS
+
+func TestLoadFS_Embed(t *testing.T) {
+	fsys, err := fs.Sub(embedded, "testdata/minimal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defs, err := LoadFS(fsys)
+	if err != nil {
+		t.Fatalf("LoadFS failed: %v", err)
+	}
+	if defs.Game.Title != "Minimal Test Game" {
+		t.Errorf("Title = %q, want %q", defs.Game.Title, "Minimal Test Game")
+	}
+}
+
+func localeFS() fstest.MapFS {
+	return fstest.MapFS{
+		"game.lua": {Data: []byte(`
+Game { title = "Locale", start = "hall", locale = "fr" }
+Room "hall" { description = "Un grand hall." }
+Messages { cant_go = "You shall not pass.", wait = "Tick." }
+`)},
+		"locales/fr.lua": {Data: []byte(`
+Messages { cant_go = "Impossible d'aller par là." }
+`)},
+	}
+}
+
+func TestLoadFS_Locale(t *testing.T) {
+	defs, err := LoadFS(localeFS())
+	if err != nil {
+		t.Fatalf("LoadFS failed: %v", err)
+	}
+	if defs.Game.Locale != "fr" {
+		t.Errorf("Locale = %q, want fr", defs.Game.Locale)
+	}
+	// The locale file runs last, so it overrides the game's own Messages.
+	if got := defs.Messages["cant_go"]; got != "Impossible d'aller par là." {
+		t.Errorf("cant_go = %q", got)
+	}
+	if got := defs.Messages["wait"]; got != "Tick." {
+		t.Errorf("wait = %q, want the game's override kept", got)
+	}
+}
+
+func TestLoadFS_MissingLocale_Fails(t *testing.T) {
+	fsys := localeFS()
+	delete(fsys, "locales/fr.lua")
+	_, err := LoadFS(fsys)
+	if err == nil {
+		t.Fatal("expected error for a missing locale file")
+	}
+	if !strings.Contains(err.Error(), "locales/fr.lua not found") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestGameFiles_Order(t *testing.T) {
+	fsys := fstest.MapFS{
+		"rooms.lua":           {},
+		"game.lua":            {},
+		"notes.txt":           {},
+		"zones/b/deep.lua":    {},
+		"zones/a.lua":         {},
+		"castle/keep.lua":     {},
+		"castle/gate.lua":     {},
+		"locales/fr.lua":      {},
+		".git/hooks/x.lua":    {},
+		"castle/readme.md":    {},
+		"castle/dungeon/.lua": {},
+	}
+	files, err := GameFiles(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"game.lua", "rooms.lua", "castle/gate.lua", "castle/keep.lua", "castle/dungeon/.lua", "zones/a.lua", "zones/b/deep.lua"}
+	if strings.Join(files, " ") != strings.Join(want, " ") {
+		t.Errorf("GameFiles = %v, want %v", files, want)
+	}
+}
+
+func TestLoadFS_Subdirectories(t *testing.T) {
+	defs, err := LoadFS(fstest.MapFS{
+		"game.lua": {Data: []byte(`
+Include "lib/helpers.lua"
+Game { title = "Folders", start = "hall" }
+hall_room("hall")
+`)},
+		"lib/helpers.lua": {Data: []byte(`
+Include "lib/helpers.lua" -- a file runs once, so this does nothing
+function hall_room(id) Room(id) { description = "A hall.", exits = { north = "keep" } } end
+`)},
+		"castle/keep.lua": {Data: []byte(`
+Room "keep" { description = "The keep.", exits = { south = "hall" } }
+Item "crown" { name = "crown", description = "A crown.", location = "keep" }
+`)},
+	})
+	if err != nil {
+		t.Fatalf("LoadFS failed: %v", err)
+	}
+	if _, ok := defs.Rooms["hall"]; !ok {
+		t.Error("hall, defined with a helper from an Included file, is missing")
+	}
+	if _, ok := defs.Entities["crown"]; !ok {
+		t.Error("crown, defined in a subdirectory, is missing")
+	}
+}
+
+func TestLoadFS_NPCInventory(t *testing.T) {
+	defs, err := LoadFS(fstest.MapFS{
+		"game.lua": {Data: []byte(`
+Game { title = "Pockets", start = "hall" }
+Room "hall" { description = "A hall." }
+NPC "guard" { name = "guard", location = "hall", inventory = { "purse" } }
+Item "purse" { name = "coin purse", description = "Heavy." }
+`)},
+	})
+	if err != nil {
+		t.Fatalf("LoadFS failed: %v", err)
+	}
+	if inv := defs.Entities["guard"].Inventory; len(inv) != 1 || inv[0] != "purse" {
+		t.Errorf("guard Inventory = %v, want [purse]", inv)
+	}
+	if loc := defs.Entities["purse"].Props["location"]; loc != "guard" {
+		t.Errorf("purse location = %v, want guard", loc)
+	}
+}
+
+func TestLoadFS_Include_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{"missing file", fstest.MapFS{
+			"game.lua": {Data: []byte(`Include "rooms/castle.lua"`)},
+		}, `executing game.lua: game.lua:1: Include "rooms/castle.lua": rooms/castle.lua not found`},
+		{"outside the game", fstest.MapFS{
+			"game.lua": {Data: []byte(`Include "../other/game.lua"`)},
+		}, "want a .lua file within the game directory"},
+		{"error in the Included file", fstest.MapFS{
+			"game.lua":         {Data: []byte(`Include "rooms/castle.lua"`)},
+			"rooms/castle.lua": {Data: []byte("\nerror(\"boom\")")},
+		}, "executing rooms/castle.lua: rooms/castle.lua:2:"},
+		{"error in a subdirectory", fstest.MapFS{
+			"game.lua":         {Data: []byte(`Game { title = "T", start = "hall" }`)},
+			"rooms/castle.lua": {Data: []byte("error(\"boom\")")},
+		}, "executing rooms/castle.lua: rooms/castle.lua:1:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFS(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_ErrorsQuoteTheSource(t *testing.T) {
+	_, err := Load("testdata/invalid_refs")
+	if err == nil {
+		t.Fatal("expected a validation error")
+	}
+	want := "testdata/invalid_refs/rooms.lua:1: room \"hall\" exit \"north\" points to undefined room \"nonexistent_room\"\n      1 | Room \"hall\" {"
+	if !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %q, want it to contain %q", err, want)
+	}
+
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{"runtime error", fstest.MapFS{
+			"game.lua": {Data: []byte("Game { title = \"T\", start = \"hall\" }\nlocal x\nx.y = 1\n")},
+		}, "executing game.lua: game.lua:3: attempt to index a non-table object(nil) with key 'y'\n    3 | x.y = 1\nstack traceback:"},
+		{"duplicate definition", fstest.MapFS{
+			"game.lua": {Data: []byte("Game { title = \"T\", start = \"hall\" }\nRoom \"hall\" { description = \"A hall.\" }\nAchievement \"brave\" {}\n")},
+			"more.lua": {Data: []byte("\n  Achievement \"brave\" { hidden = true }\n")},
+		}, "more.lua:2: duplicate achievement \"brave\" (first defined at game.lua:3)\n    2 |   Achievement \"brave\" { hidden = true }"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFS(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %q, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadFS_EnemyBehaviorConditionsAndSequences(t *testing.T) {
+	defs, err := LoadFS(fstest.MapFS{
+		"game.lua": {Data: []byte(`
+Game { title = "T", start = "hall" }
+Room "hall" { description = "A hall." }
+Enemy "troll" {
+    name = "Troll", location = "hall",
+    stats = { hp = 20, max_hp = 20, attack = 5, defense = 2 },
+    behavior = {
+        { action = "flee", weight = 80, when = { StatLt("self", "hp", 4) } },
+        { sequence = { "defend", "attack" }, weight = 20 },
+    },
+}
+`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	behavior := defs.Entities["troll"].Props["behavior"].([]types.BehaviorEntry)
+	if len(behavior) != 2 {
+		t.Fatalf("behavior = %+v", behavior)
+	}
+	if when := behavior[0].When; len(when) != 1 || when[0].Type != "stat_lt" || when[0].Params["entity"] != "self" {
+		t.Errorf("when = %+v, want StatLt on self", when)
+	}
+	if seq := behavior[1].Sequence; len(seq) != 2 || seq[0] != "defend" || seq[1] != "attack" {
+		t.Errorf("sequence = %v, want defend then attack", seq)
+	}
+}
+
+func TestLoadFS_EnemyAttacks(t *testing.T) {
+	defs, err := LoadFS(fstest.MapFS{
+		"game.lua": {Data: []byte(`
+Game { title = "T", start = "hall" }
+Room "hall" { description = "A hall." }
+Enemy "spider" {
+    name = "Spider", location = "hall",
+    stats = { hp = 6, max_hp = 6, attack = 2, defense = 1 },
+    attacks = {
+        { name = "bite", damage = "1d6+2", chance = 60, effect = "poison" },
+        { name = "web", damage = 1, chance = 40, message = "The {enemy} spits a web at you!" },
+    },
+}
+Rule("cure", When { verb = "drink" }, { FlagSet("poison") }, Then { SetFlag("poison", false) })
+`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attacks := defs.Entities["spider"].Props["attacks"].([]types.AttackDef)
+	want := []types.AttackDef{
+		{Name: "bite", Damage: "1d6+2", Chance: 60, Effect: "poison"},
+		{Name: "web", Damage: "1", Chance: 40, Message: "The {enemy} spits a web at you!"},
+	}
+	if !reflect.DeepEqual(attacks, want) {
+		t.Errorf("attacks = %+v, want %+v", attacks, want)
+	}
+}
+
+func TestLoadFS_DiceAmounts(t *testing.T) {
+	defs, err := LoadFS(fstest.MapFS{
+		"game.lua": {Data: []byte(`
+Game { title = "T", start = "hall", player_stats = { hp = 10, max_hp = 10, attack = 1, defense = 1 } }
+Room "hall" { description = "A hall." }
+Enemy "rat" {
+    name = "Rat", location = "hall",
+    stats = { hp = 2, max_hp = 2, attack = 1, defense = 1 },
+    behavior = { { action = "attack", weight = 1 } },
+    loot = { gold = "2d6" },
+}
+Rule("trap", When { verb = "jump" }, {}, Then { Damage("player", "2d4+1"), IncCounter("gold", "d6"), Heal("player", 2) })
+`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	effs := defs.GlobalRules[0].Effects
+	if effs[0].Params["amount"] != "2d4+1" || effs[1].Params["amount"] != "d6" || effs[2].Params["amount"] != 2 {
+		t.Errorf("effects = %+v, want the dice kept as written", effs)
+	}
+	if gold := defs.Entities["rat"].Props["loot_gold"]; gold != "2d6" {
+		t.Errorf("loot_gold = %v, want 2d6", gold)
+	}
+}
+
+func TestLoadFS_CombatUse(t *testing.T) {
+	defs, err := LoadFS(fstest.MapFS{
+		"game.lua": {Data: []byte(`
+Game { title = "T", start = "hall" }
+Room "hall" { description = "A hall." }
+Item "bomb" { name = "bomb", location = "hall", combat_use = { damage = "2d6", consumes = true } }
+Item "salve" { name = "salve", location = "hall", combat_use = { heal = 5, message = "You dab on the {item}." } }
+`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if use := defs.Entities["bomb"].Props["combat_use"]; use != (types.CombatUse{Damage: "2d6", Consumes: true}) {
+		t.Errorf("bomb combat_use = %+v", use)
+	}
+	if use := defs.Entities["salve"].Props["combat_use"]; use != (types.CombatUse{Heal: "5", Message: "You dab on the {item}."}) {
+		t.Errorf("salve combat_use = %+v", use)
+	}
+}
+
+func TestLoadFS_StartCombatSurprise(t *testing.T) {
+	defs, err := LoadFS(fstest.MapFS{
+		"game.lua": {Data: []byte(`
+Game { title = "T", start = "hall" }
+Room "hall" { description = "A hall." }
+Enemy "wolf" { name = "wolf", location = "hall", stats = { hp = 5, max_hp = 5, attack = 1, defense = 1, speed = 4 } }
+Rule("pounce", When { verb = "jump" }, {}, Then { StartCombat("wolf", { surprise = true }) })
+Rule("provoke", When { verb = "shout" }, {}, Then { StartCombat("wolf") })
+`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	effects := map[string]types.Effect{}
+	for _, r := range defs.GlobalRules {
+		effects[r.ID] = r.Effects[0]
+	}
+	if got := effects["pounce"].Params["surprise"]; got != true {
+		t.Errorf("pounce surprise = %v, want true", got)
+	}
+	if _, ok := effects["provoke"].Params["surprise"]; ok {
+		t.Errorf("provoke should have no surprise param: %v", effects["provoke"].Params)
+	}
+	if speed := defs.Entities["wolf"].Props["speed"]; speed != 4 {
+		t.Errorf("wolf speed = %v, want 4", speed)
+	}
+}
+
+func TestLoadFS_DescriptionList(t *testing.T) {
+	defs, err := LoadFS(fstest.MapFS{
+		"game.lua": {Data: []byte(`
+Game { title = "T", start = "hall" }
+Room "hall" { description = "A hall." }
+Entity "mural" { name = "mural", location = "hall",
+    description = { "A faded mural.", "A crown is painted in one corner.", "The mural of the old court." } }
+`)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	props := defs.Entities["mural"].Props
+	want := []string{"A faded mural.", "A crown is painted in one corner.", "The mural of the old court."}
+	if got := props["descriptions"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("descriptions = %v, want %v", got, want)
+	}
+	if props["description"] != "The mural of the old court." {
+		t.Errorf("description = %v, want the last text", props["description"])
+	}
+}
+
+func TestLoad_Frozen(t *testing.T) {
+	defs, err := Load("testdata/minimal")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if err := defs.CheckFrozen(); err != nil {
+		t.Fatalf("freshly loaded: %v", err)
+	}
+	defs.Game.Start = "elsewhere"
+	if err := defs.CheckFrozen(); err == nil {
+		t.Error("a change to the loaded definitions should be caught")
+	}
+}
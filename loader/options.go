@@ -0,0 +1,41 @@
+package loader
+
+import (
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/engine/rules"
+)
+
+// Option configures how a game is loaded or checked; see Load.
+type Option func(*options)
+
+// options holds what the Options passed to Load set.
+type options struct {
+	effects    effects.Registry // effect types the embedding program adds
+	conditions rules.Registry   // condition types the embedding program adds
+}
+
+func newOptions(with []Option) options {
+	var opts options
+	for _, opt := range with {
+		opt(&opts)
+	}
+	return opts
+}
+
+// WithEffects accepts the effect types in r, with whatever parameters
+// they are given; the program checks its own. Give the engine the same
+// registry, with engine.WithEffects, to apply them.
+func WithEffects(r effects.Registry) Option {
+	return func(o *options) {
+		o.effects = r
+	}
+}
+
+// WithConditions accepts the condition types in r, with whatever
+// parameters they are given; the program checks its own. Give the engine
+// the same registry, with engine.WithConditions, to evaluate them.
+func WithConditions(r rules.Registry) Option {
+	return func(o *options) {
+		o.conditions = r
+	}
+}
@@ -0,0 +1,257 @@
+package loader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/dice"
+)
+
+// paramKind is the type of value a parameter takes.
+type paramKind int
+
+const (
+	kindString paramKind = iota // a string
+	kindBool                    // true or false
+	kindInt                     // a whole number
+	kindRoll                    // a whole number or a dice expression, such as "2d4+1"
+	kindAny                     // any value, nil included
+)
+
+func (k paramKind) String() string {
+	switch k {
+	case kindBool:
+		return "a boolean"
+	case kindInt:
+		return "a whole number"
+	case kindRoll:
+		return "a whole number or dice expression"
+	}
+	return "a string"
+}
+
+// param describes one parameter of an effect or condition.
+type param struct {
+	name     string
+	kind     paramKind
+	optional bool
+	min      *int // the least value allowed, for kindInt and kindRoll
+}
+
+func str(name string) param     { return param{name: name, kind: kindString} }
+func boolean(name string) param { return param{name: name, kind: kindBool} }
+func whole(name string) param   { return param{name: name, kind: kindInt} }
+func value(name string) param   { return param{name: name, kind: kindAny, optional: true} }
+
+// atLeast returns a whole-number parameter that may not be below min.
+func atLeast(name string, min int) param {
+	return param{name: name, kind: kindInt, min: &min}
+}
+
+// rolled returns p, made to take a dice expression as well as a number.
+func rolled(p param) param {
+	p.kind = kindRoll
+	return p
+}
+
+// optional returns p, made optional.
+func optional(p param) param {
+	p.optional = true
+	return p
+}
+
+// effectParams lists the parameters of every effect type; a type not
+// listed is unknown.
+var effectParams = map[string][]param{
+	"say":                {str("text")},
+	"notify":             {str("text")},
+	"give_item":          {str("item"), optional(atLeast("amount", 1))},
+	"remove_item":        {str("item"), optional(atLeast("amount", 1))},
+	"give_to":            {str("item"), str("npc")},
+	"transfer_item":      {str("item"), str("from"), str("to")},
+	"wear_item":          {str("item")},
+	"unwear_item":        {str("item")},
+	"consume_item":       {str("item")},
+	"enter_vehicle":      {str("entity")},
+	"leave_vehicle":      {},
+	"attach":             {str("entity"), str("to")},
+	"detach":             {str("entity")},
+	"unlock":             {str("entity"), optional(str("key"))},
+	"lock":               {str("entity"), optional(str("key"))},
+	"fill_vessel":        {str("vessel"), str("liquid"), optional(atLeast("amount", 0))},
+	"pour_liquid":        {str("vessel"), optional(str("target")), optional(atLeast("amount", 0))},
+	"drink_liquid":       {str("vessel"), optional(atLeast("amount", 1))},
+	"set_flag":           {str("flag"), boolean("value")},
+	"inc_counter":        {str("counter"), rolled(whole("amount"))},
+	"dec_counter":        {str("counter"), rolled(whole("amount"))},
+	"mul_counter":        {str("counter"), whole("factor")},
+	"set_counter":        {str("counter"), rolled(whole("value"))},
+	"set_prop":           {str("entity"), str("prop"), value("value")},
+	"inc_prop":           {str("entity"), str("prop"), rolled(whole("amount"))},
+	"dec_prop":           {str("entity"), str("prop"), rolled(whole("amount"))},
+	"move_entity":        {str("entity"), str("room")},
+	"move_player":        {str("room")},
+	"switch_player":      {str("player")},
+	"open_exit":          {str("room"), str("direction"), str("target")},
+	"close_exit":         {str("room"), str("direction")},
+	"cutaway":            {str("room"), optional(atLeast("lines", 0))},
+	"ask":                {str("answer")},
+	"emit_event":         {str("event")},
+	"start_dialogue":     {str("npc")},
+	"stop":               {},
+	"continue":           {},
+	"start_combat":       {str("enemy"), optional(boolean("surprise"))},
+	"end_combat":         {},
+	"damage":             {str("target"), rolled(atLeast("amount", 0))},
+	"heal":               {str("target"), rolled(atLeast("amount", 0))},
+	"set_stat":           {str("target"), str("stat"), whole("value")},
+	"gain_xp":            {atLeast("amount", 0)},
+	"end_game":           {str("ending")},
+	"unlock_achievement": {str("achievement")},
+	"learn_fact":         {str("fact")},
+	"advance_time":       {atLeast("minutes", 0)},
+	"play_sound":         {str("sound")},
+	"play_music":         {str("music")},
+}
+
+// conditionSpec describes a condition type: the parameters it takes, and
+// the state inputs it reads, in the notation of Computed uses lists, where
+// "{name}" stands for the value of parameter name. A type that reads no
+// inputs may not appear in computed cases.
+type conditionSpec struct {
+	params []param
+	reads  []string
+}
+
+// conditionSpecs describes every condition type; a type not listed is
+// unknown. A not condition's inner condition is checked apart.
+var conditionSpecs = map[string]conditionSpec{
+	"has_item":        {[]param{str("item")}, []string{"inventory.{item}"}},
+	"flag_set":        {[]param{str("flag")}, []string{"flags.{flag}"}},
+	"flag_not":        {[]param{str("flag")}, []string{"flags.{flag}"}},
+	"flag_is":         {[]param{str("flag"), boolean("value")}, []string{"flags.{flag}"}},
+	"in_room":         {[]param{str("room")}, []string{"player.location"}},
+	"prop_is":         {[]param{str("entity"), str("prop"), value("value")}, []string{"entities.{entity}.{prop}"}},
+	"prop_gt":         {[]param{str("entity"), str("prop"), whole("value")}, []string{"entities.{entity}.{prop}"}},
+	"prop_lt":         {[]param{str("entity"), str("prop"), whole("value")}, []string{"entities.{entity}.{prop}"}},
+	"counter_gt":      {[]param{str("counter"), whole("value")}, []string{"counters.{counter}"}},
+	"counter_lt":      {[]param{str("counter"), whole("value")}, []string{"counters.{counter}"}},
+	"counter_eq":      {[]param{str("counter"), whole("value")}, []string{"counters.{counter}"}},
+	"counter_between": {[]param{str("counter"), whole("min"), whole("max")}, []string{"counters.{counter}"}},
+	"counter_cmp":     {[]param{str("counter"), str("op"), str("other")}, []string{"counters.{counter}", "counters.{other}"}},
+	"not":             {},
+	"in_combat":       {nil, []string{"combat"}},
+	"in_combat_with":  {[]param{str("entity")}, []string{"combat"}},
+	"in_vehicle":      {[]param{optional(str("entity"))}, []string{"player.vehicle"}},
+	"is_attached":     {[]param{str("entity"), optional(str("to"))}, []string{"attached.{entity}"}},
+	"has_liquid":      {[]param{str("liquid"), optional(str("vessel"))}, []string{"liquids.{liquid}"}},
+	"is_player":       {[]param{str("player")}, []string{"player.active"}},
+	"knows_fact":      {[]param{str("fact")}, []string{"facts.{fact}"}},
+	"stat_gt":         {[]param{str("entity"), str("stat"), whole("value")}, []string{"stats.{entity}.{stat}"}},
+	"stat_lt":         {[]param{str("entity"), str("stat"), whole("value")}, []string{"stats.{entity}.{stat}"}},
+	"computed_is":     {[]param{str("computed"), value("value")}, nil},
+	"time_is":         {[]param{str("period")}, []string{"clock"}},
+	"time_between":    {[]param{str("from"), str("to")}, []string{"clock"}},
+}
+
+// inputs returns the state inputs a condition of this type reads, given its
+// parameters.
+func (c conditionSpec) inputs(params map[string]any) []string {
+	out := make([]string, len(c.reads))
+	for i, r := range c.reads {
+		for _, p := range c.params {
+			v, _ := params[p.name].(string)
+			r = strings.ReplaceAll(r, "{"+p.name+"}", v)
+		}
+		out[i] = r
+	}
+	return out
+}
+
+// checkParams reports an error for each parameter of an effect or condition
+// (what) of type typ that is missing, of the wrong type or out of range,
+// and a warning for each it doesn't take. It returns false if it reported
+// an error, so that checks relying on the parameters can be skipped.
+func checkParams(subject, what, typ string, schema []param, params map[string]any, ve *ValidationError) bool {
+	ok := true
+	known := map[string]bool{}
+	for _, p := range schema {
+		known[p.name] = true
+		v, present := params[p.name]
+		if !present {
+			if !p.optional {
+				ve.addError(subject, fmt.Sprintf("%s %s requires %s", what, typ, p.name))
+				ok = false
+			}
+			continue
+		}
+		if msg := p.check(v); msg != "" {
+			ve.addError(subject, fmt.Sprintf("%s %s %s %s", what, typ, p.name, msg))
+			ok = false
+		}
+	}
+	var extra []string
+	for name := range params {
+		if !known[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		ve.addWarning(subject, fmt.Sprintf("%s %s has unknown parameter %q", what, typ, name))
+	}
+	return ok
+}
+
+// check returns what is wrong with v as a value of p ("must be ..."), or
+// "" if nothing.
+func (p param) check(v any) string {
+	switch p.kind {
+	case kindAny:
+		return ""
+	case kindString:
+		if _, ok := v.(string); ok {
+			return ""
+		}
+	case kindBool:
+		if _, ok := v.(bool); ok {
+			return ""
+		}
+	case kindInt, kindRoll:
+		n, ok := v.(int)
+		if expr, isString := v.(string); isString && p.kind == kindRoll {
+			d, err := dice.Parse(expr)
+			if err != nil {
+				return fmt.Sprintf("must be %s: %v", p.kind, err)
+			}
+			if p.min != nil && d.Min() < *p.min {
+				return fmt.Sprintf("must be at least %d, got %q, which can roll %d", *p.min, expr, d.Min())
+			}
+			return ""
+		}
+		if !ok {
+			break
+		}
+		if p.min != nil && n < *p.min {
+			return fmt.Sprintf("must be at least %d, got %d", *p.min, n)
+		}
+		return ""
+	}
+	return fmt.Sprintf("must be %s, got %s", p.kind, describeValue(v))
+}
+
+// describeValue renders a parameter value for an error, by its Lua type.
+func describeValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case int, float64:
+		return fmt.Sprintf("number %v", v)
+	}
+	return "a table"
+}
@@ -0,0 +1,108 @@
+package loader
+
+import (
+	"testing"
+
+	"github.com/nathoo/questcore/types"
+)
+
+func TestValidate_EffectParams(t *testing.T) {
+	defs := validDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "bad", Scope: "global", When: types.MatchCriteria{Verb: "look"},
+		Effects: []types.Effect{
+			{Type: "say", Params: map[string]any{}},
+			{Type: "damage", Params: map[string]any{"target": "player", "amount": "five"}},
+			{Type: "heal", Params: map[string]any{"target": "player", "amount": "1d4-3"}},
+			{Type: "inc_counter", Params: map[string]any{"counter": "gold", "amount": "2d6+3"}},
+			{Type: "set_flag", Params: map[string]any{"flag": "door_open"}},
+			{Type: "gain_xp", Params: map[string]any{"amount": -10}},
+			{Type: "set_counter", Params: map[string]any{"counter": "gold", "value": 2.5}},
+			{Type: "end_game", Params: map[string]any{}},
+		},
+	})
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for bad effect params")
+	}
+	errs := err.(*ValidationError).Errors
+	assertContains(t, errs, "effect say requires text")
+	assertContains(t, errs, `effect damage amount must be a whole number or dice expression: dice expression "five": want NdS, NdS+B or a number`)
+	assertContains(t, errs, `effect heal amount must be at least 0, got "1d4-3", which can roll -2`)
+	assertContains(t, errs, "effect set_flag requires value")
+	assertContains(t, errs, "effect gain_xp amount must be at least 0, got -10")
+	assertContains(t, errs, "effect set_counter value must be a whole number or dice expression, got number 2.5")
+	assertContains(t, errs, "effect end_game requires ending")
+	// A missing parameter isn't reported again as a bad reference.
+	for _, e := range errs {
+		if contains(e, "inc_counter") {
+			t.Errorf("a dice expression should do for an amount, got %q", e)
+		}
+		if contains(e, `undefined ending ""`) {
+			t.Errorf("unexpected follow-on error %q", e)
+		}
+	}
+}
+
+func TestValidate_ConditionParams(t *testing.T) {
+	defs := validDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "bad", Scope: "global", When: types.MatchCriteria{Verb: "look"},
+		Conditions: []types.Condition{
+			{Type: "flag_is", Params: map[string]any{"flag": "door_open", "value": "yes"}},
+			{Type: "counter_gt", Params: map[string]any{"value": 3}},
+			{Type: "not", Negate: true},
+			{Type: "not", Negate: true, Inner: &types.Condition{Type: "in_room", Params: map[string]any{"room": 7}}},
+		},
+	})
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for bad condition params")
+	}
+	errs := err.(*ValidationError).Errors
+	assertContains(t, errs, `condition flag_is value must be a boolean, got string "yes"`)
+	assertContains(t, errs, "condition counter_gt requires counter")
+	assertContains(t, errs, "condition not requires a condition to negate")
+	assertContains(t, errs, "condition in_room room must be a string, got number 7")
+}
+
+func TestValidate_ParamsInTopicsAndHandlers(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["guard"] = types.EntityDef{ID: "guard", Kind: "npc", Props: map[string]any{"location": "hall"},
+		Topics: map[string]types.TopicDef{"gate": {
+			Text:     "The gate is shut.",
+			Requires: []types.Condition{{Type: "has_item", Params: map[string]any{}}},
+			Effects:  []types.Effect{{Type: "heal", Params: map[string]any{"target": "player", "amount": true}}},
+		}}}
+	defs.Handlers = []types.EventHandler{{ID: "handler:0", EventType: "room_entered",
+		Effects: []types.Effect{{Type: "advance_time", Params: map[string]any{"minutes": -5}}}}}
+
+	ve := analyze(defs)
+	assertContains(t, ve.Errors, "condition has_item requires item")
+	assertContains(t, ve.Errors, "effect heal amount must be a whole number or dice expression, got boolean true")
+	assertContains(t, ve.Errors, "effect advance_time minutes must be at least 0, got -5")
+	for _, d := range ve.Diagnostics {
+		if contains(d.Message, "has_item") && d.Subject != "entity:guard" {
+			t.Errorf("topic error subject = %q, want entity:guard", d.Subject)
+		}
+		if contains(d.Message, "advance_time") && d.Subject != "handler:0" {
+			t.Errorf("handler error subject = %q, want handler:0", d.Subject)
+		}
+	}
+}
+
+func TestValidate_UnknownParamWarns(t *testing.T) {
+	defs := validDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "typo", Scope: "global", When: types.MatchCriteria{Verb: "look"},
+		Effects: []types.Effect{{Type: "say", Params: map[string]any{"text": "Hi.", "txet": "Hi."}}},
+	})
+
+	ve := analyze(defs)
+	if len(ve.Errors) != 0 {
+		t.Errorf("errors = %v, want none", ve.Errors)
+	}
+	assertContains(t, ve.Warnings, `effect say has unknown parameter "txet"`)
+}
@@ -0,0 +1,6 @@
+Game {
+    title = "Lint",
+    author = "Test",
+    version = "1.0",
+    start = "hall"
+}
@@ -0,0 +1,10 @@
+Item "lamp" {
+    name = "lamp",
+    description = "A brass lamp.",
+    location = "hall"
+}
+
+Item "ghost_key" {
+    name = "ghost key",
+    description = "A key that is never placed anywhere."
+}
@@ -0,0 +1,13 @@
+Room "hall" {
+    description = "A hall.",
+    exits = { north = "garden" }
+}
+
+Room "garden" {
+    description = "A garden.",
+    exits = { south = "hall" }
+}
+
+Room "island" {
+    description = "Nobody can get here."
+}
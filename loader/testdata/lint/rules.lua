@@ -0,0 +1,22 @@
+Rule("contradiction",
+    When { verb = "push" },
+    { FlagSet("lit"), FlagNot("lit") },
+    Then { Say("Impossible.") }
+)
+
+Rule("light_lamp",
+    When { verb = "light", object = "lamp" },
+    Then { SetFlag("lit", true) }
+)
+
+Rule("needs_unset_flag",
+    When { verb = "pull" },
+    { FlagSet("nobody_sets_this") },
+    Then { SetFlag("write_only", true) }
+)
+
+Rule("counter_gap",
+    When { verb = "jump" },
+    { CounterGt("jumps", 3), CounterLt("jumps", 4) },
+    Then { IncCounter("jumps", 1) }
+)
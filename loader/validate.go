@@ -2,180 +2,492 @@ package loader
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
+	"slices"
+	"sort"
 	"strings"
 
+	"github.com/nathoo/questcore/engine/dice"
+	"github.com/nathoo/questcore/engine/messages"
+	"github.com/nathoo/questcore/engine/rules"
 	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/theme"
 	"github.com/nathoo/questcore/types"
 )
 
 // ValidationError collects all validation errors and warnings.
 type ValidationError struct {
-	Errors   []string
-	Warnings []string
+	Errors      []string
+	Warnings    []string
+	Diagnostics []Diagnostic // the same findings, tagged with their subject
+}
+
+// Diagnostic is a single validation finding. Subject identifies the
+// definition it concerns ("game", "room:<id>", "entity:<id>", "rule:<id>",
+// "handler:<n>", "computed:<id>", "answer:<id>"); File, Line and Excerpt are
+// filled in from the positions recorded while the Lua files ran.
+type Diagnostic struct {
+	Severity string `json:"severity"` // "error" or "warning"
+	Message  string `json:"message"`
+	Subject  string `json:"subject,omitempty"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Excerpt  string `json:"excerpt,omitempty"` // the numbered source line, as in "12 | Room \"hall\" {"
+}
+
+// String renders d as a line of text, prefixed with its position when
+// known and followed by its source excerpt on the next line.
+func (d Diagnostic) String() string {
+	s := d.Message
+	if d.File != "" {
+		pos := d.File
+		if d.Line > 0 {
+			pos = fmt.Sprintf("%s:%d", d.File, d.Line)
+		}
+		s = pos + ": " + s
+	}
+	if d.Excerpt != "" {
+		s += "\n    " + d.Excerpt
+	}
+	return s
+}
+
+func (e *ValidationError) addError(subject, msg string) {
+	e.Errors = append(e.Errors, msg)
+	e.Diagnostics = append(e.Diagnostics, Diagnostic{Severity: "error", Message: msg, Subject: subject})
+}
+
+func (e *ValidationError) addWarning(subject, msg string) {
+	e.Warnings = append(e.Warnings, msg)
+	e.Diagnostics = append(e.Diagnostics, Diagnostic{Severity: "warning", Message: msg, Subject: subject})
 }
 
 func (e *ValidationError) Error() string {
+	var lines []string
+	for _, d := range e.Diagnostics {
+		if d.Severity == "error" {
+			lines = append(lines, strings.ReplaceAll(d.String(), "\n", "\n  "))
+		}
+	}
 	return fmt.Sprintf("validation failed with %d error(s):\n  %s",
-		len(e.Errors), strings.Join(e.Errors, "\n  "))
-}
-
-// Known effect types.
-var validEffectTypes = map[string]bool{
-	"say":            true,
-	"give_item":      true,
-	"remove_item":    true,
-	"set_flag":       true,
-	"inc_counter":    true,
-	"set_counter":    true,
-	"set_prop":       true,
-	"move_entity":    true,
-	"move_player":    true,
-	"open_exit":      true,
-	"close_exit":     true,
-	"emit_event":     true,
-	"start_dialogue": true,
-	"stop":           true,
-	"start_combat":   true,
-	"end_combat":     true,
-	"damage":         true,
-	"heal":           true,
-	"set_stat":       true,
-}
-
-// Known condition types.
-var validConditionTypes = map[string]bool{
-	"has_item":       true,
-	"flag_set":       true,
-	"flag_not":       true,
-	"flag_is":        true,
-	"in_room":        true,
-	"prop_is":        true,
-	"counter_gt":     true,
-	"counter_lt":     true,
-	"not":            true,
-	"in_combat":      true,
-	"in_combat_with": true,
-	"stat_gt":        true,
-	"stat_lt":        true,
+		len(e.Errors), strings.Join(lines, "\n  "))
 }
 
 // validate checks the compiled defs for referential integrity and consistency.
-func validate(defs *state.Defs) error {
+// Warnings are printed to stderr; errors are returned as a *ValidationError.
+func validate(defs *state.Defs, with ...Option) error {
+	return finish(analyze(defs, with...))
+}
+
+// finish prints ve's warnings to stderr and returns ve if it holds errors.
+func finish(ve *ValidationError) error {
+	// Print warnings to stderr, with their positions but not excerpts, so
+	// that they stay a line each.
+	for _, d := range ve.Diagnostics {
+		if d.Severity == "warning" {
+			d.Excerpt = ""
+			fmt.Fprintf(os.Stderr, "warning: %s\n", d)
+		}
+	}
+
+	if len(ve.Errors) > 0 {
+		return ve
+	}
+	return nil
+}
+
+// analyze runs all validation checks and collects errors and warnings
+// without printing anything.
+func analyze(defs *state.Defs, with ...Option) *ValidationError {
 	ve := &ValidationError{}
+	opts := newOptions(with)
 
 	// Game title required.
 	if defs.Game.Title == "" {
-		ve.Errors = append(ve.Errors, "Game.Title is required")
+		ve.addError("game", "Game.Title is required")
 	}
 
 	// Start room exists.
 	if defs.Game.Start == "" {
-		ve.Errors = append(ve.Errors, "Game.Start is required")
+		ve.addError("game", "Game.Start is required")
 	} else if _, ok := defs.Rooms[defs.Game.Start]; !ok {
-		ve.Errors = append(ve.Errors, fmt.Sprintf(
+		ve.addError("game", fmt.Sprintf(
 			"start room %q not found in defined rooms", defs.Game.Start))
 	}
 
 	// Exit targets valid.
-	for roomID, room := range defs.Rooms {
-		for dir, target := range room.Exits {
-			if _, ok := defs.Rooms[target]; !ok {
-				ve.Errors = append(ve.Errors, fmt.Sprintf(
+	for _, roomID := range sortedKeys(defs.Rooms) {
+		room := defs.Rooms[roomID]
+		for _, dir := range sortedKeys(room.Exits) {
+			target := room.Exits[dir]
+			if target == "" {
+				ve.addError("room:"+roomID, fmt.Sprintf(
+					"room %q exit %q has no target room (set to)", roomID, dir))
+			} else if _, ok := defs.Rooms[target]; !ok {
+				ve.addError("room:"+roomID, fmt.Sprintf(
 					"room %q exit %q points to undefined room %q", roomID, dir, target))
 			}
 		}
+		for _, dir := range sortedKeys(room.ExitGuards) {
+			guard := room.ExitGuards[dir]
+			validateConditions("room:"+roomID, guard.Requires, defs, opts, ve)
+			if guard.Vehicle != "" {
+				validateVehicle("room:"+roomID, fmt.Sprintf("room %q exit %q", roomID, dir), guard.Vehicle, defs, ve)
+			}
+			if guard.Door != "" {
+				validateLockable("room:"+roomID, fmt.Sprintf("room %q exit %q door", roomID, dir), guard.Door, defs, ve)
+			}
+		}
+		validateCapacity(roomID, room, defs, ve)
+		validateAmbience(roomID, room, defs, opts, ve)
 		// Validate room rules.
-		validateRules(room.Rules, defs, ve)
+		validateRules(room.Rules, defs, opts, ve)
 	}
 
 	// Rule IDs unique across all scopes.
 	ruleIDs := map[string]bool{}
-	allRules := collectAllRules(defs)
+	allRules := rules.AllRules(defs)
 	for _, rule := range allRules {
 		if ruleIDs[rule.ID] {
-			ve.Errors = append(ve.Errors, fmt.Sprintf(
+			ve.addError("rule:"+rule.ID, fmt.Sprintf(
 				"duplicate rule ID %q", rule.ID))
 		}
 		ruleIDs[rule.ID] = true
 	}
 
 	// Validate global rules.
-	validateRules(defs.GlobalRules, defs, ve)
+	validateRules(defs.GlobalRules, defs, opts, ve)
 
 	// Validate entity rules.
-	for _, entity := range defs.Entities {
-		validateRules(entity.Rules, defs, ve)
+	for _, entityID := range sortedKeys(defs.Entities) {
+		entity := defs.Entities[entityID]
+		validateRules(entity.Rules, defs, opts, ve)
+
+		// Validate topic labels, conditions and effects.
+		validateTopicLabels(entityID, entity.Topics, ve)
+		for _, key := range sortedKeys(entity.Topics) {
+			topic := entity.Topics[key]
+			validateConditions("entity:"+entityID, topic.Requires, defs, opts, ve)
+			validateEffects("entity:"+entityID, topic.Effects, defs, opts, ve)
+		}
+
+		// Validate the starting inventory.
+		for _, item := range entity.Inventory {
+			def, ok := defs.Entities[item]
+			if !ok {
+				ve.addError("entity:"+entityID, fmt.Sprintf(
+					"entity %q inventory references undefined entity %q", entityID, item))
+			} else if loc, _ := def.Props["location"].(string); loc != entityID {
+				ve.addError("entity:"+entityID, fmt.Sprintf(
+					"entity %q inventory lists %q, whose location is %q", entityID, item, loc))
+			}
+		}
+
+		// Validate responses to items given and shown.
+		validateItemResponses(entityID, entity, "on_receive", entity.OnReceive, defs, opts, ve)
+		validateItemResponses(entityID, entity, "on_show", entity.OnShow, defs, opts, ve)
+
+		// Validate reaction conditions and effects.
+		for _, reaction := range entity.Reactions {
+			validateConditions("entity:"+entityID, reaction.Conditions, defs, opts, ve)
+			validateEffects("entity:"+entityID, reaction.Effects, defs, opts, ve)
+		}
+	}
+
+	// Validate amusing entries.
+	for i, entry := range defs.Game.Amusing {
+		if entry.Text == "" {
+			ve.addError("game", fmt.Sprintf(
+				"Game.amusing entry %d has no text", i+1))
+		}
+		validateConditions("game", entry.Conditions, defs, opts, ve)
+	}
+	for i, objective := range defs.Game.Objectives {
+		if objective.Text == "" {
+			ve.addError("game", fmt.Sprintf(
+				"Game.objectives entry %d has no text", i+1))
+		}
+		validateConditions("game", objective.Conditions, defs, opts, ve)
+	}
+
+	if err := theme.Theme(defs.Game.Theme).Check(); err != nil {
+		ve.addError("game", fmt.Sprintf("Game.theme: %v", err))
+	}
+
+	if defs.Game.CommandLogLimit < 0 {
+		ve.addError("game", fmt.Sprintf(
+			"Game.command_log_limit must be at least 0, got %d", defs.Game.CommandLogLimit))
+	}
+	if defs.Game.AmbienceCooldown < 0 {
+		ve.addError("game", fmt.Sprintf(
+			"Game.ambience_cooldown must be at least 0, got %d", defs.Game.AmbienceCooldown))
+	}
+	for _, verb := range sortedKeys(defs.Game.Noise) {
+		if n := defs.Game.Noise[verb]; n < 0 {
+			ve.addError("game", fmt.Sprintf("Game.noise: %q must be at least 0, got %d", verb, n))
+		}
+	}
+	for i, text := range defs.Game.UnknownVerbResponses {
+		if strings.TrimSpace(text) == "" {
+			ve.addError("game", fmt.Sprintf("Game.unknown_verb_responses[%d] is empty", i+1))
+		}
+	}
+
+	// Level thresholds must rise from one level to the next.
+	prevXP := 0
+	for i, level := range defs.Game.Levels {
+		if level.XP <= prevXP {
+			ve.addError("game", fmt.Sprintf(
+				"Game.levels[%d] xp must be greater than %d, got %d", i+1, prevXP, level.XP))
+		}
+		prevXP = level.XP
+	}
+
+	if c := defs.Game.Clock; c != nil {
+		for _, t := range []struct {
+			name  string
+			value int
+		}{{"start", c.Start}, {"dawn", c.Dawn}, {"dusk", c.Dusk}} {
+			if t.value < 0 {
+				ve.addError("game", fmt.Sprintf(
+					"Game.clock.%s must be a time of day \"HH:MM\"", t.name))
+			}
+		}
+		if c.MinutesPerTurn < 0 {
+			ve.addError("game", fmt.Sprintf(
+				"Game.clock.minutes_per_turn must be at least 0, got %d", c.MinutesPerTurn))
+		}
+	}
+
+	switch defs.Game.OnDeath {
+	case "", "prompt", "respawn", "restart":
+	default:
+		ve.addError("game", fmt.Sprintf(
+			"Game.on_death must be \"prompt\", \"respawn\" or \"restart\", got %q", defs.Game.OnDeath))
+	}
+	if room := defs.Game.RespawnRoom; room != "" {
+		if _, ok := defs.Rooms[room]; !ok {
+			ve.addError("game", fmt.Sprintf(
+				"Game.respawn_room references undefined room %q", room))
+		}
+	}
+	validatePlayers(defs, ve)
+	if p := defs.Game.DeathPenalty; p.Gold < 0 || p.XP < 0 {
+		ve.addError("game", fmt.Sprintf(
+			"Game.death_penalty must not be negative, got gold %d, xp %d", p.Gold, p.XP))
+	}
+
+	// Classic response overrides must name an entry in the pack.
+	for _, key := range sortedKeys(defs.Game.ClassicResponses) {
+		if _, ok := classicResponses[key]; !ok {
+			ve.addWarning("game", fmt.Sprintf(
+				"Game.classic_responses has no entry %q", key))
+		}
+	}
+
+	// Built-ins turned off must be built-ins, and some rule should do the
+	// verb's job instead.
+	for _, verb := range sortedKeys(defs.Game.Builtins) {
+		switch {
+		case !builtinVerbs[verb]:
+			ve.addWarning("game", fmt.Sprintf(
+				"Game.builtins: %q has no built-in behavior", verb))
+		case !defs.Game.Builtins[verb] && !hasRuleFor(allRules, verb):
+			ve.addWarning("game", fmt.Sprintf(
+				"Game.builtins turns off %q but no rule handles it", verb))
+		}
+	}
+
+	// Message overrides must name an entry in the catalog and use only its
+	// placeholders.
+	for _, key := range sortedKeys(defs.Messages) {
+		def, ok := messages.Defaults[key]
+		if !ok {
+			ve.addWarning("game", fmt.Sprintf("Messages has no entry %q", key))
+			continue
+		}
+		known := messages.Placeholders(def)
+		for _, name := range messages.Placeholders(defs.Messages[key]) {
+			if !slices.Contains(known, name) {
+				ve.addWarning("game", fmt.Sprintf(
+					"Messages.%s uses unknown placeholder {%s}", key, name))
+			}
+		}
+	}
 
-		// Validate topic conditions and effects.
-		for _, topic := range entity.Topics {
-			validateConditions(topic.Requires, defs, ve)
-			validateEffects(topic.Effects, defs, ve)
+	// Validate idle nudges.
+	if idle := defs.Game.IdleNudge; idle != nil {
+		if idle.Minutes <= 0 {
+			ve.addError("game", "Game.idle_nudge.minutes must be a positive number")
+		}
+		for i, nudge := range idle.Nudges {
+			if nudge.Text == "" {
+				ve.addError("game", fmt.Sprintf(
+					"Game.idle_nudge nudge %d has no text", i+1))
+			}
+			validateConditions("game", nudge.Conditions, defs, opts, ve)
 		}
 	}
 
+	// Validate computed properties.
+	for _, id := range sortedKeys(defs.Computed) {
+		validateComputed(defs.Computed[id], defs, opts, ve)
+	}
+
+	// Validate free-text questions.
+	for _, id := range sortedKeys(defs.Answers) {
+		def := defs.Answers[id]
+		subject := "answer:" + id
+		if len(def.Accept) == 0 {
+			ve.addError(subject, fmt.Sprintf("answer %q has no accepted answers", id))
+		}
+		if def.Attempts < 1 {
+			ve.addError(subject, fmt.Sprintf("answer %q attempts must be at least 1", id))
+		}
+		validateEffects(subject, def.Success, defs, opts, ve)
+		validateEffects(subject, def.Failure, defs, opts, ve)
+	}
+
+	// Validate meta-commands.
+	for _, name := range sortedKeys(defs.MetaCommands) {
+		validateMetaCommand(defs.MetaCommands[name], defs, opts, ve)
+	}
+
 	// Validate handlers.
-	for _, handler := range defs.Handlers {
-		validateConditions(handler.Conditions, defs, ve)
-		validateEffects(handler.Effects, defs, ve)
+	handlerIDs := map[string]bool{}
+	for i, handler := range defs.Handlers {
+		subject := handlerSubject(i)
+		if handlerIDs[handler.ID] {
+			ve.addError(subject, fmt.Sprintf("duplicate handler ID %q", handler.ID))
+		}
+		handlerIDs[handler.ID] = true
+		validateConditions(subject, handler.Conditions, defs, opts, ve)
+		validateEffects(subject, handler.Effects, defs, opts, ve)
 	}
 
 	// Validate enemies.
 	hasEnemies := false
-	for entityID, entity := range defs.Entities {
+	for _, entityID := range sortedKeys(defs.Entities) {
+		entity := defs.Entities[entityID]
 		if entity.Kind == "enemy" {
 			hasEnemies = true
-			validateEnemy(entityID, entity, defs, ve)
+			validateEnemy(entityID, entity, defs, opts, ve)
+		}
+	}
+
+	// Items' combat use.
+	for _, entityID := range sortedKeys(defs.Entities) {
+		if use, ok := defs.Entities[entityID].Props["combat_use"].(types.CombatUse); ok {
+			validateCombatUse(entityID, use, ve)
+		}
+	}
+
+	// A list of descriptions needs at least one to show.
+	for _, entityID := range sortedKeys(defs.Entities) {
+		if texts, ok := defs.Entities[entityID].Props["descriptions"].([]string); ok && len(texts) == 0 {
+			ve.addError("entity:"+entityID, fmt.Sprintf(
+				"entity %q description list is empty", entityID))
+		}
+	}
+
+	// A stackable item's quantity counts units.
+	for _, entityID := range sortedKeys(defs.Entities) {
+		if v, ok := defs.Entities[entityID].Props["quantity"]; ok {
+			if n, isInt := toValidateInt(v); !isInt || n < 0 {
+				ve.addError("entity:"+entityID, fmt.Sprintf(
+					"entity %q quantity must be a non-negative integer, got %v", entityID, v))
+			}
 		}
 	}
 
 	// Warn if enemies exist but no player_stats defined.
 	if hasEnemies && defs.Game.PlayerStats == nil {
-		ve.Warnings = append(ve.Warnings, "enemy entities exist but Game.PlayerStats is not defined")
+		ve.addWarning("game", "enemy entities exist but Game.PlayerStats is not defined")
 	}
 
-	// Warnings: dangling item locations.
-	for entityID, entity := range defs.Entities {
+	// Warnings: dangling item locations. An entity may also be carried by
+	// another.
+	for _, entityID := range sortedKeys(defs.Entities) {
+		entity := defs.Entities[entityID]
 		if loc, ok := entity.Props["location"].(string); ok && loc != "" {
-			if _, ok := defs.Rooms[loc]; !ok {
-				ve.Warnings = append(ve.Warnings, fmt.Sprintf(
-					"entity %q location %q does not match any defined room", entityID, loc))
+			_, inRoom := defs.Rooms[loc]
+			if _, carried := defs.Entities[loc]; !inRoom && !carried {
+				ve.addWarning("entity:"+entityID, fmt.Sprintf(
+					"entity %q location %q does not match any defined room or entity", entityID, loc))
+			}
+		}
+		if key, ok := entity.Props["lock_key"].(string); ok && key != "" {
+			if _, ok := defs.Entities[key]; !ok {
+				ve.addError("entity:"+entityID, fmt.Sprintf(
+					"entity %q lock key references undefined entity %q", entityID, key))
+			}
+		}
+		for _, prop := range []string{"filled_with", "liquid_source"} {
+			if liquid, ok := entity.Props[prop].(string); ok && liquid != "" {
+				validateLiquid("entity:"+entityID, fmt.Sprintf("entity %q %s", entityID, prop), liquid, defs, ve)
 			}
 		}
 	}
 
-	// Print warnings to stderr.
-	for _, w := range ve.Warnings {
-		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
-	}
+	return ve
+}
 
-	if len(ve.Errors) > 0 {
-		return ve
-	}
-	return nil
+// handlerSubject returns the diagnostic subject for the i-th event handler.
+func handlerSubject(i int) string {
+	return fmt.Sprintf("handler:%d", i)
 }
 
-func validateRules(rules []types.RuleDef, defs *state.Defs, ve *ValidationError) {
+func validateRules(rules []types.RuleDef, defs *state.Defs, opts options, ve *ValidationError) {
 	for _, rule := range rules {
-		validateConditions(rule.Conditions, defs, ve)
-		validateEffects(rule.Effects, defs, ve)
+		subject := "rule:" + rule.ID
+		validateConditions(subject, rule.Conditions, defs, opts, ve)
+		validateEffects(subject, rule.Effects, defs, opts, ve)
+
+		switch rule.When.Phase {
+		case "", "before", "after":
+		default:
+			ve.addError(subject, fmt.Sprintf(
+				"rule %q has unknown phase %q (use \"before\" or \"after\")", rule.ID, rule.When.Phase))
+		}
+		if rule.When.Succeeded != nil && rule.When.Phase != "after" {
+			ve.addError(subject, fmt.Sprintf(
+				"rule %q uses succeeded outside the after phase", rule.ID))
+		}
 
 		// Warn on unrecognized verbs in When.
 		if rule.When.Verb != "" {
 			verb := rule.When.Verb
 			if !isKnownVerb(verb) {
-				ve.Warnings = append(ve.Warnings, fmt.Sprintf(
+				ve.addWarning(subject, fmt.Sprintf(
 					"rule %q uses unrecognized verb %q", rule.ID, verb))
 			}
 		}
 	}
 }
 
-func validateConditions(conditions []types.Condition, defs *state.Defs, ve *ValidationError) {
+// counterOps are the comparisons a counter_cmp condition may make.
+var counterOps = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+func validateConditions(subject string, conditions []types.Condition, defs *state.Defs, opts options, ve *ValidationError) {
 	for _, cond := range conditions {
-		if !validConditionTypes[cond.Type] {
-			ve.Errors = append(ve.Errors, fmt.Sprintf(
+		spec, known := conditionSpecs[cond.Type]
+		if !known && opts.conditions.Has(cond.Type) {
+			continue // added by the embedding program, which checks its own
+		}
+		if !known {
+			ve.addError(subject, fmt.Sprintf(
 				"unknown condition type %q", cond.Type))
+			continue
+		}
+		if cond.Type == "not" && cond.Inner == nil {
+			ve.addError(subject, "condition not requires a condition to negate")
+			continue
+		}
+		if !checkParams(subject, "condition", cond.Type, spec.params, cond.Params, ve) {
+			continue
 		}
 
 		// Check entity/room refs in conditions.
@@ -183,37 +495,143 @@ func validateConditions(conditions []types.Condition, defs *state.Defs, ve *Vali
 		case "has_item":
 			if item, ok := cond.Params["item"].(string); ok && !isTemplate(item) {
 				if _, ok := defs.Entities[item]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"condition has_item references undefined entity %q", item))
 				}
 			}
 		case "in_room":
 			if room, ok := cond.Params["room"].(string); ok && !isTemplate(room) {
 				if _, ok := defs.Rooms[room]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"condition in_room references undefined room %q", room))
 				}
 			}
 		case "prop_is":
 			if entity, ok := cond.Params["entity"].(string); ok && !isTemplate(entity) {
 				if _, ok := defs.Entities[entity]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"condition prop_is references undefined entity %q", entity))
 				}
 			}
+		case "prop_gt", "prop_lt":
+			validateNumericProp(subject, "condition "+cond.Type, cond.Params, defs, ve)
+		case "in_vehicle":
+			if entity, ok := cond.Params["entity"].(string); ok && entity != "" && !isTemplate(entity) {
+				validateVehicle(subject, "condition in_vehicle", entity, defs, ve)
+			}
+		case "is_player":
+			if player, ok := cond.Params["player"].(string); ok {
+				if _, ok := defs.Game.Players[player]; !ok {
+					ve.addError(subject, fmt.Sprintf(
+						"condition is_player references undefined player %q", player))
+				}
+			}
+		case "knows_fact":
+			if fact, ok := cond.Params["fact"].(string); ok {
+				if _, ok := defs.Facts[fact]; !ok {
+					ve.addError(subject, fmt.Sprintf(
+						"condition knows_fact references undefined fact %q", fact))
+				}
+			}
+		case "has_liquid":
+			if liquid, ok := cond.Params["liquid"].(string); ok && !isTemplate(liquid) {
+				validateLiquid(subject, "condition has_liquid", liquid, defs, ve)
+			}
+			if vessel, ok := cond.Params["vessel"].(string); ok && vessel != "" && !isTemplate(vessel) {
+				if _, ok := defs.Entities[vessel]; !ok {
+					ve.addError(subject, fmt.Sprintf(
+						"condition has_liquid references undefined entity %q", vessel))
+				}
+			}
+		case "is_attached":
+			for _, key := range []string{"entity", "to"} {
+				if id, ok := cond.Params[key].(string); ok && id != "" && !isTemplate(id) {
+					if _, ok := defs.Entities[id]; !ok {
+						ve.addError(subject, fmt.Sprintf(
+							"condition is_attached references undefined entity %q", id))
+					}
+				}
+			}
+		case "computed_is":
+			if name, ok := cond.Params["computed"].(string); ok {
+				if _, ok := defs.Computed[name]; !ok {
+					ve.addError(subject, fmt.Sprintf(
+						"condition computed_is references undefined computed property %q", name))
+				}
+			}
+		case "time_is", "time_between":
+			if defs.Game.Clock == nil {
+				ve.addError(subject, fmt.Sprintf(
+					"condition %s requires Game.clock", cond.Type))
+			}
+			if cond.Type == "time_is" {
+				if period, _ := cond.Params["period"].(string); period != "day" && period != "night" {
+					ve.addError(subject, fmt.Sprintf(
+						"condition time_is period must be \"day\" or \"night\", got %q", period))
+				}
+				break
+			}
+			for _, key := range []string{"from", "to"} {
+				str, _ := cond.Params[key].(string)
+				if _, ok := state.ParseClock(str); !ok {
+					ve.addError(subject, fmt.Sprintf(
+						"condition time_between %s must be a time of day \"HH:MM\", got %q", key, str))
+				}
+			}
+		case "counter_between":
+			lo, hi := cond.Params["min"].(int), cond.Params["max"].(int)
+			if lo > hi {
+				ve.addError(subject, fmt.Sprintf(
+					"condition counter_between min %d is greater than max %d", lo, hi))
+			}
+		case "counter_cmp":
+			if op, _ := cond.Params["op"].(string); !counterOps[op] {
+				ve.addError(subject, fmt.Sprintf(
+					"condition counter_cmp op must be one of ==, !=, <, <=, >, >=, got %q", op))
+			}
 		case "not":
 			if cond.Inner != nil {
-				validateConditions([]types.Condition{*cond.Inner}, defs, ve)
+				validateConditions(subject, []types.Condition{*cond.Inner}, defs, opts, ve)
 			}
 		}
 	}
 }
 
-func validateEffects(effects []types.Effect, defs *state.Defs, ve *ValidationError) {
-	for _, eff := range effects {
-		if !validEffectTypes[eff.Type] {
-			ve.Errors = append(ve.Errors, fmt.Sprintf(
+// validateNumericProp checks that the entity an inc_prop, dec_prop, prop_gt
+// or prop_lt (what) refers to exists and declares the property as a number.
+func validateNumericProp(subject, what string, params map[string]any, defs *state.Defs, ve *ValidationError) {
+	entity, _ := params["entity"].(string)
+	prop, _ := params["prop"].(string)
+	if isTemplate(entity) {
+		return
+	}
+	def, ok := defs.Entities[entity]
+	if !ok {
+		ve.addError(subject, fmt.Sprintf(
+			"%s references undefined entity %q", what, entity))
+		return
+	}
+	switch def.Props[prop].(type) {
+	case int, float64:
+	default:
+		ve.addError(subject, fmt.Sprintf(
+			"%s: entity %q must declare %q as a number", what, entity, prop))
+	}
+}
+
+func validateEffects(subject string, effs []types.Effect, defs *state.Defs, opts options, ve *ValidationError) {
+	for _, eff := range effs {
+		params, known := effectParams[eff.Type]
+		if !known && opts.effects.Has(eff.Type) {
+			continue // added by the embedding program, which checks its own
+		}
+		if !known {
+			ve.addError(subject, fmt.Sprintf(
 				"unknown effect type %q", eff.Type))
+			continue
+		}
+		if !checkParams(subject, "effect", eff.Type, params, eff.Params, ve) {
+			continue
 		}
 
 		// Check entity/room refs in effects.
@@ -221,78 +639,175 @@ func validateEffects(effects []types.Effect, defs *state.Defs, ve *ValidationErr
 		case "give_item":
 			if item, ok := eff.Params["item"].(string); ok && !isTemplate(item) {
 				if _, ok := defs.Entities[item]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"effect give_item references undefined entity %q", item))
 				}
 			}
-		case "remove_item":
+		case "remove_item", "wear_item", "unwear_item", "consume_item":
 			if item, ok := eff.Params["item"].(string); ok && !isTemplate(item) {
 				if _, ok := defs.Entities[item]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
-						"effect remove_item references undefined entity %q", item))
+					ve.addError(subject, fmt.Sprintf(
+						"effect %s references undefined entity %q", eff.Type, item))
 				}
 			}
 		case "set_prop":
 			if entity, ok := eff.Params["entity"].(string); ok && !isTemplate(entity) {
 				if _, ok := defs.Entities[entity]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"effect set_prop references undefined entity %q", entity))
 				}
 			}
+		case "transfer_item":
+			for _, key := range []string{"item", "from", "to"} {
+				id, ok := eff.Params[key].(string)
+				if !ok || isTemplate(id) || key != "item" && id == "player" {
+					continue
+				}
+				if _, ok := defs.Entities[id]; !ok {
+					ve.addError(subject, fmt.Sprintf(
+						"effect transfer_item references undefined entity %q", id))
+				}
+			}
+		case "give_to":
+			for _, key := range []string{"item", "npc"} {
+				if id, ok := eff.Params[key].(string); ok && !isTemplate(id) {
+					if _, ok := defs.Entities[id]; !ok {
+						ve.addError(subject, fmt.Sprintf(
+							"effect give_to references undefined entity %q", id))
+					}
+				}
+			}
+		case "inc_prop", "dec_prop":
+			validateNumericProp(subject, "effect "+eff.Type, eff.Params, defs, ve)
+		case "enter_vehicle":
+			if entity, ok := eff.Params["entity"].(string); ok && !isTemplate(entity) {
+				validateVehicle(subject, "effect enter_vehicle", entity, defs, ve)
+			}
+		case "fill_vessel", "pour_liquid", "drink_liquid":
+			if vessel, ok := eff.Params["vessel"].(string); ok && !isTemplate(vessel) {
+				if _, ok := defs.Entities[vessel]; !ok {
+					ve.addError(subject, fmt.Sprintf(
+						"effect %s references undefined entity %q", eff.Type, vessel))
+				}
+			}
+			if liquid, ok := eff.Params["liquid"].(string); ok && !isTemplate(liquid) {
+				validateLiquid(subject, "effect fill_vessel", liquid, defs, ve)
+			}
+		case "attach", "detach":
+			for _, key := range []string{"entity", "to"} {
+				if id, ok := eff.Params[key].(string); ok && !isTemplate(id) {
+					if _, ok := defs.Entities[id]; !ok {
+						ve.addError(subject, fmt.Sprintf(
+							"effect %s references undefined entity %q", eff.Type, id))
+					}
+				}
+			}
+		case "unlock", "lock":
+			if entity, ok := eff.Params["entity"].(string); ok && !isTemplate(entity) {
+				validateLockable(subject, "effect "+eff.Type, entity, defs, ve)
+			}
+			if key, ok := eff.Params["key"].(string); ok && key != "" && !isTemplate(key) {
+				if _, ok := defs.Entities[key]; !ok {
+					ve.addError(subject, fmt.Sprintf(
+						"effect %s references undefined key %q", eff.Type, key))
+				}
+			}
 		case "move_entity":
 			if entity, ok := eff.Params["entity"].(string); ok && !isTemplate(entity) {
 				if _, ok := defs.Entities[entity]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"effect move_entity references undefined entity %q", entity))
 				}
 			}
 			if room, ok := eff.Params["room"].(string); ok && !isTemplate(room) {
 				if _, ok := defs.Rooms[room]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"effect move_entity references undefined room %q", room))
 				}
 			}
+		case "switch_player":
+			if player, ok := eff.Params["player"].(string); ok {
+				if _, ok := defs.Game.Players[player]; !ok {
+					ve.addError(subject, fmt.Sprintf(
+						"effect switch_player references undefined player %q", player))
+				}
+			}
 		case "move_player":
 			if room, ok := eff.Params["room"].(string); ok && !isTemplate(room) {
 				if _, ok := defs.Rooms[room]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"effect move_player references undefined room %q", room))
 				}
 			}
+		case "cutaway":
+			if room, ok := eff.Params["room"].(string); ok && !isTemplate(room) {
+				if _, ok := defs.Rooms[room]; !ok {
+					ve.addError(subject, fmt.Sprintf(
+						"effect cutaway references undefined room %q", room))
+				}
+			}
+		case "end_game":
+			ending, _ := eff.Params["ending"].(string)
+			if _, ok := defs.Endings[ending]; !ok {
+				ve.addError(subject, fmt.Sprintf(
+					"effect end_game references undefined ending %q", ending))
+			}
+		case "play_sound":
+			if sound, _ := eff.Params["sound"].(string); sound == "" {
+				ve.addError(subject, "effect play_sound requires a sound name")
+			}
+		case "unlock_achievement":
+			achievement, _ := eff.Params["achievement"].(string)
+			if _, ok := defs.Achievements[achievement]; !ok {
+				ve.addError(subject, fmt.Sprintf(
+					"effect unlock_achievement references undefined achievement %q", achievement))
+			}
+		case "learn_fact":
+			fact, _ := eff.Params["fact"].(string)
+			if _, ok := defs.Facts[fact]; !ok {
+				ve.addError(subject, fmt.Sprintf(
+					"effect learn_fact references undefined fact %q", fact))
+			}
+		case "ask":
+			answer, _ := eff.Params["answer"].(string)
+			if _, ok := defs.Answers[answer]; !ok {
+				ve.addError(subject, fmt.Sprintf(
+					"effect ask references undefined answer %q", answer))
+			}
 		case "open_exit":
 			if room, ok := eff.Params["room"].(string); ok && !isTemplate(room) {
 				if _, ok := defs.Rooms[room]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"effect open_exit references undefined room %q", room))
 				}
 			}
 			if target, ok := eff.Params["target"].(string); ok && !isTemplate(target) {
 				if _, ok := defs.Rooms[target]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"effect open_exit target references undefined room %q", target))
 				}
 			}
 		case "close_exit":
 			if room, ok := eff.Params["room"].(string); ok && !isTemplate(room) {
 				if _, ok := defs.Rooms[room]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"effect close_exit references undefined room %q", room))
 				}
 			}
 		case "start_dialogue":
 			if npc, ok := eff.Params["npc"].(string); ok && !isTemplate(npc) {
 				if _, ok := defs.Entities[npc]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"effect start_dialogue references undefined entity %q", npc))
 				}
 			}
 		case "start_combat":
 			if enemy, ok := eff.Params["enemy"].(string); ok && !isTemplate(enemy) {
 				if e, ok := defs.Entities[enemy]; !ok {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"effect start_combat references undefined entity %q", enemy))
 				} else if e.Kind != "enemy" {
-					ve.Errors = append(ve.Errors, fmt.Sprintf(
+					ve.addError(subject, fmt.Sprintf(
 						"effect start_combat target %q is kind %q, expected \"enemy\"", enemy, e.Kind))
 				}
 			}
@@ -300,19 +815,6 @@ func validateEffects(effects []types.Effect, defs *state.Defs, ve *ValidationErr
 	}
 }
 
-// collectAllRules gathers all rules from all scopes.
-func collectAllRules(defs *state.Defs) []types.RuleDef {
-	var all []types.RuleDef
-	all = append(all, defs.GlobalRules...)
-	for _, room := range defs.Rooms {
-		all = append(all, room.Rules...)
-	}
-	for _, entity := range defs.Entities {
-		all = append(all, entity.Rules...)
-	}
-	return all
-}
-
 // isTemplate returns true if the string contains a template variable.
 func isTemplate(s string) bool {
 	return strings.Contains(s, "{") && strings.Contains(s, "}")
@@ -324,13 +826,15 @@ var knownVerbs = map[string]bool{
 	"go": true, "use": true, "open": true, "close": true,
 	"talk": true, "give": true, "push": true, "pull": true,
 	"attack": true, "defend": true, "flee": true,
-	"inventory": true, "wait": true,
-	"read": true, "eat": true, "drink": true, "climb": true,
+	"inventory": true, "wait": true, "stats": true, "achievements": true, "notes": true,
+	"topics": true, "read": true, "eat": true, "drink": true, "climb": true,
+	"wear": true, "remove": true,
 	"unlock": true, "lock": true, "search": true, "listen": true,
 	"smell": true, "touch": true, "taste": true, "throw": true,
-	"put": true, "ask": true, "tell": true, "show": true,
-	"say": true, "move": true, "enter": true, "leave": true,
-	"help": true, "save": true, "load": true, "quit": true,
+	"put": true, "ask": true, "tell": true, "show": true, "steal": true,
+	"say": true, "move": true, "enter": true, "exit": true, "leave": true,
+	"tie": true, "untie": true, "fill": true, "pour": true, "sneak": true,
+	"help": true, "save": true, "load": true, "quit": true, "restart": true,
 	// Direction verbs.
 	"north": true, "south": true, "east": true, "west": true,
 	"northeast": true, "northwest": true, "southeast": true, "southwest": true,
@@ -338,7 +842,63 @@ var knownVerbs = map[string]bool{
 }
 
 func isKnownVerb(verb string) bool {
-	return knownVerbs[verb]
+	return knownVerbs[verb] || isClassicVerb(verb)
+}
+
+// builtinVerbs are the verbs with built-in behavior, which Game.builtins
+// can turn off.
+var builtinVerbs = map[string]bool{
+	"go": true, "look": true, "inventory": true, "stats": true, "achievements": true, "notes": true,
+	"examine": true, "read": true, "take": true, "drop": true, "wear": true,
+	"remove": true, "eat": true, "drink": true, "talk": true, "topics": true,
+	"give": true, "show": true, "steal": true, "wait": true,
+	"enter": true, "exit": true, "tie": true, "untie": true, "fill": true, "pour": true,
+	"sneak": true, "unlock": true, "lock": true,
+}
+
+// builtinMetaCommands are the meta-commands the cli and tui front ends
+// provide, which a game's own MetaCommand() may not take over.
+var builtinMetaCommands = map[string]bool{
+	"/quit": true, "/exit": true, "/save": true, "/load": true,
+	"/export-save": true, "/import-save": true, "/mark": true, "/rewind": true,
+	"/help": true, "/state": true, "/exec": true, "/trace": true, "/lua": true,
+	"/why": true, "/rules": true, "/stats": true,
+}
+
+// validateMetaCommand checks a MetaCommand() declaration: a single word
+// after the slash, not one of the built-in meta-commands, and either a verb
+// or effects to run.
+func validateMetaCommand(def types.MetaCommandDef, defs *state.Defs, opts options, ve *ValidationError) {
+	subject := "meta:" + def.Name
+	switch {
+	case !strings.HasPrefix(def.Name, "/") || len(def.Name) < 2 || strings.ContainsAny(def.Name, " \t"):
+		ve.addError(subject, fmt.Sprintf(
+			"meta-command %q must be a slash followed by a single word, such as \"/spells\"", def.Name))
+	case builtinMetaCommands[def.Name]:
+		ve.addError(subject, fmt.Sprintf(
+			"meta-command %q collides with the built-in %s", def.Name, def.Name))
+	}
+	switch {
+	case def.Verb != "" && len(def.Effects) > 0:
+		ve.addError(subject, fmt.Sprintf(
+			"meta-command %q has both a verb and effects; give one", def.Name))
+	case def.Verb == "" && len(def.Effects) == 0:
+		ve.addError(subject, fmt.Sprintf(
+			"meta-command %q has neither a verb nor effects", def.Name))
+	}
+	validateEffects(subject, def.Effects, defs, opts, ve)
+}
+
+// hasRuleFor reports whether any of rules takes the place of verb's
+// built-in behavior: one matching the verb outside the before and after
+// phases.
+func hasRuleFor(rules []types.RuleDef, verb string) bool {
+	for _, r := range rules {
+		if r.When.Verb == verb && r.When.Phase == "" {
+			return true
+		}
+	}
+	return false
 }
 
 // Known enemy behavior actions.
@@ -349,18 +909,37 @@ var validBehaviorActions = map[string]bool{
 }
 
 // validateEnemy checks that an enemy entity has valid stats, behavior, and loot.
-func validateEnemy(entityID string, entity types.EntityDef, defs *state.Defs, ve *ValidationError) {
+// validateCombatUse checks an item's combat_use table.
+func validateCombatUse(entityID string, use types.CombatUse, ve *ValidationError) {
+	subject := "entity:" + entityID
+	if use.Damage == "" && use.Heal == "" {
+		ve.addError(subject, fmt.Sprintf(
+			"item %q combat_use has neither damage nor heal", entityID))
+	}
+	for _, f := range []struct{ name, expr string }{{"damage", use.Damage}, {"heal", use.Heal}} {
+		if f.expr == "" {
+			continue
+		}
+		if _, err := dice.Parse(f.expr); err != nil {
+			ve.addError(subject, fmt.Sprintf(
+				"item %q combat_use %s: %v", entityID, f.name, err))
+		}
+	}
+}
+
+func validateEnemy(entityID string, entity types.EntityDef, defs *state.Defs, opts options, ve *ValidationError) {
+	subject := "entity:" + entityID
 	// Required stats.
 	for _, stat := range []string{"hp", "max_hp", "attack", "defense"} {
 		v, ok := entity.Props[stat]
 		if !ok {
-			ve.Errors = append(ve.Errors, fmt.Sprintf(
+			ve.addError(subject, fmt.Sprintf(
 				"enemy %q missing required stat %q", entityID, stat))
 			continue
 		}
 		n, isInt := toValidateInt(v)
 		if !isInt || n <= 0 {
-			ve.Errors = append(ve.Errors, fmt.Sprintf(
+			ve.addError(subject, fmt.Sprintf(
 				"enemy %q stat %q must be a positive integer, got %v", entityID, stat, v))
 		}
 	}
@@ -368,33 +947,83 @@ func validateEnemy(entityID string, entity types.EntityDef, defs *state.Defs, ve
 	// Behavior (optional — warn if missing).
 	if behavior, ok := entity.Props["behavior"].([]types.BehaviorEntry); ok {
 		for _, b := range behavior {
-			if !validBehaviorActions[b.Action] {
-				ve.Errors = append(ve.Errors, fmt.Sprintf(
-					"enemy %q behavior action %q is not valid (attack, defend, flee)", entityID, b.Action))
+			actions, label := []string{b.Action}, b.Action
+			if len(b.Sequence) > 0 {
+				if b.Action != "" {
+					ve.addError(subject, fmt.Sprintf(
+						"enemy %q behavior entry has both an action %q and a sequence", entityID, b.Action))
+				}
+				actions, label = b.Sequence, strings.Join(b.Sequence, " then ")
+			}
+			for _, action := range actions {
+				if !validBehaviorActions[action] {
+					ve.addError(subject, fmt.Sprintf(
+						"enemy %q behavior action %q is not valid (attack, defend, flee)", entityID, action))
+				}
 			}
 			if b.Weight <= 0 {
-				ve.Errors = append(ve.Errors, fmt.Sprintf(
-					"enemy %q behavior action %q weight must be positive, got %d", entityID, b.Action, b.Weight))
+				ve.addError(subject, fmt.Sprintf(
+					"enemy %q behavior action %q weight must be positive, got %d", entityID, label, b.Weight))
 			}
+			validateConditions(subject, rules.BindSelf(b.When, entityID), defs, opts, ve)
 		}
 	} else if _, hasBehavior := entity.Props["behavior"]; !hasBehavior {
-		ve.Warnings = append(ve.Warnings, fmt.Sprintf(
+		ve.addWarning(subject, fmt.Sprintf(
 			"enemy %q has no behavior table (defaults to attack-only)", entityID))
 	}
 
+	// Attacks (optional).
+	if attacks, ok := entity.Props["attacks"].([]types.AttackDef); ok {
+		for i, a := range attacks {
+			if a.Name == "" {
+				ve.addError(subject, fmt.Sprintf(
+					"enemy %q attack %d has no name", entityID, i+1))
+			}
+			if _, err := dice.Parse(a.Damage); err != nil {
+				ve.addError(subject, fmt.Sprintf(
+					"enemy %q attack %q damage: %v", entityID, a.Name, err))
+			}
+			if a.Chance <= 0 {
+				ve.addError(subject, fmt.Sprintf(
+					"enemy %q attack %q chance must be positive, got %d", entityID, a.Name, a.Chance))
+			}
+		}
+	}
+
 	// Loot items (optional).
 	if lootItems, ok := entity.Props["loot_items"].([]types.LootEntry); ok {
 		for _, item := range lootItems {
 			if _, ok := defs.Entities[item.ItemID]; !ok {
-				ve.Errors = append(ve.Errors, fmt.Sprintf(
+				ve.addError(subject, fmt.Sprintf(
 					"enemy %q loot references undefined entity %q", entityID, item.ItemID))
 			}
 			if item.Chance < 1 || item.Chance > 100 {
-				ve.Errors = append(ve.Errors, fmt.Sprintf(
+				ve.addError(subject, fmt.Sprintf(
 					"enemy %q loot item %q chance must be 1-100, got %d", entityID, item.ItemID, item.Chance))
 			}
 		}
 	}
+	if expr, ok := entity.Props["loot_gold"].(string); ok {
+		if _, err := dice.Parse(expr); err != nil {
+			ve.addError(subject, fmt.Sprintf("enemy %q loot gold: %v", entityID, err))
+		}
+	}
+
+	// Morale and negotiation (optional).
+	if v, ok := entity.Props["morale"]; ok {
+		if n, isInt := toValidateInt(v); !isInt || n < 0 {
+			ve.addError(subject, fmt.Sprintf(
+				"enemy %q stat \"morale\" must be a non-negative integer, got %v", entityID, v))
+		}
+	}
+	if v, ok := entity.Props["on_break"]; ok && v != "surrender" && v != "flee" {
+		ve.addError(subject, fmt.Sprintf(
+			"enemy %q on_break %v is not valid (surrender, flee)", entityID, v))
+	}
+	if entity.Props["can_negotiate"] == true && len(entity.Topics) == 0 {
+		ve.addWarning(subject, fmt.Sprintf(
+			"enemy %q can_negotiate but has no topics to talk about", entityID))
+	}
 }
 
 // toValidateInt converts a value to int for validation purposes.
@@ -410,3 +1039,272 @@ func toValidateInt(v any) (int, bool) {
 		return 0, false
 	}
 }
+
+// validateComputed checks that a computed property has cases and that every
+// case condition reads only inputs declared in Uses.
+func validateComputed(def types.ComputedDef, defs *state.Defs, opts options, ve *ValidationError) {
+	subject := "computed:" + def.ID
+	if len(def.Cases) == 0 {
+		ve.addError(subject, fmt.Sprintf(
+			"computed %q has no cases", def.ID))
+	}
+	uses := map[string]bool{}
+	for _, u := range def.Uses {
+		uses[u] = true
+	}
+	for _, c := range def.Cases {
+		validateConditions(subject, c.When, defs, opts, ve)
+		for _, cond := range c.When {
+			inputs, ok := conditionInputs(cond, opts)
+			if !ok {
+				ve.addError(subject, fmt.Sprintf(
+					"computed %q cannot use condition type %q", def.ID, cond.Type))
+				continue
+			}
+			for _, input := range inputs {
+				if !uses[input] {
+					ve.addError(subject, fmt.Sprintf(
+						"computed %q reads %q but does not declare it in uses", def.ID, input))
+				}
+			}
+		}
+	}
+}
+
+// conditionInputs returns the state inputs a condition reads, in the
+// notation used by Computed uses lists; a condition type added by the
+// embedding program reads "custom.<type>". Returns false for conditions that
+// may not appear in computed cases (computed_is, to rule out cycles).
+func conditionInputs(cond types.Condition, opts options) ([]string, bool) {
+	if cond.Type == "not" {
+		if cond.Inner == nil {
+			return nil, false
+		}
+		return conditionInputs(*cond.Inner, opts)
+	}
+	spec, known := conditionSpecs[cond.Type]
+	if !known && opts.conditions.Has(cond.Type) {
+		return []string{"custom." + cond.Type}, true
+	}
+	if len(spec.reads) == 0 {
+		return nil, false
+	}
+	return spec.inputs(cond.Params), true
+}
+
+// sortedKeys returns the keys of a map in sorted order, for deterministic
+// error output.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// validateItemResponses checks an entity's on_receive or on_show (field)
+// entries: only NPCs are given or shown items, and each entry names an
+// entity.
+func validateItemResponses(entityID string, entity types.EntityDef, field string, responses map[string]types.ItemResponseDef, defs *state.Defs, opts options, ve *ValidationError) {
+	subject := "entity:" + entityID
+	if len(responses) > 0 && entity.Kind != "npc" {
+		ve.addWarning(subject, fmt.Sprintf(
+			"entity %q has %s but is not an NPC, so it is never given or shown items", entityID, field))
+	}
+	for _, item := range sortedKeys(responses) {
+		if _, ok := defs.Entities[item]; !ok {
+			ve.addError(subject, fmt.Sprintf(
+				"entity %q %s references undefined entity %q", entityID, field, item))
+		}
+		validateConditions(subject, responses[item].Conditions, defs, opts, ve)
+		validateEffects(subject, responses[item].Effects, defs, opts, ve)
+	}
+}
+
+// validateTopicLabels checks that the player can tell an NPC's topics
+// apart: no label is shared with, or names, another topic.
+func validateTopicLabels(entityID string, topics map[string]types.TopicDef, ve *ValidationError) {
+	names := map[string]string{} // lower-case key or label → topic key
+	for _, key := range sortedKeys(topics) {
+		names[strings.ToLower(key)] = key
+	}
+	for _, key := range sortedKeys(topics) {
+		label := strings.ToLower(topics[key].Label)
+		if label == "" || label == strings.ToLower(key) {
+			continue
+		}
+		if other, ok := names[label]; ok {
+			ve.addError("entity:"+entityID, fmt.Sprintf(
+				"entity %q topic %q label %q is also the name of topic %q", entityID, key, topics[key].Label, other))
+			continue
+		}
+		names[label] = key
+	}
+}
+
+// validateVehicle checks that id, which what names as a vehicle, is an
+// enterable entity.
+func validateVehicle(subject, what, id string, defs *state.Defs, ve *ValidationError) {
+	def, ok := defs.Entities[id]
+	if !ok {
+		ve.addError(subject, fmt.Sprintf("%s references undefined entity %q", what, id))
+	} else if def.Props["enterable"] != true {
+		ve.addWarning(subject, fmt.Sprintf("%s names %q, which is not enterable", what, id))
+	}
+}
+
+// validateLockable checks that id, which what names as a door or
+// container, is an entity with a lock.
+func validateLockable(subject, what, id string, defs *state.Defs, ve *ValidationError) {
+	def, ok := defs.Entities[id]
+	if !ok {
+		ve.addError(subject, fmt.Sprintf("%s references undefined entity %q", what, id))
+	} else if def.Props["lockable"] != true {
+		ve.addWarning(subject, fmt.Sprintf("%s names %q, which has no lock", what, id))
+	}
+}
+
+// validatePlayers checks the player characters of a game with several:
+// the one it starts as, and each one's start room and inventory.
+func validatePlayers(defs *state.Defs, ve *ValidationError) {
+	players := defs.Game.Players
+	if len(players) == 0 {
+		if defs.Game.StartPlayer != "" {
+			ve.addError("game", fmt.Sprintf(
+				"Game.start_player %q set but Game.players is empty", defs.Game.StartPlayer))
+		}
+		return
+	}
+	if defs.Game.StartPlayer == "" {
+		ve.addError("game", "Game.start_player is required with Game.players")
+	} else if _, ok := players[defs.Game.StartPlayer]; !ok {
+		ve.addError("game", fmt.Sprintf(
+			"Game.start_player %q is not one of Game.players", defs.Game.StartPlayer))
+	}
+	for _, id := range sortedKeys(players) {
+		def := players[id]
+		if def.Start != "" {
+			if _, ok := defs.Rooms[def.Start]; !ok {
+				ve.addError("game", fmt.Sprintf(
+					"player %q start room %q not found in defined rooms", id, def.Start))
+			}
+		}
+		for _, item := range def.Inventory {
+			if _, ok := defs.Entities[item]; !ok {
+				ve.addError("game", fmt.Sprintf(
+					"player %q inventory references undefined entity %q", id, item))
+			} else if loc, _ := defs.Entities[item].Props["location"].(string); loc != "" {
+				ve.addWarning("game", fmt.Sprintf(
+					"player %q starts with %q, which also has a location", id, item))
+			}
+		}
+	}
+}
+
+// validateLiquid checks that id, which what names as a liquid, is an
+// entity with liquid = true.
+func validateLiquid(subject, what, id string, defs *state.Defs, ve *ValidationError) {
+	def, ok := defs.Entities[id]
+	if !ok {
+		ve.addError(subject, fmt.Sprintf("%s references undefined entity %q", what, id))
+	} else if def.Props["liquid"] != true {
+		ve.addWarning(subject, fmt.Sprintf("%s names %q, which is not a liquid", what, id))
+	}
+}
+
+// validateAmbience checks a room's ambience entries, and warns if their
+// chances add up to more than 100, leaving the later ones unreachable
+// while the earlier ones all hold.
+func validateAmbience(roomID string, room types.RoomDef, defs *state.Defs, opts options, ve *ValidationError) {
+	subject := "room:" + roomID
+	total := 0
+	for i, line := range room.Ambience {
+		if line.Text == "" {
+			ve.addError(subject, fmt.Sprintf(
+				"room %q ambience entry %d has no text", roomID, i+1))
+		}
+		if line.Chance < 1 || line.Chance > 100 {
+			ve.addError(subject, fmt.Sprintf(
+				"room %q ambience entry %d chance must be from 1 to 100, got %d", roomID, i+1, line.Chance))
+		}
+		validateConditions(subject, line.Conditions, defs, opts, ve)
+		total += line.Chance
+	}
+	if total > 100 {
+		ve.addWarning(subject, fmt.Sprintf(
+			"room %q ambience chances add up to %d%%; entries past 100%% show only when earlier ones are ruled out", roomID, total))
+	}
+}
+
+// validateCapacity checks a room's capacity and overflow settings, and warns
+// when the room starts out holding more NPCs and enemies than it allows.
+func validateCapacity(roomID string, room types.RoomDef, defs *state.Defs, ve *ValidationError) {
+	if room.Capacity < 0 {
+		ve.addError("room:"+roomID, fmt.Sprintf(
+			"room %q capacity must be at least 0, got %d", roomID, room.Capacity))
+	}
+	switch room.Overflow {
+	case "", "reject", "adjacent":
+	default:
+		ve.addError("room:"+roomID, fmt.Sprintf(
+			"room %q overflow must be \"reject\" or \"adjacent\", got %q", roomID, room.Overflow))
+	}
+	if room.Capacity <= 0 {
+		return
+	}
+	occupants := 0
+	for _, def := range defs.Entities {
+		if (def.Kind == "npc" || def.Kind == "enemy") && def.Props["location"] == roomID {
+			occupants++
+		}
+	}
+	if occupants > room.Capacity {
+		ve.addWarning("room:"+roomID, fmt.Sprintf(
+			"room %q starts with %d occupants but has capacity %d", roomID, occupants, room.Capacity))
+	}
+}
+
+// validateImages checks that the images rooms and entities declare exist in
+// the game directory fsys.
+func validateImages(fsys fs.FS, defs *state.Defs, ve *ValidationError) {
+	for _, roomID := range sortedKeys(defs.Rooms) {
+		if image := defs.Rooms[roomID].Image; image != "" && !isFile(fsys, image) {
+			ve.addError("room:"+roomID, fmt.Sprintf("room %q image %q not found", roomID, image))
+		}
+	}
+	for _, entityID := range sortedKeys(defs.Entities) {
+		image, _ := defs.Entities[entityID].Props["image"].(string)
+		if image != "" && !isFile(fsys, image) {
+			ve.addError("entity:"+entityID, fmt.Sprintf("entity %q image %q not found", entityID, image))
+		}
+	}
+}
+
+// Images returns the images the game's rooms and entities declare, as
+// slash-separated paths relative to the game directory, sorted and without
+// repeats.
+func Images(defs *state.Defs) []string {
+	seen := map[string]bool{}
+	for _, room := range defs.Rooms {
+		if room.Image != "" {
+			seen[room.Image] = true
+		}
+	}
+	for _, entity := range defs.Entities {
+		if image, _ := entity.Props["image"].(string); image != "" {
+			seen[image] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// isFile reports whether name, a slash-separated path relative to the root
+// of fsys, is a regular file.
+func isFile(fsys fs.FS, name string) bool {
+	if !fs.ValidPath(name) {
+		return false
+	}
+	info, err := fs.Stat(fsys, name)
+	return err == nil && info.Mode().IsRegular()
+}
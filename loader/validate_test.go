@@ -1,8 +1,13 @@
 package loader
 
 import (
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
 
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/engine/rules"
 	"github.com/nathoo/questcore/engine/state"
 	"github.com/nathoo/questcore/types"
 )
@@ -126,6 +131,38 @@ func TestValidate_UnknownConditionType(t *testing.T) {
 	assertContains(t, ve.Errors, "unknown condition type")
 }
 
+func TestValidate_RegisteredTypes(t *testing.T) {
+	effs := effects.NewRegistry()
+	if err := effs.Add("test_rumble", func(*types.State, *state.Defs, types.Effect, effects.Context) ([]types.Event, []string) {
+		return nil, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	conds := rules.NewRegistry()
+	if err := conds.Add("test_is_raining", func(types.Condition, *types.State, *state.Defs) bool {
+		return false
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	defs := validDefs()
+	defs.GlobalRules = []types.RuleDef{
+		{
+			ID:         "r1",
+			Scope:      "global",
+			Conditions: []types.Condition{{Type: "test_is_raining", Params: map[string]any{"heavy": true}}},
+			Effects:    []types.Effect{{Type: "test_rumble", Params: map[string]any{"strength": 3}}},
+		},
+	}
+
+	if err := validate(defs); err == nil {
+		t.Error("types not given to the loader should not validate")
+	}
+	if err := validate(defs, WithEffects(effs), WithConditions(conds)); err != nil {
+		t.Errorf("registered types should validate, got: %v", err)
+	}
+}
+
 func TestValidate_UndefinedEntityInEffect(t *testing.T) {
 	defs := validDefs()
 	defs.GlobalRules = []types.RuleDef{
@@ -195,6 +232,173 @@ func TestValidate_UnrecognizedVerb_Warning(t *testing.T) {
 	}
 }
 
+func TestValidate_ExitGuards(t *testing.T) {
+	defs := validDefs()
+	defs.Rooms["hall"] = types.RoomDef{
+		ID:    "hall",
+		Exits: map[string]string{"north": "", "east": "hall"},
+		ExitGuards: map[string]types.ExitGuardDef{
+			"north": {},
+			"east":  {Requires: []types.Condition{{Type: "has_item", Params: map[string]any{"item": "ghost"}}}},
+		},
+	}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for bad exit guards")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, `exit "north" has no target room`)
+	assertContains(t, ve.Errors, `"ghost"`)
+}
+
+func TestValidate_Vehicles(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["crate"] = types.EntityDef{ID: "crate", Kind: "entity", Props: map[string]any{"location": "hall"}}
+	defs.Rooms["hall"] = types.RoomDef{
+		ID:    "hall",
+		Exits: map[string]string{"north": "hall", "east": "hall"},
+		ExitGuards: map[string]types.ExitGuardDef{
+			"north": {Vehicle: "raft"},
+			"east":  {Vehicle: "crate"},
+		},
+	}
+	defs.GlobalRules = []types.RuleDef{{ID: "r1", Scope: "global",
+		Conditions: []types.Condition{{Type: "in_vehicle"}},
+		Effects:    []types.Effect{{Type: "enter_vehicle", Params: map[string]any{"entity": "cart"}}, {Type: "leave_vehicle", Params: map[string]any{}}}}}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for undefined vehicles")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, `exit "north" references undefined entity "raft"`)
+	assertContains(t, ve.Errors, `effect enter_vehicle references undefined entity "cart"`)
+	assertContains(t, ve.Warnings, `"crate", which is not enterable`)
+	if len(ve.Errors) != 2 {
+		t.Errorf("errors = %v", ve.Errors)
+	}
+}
+
+func TestValidate_Attach(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["key"] = types.EntityDef{ID: "key", Kind: "item", Props: map[string]any{"location": "hall"}}
+	defs.GlobalRules = []types.RuleDef{{ID: "r1", Scope: "global",
+		Conditions: []types.Condition{{Type: "is_attached", Params: map[string]any{"entity": "rope"}}},
+		Effects: []types.Effect{
+			{Type: "attach", Params: map[string]any{"entity": "key", "to": "hook"}},
+			{Type: "detach", Params: map[string]any{"entity": "key"}},
+		}}}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for undefined entities")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, `condition is_attached references undefined entity "rope"`)
+	assertContains(t, ve.Errors, `effect attach references undefined entity "hook"`)
+	if len(ve.Errors) != 2 {
+		t.Errorf("errors = %v", ve.Errors)
+	}
+}
+
+func TestValidate_Locks(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["chest"] = types.EntityDef{ID: "chest", Kind: "entity",
+		Props: map[string]any{"location": "hall", "lockable": true, "lock_key": "skeleton_key", "locked": true}}
+	defs.Entities["crate"] = types.EntityDef{ID: "crate", Kind: "entity", Props: map[string]any{"location": "hall"}}
+	defs.Rooms["hall"] = types.RoomDef{
+		ID:         "hall",
+		Exits:      map[string]string{"north": "hall"},
+		ExitGuards: map[string]types.ExitGuardDef{"north": {Door: "gate"}},
+	}
+	defs.GlobalRules = []types.RuleDef{{ID: "r1", Scope: "global",
+		Effects: []types.Effect{
+			{Type: "unlock", Params: map[string]any{"entity": "crate"}},
+			{Type: "lock", Params: map[string]any{"entity": "chest", "key": "bent_pin"}},
+		}}}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for undefined keys and doors")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, `entity "chest" lock key references undefined entity "skeleton_key"`)
+	assertContains(t, ve.Errors, `room "hall" exit "north" door references undefined entity "gate"`)
+	assertContains(t, ve.Errors, `effect lock references undefined key "bent_pin"`)
+	assertContains(t, ve.Warnings, `effect unlock names "crate", which has no lock`)
+	if len(ve.Errors) != 3 {
+		t.Errorf("errors = %v", ve.Errors)
+	}
+}
+
+func TestValidate_Liquids(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["bottle"] = types.EntityDef{ID: "bottle", Kind: "item",
+		Props: map[string]any{"location": "hall", "holds_liquid": true, "filled_with": "wine"}}
+	defs.Entities["well"] = types.EntityDef{ID: "well", Kind: "entity",
+		Props: map[string]any{"location": "hall", "liquid_source": "bottle"}}
+	defs.GlobalRules = []types.RuleDef{{ID: "r1", Scope: "global",
+		Conditions: []types.Condition{{Type: "has_liquid", Params: map[string]any{"liquid": "oil"}}},
+		Effects:    []types.Effect{{Type: "pour_liquid", Params: map[string]any{"vessel": "jug"}}}}}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for undefined liquids")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, `entity "bottle" filled_with references undefined entity "wine"`)
+	assertContains(t, ve.Errors, `condition has_liquid references undefined entity "oil"`)
+	assertContains(t, ve.Errors, `effect pour_liquid references undefined entity "jug"`)
+	assertContains(t, ve.Warnings, `"bottle", which is not a liquid`)
+	if len(ve.Errors) != 3 {
+		t.Errorf("errors = %v", ve.Errors)
+	}
+}
+
+func TestValidate_Players(t *testing.T) {
+	defs := validDefs()
+	defs.Game.Players = map[string]types.PlayerDef{
+		"alice": {Start: "attic"},
+		"bob":   {Inventory: []string{"lamp"}},
+	}
+	defs.Game.StartPlayer = "carol"
+	defs.GlobalRules = []types.RuleDef{{ID: "r1", Scope: "global",
+		Conditions: []types.Condition{{Type: "is_player", Params: map[string]any{"player": "alice"}}},
+		Effects:    []types.Effect{{Type: "switch_player", Params: map[string]any{"player": "dave"}}}}}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for undefined players")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, `Game.start_player "carol" is not one of Game.players`)
+	assertContains(t, ve.Errors, `player "alice" start room "attic" not found`)
+	assertContains(t, ve.Errors, `player "bob" inventory references undefined entity "lamp"`)
+	assertContains(t, ve.Errors, `effect switch_player references undefined player "dave"`)
+	if len(ve.Errors) != 4 {
+		t.Errorf("errors = %v", ve.Errors)
+	}
+}
+
+func TestValidate_Facts(t *testing.T) {
+	defs := validDefs()
+	defs.Facts = map[string]types.FactDef{"password": {ID: "password"}}
+	defs.GlobalRules = []types.RuleDef{{ID: "r1", Scope: "global",
+		Conditions: []types.Condition{{Type: "knows_fact", Params: map[string]any{"fact": "password"}}},
+		Effects:    []types.Effect{{Type: "learn_fact", Params: map[string]any{"fact": "map"}}}}}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected an error for the undefined fact")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, `effect learn_fact references undefined fact "map"`)
+	if len(ve.Errors) != 1 {
+		t.Errorf("errors = %v", ve.Errors)
+	}
+}
+
 func TestValidate_UndefinedRoomInEffect(t *testing.T) {
 	defs := validDefs()
 	defs.GlobalRules = []types.RuleDef{
@@ -255,6 +459,176 @@ func TestValidate_UndefinedRoomInCondition(t *testing.T) {
 	assertContains(t, ve.Errors, "undefined room")
 }
 
+func TestValidate_ComputedUndeclaredInput(t *testing.T) {
+	defs := validDefs()
+	defs.Computed = map[string]types.ComputedDef{
+		"door_status": {
+			ID:   "door_status",
+			Uses: []string{"flags.door_open"},
+			Cases: []types.ComputedCase{
+				{When: []types.Condition{{Type: "counter_gt", Params: map[string]any{"counter": "gold", "value": 1}}}, Value: "rich"},
+			},
+		},
+	}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for undeclared computed input")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, `reads "counters.gold"`)
+}
+
+func TestValidate_ComputedCounterCmpReadsBoth(t *testing.T) {
+	defs := validDefs()
+	defs.Computed = map[string]types.ComputedDef{
+		"affordable": {
+			ID:   "affordable",
+			Uses: []string{"counters.gold"},
+			Cases: []types.ComputedCase{
+				{When: []types.Condition{{Type: "counter_cmp", Params: map[string]any{"counter": "gold", "op": ">=", "other": "price"}}}, Value: true},
+			},
+		},
+	}
+
+	ve := validate(defs).(*ValidationError)
+	assertContains(t, ve.Errors, `reads "counters.price"`)
+}
+
+func TestConditionInputs(t *testing.T) {
+	conds := rules.NewRegistry()
+	if err := conds.Add("test_is_raining", func(types.Condition, *types.State, *state.Defs) bool {
+		return false
+	}); err != nil {
+		t.Fatal(err)
+	}
+	opts := newOptions([]Option{WithConditions(conds)})
+
+	tests := []struct {
+		cond types.Condition
+		want []string // nil: the condition may not appear in computed cases
+	}{
+		{types.Condition{Type: "has_item", Params: map[string]any{"item": "key"}}, []string{"inventory.key"}},
+		{types.Condition{Type: "flag_is", Params: map[string]any{"flag": "lit", "value": true}}, []string{"flags.lit"}},
+		{types.Condition{Type: "counter_cmp", Params: map[string]any{"counter": "gold", "op": ">", "other": "price"}}, []string{"counters.gold", "counters.price"}},
+		{types.Condition{Type: "in_room", Params: map[string]any{"room": "hall"}}, []string{"player.location"}},
+		{types.Condition{Type: "prop_is", Params: map[string]any{"entity": "door", "prop": "locked", "value": true}}, []string{"entities.door.locked"}},
+		{types.Condition{Type: "in_combat"}, []string{"combat"}},
+		{types.Condition{Type: "stat_lt", Params: map[string]any{"entity": "player", "stat": "hp", "value": 5}}, []string{"stats.player.hp"}},
+		{types.Condition{Type: "in_vehicle", Params: map[string]any{"entity": "boat"}}, []string{"player.vehicle"}},
+		{types.Condition{Type: "is_attached", Params: map[string]any{"entity": "rope", "to": "hook"}}, []string{"attached.rope"}},
+		{types.Condition{Type: "has_liquid", Params: map[string]any{"liquid": "water"}}, []string{"liquids.water"}},
+		{types.Condition{Type: "is_player", Params: map[string]any{"player": "bob"}}, []string{"player.active"}},
+		{types.Condition{Type: "knows_fact", Params: map[string]any{"fact": "password"}}, []string{"facts.password"}},
+		{types.Condition{Type: "time_is", Params: map[string]any{"period": "night"}}, []string{"clock"}},
+		{types.Condition{Type: "time_between", Params: map[string]any{"from": "08:00", "to": "17:00"}}, []string{"clock"}},
+		{types.Condition{Type: "not", Inner: &types.Condition{Type: "knows_fact", Params: map[string]any{"fact": "password"}}}, []string{"facts.password"}},
+		{types.Condition{Type: "test_is_raining"}, []string{"custom.test_is_raining"}},
+		{types.Condition{Type: "computed_is", Params: map[string]any{"computed": "a", "value": 1}}, nil},
+		{types.Condition{Type: "not"}, nil},
+		{types.Condition{Type: "bogus"}, nil},
+	}
+	for _, tt := range tests {
+		got, ok := conditionInputs(tt.cond, opts)
+		if ok != (tt.want != nil) || !slices.Equal(got, tt.want) {
+			t.Errorf("conditionInputs(%s) = %v, %v; want %v", tt.cond.Type, got, ok, tt.want)
+		}
+	}
+}
+
+func TestValidate_ComputedReadsFact(t *testing.T) {
+	defs := validDefs()
+	defs.Computed = map[string]types.ComputedDef{
+		"mood": {
+			ID:   "mood",
+			Uses: []string{"flags.door_open"},
+			Cases: []types.ComputedCase{
+				{When: []types.Condition{{Type: "knows_fact", Params: map[string]any{"fact": "secret"}}}, Value: "smug"},
+			},
+		},
+	}
+
+	ve := validate(defs).(*ValidationError)
+	assertContains(t, ve.Errors, `reads "facts.secret"`)
+	for _, e := range ve.Errors {
+		if strings.Contains(e, "cannot use condition type") {
+			t.Errorf("knows_fact should be usable in computed cases, got %q", e)
+		}
+	}
+}
+
+func TestValidate_CounterConditions(t *testing.T) {
+	defs := validDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "buy", Scope: "global", When: types.MatchCriteria{Verb: "buy"},
+		Conditions: []types.Condition{
+			{Type: "counter_cmp", Params: map[string]any{"counter": "gold", "op": "=>", "other": "price"}},
+			{Type: "counter_between", Params: map[string]any{"counter": "gold", "min": 9, "max": 3}},
+		},
+	})
+
+	ve := validate(defs).(*ValidationError)
+	assertContains(t, ve.Errors, `condition counter_cmp op must be one of ==, !=, <, <=, >, >=, got "=>"`)
+	assertContains(t, ve.Errors, "condition counter_between min 9 is greater than max 3")
+}
+
+func TestValidate_NumericProps(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["plant"] = types.EntityDef{ID: "plant", Kind: "entity", Props: map[string]any{
+		"location": "hall", "growth": 0, "name": "fern",
+	}}
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "water", Scope: "global", When: types.MatchCriteria{Verb: "water"},
+		Conditions: []types.Condition{
+			{Type: "prop_lt", Params: map[string]any{"entity": "plant", "prop": "growth", "value": 3}},
+			{Type: "prop_gt", Params: map[string]any{"entity": "plant", "prop": "name", "value": 0}},
+		},
+		Effects: []types.Effect{
+			{Type: "inc_prop", Params: map[string]any{"entity": "plant", "prop": "growth", "amount": 1}},
+			{Type: "dec_prop", Params: map[string]any{"entity": "plant", "prop": "water", "amount": 1}},
+		},
+	})
+
+	ve := validate(defs).(*ValidationError)
+	assertContains(t, ve.Errors, `condition prop_gt: entity "plant" must declare "name" as a number`)
+	assertContains(t, ve.Errors, `effect dec_prop: entity "plant" must declare "water" as a number`)
+	for _, e := range ve.Errors {
+		if strings.Contains(e, `"growth"`) {
+			t.Errorf("growth is numeric, got error %q", e)
+		}
+	}
+}
+
+func TestValidate_ComputedCannotReferenceComputed(t *testing.T) {
+	defs := validDefs()
+	defs.Computed = map[string]types.ComputedDef{
+		"a": {ID: "a", Cases: []types.ComputedCase{
+			{When: []types.Condition{{Type: "computed_is", Params: map[string]any{"computed": "a", "value": 1}}}, Value: 1},
+		}},
+	}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for computed_is inside a computed case")
+	}
+	assertContains(t, err.(*ValidationError).Errors, "cannot use condition type")
+}
+
+func TestValidate_ComputedIsUndefined(t *testing.T) {
+	defs := validDefs()
+	defs.GlobalRules = []types.RuleDef{
+		{ID: "r1", Scope: "global", Conditions: []types.Condition{
+			{Type: "computed_is", Params: map[string]any{"computed": "ghost", "value": 1}},
+		}},
+	}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for undefined computed property")
+	}
+	assertContains(t, err.(*ValidationError).Errors, "undefined computed property")
+}
+
 // assertContains checks that at least one string in the slice contains substr.
 func assertContains(t *testing.T, strs []string, substr string) {
 	t.Helper()
@@ -278,3 +652,495 @@ func searchString(s, substr string) bool {
 	}
 	return false
 }
+
+func TestValidate_IdleNudge(t *testing.T) {
+	defs := validDefs()
+	defs.Game.IdleNudge = &types.IdleNudgeDef{
+		Nudges: []types.NudgeDef{{Conditions: []types.Condition{
+			{Type: "in_room", Params: map[string]any{"room": "nowhere"}},
+		}}},
+	}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for bad idle_nudge")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, "minutes must be a positive number")
+	assertContains(t, ve.Errors, "has no text")
+	assertContains(t, ve.Errors, "nowhere")
+}
+
+func TestValidate_Answers(t *testing.T) {
+	defs := validDefs()
+	defs.Answers = map[string]types.AnswerDef{
+		"riddle": {ID: "riddle", Attempts: 0, Success: []types.Effect{
+			{Type: "give_item", Params: map[string]any{"item": "ghost"}},
+		}},
+	}
+	defs.GlobalRules = []types.RuleDef{
+		{ID: "r1", Scope: "global", Effects: []types.Effect{
+			{Type: "ask", Params: map[string]any{"answer": "missing"}},
+		}},
+	}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for bad answer definitions")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, "has no accepted answers")
+	assertContains(t, ve.Errors, "attempts must be at least 1")
+	assertContains(t, ve.Errors, `undefined entity "ghost"`)
+	assertContains(t, ve.Errors, `undefined answer "missing"`)
+}
+
+func TestValidate_MetaCommands(t *testing.T) {
+	defs := validDefs()
+	defs.MetaCommands = map[string]types.MetaCommandDef{
+		"/save":  {Name: "/save", Verb: "look"},
+		"spells": {Name: "spells", Verb: "cast"},
+		"/both": {Name: "/both", Verb: "look", Effects: []types.Effect{
+			{Type: "say", Params: map[string]any{"text": "hi"}},
+		}},
+		"/empty": {Name: "/empty"},
+		"/ghost": {Name: "/ghost", Effects: []types.Effect{
+			{Type: "give_item", Params: map[string]any{"item": "ghost"}},
+		}},
+		"/map": {Name: "/map", Verb: "look"},
+	}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for bad meta-commands")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, `"/save" collides with the built-in /save`)
+	assertContains(t, ve.Errors, `"spells" must be a slash followed by a single word`)
+	assertContains(t, ve.Errors, `"/both" has both a verb and effects`)
+	assertContains(t, ve.Errors, `"/empty" has neither a verb nor effects`)
+	assertContains(t, ve.Errors, `undefined entity "ghost"`)
+	for _, e := range ve.Errors {
+		if strings.Contains(e, `"/map"`) {
+			t.Errorf("unexpected error for /map: %s", e)
+		}
+	}
+}
+
+func TestValidate_RulePhase(t *testing.T) {
+	succeeded := true
+	defs := validDefs()
+	defs.GlobalRules = []types.RuleDef{
+		{ID: "bad_phase", Scope: "global", When: types.MatchCriteria{Verb: "take", Phase: "during"}},
+		{ID: "early_outcome", Scope: "global", When: types.MatchCriteria{Verb: "take", Phase: "before", Succeeded: &succeeded}},
+		{ID: "ok_after", Scope: "global", When: types.MatchCriteria{Verb: "take", Phase: "after", Succeeded: &succeeded}},
+	}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for bad phases")
+	}
+	ve := err.(*ValidationError)
+	if len(ve.Errors) != 2 {
+		t.Errorf("expected 2 errors, got %v", ve.Errors)
+	}
+	assertContains(t, ve.Errors, `unknown phase "during"`)
+	assertContains(t, ve.Errors, "succeeded outside the after phase")
+}
+
+func TestValidate_RoomCapacity(t *testing.T) {
+	defs := validDefs()
+	defs.Rooms["cellar"] = types.RoomDef{ID: "cellar", Capacity: -1, Overflow: "spill"}
+	defs.Rooms["closet"] = types.RoomDef{ID: "closet", Capacity: 1}
+	for _, id := range []string{"rat", "bat"} {
+		defs.Entities[id] = types.EntityDef{ID: id, Kind: "enemy", Props: map[string]any{"location": "closet"}}
+	}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for bad capacity settings")
+	}
+	ve := err.(*ValidationError)
+	assertContains(t, ve.Errors, "capacity must be at least 0")
+	assertContains(t, ve.Errors, `overflow must be "reject" or "adjacent"`)
+	assertContains(t, ve.Warnings, "starts with 2 occupants but has capacity 1")
+}
+
+func TestValidate_Ambience(t *testing.T) {
+	defs := validDefs()
+	defs.Game.AmbienceCooldown = -1
+	defs.Game.UnknownVerbResponses = []string{"Eh?", " "}
+	defs.Rooms["cellar"] = types.RoomDef{ID: "cellar", Ambience: []types.AmbienceDef{
+		{Chance: 80, Text: "Water drips."},
+		{Chance: 0, Text: "A rat squeaks."},
+		{Chance: 40},
+	}}
+
+	ve := analyze(defs)
+	assertContains(t, ve.Errors, "Game.ambience_cooldown must be at least 0, got -1")
+	assertContains(t, ve.Errors, "Game.unknown_verb_responses[2] is empty")
+	assertContains(t, ve.Errors, `room "cellar" ambience entry 2 chance must be from 1 to 100, got 0`)
+	assertContains(t, ve.Errors, `room "cellar" ambience entry 3 has no text`)
+	assertContains(t, ve.Warnings, `room "cellar" ambience chances add up to 120%`)
+}
+
+func TestValidate_Noise(t *testing.T) {
+	defs := validDefs()
+	defs.Game.Noise = map[string]int{"yell": 10, "take": -1}
+
+	ve := analyze(defs)
+	assertContains(t, ve.Errors, `Game.noise: "take" must be at least 0, got -1`)
+	if len(ve.Errors) != 1 {
+		t.Errorf("errors = %v", ve.Errors)
+	}
+}
+
+func TestValidate_TopicLabels(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["sage"] = types.EntityDef{ID: "sage", Kind: "npc", Props: map[string]any{"location": "hall"},
+		Topics: map[string]types.TopicDef{
+			"a": {Text: "A.", Label: "Stars"},
+			"b": {Text: "B.", Label: "stars"},
+			"c": {Text: "C.", Label: "a"},
+			"d": {Text: "D.", Label: "D"},
+		}}
+
+	ve := analyze(defs)
+	assertContains(t, ve.Errors, `entity "sage" topic "b" label "stars" is also the name of topic "a"`)
+	assertContains(t, ve.Errors, `entity "sage" topic "c" label "a" is also the name of topic "a"`)
+	for _, e := range ve.Errors {
+		if strings.Contains(e, `topic "d"`) {
+			t.Errorf("a label matching its own key is fine, got %q", e)
+		}
+	}
+}
+
+func TestValidate_ItemResponses(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["coin"] = types.EntityDef{ID: "coin", Kind: "item", Props: map[string]any{"location": "hall"}}
+	defs.Entities["sage"] = types.EntityDef{ID: "sage", Kind: "npc", Props: map[string]any{"location": "hall"},
+		OnReceive: map[string]types.ItemResponseDef{
+			"coin":  {Text: "Thanks."},
+			"ghost": {Text: "Boo."},
+		}}
+	defs.Entities["statue"] = types.EntityDef{ID: "statue", Kind: "entity", Props: map[string]any{"location": "hall"},
+		OnShow: map[string]types.ItemResponseDef{"coin": {Text: "..."}}}
+
+	ve := analyze(defs)
+	assertContains(t, ve.Errors, `entity "sage" on_receive references undefined entity "ghost"`)
+	assertContains(t, ve.Warnings, `entity "statue" has on_show but is not an NPC`)
+}
+
+func TestValidate_Inventory(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["coin"] = types.EntityDef{ID: "coin", Kind: "item", Props: map[string]any{"location": "hall"}}
+	defs.Entities["sage"] = types.EntityDef{ID: "sage", Kind: "npc", Props: map[string]any{"location": "hall"},
+		Inventory: []string{"coin", "ghost"}}
+
+	ve := analyze(defs)
+	assertContains(t, ve.Errors, `entity "sage" inventory references undefined entity "ghost"`)
+	assertContains(t, ve.Errors, `entity "sage" inventory lists "coin", whose location is "hall"`)
+}
+
+func TestValidate_DuplicateHandlerID(t *testing.T) {
+	defs := validDefs()
+	defs.Handlers = []types.EventHandler{
+		{ID: "log", EventType: "*"},
+		{ID: "log", EventType: "item_taken"},
+	}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for duplicate handler ID")
+	}
+	assertContains(t, err.(*ValidationError).Errors, `duplicate handler ID "log"`)
+}
+
+func TestValidate_NegativeCommandLogLimit(t *testing.T) {
+	defs := validDefs()
+	defs.Game.CommandLogLimit = -1
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for negative command_log_limit")
+	}
+	assertContains(t, err.(*ValidationError).Errors, "command_log_limit must be at least 0")
+}
+
+func TestValidate_Theme(t *testing.T) {
+	defs := validDefs()
+	defs.Game.Theme = map[string]string{"preset": "light", "header": "#8b0000"}
+	if err := validate(defs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	defs.Game.Theme["headline"] = "1"
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for an unknown theme color")
+	}
+	assertContains(t, err.(*ValidationError).Errors, `Game.theme: unknown color "headline"`)
+}
+
+func TestValidate_LevelsMustRise(t *testing.T) {
+	defs := validDefs()
+	defs.Game.Levels = []types.LevelDef{{XP: 100}, {XP: 100}}
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for non-increasing level xp")
+	}
+	assertContains(t, err.(*ValidationError).Errors, "Game.levels[2] xp must be greater than 100, got 100")
+}
+
+func TestValidate_DeathPolicy(t *testing.T) {
+	defs := validDefs()
+	defs.Game.OnDeath = "reincarnate"
+	defs.Game.RespawnRoom = "nowhere"
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for a bad death policy")
+	}
+	errs := err.(*ValidationError).Errors
+	assertContains(t, errs, `Game.on_death must be "prompt", "respawn" or "restart", got "reincarnate"`)
+	assertContains(t, errs, `Game.respawn_room references undefined room "nowhere"`)
+}
+
+func TestValidate_EndGameUndefinedEnding(t *testing.T) {
+	defs := validDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "win", Scope: "global", When: types.MatchCriteria{Verb: "wave"},
+		Effects: []types.Effect{{Type: "end_game", Params: map[string]any{"ending": "good"}}},
+	})
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for undefined ending")
+	}
+	assertContains(t, err.(*ValidationError).Errors, `effect end_game references undefined ending "good"`)
+}
+
+func TestValidate_UnlockUndefinedAchievement(t *testing.T) {
+	defs := validDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "spare", Scope: "global", When: types.MatchCriteria{Verb: "wave"},
+		Effects: []types.Effect{{Type: "unlock_achievement", Params: map[string]any{"achievement": "pacifist"}}},
+	})
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for undefined achievement")
+	}
+	assertContains(t, err.(*ValidationError).Errors, `effect unlock_achievement references undefined achievement "pacifist"`)
+}
+
+func TestValidate_PlaySoundRequiresName(t *testing.T) {
+	defs := validDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "ring", Scope: "global", When: types.MatchCriteria{Verb: "ring"},
+		Effects: []types.Effect{{Type: "play_sound", Params: map[string]any{"sound": ""}}},
+	})
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for empty sound")
+	}
+	assertContains(t, err.(*ValidationError).Errors, "effect play_sound requires a sound name")
+}
+
+func TestValidate_Clock(t *testing.T) {
+	defs := validDefs()
+	defs.Game.Clock = &types.ClockDef{Start: -1, MinutesPerTurn: -5, Dawn: 360, Dusk: 1200}
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "nap", Scope: "global", When: types.MatchCriteria{Verb: "wait"},
+		Conditions: []types.Condition{
+			{Type: "time_is", Params: map[string]any{"period": "dusk"}},
+			{Type: "time_between", Params: map[string]any{"from": "9pm", "to": "23:00"}},
+		},
+	})
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected errors for a bad clock")
+	}
+	errs := err.(*ValidationError).Errors
+	assertContains(t, errs, `Game.clock.start must be a time of day "HH:MM"`)
+	assertContains(t, errs, "Game.clock.minutes_per_turn must be at least 0, got -5")
+	assertContains(t, errs, `condition time_is period must be "day" or "night", got "dusk"`)
+	assertContains(t, errs, `condition time_between from must be a time of day "HH:MM", got "9pm"`)
+}
+
+func TestValidate_TimeConditionWithoutClock(t *testing.T) {
+	defs := validDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "nap", Scope: "global", When: types.MatchCriteria{Verb: "wait"},
+		Conditions: []types.Condition{{Type: "time_is", Params: map[string]any{"period": "night"}}},
+	})
+
+	err := validate(defs)
+	if err == nil {
+		t.Fatal("expected error for a time condition without a clock")
+	}
+	assertContains(t, err.(*ValidationError).Errors, "condition time_is requires Game.clock")
+}
+
+func TestValidate_MessageOverrides(t *testing.T) {
+	defs := validDefs()
+	defs.Messages = map[string]string{
+		"cant_go":  "Impossible d'aller par là.",
+		"take":     "Vous prenez {objet}.",
+		"no_entry": "Hm.",
+	}
+
+	ve := analyze(defs)
+	if len(ve.Errors) != 0 {
+		t.Fatalf("message overrides should be warnings only, got %v", ve.Errors)
+	}
+	if len(ve.Warnings) != 2 {
+		t.Errorf("expected 2 warnings, got %v", ve.Warnings)
+	}
+	assertContains(t, ve.Warnings, `Messages has no entry "no_entry"`)
+	assertContains(t, ve.Warnings, "Messages.take uses unknown placeholder {objet}")
+}
+
+func TestValidateImages(t *testing.T) {
+	defs := validDefs()
+	room := defs.Rooms[defs.Game.Start]
+	room.Image = "art/hall.png"
+	defs.Rooms[room.ID] = room
+	defs.Entities["lamp"] = types.EntityDef{ID: "lamp", Kind: "item", Props: map[string]any{"image": "art/lamp.png"}}
+	defs.Entities["map"] = types.EntityDef{ID: "map", Kind: "item", Props: map[string]any{"image": "../map.png"}}
+	fsys := fstest.MapFS{
+		"art/hall.png": {Data: []byte("png")},
+		"art/lamp.png": {Data: []byte("png")},
+	}
+
+	ve := &ValidationError{}
+	validateImages(fsys, defs, ve)
+	if len(ve.Errors) != 1 {
+		t.Fatalf("expected 1 error, got %v", ve.Errors)
+	}
+	assertContains(t, ve.Errors, `entity "map" image "../map.png" not found`)
+
+	delete(fsys, "art/hall.png")
+	ve = &ValidationError{}
+	validateImages(fsys, defs, ve)
+	assertContains(t, ve.Errors, `room "hall" image "art/hall.png" not found`)
+}
+
+func TestValidate_EnemyBehaviorEntries(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["troll"] = types.EntityDef{ID: "troll", Kind: "enemy", Props: map[string]any{
+		"location": "hall", "hp": 20, "max_hp": 20, "attack": 5, "defense": 2,
+		"behavior": []types.BehaviorEntry{
+			{Action: "flee", Weight: 80, When: []types.Condition{
+				{Type: "stat_lt", Params: map[string]any{"entity": "self", "stat": "hp", "value": 4}},
+				{Type: "has_item", Params: map[string]any{"item": "club"}},
+			}},
+			{Sequence: []string{"defend", "charge"}, Weight: 20},
+			{Action: "attack", Sequence: []string{"attack"}, Weight: 10},
+		},
+	}}
+
+	ve := validate(defs).(*ValidationError)
+	assertContains(t, ve.Errors, `condition has_item references undefined entity "club"`)
+	assertContains(t, ve.Errors, `enemy "troll" behavior action "charge" is not valid`)
+	assertContains(t, ve.Errors, `enemy "troll" behavior entry has both an action "attack" and a sequence`)
+	for _, e := range ve.Errors {
+		if strings.Contains(e, `"self"`) {
+			t.Errorf("self should name the enemy, got %q", e)
+		}
+	}
+}
+
+func TestValidate_EnemyAttacks(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["spider"] = types.EntityDef{ID: "spider", Kind: "enemy", Props: map[string]any{
+		"location": "hall", "hp": 6, "max_hp": 6, "attack": 2, "defense": 1,
+		"behavior": []types.BehaviorEntry{{Action: "attack", Weight: 1}},
+		"attacks": []types.AttackDef{
+			{Name: "bite", Damage: "1d6+", Chance: 60},
+			{Name: "web", Damage: "1", Chance: 0},
+			{Damage: "d4", Chance: 10},
+		},
+	}}
+
+	ve := validate(defs).(*ValidationError)
+	assertContains(t, ve.Errors, `enemy "spider" attack "bite" damage: dice expression "1d6+": bad bonus "+"`)
+	assertContains(t, ve.Errors, `enemy "spider" attack "web" chance must be positive, got 0`)
+	assertContains(t, ve.Errors, `enemy "spider" attack 3 has no name`)
+}
+
+func TestValidate_CombatUse(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["bomb"] = types.EntityDef{ID: "bomb", Kind: "item", Props: map[string]any{
+		"combat_use": types.CombatUse{Damage: "2d", Consumes: true},
+	}}
+	defs.Entities["charm"] = types.EntityDef{ID: "charm", Kind: "item", Props: map[string]any{
+		"combat_use": types.CombatUse{Consumes: true},
+	}}
+
+	ve := validate(defs).(*ValidationError)
+	assertContains(t, ve.Errors, `item "bomb" combat_use damage: dice expression "2d"`)
+	assertContains(t, ve.Errors, `item "charm" combat_use has neither damage nor heal`)
+}
+
+func TestValidate_EnemyMorale(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["bandit"] = types.EntityDef{ID: "bandit", Kind: "enemy", Props: map[string]any{
+		"location": "hall", "hp": 6, "max_hp": 6, "attack": 2, "defense": 1,
+		"behavior":      []types.BehaviorEntry{{Action: "attack", Weight: 1}},
+		"morale":        -1,
+		"on_break":      "cry",
+		"can_negotiate": true,
+	}}
+
+	ve := validate(defs).(*ValidationError)
+	assertContains(t, ve.Errors, `enemy "bandit" stat "morale" must be a non-negative integer, got -1`)
+	assertContains(t, ve.Errors, `enemy "bandit" on_break cry is not valid (surrender, flee)`)
+	assertContains(t, ve.Warnings, `enemy "bandit" can_negotiate but has no topics to talk about`)
+}
+
+func TestValidate_Builtins(t *testing.T) {
+	defs := validDefs()
+	defs.Game.Builtins = map[string]bool{"take": false, "drop": false, "jump": false}
+	defs.GlobalRules = append(defs.GlobalRules,
+		types.RuleDef{ID: "grab", Scope: "global", When: types.MatchCriteria{Verb: "take"}},
+		types.RuleDef{ID: "after_drop", Scope: "global", When: types.MatchCriteria{Verb: "drop", Phase: "after"}},
+	)
+
+	ve := analyze(defs)
+	assertContains(t, ve.Warnings, `Game.builtins turns off "drop" but no rule handles it`)
+	assertContains(t, ve.Warnings, `Game.builtins: "jump" has no built-in behavior`)
+	for _, w := range ve.Warnings {
+		if strings.Contains(w, `"take"`) {
+			t.Errorf("take has a covering rule, got warning %q", w)
+		}
+	}
+}
+
+func TestValidate_Quantity(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["coin"] = types.EntityDef{ID: "coin", Kind: "item", Props: map[string]any{
+		"location": "hall", "quantity": -2,
+	}}
+	defs.GlobalRules = []types.RuleDef{{
+		ID: "overpay", Scope: "global", When: types.MatchCriteria{Verb: "pay"},
+		Effects: []types.Effect{{Type: "remove_item", Params: map[string]any{"item": "coin", "amount": 0}}},
+	}}
+
+	ve := validate(defs).(*ValidationError)
+	assertContains(t, ve.Errors, `entity "coin" quantity must be a non-negative integer, got -2`)
+	assertContains(t, ve.Errors, "amount")
+}
+
+func TestValidate_EmptyDescriptionList(t *testing.T) {
+	defs := validDefs()
+	defs.Entities["mural"] = types.EntityDef{ID: "mural", Kind: "entity", Props: map[string]any{
+		"location": "hall", "descriptions": []string{},
+	}}
+
+	ve := validate(defs).(*ValidationError)
+	assertContains(t, ve.Errors, `entity "mural" description list is empty`)
+}
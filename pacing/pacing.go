@@ -0,0 +1,183 @@
+// Package pacing replays playtest logs against a game and reports how each
+// session was paced: where the turns went, when items were first picked up,
+// how often commands failed as the session went on, and whether it reached
+// an ending.
+package pacing
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/state"
+)
+
+// DefaultWindow is the number of turns per failure-rate window.
+const DefaultWindow = 10
+
+// Options configures a replay.
+type Options struct {
+	// Seed is the RNG seed the engine starts from.
+	Seed int64
+	// Window is the number of turns per failure-rate window; 0 means
+	// DefaultWindow.
+	Window int
+}
+
+// Window is a run of consecutive turns and how many of its commands failed.
+type Window struct {
+	Start    int // first turn, 1-based
+	End      int // last turn
+	Failures int
+}
+
+// Rate returns the fraction of the window's commands that failed.
+func (w Window) Rate() float64 {
+	return float64(w.Failures) / float64(w.End-w.Start+1)
+}
+
+// ItemTurn records the turn an item first entered the inventory.
+type ItemTurn struct {
+	Item string
+	Turn int
+}
+
+// Report is the pacing of one playtest session.
+type Report struct {
+	Log        string         // log file the session was replayed from
+	Turns      int            // commands played
+	RoomTurns  map[string]int // turns spent in each room, by the room the command was typed in
+	FirstTaken []ItemTurn     // items in the order they were first picked up
+	Failures   int            // commands that weren't understood or that nothing handled
+	Windows    []Window       // failures over time
+	Ended      bool           // the session reached an ending
+}
+
+// Analyze replays the commands in log against a fresh engine and returns the
+// session's pacing. Blank lines and lines starting with '#' are skipped, as
+// are meta-commands ("/save", ...) and anything typed after an ending.
+func Analyze(defs *state.Defs, log io.Reader, opts Options) (*Report, error) {
+	window := opts.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	eng := engine.New(defs, engine.WithSeed(opts.Seed))
+
+	r := &Report{RoomTurns: map[string]int{}}
+	taken := map[string]bool{}
+	for _, id := range eng.State.Player.Inventory {
+		taken[id] = true // starting inventory isn't a pickup
+	}
+
+	scanner := bufio.NewScanner(log)
+	for scanner.Scan() {
+		input := strings.TrimSpace(scanner.Text())
+		if input == "" || strings.HasPrefix(input, "#") || strings.HasPrefix(input, "/") {
+			continue
+		}
+		if r.Ended {
+			break
+		}
+
+		// Commands chained in a line count as turns of their own.
+		for _, command := range eng.Commands(input) {
+			room := eng.State.Player.Location
+			result := eng.Step(command)
+			r.Turns++
+			r.RoomTurns[room]++
+
+			if (r.Turns-1)%window == 0 {
+				r.Windows = append(r.Windows, Window{Start: r.Turns})
+			}
+			w := &r.Windows[len(r.Windows)-1]
+			w.End = r.Turns
+			if result.Failed {
+				r.Failures++
+				w.Failures++
+			}
+
+			for _, id := range eng.State.Player.Inventory {
+				if !taken[id] {
+					taken[id] = true
+					r.FirstTaken = append(r.FirstTaken, ItemTurn{Item: id, Turn: r.Turns})
+				}
+			}
+			r.Ended = state.GetFlag(eng.State, "game_over")
+			if eng.EndsChain(result) {
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// AnalyzeFiles replays each log file and returns their reports in order.
+func AnalyzeFiles(defs *state.Defs, paths []string, opts Options) ([]*Report, error) {
+	var reports []*Report
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		r, err := Analyze(defs, f, opts)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		r.Log = filepath.Base(path)
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// String renders the report as a short human-readable summary.
+func (r *Report) String() string {
+	var b strings.Builder
+	status := "reached an ending"
+	if !r.Ended {
+		status = "NO ENDING"
+	}
+	fmt.Fprintf(&b, "%s: %d turns, %d failed, %s\n", r.Log, r.Turns, r.Failures, status)
+
+	rooms := make([]string, 0, len(r.RoomTurns))
+	for id := range r.RoomTurns {
+		rooms = append(rooms, id)
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		if r.RoomTurns[rooms[i]] != r.RoomTurns[rooms[j]] {
+			return r.RoomTurns[rooms[i]] > r.RoomTurns[rooms[j]]
+		}
+		return rooms[i] < rooms[j]
+	})
+	var parts []string
+	for _, id := range rooms {
+		parts = append(parts, fmt.Sprintf("%s %d", id, r.RoomTurns[id]))
+	}
+	fmt.Fprintf(&b, "  rooms: %s\n", strings.Join(parts, ", "))
+
+	if len(r.FirstTaken) > 0 {
+		parts = parts[:0]
+		for _, it := range r.FirstTaken {
+			parts = append(parts, fmt.Sprintf("%s @%d", it.Item, it.Turn))
+		}
+		fmt.Fprintf(&b, "  first taken: %s\n", strings.Join(parts, ", "))
+	}
+
+	if len(r.Windows) > 0 {
+		parts = parts[:0]
+		for _, w := range r.Windows {
+			parts = append(parts, fmt.Sprintf("%d-%d %.0f%%", w.Start, w.End, w.Rate()*100))
+		}
+		fmt.Fprintf(&b, "  failure rate: %s\n", strings.Join(parts, ", "))
+	}
+	return b.String()
+}
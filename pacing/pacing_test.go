@@ -0,0 +1,94 @@
+package pacing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// testDefs returns a two-room game with a lamp to pick up and an ending
+// reached by pulling the lever in the garden.
+func testDefs() *state.Defs {
+	return &state.Defs{
+		Game: types.GameDef{Title: "Test", Start: "hall"},
+		Rooms: map[string]types.RoomDef{
+			"hall":   {ID: "hall", Description: "A grand hall.", Exits: map[string]string{"north": "garden"}},
+			"garden": {ID: "garden", Description: "A peaceful garden.", Exits: map[string]string{"south": "hall"}},
+		},
+		Entities: map[string]types.EntityDef{
+			"lamp": {ID: "lamp", Kind: "item", Props: map[string]any{
+				"name": "lamp", "location": "hall", "takeable": true,
+			}},
+			"lever": {ID: "lever", Kind: "entity", Props: map[string]any{
+				"name": "lever", "location": "garden",
+			}},
+		},
+		GlobalRules: []types.RuleDef{{
+			ID: "pull_lever", Scope: "global",
+			When: types.MatchCriteria{Verb: "pull", Object: "lever"},
+			Effects: []types.Effect{
+				{Type: "set_flag", Params: map[string]any{"flag": "game_over", "value": true}},
+			},
+		}},
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	log := "# playtest\nlook\ntake lamp\ndance\n/save slot\nnorth\nxyzzy\npull lever\nlook\n"
+	r, err := Analyze(testDefs(), strings.NewReader(log), Options{Window: 3})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	if r.Turns != 6 {
+		t.Errorf("Turns = %d, want 6", r.Turns)
+	}
+	if r.RoomTurns["hall"] != 4 || r.RoomTurns["garden"] != 2 {
+		t.Errorf("RoomTurns = %v, want hall 4, garden 2", r.RoomTurns)
+	}
+	if len(r.FirstTaken) != 1 || r.FirstTaken[0] != (ItemTurn{Item: "lamp", Turn: 2}) {
+		t.Errorf("FirstTaken = %v, want [lamp @2]", r.FirstTaken)
+	}
+	if r.Failures != 2 {
+		t.Errorf("Failures = %d, want 2", r.Failures)
+	}
+	want := []Window{{Start: 1, End: 3, Failures: 1}, {Start: 4, End: 6, Failures: 1}}
+	if len(r.Windows) != len(want) {
+		t.Fatalf("Windows = %v, want %v", r.Windows, want)
+	}
+	for i := range want {
+		if r.Windows[i] != want[i] {
+			t.Errorf("Windows[%d] = %v, want %v", i, r.Windows[i], want[i])
+		}
+	}
+	if !r.Ended {
+		t.Error("expected session to reach an ending")
+	}
+}
+
+func TestAnalyze_ChainedCommands(t *testing.T) {
+	r, err := Analyze(testDefs(), strings.NewReader("take lamp and north, then dance, then south\n"), Options{})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	// The chain stops at the failed dance.
+	if r.Turns != 3 || r.RoomTurns["hall"] != 2 || r.RoomTurns["garden"] != 1 || r.Failures != 1 {
+		t.Errorf("Turns = %d, RoomTurns = %v, Failures = %d; want 3, hall 2 garden 1, 1", r.Turns, r.RoomTurns, r.Failures)
+	}
+}
+
+func TestAnalyze_NoEnding(t *testing.T) {
+	r, err := Analyze(testDefs(), strings.NewReader("north\nsouth\n"), Options{})
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if r.Ended {
+		t.Error("expected session without an ending")
+	}
+	r.Log = "short.txt"
+	if s := r.String(); !strings.Contains(s, "short.txt: 2 turns, 0 failed, NO ENDING") {
+		t.Errorf("String() = %q", s)
+	}
+}
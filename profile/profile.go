@@ -0,0 +1,70 @@
+// Package profile stores what a player carries between playthroughs of a
+// game — currently the achievements they have unlocked — in a small JSON
+// file per game, separate from save files.
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/nathoo/questcore/config"
+	"github.com/nathoo/questcore/types"
+)
+
+// Profile is the per-user, per-game profile file.
+type Profile struct {
+	Game         string          `json:"game"`
+	Achievements map[string]bool `json:"achievements"`
+}
+
+// DefaultPath returns where the profile for the game titled game is kept:
+// profiles/<game>.json in config.DataDir, named as config.FileName names it.
+func DefaultPath(game string) string {
+	return filepath.Join(config.DataDir(), "profiles", config.FileName(game)+".json")
+}
+
+// Load reads the profile at path. A missing file is an empty profile, since
+// every player starts without one.
+func Load(path string) (*Profile, error) {
+	p := &Profile{Achievements: map[string]bool{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, err
+	}
+	if p.Achievements == nil {
+		p.Achievements = map[string]bool{}
+	}
+	return p, nil
+}
+
+// Save writes the profile to path, creating its directory if needed.
+func (p *Profile) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// Unlocked reports whether evts include a newly unlocked achievement, so
+// front ends know when the profile needs saving.
+func Unlocked(evts []types.Event) bool {
+	for _, evt := range evts {
+		if evt.Type == "achievement_unlocked" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package profile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad_MissingFileIsEmpty(t *testing.T) {
+	p, err := Load(filepath.Join(t.TempDir(), "none.json"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if p.Achievements == nil || len(p.Achievements) != 0 {
+		t.Errorf("Achievements = %v, want empty map", p.Achievements)
+	}
+}
+
+func TestSaveLoad_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles", "crown.json")
+	p := &Profile{Game: "The Lost Crown", Achievements: map[string]bool{"pacifist": true}}
+	if err := p.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.Game != "The Lost Crown" || !got.Achievements["pacifist"] {
+		t.Errorf("loaded %+v", got)
+	}
+}
+
+func TestLoad_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("expected an error for a corrupt profile")
+	}
+}
@@ -0,0 +1,213 @@
+package schema
+
+// descriptions documents the definition types, keyed by "Type" or
+// "Type.Field". Every type and field reachable from state.Defs needs an
+// entry; the tests check that none are missing.
+var descriptions = map[string]string{
+	"Defs":              "A compiled game: everything the Lua content defined.",
+	"Defs.Game":         "Game metadata from the Game{} block.",
+	"Defs.Rooms":        "Rooms by ID.",
+	"Defs.Entities":     "Items, NPCs, enemies and other entities by ID.",
+	"Defs.GlobalRules":  "Rules declared outside any room or entity, ending with the built-in classic responses.",
+	"Defs.Handlers":     "Event handlers declared with On().",
+	"Defs.Computed":     "Derived values by ID.",
+	"Defs.Answers":      "Free-text questions, such as riddles, by ID.",
+	"Defs.Endings":      "Endings reached with EndGame(), by ID.",
+	"Defs.Achievements": "Achievements unlocked with UnlockAchievement(), by ID.",
+	"Defs.Facts":        "Facts learned with LearnFact(), by ID.",
+	"Defs.Messages":     "Overrides of the engine's built-in text from Messages{}, by key.",
+	"Defs.MetaCommands": "The game's own slash-commands from MetaCommand(), by name.",
+
+	"GameDef":                      "Game metadata.",
+	"GameDef.Title":                "Display name of the game.",
+	"GameDef.Author":               "Author name.",
+	"GameDef.Version":              "Version string.",
+	"GameDef.Start":                "ID of the room the player starts in.",
+	"GameDef.Intro":                "Text shown when the game begins.",
+	"GameDef.Locale":               "Locale whose locales/<locale>.lua file is loaded after the game's files; empty for none.",
+	"GameDef.PlayerStats":          "Initial player stats, such as hp, attack, max_weight.",
+	"GameDef.Levels":               "Levels the player can reach by earning XP, from level 2 up; null for no leveling.",
+	"GameDef.MaxScore":             "Most points the score counter can reach; 0 if not scored out of a maximum.",
+	"GameDef.Clock":                "The in-game clock; null for none.",
+	"GameDef.OnDeath":              "What happens when the player dies: \"prompt\" (or empty), \"respawn\" or \"restart\".",
+	"GameDef.RespawnRoom":          "Room the player respawns in; empty for the start room.",
+	"GameDef.DeathPenalty":         "What a respawn costs.",
+	"GameDef.InventoryCategories":  "Display order for item categories.",
+	"GameDef.Amusing":              "Entries offered after the game ends.",
+	"GameDef.IdleNudge":            "Hints shown to an idle player; null for none.",
+	"GameDef.Objectives":           "The player's goals; the first whose conditions hold is the current one.",
+	"GameDef.Theme":                "Terminal colors by name, plus the \"preset\" they start from; the player's own theme overrides it.",
+	"GameDef.NoClassicResponses":   "True if the built-in replies to \"xyzzy\", \"pray\", ... are turned off.",
+	"GameDef.ClassicResponses":     "Overrides of the built-in replies by entry; an empty string silences one.",
+	"GameDef.Builtins":             "Verbs whose built-in behavior is on (true) or turned off (false), such as take; absent verbs are on.",
+	"GameDef.UnknownVerbResponses": "Replies, in turn, to verbs neither the parser nor any rule knows; the engine has a default set.",
+	"GameDef.AmbienceCooldown":     "Fewest turns between two lines of room ambience; 0 means no limit.",
+	"GameDef.Noise":                "How much noise verbs make, by verb, overriding the engine's levels; 0 is silent.",
+	"GameDef.CommandLogLimit":      "Most recent commands kept in memory; 0 means the default.",
+	"GameDef.CompactSaves":         "True if saves leave out the command log.",
+	"GameDef.Players":              "Player characters the game switches between, by ID; null for a single player.",
+	"GameDef.StartPlayer":          "ID of the player character the game starts as.",
+
+	"PlayerDef":           "A player character of a game with several.",
+	"PlayerDef.Start":     "ID of the room the character starts in; empty for the game's start room.",
+	"PlayerDef.Stats":     "The character's starting stats; null for the game's player_stats.",
+	"PlayerDef.Inventory": "IDs of the items the character starts with.",
+
+	"LevelDef":       "A level the player can reach.",
+	"LevelDef.XP":    "Total XP needed to reach the level.",
+	"LevelDef.Stats": "Added to the player's stats on reaching the level.",
+
+	"ClockDef":                "The in-game clock. Times of day are minutes after midnight.",
+	"ClockDef.Start":          "Time of day the game begins.",
+	"ClockDef.MinutesPerTurn": "Minutes each turn advances the clock.",
+	"ClockDef.Dawn":           "When day begins.",
+	"ClockDef.Dusk":           "When night begins.",
+
+	"DeathPenaltyDef":           "What the player loses on respawning.",
+	"DeathPenaltyDef.Gold":      "Taken from the gold counter.",
+	"DeathPenaltyDef.XP":        "Taken from the xp stat, never costing a level.",
+	"DeathPenaltyDef.DropItems": "True if the inventory is left where the player died.",
+
+	"IdleNudgeDef":         "Idle hints for interactive front ends.",
+	"IdleNudgeDef.Minutes": "Idle time before a nudge.",
+	"IdleNudgeDef.Nudges":  "Candidate nudges; the first whose conditions hold is shown.",
+
+	"NudgeDef":            "One contextual idle nudge.",
+	"NudgeDef.Text":       "Text shown.",
+	"NudgeDef.Conditions": "Conditions that must all hold.",
+
+	"ObjectiveDef":            "A goal front ends may show the player.",
+	"ObjectiveDef.Text":       "Text shown.",
+	"ObjectiveDef.Conditions": "Conditions under which this is the current objective.",
+
+	"AmusingDef":            "An entry in the post-ending \"amusing things\" list.",
+	"AmusingDef.Text":       "Text shown.",
+	"AmusingDef.Conditions": "Conditions on the final state that must all hold.",
+
+	"RoomDef":                  "A room.",
+	"RoomDef.ID":               "Room ID.",
+	"RoomDef.Description":      "Text shown on entering or looking.",
+	"RoomDef.FirstDescription": "Shown instead of Description on the first visit.",
+	"RoomDef.NightDescription": "Shown instead of Description at night, if the game has a clock.",
+	"RoomDef.Image":            "Illustration for front ends that show pictures, a path relative to the game directory.",
+	"RoomDef.Exits":            "Target room IDs by direction.",
+	"RoomDef.ExitGuards":       "What going through an exit requires, by direction.",
+	"RoomDef.Rules":            "Rules scoped to this room.",
+	"RoomDef.Fallbacks":        "Custom failure text by verb.",
+	"RoomDef.Capacity":         "Most NPCs and enemies the room holds; 0 means unlimited.",
+	"RoomDef.Overflow":         "What happens when the room is full: \"reject\" (or empty) or \"adjacent\".",
+	"RoomDef.Ambience":         "Lines of atmosphere, one of which may show each turn the player spends in the room.",
+
+	"ExitGuardDef":                    "What an exit requires of the player to be taken.",
+	"ExitGuardDef.Requires":           "Conditions that must all hold to go through the exit.",
+	"ExitGuardDef.BlockedText":        "Shown when the player tries the exit while blocked; empty for the built-in message.",
+	"ExitGuardDef.VisibleWhenBlocked": "Whether the exit is listed while blocked.",
+	"ExitGuardDef.Vehicle":            "Enterable entity the player must be in to take the exit; empty for none.",
+	"ExitGuardDef.Door":               "Entity with a lock that bars the exit while locked; empty for none.",
+
+	"AmbienceDef":            "A line of room atmosphere.",
+	"AmbienceDef.Chance":     "Percent chance per turn, from 1 to 100.",
+	"AmbienceDef.Text":       "Text shown.",
+	"AmbienceDef.Conditions": "Conditions that must all hold for the line to be rolled.",
+
+	"EntityDef":           "An item, NPC, enemy or other entity.",
+	"EntityDef.ID":        "Entity ID.",
+	"EntityDef.Kind":      "\"item\", \"npc\", \"enemy\" or \"entity\".",
+	"EntityDef.Props":     "Base properties, such as name, location, takeable.",
+	"EntityDef.Rules":     "Rules scoped to this entity.",
+	"EntityDef.Topics":    "NPC dialogue topics by key; null for other kinds.",
+	"EntityDef.Reactions": "Handlers for events that happen in the entity's room.",
+	"EntityDef.Inventory": "Items the entity starts out carrying; each item's location becomes the entity.",
+	"EntityDef.OnReceive": "An NPC's responses to being given an item, by item ID; it takes only items with a response whose conditions hold.",
+	"EntityDef.OnShow":    "An NPC's responses to being shown an item, by item ID.",
+
+	"ItemResponseDef":            "An NPC's response to being given or shown an item.",
+	"ItemResponseDef.Text":       "What the NPC says; empty for the built-in message.",
+	"ItemResponseDef.Conditions": "Conditions for the response; if they don't hold, the NPC declines.",
+	"ItemResponseDef.Effects":    "Effects applied with the response.",
+
+	"TopicDef":          "One NPC dialogue topic.",
+	"TopicDef.Text":     "What the NPC says.",
+	"TopicDef.Label":    "Name shown in topic lists and accepted in place of the key; empty for the key.",
+	"TopicDef.Once":     "True if the topic is hidden once the player has heard it.",
+	"TopicDef.Requires": "Conditions for the topic to be available.",
+	"TopicDef.Effects":  "Effects applied when the topic is discussed.",
+
+	"RuleDef":             "A rule mapping a player command to effects.",
+	"RuleDef.ID":          "Unique rule ID.",
+	"RuleDef.Scope":       "\"room:<id>\", \"entity:<id>\" or \"global\".",
+	"RuleDef.When":        "The commands the rule matches.",
+	"RuleDef.Conditions":  "Conditions that must all hold.",
+	"RuleDef.Effects":     "Effects applied when the rule fires.",
+	"RuleDef.Priority":    "Tie-breaker between equally specific rules; higher wins.",
+	"RuleDef.SourceOrder": "Declaration order, the final tie-breaker.",
+	"RuleDef.Once":        "True if the rule stops matching after it first fires.",
+
+	"MatchCriteria":            "What a rule matches against.",
+	"MatchCriteria.Verb":       "Canonical verb.",
+	"MatchCriteria.Object":     "Specific object entity ID.",
+	"MatchCriteria.Target":     "Specific target entity ID.",
+	"MatchCriteria.ObjectKind": "Object entity kind, such as \"item\".",
+	"MatchCriteria.TargetProp": "Properties the target must have.",
+	"MatchCriteria.ObjectProp": "Properties the object must have.",
+	"MatchCriteria.Phase":      "\"\" to replace the built-in behavior, \"before\" or \"after\".",
+	"MatchCriteria.Succeeded":  "After phase: match only if the command did (true) or did not (false) succeed; null for either.",
+
+	"Condition":        "A predicate on game state.",
+	"Condition.Type":   "Condition type, such as \"has_item\" or \"flag_set\".",
+	"Condition.Params": "Condition-specific parameters.",
+	"Condition.Negate": "True if the condition is wrapped in Not().",
+	"Condition.Inner":  "For Not(): the negated condition.",
+
+	"Effect":        "A single atomic state change.",
+	"Effect.Type":   "Effect type, such as \"say\" or \"give_item\".",
+	"Effect.Params": "Effect-specific parameters.",
+
+	"EventHandler":            "Effects triggered by an event rather than a command.",
+	"EventHandler.ID":         "Explicit ID, or a generated one.",
+	"EventHandler.EventType":  "Event type; a trailing \"*\" matches any suffix.",
+	"EventHandler.Priority":   "Higher runs first; ties keep declaration order.",
+	"EventHandler.Once":       "True if the handler fires at most once per game.",
+	"EventHandler.Conditions": "Conditions that must all hold.",
+	"EventHandler.Effects":    "Effects applied when the handler fires.",
+
+	"ComputedDef":         "A value derived from state.",
+	"ComputedDef.ID":      "Computed value ID.",
+	"ComputedDef.Uses":    "Declared inputs, such as \"flags.door_open\".",
+	"ComputedDef.Cases":   "Cases in order; the first whose conditions hold supplies the value.",
+	"ComputedDef.Default": "Value when no case holds.",
+
+	"ComputedCase":       "One branch of a computed value.",
+	"ComputedCase.When":  "Conditions that must all hold.",
+	"ComputedCase.Value": "The value.",
+
+	"EndingDef":       "One of the ways the game can conclude.",
+	"EndingDef.ID":    "Ending ID.",
+	"EndingDef.Title": "Title shown with the epilogue; defaults to the ID.",
+	"EndingDef.Text":  "Epilogue text.",
+
+	"AchievementDef":             "An achievement, kept in the player's profile once unlocked.",
+	"AchievementDef.ID":          "Achievement ID.",
+	"AchievementDef.Name":        "Display name; defaults to the ID.",
+	"AchievementDef.Description": "What the player did, or must do, to unlock it.",
+	"AchievementDef.Hidden":      "True if the achievement is only listed once unlocked.",
+	"AchievementDef.Order":       "Declaration order, for listing.",
+
+	"FactDef":      "Something the player can learn, such as a password.",
+	"FactDef.ID":   "Fact ID.",
+	"FactDef.Text": "How the notes command lists it; empty to keep it unlisted.",
+
+	"MetaCommandDef":         "A slash-command the game adds to the front ends' own.",
+	"MetaCommandDef.Name":    "The command, including the slash, such as \"/spells\".",
+	"MetaCommandDef.Help":    "Text listed by /help; empty for none.",
+	"MetaCommandDef.Verb":    "Game command run in its place, with the meta-command's arguments; empty to run Effects.",
+	"MetaCommandDef.Effects": "Effects applied outside the turn.",
+
+	"AnswerDef":          "A free-text question posed by the ask effect.",
+	"AnswerDef.ID":       "Question ID.",
+	"AnswerDef.Accept":   "Accepted answers, compared ignoring case, punctuation and articles.",
+	"AnswerDef.Attempts": "Wrong answers allowed before Failure runs.",
+	"AnswerDef.Retry":    "Shown after a wrong answer while attempts remain.",
+	"AnswerDef.Success":  "Effects applied on a correct answer.",
+	"AnswerDef.Failure":  "Effects applied once the attempts run out.",
+}
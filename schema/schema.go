@@ -0,0 +1,139 @@
+// Package schema exports a game's compiled definitions as canonical JSON,
+// together with a JSON Schema describing every definition type, so external
+// editors, visualizers and CI validators can read QuestCore content without
+// embedding the Go loader.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/nathoo/questcore/engine/state"
+)
+
+// Version is the export format version. It changes whenever a definition
+// type gains, loses or renames a field.
+const Version = 10
+
+// Export is the document written by "questcore schema".
+type Export struct {
+	Version int            // export format version
+	Schema  map[string]any // JSON Schema for Defs
+	Defs    *state.Defs    // the compiled game; nil when only the schema is exported
+}
+
+// New returns the export for defs, or for the schema alone if defs is nil.
+func New(defs *state.Defs) *Export {
+	return &Export{Version: Version, Schema: Schema(), Defs: defs}
+}
+
+// Marshal renders the export as canonical JSON: struct fields in
+// declaration order, map keys sorted, two-space indentation and a trailing
+// newline. The same game always exports to the same bytes.
+func (e *Export) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Schema returns a JSON Schema (draft 2020-12) for state.Defs. Every struct
+// reachable from Defs becomes an entry under "$defs", documented from the
+// descriptions table.
+func Schema() map[string]any {
+	g := &generator{defs: map[string]any{}}
+	root := g.schemaFor(reflect.TypeOf(state.Defs{}))
+	return map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         fmt.Sprintf("urn:questcore:defs:v%d", Version),
+		"title":       "QuestCore game definitions",
+		"description": descriptions["Defs"],
+		"$ref":        root["$ref"],
+		"$defs":       g.defs,
+	}
+}
+
+// generator builds schemas by reflection, collecting named structs in defs.
+type generator struct {
+	defs map[string]any
+}
+
+// schemaFor returns the schema for a Go type. Structs are emitted once into
+// g.defs and referenced by name, which also handles recursive types such as
+// Condition.Inner.
+func (g *generator) schemaFor(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schemaFor(t.Elem()))
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = nil // reserve the name before recursing
+			g.defs[name] = g.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/$defs/" + name}
+	case reflect.Slice:
+		return nullable(map[string]any{"type": "array", "items": g.schemaFor(t.Elem())})
+	case reflect.Map:
+		return nullable(map[string]any{"type": "object", "additionalProperties": g.schemaFor(t.Elem())})
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{} // any: Lua values may be any JSON value
+}
+
+// structSchema describes the exported fields of a struct. Every field is
+// required, since the export always writes them all.
+func (g *generator) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		s := g.schemaFor(f.Type)
+		if desc, ok := descriptions[t.Name()+"."+f.Name]; ok {
+			if _, isRef := s["$ref"]; isRef {
+				// Siblings of $ref are allowed in 2020-12, but keep the
+				// reference itself untouched for older tools.
+				s = map[string]any{"allOf": []any{s}, "description": desc}
+			} else {
+				s["description"] = desc
+			}
+		}
+		props[f.Name] = s
+		required = append(required, f.Name)
+	}
+	s := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+	if desc, ok := descriptions[t.Name()]; ok {
+		s["description"] = desc
+	}
+	return s
+}
+
+// nullable allows null in addition to s, since Go writes nil slices, maps
+// and pointers as null.
+func nullable(s map[string]any) map[string]any {
+	if ref, ok := s["$ref"]; ok {
+		return map[string]any{"anyOf": []any{map[string]any{"$ref": ref}, map[string]any{"type": "null"}}}
+	}
+	if _, ok := s["type"]; !ok {
+		return s // already accepts null
+	}
+	s["type"] = []any{s["type"], "null"}
+	return s
+}
@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+func testDefs() *state.Defs {
+	return &state.Defs{
+		Game: types.GameDef{Title: "Test", Start: "hall"},
+		Rooms: map[string]types.RoomDef{
+			"hall":   {ID: "hall", Description: "A grand hall.", Exits: map[string]string{"north": "garden"}},
+			"garden": {ID: "garden", Description: "A garden.", Exits: map[string]string{"south": "hall"}},
+		},
+		Entities: map[string]types.EntityDef{
+			"key": {ID: "key", Kind: "item", Props: map[string]any{"name": "key", "location": "hall", "takeable": true}},
+		},
+		GlobalRules: []types.RuleDef{{
+			ID: "open_gate", Scope: "global",
+			When: types.MatchCriteria{Verb: "open", Object: "gate"},
+			Conditions: []types.Condition{{
+				Type: "not", Negate: true,
+				Inner: &types.Condition{Type: "flag_set", Params: map[string]any{"flag": "gate_open"}},
+			}},
+			Effects: []types.Effect{{Type: "set_flag", Params: map[string]any{"flag": "gate_open", "value": true}}},
+		}},
+	}
+}
+
+func TestSchema_EveryTypeAndFieldDocumented(t *testing.T) {
+	defs := Schema()["$defs"].(map[string]any)
+	for _, name := range []string{"Defs", "GameDef", "RoomDef", "EntityDef", "RuleDef", "Condition", "Effect", "EventHandler", "AnswerDef"} {
+		if defs[name] == nil {
+			t.Errorf("$defs missing %s", name)
+		}
+	}
+	for name, d := range defs {
+		def := d.(map[string]any)
+		if def["description"] == nil {
+			t.Errorf("%s has no description", name)
+		}
+		for field, p := range def["properties"].(map[string]any) {
+			if p.(map[string]any)["description"] == nil {
+				t.Errorf("%s.%s has no description", name, field)
+			}
+		}
+	}
+}
+
+func TestSchema_RecursiveAndNullable(t *testing.T) {
+	defs := Schema()["$defs"].(map[string]any)
+	cond := defs["Condition"].(map[string]any)["properties"].(map[string]any)
+
+	inner := cond["Inner"].(map[string]any)
+	ref := inner["anyOf"].([]any)[0].(map[string]any)["$ref"]
+	if ref != "#/$defs/Condition" {
+		t.Errorf("Condition.Inner ref = %v, want #/$defs/Condition", ref)
+	}
+	params := cond["Params"].(map[string]any)
+	if !reflect.DeepEqual(params["type"], []any{"object", "null"}) {
+		t.Errorf("Condition.Params type = %v, want [object null]", params["type"])
+	}
+}
+
+func TestExport_CanonicalAndRoundTrips(t *testing.T) {
+	first, err := New(testDefs()).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	second, err := New(testDefs()).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("two exports of the same game differ")
+	}
+	if !bytes.HasSuffix(first, []byte("}\n")) {
+		t.Error("export should end with a newline")
+	}
+
+	var back struct {
+		Version int
+		Defs    state.Defs
+	}
+	if err := json.Unmarshal(first, &back); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if back.Version != Version {
+		t.Errorf("Version = %d, want %d", back.Version, Version)
+	}
+	if back.Defs.Rooms["hall"].Exits["north"] != "garden" {
+		t.Errorf("hall exits = %v", back.Defs.Rooms["hall"].Exits)
+	}
+	if inner := back.Defs.GlobalRules[0].Conditions[0].Inner; inner == nil || inner.Type != "flag_set" {
+		t.Errorf("inner condition = %+v, want flag_set", inner)
+	}
+}
+
+func TestExport_SchemaOnly(t *testing.T) {
+	data, err := New(nil).Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !bytes.Contains(data, []byte(`"Defs": null`)) {
+		t.Error("schema-only export should have null Defs")
+	}
+}
@@ -0,0 +1,166 @@
+// Package simulate runs automated combats against a game's enemies to help
+// balance them: the player fights the enemy over and over, attacking every
+// round, each time with a different seed, and the report tells how often
+// they won, how long the fights lasted, and how hard each side hit.
+package simulate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/effects"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// DefaultRuns is the number of combats simulated when Options.Runs is 0.
+const DefaultRuns = 1000
+
+// DefaultMaxRounds is the number of rounds after which a combat is called a
+// draw when Options.MaxRounds is 0.
+const DefaultMaxRounds = 100
+
+// Options configures a simulation.
+type Options struct {
+	// Runs is the number of combats; 0 means DefaultRuns.
+	Runs int
+	// Seed is the RNG seed of the first combat; each later one uses the
+	// next seed.
+	Seed int64
+	// MaxRounds is how many rounds a combat may last before it is called
+	// a draw; 0 means DefaultMaxRounds.
+	MaxRounds int
+	// Stats override the player's starting stats, to try out another
+	// build ("attack" = 6, ...).
+	Stats map[string]int
+}
+
+// Report is the outcome of the combats against one enemy.
+type Report struct {
+	Enemy  string // entity ID of the enemy fought
+	Runs   int    // combats simulated
+	Wins   int    // combats the player won
+	Losses int    // combats the player lost
+	Draws  int    // combats neither side won, within MaxRounds
+	Rounds int    // rounds fought, over all combats
+
+	// PlayerHits and EnemyHits count each side's hits by the damage they
+	// did.
+	PlayerHits map[int]int
+	EnemyHits  map[int]int
+}
+
+// WinRate returns the fraction of combats the player won.
+func (r *Report) WinRate() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	return float64(r.Wins) / float64(r.Runs)
+}
+
+// AvgRounds returns the average number of rounds a combat lasted.
+func (r *Report) AvgRounds() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	return float64(r.Rounds) / float64(r.Runs)
+}
+
+// Run simulates combats between the player and enemy, each in a fresh game
+// with the player moved to the enemy's room.
+func Run(defs *state.Defs, enemy string, opts Options) (*Report, error) {
+	def, ok := defs.Entities[enemy]
+	if !ok {
+		return nil, fmt.Errorf("unknown entity %q", enemy)
+	}
+	if def.Kind != "enemy" {
+		return nil, fmt.Errorf("%q is not an enemy (kind %q)", enemy, def.Kind)
+	}
+	runs := opts.Runs
+	if runs <= 0 {
+		runs = DefaultRuns
+	}
+	maxRounds := opts.MaxRounds
+	if maxRounds <= 0 {
+		maxRounds = DefaultMaxRounds
+	}
+
+	r := &Report{Enemy: enemy, Runs: runs, PlayerHits: map[int]int{}, EnemyHits: map[int]int{}}
+	for i := 0; i < runs; i++ {
+		fight(defs, enemy, opts.Seed+int64(i), maxRounds, opts.Stats, r)
+	}
+	return r, nil
+}
+
+// fight runs one combat with seed and adds its outcome to r.
+func fight(defs *state.Defs, enemy string, seed int64, maxRounds int, stats map[string]int, r *Report) {
+	eng := engine.New(defs, engine.WithSeed(seed))
+	for stat, value := range stats {
+		state.SetStat(eng.State, "player", stat, value)
+	}
+	room := state.EntityLocation(eng.State, defs, enemy)
+	if _, ok := defs.Rooms[room]; ok {
+		eng.State.Player.Location = room
+	}
+	start := types.Effect{Type: "start_combat", Params: map[string]any{"enemy": enemy}}
+	effects.Apply(eng.State, defs, []types.Effect{start}, effects.Context{Actor: "player"})
+
+	for range maxRounds {
+		result := eng.Step("attack")
+		r.Rounds++
+		for _, evt := range result.Events {
+			switch evt.Type {
+			case "entity_damaged":
+				amount, _ := evt.Data["amount"].(int)
+				if evt.Data["target"] == "player" {
+					r.EnemyHits[amount]++
+				} else {
+					r.PlayerHits[amount]++
+				}
+			case "enemy_defeated":
+				r.Wins++
+				return
+			case "player_defeated":
+				r.Losses++
+				return
+			}
+		}
+		if !state.InCombat(eng.State) {
+			// Ended some other way, by a rule say; neither side won.
+			break
+		}
+	}
+	r.Draws++
+}
+
+// String renders the report as a short human-readable summary.
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d combats, won %.1f%% (%d won, %d lost, %d drawn), %.1f rounds on average\n",
+		r.Enemy, r.Runs, r.WinRate()*100, r.Wins, r.Losses, r.Draws, r.AvgRounds())
+	fmt.Fprintf(&b, "  player hits: %s\n", histogram(r.PlayerHits))
+	fmt.Fprintf(&b, "  enemy hits: %s\n", histogram(r.EnemyHits))
+	return b.String()
+}
+
+// histogram renders hits by damage as "2×14, 3×20, ..." with their average.
+func histogram(hits map[int]int) string {
+	if len(hits) == 0 {
+		return "none"
+	}
+	amounts := make([]int, 0, len(hits))
+	total, count := 0, 0
+	for amount, n := range hits {
+		amounts = append(amounts, amount)
+		total += amount * n
+		count += n
+	}
+	sort.Ints(amounts)
+	parts := make([]string, len(amounts))
+	for i, amount := range amounts {
+		parts[i] = fmt.Sprintf("%d×%d", amount, hits[amount])
+	}
+	return fmt.Sprintf("%s (average %.1f)", strings.Join(parts, ", "), float64(total)/float64(count))
+}
@@ -0,0 +1,96 @@
+package simulate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// testDefs returns a game with a rat the player should always beat and a
+// troll that should always beat the player.
+func testDefs() *state.Defs {
+	return &state.Defs{
+		Game: types.GameDef{
+			Title:       "Test",
+			Start:       "hall",
+			PlayerStats: map[string]int{"hp": 20, "max_hp": 20, "attack": 5, "defense": 2},
+		},
+		Rooms: map[string]types.RoomDef{
+			"hall": {ID: "hall", Description: "A grand hall.", Exits: map[string]string{"north": "cave"}},
+			"cave": {ID: "cave", Description: "A dark cave.", Exits: map[string]string{"south": "hall"}},
+		},
+		Entities: map[string]types.EntityDef{
+			"rat": {ID: "rat", Kind: "enemy", Props: map[string]any{
+				"name": "rat", "location": "hall", "alive": true,
+				"hp": 3, "max_hp": 3, "attack": 1, "defense": 0,
+			}},
+			"troll": {ID: "troll", Kind: "enemy", Props: map[string]any{
+				"name": "troll", "location": "cave", "alive": true,
+				"hp": 200, "max_hp": 200, "attack": 30, "defense": 10,
+			}},
+			"lamp": {ID: "lamp", Kind: "item", Props: map[string]any{"name": "lamp", "location": "hall"}},
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	defs := testDefs()
+	rat, err := Run(defs, "rat", Options{Runs: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rat.Runs != 50 || rat.Wins != 50 || rat.WinRate() != 1 {
+		t.Errorf("rat: %d runs, %d wins, rate %v", rat.Runs, rat.Wins, rat.WinRate())
+	}
+	if rat.AvgRounds() != 1 || len(rat.PlayerHits) == 0 || len(rat.EnemyHits) != 0 {
+		t.Errorf("the rat should fall to the first blow: %.1f rounds, hits %v / %v", rat.AvgRounds(), rat.PlayerHits, rat.EnemyHits)
+	}
+
+	troll, err := Run(defs, "troll", Options{Runs: 20, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if troll.Losses != 20 || troll.WinRate() != 0 {
+		t.Errorf("troll: %d losses of %d", troll.Losses, troll.Runs)
+	}
+	for damage := range troll.EnemyHits {
+		if damage < 29 {
+			t.Errorf("troll hit for %d, below its attack less the player's defense", damage)
+		}
+	}
+
+	strong, _ := Run(defs, "troll", Options{Runs: 20, Stats: map[string]int{"hp": 1000, "attack": 60}})
+	if strong.Wins != 20 {
+		t.Errorf("a stronger build should beat the troll: %d wins of %d", strong.Wins, strong.Runs)
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	a, _ := Run(testDefs(), "troll", Options{Runs: 10, Seed: 3})
+	b, _ := Run(testDefs(), "troll", Options{Runs: 10, Seed: 3})
+	if a.String() != b.String() {
+		t.Errorf("same seed, different reports:\n%s\n%s", a, b)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	if _, err := Run(testDefs(), "dragon", Options{}); err == nil {
+		t.Error("unknown entity accepted")
+	}
+	if _, err := Run(testDefs(), "lamp", Options{}); err == nil || !strings.Contains(err.Error(), "not an enemy") {
+		t.Errorf("err = %v, want not an enemy", err)
+	}
+}
+
+func TestReport_String(t *testing.T) {
+	r := &Report{Enemy: "rat", Runs: 4, Wins: 3, Losses: 1, Rounds: 10,
+		PlayerHits: map[int]int{2: 1, 4: 3}, EnemyHits: map[int]int{}}
+	want := "rat: 4 combats, won 75.0% (3 won, 1 lost, 0 drawn), 2.5 rounds on average\n" +
+		"  player hits: 2×1, 4×3 (average 3.5)\n" +
+		"  enemy hits: none\n"
+	if got := r.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
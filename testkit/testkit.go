@@ -0,0 +1,196 @@
+// Package testkit runs command scripts against a game and compares the
+// transcripts with stored golden files, turning playthroughs into
+// regression tests for game authors.
+package testkit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/loader"
+)
+
+// Options configures a script run.
+type Options struct {
+	// Seed is the RNG seed the engine starts from.
+	Seed int64
+	// Exact disables masking of numbers in output from turns that drew
+	// random numbers. By default every run of digits in such output is
+	// replaced with '#', so transcripts survive rebalancing and RNG
+	// reordering.
+	Exact bool
+}
+
+// Run plays the commands in script against a fresh engine and returns the
+// transcript: the intro and opening room, then each command echoed as
+// "> command" followed by its output and any notifications, bracketed as in
+// plain mode. Commands chained in a line run as in play, their output
+// separated by blank lines. Blank lines and lines starting with '#' are skipped;
+// meta-commands ("/save", ...) are not supported.
+func Run(defs *state.Defs, script io.Reader, opts Options) (string, error) {
+	eng := engine.New(defs, engine.WithSeed(opts.Seed))
+
+	var out strings.Builder
+	if defs.Game.Intro != "" {
+		out.WriteString(defs.Game.Intro + "\n\n")
+	}
+	writeLines(&out, eng.Step("look").Output)
+
+	scanner := bufio.NewScanner(script)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		input := strings.TrimSpace(scanner.Text())
+		if input == "" || strings.HasPrefix(input, "#") {
+			continue
+		}
+		if strings.HasPrefix(input, "/") {
+			return "", fmt.Errorf("line %d: meta-command %q is not supported in test scripts", lineNo, input)
+		}
+
+		before := eng.RNG.Position()
+		var output []string
+		for i, result := range eng.StepAll(input) {
+			if i > 0 {
+				output = append(output, "")
+			}
+			output = append(output, result.Output...)
+			for _, note := range result.Notifications {
+				output = append(output, "["+note+"]")
+			}
+			if result.Epilogue != nil {
+				output = append(output, engine.EpilogueLines(result.Epilogue)...)
+			}
+		}
+		if !opts.Exact && eng.RNG.Position() != before {
+			output = maskDigits(output)
+		}
+
+		out.WriteString("\n> " + input + "\n")
+		writeLines(&out, output)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// writeLines writes lines as plain text, one per line.
+func writeLines(out *strings.Builder, lines []string) {
+	for _, line := range lines {
+		out.WriteString(strings.TrimRight(markup.Plain(line), " \t") + "\n")
+	}
+}
+
+// digits matches a run of digits.
+var digits = regexp.MustCompile(`[0-9]+`)
+
+// maskDigits replaces every run of digits in lines with a single '#'.
+func maskDigits(lines []string) []string {
+	masked := make([]string, len(lines))
+	for i, line := range lines {
+		masked[i] = digits.ReplaceAllString(line, "#")
+	}
+	return masked
+}
+
+// Result is the outcome of one script in a test directory.
+type Result struct {
+	Script  string // path of the .script file
+	Golden  string // path of the .golden file
+	Passed  bool
+	Updated bool   // the golden file was (re)written
+	Diff    string // first difference, if the run failed
+	Err     error  // the script could not be run
+}
+
+// RunDir loads the game in gameDir and runs every tests/*.script file in it,
+// comparing each transcript with the .golden file of the same name. With
+// update, golden files are written instead of compared.
+func RunDir(gameDir string, update bool, opts Options) ([]Result, error) {
+	defs, err := loader.Load(gameDir)
+	if err != nil {
+		return nil, err
+	}
+
+	scripts, err := filepath.Glob(filepath.Join(gameDir, "tests", "*.script"))
+	if err != nil {
+		return nil, err
+	}
+	if len(scripts) == 0 {
+		return nil, fmt.Errorf("no tests/*.script files in %s", gameDir)
+	}
+	sort.Strings(scripts)
+
+	var results []Result
+	for _, script := range scripts {
+		results = append(results, runFile(defs, script, update, opts))
+	}
+	return results, nil
+}
+
+func runFile(defs *state.Defs, script string, update bool, opts Options) Result {
+	r := Result{
+		Script: script,
+		Golden: strings.TrimSuffix(script, ".script") + ".golden",
+	}
+
+	f, err := os.Open(script)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	got, err := Run(defs, f, opts)
+	f.Close()
+	if err != nil {
+		r.Err = err
+		return r
+	}
+
+	if update {
+		if err := os.WriteFile(r.Golden, []byte(got), 0o644); err != nil {
+			r.Err = err
+			return r
+		}
+		r.Passed, r.Updated = true, true
+		return r
+	}
+
+	want, err := os.ReadFile(r.Golden)
+	if err != nil {
+		r.Err = fmt.Errorf("reading golden file (run with -update to create it): %w", err)
+		return r
+	}
+	r.Diff = Diff(string(want), got)
+	r.Passed = r.Diff == ""
+	return r
+}
+
+// Diff describes the first line where two transcripts differ, or returns ""
+// if they are equal. Line endings are normalized before comparing.
+func Diff(want, got string) string {
+	wantLines := strings.Split(strings.ReplaceAll(want, "\r\n", "\n"), "\n")
+	gotLines := strings.Split(strings.ReplaceAll(got, "\r\n", "\n"), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g || i >= len(wantLines) || i >= len(gotLines) {
+			return fmt.Sprintf("line %d:\n  want: %q\n  got:  %q", i+1, w, g)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package testkit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+// testDefs returns a two-room game.
+func testDefs() *state.Defs {
+	return &state.Defs{
+		Game: types.GameDef{Title: "Test", Start: "hall", Intro: "Welcome."},
+		Rooms: map[string]types.RoomDef{
+			"hall":   {ID: "hall", Description: "A grand hall.", Exits: map[string]string{"north": "garden"}},
+			"garden": {ID: "garden", Description: "A peaceful garden.", Exits: map[string]string{"south": "hall"}},
+		},
+		Entities: map[string]types.EntityDef{},
+	}
+}
+
+func TestRun_Transcript(t *testing.T) {
+	got, err := Run(testDefs(), strings.NewReader("# comment\n\nnorth\n"), Options{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := "Welcome.\n\nA grand hall.\nExits: north.\n\n> north\nA peaceful garden.\nExits: south.\n"
+	if got != want {
+		t.Errorf("transcript =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestRun_ChainedCommands(t *testing.T) {
+	got, err := Run(testDefs(), strings.NewReader("north and south\n"), Options{})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := "Welcome.\n\nA grand hall.\nExits: north.\n\n> north and south\nA peaceful garden.\nExits: south.\n\nA grand hall.\nExits: north.\n"
+	if got != want {
+		t.Errorf("transcript =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestRun_MetaCommandRejected(t *testing.T) {
+	if _, err := Run(testDefs(), strings.NewReader("north\n/save x\n"), Options{}); err == nil {
+		t.Fatal("expected error for meta-command")
+	}
+}
+
+func TestMaskDigits(t *testing.T) {
+	got := maskDigits([]string{"Roll: 1d20+3 = 17", "no digits"})
+	if got[0] != "Roll: #d#+# = #" || got[1] != "no digits" {
+		t.Errorf("maskDigits = %q", got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	if d := Diff("a\nb\n", "a\r\nb\r\n"); d != "" {
+		t.Errorf("expected no diff across line endings, got %q", d)
+	}
+	if d := Diff("a\nb\n", "a\nc\n"); !strings.Contains(d, "line 2") {
+		t.Errorf("diff = %q, expected line 2", d)
+	}
+	if d := Diff("a\n", "a\nextra\n"); !strings.Contains(d, "extra") {
+		t.Errorf("diff = %q, expected extra line", d)
+	}
+}
+
+func TestRunDir_UpdateThenCompare(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "game.lua"), []byte(`
+Game { title = "T", start = "hall" }
+Room "hall" { description = "A hall.", exits = { north = "yard" } }
+Room "yard" { description = "A yard.", exits = { south = "hall" } }
+`), 0o644)
+	os.MkdirAll(filepath.Join(dir, "tests"), 0o755)
+	script := filepath.Join(dir, "tests", "walk.script")
+	os.WriteFile(script, []byte("north\n"), 0o644)
+
+	// Missing golden file fails.
+	results, err := RunDir(dir, false, Options{})
+	if err != nil {
+		t.Fatalf("RunDir: %v", err)
+	}
+	if len(results) != 1 || results[0].Err == nil {
+		t.Fatalf("expected missing golden error, got %+v", results)
+	}
+
+	// -update writes it.
+	results, _ = RunDir(dir, true, Options{})
+	if !results[0].Updated {
+		t.Fatalf("expected golden to be written, got %+v", results[0])
+	}
+
+	// Now it passes.
+	results, _ = RunDir(dir, false, Options{})
+	if !results[0].Passed {
+		t.Fatalf("expected pass, got %+v", results[0])
+	}
+
+	// A changed transcript fails with a diff.
+	os.WriteFile(script, []byte("north\nsouth\n"), 0o644)
+	results, _ = RunDir(dir, false, Options{})
+	if results[0].Passed || results[0].Diff == "" {
+		t.Errorf("expected failure with diff, got %+v", results[0])
+	}
+}
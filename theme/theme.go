@@ -0,0 +1,264 @@
+// Package theme holds the color schemes of the terminal front ends: the
+// built-in presets, the player's theme file (theme.toml; see DefaultPath), and
+// the theme a game may set for itself in Game.theme.
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nathoo/questcore/config"
+)
+
+// Theme maps color names to colors. A color is an ANSI 256-color number
+// ("208") or a hex RGB value ("#ff8700"); an empty color leaves the
+// terminal's own. The "preset" key, if present, names the preset the theme
+// starts from.
+type Theme map[string]string
+
+// Colors are the names of the colors a theme sets.
+var Colors = []string{
+	"text",      // paragraphs of game output
+	"header",    // room names and other headers
+	"quote",     // quoted text
+	"quote_bar", // the bar beside quotes
+	"aside",     // asides such as the exits list
+	"dialogue",  // what characters say
+	"system",    // meta-command output
+	"error",     // failed commands
+	"input",     // the player's echoed commands
+	"prompt",    // the input prompt
+	"combat",    // combat output and the combat HUD
+	"game_over", // defeat and game over
+	"victory",   // the victory box
+	"ending",    // the epilogue of an ending
+	"status",    // status bar text
+	"status_bg", // status bar background
+	"notice",    // notification text
+	"notice_bg", // notification background
+	"border",    // the sidebar's border
+}
+
+// Presets are the names of the built-in themes, the first the default.
+var Presets = []string{"dark", "light", "monochrome", "high-contrast"}
+
+var presets = map[string]Theme{
+	"dark": {
+		"text": "255", "header": "255", "quote": "250", "quote_bar": "240",
+		"aside": "243", "dialogue": "228", "system": "243", "error": "196",
+		"input": "34", "prompt": "34", "combat": "208", "game_over": "196",
+		"victory": "34", "ending": "220", "status": "252", "status_bg": "236",
+		"notice": "228", "notice_bg": "58", "border": "238",
+	},
+	"light": {
+		"text": "235", "header": "232", "quote": "238", "quote_bar": "248",
+		"aside": "244", "dialogue": "130", "system": "244", "error": "160",
+		"input": "28", "prompt": "28", "combat": "166", "game_over": "160",
+		"victory": "28", "ending": "136", "status": "235", "status_bg": "252",
+		"notice": "94", "notice_bg": "229", "border": "250",
+	},
+	"monochrome": {},
+	"high-contrast": {
+		"text": "15", "header": "15", "quote": "15", "quote_bar": "15",
+		"aside": "250", "dialogue": "11", "system": "250", "error": "9",
+		"input": "10", "prompt": "10", "combat": "214", "game_over": "9",
+		"victory": "10", "ending": "11", "status": "0", "status_bg": "15",
+		"notice": "0", "notice_bg": "11", "border": "15",
+	},
+}
+
+// Preset returns a copy of the built-in theme called name.
+func Preset(name string) (Theme, bool) {
+	p, ok := presets[name]
+	if !ok {
+		return nil, false
+	}
+	t := Theme{}
+	for _, c := range Colors {
+		t[c] = p[c]
+	}
+	return t, true
+}
+
+// Default returns the default theme.
+func Default() Theme {
+	t, _ := Preset(Presets[0])
+	return t
+}
+
+// Check reports the first problem with t: a key that is not a color or
+// "preset", an unknown preset, or a malformed color.
+func (t Theme) Check() error {
+	for _, key := range sortedKeys(t) {
+		value := t[key]
+		switch {
+		case key == "preset":
+			if _, ok := presets[value]; !ok {
+				return fmt.Errorf("unknown preset %q (choose %s)", value, strings.Join(Presets, ", "))
+			}
+		case !slices.Contains(Colors, key):
+			return fmt.Errorf("unknown color %q", key)
+		case !validColor(value):
+			return fmt.Errorf("%s: %q is not a color number (0-255) or #rrggbb", key, value)
+		}
+	}
+	return nil
+}
+
+// Resolve picks the theme to use. A preset chosen on the command line wins
+// outright. Otherwise the player's theme file, if they have one, or else the
+// game's own theme applies, on top of the preset it names (the default if
+// none). Any of the three may be empty.
+func Resolve(flag string, file, game Theme) (Theme, error) {
+	if flag != "" {
+		t, ok := Preset(flag)
+		if !ok {
+			return nil, fmt.Errorf("unknown theme %q (choose %s)", flag, strings.Join(Presets, ", "))
+		}
+		return t, nil
+	}
+	src := game
+	if len(file) > 0 {
+		src = file
+	}
+	if err := src.Check(); err != nil {
+		return nil, err
+	}
+	t := Default()
+	if name := src["preset"]; name != "" {
+		t, _ = Preset(name)
+	}
+	for key, value := range src {
+		if key != "preset" {
+			t[key] = value
+		}
+	}
+	return t, nil
+}
+
+// Select resolves the theme to use, as Resolve does, with the player's
+// theme file read from DefaultPath.
+func Select(flag string, game Theme) (Theme, error) {
+	file, err := Load(DefaultPath())
+	if err != nil {
+		return nil, fmt.Errorf("loading theme: %w", err)
+	}
+	return Resolve(flag, file, game)
+}
+
+// DefaultPath returns where the player's theme file is kept: theme.toml in
+// config.Dir.
+func DefaultPath() string {
+	return filepath.Join(config.Dir(), "theme.toml")
+}
+
+// Load reads the theme file at path. A missing file is an empty theme.
+func Load(path string) (Theme, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Theme{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t, err := Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Parse reads a theme file. It is TOML limited to top-level keys set to
+// strings or integers, one per line:
+//
+//	# Start from the light preset, with dark red room names.
+//	preset = "light"
+//	header = "#8b0000"
+//	dialogue = 130
+func Parse(data string) (Theme, error) {
+	t := Theme{}
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(stripComment(line))
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", i+1)
+		}
+		key = strings.TrimSpace(key)
+		value, err := parseValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		t[key] = value
+		if err := (Theme{key: value}).Check(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	return t, nil
+}
+
+// parseValue parses a TOML string or integer.
+func parseValue(v string) (string, error) {
+	switch {
+	case len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0]:
+		return v[1 : len(v)-1], nil
+	case v != "":
+		if _, err := strconv.Atoi(v); err == nil {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("value %s must be a quoted string or a number", v)
+}
+
+// stripComment removes a "#" comment that is not inside quotes.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// validColor reports whether c is empty, a number from 0 to 255, or "#"
+// followed by three or six hex digits.
+func validColor(c string) bool {
+	if c == "" {
+		return true
+	}
+	if hex, ok := strings.CutPrefix(c, "#"); ok {
+		if len(hex) != 3 && len(hex) != 6 {
+			return false
+		}
+		_, err := strconv.ParseUint(hex, 16, 32)
+		return err == nil
+	}
+	n, err := strconv.Atoi(c)
+	return err == nil && n >= 0 && n <= 255
+}
+
+func sortedKeys(t Theme) []string {
+	keys := make([]string, 0, len(t))
+	for k := range t {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,110 @@
+package theme
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPresets_SetEveryColor(t *testing.T) {
+	for _, name := range Presets {
+		p, ok := Preset(name)
+		if !ok {
+			t.Fatalf("preset %q missing", name)
+		}
+		if len(p) != len(Colors) {
+			t.Errorf("preset %q has %d colors, want %d", name, len(p), len(Colors))
+		}
+		if err := p.Check(); err != nil {
+			t.Errorf("preset %q: %v", name, err)
+		}
+		if name != "monochrome" {
+			for _, c := range Colors {
+				if p[c] == "" {
+					t.Errorf("preset %q leaves %s unset", name, c)
+				}
+			}
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	got, err := Parse(`# My colors
+preset = "light"   # start light
+header = "#8b0000"
+
+dialogue = 130
+quote = '#0f0'
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Theme{"preset": "light", "header": "#8b0000", "dialogue": "130", "quote": "#0f0"}
+	if len(got) != len(want) {
+		t.Fatalf("Parse = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"header", "line 1: expected key = value"},
+		{"\nheader = red", "line 2: value red must be a quoted string or a number"},
+		{`colour = "1"`, `unknown color "colour"`},
+		{`preset = "solarized"`, `unknown preset "solarized"`},
+		{`text = 300`, "not a color number"},
+		{"[colors]", "expected key = value"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.input)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	game := Theme{"preset": "light", "header": "1"}
+	file := Theme{"header": "2"}
+
+	got, err := Resolve("", nil, game)
+	if err != nil || got["header"] != "1" || got["text"] != "235" {
+		t.Errorf("game theme: header %q text %q, %v", got["header"], got["text"], err)
+	}
+	got, err = Resolve("", file, game)
+	if err != nil || got["header"] != "2" || got["text"] != "255" {
+		t.Errorf("theme file should replace the game's: header %q text %q, %v", got["header"], got["text"], err)
+	}
+	got, err = Resolve("monochrome", file, game)
+	if err != nil || got["header"] != "" || got["text"] != "" {
+		t.Errorf("flag should win: header %q text %q, %v", got["header"], got["text"], err)
+	}
+	if _, err := Resolve("neon", nil, nil); err == nil {
+		t.Error("unknown flag preset accepted")
+	}
+	if _, err := Resolve("", nil, Theme{"txt": "1"}); err == nil {
+		t.Error("bad game theme accepted")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	got, err := Load(filepath.Join(dir, "missing.toml"))
+	if err != nil || len(got) != 0 {
+		t.Fatalf("missing file: %v, %v", got, err)
+	}
+
+	path := filepath.Join(dir, "theme.toml")
+	os.WriteFile(path, []byte("text = \"nope\"\n"), 0o644)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), path+": line 1") {
+		t.Errorf("error = %v, want it to name the file and line", err)
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/nathoo/questcore/engine/markup"
 	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
 )
 
 // healthBar produces an ASCII bar like ████████░░░░ using block characters.
@@ -38,19 +40,17 @@ func padRight(s string, width int) string {
 	return s + strings.Repeat(" ", width-w)
 }
 
-// renderCombatStatus produces the bordered combat HUD.
-func (m Model) renderCombatStatus() string {
+// renderCombatHUD produces the combat header shown above the narrative
+// while a fight is on: the round, and each side's health, attack and
+// defense, marked if they defended in the round just fought. It returns ""
+// out of combat, and in accessible mode.
+func (m Model) renderCombatHUD() string {
 	s := m.engine.State
-	if !state.InCombat(s) {
+	if !state.InCombat(s) || m.access != nil {
 		return ""
 	}
 
 	enemyID := s.Combat.EnemyID
-	enemyHP, _ := state.GetStat(s, m.defs, enemyID, "hp")
-	enemyMaxHP, _ := state.GetStat(s, m.defs, enemyID, "max_hp")
-	playerHP, _ := state.GetStat(s, m.defs, "player", "hp")
-	playerMaxHP, _ := state.GetStat(s, m.defs, "player", "max_hp")
-
 	enemyName := enemyID
 	if n, ok := state.GetEntityProp(s, m.defs, enemyID, "name"); ok {
 		if ns, ok := n.(string); ok {
@@ -58,30 +58,42 @@ func (m Model) renderCombatStatus() string {
 		}
 	}
 
-	const barWidth = 12
-	const nameWidth = 20
-	hpEnemy := fmt.Sprintf("%d/%d HP", enemyHP, enemyMaxHP)
-	hpPlayer := fmt.Sprintf("%d/%d HP", playerHP, playerMaxHP)
-	// Right-align HP values to the same width.
-	hpWidth := len(hpEnemy)
-	if len(hpPlayer) > hpWidth {
-		hpWidth = len(hpPlayer)
-	}
+	content := m.combatantLine(enemyID, enemyName, m.defended.enemy) + "\n" +
+		m.combatantLine("player", "You", m.defended.player)
 
-	line1 := fmt.Sprintf("%s %s  %*s", padRight(enemyName, nameWidth), healthBar(enemyHP, enemyMaxHP, barWidth), hpWidth, hpEnemy)
-	line2 := fmt.Sprintf("%s %s  %*s", padRight("You", nameWidth), healthBar(playerHP, playerMaxHP, barWidth), hpWidth, hpPlayer)
-	content := line1 + "\n" + line2
+	style := m.styles.box("combat")
+	if m.width > 4 {
+		style = style.Width(m.width - 2)
+	}
 
-	box := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("208")).
-		Foreground(lipgloss.Color("208")).
-		Padding(0, 1).
-		Render(content)
+	return injectBorderTitle(style.Render(content), fmt.Sprintf(" COMBAT · ROUND %d ", s.Combat.RoundCount+1))
+}
 
-	box = injectBorderTitle(box, " COMBAT ")
+// combatantLine is one side's line in the combat HUD.
+func (m Model) combatantLine(id, name string, defended bool) string {
+	const barWidth = 12
+	const nameWidth = 20
+	s := m.engine.State
+	hp, _ := state.GetStat(s, m.defs, id, "hp")
+	maxHP, _ := state.GetStat(s, m.defs, id, "max_hp")
+	attack, _ := state.GetStat(s, m.defs, id, "attack")
+	defense, _ := state.GetStat(s, m.defs, id, "defense")
+
+	line := fmt.Sprintf("%s %s  %9s  ATK %d  DEF %d", padRight(name, nameWidth),
+		healthBar(hp, maxHP, barWidth), fmt.Sprintf("%d/%d HP", hp, maxHP), attack, defense)
+	if defended {
+		line += "  defending"
+	}
+	return line
+}
 
-	return box
+// hudHeight returns the number of rows the combat HUD takes: its two lines
+// and border while in combat, and none otherwise or in accessible mode.
+func (m Model) hudHeight() int {
+	if state.InCombat(m.engine.State) && m.access == nil {
+		return 4
+	}
+	return 0
 }
 
 // renderVictory produces a bordered box showing the final combat result after
@@ -113,12 +125,7 @@ func (m Model) renderVictory(enemyID string) string {
 	line2 := fmt.Sprintf("%s %s  %*s", padRight("You", nameWidth), healthBar(playerHP, playerMaxHP, barWidth), hpWidth, hpPlayer)
 	content := line1 + "\n" + line2
 
-	box := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("34")).
-		Foreground(lipgloss.Color("34")).
-		Padding(0, 1).
-		Render(content)
+	box := m.styles.box("victory").Render(content)
 
 	box = injectBorderTitle(box, " VICTORY ")
 
@@ -153,12 +160,7 @@ func (m Model) renderDefeat(enemyID string) string {
 	line2 := fmt.Sprintf("%s %s  %*s", padRight("You", nameWidth), healthBar(0, playerMaxHP, barWidth), hpWidth, hpPlayer)
 	content := line1 + "\n" + line2
 
-	box := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("196")).
-		Foreground(lipgloss.Color("196")).
-		Padding(0, 1).
-		Render(content)
+	box := m.styles.box("game_over").Render(content)
 
 	box = injectBorderTitle(box, " DEFEAT ")
 
@@ -179,14 +181,10 @@ func (m Model) renderGameOver(enemyID string) string {
 		}
 	}
 
-	content := fmt.Sprintf("You were slain by the %s.\n\n/load to restore a save\n/quit to exit", enemyName)
+	content := fmt.Sprintf("You were slain by the %s.\n\nrestart to play again\n/load to restore a save\n/quit to exit", enemyName)
 
-	box := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("196")).
-		Foreground(lipgloss.Color("196")).
+	box := m.styles.box("game_over").
 		Bold(true).
-		Padding(0, 1).
 		Render(content)
 
 	box = injectBorderTitle(box, " GAME OVER ")
@@ -194,6 +192,22 @@ func (m Model) renderGameOver(enemyID string) string {
 	return box
 }
 
+// renderEpilogue produces the bordered screen for an ending the player
+// reached: the ending's title, its epilogue text, and the final score.
+func (m Model) renderEpilogue(ep *types.Epilogue) string {
+	content := ep.ScoreLine
+	if ep.Text != "" {
+		content = markup.Plain(ep.Text) + "\n\n" + content
+	}
+
+	style := m.styles.box("ending")
+	if m.width > 8 {
+		style = style.Width(min(m.width-4, 72))
+	}
+
+	return injectBorderTitle(style.Render(content), " "+strings.ToUpper(ep.Title)+" ")
+}
+
 // injectBorderTitle replaces part of the top border line with a title string.
 // Handles ANSI escape codes in the rendered border by working on the visible
 // characters only.
@@ -0,0 +1,63 @@
+package tui
+
+import "strings"
+
+// completion is the state of tab-completion. Pressing tab again while the
+// input still holds the last completion moves on to the next candidate.
+type completion struct {
+	head       string   // the input before the completed fragment
+	candidates []string // what the fragment may complete to, sorted
+	index      int      // the candidate in the input
+}
+
+func (c completion) value() string {
+	return c.head + c.candidates[c.index]
+}
+
+// complete completes the word being typed, or cycles to the next candidate
+// if the input is unchanged since the last tab.
+func (m *Model) complete() {
+	value := m.input.Value()
+	if len(m.completion.candidates) > 0 && value == m.completion.value() {
+		m.completion.index = (m.completion.index + 1) % len(m.completion.candidates)
+	} else {
+		m.completion = newCompletion(value, m.engine.Verbs(), m.engine.VisibleNames())
+		if len(m.completion.candidates) == 0 {
+			return
+		}
+	}
+	m.input.SetValue(m.completion.value())
+	m.input.CursorEnd()
+}
+
+// newCompletion finds the candidates for input. The first word completes to
+// a verb; after it, the longest tail of the input that starts a word and
+// begins some name completes to that name, so that multi-word names work:
+// "use rusty k" completes to "use rusty key". A trailing space offers every
+// name. Matching ignores case.
+func newCompletion(input string, verbs, names []string) completion {
+	lower := strings.ToLower(input)
+	if !strings.Contains(lower, " ") {
+		return completion{candidates: withPrefix(verbs, lower)}
+	}
+	for i := strings.Index(lower, " ") + 1; i <= len(lower); i++ {
+		if lower[i-1] != ' ' || (i < len(lower) && lower[i] == ' ') {
+			continue
+		}
+		if matches := withPrefix(names, lower[i:]); len(matches) > 0 {
+			return completion{head: input[:i], candidates: matches}
+		}
+	}
+	return completion{}
+}
+
+// withPrefix returns the words that start with prefix.
+func withPrefix(words []string, prefix string) []string {
+	var matches []string
+	for _, w := range words {
+		if strings.HasPrefix(w, prefix) {
+			matches = append(matches, w)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// linkAt returns the command a click at column x of viewport line y (counted
+// from the top of the content) issues: "examine <name>" on the bold name of
+// something in view, as in "You see: ...", and "go <dir>" on an exit named in
+// an aside, as in the exits list. It returns "" if there is nothing to click
+// there.
+func (m Model) linkAt(x, y int) string {
+	if y < 0 || y >= len(m.rows) || m.rows[y].line < 0 {
+		return ""
+	}
+	r := m.rows[y]
+	col := r.indent
+	for _, p := range r.pieces {
+		w := utf8.RuneCountInString(p.Text)
+		if x >= col && x < col+w {
+			if p.src < 0 {
+				return ""
+			}
+			return m.link(m.rawLines[r.line], p)
+		}
+		col += w
+	}
+	return ""
+}
+
+// link returns the command for clicking piece p of line rl, or "".
+func (m Model) link(rl rawLine, p piece) string {
+	if span := rl.spans[p.src]; span.Bold {
+		name := strings.ToLower(strings.TrimSpace(span.Text))
+		if slices.Contains(m.engine.VisibleNames(), name) {
+			return "examine " + name
+		}
+	}
+	if rl.kind == kindAside {
+		word := strings.ToLower(strings.TrimFunc(p.Text, func(r rune) bool { return !unicode.IsLetter(r) }))
+		if slices.Contains(m.engine.ExitDirs(m.engine.State.Player.Location), word) {
+			return "go " + word
+		}
+	}
+	return ""
+}
@@ -0,0 +1,55 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// menu holds the choices the last command offered (see
+// types.Result.Choices). While the input line is empty the player picks one
+// with the arrow keys and Enter, or by its number; Esc or typing a command
+// dismisses it.
+type menu struct {
+	choices []types.Choice
+	cursor  int
+}
+
+// active reports whether there are choices to pick from.
+func (mn menu) active() bool {
+	return len(mn.choices) > 0
+}
+
+// move moves the selection by delta, wrapping around the ends.
+func (mn *menu) move(delta int) {
+	n := len(mn.choices)
+	mn.cursor = ((mn.cursor+delta)%n + n) % n
+}
+
+// pick returns the command of the choice numbered n, counting from 1.
+func (mn menu) pick(n int) (string, bool) {
+	if n < 1 || n > len(mn.choices) {
+		return "", false
+	}
+	return mn.choices[n-1].Command, true
+}
+
+// height is how many rows the menu takes on screen.
+func (mn menu) height() int {
+	return len(mn.choices)
+}
+
+// render draws the menu, numbered, with the selection marked.
+func (mn menu) render(st styles) string {
+	lines := make([]string, len(mn.choices))
+	for i, choice := range mn.choices {
+		line := fmt.Sprintf("%d. %s", i+1, choice.Label)
+		if i == mn.cursor {
+			lines[i] = st.menuSelected.Render("› " + line)
+		} else {
+			lines[i] = st.menu.Render("  " + line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,223 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/types"
+)
+
+const (
+	sidebarWidth     = 30 // columns taken by the sidebar, border included
+	sidebarMinWidth  = 70 // narrowest terminal that shows the sidebar
+	sidebarMapHeight = 9  // rows given to the map
+)
+
+// sidebarShown reports whether the sidebar is displayed: the player has
+// toggled it on and the terminal is wide enough for it.
+func (m Model) sidebarShown() bool {
+	return m.sidebar && m.width >= sidebarMinWidth
+}
+
+// narrativeWidth returns the width left to the narrative viewport.
+func (m Model) narrativeWidth() int {
+	if m.sidebarShown() {
+		return m.width - sidebarWidth
+	}
+	return m.width
+}
+
+// renderSidebar draws the sidebar, height rows tall: the current room and its
+// exits, a map of the visited rooms around it, the inventory, the player's
+// stats, and the current objective.
+func (m Model) renderSidebar(height int) string {
+	s := m.engine.State
+	width := sidebarWidth - 2 // less the border and padding
+
+	lines := []string{m.styles.sidebarTitle.Render(truncate(roomDisplayName(s.Player.Location), width))}
+	exits := m.engine.ExitDirs(s.Player.Location)
+	if len(exits) > 0 {
+		lines = append(lines, wrapLines("Exits: "+strings.Join(exits, ", "), width)...)
+	}
+
+	lines = append(lines, "", m.styles.sidebarHeading.Render("Map"))
+	for _, row := range renderMap(s, m.defs, width, sidebarMapHeight) {
+		lines = append(lines, strings.ReplaceAll(row, "@", m.styles.sidebarPlayer.Render("@")))
+	}
+
+	lines = append(lines, "", m.styles.sidebarHeading.Render("Inventory"))
+	if len(s.Player.Inventory) == 0 {
+		lines = append(lines, "(nothing)")
+	}
+	for _, id := range s.Player.Inventory {
+		lines = append(lines, truncate(m.itemName(id), width))
+	}
+
+	if stats := m.sidebarStats(); len(stats) > 0 {
+		lines = append(lines, "")
+		lines = append(lines, stats...)
+	}
+
+	if objective := m.engine.Objective(); objective != "" {
+		lines = append(lines, "", m.styles.sidebarHeading.Render("Objective"))
+		lines = append(lines, wrapLines(objective, width)...)
+	}
+
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return m.styles.sidebar.Width(sidebarWidth - 1).Height(height).Render(strings.Join(lines, "\n"))
+}
+
+// sidebarStats lists the player's health, level and the time of day, for
+// the games that have them.
+func (m Model) sidebarStats() []string {
+	s := m.engine.State
+	var lines []string
+	if hp, ok := s.Player.Stats["hp"]; ok {
+		if maxHP, ok := s.Player.Stats["max_hp"]; ok {
+			lines = append(lines, fmt.Sprintf("HP %s %d/%d", healthBar(hp, maxHP, 10), hp, maxHP))
+		}
+	}
+	if len(m.defs.Game.Levels) > 0 {
+		lines = append(lines, fmt.Sprintf("Lv %d · XP %d", s.Player.Stats["level"], s.Player.Stats["xp"]))
+	}
+	if m.defs.Game.Clock != nil {
+		lines = append(lines, fmt.Sprintf("Day %d %s", state.Day(s), state.FormatClock(state.TimeOfDay(s))))
+	}
+	return lines
+}
+
+// itemName returns the display name of an entity.
+func (m Model) itemName(id string) string {
+	if n, ok := state.GetEntityProp(m.engine.State, m.defs, id, "name"); ok {
+		if ns, ok := n.(string); ok {
+			return ns
+		}
+	}
+	return id
+}
+
+// gridPos is a room's place on the map, in rooms east and south of the
+// player.
+type gridPos struct{ x, y int }
+
+// mapSteps are the map offsets of the compass directions. Up and down have
+// no place on a flat map.
+var mapSteps = map[string]gridPos{
+	"north": {0, -1}, "south": {0, 1}, "east": {1, 0}, "west": {-1, 0},
+	"northeast": {1, -1}, "northwest": {-1, -1}, "southeast": {1, 1}, "southwest": {-1, 1},
+}
+
+// layoutMap places the player's room and the visited rooms around it on a
+// grid, following exits breadth-first: each room goes one step from the room
+// it was reached from, in the exit's direction. Rooms reached only by going
+// up or down, or whose spot is already taken, are left off.
+func layoutMap(s *types.State, defs *state.Defs) map[string]gridPos {
+	start := s.Player.Location
+	placed := map[string]gridPos{start: {}}
+	taken := map[gridPos]bool{{}: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		exits := state.RoomExits(s, defs, id)
+		for _, dir := range sortedDirs(exits) {
+			step, ok := mapSteps[dir]
+			target := exits[dir]
+			if _, done := placed[target]; !ok || done || !state.HasVisited(s, target) {
+				continue
+			}
+			pos := gridPos{placed[id].x + step.x, placed[id].y + step.y}
+			if taken[pos] {
+				continue
+			}
+			placed[target] = pos
+			taken[pos] = true
+			queue = append(queue, target)
+		}
+	}
+	return placed
+}
+
+// renderMap draws the map around the player within width columns and height
+// rows: "@" is the player, "o" another visited room, and lines join rooms
+// with an exit between them. Blank rows above and below are dropped.
+func renderMap(s *types.State, defs *state.Defs, width, height int) []string {
+	canvas := make([][]byte, height)
+	for i := range canvas {
+		canvas[i] = []byte(strings.Repeat(" ", width))
+	}
+	cx, cy := width/2, height/2
+	set := func(x, y int, c byte) {
+		if x >= 0 && x < width && y >= 0 && y < height {
+			canvas[y][x] = c
+		}
+	}
+
+	placed := layoutMap(s, defs)
+	for id, pos := range placed {
+		x, y := cx+2*pos.x, cy+2*pos.y
+		exits := state.RoomExits(s, defs, id)
+		for dir, target := range exits {
+			step, ok := mapSteps[dir]
+			if to, shown := placed[target]; ok && shown && to == (gridPos{pos.x + step.x, pos.y + step.y}) {
+				set(x+step.x, y+step.y, mapLink(step))
+			}
+		}
+		set(x, y, 'o')
+	}
+	set(cx, cy, '@')
+
+	var rows []string
+	for _, row := range canvas {
+		rows = append(rows, strings.TrimRight(string(row), " "))
+	}
+	for len(rows) > 0 && rows[0] == "" {
+		rows = rows[1:]
+	}
+	for len(rows) > 0 && rows[len(rows)-1] == "" {
+		rows = rows[:len(rows)-1]
+	}
+	return rows
+}
+
+// mapLink returns the character joining two rooms a step apart.
+func mapLink(step gridPos) byte {
+	switch {
+	case step.y == 0:
+		return '-'
+	case step.x == 0:
+		return '|'
+	case step.x == step.y:
+		return '\\'
+	}
+	return '/'
+}
+
+// sortedDirs returns the directions of exits in alphabetical order.
+func sortedDirs(exits map[string]string) []string {
+	dirs := make([]string, 0, len(exits))
+	for dir := range exits {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// truncate shortens text to width columns, ending it with an ellipsis if it
+// was cut.
+func truncate(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// wrapLines word-wraps text to width and returns its lines.
+func wrapLines(text string, width int) []string {
+	return strings.Split(wordWrap(text, width), "\n")
+}
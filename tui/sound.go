@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/nathoo/questcore/types"
+)
+
+// soundEnv names the environment variable that turns on sound cues: "bell"
+// rings the terminal bell for each sound, and anything else is a command run
+// for each cue with the cue's type and name appended, e.g.
+//
+//	QUESTCORE_SOUND="$HOME/bin/qc-play"   →   qc-play sound door_creak
+//	                                          qc-play music dungeon_theme
+//
+// Music stops with an empty name, which the command receives as "".
+const soundEnv = "QUESTCORE_SOUND"
+
+// cuePlayer reacts to a game's sound and music cues.
+type cuePlayer struct {
+	bell    io.Writer // rings the bell for sounds; nil when running a command
+	command []string  // program and arguments to run for each cue
+	start   func(*exec.Cmd) error
+}
+
+// newCuePlayer returns the player for a QUESTCORE_SOUND setting, or nil if
+// sound cues are off.
+func newCuePlayer(setting string) *cuePlayer {
+	switch strings.TrimSpace(setting) {
+	case "":
+		return nil
+	case "bell":
+		return &cuePlayer{bell: os.Stdout}
+	}
+	return &cuePlayer{command: strings.Fields(setting), start: startDetached}
+}
+
+// play acts on cues. Commands run in the background; their output and
+// failures are ignored, so a broken player never interrupts the game.
+func (p *cuePlayer) play(cues []types.Cue) {
+	if p == nil {
+		return
+	}
+	for _, cue := range cues {
+		if p.bell != nil {
+			if cue.Type == "sound" {
+				io.WriteString(p.bell, "\a")
+			}
+			continue
+		}
+		args := append(append([]string{}, p.command[1:]...), cue.Type, cue.Name)
+		p.start(exec.Command(p.command[0], args...))
+	}
+}
+
+// startDetached starts cmd and reaps it when it exits.
+func startDetached(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -2,7 +2,6 @@ package tui
 
 import (
 	"fmt"
-	"sort"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -22,19 +21,17 @@ func roomDisplayName(id string) string {
 }
 
 // renderStatusBar produces a full-width inverted status line showing
-// current room, exits, inventory, and turn count.
+// current room, exits, stats, clock, inventory, and turn count, or the latest
+// notifications while they are being flashed.
 func (m Model) renderStatusBar() string {
+	if m.notice != "" {
+		return m.styles.notice.Width(m.width).Render(" ★ " + m.notice)
+	}
 	s := m.engine.State
 
 	roomName := roomDisplayName(s.Player.Location)
 
-	exits := state.RoomExits(s, m.defs, s.Player.Location)
-	dirs := make([]string, 0, len(exits))
-	for dir := range exits {
-		dirs = append(dirs, dir)
-	}
-	sort.Strings(dirs)
-	exitStr := strings.Join(dirs, ",")
+	exitStr := strings.Join(m.engine.ExitDirs(s.Player.Location), ",")
 
 	invCount := len(s.Player.Inventory)
 
@@ -46,19 +43,21 @@ func (m Model) renderStatusBar() string {
 			left += fmt.Sprintf(" | HP: %d/%d", hp, maxHP)
 		}
 	}
+	// Show level and XP if the game has levels.
+	if len(m.defs.Game.Levels) > 0 {
+		left += fmt.Sprintf(" | Lv %d · XP %d", s.Player.Stats["level"], s.Player.Stats["xp"])
+	}
+	// Show the clock if the game has one.
+	if m.defs.Game.Clock != nil {
+		left += fmt.Sprintf(" | Day %d %s", state.Day(s), state.FormatClock(state.TimeOfDay(s)))
+	}
 	right := fmt.Sprintf("T:%d ", s.TurnCount)
 
 	// Show inventory items if they fit, otherwise just count.
 	if invCount > 0 {
 		var names []string
 		for _, id := range s.Player.Inventory {
-			name := id
-			if n, ok := state.GetEntityProp(s, m.defs, id, "name"); ok {
-				if ns, ok := n.(string); ok {
-					name = ns
-				}
-			}
-			names = append(names, name)
+			names = append(names, m.itemName(id))
 		}
 		invStr := strings.Join(names, ", ")
 		candidate := fmt.Sprintf("Inv: %s | T:%d ", invStr, s.TurnCount)
@@ -75,5 +74,5 @@ func (m Model) renderStatusBar() string {
 	}
 
 	bar := left + strings.Repeat(" ", gap) + right
-	return styleStatusBar.Width(m.width).Render(bar)
+	return m.styles.statusBar.Width(m.width).Render(bar)
 }
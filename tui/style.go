@@ -2,129 +2,276 @@ package tui
 
 import (
 	"strings"
+	"unicode"
 
 	"github.com/charmbracelet/lipgloss"
-)
-
-// Styles used throughout the TUI.
-var (
-	styleStatusBar = lipgloss.NewStyle().
-			Background(lipgloss.Color("236")).
-			Foreground(lipgloss.Color("252")).
-			Bold(true)
-
-	styleInputPrompt = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("34"))
-
-	styleRoomDesc = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("255"))
-
-	styleYouSee = lipgloss.NewStyle().
-			Bold(true)
-
-	styleExits = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("243"))
 
-	styleDialogue = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("228"))
-
-	styleSystem = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("243"))
-
-	styleError = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("196"))
+	"github.com/nathoo/questcore/engine/markup"
+	"github.com/nathoo/questcore/theme"
+)
 
-	stylePlayerInput = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("34"))
+// styles are the TUI's styles, built from a theme by newStyles.
+type styles struct {
+	statusBar      lipgloss.Style
+	notice         lipgloss.Style
+	inputPrompt    lipgloss.Style
+	roomDesc       lipgloss.Style
+	header         lipgloss.Style
+	quote          lipgloss.Style
+	quoteBar       lipgloss.Style
+	aside          lipgloss.Style
+	dialogue       lipgloss.Style
+	system         lipgloss.Style
+	error          lipgloss.Style
+	playerInput    lipgloss.Style
+	combat         lipgloss.Style
+	combatHeader   lipgloss.Style
+	gameOver       lipgloss.Style
+	combatPrompt   lipgloss.Style
+	gameOverPrompt lipgloss.Style
+	sidebar        lipgloss.Style
+	sidebarTitle   lipgloss.Style
+	sidebarHeading lipgloss.Style
+	sidebarPlayer  lipgloss.Style
+	menu           lipgloss.Style
+	menuSelected   lipgloss.Style
 
-	styleTrace = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("240"))
+	palette theme.Theme // for the bordered boxes drawn on the fly
+}
 
-	styleCombat = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("208"))
+// newStyles returns the TUI's styles in the colors of t. Where t leaves
+// the status bar or a notice without a background, they are shown in
+// reverse video.
+func newStyles(t theme.Theme) styles {
+	fg := func(name string) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(t[name]))
+	}
+	bar := func(name string) lipgloss.Style {
+		if t[name+"_bg"] == "" {
+			return fg(name).Reverse(true).Bold(true)
+		}
+		return fg(name).Background(lipgloss.Color(t[name+"_bg"])).Bold(true)
+	}
 
-	styleCombatHeader = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("208")).
-				Bold(true)
+	return styles{
+		statusBar:      bar("status"),
+		notice:         bar("notice"),
+		inputPrompt:    fg("prompt"),
+		roomDesc:       fg("text"),
+		header:         fg("header").Bold(true).Underline(true),
+		quote:          fg("quote").Italic(true),
+		quoteBar:       fg("quote_bar"),
+		aside:          fg("aside"),
+		dialogue:       fg("dialogue"),
+		system:         fg("system"),
+		error:          fg("error"),
+		playerInput:    fg("input"),
+		combat:         fg("combat"),
+		combatHeader:   fg("combat").Bold(true),
+		gameOver:       fg("game_over").Bold(true),
+		combatPrompt:   fg("combat"),
+		gameOverPrompt: fg("game_over"),
 
-	styleGameOver = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("196")).
-			Bold(true)
+		sidebar: lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder(), false, false, false, true).
+			BorderForeground(lipgloss.Color(t["border"])).
+			PaddingLeft(1),
+		sidebarTitle:   fg("header").Bold(true),
+		sidebarHeading: fg("aside"),
+		sidebarPlayer:  fg("input").Bold(true),
+		menu:           fg("system"),
+		menuSelected:   fg("input").Bold(true),
 
-	styleCombatPrompt = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("208"))
+		palette: t,
+	}
+}
 
-	styleGameOverPrompt = lipgloss.NewStyle().
-				Foreground(lipgloss.Color("196"))
-)
+// box is the style of a bordered box drawn in the theme's color name.
+func (st styles) box(name string) lipgloss.Style {
+	c := lipgloss.Color(st.palette[name])
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(c).
+		Foreground(c).
+		Padding(0, 1)
+}
 
 // lineKind identifies the type of an output line for styling.
 type lineKind int
 
 const (
 	kindRoomDesc lineKind = iota
-	kindYouSee
-	kindExits
+	kindHeader
+	kindQuote
 	kindDialogue
-	kindSystem
 	kindError
-	kindTrace
+	kindAside
 	kindCombat
 	kindCombatHeader
 	kindGameOver
 	kindPreStyled
 )
 
-// classifyLine determines what kind of output line this is.
-func classifyLine(line string) lineKind {
-	switch {
-	case strings.HasPrefix(line, "[trace]"):
-		return kindTrace
-	case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
-		return kindSystem
-	case strings.HasPrefix(line, "You see:"):
-		return kindYouSee
-	case strings.HasPrefix(line, "Exits:"):
-		return kindExits
-	case strings.HasPrefix(line, "You don't see"),
-		strings.HasPrefix(line, "You can't"),
-		strings.HasPrefix(line, "You don't have"):
-		return kindError
-	case containsQuotedSpeech(line):
-		return kindDialogue
+// blockKinds maps the kinds of markup blocks in game output to line kinds.
+var blockKinds = map[markup.Kind]lineKind{
+	markup.Paragraph: kindRoomDesc,
+	markup.Header:    kindHeader,
+	markup.Quote:     kindQuote,
+	markup.Dialogue:  kindDialogue,
+	markup.Failure:   kindError,
+	markup.Aside:     kindAside,
+}
+
+// kind returns the style for a given lineKind.
+func (st styles) kind(kind lineKind) lipgloss.Style {
+	switch kind {
+	case kindHeader:
+		return st.header
+	case kindQuote:
+		return st.quote
+	case kindDialogue:
+		return st.dialogue
+	case kindError:
+		return st.error
+	case kindAside:
+		return st.aside
+	case kindCombat:
+		return st.combat
+	case kindCombatHeader:
+		return st.combatHeader
+	case kindGameOver:
+		return st.gameOver
 	default:
-		return kindRoomDesc
+		return st.roomDesc
 	}
 }
 
-// containsQuotedSpeech checks if a line contains NPC dialogue in single quotes.
-func containsQuotedSpeech(line string) bool {
-	inQuote := false
-	quoteLen := 0
-	for _, r := range line {
-		if r == '\'' {
-			if inQuote && quoteLen > 5 {
-				return true
+// piece is the part of a span that lies on one wrapped line. src is the
+// index of the span it came from, or -1 for a space inserted between words.
+type piece struct {
+	markup.Span
+	src int
+}
+
+// row is one line of the viewport. Lines of game output keep the pieces
+// they show, starting at column indent, so that clicks can be mapped back
+// to them; line is their index in rawLines, or -1 for any other line.
+type row struct {
+	line   int
+	indent int
+	pieces []piece
+}
+
+// renderBlock wraps and styles a line of game output: quotes get a bar down
+// their left side, and attributed dialogue is headed by the speaker's name.
+// It also returns the rows the output takes.
+func (st styles) renderBlock(rl rawLine, width int) (string, []row) {
+	style := st.kind(rl.kind)
+	switch rl.kind {
+	case kindQuote:
+		bar := st.quoteBar.Render("│ ")
+		text, rows := renderSpans(rl.spans, width-2, style)
+		for i := range rows {
+			rows[i].indent = 2
+		}
+		return bar + strings.ReplaceAll(text, "\n", "\n"+bar), rows
+	case kindDialogue:
+		text, rows := renderSpans(rl.spans, width, style)
+		if rl.speaker != "" {
+			return style.Bold(true).Render(rl.speaker) + "\n" + text, append([]row{{}}, rows...)
+		}
+		return text, rows
+	}
+	return renderSpans(rl.spans, width, style)
+}
+
+// renderSpans word-wraps spans to width and renders them in style, with
+// bold spans in bold.
+func renderSpans(spans []markup.Span, width int, style lipgloss.Style) (string, []row) {
+	var out strings.Builder
+	var rows []row
+	for i, line := range wrapSpans(spans, width) {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		for _, p := range line {
+			s := style
+			if p.Bold {
+				s = s.Bold(true)
+			}
+			out.WriteString(s.Render(p.Text))
+		}
+		rows = append(rows, row{pieces: line})
+	}
+	return out.String(), rows
+}
+
+// wrapSpans breaks spans into lines at word boundaries, as wordWrap does for
+// plain text, keeping each word's styling. A word may mix styles, as the
+// bold name and plain comma in "*key*," do.
+func wrapSpans(spans []markup.Span, width int) [][]piece {
+	var words [][]piece
+	var word []piece
+	for src, span := range spans {
+		text := span.Text
+		for text != "" {
+			i := strings.IndexFunc(text, unicode.IsSpace)
+			if i != 0 {
+				if i < 0 {
+					i = len(text)
+				}
+				word = append(word, piece{markup.Span{Text: text[:i], Bold: span.Bold}, src})
+				text = text[i:]
+				continue
 			}
-			inQuote = !inQuote
-			quoteLen = 0
-		} else if inQuote {
-			quoteLen++
+			if len(word) > 0 {
+				words = append(words, word)
+				word = nil
+			}
+			text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		}
+	}
+	if len(word) > 0 {
+		words = append(words, word)
+	}
+
+	var lines [][]piece
+	var line []piece
+	lineLen := 0
+	for _, w := range words {
+		wLen := 0
+		for _, span := range w {
+			wLen += len(span.Text)
 		}
+		switch {
+		case len(line) == 0:
+		case lineLen+1+wLen > width:
+			lines = append(lines, line)
+			line, lineLen = nil, 0
+		default:
+			line = append(line, piece{markup.Span{Text: " "}, -1})
+			lineLen++
+		}
+		line = append(line, w...)
+		lineLen += wLen
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
 	}
-	return false
+	return lines
 }
 
-// styledYouSee renders "You see: item1, item2." with item names bold.
-func styledYouSee(line string) string {
-	const prefix = "You see: "
-	if !strings.HasPrefix(line, prefix) {
-		return styleRoomDesc.Render(line)
+// plainLines renders game output lines as plain text, for screens that
+// show them unstyled.
+func plainLines(lines []string) []string {
+	plain := make([]string, len(lines))
+	for i, line := range lines {
+		plain[i] = markup.Plain(line)
 	}
-	return styleRoomDesc.Render(prefix) + styleYouSee.Render(line[len(prefix):])
+	return plain
 }
 
-// styledSystemMsg renders a system message in gray with brackets.
-func styledSystemMsg(text string) string {
-	return styleSystem.Render("[" + text + "]")
+// systemMsg renders a system message in gray with brackets.
+func (st styles) systemMsg(text string) string {
+	return st.system.Render("[" + text + "]")
 }
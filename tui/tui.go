@@ -1,51 +1,112 @@
+// Package tui provides a Bubble Tea terminal UI for the QuestCore game engine.
 package tui
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 
+	"github.com/nathoo/questcore/access"
+	"github.com/nathoo/questcore/config"
+	"github.com/nathoo/questcore/console"
 	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/markup"
 	"github.com/nathoo/questcore/engine/save"
 	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/engine/trace"
+	"github.com/nathoo/questcore/history"
+	"github.com/nathoo/questcore/profile"
+	"github.com/nathoo/questcore/theme"
 	"github.com/nathoo/questcore/types"
 )
 
-// rawLine stores an unstyled output line with its classification,
-// so we can re-wrap and re-style when the terminal is resized.
+// rawLine stores an unstyled output line with its parsed markup, so we can
+// re-wrap and re-style when the terminal is resized.
 type rawLine struct {
 	text     string
 	kind     lineKind
-	isInput  bool // true for echoed player input
-	isSystem bool // true for system messages
+	speaker  string        // who is talking, for dialogue
+	spans    []markup.Span // the text, for game output
+	isInput  bool          // true for echoed player input
+	isSystem bool          // true for system messages
 }
 
 // Model is the Bubble Tea model for the QuestCore TUI.
 type Model struct {
 	engine *engine.Engine
 	defs   *state.Defs
+	styles styles // in the colors of the theme given to New
 
 	viewport viewport.Model
 	input    textinput.Model
-	history  *History
+	history  *history.History
+
+	completion completion // the last tab-completion, for cycling
+	menu       menu       // the choices the last command offered
 
 	rawLines []rawLine // accumulated narrative lines (unstyled, for re-wrapping)
+	rows     []row     // the viewport's lines, for mapping clicks to output
 
 	width    int
 	height   int
 	ready    bool
 	trace    bool
+	sidebar  bool // the sidebar is toggled on; see sidebarShown
 	quitting bool
 	lastCmd  string
 	saveDir  string
+
+	autosave  int // turns between saves to the "autosave" slot (0 = off); see Options
+	autosaved int // the turn of the last autosave
+
+	profilePath string     // achievements kept between playthroughs ("" = this session only)
+	historyPath string     // commands kept between sessions ("" = this session only)
+	cues        *cuePlayer // plays sound and music cues (nil = off)
+
+	access *access.Formatter // words output for screen readers (nil = as shown); see Options
+
+	console *console.Console // the authors' /lua prompt (nil = off); see Options
+	luaMode bool             // input is Lua for console, until /lua again
+
+	defended struct{ player, enemy bool } // who defended in the last combat round, for the HUD
+
+	execDepth int                     // nesting level of /exec files
+	marks     map[string]*types.State // in-memory checkpoints set by /mark, by name
+	recorder  *history.Recorder       // where /record writes commands (nil = not recording)
+
+	traceOnly []trace.Subsystem // subsystems trace shows (none = all)
+	traceLog  *trace.Memory     // the engine's trace records since the last command (nil = not yet traced)
+	lastTrace []trace.Event     // the trace records of the last game command, for /trace and /why
+	idleSeq   int               // bumped on every command; stale idle timers are ignored
+
+	notice    string // latest notifications, flashed in the status bar
+	noticeSeq int    // bumped on every new notice; stale clear timers are ignored
+}
+
+// idleMsg fires when the player has been idle for the game's nudge interval.
+type idleMsg struct {
+	seq int
+}
+
+// noticeMsg clears the status-bar notice once it has been shown long enough.
+type noticeMsg struct {
+	seq int
 }
 
+// noticeDuration is how long a notification stays in the status bar.
+const noticeDuration = 4 * time.Second
+
 // gameOutputMsg carries output from the engine into the Update loop.
 type gameOutputMsg struct {
 	input    string   // echoed player input (empty for intro)
@@ -53,42 +114,116 @@ type gameOutputMsg struct {
 	isSystem bool     // true for meta-command output
 }
 
-// New creates a TUI model wired to the given engine.
-func New(eng *engine.Engine, defs *state.Defs) Model {
+// New creates a TUI model wired to the given engine, colored with t.
+func New(eng *engine.Engine, defs *state.Defs, t theme.Theme) Model {
+	st := newStyles(t)
 	ti := textinput.New()
 	ti.Prompt = "> "
 	ti.Focus()
-	ti.CharLimit = 256
-	ti.PromptStyle = styleInputPrompt
+	ti.CharLimit = 4096 // room for pasted /import-save codes
+	ti.PromptStyle = st.inputPrompt
 
-	home, _ := os.UserHomeDir()
 	return Model{
 		engine:  eng,
 		defs:    defs,
+		styles:  st,
 		input:   ti,
-		history: NewHistory(100),
-		saveDir: filepath.Join(home, ".questcore", "saves"),
+		history: history.New(history.DefaultMax),
+		saveDir: filepath.Join(config.DataDir(), "saves"),
 	}
 }
 
+// Options are the player's choices for how the TUI looks.
+type Options struct {
+	// Theme names the preset to color the TUI with. If empty, the colors
+	// are those of the player's theme file or the game's theme; see
+	// theme.Select.
+	Theme string
+
+	// Accessible suits the TUI to screen readers: output is put in words
+	// by package access, without color, boxes, the combat HUD, the
+	// sidebar or blank lines between turns.
+	Accessible bool
+
+	// Console is the authors' /lua prompt (nil = off; see --dev).
+	Console *console.Console
+
+	// SaveDir is where /save and /load keep saves ("" = saves in
+	// config.DataDir).
+	SaveDir string
+
+	// Autosave is the number of turns between saves to the "autosave"
+	// slot (0 = off).
+	Autosave int
+}
+
 // Run starts the Bubble Tea program.
-func Run(eng *engine.Engine, defs *state.Defs) error {
-	m := New(eng, defs)
+func Run(eng *engine.Engine, defs *state.Defs, opts Options) error {
+	themeName := opts.Theme
+	if opts.Accessible {
+		themeName = "monochrome"
+	}
+	t, err := theme.Select(themeName, defs.Game.Theme)
+	if err != nil {
+		return err
+	}
+	m := New(eng, defs, t)
+	if opts.Accessible {
+		m.access = access.New(eng)
+	}
+	m.console = opts.Console
+	if opts.SaveDir != "" {
+		m.saveDir = opts.SaveDir
+	}
+	m.autosave = opts.Autosave
+	m.profilePath = profile.DefaultPath(defs.Game.Title)
+	m.cues = newCuePlayer(os.Getenv(soundEnv))
+	prof, err := profile.Load(m.profilePath)
+	if err != nil {
+		return fmt.Errorf("loading achievements: %w", err)
+	}
+	eng.State.Achievements = prof.Achievements
+	m.historyPath = history.DefaultPath()
+	if m.history, err = history.Load(m.historyPath, history.DefaultMax); err != nil {
+		return fmt.Errorf("loading history: %w", err)
+	}
+
 	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
-	_, err := p.Run()
+	_, err = p.Run()
 	return err
 }
 
 // Init returns the initial command that produces intro text and first look.
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(textinput.Blink, m.initialOutput())
+	return tea.Batch(textinput.Blink, m.initialOutput(), m.idleTimer())
+}
+
+// idleTimer starts the idle-nudge countdown for the current command, or
+// returns nil if the game has idle nudges turned off.
+func (m Model) idleTimer() tea.Cmd {
+	idle := m.defs.Game.IdleNudge
+	if idle == nil || idle.Minutes <= 0 {
+		return nil
+	}
+	seq := m.idleSeq
+	return tea.Tick(time.Duration(idle.Minutes)*time.Minute, func(time.Time) tea.Msg {
+		return idleMsg{seq: seq}
+	})
+}
+
+// noticeTimer starts the countdown that clears the current notice.
+func (m Model) noticeTimer() tea.Cmd {
+	seq := m.noticeSeq
+	return tea.Tick(noticeDuration, func(time.Time) tea.Msg {
+		return noticeMsg{seq: seq}
+	})
 }
 
 func (m Model) initialOutput() tea.Cmd {
 	return func() tea.Msg {
 		var lines []string
 
-		lines = append(lines, m.defs.Game.Title+" v"+m.defs.Game.Version+" by "+m.defs.Game.Author)
+		lines = append(lines, markup.Escape(m.defs.Game.Title+" v"+m.defs.Game.Version+" by "+m.defs.Game.Author))
 		lines = append(lines, "")
 
 		if m.defs.Game.Intro != "" {
@@ -98,6 +233,10 @@ func (m Model) initialOutput() tea.Cmd {
 
 		result := m.engine.Step("look")
 		lines = append(lines, result.Output...)
+		if m.access != nil {
+			// Accessible mode words each line and drops the blank ones.
+			lines = m.access.Step(types.Result{Output: lines})
+		}
 
 		return gameOutputMsg{lines: lines}
 	}
@@ -112,30 +251,27 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 
-		vpHeight := m.height - 2 // 1 status bar + 1 input line
-		if vpHeight < 1 {
-			vpHeight = 1
-		}
-
 		if !m.ready {
-			m.viewport = viewport.New(m.width, vpHeight)
+			m.viewport = viewport.New(0, 0)
 			m.viewport.KeyMap = viewportKeyMap()
 			m.ready = true
-		} else {
-			m.viewport.Width = m.width
-			m.viewport.Height = vpHeight
 		}
-
 		m.refreshViewport()
 
 	case tea.KeyMsg:
+		if m.menu.active() && m.input.Value() == "" {
+			if model, cmd, ok := m.menuKey(msg); ok {
+				return model, cmd
+			}
+		}
 		switch msg.String() {
 		case "ctrl+c":
+			m.stopRecording()
 			m.quitting = true
 			return m, tea.Quit
 
 		case "enter":
-			return m.handleEnter()
+			return m.submit()
 
 		case "up":
 			if prev, ok := m.history.Prev(); ok {
@@ -154,14 +290,57 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 
+		case "tab":
+			// Tab completes what is being typed; on an empty input it
+			// shows or hides the sidebar, except in accessible mode.
+			if strings.TrimSpace(m.input.Value()) != "" {
+				m.complete()
+				return m, nil
+			}
+			m.sidebar = !m.sidebar && m.access == nil
+			m.refreshViewport()
+			return m, nil
+
 		case "pgup", "pgdown":
 			var vpCmd tea.Cmd
 			m.viewport, vpCmd = m.viewport.Update(msg)
 			return m, vpCmd
 		}
 
+	case tea.MouseMsg:
+		y := msg.Y - m.hudHeight()
+		if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft &&
+			msg.X < m.viewport.Width && y >= 0 && y < m.viewport.Height {
+			if cmd := m.linkAt(msg.X, m.viewport.YOffset+y); cmd != "" {
+				m.input.SetValue(cmd)
+				return m.submit()
+			}
+			return m, nil
+		}
+		if tea.MouseEvent(msg).IsWheel() {
+			var vpCmd tea.Cmd
+			m.viewport, vpCmd = m.viewport.Update(msg)
+			return m, vpCmd
+		}
+
 	case gameOutputMsg:
 		m = m.appendOutput(msg)
+
+	case idleMsg:
+		// Only the timer started by the latest command counts; the nudge
+		// doesn't take a turn and isn't repeated until the next command.
+		if msg.seq == m.idleSeq {
+			if text := m.engine.IdleNudge(); text != "" {
+				m = m.appendOutput(gameOutputMsg{lines: []string{markup.Plain(text)}, isSystem: true})
+			}
+		}
+		return m, nil
+
+	case noticeMsg:
+		if msg.seq == m.noticeSeq {
+			m.notice = ""
+		}
+		return m, nil
 	}
 
 	var inputCmd tea.Cmd
@@ -171,6 +350,50 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// menuKey handles a key press while a menu of choices is up and the input
+// line is empty: the arrow keys move the selection, Enter or a number picks
+// a choice and Esc dismisses the menu. It reports whether the key was one
+// of those.
+func (m Model) menuKey(msg tea.KeyMsg) (tea.Model, tea.Cmd, bool) {
+	key := msg.String()
+	switch key {
+	case "up":
+		m.menu.move(-1)
+		return m, nil, true
+	case "down":
+		m.menu.move(1)
+		return m, nil, true
+	case "enter":
+		m.input.SetValue(m.menu.choices[m.menu.cursor].Command)
+		model, cmd := m.submit()
+		return model, cmd, true
+	case "esc":
+		m.menu = menu{}
+		m.refreshViewport()
+		return m, nil, true
+	}
+	if n, err := strconv.Atoi(key); err == nil {
+		if command, ok := m.menu.pick(n); ok {
+			m.input.SetValue(command)
+			model, cmd := m.submit()
+			return model, cmd, true
+		}
+	}
+	return m, nil, false
+}
+
+// submit runs the command in the input line, as if enter had been pressed,
+// and restarts the idle timer.
+func (m Model) submit() (tea.Model, tea.Cmd) {
+	model, cmd := m.handleEnter()
+	next := model.(Model)
+	next.idleSeq++
+	if next.noticeSeq != m.noticeSeq {
+		return next, tea.Batch(cmd, next.idleTimer(), next.noticeTimer())
+	}
+	return next, tea.Batch(cmd, next.idleTimer())
+}
+
 // handleEnter processes the submitted input line.
 func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 	input := strings.TrimSpace(m.input.Value())
@@ -180,8 +403,13 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	m.menu = menu{}
 	m.history.Push(input)
 	m.history.ResetCursor()
+	if m.historyPath != "" {
+		// History is a convenience: failing to keep it doesn't stop play.
+		m.history.Save(m.historyPath)
+	}
 
 	// Handle "again" / "g".
 	lower := strings.ToLower(input)
@@ -197,29 +425,56 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 		m.lastCmd = input
 	}
 
-	// Meta-commands.
+	// Meta-commands. The game's own may stand for a game command, which is
+	// run in their place.
 	if strings.HasPrefix(input, "/") {
-		output, quit := m.handleMeta(input)
-		m = m.appendOutput(gameOutputMsg{input: input, lines: output, isSystem: true})
-		if quit {
-			m.quitting = true
-			return m, tea.Quit
+		result, command, ok := m.engine.MetaCommand(input)
+		if ok && command == "" {
+			m = m.appendOutput(gameOutputMsg{input: input, lines: result.Output})
+			return m, nil
 		}
-		m.updatePrompt()
+		if !ok {
+			output, quit := m.handleMeta(input)
+			m = m.appendOutput(gameOutputMsg{input: input, lines: output, isSystem: true})
+			if quit {
+				m.stopRecording()
+				m.quitting = true
+				return m, tea.Quit
+			}
+			m.updatePrompt()
+			return m, nil
+		}
+		input = command
+	} else if m.luaMode {
+		m = m.appendOutput(gameOutputMsg{input: input, lines: m.evalLua(input), isSystem: true})
 		return m, nil
 	}
 
-	// Game over input blocking.
-	if state.GetFlag(m.engine.State, "game_over") {
-		m = m.appendOutput(gameOutputMsg{
-			input: input,
-			lines: []string{"Game over. Use /load to restore a save or /quit to exit."},
-		})
-		m.updatePrompt()
-		return m, nil
+	// Commands chained with "and" or "then" each show as if typed alone.
+	for _, command := range m.engine.Commands(input) {
+		output, result := m.stepCommand(command)
+		m = m.appendOutput(gameOutputMsg{input: command, lines: output})
+		m.menu = menu{choices: result.Choices}
+		if m.engine.EndsChain(result) {
+			break
+		}
 	}
+	m.refreshViewport()
+	m.updatePrompt()
+	return m, nil
+}
+
+// runGameCommand steps the engine with a game command and returns its output
+// with any combat, game-over, and trace displays added.
+func (m *Model) runGameCommand(input string) []string {
+	output, _ := m.stepCommand(input)
+	return output
+}
 
-	// Snapshot combat state before step (combat may end during step).
+// stepCommand is runGameCommand, also returning the step's result.
+func (m *Model) stepCommand(input string) ([]string, types.Result) {
+	// Snapshot combat and ending state before step (both may change during step).
+	wasOver := state.GetFlag(m.engine.State, "game_over")
 	wasCombat := state.InCombat(m.engine.State)
 	var preCombatEnemyID string
 	if wasCombat {
@@ -227,31 +482,86 @@ func (m Model) handleEnter() (tea.Model, tea.Cmd) {
 	}
 
 	// Game command.
+	if m.traceLog == nil {
+		m.traceLog = &trace.Memory{}
+		m.engine.Trace = trace.Multi(m.engine.Trace, m.traceLog)
+	}
+	m.traceLog.Take() // drop records of steps run by meta-commands
+	var output []string
+	if m.recorder != nil {
+		if err := m.recorder.Add(input); err != nil {
+			output = append(output, markup.Fail(markup.Escape(fmt.Sprintf("Recording failed: %v", err))))
+			output = append(output, m.stopRecording()...)
+		}
+	}
 	result := m.engine.Step(input)
-	output := result.Output
+	m.lastTrace = m.traceLog.Take()
+	output = append(slices.Clip(result.Output), output...)
+	m.cues.play(result.Cues)
+	m.defended.player, m.defended.enemy = false, false
+	for _, eff := range result.Effects {
+		switch {
+		case eff.Type == "set_defending":
+			m.defended.player = true
+		case eff.Type == "set_prop" && eff.Params["prop"] == "defending" && eff.Params["value"] == true:
+			m.defended.enemy = true
+		}
+	}
+	if len(result.Notifications) > 0 {
+		m.notice = strings.Join(result.Notifications, " · ")
+		m.noticeSeq++
+	}
+	if profile.Unlocked(result.Events) && m.profilePath != "" {
+		p := &profile.Profile{Game: m.defs.Game.Title, Achievements: m.engine.State.Achievements}
+		if err := p.Save(m.profilePath); err != nil {
+			output = append(output, markup.Fail(markup.Escape(fmt.Sprintf("Could not save achievements: %v", err))))
+		}
+	}
+	turn := m.engine.State.TurnCount
+	if turn < m.autosaved {
+		m.autosaved = turn // an earlier save was loaded
+	}
+	if m.autosave > 0 && turn-m.autosaved >= m.autosave {
+		m.autosaved = turn
+		if err := m.writeSave("autosave"); err != nil {
+			output = append(output, markup.Fail(markup.Escape(fmt.Sprintf("Autosave failed: %v", err))))
+		}
+	}
+
+	if m.access != nil {
+		// Accessible mode puts the step in words, without the boxes below.
+		output = append(m.access.Step(result), output[len(result.Output):]...)
+		if m.trace {
+			output = append(output, m.formatTrace()...)
+		}
+		return output, result
+	}
 
-	// Combat display injection.
-	if state.InCombat(m.engine.State) {
-		if box := m.renderCombatStatus(); box != "" {
-			output = append(output, box)
+	// Combat display injection. The player may have died even though the
+	// game isn't over, if the game respawns or restarts them.
+	died := false
+	for _, evt := range result.Events {
+		if evt.Type == "player_defeated" {
+			died = true
 		}
-	} else if wasCombat && !state.GetFlag(m.engine.State, "game_over") {
+	}
+	if wasCombat && !state.InCombat(m.engine.State) && !died && !state.GetFlag(m.engine.State, "game_over") {
 		// Combat just ended with victory — show final result.
 		output = append(output, m.renderVictory(preCombatEnemyID))
 	}
-	if state.GetFlag(m.engine.State, "game_over") {
-		if wasCombat {
-			output = append(output, m.renderDefeat(preCombatEnemyID))
-		}
+	if wasCombat && died {
+		output = append(output, m.renderDefeat(preCombatEnemyID))
+	}
+	if result.Epilogue != nil {
+		output = append(output, m.renderEpilogue(result.Epilogue), "", result.Epilogue.Prompt)
+	} else if state.GetFlag(m.engine.State, "game_over") && !wasOver {
 		output = append(output, m.renderGameOver(preCombatEnemyID))
 	}
 
 	if m.trace {
-		output = append(output, m.formatTrace(result)...)
+		output = append(output, m.formatTrace()...)
 	}
-	m = m.appendOutput(gameOutputMsg{input: input, lines: output})
-	m.updatePrompt()
-	return m, nil
+	return output, result
 }
 
 // appendOutput adds lines to the narrative and refreshes the viewport.
@@ -265,63 +575,79 @@ func (m Model) appendOutput(msg gameOutputMsg) Model {
 	inCombat := state.InCombat(m.engine.State) || state.GetFlag(m.engine.State, "game_over")
 	for _, line := range msg.lines {
 		rl := rawLine{text: line, isSystem: msg.isSystem}
-		if !msg.isSystem {
-			// Detect pre-styled lines (lipgloss bordered boxes contain ANSI escapes).
-			if strings.Contains(line, "\x1b[") {
-				rl.kind = kindPreStyled
-			} else {
-				rl.kind = classifyLine(line)
-				// In combat/game-over context, color plain narrative lines as combat.
-				if inCombat && rl.kind == kindRoomDesc {
-					rl.kind = kindCombat
-				}
+		switch {
+		case msg.isSystem:
+		case strings.Contains(line, "\x1b["):
+			// Pre-styled lines (lipgloss bordered boxes contain ANSI escapes).
+			rl.kind = kindPreStyled
+		default:
+			b := markup.Parse(line)
+			rl.kind, rl.speaker, rl.spans = blockKinds[b.Kind], b.Speaker, b.Spans
+			// In combat/game-over context, color plain narrative lines as combat.
+			if inCombat && rl.kind == kindRoomDesc {
+				rl.kind = kindCombat
 			}
 		}
 		m.rawLines = append(m.rawLines, rl)
 	}
 
-	// Blank line separator between turns.
-	m.rawLines = append(m.rawLines, rawLine{})
+	// Blank line separator between turns, which screen readers would
+	// announce.
+	if m.access == nil {
+		m.rawLines = append(m.rawLines, rawLine{})
+	}
 
 	m.refreshViewport()
 
 	return m
 }
 
-// refreshViewport re-wraps and re-styles all raw lines at the current width
-// and updates the viewport content.
+// layout sizes the viewport to the space left by the combat HUD, the
+// sidebar, the status bar and the input line.
+func (m *Model) layout() {
+	m.viewport.Width = m.narrativeWidth()
+	m.viewport.Height = max(m.height-2-m.hudHeight()-m.menu.height(), 1) // 1 status bar + 1 input line
+}
+
+// refreshViewport lays out the screen, re-wraps and re-styles all raw lines
+// at the current width, and updates the viewport content.
 func (m *Model) refreshViewport() {
 	if !m.ready {
 		return
 	}
+	m.layout()
 
-	width := m.width
+	width := m.narrativeWidth()
 	if width < 10 {
 		width = 10
 	}
 
 	var styled []string
-	for _, rl := range m.rawLines {
-		if rl.text == "" {
-			styled = append(styled, "")
-			continue
-		}
-
-		// Pre-styled lines (lipgloss boxes) skip word-wrap and re-styling.
-		if rl.kind == kindPreStyled {
-			styled = append(styled, rl.text)
-			continue
-		}
-
-		wrapped := wordWrap(rl.text, width)
-
+	m.rows = nil
+	for i, rl := range m.rawLines {
+		var text string
+		var rows []row
 		switch {
+		case rl.text == "":
+		case rl.kind == kindPreStyled:
+			// Pre-styled lines (lipgloss boxes) skip word-wrap and re-styling.
+			text = rl.text
 		case rl.isInput:
-			styled = append(styled, stylePlayerInput.Render(wrapped))
+			text = m.styles.playerInput.Render(wordWrap(rl.text, width))
 		case rl.isSystem:
-			styled = append(styled, styledSystemMsg(wrapped))
+			text = m.styles.systemMsg(wordWrap(rl.text, width))
 		default:
-			styled = append(styled, renderLineKind(wrapped, rl.kind))
+			text, rows = m.styles.renderBlock(rl, width)
+		}
+		styled = append(styled, text)
+
+		for j := 0; j <= strings.Count(text, "\n"); j++ {
+			r := row{line: -1}
+			if j < len(rows) {
+				r = rows[j]
+				r.line = i
+			}
+			m.rows = append(m.rows, r)
 		}
 	}
 
@@ -329,34 +655,6 @@ func (m *Model) refreshViewport() {
 	m.viewport.GotoBottom()
 }
 
-// renderLineKind applies the style for a given lineKind.
-func renderLineKind(line string, kind lineKind) string {
-	switch kind {
-	case kindYouSee:
-		return styledYouSee(line)
-	case kindExits:
-		return styleExits.Render(line)
-	case kindDialogue:
-		return styleDialogue.Render(line)
-	case kindSystem:
-		return styleSystem.Render(line)
-	case kindError:
-		return styleError.Render(line)
-	case kindTrace:
-		return styleTrace.Render(line)
-	case kindCombat:
-		return styleCombat.Render(line)
-	case kindCombatHeader:
-		return styleCombatHeader.Render(line)
-	case kindGameOver:
-		return styleGameOver.Render(line)
-	case kindPreStyled:
-		return line
-	default:
-		return styleRoomDesc.Render(line)
-	}
-}
-
 // wordWrap wraps text to fit within the given width, breaking at word
 // boundaries. Preserves existing newlines within the text.
 func wordWrap(text string, width int) string {
@@ -391,7 +689,8 @@ func wordWrap(text string, width int) string {
 	return result.String()
 }
 
-// View renders the full TUI layout: viewport + status bar + input.
+// View renders the full TUI layout: combat HUD (in a fight) + viewport (and
+// sidebar) + menu of choices (when offered) + status bar + input.
 func (m Model) View() string {
 	if m.quitting {
 		return ""
@@ -400,7 +699,17 @@ func (m Model) View() string {
 		return "Loading..."
 	}
 
-	return m.viewport.View() + "\n" + m.renderStatusBar() + "\n" + m.input.View()
+	narrative := m.viewport.View()
+	if m.sidebarShown() {
+		narrative = lipgloss.JoinHorizontal(lipgloss.Top, narrative, m.renderSidebar(m.viewport.Height))
+	}
+	if hud := m.renderCombatHUD(); hud != "" {
+		narrative = hud + "\n" + narrative
+	}
+	if m.menu.active() {
+		narrative += "\n" + m.menu.render(m.styles)
+	}
+	return narrative + "\n" + m.renderStatusBar() + "\n" + m.input.View()
 }
 
 // handleMeta dispatches meta-commands. Returns output lines and quit flag.
@@ -422,46 +731,140 @@ func (m *Model) handleMeta(input string) ([]string, bool) {
 	case "/load":
 		return m.cmdLoad(arg), false
 
+	case "/export-save":
+		return m.cmdExportSave(), false
+
+	case "/import-save":
+		return m.cmdImportSave(strings.TrimPrefix(input, cmd)), false
+
+	case "/mark":
+		return m.cmdMark(arg), false
+
+	case "/rewind":
+		return m.cmdRewind(arg), false
+
+	case "/record":
+		return m.cmdRecord(arg), false
+
+	case "/stats":
+		return m.engine.StatsReport(), false
+
 	case "/help":
 		return m.cmdHelp(), false
 
 	case "/state":
 		return m.cmdState(), false
 
+	case "/exec":
+		return m.cmdExec(strings.TrimSpace(strings.TrimPrefix(input, cmd)))
+
 	case "/trace":
-		m.trace = !m.trace
-		if m.trace {
-			return []string{"Trace output enabled."}, false
-		}
-		return []string{"Trace output disabled."}, false
+		return m.cmdTrace(arg), false
+
+	case "/lua":
+		return m.cmdLua(strings.TrimSpace(strings.TrimPrefix(input, cmd))), false
+
+	case "/why":
+		return trace.Why(m.lastTrace), false
+
+	case "/rules":
+		return m.cmdRules(strings.TrimSpace(strings.TrimPrefix(input, cmd))), false
 
 	default:
-		return []string{fmt.Sprintf("Unknown command: %s. Type /help for available commands.", cmd)}, false
+		// The game's own meta-commands, when run from an /exec file.
+		result, command, ok := m.engine.MetaCommand(input)
+		switch {
+		case !ok:
+			return []string{fmt.Sprintf("Unknown command: %s. Type /help for available commands.", cmd)}, false
+		case command != "":
+			return plainLines(m.runGameCommand(command)), false
+		default:
+			return plainLines(result.Output), false
+		}
 	}
 }
 
-func (m *Model) cmdSave(name string) []string {
-	if name == "" {
-		name = "quicksave"
+// maxExecDepth limits how deeply /exec files may include each other.
+const maxExecDepth = 8
+
+// cmdExec runs each line of a command file as if it had been typed, and
+// returns the echoed commands with their output. Blank lines and # comments
+// are skipped. The quit flag is set if the file issued /quit.
+func (m *Model) cmdExec(path string) ([]string, bool) {
+	if path == "" {
+		return []string{"Usage: /exec <file>"}, false
+	}
+	if m.execDepth >= maxExecDepth {
+		return []string{fmt.Sprintf("Exec failed: %s: files nested too deeply.", path)}, false
 	}
 
-	data, err := save.Save(m.engine.State, m.defs)
+	data, err := os.ReadFile(path)
 	if err != nil {
-		return []string{fmt.Sprintf("Save failed: %v", err)}
+		return []string{fmt.Sprintf("Exec failed: %v", err)}, false
 	}
 
-	if err := os.MkdirAll(m.saveDir, 0o755); err != nil {
-		return []string{fmt.Sprintf("Save failed: %v", err)}
+	m.execDepth++
+	defer func() { m.execDepth-- }()
+	var output []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		output = append(output, "> "+line)
+		if strings.HasPrefix(line, "/") {
+			lines, quit := m.handleMeta(line)
+			output = append(output, lines...)
+			if quit {
+				return output, true
+			}
+			continue
+		}
+
+		lower := strings.ToLower(line)
+		if lower == "again" || lower == "g" {
+			if m.lastCmd == "" {
+				output = append(output, "Nothing to repeat.")
+				continue
+			}
+			line = m.lastCmd
+		} else {
+			m.lastCmd = line
+		}
+		for _, command := range m.engine.Commands(line) {
+			lines, result := m.stepCommand(command)
+			output = append(output, plainLines(lines)...)
+			if m.engine.EndsChain(result) {
+				break
+			}
+		}
+	}
+	return output, false
+}
+
+func (m *Model) cmdSave(name string) []string {
+	if name == "" {
+		name = "quicksave"
 	}
 
-	path := filepath.Join(m.saveDir, name+".json")
-	if err := os.WriteFile(path, data, 0o644); err != nil {
+	if err := m.writeSave(name); err != nil {
 		return []string{fmt.Sprintf("Save failed: %v", err)}
 	}
-
 	return []string{fmt.Sprintf("Game saved to %s.", name)}
 }
 
+// writeSave saves the game to the slot name in the save directory.
+func (m *Model) writeSave(name string) error {
+	data, err := save.Save(m.engine.State, m.defs)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(m.saveDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.saveDir, name+".json"), data, 0o644)
+}
+
 func (m *Model) cmdLoad(name string) []string {
 	if name == "" {
 		name = "quicksave"
@@ -474,28 +877,159 @@ func (m *Model) cmdLoad(name string) []string {
 	}
 
 	sd, err := save.Load(data)
+	if err == nil {
+		err = sd.CheckGame(m.defs)
+	}
 	if err != nil {
 		return []string{fmt.Sprintf("Load failed: %v", err)}
 	}
 
 	save.ApplySave(m.engine.State, sd)
 	m.engine.RestoreRNG(sd.RNGSeed, sd.RNGPosition)
+	m.cues.play([]types.Cue{{Type: "music", Name: sd.Music}})
+	if m.access != nil {
+		m.access.Sync()
+	}
 
 	output := []string{fmt.Sprintf("Game loaded from %s (turn %d).", name, sd.Turn)}
-	result := m.engine.Step("look")
-	output = append(output, result.Output...)
+	output = append(output, plainLines(m.engine.Look().Output)...)
+	return output
+}
+
+func (m *Model) cmdExportSave() []string {
+	code, err := save.Export(m.engine.State, m.defs)
+	if err != nil {
+		return []string{fmt.Sprintf("Export failed: %v", err)}
+	}
+	return []string{"Save code (restore with /import-save <code>):", code}
+}
+
+func (m *Model) cmdImportSave(code string) []string {
+	if strings.TrimSpace(code) == "" {
+		return []string{"Usage: /import-save <code>"}
+	}
+	sd, err := save.Import(code)
+	if err == nil {
+		err = sd.CheckGame(m.defs)
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("Import failed: %v", err)}
+	}
+
+	save.ApplySave(m.engine.State, sd)
+	m.engine.RestoreRNG(sd.RNGSeed, sd.RNGPosition)
+	m.cues.play([]types.Cue{{Type: "music", Name: sd.Music}})
+	if m.access != nil {
+		m.access.Sync()
+	}
+
+	output := []string{fmt.Sprintf("Save code imported (turn %d).", sd.Turn)}
+	output = append(output, plainLines(m.engine.Look().Output)...)
+	return output
+}
+
+// cmdRecord starts writing the game commands played to the script file
+// path, stops with "off", or says where they are going.
+func (m *Model) cmdRecord(path string) []string {
+	switch path {
+	case "":
+		if m.recorder == nil {
+			return []string{"Not recording. Usage: /record <file>, /record off"}
+		}
+		return []string{fmt.Sprintf("Recording to %s (%d commands).", m.recorder.Path(), m.recorder.Count())}
+	case "off":
+		if m.recorder == nil {
+			return []string{"Not recording."}
+		}
+		return m.stopRecording()
+	default:
+		output := m.stopRecording()
+		r, err := history.Record(path, m.engine)
+		if err != nil {
+			return append(output, fmt.Sprintf("Could not record: %v", err))
+		}
+		m.recorder = r
+		return append(output, fmt.Sprintf("Recording to %s (%d commands so far).", path, r.Count()))
+	}
+}
+
+// stopRecording closes the /record script, if one is open, and says so.
+func (m *Model) stopRecording() []string {
+	if m.recorder == nil {
+		return nil
+	}
+	r := m.recorder
+	m.recorder = nil
+	if err := r.Close(); err != nil {
+		return []string{fmt.Sprintf("Recording failed: %v", err)}
+	}
+	return []string{fmt.Sprintf("Recorded %d commands to %s.", r.Count(), r.Path())}
+}
+
+// cmdMark remembers the game as it is now under name, for /rewind. Marks
+// live in memory only and are lost on quitting.
+func (m *Model) cmdMark(name string) []string {
+	if name == "" {
+		return []string{"Usage: /mark <name>"}
+	}
+	if m.marks == nil {
+		m.marks = map[string]*types.State{}
+	}
+	m.marks[name] = m.engine.Snapshot()
+	return []string{fmt.Sprintf("Marked %s (turn %d).", name, m.engine.State.TurnCount)}
+}
+
+// cmdRewind returns the game to the mark called name. Achievements unlocked
+// since are kept, as they are by /load.
+func (m *Model) cmdRewind(name string) []string {
+	snap, ok := m.marks[name]
+	if !ok {
+		msg := "Usage: /rewind <name>"
+		if name != "" {
+			msg = fmt.Sprintf("No mark called %s.", name)
+		}
+		if len(m.marks) == 0 {
+			return []string{msg + " Set one with /mark <name>."}
+		}
+		return []string{msg + " Marks: " + strings.Join(slices.Sorted(maps.Keys(m.marks)), ", ") + "."}
+	}
+	achievements := m.engine.State.Achievements
+	m.engine.Restore(snap)
+	m.engine.State.Achievements = achievements
+	m.cues.play([]types.Cue{{Type: "music", Name: snap.Music}})
+	if m.access != nil {
+		m.access.Sync()
+	}
+
+	output := []string{fmt.Sprintf("Rewound to %s (turn %d).", name, snap.TurnCount)}
+	output = append(output, plainLines(m.engine.Look().Output)...)
 	return output
 }
 
 func (m *Model) cmdHelp() []string {
-	return []string{
+	help := []string{
 		"System:",
 		"  /save [name]  — Save game (default: quicksave)",
 		"  /load [name]  — Load game (default: quicksave)",
+		"  /export-save  — Print the game as a code to copy",
+		"  /import-save <code> — Restore a game from a code",
+		"  /mark <name>  — Remember this moment, in memory only",
+		"  /rewind <name> — Return to a mark",
+		"  /stats        — Show statistics for this session and game",
+		"  /record <file> — Write the commands you play to a --script file",
+		"  /record off   — Stop recording",
 		"  /quit         — Exit game",
 		"  /help         — Show this help",
 		"  /state        — Debug: dump current state",
-		"  /trace        — Toggle debug trace output",
+		"  /trace [subsystems] — Toggle debug trace output, or show only",
+		"                  some of parse,resolve,rules,effects,events",
+		"  /why          — Debug: explain how the last command was handled",
+		"  /lua [code]   — Debug: run Lua against the game (with --dev)",
+		"  /rules [verb|thing] — Debug: list the rules that can apply here",
+		"  /exec <file>  — Run commands from a file",
+	}
+	help = append(help, m.engine.MetaCommandHelp()...)
+	return append(help,
 		"",
 		"Game commands:",
 		"  look (l)              — Describe the room",
@@ -510,6 +1044,8 @@ func (m *Model) cmdHelp() []string {
 		"  give <item> to <npc>  — Give an item to someone",
 		"  inventory (i)         — Check what you're carrying",
 		"  wait (z)              — Let time pass",
+		"  verbose / brief       — Room text every visit / first visit only",
+		"  superbrief            — Never show room text when moving",
 		"  again (g)             — Repeat your last command",
 		"",
 		"Combat:",
@@ -517,8 +1053,12 @@ func (m *Model) cmdHelp() []string {
 		"  defend                — Defend (reduces damage taken)",
 		"  flee                  — Attempt to flee combat",
 		"",
-		"Navigation: PgUp/PgDn to scroll, Up/Down for command history",
-	}
+		"Navigation: PgUp/PgDn to scroll, Up/Down for command history,",
+		"  Tab to complete a verb or name (again for the next match),",
+		"  Tab on an empty line to show or hide the sidebar,",
+		"  click an exit to go there or a name to examine it,",
+		"  Up/Down and Enter (or a number) to pick from offered choices",
+	)
 }
 
 func (m *Model) cmdState() []string {
@@ -543,35 +1083,104 @@ func (m *Model) cmdState() []string {
 	return output
 }
 
-func (m *Model) formatTrace(result types.Result) []string {
-	var lines []string
-	if len(result.Effects) > 0 {
-		lines = append(lines, fmt.Sprintf("[trace] Effects: %d", len(result.Effects)))
-		for _, e := range result.Effects {
-			lines = append(lines, fmt.Sprintf("[trace]   %s %v", e.Type, e.Params))
+// cmdTrace toggles trace output, or with a list of subsystems
+// ("rules,effects", or "all") turns it on for those.
+func (m *Model) cmdTrace(list string) []string {
+	if list == "" {
+		m.trace = !m.trace
+	} else {
+		only, err := trace.ParseSubsystems(list)
+		if err != nil {
+			return []string{err.Error()}
 		}
+		m.trace, m.traceOnly = true, only
+	}
+	if m.trace {
+		return []string{"Trace output enabled."}
 	}
-	if len(result.Events) > 0 {
-		lines = append(lines, fmt.Sprintf("[trace] Events: %d", len(result.Events)))
-		for _, e := range result.Events {
-			lines = append(lines, fmt.Sprintf("[trace]   %s", e.Type))
+	return []string{"Trace output disabled."}
+}
+
+// cmdRules lists the rules that can apply here, for query; see
+// Engine.ApplicableRules.
+func (m *Model) cmdRules(query string) []string {
+	entries, err := m.engine.ApplicableRules(query)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	about := ""
+	if query != "" {
+		about = " for " + query
+	}
+	if len(entries) == 0 {
+		return []string{"No rules" + about + " here."}
+	}
+	lines := []string{"Rules" + about + " here:"}
+	for _, e := range entries {
+		lines = append(lines, "  "+e.String())
+	}
+	return lines
+}
+
+// formatTrace lists the trace records of the last command as asides.
+func (m *Model) formatTrace() []string {
+	filter := trace.Filter{Level: trace.Debug, Subsystems: m.traceOnly}
+	var lines []string
+	for _, e := range m.lastTrace {
+		if filter.Allows(e) {
+			lines = append(lines, markup.Note(markup.Escape(e.String())))
 		}
 	}
 	return lines
 }
 
+// cmdLua runs code in the Lua console, or without code switches to and
+// from Lua mode, where every line that isn't a meta-command is Lua.
+func (m *Model) cmdLua(code string) []string {
+	switch {
+	case m.console == nil:
+		return []string{"The Lua console is for authors: start the game with --dev."}
+	case code != "":
+		return m.evalLua(code)
+	case m.luaMode:
+		m.luaMode = false
+		return []string{"Back to the game."}
+	}
+	m.luaMode = true
+	return []string{"Lua console: type Lua to inspect or change the game, /lua to return."}
+}
+
+// evalLua runs code in the Lua console and returns what it gave.
+func (m *Model) evalLua(code string) []string {
+	out, err := m.console.Eval(code)
+	var lines []string
+	if out != "" {
+		lines = strings.Split(markup.Escape(out), "\n")
+	}
+	if err != nil {
+		lines = append(lines, markup.Fail(markup.Escape(fmt.Sprintf("Lua error: %v", err))))
+	}
+	if m.access != nil {
+		m.access.Sync()
+	}
+	return lines
+}
+
 // updatePrompt sets the input prompt based on game state.
 func (m *Model) updatePrompt() {
 	switch {
+	case m.luaMode:
+		m.input.Prompt = "lua> "
+		m.input.PromptStyle = m.styles.inputPrompt
 	case state.GetFlag(m.engine.State, "game_over"):
-		m.input.Prompt = "/load or /quit> "
-		m.input.PromptStyle = styleGameOverPrompt
+		m.input.Prompt = "restart, restore, or /quit> "
+		m.input.PromptStyle = m.styles.gameOverPrompt
 	case state.InCombat(m.engine.State):
 		m.input.Prompt = "What do you do? (attack, defend, use <item>, flee) "
-		m.input.PromptStyle = styleCombatPrompt
+		m.input.PromptStyle = m.styles.combatPrompt
 	default:
 		m.input.Prompt = "> "
-		m.input.PromptStyle = styleInputPrompt
+		m.input.PromptStyle = m.styles.inputPrompt
 	}
 }
 
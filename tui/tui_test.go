@@ -1,11 +1,23 @@
 package tui
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/nathoo/questcore/access"
+	"github.com/nathoo/questcore/console"
 	"github.com/nathoo/questcore/engine"
+	"github.com/nathoo/questcore/engine/markup"
 	"github.com/nathoo/questcore/engine/state"
+	"github.com/nathoo/questcore/theme"
 	"github.com/nathoo/questcore/types"
 )
 
@@ -28,47 +40,49 @@ func TestRoomDisplayName(t *testing.T) {
 	}
 }
 
-func TestClassifyLine(t *testing.T) {
-	tests := []struct {
-		line string
-		want lineKind
-	}{
-		{"You see: rusty key, old book.", kindYouSee},
-		{"Exits: north, south, east.", kindExits},
-		{"[Game saved to test.]", kindSystem},
-		{"[trace] Effects: 2", kindTrace},
-		{"You don't see that here.", kindError},
-		{"You can't go that way.", kindError},
-		{"You don't have that.", kindError},
-		{"A grand hall with stone walls.", kindRoomDesc},
-		{"Taken.", kindRoomDesc},
-		{"", kindRoomDesc},
-		{"'Ah, the adventurer. I wondered when they'd send someone competent.'", kindDialogue},
-	}
-	for _, tt := range tests {
-		got := classifyLine(tt.line)
-		if got != tt.want {
-			t.Errorf("classifyLine(%q) = %v, want %v", tt.line, got, tt.want)
+func TestAppendOutput_KindsFromMarkup(t *testing.T) {
+	m := New(engine.New(testDefs()), testDefs(), theme.Default())
+	m = m.appendOutput(gameOutputMsg{lines: []string{
+		"A grand hall.",
+		"You see: *rusty key*.",
+		"~ Exits: north.",
+		"! You can't go that way.",
+		"@Scholar: 'Welcome.'",
+		"# Chapter One",
+		"> Carved in stone.",
+	}})
+	want := []lineKind{kindRoomDesc, kindRoomDesc, kindAside, kindError, kindDialogue, kindHeader, kindQuote}
+	for i, kind := range want {
+		if got := m.rawLines[i].kind; got != kind {
+			t.Errorf("line %d (%q) kind = %v, want %v", i, m.rawLines[i].text, got, kind)
 		}
 	}
+	if got := m.rawLines[4].speaker; got != "Scholar" {
+		t.Errorf("dialogue speaker = %q, want Scholar", got)
+	}
+	if spans := m.rawLines[1].spans; len(spans) != 3 || !spans[1].Bold || spans[1].Text != "rusty key" {
+		t.Errorf("contents spans = %v, want the key in bold", spans)
+	}
 }
 
-func TestContainsQuotedSpeech(t *testing.T) {
-	tests := []struct {
-		line string
-		want bool
-	}{
-		{"'Hello, adventurer. Welcome to the castle.'", true},
-		{"It's a door.", false},    // short quote segment
-		{"No quotes here.", false}, // no quotes at all
-		{"'Hi'", false},            // too short
-		{"She says 'the crown is lost forever, you must find it.'", true},
-	}
-	for _, tt := range tests {
-		got := containsQuotedSpeech(tt.line)
-		if got != tt.want {
-			t.Errorf("containsQuotedSpeech(%q) = %v, want %v", tt.line, got, tt.want)
+func TestWrapSpans(t *testing.T) {
+	spans := markup.Parse("You see: *rusty key*, *old book*.").Spans
+	lines := wrapSpans(spans, 14)
+	var got []string
+	for _, line := range lines {
+		var sb strings.Builder
+		for _, span := range line {
+			if span.Bold {
+				sb.WriteString("*" + span.Text + "*")
+			} else {
+				sb.WriteString(span.Text)
+			}
 		}
+		got = append(got, sb.String())
+	}
+	want := []string{"You see: *rusty*", "*key*, *old* *book*."}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("wrapSpans = %q, want %q", got, want)
 	}
 }
 
@@ -94,112 +108,6 @@ func TestWordWrap(t *testing.T) {
 	}
 }
 
-func TestHistory_PushAndPrev(t *testing.T) {
-	h := NewHistory(5)
-	h.Push("look")
-	h.Push("go north")
-	h.Push("take key")
-
-	prev, ok := h.Prev()
-	if !ok || prev != "take key" {
-		t.Errorf("expected 'take key', got %q (ok=%v)", prev, ok)
-	}
-
-	prev, ok = h.Prev()
-	if !ok || prev != "go north" {
-		t.Errorf("expected 'go north', got %q (ok=%v)", prev, ok)
-	}
-
-	prev, ok = h.Prev()
-	if !ok || prev != "look" {
-		t.Errorf("expected 'look', got %q (ok=%v)", prev, ok)
-	}
-
-	// At oldest, stays there.
-	prev, ok = h.Prev()
-	if !ok || prev != "look" {
-		t.Errorf("expected 'look' at boundary, got %q (ok=%v)", prev, ok)
-	}
-}
-
-func TestHistory_Next(t *testing.T) {
-	h := NewHistory(5)
-	h.Push("look")
-	h.Push("go north")
-
-	h.Prev() // "go north"
-	h.Prev() // "look"
-
-	next, ok := h.Next()
-	if !ok || next != "go north" {
-		t.Errorf("expected 'go north', got %q (ok=%v)", next, ok)
-	}
-
-	_, ok = h.Next()
-	if ok {
-		t.Error("expected false when past newest entry")
-	}
-}
-
-func TestHistory_Empty(t *testing.T) {
-	h := NewHistory(5)
-	_, ok := h.Prev()
-	if ok {
-		t.Error("expected false on empty history")
-	}
-	_, ok = h.Next()
-	if ok {
-		t.Error("expected false on empty history")
-	}
-}
-
-func TestHistory_MaxSize(t *testing.T) {
-	h := NewHistory(2)
-	h.Push("a")
-	h.Push("b")
-	h.Push("c") // "a" evicted
-
-	prev, _ := h.Prev()
-	if prev != "c" {
-		t.Errorf("expected 'c', got %q", prev)
-	}
-	prev, _ = h.Prev()
-	if prev != "b" {
-		t.Errorf("expected 'b', got %q", prev)
-	}
-	// "a" is gone.
-	prev, _ = h.Prev()
-	if prev != "b" {
-		t.Errorf("expected 'b' at boundary, got %q", prev)
-	}
-}
-
-func TestHistory_NoDuplicates(t *testing.T) {
-	h := NewHistory(5)
-	h.Push("look")
-	h.Push("look") // skipped
-	h.Push("look") // skipped
-
-	if len(h.entries) != 1 {
-		t.Errorf("expected 1 entry, got %d", len(h.entries))
-	}
-}
-
-func TestHistory_ResetCursor(t *testing.T) {
-	h := NewHistory(5)
-	h.Push("look")
-	h.Push("go north")
-
-	h.Prev() // "go north"
-	h.ResetCursor()
-
-	// After reset, Prev starts from the end again.
-	prev, ok := h.Prev()
-	if !ok || prev != "go north" {
-		t.Errorf("expected 'go north' after reset, got %q", prev)
-	}
-}
-
 // testDefs returns minimal game definitions for TUI testing.
 func testDefs() *state.Defs {
 	return &state.Defs{
@@ -283,7 +191,7 @@ func TestInjectBorderTitle(t *testing.T) {
 func TestHandleMeta_Quit(t *testing.T) {
 	defs := testDefs()
 	eng := engine.New(defs)
-	m := New(eng, defs)
+	m := New(eng, defs, theme.Default())
 
 	_, quit := m.handleMeta("/quit")
 	if !quit {
@@ -299,7 +207,7 @@ func TestHandleMeta_Quit(t *testing.T) {
 func TestHandleMeta_Save(t *testing.T) {
 	defs := testDefs()
 	eng := engine.New(defs)
-	m := New(eng, defs)
+	m := New(eng, defs, theme.Default())
 	m.saveDir = t.TempDir()
 
 	output, quit := m.handleMeta("/save test")
@@ -314,7 +222,7 @@ func TestHandleMeta_Save(t *testing.T) {
 func TestHandleMeta_LoadNonexistent(t *testing.T) {
 	defs := testDefs()
 	eng := engine.New(defs)
-	m := New(eng, defs)
+	m := New(eng, defs, theme.Default())
 	m.saveDir = t.TempDir()
 
 	output, quit := m.handleMeta("/load nonexistent")
@@ -329,7 +237,7 @@ func TestHandleMeta_LoadNonexistent(t *testing.T) {
 func TestHandleMeta_Help(t *testing.T) {
 	defs := testDefs()
 	eng := engine.New(defs)
-	m := New(eng, defs)
+	m := New(eng, defs, theme.Default())
 
 	output, quit := m.handleMeta("/help")
 	if quit {
@@ -347,7 +255,7 @@ func TestHandleMeta_Help(t *testing.T) {
 func TestHandleMeta_Trace(t *testing.T) {
 	defs := testDefs()
 	eng := engine.New(defs)
-	m := New(eng, defs)
+	m := New(eng, defs, theme.Default())
 
 	output, _ := m.handleMeta("/trace")
 	if !m.trace {
@@ -366,10 +274,25 @@ func TestHandleMeta_Trace(t *testing.T) {
 	}
 }
 
+func TestRunGameCommand_TraceSubsystems(t *testing.T) {
+	defs := testDefs()
+	eng := engine.New(defs)
+	m := New(eng, defs, theme.Default())
+
+	m.handleMeta("/trace rules")
+	output := strings.Join(m.runGameCommand("north"), "\n")
+	if !strings.Contains(output, "[trace] rules: handled by the built-in go") {
+		t.Errorf("expected the rules traced, got:\n%s", output)
+	}
+	if strings.Contains(output, "[trace] parse:") {
+		t.Errorf("expected only the rules traced, got:\n%s", output)
+	}
+}
+
 func TestHandleMeta_Unknown(t *testing.T) {
 	defs := testDefs()
 	eng := engine.New(defs)
-	m := New(eng, defs)
+	m := New(eng, defs, theme.Default())
 
 	output, quit := m.handleMeta("/bogus")
 	if quit {
@@ -383,7 +306,7 @@ func TestHandleMeta_Unknown(t *testing.T) {
 func TestHandleMeta_State(t *testing.T) {
 	defs := testDefs()
 	eng := engine.New(defs)
-	m := New(eng, defs)
+	m := New(eng, defs, theme.Default())
 
 	output, quit := m.handleMeta("/state")
 	if quit {
@@ -398,3 +321,545 @@ func TestHandleMeta_State(t *testing.T) {
 		t.Error("expected turn count in state output")
 	}
 }
+
+func TestHandleMeta_Exec(t *testing.T) {
+	defs := testDefs()
+	eng := engine.New(defs)
+	m := New(eng, defs, theme.Default())
+
+	script := filepath.Join(t.TempDir(), "setup.txt")
+	os.WriteFile(script, []byte("# setup\ntake key\nnorth\n"), 0o644)
+
+	output, quit := m.handleMeta("/exec " + script)
+	if quit {
+		t.Error("expected quit=false for /exec")
+	}
+	joined := strings.Join(output, "\n")
+	for _, want := range []string{"> take key", "> north", "peaceful garden"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("expected %q in output, got %v", want, output)
+		}
+	}
+	if eng.State.Player.Location != "garden" {
+		t.Errorf("location = %q, want garden", eng.State.Player.Location)
+	}
+}
+
+func TestIdleNudge_OnlyLatestTimerCounts(t *testing.T) {
+	defs := testDefs()
+	defs.Game.IdleNudge = &types.IdleNudgeDef{
+		Minutes: 2,
+		Nudges:  []types.NudgeDef{{Text: "Try the key."}},
+	}
+	eng := engine.New(defs)
+	m := New(eng, defs, theme.Default())
+	m.idleSeq = 3
+
+	model, _ := m.Update(idleMsg{seq: 2})
+	if got := len(model.(Model).rawLines); got != 0 {
+		t.Fatalf("stale idle timer added %d lines", got)
+	}
+
+	model, _ = m.Update(idleMsg{seq: 3})
+	lines := model.(Model).rawLines
+	if len(lines) == 0 || lines[0].text != "Try the key." || !lines[0].isSystem {
+		t.Errorf("rawLines = %+v, want the nudge as a system line", lines)
+	}
+	if eng.State.TurnCount != 0 {
+		t.Errorf("TurnCount = %d, nudge should not take a turn", eng.State.TurnCount)
+	}
+}
+
+func TestIdleTimer_DisabledByDefault(t *testing.T) {
+	defs := testDefs()
+	m := New(engine.New(defs), defs, theme.Default())
+	if m.idleTimer() != nil {
+		t.Error("expected no idle timer without idle_nudge")
+	}
+}
+
+func TestHandleMeta_ExportImportSave(t *testing.T) {
+	defs := testDefs()
+	eng := engine.New(defs)
+	m := New(eng, defs, theme.Default())
+	eng.Step("north")
+
+	output, _ := m.handleMeta("/export-save")
+	if len(output) != 2 {
+		t.Fatalf("expected header and code, got %v", output)
+	}
+	code := output[1]
+
+	eng2 := engine.New(defs)
+	m2 := New(eng2, defs, theme.Default())
+	output, _ = m2.handleMeta("/import-save " + code)
+	if len(output) == 0 || !strings.Contains(output[0], "Save code imported") {
+		t.Errorf("expected import confirmation, got %v", output)
+	}
+	if eng2.State.Player.Location != "garden" {
+		t.Errorf("location = %q, want garden", eng2.State.Player.Location)
+	}
+}
+
+func TestNotice_FlashedAndCleared(t *testing.T) {
+	defs := testDefs()
+	defs.GlobalRules = append(defs.GlobalRules, types.RuleDef{
+		ID: "sing", Scope: "global",
+		When: types.MatchCriteria{Verb: "sing"},
+		Effects: []types.Effect{
+			{Type: "say", Params: map[string]any{"text": "You sing a verse."}},
+			{Type: "notify", Params: map[string]any{"text": "Achievement: Bard"}},
+		},
+	})
+	m := New(engine.New(defs), defs, theme.Default())
+	m.width = 80
+
+	output := m.runGameCommand("sing")
+	for _, line := range output {
+		if strings.Contains(line, "Achievement") {
+			t.Errorf("notification leaked into narrative: %v", output)
+		}
+	}
+	if m.notice != "Achievement: Bard" || m.noticeSeq != 1 {
+		t.Fatalf("notice = %q (seq %d)", m.notice, m.noticeSeq)
+	}
+	if bar := m.renderStatusBar(); !strings.Contains(bar, "Achievement: Bard") {
+		t.Errorf("status bar = %q, want the notice", bar)
+	}
+
+	model, _ := m.Update(noticeMsg{seq: 0})
+	if model.(Model).notice == "" {
+		t.Error("stale timer cleared the notice")
+	}
+	model, _ = m.Update(noticeMsg{seq: 1})
+	if model.(Model).notice != "" {
+		t.Error("expected notice to be cleared")
+	}
+}
+
+func TestCuePlayer(t *testing.T) {
+	if p := newCuePlayer(""); p != nil {
+		t.Errorf("expected no player when sound is off, got %+v", p)
+	}
+	var nilPlayer *cuePlayer
+	nilPlayer.play([]types.Cue{{Type: "sound", Name: "bell"}}) // must not panic
+
+	cues := []types.Cue{{Type: "sound", Name: "door_creak"}, {Type: "music", Name: "dungeon"}}
+
+	var bell strings.Builder
+	(&cuePlayer{bell: &bell}).play(cues)
+	if bell.String() != "\a" {
+		t.Errorf("bell mode wrote %q, want one bell for the sound", bell.String())
+	}
+
+	var ran [][]string
+	p := newCuePlayer("qc-play --quiet")
+	p.start = func(cmd *exec.Cmd) error {
+		ran = append(ran, cmd.Args)
+		return nil
+	}
+	p.play(cues)
+	want := [][]string{
+		{"qc-play", "--quiet", "sound", "door_creak"},
+		{"qc-play", "--quiet", "music", "dungeon"},
+	}
+	if !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+}
+
+func TestRenderMap(t *testing.T) {
+	defs := testDefs()
+	defs.Rooms["hall"] = types.RoomDef{ID: "hall", Exits: map[string]string{"north": "garden", "east": "kitchen", "up": "attic"}}
+	defs.Rooms["garden"] = types.RoomDef{ID: "garden", Exits: map[string]string{"south": "hall", "southeast": "kitchen"}}
+	defs.Rooms["kitchen"] = types.RoomDef{ID: "kitchen", Exits: map[string]string{"west": "hall", "east": "cellar"}}
+	defs.Rooms["cellar"] = types.RoomDef{ID: "cellar", Exits: map[string]string{"west": "kitchen"}}
+	defs.Rooms["attic"] = types.RoomDef{ID: "attic", Exits: map[string]string{"down": "hall"}}
+	s := state.NewState(defs)
+	s.Visited = map[string]bool{"hall": true, "garden": true, "kitchen": true, "attic": true}
+
+	// The unvisited cellar and the attic above are left off the map.
+	got := renderMap(s, defs, 9, 5)
+	want := []string{
+		"    o",
+		"    |\\",
+		"    @-o",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("renderMap =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestSidebar_ToggleNarrowsNarrative(t *testing.T) {
+	defs := testDefs()
+	defs.Game.Objectives = []types.ObjectiveDef{{Text: "Find the key."}}
+	m := New(engine.New(defs), defs, theme.Default())
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	m = next.(Model)
+	if m.sidebarShown() || m.viewport.Width != 100 {
+		t.Fatalf("sidebar should start hidden, viewport width %d", m.viewport.Width)
+	}
+
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = next.(Model)
+	if !m.sidebarShown() || m.viewport.Width != 100-sidebarWidth {
+		t.Fatalf("after tab: shown %v, viewport width %d", m.sidebarShown(), m.viewport.Width)
+	}
+	view := m.View()
+	for _, want := range []string{"Hall", "Exits: north", "Inventory", "Objective", "Find the key."} {
+		if !strings.Contains(view, want) {
+			t.Errorf("sidebar missing %q", want)
+		}
+	}
+
+	// Too narrow a terminal hides it again.
+	next, _ = m.Update(tea.WindowSizeMsg{Width: 60, Height: 30})
+	if m = next.(Model); m.sidebarShown() || m.viewport.Width != 60 {
+		t.Errorf("narrow terminal: shown %v, viewport width %d", m.sidebarShown(), m.viewport.Width)
+	}
+}
+
+func TestNewCompletion(t *testing.T) {
+	verbs := []string{"examine", "exit", "take", "talk"}
+	names := []string{"key", "rusty key", "rusty sword", "sword"}
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"ex", []string{"examine", "exit"}},
+		{"Ta", []string{"take", "talk"}},
+		{"take k", []string{"take key"}},
+		{"take rusty s", []string{"take rusty sword"}},
+		{"take rusty", []string{"take rusty key", "take rusty sword"}},
+		{"use key on sw", []string{"use key on sword"}},
+		{"take ", []string{"take key", "take rusty key", "take rusty sword", "take sword"}},
+		{"take lamp", nil},
+		{"dance", nil},
+	}
+	for _, tt := range tests {
+		c := newCompletion(tt.input, verbs, names)
+		var got []string
+		for i := range c.candidates {
+			c.index = i
+			got = append(got, c.value())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("newCompletion(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestTab_CompletesAndCycles(t *testing.T) {
+	defs := testDefs()
+	defs.Entities["kettle"] = types.EntityDef{ID: "kettle", Kind: "item", Props: map[string]any{
+		"name": "kettle", "location": "hall",
+	}}
+	m := New(engine.New(defs), defs, theme.Default())
+	tab := func() {
+		next, _ := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+		m = next.(Model)
+	}
+
+	m.input.SetValue("take k")
+	tab()
+	if got := m.input.Value(); got != "take kettle" {
+		t.Fatalf("first tab: %q", got)
+	}
+	tab()
+	if got := m.input.Value(); got != "take key" {
+		t.Errorf("second tab: %q", got)
+	}
+	tab()
+	if got := m.input.Value(); got != "take kettle" {
+		t.Errorf("third tab should wrap around: %q", got)
+	}
+	if m.sidebar {
+		t.Error("completing should not toggle the sidebar")
+	}
+
+	m.input.SetValue("")
+	tab()
+	if !m.sidebar {
+		t.Error("tab on an empty input should toggle the sidebar")
+	}
+}
+
+func TestMenu_PicksChoiceWithKeys(t *testing.T) {
+	defs := testDefs()
+	m := New(engine.New(defs), defs, theme.Default())
+	press := func(msg tea.KeyMsg) {
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+
+	m.input.SetValue("restart")
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.menu.active() || m.menu.choices[0].Command != "yes" {
+		t.Fatalf("menu = %+v, want yes/no", m.menu)
+	}
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	press(tea.KeyMsg{Type: tea.KeyDown})
+	if m.menu.cursor != 0 {
+		t.Errorf("cursor = %d, want 0 after wrapping around the end", m.menu.cursor)
+	}
+	press(tea.KeyMsg{Type: tea.KeyUp})
+	if m.menu.cursor != 1 {
+		t.Errorf("cursor = %d, want 1 after wrapping around the start", m.menu.cursor)
+	}
+	press(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")})
+	if m.menu.active() {
+		t.Error("menu still up after a pick")
+	}
+	if m.engine.State.Pending != "" {
+		t.Errorf("Pending = %q, want the restart answered", m.engine.State.Pending)
+	}
+	if last := m.rawLines[len(m.rawLines)-2].text; last != "Okay, carrying on." {
+		t.Errorf("last line = %q, want the restart declined", last)
+	}
+
+	m.input.SetValue("restart")
+	press(tea.KeyMsg{Type: tea.KeyEnter})
+	press(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.menu.active() {
+		t.Error("Esc should dismiss the menu")
+	}
+}
+
+func TestLinks_ExitsAndEntitiesClickable(t *testing.T) {
+	defs := testDefs()
+	eng := engine.New(defs)
+	m := New(eng, defs, theme.Default())
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = next.(Model)
+	m = m.appendOutput(gameOutputMsg{lines: eng.Step("look").Output})
+
+	links := map[string][2]int{}
+	for y := range m.rows {
+		for x := 0; x < 80; x++ {
+			if cmd := m.linkAt(x, y); cmd != "" {
+				if _, seen := links[cmd]; !seen {
+					links[cmd] = [2]int{x, y}
+				}
+			}
+		}
+	}
+	if len(links) != 2 {
+		t.Fatalf("links = %v, want examine rusty key and go north", links)
+	}
+	if _, ok := links["examine rusty key"]; !ok {
+		t.Errorf("no link to examine the key in %v", links)
+	}
+
+	at, ok := links["go north"]
+	if !ok {
+		t.Fatalf("no link to go north in %v", links)
+	}
+	click := tea.MouseMsg{X: at[0], Y: at[1] - m.viewport.YOffset, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft}
+	next, _ = m.Update(click)
+	m = next.(Model)
+	if eng.State.Player.Location != "garden" {
+		t.Errorf("clicking the exit left the player in %q", eng.State.Player.Location)
+	}
+	if prev, _ := m.history.Prev(); prev != "go north" {
+		t.Errorf("history has %q, want the clicked command", prev)
+	}
+}
+
+func TestCombatHUD(t *testing.T) {
+	defs := testDefs()
+	defs.Game.PlayerStats = map[string]int{"hp": 20, "max_hp": 20, "attack": 5, "defense": 2}
+	defs.Entities["goblin"] = types.EntityDef{
+		ID:   "goblin",
+		Kind: "enemy",
+		Props: map[string]any{
+			"name": "Cave Goblin", "location": "garden",
+			"hp": 12, "max_hp": 12, "attack": 4, "defense": 1, "alive": true,
+		},
+	}
+	eng := engine.New(defs)
+	m := New(eng, defs, theme.Default())
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = next.(Model)
+	if m.renderCombatHUD() != "" || m.viewport.Height != 22 {
+		t.Fatalf("out of combat: HUD %q, viewport height %d", m.renderCombatHUD(), m.viewport.Height)
+	}
+
+	eng.State.Player.Location = "garden"
+	eng.State.Combat = types.CombatState{Active: true, EnemyID: "goblin"}
+	m.input.SetValue("defend")
+	next, _ = m.submit()
+	m = next.(Model)
+	if !state.InCombat(eng.State) {
+		t.Fatal("combat ended after defending")
+	}
+	if m.viewport.Height != 22-m.hudHeight() || m.hudHeight() == 0 {
+		t.Errorf("viewport height %d with a %d-row HUD", m.viewport.Height, m.hudHeight())
+	}
+
+	view := m.View()
+	hp, _ := state.GetStat(eng.State, defs, "player", "hp")
+	for _, want := range []string{"COMBAT · ROUND 2", "Cave Goblin", "12/12 HP", fmt.Sprintf("%d/20 HP", hp), "ATK 5  DEF 2", "ATK 4  DEF 1", "defending"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view lacks %q:\n%s", want, view)
+		}
+	}
+	if strings.Count(view, "COMBAT") != 1 {
+		t.Errorf("the HUD should be shown once, not added to the narrative:\n%s", view)
+	}
+
+	eng.State.Combat = types.CombatState{}
+	m.refreshViewport()
+	if strings.Contains(m.View(), "COMBAT") || m.viewport.Height != 22 {
+		t.Errorf("HUD still shown after combat, viewport height %d", m.viewport.Height)
+	}
+}
+
+func TestNewStyles(t *testing.T) {
+	light, _ := theme.Preset("light")
+	st := newStyles(light)
+	if got := st.header.GetForeground(); got != lipgloss.Color("232") {
+		t.Errorf("header color = %v, want the light preset's", got)
+	}
+	if got := st.statusBar.GetBackground(); got != lipgloss.Color("252") {
+		t.Errorf("status bar background = %v, want the light preset's", got)
+	}
+
+	mono, _ := theme.Preset("monochrome")
+	st = newStyles(mono)
+	if !st.statusBar.GetReverse() || !st.header.GetBold() {
+		t.Error("monochrome should keep the status bar visible in reverse video and headers bold")
+	}
+}
+
+func TestAccessible(t *testing.T) {
+	defs := testDefs()
+	defs.Game.PlayerStats = map[string]int{"hp": 20, "max_hp": 20, "attack": 5, "defense": 2}
+	defs.Entities["goblin"] = types.EntityDef{
+		ID:   "goblin",
+		Kind: "enemy",
+		Props: map[string]any{
+			"name": "Cave Goblin", "location": "hall",
+			"hp": 1, "max_hp": 1, "attack": 4, "defense": 0, "alive": true,
+		},
+	}
+	eng := engine.New(defs)
+	m := New(eng, defs, theme.Default())
+	m.access = access.New(eng)
+	next, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m = next.(Model)
+	next, _ = m.Update(m.initialOutput()())
+	m = next.(Model)
+
+	eng.State.Combat = types.CombatState{Active: true, EnemyID: "goblin"}
+	if m.renderCombatHUD() != "" || m.hudHeight() != 0 {
+		t.Error("the combat HUD should be hidden")
+	}
+	m.input.SetValue("attack")
+	next, _ = m.submit()
+	m = next.(Model)
+	if state.InCombat(eng.State) {
+		t.Fatal("the goblin survived the attack")
+	}
+
+	var text []string
+	for _, rl := range m.rawLines {
+		if rl.text == "" {
+			t.Error("blank lines should not separate turns")
+		}
+		text = append(text, rl.text)
+	}
+	all := strings.Join(text, "\n")
+	if !strings.Contains(all, "The only exit is north.") {
+		t.Errorf("exits should be in words:\n%s", all)
+	}
+	if strings.Contains(all, "VICTORY") || strings.Contains(all, "╭") || strings.Contains(all, "\x1b[") {
+		t.Errorf("output should have no boxes:\n%s", all)
+	}
+
+	m.input.SetValue("")
+	next, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	if next.(Model).sidebar {
+		t.Error("tab should not show the sidebar")
+	}
+}
+
+func TestHandleMeta_MarkAndRewind(t *testing.T) {
+	defs := testDefs()
+	eng := engine.New(defs)
+	m := New(eng, defs, theme.Default())
+
+	m.handleMeta("/mark hall")
+	eng.Step("north")
+	eng.State.Achievements = map[string]bool{"explorer": true}
+
+	output, quit := m.handleMeta("/rewind hall")
+	if quit || len(output) == 0 || output[0] != "Rewound to hall (turn 0)." {
+		t.Fatalf("rewind = %v, %v", output, quit)
+	}
+	if eng.State.Player.Location != "hall" {
+		t.Errorf("location = %q, want hall", eng.State.Player.Location)
+	}
+	if !eng.State.Achievements["explorer"] {
+		t.Error("achievements were rewound too")
+	}
+
+	// The mark can be rewound to again.
+	eng.Step("north")
+	m.handleMeta("/rewind hall")
+	if eng.State.Player.Location != "hall" {
+		t.Errorf("second rewind: location = %q, want hall", eng.State.Player.Location)
+	}
+}
+
+func TestHandleMeta_RewindWithQuestionPending(t *testing.T) {
+	defs := testDefs()
+	defs.Answers = map[string]types.AnswerDef{
+		"riddle": {ID: "riddle", Accept: []string{"echo"}, Attempts: 2},
+	}
+	eng := engine.New(defs)
+	m := New(eng, defs, theme.Default())
+	eng.State.Pending = "answer:riddle"
+	eng.State.Attempts = map[string]int{"riddle": 1}
+	m.handleMeta("/mark riddle")
+	turns, logged := eng.State.TurnCount, len(eng.State.CommandLog)
+
+	output, _ := m.handleMeta("/rewind riddle")
+	if strings.Contains(strings.Join(output, "\n"), "not the answer") {
+		t.Errorf("rewinding answered the riddle: %v", output)
+	}
+	if got := eng.State.Attempts["riddle"]; got != 1 || eng.State.Pending != "answer:riddle" {
+		t.Errorf("attempts = %d, pending = %q; want 1, answer:riddle", got, eng.State.Pending)
+	}
+	if eng.State.TurnCount != turns || len(eng.State.CommandLog) != logged {
+		t.Errorf("rewinding took a turn: turn %d, log %v", eng.State.TurnCount, eng.State.CommandLog)
+	}
+}
+
+func TestHandleMeta_Lua(t *testing.T) {
+	defs := testDefs()
+	eng := engine.New(defs)
+	m := New(eng, defs, theme.Default())
+
+	if output, _ := m.handleMeta("/lua location()"); len(output) != 1 || !strings.Contains(output[0], "--dev") {
+		t.Errorf("without a console: %v", output)
+	}
+
+	m.console = console.New(eng)
+	defer m.console.Close()
+	m.handleMeta("/lua")
+	m.updatePrompt()
+	if !m.luaMode || m.input.Prompt != "lua> " {
+		t.Fatalf("luaMode = %v, prompt = %q", m.luaMode, m.input.Prompt)
+	}
+	if got := m.evalLua("teleport('garden') return location()"); len(got) != 1 || got[0] != `"garden"` {
+		t.Errorf("eval = %v", got)
+	}
+	if eng.State.Player.Location != "garden" {
+		t.Errorf("location = %q, want garden", eng.State.Player.Location)
+	}
+	m.handleMeta("/lua")
+	m.updatePrompt()
+	if m.luaMode || m.input.Prompt != "> " {
+		t.Errorf("after /lua again: luaMode = %v, prompt = %q", m.luaMode, m.input.Prompt)
+	}
+}
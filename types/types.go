@@ -7,6 +7,7 @@ type Intent struct {
 	Verb   string
 	Object string // optional
 	Target string // optional
+	Count  int    // how many of the object, as in "take 3 coins"; 0 = not said
 }
 
 // Effect is a single atomic state mutation instruction.
@@ -26,6 +27,47 @@ type Result struct {
 	Effects []Effect
 	Events  []Event
 	Output  []string
+	// Notifications are announcements outside the narrative (score changes,
+	// achievements, quest updates) for the front end to show on the side.
+	Notifications []string
+	// Cues are the sounds to play and music changes, for front ends that
+	// have audio. The engine itself plays nothing.
+	Cues   []Cue
+	Failed bool // the command wasn't understood, or nothing handled it
+	// Image is the illustration for this turn: that of the room just shown
+	// or the entity just examined, as a path relative to the game directory.
+	Image string
+	// Epilogue is set on the turn the game reaches one of its endings, for
+	// the front end to present specially.
+	Epilogue *Epilogue
+	// Choices are the options the output just offered — the ending menu,
+	// a yes/no question, the things an ambiguous name could mean, an NPC's
+	// topics — for front ends to present as a menu. Typing still works.
+	Choices []Choice
+}
+
+// Choice is one option of a choice prompt.
+type Choice struct {
+	Label   string // shown in the menu
+	Command string // input that picks the option, as if typed
+}
+
+// Cue asks the front end to play a sound or change the music.
+type Cue struct {
+	Type string // "sound" or "music"
+	Name string // the sound or track; "" for music means stop
+}
+
+// Epilogue describes the ending the player reached.
+type Epilogue struct {
+	Ending    string // ending ID
+	Title     string
+	Text      string
+	Score     int // the "score" counter
+	MaxScore  int // Game.MaxScore; 0 = the game isn't scored out of a maximum
+	Turns     int
+	ScoreLine string // the score and turns, worded for display
+	Prompt    string // the post-ending menu, shown after the epilogue
 }
 
 // MatchCriteria defines what intent a rule matches against.
@@ -36,6 +78,8 @@ type MatchCriteria struct {
 	ObjectKind string         // match by entity kind (e.g. "item")
 	TargetProp map[string]any // target must have these props
 	ObjectProp map[string]any // object must have these props
+	Phase      string         // "" (replaces built-in behavior), "before" or "after"
+	Succeeded  *bool          // after phase: match only if the command did (not) succeed
 }
 
 // Condition is a predicate that must be true for a rule to fire.
@@ -55,11 +99,14 @@ type RuleDef struct {
 	Effects     []Effect
 	Priority    int
 	SourceOrder int
+	Once        bool // stops matching after it first fires
 }
 
 // TopicDef defines a single dialogue topic for an NPC.
 type TopicDef struct {
 	Text     string
+	Label    string // shown in topic lists, and accepted in place of the key ("" = the key)
+	Once     bool   // hidden once the player has heard it
 	Requires []Condition
 	Effects  []Effect
 }
@@ -71,15 +118,65 @@ type EntityDef struct {
 	Props  map[string]any      // base properties from Lua
 	Rules  []RuleDef           // rules scoped to this entity
 	Topics map[string]TopicDef // NPC topics (nil for non-NPCs)
+
+	// Reactions are handlers for events that happen in the entity's room.
+	Reactions []EventHandler
+
+	// Inventory lists the items the entity starts out carrying. The loader
+	// makes the entity each item's location.
+	Inventory []string
+
+	// OnReceive and OnShow are an NPC's responses, by item ID, to being
+	// given or shown an item. An NPC takes only items it has a response
+	// for whose conditions hold.
+	OnReceive map[string]ItemResponseDef
+	OnShow    map[string]ItemResponseDef
+}
+
+// ItemResponseDef is an NPC's response to being given or shown an item.
+type ItemResponseDef struct {
+	Text       string // what the NPC says ("" = the built-in message)
+	Conditions []Condition
+	Effects    []Effect
 }
 
 // RoomDef is the base definition of a room.
 type RoomDef struct {
-	ID          string
-	Description string
-	Exits       map[string]string // direction → room_id
-	Rules       []RuleDef
-	Fallbacks   map[string]string // verb → custom failure text
+	ID               string
+	Description      string
+	FirstDescription string                  // shown instead of Description on the first visit
+	NightDescription string                  // shown instead of Description at night, if the game has a clock
+	Image            string                  // illustration, a path relative to the game directory
+	Exits            map[string]string       // direction → room_id
+	ExitGuards       map[string]ExitGuardDef // direction → what going that way requires
+	Rules            []RuleDef
+	Fallbacks        map[string]string // verb → custom failure text
+	Capacity         int               // most NPCs and enemies the room holds; 0 = unlimited
+	Overflow         string            // when full: "reject" (default) or "adjacent"
+	Ambience         []AmbienceDef     // atmosphere, rolled once per turn the player spends here
+}
+
+// ExitGuardDef is what an exit requires of the player to be taken, such as
+// a lowered bridge or a boat. While its conditions don't all hold, or the
+// player isn't in its vehicle, going that way
+// shows BlockedText instead, and the exit is left out of the exits list
+// unless VisibleWhenBlocked.
+type ExitGuardDef struct {
+	Requires           []Condition
+	BlockedText        string // "" = the built-in "You can't go that way."
+	VisibleWhenBlocked bool
+	Vehicle            string // enterable entity the player must be in ("" = any or none)
+	Door               string // entity with a lock that bars the way while locked ("" = none)
+}
+
+// AmbienceDef is a line of room atmosphere, such as "A rat scurries past."
+// Each turn the player spends in the room, one percentile roll picks at
+// most one of the entries whose conditions hold: the first takes rolls up
+// to its chance, the next the following ones, and so on.
+type AmbienceDef struct {
+	Chance     int // percent chance per turn, 1-100
+	Text       string
+	Conditions []Condition
 }
 
 // GameDef holds game metadata from Lua.
@@ -89,7 +186,102 @@ type GameDef struct {
 	Version     string
 	Start       string // starting room ID
 	Intro       string
+	Locale      string         // selects locales/<locale>.lua; "" for none
 	PlayerStats map[string]int // combat stats: hp, max_hp, attack, defense
+	Levels      []LevelDef     // Levels[0] is level 2; nil = no leveling
+
+	MaxScore int // most points the "score" counter can reach; shown in epilogues
+
+	Clock *ClockDef // nil = no in-game clock
+
+	OnDeath      string          // "prompt" (default when empty), "respawn" or "restart"
+	RespawnRoom  string          // where "respawn" brings the player back; "" = Start
+	DeathPenalty DeathPenaltyDef // what a respawn costs
+
+	InventoryCategories []string       // display order for item categories
+	Amusing             []AmusingDef   // "amusing things" offered after an ending
+	IdleNudge           *IdleNudgeDef  // nil = no idle nudges
+	Objectives          []ObjectiveDef // the player's current goal; first whose conditions hold
+
+	Theme map[string]string // terminal colors by name, and "preset"; see package theme
+
+	NoClassicResponses bool              // turns off the built-in replies to "xyzzy", "pray", ...
+	ClassicResponses   map[string]string // per-entry overrides of those replies; "" silences one
+
+	Builtins map[string]bool // false turns off a verb's built-in behavior, leaving it to rules
+
+	AmbienceCooldown int // fewest turns between two lines of room ambience; 0 = no limit
+
+	Noise map[string]int // how much noise verbs make, overriding the engine's levels; 0 = silent
+
+	UnknownVerbResponses []string // replies, in turn, to verbs neither the parser nor any rule knows
+
+	CommandLogLimit int  // most recent commands kept in memory; 0 = DefaultCommandLogLimit
+	CompactSaves    bool // saves record the command log's length and hash instead of the log
+
+	Players     map[string]PlayerDef // player characters to switch between; nil = just the one
+	StartPlayer string               // which of Players the game starts as
+}
+
+// PlayerDef is a player character of a game with several, declared in
+// Game.players. Each has its own location, inventory and stats; the
+// switch_player effect changes which one the player controls.
+type PlayerDef struct {
+	Start     string         // room the character starts in; "" = Game.Start
+	Stats     map[string]int // starting stats; nil = Game.PlayerStats
+	Inventory []string       // items the character starts with
+}
+
+// LevelDef is a level the player can reach by earning experience.
+type LevelDef struct {
+	XP    int            // total XP needed to reach the level
+	Stats map[string]int // added to the player's stats on reaching it
+}
+
+// ClockDef configures the in-game clock. Times of day are minutes after
+// midnight.
+type ClockDef struct {
+	Start          int // time of day the game begins
+	MinutesPerTurn int // minutes each turn advances the clock
+	Dawn           int // when day begins
+	Dusk           int // when night begins
+}
+
+// DeathPenaltyDef is what the player loses on dying under the "respawn"
+// death policy.
+type DeathPenaltyDef struct {
+	Gold      int  // taken from the gold counter
+	XP        int  // taken from the xp stat; never costs a level
+	DropItems bool // the inventory is left where the player died
+}
+
+// IdleNudgeDef configures the gentle hint shown by interactive front ends
+// when the player has not typed anything for a while.
+type IdleNudgeDef struct {
+	Minutes int        // idle time before a nudge
+	Nudges  []NudgeDef // first whose conditions hold is shown
+}
+
+// NudgeDef is one contextual idle nudge.
+type NudgeDef struct {
+	Text       string
+	Conditions []Condition
+}
+
+// ObjectiveDef is a goal front ends may show the player, such as "Find the
+// lost crown." It is current while its conditions hold and no earlier
+// objective's do.
+type ObjectiveDef struct {
+	Text       string
+	Conditions []Condition
+}
+
+// AmusingDef is an entry in the post-ending "amusing things" list. It is
+// shown only if its conditions hold against the final state, so authors can
+// point out what the player missed.
+type AmusingDef struct {
+	Text       string
+	Conditions []Condition
 }
 
 // Player holds the player's runtime state.
@@ -97,6 +289,12 @@ type Player struct {
 	Location  string
 	Inventory []string
 	Stats     map[string]int
+	Worn      []string // inventory items currently worn
+	Vehicle   string   // enterable entity the player is in or riding ("" = none)
+
+	// Quantities holds how many of each stackable item (one with a
+	// quantity prop) the player carries.
+	Quantities map[string]int
 }
 
 // EntityState holds runtime overrides for an entity.
@@ -112,12 +310,38 @@ type CombatState struct {
 	RoundCount       int
 	Defending        bool   // true if player chose defend this round
 	PreviousLocation string // room before combat started (for flee)
+	Initiative       string // who acts first: "player" or "enemy"
+	Surprise         bool   // enemy caught unawares: it loses its first turn
 }
 
-// BehaviorEntry defines a weighted action for enemy AI.
+// BehaviorEntry defines a weighted action for enemy AI. Each enemy turn one
+// entry whose When conditions hold is picked, by weight. In the conditions
+// "self" names the enemy, as in StatLt("self", "hp", 4).
 type BehaviorEntry struct {
-	Action string
-	Weight int
+	Action   string
+	Weight   int
+	When     []Condition
+	Sequence []string // actions taken on successive turns, instead of Action
+}
+
+// AttackDef is one of an enemy's attacks. When the enemy attacks, it picks
+// one by Chance, as a weight, and deals Damage plus its attack stat, less
+// the defender's defense.
+type AttackDef struct {
+	Name    string
+	Damage  string // dice expression, such as "1d6+2"
+	Chance  int
+	Message string // shown instead of the usual "The {enemy} attacks you with its {attack}!"
+	Effect  string // status inflicted on a hit: sets the flag of that name
+}
+
+// CombatUse is what an item does when the player uses or throws it in a
+// fight, from the item's combat_use table.
+type CombatUse struct {
+	Damage   string // dice dealt to the enemy, less its defense ("" = none)
+	Heal     string // dice of HP restored to the player ("" = none)
+	Consumes bool   // the item is used up
+	Message  string // shown instead of "You use the {item} on the {enemy}!"
 }
 
 // LootEntry defines a possible item drop from an enemy.
@@ -128,20 +352,132 @@ type LootEntry struct {
 
 // State is the complete mutable game state.
 type State struct {
-	Player      Player
-	Entities    map[string]EntityState // runtime property overrides
-	Flags       map[string]bool
-	Counters    map[string]int
-	TurnCount   int
-	RNGSeed     int64
-	RNGPosition int64 // number of RNG calls for save/restore
-	CommandLog  []string
-	Combat      CombatState
+	Player       Player
+	Entities     map[string]EntityState // runtime property overrides
+	Flags        map[string]bool
+	Counters     map[string]int
+	TurnCount    int
+	RNGSeed      int64
+	RNGPosition  int64    // number of RNG calls for save/restore
+	CommandLog   []string // most recent commands, oldest first; see state.LogCommand
+	CommandCount int      // commands logged in total, including ones dropped from CommandLog
+	CommandHash  string   // running hash over every command logged
+	Combat       CombatState
+	Ending       string            // ID of the ending reached ("" = none yet)
+	Pending      string            // kind of prompt awaiting the next input ("" = none)
+	Attempts     map[string]int    // wrong answers given to each question asked
+	Fired        map[string]bool   // IDs of once rules and handlers that have fired
+	Visited      map[string]bool   // room IDs the player has been in
+	Examined     map[string]int    // times the player has examined each entity
+	Heard        map[string]bool   // NPC topics the player has heard, as "npc.topic"
+	Attached     map[string]string // entity → entity it is tied to; see state.IsAttached
+	Facts        []string          // IDs of the facts the player has learned, in the order learned
+	ActivePlayer string            // ID of the player character Player is ("" = the game has one)
+	Players      map[string]Player // the other player characters, as they were left
+	Verbosity    string            // "verbose" (default when empty), "brief", or "superbrief"
+	Clock        int               // minutes since midnight of the first day; see state.TimeOfDay
+	Music        string            // music track playing ("" = none)
+	AmbienceWait int               // turns before room ambience may show again
+	Stats        Stats             // the playthrough's running totals
+
+	// Locations indexes where entities are: room ID → IDs of the entities
+	// there. It is derived from Entities and the definitions, kept up to
+	// date by state.SetEntityLocation, and rebuilt when nil; see
+	// state.EntitiesInRoom.
+	Locations map[string]map[string]bool
+
+	// Achievements are the IDs of unlocked achievements. They belong to the
+	// player's profile rather than to this playthrough: they survive
+	// restarts and are not saved with the game.
+	Achievements map[string]bool
+}
+
+// Stats are running totals of a playthrough, shown by /stats.
+type Stats struct {
+	Turns           int
+	RoomsVisited    int
+	ItemsCollected  int
+	EnemiesDefeated int
+	DamageDealt     int
+	DamageTaken     int
+	Deaths          int
+	Commands        int // commands run, understood or not
+	Failed          int // commands that failed or weren't understood
+	Draws           int // random numbers drawn
+}
+
+// ComputedDef is a derived value declared in Lua and evaluated by the engine
+// from state. The first case whose conditions hold supplies the value;
+// Default is used when none do.
+type ComputedDef struct {
+	ID      string
+	Uses    []string // declared inputs, e.g. "flags.door_open", "counters.gold"
+	Cases   []ComputedCase
+	Default any
+}
+
+// ComputedCase is one branch of a computed value.
+type ComputedCase struct {
+	When  []Condition
+	Value any
+}
+
+// EndingDef is one of the ways a game can conclude, reached with the
+// end_game effect.
+type EndingDef struct {
+	ID    string
+	Title string // e.g. "Victory"; defaults to the ID
+	Text  string // epilogue
+}
+
+// AchievementDef is an achievement the player can unlock with the
+// unlock_achievement effect. Unlocked achievements persist across
+// playthroughs.
+type AchievementDef struct {
+	ID          string
+	Name        string // e.g. "Pacifist"; defaults to the ID
+	Description string
+	Hidden      bool // listed only once unlocked
+	Order       int  // declaration order, for listing
+}
+
+// FactDef is something the player can learn, with the learn_fact effect,
+// such as a password. Learned facts with text are listed by the notes
+// command.
+type FactDef struct {
+	ID   string
+	Text string // how the notes put it, e.g. "The password is swordfish."; "" = not listed
+}
+
+// AnswerDef is a free-text question, such as a riddle, posed by the ask
+// effect. The player's next input is checked against Accept; Success runs on
+// a match, Failure once Attempts wrong answers have been given.
+type AnswerDef struct {
+	ID       string
+	Accept   []string // accepted answers, compared ignoring case, punctuation and articles
+	Attempts int      // wrong answers allowed before Failure runs
+	Retry    string   // shown after a wrong answer while attempts remain
+	Success  []Effect
+	Failure  []Effect
+}
+
+// MetaCommandDef is a slash-command the game adds to the front ends' own,
+// such as "/spells". It either runs Effects outside the turn, like /help,
+// or stands for Verb, as if the player had typed it with the command's
+// arguments.
+type MetaCommandDef struct {
+	Name    string // including the slash, e.g. "/spells"
+	Help    string // listed by /help ("" = not listed)
+	Verb    string // game command run in its place ("" = run Effects)
+	Effects []Effect
 }
 
 // EventHandler is a rule triggered by an event rather than a player command.
 type EventHandler struct {
-	EventType  string
+	ID         string // explicit id, or "handler:<n>" by declaration order
+	EventType  string // event type; a trailing "*" matches any suffix
+	Priority   int    // higher runs first; ties keep declaration order
+	Once       bool   // fires at most once per game
 	Conditions []Condition
 	Effects    []Effect
 }